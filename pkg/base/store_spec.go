@@ -48,8 +48,19 @@ type StoreSpec struct {
 	SizePercent float64
 	InMemory    bool
 	Attributes  roachpb.Attributes
+	// EncryptionKeyPath is the path of the file containing the active store
+	// key, or PlainStoreKey. Empty if encryption at rest is not configured.
+	EncryptionKeyPath string
+	// OldEncryptionKeyPath is the path of the file containing the store key
+	// that was active before the last key rotation, or PlainStoreKey.
+	OldEncryptionKeyPath string
 }
 
+// PlainStoreKey can be specified in place of the path of a store key file to
+// denote unencrypted data. It is used to enable encryption on an existing
+// store (as the old key) or to disable it (as the active key).
+const PlainStoreKey = "plain"
+
 // String returns a fully parsable version of the store spec.
 func (ss StoreSpec) String() string {
 	var buffer bytes.Buffer
@@ -75,6 +86,12 @@ func (ss StoreSpec) String() string {
 		}
 		fmt.Fprintf(&buffer, ",")
 	}
+	if ss.EncryptionKeyPath != "" {
+		fmt.Fprintf(&buffer, "key=%s,", ss.EncryptionKeyPath)
+	}
+	if ss.OldEncryptionKeyPath != "" {
+		fmt.Fprintf(&buffer, "old-key=%s,", ss.OldEncryptionKeyPath)
+	}
 	// Trim the extra comma from the end if it exists.
 	if l := buffer.Len(); l > 0 {
 		buffer.Truncate(l - 1)
@@ -98,7 +115,7 @@ var fractionRegex = regexp.MustCompile(`^([0-9]+\.[0-9]*|[0-9]*\.[0-9]+|[0-9]+(\
 
 // newStoreSpec parses the string passed into a --store flag and returns a
 // StoreSpec if it is correctly parsed.
-// There are four possible fields that can be passed in, comma separated:
+// There are six possible fields that can be passed in, comma separated:
 // - path=xxx The directory in which to the rocks db instance should be
 //   located, required unless using a in memory storage.
 // - type=mem This specifies that the store is an in memory storage instead of
@@ -112,6 +129,12 @@ var fractionRegex = regexp.MustCompile(`^([0-9]+\.[0-9]*|[0-9]*\.[0-9]+|[0-9]+(\
 //   - 20%             -> 20% of the available space
 //   - 0.2             -> 20% of the available space
 // - attrs=xxx:yyy:zzz A colon separated list of optional attributes.
+// - key=xxx The path of a file containing the AES key (16, 24 or 32 raw
+//   bytes) with which to encrypt the store's data at rest, or "plain" for no
+//   encryption. Requires a CCL binary.
+// - old-key=xxx The path of the file containing the previous key (or "plain")
+//   after a key rotation. Data written under the old key is re-encrypted with
+//   the new one over time; the old key is needed until that is complete.
 // Note that commas are forbidden within any field name or value.
 func newStoreSpec(value string) (StoreSpec, error) {
 	if len(value) == 0 {
@@ -198,6 +221,10 @@ func newStoreSpec(value string) (StoreSpec, error) {
 			} else {
 				return StoreSpec{}, fmt.Errorf("%s is not a valid store type", value)
 			}
+		case "key":
+			ss.EncryptionKeyPath = value
+		case "old-key":
+			ss.OldEncryptionKeyPath = value
 		default:
 			return StoreSpec{}, fmt.Errorf("%s is not a valid store field", field)
 		}
@@ -210,9 +237,15 @@ func newStoreSpec(value string) (StoreSpec, error) {
 		if ss.SizePercent == 0 && ss.SizeInBytes == 0 {
 			return StoreSpec{}, fmt.Errorf("size must be specified for an in memory store")
 		}
+		if ss.EncryptionKeyPath != "" {
+			return StoreSpec{}, fmt.Errorf("key specified for in memory store")
+		}
 	} else if ss.Path == "" {
 		return StoreSpec{}, fmt.Errorf("no path specified")
 	}
+	if ss.OldEncryptionKeyPath != "" && ss.EncryptionKeyPath == "" {
+		return StoreSpec{}, fmt.Errorf("old-key specified without key")
+	}
 	return ss, nil
 }
 
@@ -0,0 +1,130 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/LICENSE
+
+package cliccl
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/sqlccl"
+	"github.com/cockroachdb/cockroach/pkg/ccl/storageccl/engineccl"
+	"github.com/cockroachdb/cockroach/pkg/cli"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+)
+
+var debugBackupCmd = &cobra.Command{
+	Use:   "backup [directory|uri]",
+	Short: "show the contents of a backup",
+	Long: `
+Show the contents of a backup, one line per table: the time range it covers,
+its size, number of rows and number of files.
+
+A plain directory argument is read directly from the local filesystem, so this
+works without a running cluster.
+`,
+	RunE: cli.MaybeDecorateGRPCError(runDebugBackup),
+}
+
+var debugEncryptionStatusCmd = &cobra.Command{
+	Use:   "encryption-status [directory] [key-file]",
+	Short: "show the progress of the encryption of a store",
+	Long: `
+Show the number of files of a store encrypted with each store key, identified
+by its key ID. If the file containing the active store key (or "plain") is
+given, also show how much of the store is encrypted with it, which reaches
+100% once a key rotation is complete and the old key is no longer needed.
+
+Only the headers of the files are read, so this can be run while the node is
+running and doesn't require the keys.
+`,
+	RunE: cli.MaybeDecorateGRPCError(runDebugEncryptionStatus),
+}
+
+func init() {
+	cli.DebugCmd.AddCommand(debugBackupCmd)
+	cli.DebugCmd.AddCommand(debugEncryptionStatusCmd)
+}
+
+func formatBackupTime(ts hlc.Timestamp) string {
+	if ts == (hlc.Timestamp{}) {
+		return "NULL"
+	}
+	return ts.GoTime().UTC().Format(time.RFC3339Nano)
+}
+
+func runDebugBackup(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return errors.New("one argument is required")
+	}
+
+	uri := args[0]
+	if !strings.Contains(uri, "://") {
+		path, err := filepath.Abs(uri)
+		if err != nil {
+			return err
+		}
+		uri = "nodelocal://" + path
+	}
+
+	desc, err := sqlccl.ReadBackupDescriptorFromURI(context.Background(), uri)
+	if err != nil {
+		return errors.Wrapf(err, "reading backup descriptor from %s", args[0])
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 2, 1, 2, ' ', 0)
+	fmt.Fprintln(tw, "database\ttable\tstart_time\tend_time\tsize_bytes\trows\tfiles")
+	for _, s := range sqlccl.SummarizeBackup(desc) {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%d\t%d\n",
+			s.Database, s.Table, formatBackupTime(s.StartTime), formatBackupTime(s.EndTime),
+			s.DataSize, s.Rows, s.Files)
+	}
+	return tw.Flush()
+}
+
+func runDebugEncryptionStatus(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 && len(args) != 2 {
+		return errors.New("a store directory and optionally a key file are required")
+	}
+
+	var activeKeyID string
+	if len(args) == 2 {
+		var err error
+		if activeKeyID, err = engineccl.StoreKeyID(args[1]); err != nil {
+			return err
+		}
+	}
+	status, err := engineccl.GetEncryptionStatus(args[0], activeKeyID)
+	if err != nil {
+		return err
+	}
+	if activeKeyID != "" {
+		fmt.Println(status)
+	}
+
+	keyIDs := make([]string, 0, len(status.KeyFiles))
+	for id := range status.KeyFiles {
+		keyIDs = append(keyIDs, id)
+	}
+	sort.Strings(keyIDs)
+	tw := tabwriter.NewWriter(os.Stdout, 2, 1, 2, ' ', 0)
+	fmt.Fprintln(tw, "key_id\tfiles")
+	for _, id := range keyIDs {
+		fmt.Fprintf(tw, "%s\t%d\n", id, status.KeyFiles[id])
+	}
+	return tw.Flush()
+}
@@ -29,6 +29,8 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/parser"
 	"github.com/cockroachdb/cockroach/pkg/sql/privilege"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine"
+	"github.com/cockroachdb/cockroach/pkg/util/encoding"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/interval"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
@@ -40,6 +42,12 @@ const (
 	// BackupDescriptorName is the file name used for serialized
 	// BackupDescriptor protos.
 	BackupDescriptorName = "BACKUP"
+	// backupEncryptionInfoName is the name of the file holding the
+	// EncryptionInfo of an encrypted backup.
+	backupEncryptionInfoName = "ENCRYPTION-INFO"
+
+	backupOptRevisionHistory = "revision_history"
+	backupOptEncPassphrase   = "encryption_passphrase"
 )
 
 // exportStorageFromURI returns an ExportStorage for the given URI.
@@ -52,7 +60,9 @@ func exportStorageFromURI(ctx context.Context, uri string) (storageccl.ExportSto
 }
 
 // readBackupDescriptor reads and unmarshals a BackupDescriptor from given base.
-func readBackupDescriptor(ctx context.Context, uri string) (BackupDescriptor, error) {
+func readBackupDescriptor(
+	ctx context.Context, uri string, encryption *roachpb.FileEncryptionOptions,
+) (BackupDescriptor, error) {
 	dir, err := exportStorageFromURI(ctx, uri)
 	if err != nil {
 		return BackupDescriptor{}, err
@@ -68,6 +78,14 @@ func readBackupDescriptor(ctx context.Context, uri string) (BackupDescriptor, er
 	if err != nil {
 		return BackupDescriptor{}, err
 	}
+	if encryption != nil {
+		if descBytes, err = storageccl.DecryptFile(descBytes, encryption.Key); err != nil {
+			return BackupDescriptor{}, err
+		}
+	} else if storageccl.AppearsEncrypted(descBytes) {
+		return BackupDescriptor{}, errors.Errorf(
+			"backup is encrypted, the %q option is required", backupOptEncPassphrase)
+	}
 	var backupDesc BackupDescriptor
 	if err := backupDesc.Unmarshal(descBytes); err != nil {
 		return BackupDescriptor{}, err
@@ -85,15 +103,132 @@ func ValidatePreviousBackups(ctx context.Context, uris []string) (hlc.Timestamp,
 		// Full backup.
 		return hlc.Timestamp{}, nil
 	}
-	backups := make([]BackupDescriptor, len(uris))
-	for i, uri := range uris {
-		desc, err := readBackupDescriptor(ctx, uri)
-		if err != nil {
-			return hlc.Timestamp{}, err
+	backups, err := loadBackupDescs(ctx, uris, nil /* encryption */)
+	if err != nil {
+		return hlc.Timestamp{}, err
+	}
+	return validatePreviousBackups(backups)
+}
+
+// readEncryptionInfo reads the EncryptionInfo of the encrypted backup at the
+// given URI.
+func readEncryptionInfo(ctx context.Context, uri string) (EncryptionInfo, error) {
+	dir, err := exportStorageFromURI(ctx, uri)
+	if err != nil {
+		return EncryptionInfo{}, err
+	}
+	defer dir.Close()
+	r, err := dir.ReadFile(ctx, backupEncryptionInfoName)
+	if err != nil {
+		return EncryptionInfo{}, errors.Wrap(err, "reading encryption info (is the backup encrypted?)")
+	}
+	defer r.Close()
+	infoBytes, err := ioutil.ReadAll(r)
+	if err != nil {
+		return EncryptionInfo{}, err
+	}
+	var info EncryptionInfo
+	if err := info.Unmarshal(infoBytes); err != nil {
+		return EncryptionInfo{}, err
+	}
+	return info, nil
+}
+
+// writeEncryptionInfo writes the EncryptionInfo of the encrypted backup at the
+// given URI.
+func writeEncryptionInfo(ctx context.Context, uri string, info EncryptionInfo) error {
+	dir, err := exportStorageFromURI(ctx, uri)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	infoBytes, err := info.Marshal()
+	if err != nil {
+		return err
+	}
+	return dir.WriteFile(ctx, backupEncryptionInfoName, bytes.NewReader(infoBytes))
+}
+
+// verifyNoBackupAt returns an error if there is already a backup, or the
+// EncryptionInfo of one, at the given URI. Overwriting the EncryptionInfo of
+// an encrypted backup would make it impossible to decrypt.
+func verifyNoBackupAt(ctx context.Context, uri string) error {
+	dir, err := exportStorageFromURI(ctx, uri)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	// ExportStorage doesn't distinguish missing files from other errors, so
+	// only a successful read counts as an existing file.
+	for _, name := range []string{BackupDescriptorName, backupEncryptionInfoName} {
+		if r, err := dir.ReadFile(ctx, name); err == nil {
+			_ = r.Close()
+			return errors.Errorf("%s already contains a %s file", uri, name)
 		}
-		backups[i] = desc
 	}
+	return nil
+}
+
+// backupEncryption sets up the encryption of the backup to the given URI if
+// the options include an encryption passphrase, returning nil otherwise. It
+// writes the EncryptionInfo of the backup, reusing the salt of the full backup
+// of an incremental chain so that a single key decrypts the whole chain. It
+// fails if there is already a backup at the URI.
+func backupEncryption(
+	ctx context.Context, opts parser.KVOptions, to string, incrementalFrom []string,
+) (*roachpb.FileEncryptionOptions, error) {
+	passphrase, ok := opts.Get(backupOptEncPassphrase)
+	if !ok {
+		return nil, nil
+	}
+	if passphrase == "" {
+		return nil, errors.Errorf("option %q requires a value", backupOptEncPassphrase)
+	}
+	var info EncryptionInfo
+	var err error
+	if len(incrementalFrom) > 0 {
+		info, err = readEncryptionInfo(ctx, incrementalFrom[0])
+	} else {
+		info.Salt, err = storageccl.GenerateSalt()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyNoBackupAt(ctx, to); err != nil {
+		return nil, err
+	}
+	if err := writeEncryptionInfo(ctx, to, info); err != nil {
+		return nil, err
+	}
+	return &roachpb.FileEncryptionOptions{
+		Key: storageccl.GenerateKey([]byte(passphrase), info.Salt),
+	}, nil
+}
 
+// encryptionFromOptions returns the encryption to use for the chain of backups
+// starting at baseURI if the options include an encryption passphrase, and nil
+// otherwise. The key is derived from the passphrase and the salt recorded in
+// the EncryptionInfo of the base backup.
+func encryptionFromOptions(
+	ctx context.Context, opts parser.KVOptions, baseURI string,
+) (*roachpb.FileEncryptionOptions, error) {
+	passphrase, ok := opts.Get(backupOptEncPassphrase)
+	if !ok {
+		return nil, nil
+	}
+	if passphrase == "" {
+		return nil, errors.Errorf("option %q requires a value", backupOptEncPassphrase)
+	}
+	info, err := readEncryptionInfo(ctx, baseURI)
+	if err != nil {
+		return nil, err
+	}
+	return &roachpb.FileEncryptionOptions{
+		Key: storageccl.GenerateKey([]byte(passphrase), info.Salt),
+	}, nil
+}
+
+func validatePreviousBackups(backups []BackupDescriptor) (hlc.Timestamp, error) {
 	// This reuses Restore's logic for lining up all the start and end
 	// timestamps to validate the previous backups that this one is incremental
 	// from.
@@ -133,17 +268,145 @@ func allRangeDescriptors(ctx context.Context, txn *client.Txn) ([]roachpb.RangeD
 	return rangeDescs, nil
 }
 
+// getAllDescChanges returns every revision of the SQL descriptors written
+// between startTime and endTime, in order of increasing time.
+func getAllDescChanges(
+	ctx context.Context, db *client.DB, startTime, endTime hlc.Timestamp,
+) ([]BackupDescriptor_DescriptorRevision, error) {
+	startKey := roachpb.Key(keys.MakeTablePrefix(keys.DescriptorTableID))
+	endKey := startKey.PrefixEnd()
+
+	req := &roachpb.ExportRequest{
+		Span:       roachpb.Span{Key: startKey, EndKey: endKey},
+		StartTime:  startTime,
+		MVCCFilter: roachpb.MVCCFilter_All,
+		ReturnSST:  true,
+	}
+	header := roachpb.Header{Timestamp: endTime}
+	res, pErr := client.SendWrappedWith(ctx, db.GetSender(), header, req)
+	if pErr != nil {
+		return nil, pErr.GoError()
+	}
+
+	var revs []BackupDescriptor_DescriptorRevision
+	for _, file := range res.(*roachpb.ExportResponse).Files {
+		if err := func() error {
+			sst := engine.NewInMem(roachpb.Attributes{}, 1<<20)
+			defer sst.Close()
+			if err := sst.IngestExternalData(file.SST); err != nil {
+				return err
+			}
+			start, end := engine.MVCCKey{Key: startKey}, engine.MVCCKey{Key: endKey}
+			return sst.Iterate(start, end, func(kv engine.MVCCKeyValue) (bool, error) {
+				remaining, _, _, err := sqlbase.DecodeTableIDIndexID(kv.Key.Key)
+				if err != nil {
+					return true, err
+				}
+				_, id, err := encoding.DecodeUvarintAscending(remaining)
+				if err != nil {
+					return true, err
+				}
+				rev := BackupDescriptor_DescriptorRevision{ID: sqlbase.ID(id), Time: kv.Key.Timestamp}
+				if len(kv.Value) > 0 {
+					var desc sqlbase.Descriptor
+					if err := (roachpb.Value{RawBytes: kv.Value}).GetProto(&desc); err != nil {
+						return true, errors.Wrapf(err, "%s: unable to unmarshal SQL descriptor", kv.Key)
+					}
+					rev.Desc = &desc
+				}
+				revs = append(revs, rev)
+				return false, nil
+			})
+		}(); err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(revs, func(i, j int) bool { return revs[i].Time.Less(revs[j].Time) })
+	return revs, nil
+}
+
+// getRelevantDescChanges returns the revisions of the given descriptors
+// between startTime and endTime, in order of increasing time. For incremental
+// backups, the first revision of each descriptor is its state as of
+// startTime, so that the descriptors can be reconstructed at any time covered
+// by the backup without looking at the previous ones.
+//
+// Only the descriptors that are backed up as of endTime are considered, so the
+// tables created or dropped during the backup's time range that aren't part of
+// it at endTime can't be restored.
+func getRelevantDescChanges(
+	ctx context.Context,
+	db *client.DB,
+	startTime, endTime hlc.Timestamp,
+	descs []sqlbase.Descriptor,
+) ([]BackupDescriptor_DescriptorRevision, error) {
+	interesting := make(map[sqlbase.ID]struct{}, len(descs))
+	for i := range descs {
+		interesting[descs[i].GetID()] = struct{}{}
+	}
+
+	var revs []BackupDescriptor_DescriptorRevision
+	if startTime != (hlc.Timestamp{}) {
+		var startDescs []sqlbase.Descriptor
+		txn := client.NewTxn(db)
+		opt := client.TxnExecOptions{AutoRetry: true, AutoCommit: true}
+		if err := txn.Exec(ctx, opt, func(ctx context.Context, txn *client.Txn, opt *client.TxnExecOptions) error {
+			var err error
+			sql.SetTxnTimestamps(txn, startTime)
+			startDescs, err = allSQLDescriptors(ctx, txn)
+			return err
+		}); err != nil {
+			return nil, err
+		}
+		for i := range startDescs {
+			desc := &startDescs[i]
+			if _, ok := interesting[desc.GetID()]; ok {
+				revs = append(revs, BackupDescriptor_DescriptorRevision{
+					Time: startTime, ID: desc.GetID(), Desc: desc,
+				})
+			}
+		}
+	}
+
+	allChanges, err := getAllDescChanges(ctx, db, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+	for _, change := range allChanges {
+		if _, ok := interesting[change.ID]; ok {
+			revs = append(revs, change)
+		}
+	}
+	return revs, nil
+}
+
 // spansForAllTableIndexes returns non-overlapping spans for every index and
-// table passed in. They would normally overlap if any of them are interleaved.
-func spansForAllTableIndexes(tables []*sqlbase.TableDescriptor) []roachpb.Span {
+// table passed in, as well as for every index of the table revisions passed in.
+// They would normally overlap if any of them are interleaved.
+func spansForAllTableIndexes(
+	tables []*sqlbase.TableDescriptor, revs []BackupDescriptor_DescriptorRevision,
+) []roachpb.Span {
 	sstIntervalTree := interval.Tree{Overlapper: interval.Range.OverlapExclusive}
-	for _, table := range tables {
+	insertIndexSpans := func(table *sqlbase.TableDescriptor) {
 		for _, index := range table.AllNonDropIndexes() {
 			if err := sstIntervalTree.Insert(intervalSpan(table.IndexSpan(index.ID)), false); err != nil {
 				panic(errors.Wrap(err, "IndexSpan"))
 			}
 		}
 	}
+	for _, table := range tables {
+		insertIndexSpans(table)
+	}
+	// Restoring to a time covered by a backup with revision history may need
+	// the data of indexes that were dropped since then.
+	for _, rev := range revs {
+		if rev.Desc == nil {
+			continue
+		}
+		if table := rev.Desc.GetTable(); table != nil {
+			insertIndexSpans(table)
+		}
+	}
 
 	var spans []roachpb.Span
 	_ = sstIntervalTree.Do(func(r interval.Interface) bool {
@@ -203,17 +466,19 @@ func backupJobDescription(
 	backup *parser.Backup, to string, incrementalFrom []string,
 ) (string, error) {
 	b := parser.Backup{
-		AsOf:            backup.AsOf,
-		Options:         backup.Options,
-		Targets:         backup.Targets,
-		IncrementalFrom: make(parser.Exprs, len(incrementalFrom)),
+		AsOf:    backup.AsOf,
+		Options: redactOptions(backup.Options),
+		Targets: backup.Targets,
+	}
+	if len(incrementalFrom) > 0 {
+		b.IncrementalFrom = make(parser.Exprs, len(incrementalFrom))
 	}
 
 	to, err := storageccl.SanitizeExportStorageURI(to)
 	if err != nil {
 		return "", err
 	}
-	backup.To = parser.NewDString(to)
+	b.To = parser.NewDString(to)
 
 	for i, from := range incrementalFrom {
 		sanitizedFrom, err := storageccl.SanitizeExportStorageURI(from)
@@ -223,7 +488,23 @@ func backupJobDescription(
 		b.IncrementalFrom[i] = parser.NewDString(sanitizedFrom)
 	}
 
-	return backup.String(), nil
+	return b.String(), nil
+}
+
+// redactOptions returns a copy of the given options with the encryption
+// passphrase hidden, for use in job descriptions.
+func redactOptions(opts parser.KVOptions) parser.KVOptions {
+	if opts == nil {
+		return nil
+	}
+	redacted := make(parser.KVOptions, len(opts))
+	for i, opt := range opts {
+		if opt.Key == backupOptEncPassphrase {
+			opt.Value = "redacted"
+		}
+		redacted[i] = opt
+	}
+	return redacted
 }
 
 // clusterNodeCount returns the approximate number of nodes in the cluster.
@@ -257,22 +538,64 @@ func (r backupFileDescriptors) Less(i, j int) bool {
 // - <dir> is given by the user and may be cloud storage
 // - Each file contains data for a key range that doesn't overlap with any other
 //   file.
+//
+// If prevBackups is not empty, the backup is incremental from them: it only
+// contains the changes since the end time of the last one.
+//
+// If encryption is set, the files and the backup descriptor are encrypted with
+// it.
+//
+// With the revision_history option, the files contain every revision of the
+// data between startTime and endTime instead of only the latest one, and the
+// backup descriptor records the revisions of the SQL descriptors, so that the
+// backup can be restored as of any time it covers.
 func Backup(
 	ctx context.Context,
 	p sql.PlanHookState,
 	uri string,
 	targets parser.TargetList,
-	startTime, endTime hlc.Timestamp,
-	_ parser.KVOptions,
+	prevBackups []BackupDescriptor,
+	endTime hlc.Timestamp,
+	opts parser.KVOptions,
+	encryption *roachpb.FileEncryptionOptions,
 	jobLogger *sql.JobLogger,
 ) (BackupDescriptor, error) {
 	// TODO(dan): Figure out how permissions should work. #6713 is tracking this
 	// for grpc.
 
+	var startTime hlc.Timestamp
+	if len(prevBackups) > 0 {
+		var err error
+		if startTime, err = validatePreviousBackups(prevBackups); err != nil {
+			return BackupDescriptor{}, err
+		}
+	}
+
+	var revisionHistory bool
+	if override, ok := opts.Get(backupOptRevisionHistory); ok {
+		if override != "" {
+			return BackupDescriptor{}, errors.Errorf("option %q does not take a value",
+				backupOptRevisionHistory)
+		}
+		revisionHistory = true
+	}
+
+	// A BACKUP without targets is a backup of the entire cluster, and can only
+	// be incremental from other full cluster backups.
+	coverage := BackupDescriptor_RequestedDescriptors
+	if targets.IsEmpty() {
+		coverage = BackupDescriptor_AllDescriptors
+	}
+	for _, prev := range prevBackups {
+		if prev.DescriptorCoverage != coverage {
+			return BackupDescriptor{}, errors.New(
+				"a full cluster backup can only be incremental from other full cluster backups, and vice versa")
+		}
+	}
+
 	var sqlDescs []sqlbase.Descriptor
 
-	storageConf, err := storageccl.ExportStorageConfFromURI(uri)
-	if err != nil {
+	if _, err := storageccl.ExportStorageConfFromURI(uri); err != nil {
 		return BackupDescriptor{}, err
 	}
 	db := p.ExecCfg().DB
@@ -291,10 +614,15 @@ func Backup(
 		}
 	}
 
-	// TODO(dan): Plumb the session database down.
-	sessionDatabase := ""
-	if sqlDescs, err = descriptorsMatchingTargets(sessionDatabase, sqlDescs, targets); err != nil {
-		return BackupDescriptor{}, err
+	if coverage == BackupDescriptor_AllDescriptors {
+		sqlDescs = fullClusterDescriptors(sqlDescs)
+	} else {
+		// TODO(dan): Plumb the session database down.
+		sessionDatabase := ""
+		var err error
+		if sqlDescs, err = descriptorsMatchingTargets(sessionDatabase, sqlDescs, targets); err != nil {
+			return BackupDescriptor{}, err
+		}
 	}
 
 	for _, desc := range sqlDescs {
@@ -308,7 +636,8 @@ func Backup(
 	// Backup users, descriptors, and the entire keyspace for user data.
 	tables := []*sqlbase.TableDescriptor{&sqlbase.DescriptorTable, &sqlbase.UsersTable}
 	for _, desc := range sqlDescs {
-		if tableDesc := desc.GetTable(); tableDesc != nil {
+		// The system tables of a full cluster backup include the users table.
+		if tableDesc := desc.GetTable(); tableDesc != nil && tableDesc.ID != keys.UsersTableID {
 			tables = append(tables, tableDesc)
 		}
 	}
@@ -319,6 +648,14 @@ func Backup(
 		}
 	}
 
+	var revs []BackupDescriptor_DescriptorRevision
+	if revisionHistory {
+		var err error
+		if revs, err = getRelevantDescChanges(ctx, db, startTime, endTime, sqlDescs); err != nil {
+			return BackupDescriptor{}, err
+		}
+	}
+
 	var ranges []roachpb.RangeDescriptor
 	if err := db.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
 		var err error
@@ -333,19 +670,31 @@ func Backup(
 
 	// We split the spans into range-sized pieces so that we can use the number of
 	// completed requests as a rough measure of progress.
-	spans := splitSpansByRanges(spansForAllTableIndexes(tables), ranges)
-
-	mu := struct {
-		syncutil.Mutex
-		files    []BackupDescriptor_File
-		dataSize int64
-	}{}
-
-	progressLogger := jobProgressLogger{
-		jobLogger:   jobLogger,
-		totalChunks: len(spans),
+	spans := spansForAllTableIndexes(tables, revs)
+	if len(prevBackups) > 0 && prevBackups[len(prevBackups)-1].RevisionHistory {
+		// The previous backup may cover the indexes of table revisions that no
+		// longer exist. Keep covering them so that the chain of backups stays
+		// restorable.
+		spans, _ = roachpb.MergeSpans(append(spans, prevBackups[len(prevBackups)-1].Spans...))
 	}
+	spans = splitSpansByRanges(spans, ranges)
 
+	desc := BackupDescriptor{
+		StartTime:          startTime,
+		EndTime:            endTime,
+		Descriptors:        sqlDescs,
+		Spans:              spans,
+		RevisionHistory:    revisionHistory,
+		DescriptorChanges:  revs,
+		DescriptorCoverage: coverage,
+	}
+	descBuf, err := desc.Marshal()
+	if err != nil {
+		return BackupDescriptor{}, err
+	}
+	jobLogger.Job.Details = sql.BackupJobDetails{
+		URI: uri, BackupDescriptor: descBuf, Encryption: encryption,
+	}
 	for _, desc := range tables {
 		jobLogger.Job.DescriptorIDs = append(jobLogger.Job.DescriptorIDs, desc.GetID())
 	}
@@ -355,6 +704,74 @@ func Backup(
 	if err := jobLogger.Started(ctx); err != nil {
 		return BackupDescriptor{}, err
 	}
+	return backup(ctx, p.ExecCfg(), jobLogger)
+}
+
+// backup exports the spans of the backup described by the details of the given
+// job that were not yet exported as of its last checkpoint, then writes the
+// backup descriptor.
+func backup(
+	ctx context.Context, execCfg *sql.ExecutorConfig, jobLogger *sql.JobLogger,
+) (BackupDescriptor, error) {
+	details := jobLogger.Job.Details.(sql.BackupJobDetails)
+	storageConf, err := storageccl.ExportStorageConfFromURI(details.URI)
+	if err != nil {
+		return BackupDescriptor{}, err
+	}
+	var desc BackupDescriptor
+	if err := desc.Unmarshal(details.BackupDescriptor); err != nil {
+		return BackupDescriptor{}, err
+	}
+	db := execCfg.DB
+
+	mu := struct {
+		syncutil.Mutex
+		files          []BackupDescriptor_File
+		dataSize       int64
+		entryCounts    roachpb.BulkOpSummary
+		completedSpans []roachpb.Span
+	}{
+		files:          desc.Files,
+		dataSize:       desc.DataSize,
+		entryCounts:    desc.EntryCounts,
+		completedSpans: details.CompletedSpans,
+	}
+
+	completed := make(map[string]struct{}, len(details.CompletedSpans))
+	for _, span := range details.CompletedSpans {
+		completed[string(span.Key)] = struct{}{}
+	}
+	var spans []roachpb.Span
+	for _, span := range desc.Spans {
+		if _, ok := completed[string(span.Key)]; !ok {
+			spans = append(spans, span)
+		}
+	}
+
+	progressLogger := jobProgressLogger{
+		jobLogger:   jobLogger,
+		totalChunks: len(desc.Spans),
+		priorChunks: len(desc.Spans) - len(spans),
+		checkpoint: func() (interface{}, error) {
+			mu.Lock()
+			checkpointDesc := desc
+			checkpointDesc.Files = mu.files
+			checkpointDesc.DataSize = mu.dataSize
+			checkpointDesc.EntryCounts = mu.entryCounts
+			completedSpans := mu.completedSpans
+			descBuf, err := checkpointDesc.Marshal()
+			mu.Unlock()
+			if err != nil {
+				return nil, err
+			}
+			return sql.BackupJobDetails{
+				URI:              details.URI,
+				BackupDescriptor: descBuf,
+				CompletedSpans:   completedSpans,
+				Encryption:       details.Encryption,
+			}, nil
+		},
+	}
 
 	// We're already limiting these on the server-side, but sending all the
 	// Export requests at once would fill up distsender/grpc/something and cause
@@ -372,12 +789,23 @@ func Backup(
 	// TODO(dan): Make this limiting per node.
 	//
 	// TODO(dan): See if there's some better solution than rate-limiting #14798.
-	maxConcurrentExports := clusterNodeCount(p.ExecCfg().Gossip) * storageccl.ParallelRequestsLimit
+	maxConcurrentExports := clusterNodeCount(execCfg.Gossip) * storageccl.ParallelRequestsLimit
 	exportsSem := make(chan struct{}, maxConcurrentExports)
 
-	header := roachpb.Header{Timestamp: endTime}
+	mvccFilter := roachpb.MVCCFilter_Latest
+	if desc.RevisionHistory {
+		mvccFilter = roachpb.MVCCFilter_All
+	}
+
+	header := roachpb.Header{Timestamp: desc.EndTime}
 	g, gCtx := errgroup.WithContext(ctx)
+	var statusErr error
 	for i := range spans {
+		// Honor PAUSE JOB and CANCEL JOB between requests. In-flight requests
+		// are allowed to finish.
+		if statusErr = jobLogger.CheckStatus(ctx); statusErr != nil {
+			break
+		}
 		select {
 		case exportsSem <- struct{}{}:
 		case <-ctx.Done():
@@ -389,9 +817,11 @@ func Backup(
 			defer func() { <-exportsSem }()
 
 			req := &roachpb.ExportRequest{
-				Span:      span,
-				Storage:   storageConf,
-				StartTime: startTime,
+				Span:       span,
+				Storage:    storageConf,
+				StartTime:  desc.StartTime,
+				MVCCFilter: mvccFilter,
+				Encryption: details.Encryption,
 			}
 			res, pErr := client.SendWrappedWith(gCtx, db.GetSender(), header, req)
 			if pErr != nil {
@@ -400,12 +830,15 @@ func Backup(
 			mu.Lock()
 			for _, file := range res.(*roachpb.ExportResponse).Files {
 				mu.files = append(mu.files, BackupDescriptor_File{
-					Span:   file.Span,
-					Path:   file.Path,
-					Sha512: file.Sha512,
+					Span:        file.Span,
+					Path:        file.Path,
+					Sha512:      file.Sha512,
+					EntryCounts: file.Exported,
 				})
 				mu.dataSize += file.DataSize
+				mu.entryCounts.Add(file.Exported)
 			}
+			mu.completedSpans = append(mu.completedSpans, span)
 			mu.Unlock()
 			if err := progressLogger.chunkFinished(ctx); err != nil {
 				// Errors while updating progress are not important enough to merit
@@ -421,21 +854,28 @@ func Backup(
 		return BackupDescriptor{}, errors.Wrapf(err, "exporting %d ranges", len(spans))
 	}
 	files, dataSize := mu.files, mu.dataSize // No more concurrency, so this is safe.
-
-	desc := BackupDescriptor{
-		StartTime:   startTime,
-		EndTime:     endTime,
-		Descriptors: sqlDescs,
-		Spans:       spans,
-		Files:       files,
-		DataSize:    dataSize,
+	if statusErr != nil {
+		if sql.IsJobCanceledError(statusErr) {
+			// Without a backup descriptor, the exported files are unusable.
+			deleteExportedFiles(ctx, storageConf, files)
+		}
+		return BackupDescriptor{}, statusErr
 	}
+
+	desc.Files = files
+	desc.DataSize = dataSize
+	desc.EntryCounts = mu.entryCounts
 	sort.Sort(backupFileDescriptors(desc.Files))
 
 	descBuf, err := desc.Marshal()
 	if err != nil {
 		return BackupDescriptor{}, err
 	}
+	if details.Encryption != nil {
+		if descBuf, err = storageccl.EncryptFile(descBuf, details.Encryption.Key); err != nil {
+			return BackupDescriptor{}, err
+		}
+	}
 
 	exportStore, err := storageccl.MakeExportStorage(ctx, storageConf)
 	if err != nil {
@@ -448,6 +888,24 @@ func Backup(
 	return desc, nil
 }
 
+// deleteExportedFiles makes a best-effort attempt at removing the files
+// exported by a canceled backup.
+func deleteExportedFiles(
+	ctx context.Context, storageConf roachpb.ExportStorage, files []BackupDescriptor_File,
+) {
+	exportStore, err := storageccl.MakeExportStorage(ctx, storageConf)
+	if err != nil {
+		log.Warningf(ctx, "unable to remove files exported by canceled backup: %+v", err)
+		return
+	}
+	defer exportStore.Close()
+	for _, file := range files {
+		if err := exportStore.Delete(ctx, file.Path); err != nil {
+			log.Warningf(ctx, "unable to remove %s exported by canceled backup: %+v", file.Path, err)
+		}
+	}
+}
+
 func backupPlanHook(
 	baseCtx context.Context, stmt parser.Statement, p sql.PlanHookState,
 ) (func() ([]parser.Datums, error), sql.ResultColumns, error) {
@@ -485,11 +943,14 @@ func backupPlanHook(
 		to := toFn()
 		incrementalFrom := incrementalFromFn()
 
-		var startTime hlc.Timestamp
+		encryption, err := backupEncryption(ctx, backup.Options, to, incrementalFrom)
+		if err != nil {
+			return nil, err
+		}
+
+		var prevBackups []BackupDescriptor
 		if backup.IncrementalFrom != nil {
-			var err error
-			startTime, err = ValidatePreviousBackups(ctx, incrementalFrom)
-			if err != nil {
+			if prevBackups, err = loadBackupDescs(ctx, incrementalFrom, encryption); err != nil {
 				return nil, err
 			}
 		}
@@ -504,17 +965,17 @@ func backupPlanHook(
 		if err != nil {
 			return nil, err
 		}
-		jobLogger := sql.NewJobLogger(p.ExecCfg().DB, p.LeaseMgr(), sql.JobRecord{
+		jobLogger := p.ExecCfg().JobRegistry.NewJobLogger(sql.JobRecord{
 			Description: description,
 			Username:    p.User(),
-			Details:     sql.BackupJobDetails{},
 		})
 		desc, err := Backup(ctx,
 			p,
 			to,
 			backup.Targets,
-			startTime, endTime,
+			prevBackups, endTime,
 			backup.Options,
+			encryption,
 			&jobLogger,
 		)
 		if err != nil {
@@ -540,6 +1001,18 @@ func backupPlanHook(
 	return fn, header, nil
 }
 
+// resumeBackup continues a backup whose node died from its last checkpoint.
+func resumeBackup(ctx context.Context, execCfg *sql.ExecutorConfig, jobLogger *sql.JobLogger) error {
+	if details := jobLogger.Job.Details.(sql.BackupJobDetails); details.Encryption != nil {
+		// The encryption key is not persisted with the job.
+		return errors.Errorf("cannot resume encrypted backup job %d: run the BACKUP again",
+			*jobLogger.JobID())
+	}
+	_, err := backup(ctx, execCfg, jobLogger)
+	return err
+}
+
 func init() {
 	sql.AddPlanHook(backupPlanHook)
+	sql.RegisterJobResumer(sql.JobTypeBackup, resumeBackup)
 }
@@ -33,10 +33,13 @@ import (
 	"golang.org/x/net/context"
 
 	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/ccl/storageccl"
+	"github.com/cockroachdb/cockroach/pkg/config"
 	"github.com/cockroachdb/cockroach/pkg/internal/client"
 	"github.com/cockroachdb/cockroach/pkg/keys"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/security"
+	"github.com/cockroachdb/cockroach/pkg/settings"
 	"github.com/cockroachdb/cockroach/pkg/sql"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
 	"github.com/cockroachdb/cockroach/pkg/storage"
@@ -46,6 +49,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/util"
 	"github.com/cockroachdb/cockroach/pkg/util/encoding"
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
 	"github.com/cockroachdb/cockroach/pkg/util/randutil"
 	"github.com/cockroachdb/cockroach/pkg/util/stop"
 )
@@ -493,6 +497,162 @@ func TestBackupRestoreSystemJobs(t *testing.T) {
 	}
 }
 
+func TestRestoreControlJob(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer sql.TestingSetJobStatusPollInterval(time.Millisecond)()
+	defer sql.TestingSetJobAdoptInterval(10 * time.Millisecond)()
+
+	// Import responses block on allowResponse, which gives the test a chance
+	// to pause, cancel and orphan the restore while it is in progress.
+	allowResponse := make(chan struct{})
+	close(allowResponse)
+	params := base.TestClusterArgs{}
+	params.ServerArgs.Knobs.Store = &storage.StoreTestingKnobs{
+		TestingResponseFilter: func(ba roachpb.BatchRequest, br *roachpb.BatchResponse) *roachpb.Error {
+			for _, res := range br.Responses {
+				if res.Import != nil {
+					<-allowResponse
+					break
+				}
+			}
+			return nil
+		},
+	}
+
+	const numAccounts = 1000
+
+	ctx, dir, tc, sqlDB, cleanupFn := backupRestoreTestSetupWithParams(t, multiNode, numAccounts, params)
+	defer cleanupFn()
+	kvDB := tc.Server(0).KVClient().(*client.DB)
+
+	sqlDB.Exec(`BACKUP DATABASE bench TO $1`, dir)
+
+	startRestore := func(intoDB string) (<-chan error, int64) {
+		sqlDB.Exec(fmt.Sprintf(`CREATE DATABASE %s`, intoDB))
+		allowResponse = make(chan struct{})
+		done := make(chan error, 1)
+		go func() {
+			_, err := sqlDB.DB.Exec(fmt.Sprintf(
+				`RESTORE bench.* FROM $1 WITH OPTIONS ('into_db'='%s')`, intoDB,
+			), dir)
+			done <- err
+		}()
+		var jobID int64
+		testutils.SucceedsSoon(t, func() error {
+			return sqlDB.DB.QueryRow(`SELECT id FROM crdb_internal.jobs
+				WHERE type = 'RESTORE' AND status = 'running' AND description LIKE $1`,
+				"%"+intoDB+"%",
+			).Scan(&jobID)
+		})
+		return done, jobID
+	}
+	jobStatus := func(jobID int64) sql.JobStatus {
+		var status string
+		sqlDB.QueryRow(`SELECT status FROM crdb_internal.jobs WHERE id = $1`, jobID).Scan(&status)
+		return sql.JobStatus(status)
+	}
+
+	t.Run("pause", func(t *testing.T) {
+		done, jobID := startRestore("pause")
+		sqlDB.Exec(`PAUSE JOB $1`, jobID)
+		close(allowResponse)
+
+		// The job stops at its next checkpoint, so it can't finish while paused.
+		select {
+		case err := <-done:
+			t.Fatalf("paused restore finished: %v", err)
+		case <-time.After(100 * time.Millisecond):
+		}
+		if e, a := sql.JobStatusPaused, jobStatus(jobID); e != a {
+			t.Fatalf("expected status %s, got %s", e, a)
+		}
+
+		sqlDB.Exec(`RESUME JOB $1`, jobID)
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+		if e, a := sql.JobStatusSucceeded, jobStatus(jobID); e != a {
+			t.Fatalf("expected status %s, got %s", e, a)
+		}
+		var count int
+		sqlDB.QueryRow(`SELECT COUNT(*) FROM pause.bank`).Scan(&count)
+		if count != numAccounts {
+			t.Fatalf("expected %d rows, got %d", numAccounts, count)
+		}
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		done, jobID := startRestore("cancel")
+		sqlDB.Exec(`CANCEL JOB $1`, jobID)
+		close(allowResponse)
+
+		if err := <-done; !testutils.IsError(err, "job canceled") {
+			t.Fatalf("expected 'job canceled' error, but got %v", err)
+		}
+		if e, a := sql.JobStatusCanceled, jobStatus(jobID); e != a {
+			t.Fatalf("expected status %s, got %s", e, a)
+		}
+
+		// The restored table was never made public, and the data that was
+		// imported before the job was canceled has been cleared.
+		var tableID int64
+		sqlDB.QueryRow(
+			`SELECT descriptor_ids[1] FROM crdb_internal.jobs WHERE id = $1`, jobID,
+		).Scan(&tableID)
+		if _, err := sqlDB.DB.Exec(`SELECT * FROM cancel.bank`); !testutils.IsError(
+			err, "does not exist",
+		) {
+			t.Fatalf("expected 'does not exist' error, but got %v", err)
+		}
+		tablePrefix := roachpb.Key(keys.MakeTablePrefix(uint32(tableID)))
+		kvs, err := kvDB.Scan(ctx, tablePrefix, tablePrefix.PrefixEnd(), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(kvs) != 0 {
+			t.Fatalf("expected data of canceled restore to be cleared, found %d keys", len(kvs))
+		}
+	})
+
+	t.Run("adopt", func(t *testing.T) {
+		done, jobID := startRestore("adopt")
+
+		// Pretend that the node running the restore has restarted since it
+		// created the job, so that another node adopts the job and resumes it.
+		var payloadBytes []byte
+		sqlDB.QueryRow(`SELECT payload FROM system.jobs WHERE id = $1`, jobID).Scan(&payloadBytes)
+		var payload sql.JobPayload
+		if err := payload.Unmarshal(payloadBytes); err != nil {
+			t.Fatal(err)
+		}
+		if payload.Lease == nil {
+			t.Fatal("expected restore job to be leased")
+		}
+		payload.Lease.Epoch--
+		payloadBytes, err := payload.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sqlDB.Exec(`UPDATE system.jobs SET payload = $1 WHERE id = $2`, payloadBytes, jobID)
+		close(allowResponse)
+
+		if err := <-done; !testutils.IsError(err, "job lease lost") {
+			t.Fatalf("expected 'job lease lost' error, but got %v", err)
+		}
+		testutils.SucceedsSoon(t, func() error {
+			if e, a := sql.JobStatusSucceeded, jobStatus(jobID); e != a {
+				return errors.Errorf("expected status %s, got %s", e, a)
+			}
+			return nil
+		})
+		var count int
+		sqlDB.QueryRow(`SELECT COUNT(*) FROM adopt.bank`).Scan(&count)
+		if count != numAccounts {
+			t.Fatalf("expected %d rows, got %d", numAccounts, count)
+		}
+	})
+}
+
 func TestBackupRestoreInterleaved(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	const numAccounts = 10
@@ -1084,6 +1244,250 @@ func TestBackupAsOfSystemTime(t *testing.T) {
 	}
 }
 
+func TestBackupRestoreFullCluster(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	const numAccounts = 10
+
+	_, dir, _, sqlDB, cleanupFn := backupRestoreTestSetup(t, singleNode, numAccounts)
+	defer cleanupFn()
+
+	settingName, _, cleanupSettings := settings.TestingAddTestVars()
+	defer cleanupSettings()
+
+	sqlDB.Exec(`CREATE USER testuser`)
+	sqlDB.Exec(`GRANT SELECT ON bench.bank TO testuser`)
+	sqlDB.Exec(fmt.Sprintf(`SET CLUSTER SETTING %s = 'backed-up'`, settingName))
+
+	var bankID int64
+	sqlDB.QueryRow(`SELECT id FROM system.namespace WHERE name = 'bank'`).Scan(&bankID)
+	zone := config.DefaultZoneConfig()
+	zone.RangeMaxBytes = 1 << 20
+	zoneBytes, err := protoutil.Marshal(&zone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.Exec(`UPSERT INTO system.zones VALUES ($1, $2)`, bankID, zoneBytes)
+
+	full := filepath.Join(dir, "full")
+	sqlDB.Exec(`BACKUP TO $1`, full)
+	sqlDB.Exec(`INSERT INTO bench.bank VALUES (-1, -1, 'new')`)
+	inc := filepath.Join(dir, "inc")
+	sqlDB.Exec(`BACKUP TO $1 INCREMENTAL FROM $2`, inc, full)
+
+	t.Run("restore", func(t *testing.T) {
+		tc := testcluster.StartTestCluster(t, singleNode, base.TestClusterArgs{})
+		defer tc.Stopper().Stop()
+		sqlDBRestore := backupSQLRunner(t, tc)
+
+		sqlDBRestore.Exec(`RESTORE FROM $1, $2`, full, inc)
+
+		var rowCount int64
+		sqlDBRestore.QueryRow(`SELECT COUNT(*) FROM bench.bank`).Scan(&rowCount)
+		if rowCount != numAccounts+1 {
+			t.Fatalf("expected %d rows but found %d", numAccounts+1, rowCount)
+		}
+
+		sqlDBRestore.CheckQueryResults(`SELECT username FROM system.users`, [][]string{{"testuser"}})
+		sqlDBRestore.CheckQueryResults(
+			fmt.Sprintf(`SELECT value FROM system.settings WHERE name = '%s'`, settingName),
+			[][]string{{"backed-up"}},
+		)
+		if grants := sqlDBRestore.QueryStr(`SHOW GRANTS ON bench.bank FOR testuser`); len(grants) != 1 {
+			t.Fatalf("expected the grant to testuser to be restored, got %v", grants)
+		}
+
+		var restoredBankID int64
+		sqlDBRestore.QueryRow(
+			`SELECT id FROM system.namespace WHERE name = 'bank'`,
+		).Scan(&restoredBankID)
+		var restoredZoneBytes []byte
+		sqlDBRestore.QueryRow(
+			`SELECT config FROM system.zones WHERE id = $1`, restoredBankID,
+		).Scan(&restoredZoneBytes)
+		var restoredZone config.ZoneConfig
+		if err := restoredZone.Unmarshal(restoredZoneBytes); err != nil {
+			t.Fatal(err)
+		}
+		if restoredZone.RangeMaxBytes != zone.RangeMaxBytes {
+			t.Fatalf("expected zone config %+v got %+v", zone, restoredZone)
+		}
+
+		sqlDBRestore.CheckQueryResults(
+			fmt.Sprintf(`SELECT COUNT(*) FROM system.namespace WHERE name = '%s'`, restoreTempSystemDB),
+			[][]string{{"0"}},
+		)
+
+		if _, err := sqlDBRestore.DB.Exec(`RESTORE FROM $1`, full); !testutils.IsError(
+			err, "requires an empty cluster",
+		) {
+			t.Fatalf("expected non-empty cluster error got: %+v", err)
+		}
+	})
+
+	t.Run("leftover temp system database", func(t *testing.T) {
+		tc := testcluster.StartTestCluster(t, singleNode, base.TestClusterArgs{})
+		defer tc.Stopper().Stop()
+		sqlDBRestore := backupSQLRunner(t, tc)
+
+		// Stand in for the temporary system database of a restore that failed
+		// after creating it.
+		sqlDBRestore.Exec(fmt.Sprintf(`CREATE DATABASE %s`, restoreTempSystemDB))
+		sqlDBRestore.Exec(fmt.Sprintf(`CREATE TABLE %s.users (username STRING PRIMARY KEY)`,
+			restoreTempSystemDB))
+
+		sqlDBRestore.Exec(`RESTORE FROM $1`, full)
+		sqlDBRestore.CheckQueryResults(`SELECT username FROM system.users`, [][]string{{"testuser"}})
+		sqlDBRestore.CheckQueryResults(
+			fmt.Sprintf(`SELECT COUNT(*) FROM system.namespace WHERE name = '%s'`, restoreTempSystemDB),
+			[][]string{{"0"}},
+		)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tables := filepath.Join(dir, "tables")
+		sqlDB.Exec(`BACKUP bench.bank TO $1`, tables)
+		if _, err := sqlDB.DB.Exec(`RESTORE FROM $1`, tables); !testutils.IsError(
+			err, "not a full cluster backup",
+		) {
+			t.Fatalf("expected not a full cluster backup error got: %+v", err)
+		}
+		if _, err := sqlDB.DB.Exec(
+			`BACKUP TO $1 INCREMENTAL FROM $2`, filepath.Join(dir, "mixed"), tables,
+		); !testutils.IsError(err, "only be incremental from other full cluster backups") {
+			t.Fatalf("expected incremental coverage mismatch error got: %+v", err)
+		}
+	})
+}
+
+func TestShowBackup(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	const numAccounts = 11
+
+	_, dir, _, sqlDB, cleanupFn := backupRestoreTestSetup(t, singleNode, numAccounts)
+	defer cleanupFn()
+
+	full, inc, inc2 := filepath.Join(dir, "full"), filepath.Join(dir, "inc"), filepath.Join(dir, "inc2")
+	sqlDB.Exec(`BACKUP TABLE bench.bank TO $1`, full)
+	sqlDB.Exec(`INSERT INTO bench.bank VALUES (-1, -1, 'new')`)
+	sqlDB.Exec(`BACKUP TABLE bench.bank TO $1 INCREMENTAL FROM $2`, inc, full)
+	sqlDB.Exec(`BACKUP TABLE bench.bank TO $1 INCREMENTAL FROM $2, $3`, inc2, full, inc)
+
+	var database, table string
+	var startTime, endTime gosql.NullString
+	var size, rows, files int64
+	sqlDB.QueryRow(`SHOW BACKUP $1`, full).Scan(
+		&database, &table, &startTime, &endTime, &size, &rows, &files,
+	)
+	if database != "bench" || table != "bank" {
+		t.Fatalf("expected bench.bank got %s.%s", database, table)
+	}
+	if startTime.Valid || !endTime.Valid {
+		t.Fatalf("expected only an end time for a full backup got [%v,%v)", startTime, endTime)
+	}
+	if rows != numAccounts || files < 1 || size <= 0 {
+		t.Fatalf("expected %d rows in at least one non-empty file got %d rows, %d files, %d bytes",
+			numAccounts, rows, files, size)
+	}
+
+	sqlDB.QueryRow(`SHOW BACKUP $1 INCREMENTAL FROM $2`, inc, full).Scan(
+		&database, &table, &startTime, &endTime, &size, &rows, &files,
+	)
+	if !startTime.Valid || !endTime.Valid {
+		t.Fatalf("expected a start and end time for an incremental backup got [%v,%v)", startTime, endTime)
+	}
+	if rows != 1 {
+		t.Fatalf("expected 1 row in incremental backup got %d", rows)
+	}
+
+	if _, err := sqlDB.DB.Exec(
+		`SHOW BACKUP $1 INCREMENTAL FROM $2`, inc2, full,
+	); !testutils.IsError(err, "no backup covers time") {
+		t.Fatalf("expected incomplete chain error got: %+v", err)
+	}
+}
+
+func TestBackupRestoreRevisionHistory(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	const numAccounts = 10
+	_, dir, _, sqlDB, cleanupFn := backupRestoreTestSetup(t, singleNode, numAccounts)
+	defer cleanupFn()
+
+	type snapshot struct {
+		ts   string
+		rows [][]string
+	}
+	var snapshots []snapshot
+	takeSnapshot := func() {
+		var ts string
+		sqlDB.QueryRow(`SELECT cluster_logical_timestamp()`).Scan(&ts)
+		snapshots = append(snapshots, snapshot{
+			ts:   ts,
+			rows: sqlDB.QueryStr(`SELECT * FROM bench.bank ORDER BY id`),
+		})
+	}
+
+	takeSnapshot()
+	sqlDB.Exec(`UPDATE bench.bank SET balance = balance + 1`)
+	takeSnapshot()
+	sqlDB.Exec(`DELETE FROM bench.bank WHERE id % 2 = 0`)
+	takeSnapshot()
+	sqlDB.Exec(`ALTER TABLE bench.bank ADD COLUMN extra INT DEFAULT 7`)
+	takeSnapshot()
+
+	full, inc := filepath.Join(dir, "full"), filepath.Join(dir, "inc")
+	sqlDB.Exec(`BACKUP DATABASE bench TO $1 WITH revision_history`, full)
+
+	sqlDB.Exec(`INSERT INTO bench.bank VALUES (100, 100, 'new', 100)`)
+	takeSnapshot()
+	sqlDB.Exec(`ALTER TABLE bench.bank DROP COLUMN payload`)
+	takeSnapshot()
+	sqlDB.Exec(`BACKUP DATABASE bench TO $1 INCREMENTAL FROM $2 WITH revision_history`, inc, full)
+
+	// A backup without revision history can only be restored as of its end
+	// time.
+	latest := filepath.Join(dir, "latest")
+	var latestTS string
+	sqlDB.QueryRow(`SELECT cluster_logical_timestamp()`).Scan(&latestTS)
+	sqlDB.Exec(fmt.Sprintf(`BACKUP DATABASE bench TO $1 AS OF SYSTEM TIME %s`, latestTS), latest)
+
+	for i, s := range snapshots {
+		sqlDB.Exec(`DROP TABLE IF EXISTS bench.bank`)
+		sqlDB.Exec(fmt.Sprintf(`RESTORE bench.bank FROM $1, $2 AS OF SYSTEM TIME %s`, s.ts), full, inc)
+		if actual := sqlDB.QueryStr(`SELECT * FROM bench.bank ORDER BY id`); !reflect.DeepEqual(actual, s.rows) {
+			t.Errorf("%d: restored as of %s expected %v got %v", i, s.ts, s.rows, actual)
+		}
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		sqlDB.Exec(`DROP TABLE bench.bank`)
+		sqlDB.Exec(fmt.Sprintf(`RESTORE bench.bank FROM $1 AS OF SYSTEM TIME %s`, latestTS), latest)
+
+		sqlDB.Exec(`DROP TABLE bench.bank`)
+		_, err := sqlDB.DB.Exec(fmt.Sprintf(
+			`RESTORE bench.bank FROM $1 AS OF SYSTEM TIME %s`, snapshots[0].ts), latest)
+		if !testutils.IsError(err, "revision_history") {
+			t.Errorf("expected revision_history error got: %+v", err)
+		}
+
+		var later string
+		sqlDB.QueryRow(`SELECT cluster_logical_timestamp()`).Scan(&later)
+		_, err = sqlDB.DB.Exec(fmt.Sprintf(
+			`RESTORE bench.bank FROM $1, $2 AS OF SYSTEM TIME %s`, later), full, inc)
+		if !testutils.IsError(err, "backups only cover up to") {
+			t.Errorf("expected 'backups only cover up to' error got: %+v", err)
+		}
+
+		_, err = sqlDB.DB.Exec(`BACKUP DATABASE bench TO $1 WITH revision_history = 'yes'`,
+			filepath.Join(dir, "bad"))
+		if !testutils.IsError(err, "does not take a value") {
+			t.Errorf("expected 'does not take a value' error got: %+v", err)
+		}
+	})
+}
+
 func TestBackupRestoreChecksum(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 
@@ -1125,6 +1529,12 @@ func TestBackupRestoreChecksum(t *testing.T) {
 		t.Fatalf("%+v", err)
 	}
 
+	// The corruption is found without restoring anything with verify_only.
+	_, err = sqlDB.DB.Exec(`RESTORE bench.* FROM $1 WITH verify_only`, dir)
+	if !testutils.IsError(err, "checksum mismatch") {
+		t.Fatalf("expected 'checksum mismatch' error got: %+v", err)
+	}
+
 	sqlDB.Exec(`DROP TABLE bench.bank`)
 	_, err = sqlDB.DB.Exec(`RESTORE bench.* FROM $1`, dir)
 	if !testutils.IsError(err, "checksum mismatch") {
@@ -1132,6 +1542,100 @@ func TestBackupRestoreChecksum(t *testing.T) {
 	}
 }
 
+func TestBackupRestoreEncrypted(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	const numAccounts = 100
+	_, dir, _, sqlDB, cleanupFn := backupRestoreTestSetup(t, singleNode, numAccounts)
+	defer cleanupFn()
+
+	// The helper helpfully prefixes it, but we're going to do direct file IO.
+	rawDir := strings.TrimPrefix(dir, "nodelocal://")
+	full, inc := filepath.Join(dir, "full"), filepath.Join(dir, "inc")
+
+	sqlDB.Exec(`BACKUP DATABASE bench TO $1 WITH encryption_passphrase = 'abc'`, full)
+	sqlDB.Exec(`INSERT INTO bench.bank VALUES (-1, -1, 'new')`)
+	sqlDB.Exec(`BACKUP DATABASE bench TO $1 INCREMENTAL FROM $2 WITH encryption_passphrase = 'abc'`,
+		inc, full)
+
+	var backupDesc BackupDescriptor
+	{
+		backupDescBytes, err := ioutil.ReadFile(filepath.Join(rawDir, "full", BackupDescriptorName))
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		if !storageccl.AppearsEncrypted(backupDescBytes) {
+			t.Fatal("expected the backup descriptor to be encrypted")
+		}
+		info, err := readEncryptionInfo(context.Background(), full)
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		key := storageccl.GenerateKey([]byte("abc"), info.Salt)
+		if backupDescBytes, err = storageccl.DecryptFile(backupDescBytes, key); err != nil {
+			t.Fatalf("%+v", err)
+		}
+		if err := backupDesc.Unmarshal(backupDescBytes); err != nil {
+			t.Fatalf("%+v", err)
+		}
+	}
+	for _, file := range backupDesc.Files {
+		data, err := ioutil.ReadFile(filepath.Join(rawDir, "full", file.Path))
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		if !storageccl.AppearsEncrypted(data) {
+			t.Fatalf("expected %s to be encrypted", file.Path)
+		}
+	}
+
+	var description string
+	sqlDB.QueryRow(
+		`SELECT description FROM crdb_internal.jobs WHERE type = 'BACKUP' ORDER BY created LIMIT 1`,
+	).Scan(&description)
+	if strings.Contains(description, "abc") || !strings.Contains(description, "redacted") {
+		t.Fatalf("expected passphrase to be redacted in job description: %s", description)
+	}
+
+	sqlDB.Exec(`RESTORE bench.* FROM $1, $2 WITH encryption_passphrase = 'abc', verify_only`, full, inc)
+
+	sqlDB.Exec(`CREATE DATABASE bench2`)
+	sqlDB.Exec(`RESTORE bench.* FROM $1, $2 WITH encryption_passphrase = 'abc', into_db = 'bench2'`,
+		full, inc)
+	sqlDB.CheckQueryResults(
+		`SELECT COUNT(*), SUM(balance), SUM(LENGTH(payload)) FROM bench2.bank`,
+		sqlDB.QueryStr(`SELECT COUNT(*), SUM(balance), SUM(LENGTH(payload)) FROM bench.bank`),
+	)
+
+	for _, tc := range []struct {
+		name  string
+		query string
+		err   string
+	}{
+		{"no passphrase", `RESTORE bench.* FROM $1, $2 WITH verify_only`,
+			"encryption_passphrase\" option is required"},
+		{"wrong passphrase", `RESTORE bench.* FROM $1, $2 WITH encryption_passphrase = 'xyz', verify_only`,
+			"incorrect passphrase"},
+		{"incremental without passphrase",
+			`BACKUP DATABASE bench TO '` + filepath.Join(dir, "inc2") + `' INCREMENTAL FROM $1, $2`,
+			"encryption_passphrase\" option is required"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := sqlDB.DB.Exec(tc.query, full, inc); !testutils.IsError(err, tc.err) {
+				t.Fatalf("expected %q error got: %+v", tc.err, err)
+			}
+		})
+	}
+
+	// Backing up to the location of an existing backup fails without replacing
+	// its salt.
+	const overwrite = `BACKUP DATABASE bench TO $1 WITH encryption_passphrase = 'xyz'`
+	if _, err := sqlDB.DB.Exec(overwrite, full); !testutils.IsError(err, "already contains a BACKUP file") {
+		t.Fatalf("expected overwrite error got: %+v", err)
+	}
+	sqlDB.Exec(`RESTORE bench.* FROM $1, $2 WITH encryption_passphrase = 'abc', verify_only`, full, inc)
+}
+
 func TestTimestampMismatch(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	const numAccounts = 1
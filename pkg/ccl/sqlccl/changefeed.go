@@ -0,0 +1,561 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/pkg/ccl/LICENSE
+
+package sqlccl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/utilccl"
+	"github.com/cockroachdb/cockroach/pkg/internal/client"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/kv"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/privilege"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/util/encoding"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/retry"
+)
+
+const (
+	// changefeedOptCursor is the option that sets the timestamp after which a
+	// changefeed starts emitting changes. It accepts the same values as AS OF
+	// SYSTEM TIME, including the "resolved" timestamps emitted by a previous
+	// changefeed, which allows a changefeed to be resumed.
+	changefeedOptCursor = "cursor"
+)
+
+// changefeedRetryOptions controls how a changefeed is restarted (from its last
+// persisted highwater) after an error. The retry count is reset whenever the
+// changefeed makes progress.
+var changefeedRetryOptions = retry.Options{
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     10 * time.Second,
+	Multiplier:     2,
+	MaxRetries:     10,
+}
+
+// changefeedRowMessage is the JSON message emitted to the sink for each
+// changed row. Value is nil if the row was deleted.
+type changefeedRowMessage struct {
+	Table   string                 `json:"table"`
+	Key     []interface{}          `json:"key"`
+	Value   map[string]interface{} `json:"value"`
+	Updated string                 `json:"updated"`
+}
+
+// changefeedResolvedMessage is the JSON message emitted to the sink once every
+// change at or below Resolved has been emitted.
+type changefeedResolvedMessage struct {
+	Resolved string `json:"resolved"`
+}
+
+// changefeedTimestamp formats ts in the decimal format understood by AS OF
+// SYSTEM TIME and the cursor option.
+func changefeedTimestamp(ts hlc.Timestamp) string {
+	return fmt.Sprintf("%d.%010d", ts.WallTime, ts.Logical)
+}
+
+// changefeed streams the changes to a set of tables to a sink.
+//
+// The changes to each table's primary index are read with RangeFeeds and
+// buffered until the RangeFeed checkpoints guarantee that no more changes at
+// or below some timestamp will be seen. The buffered changes up to that
+// timestamp are then emitted in timestamp order, followed by a "resolved"
+// message. Once the sink has been flushed, the timestamp is persisted as the
+// job's highwater, from which the changefeed is restarted after an error.
+// Changes are thus delivered at least once: a change may be repeated (but not
+// lost) if the changefeed fails between emitting it and persisting the
+// highwater.
+//
+// The table descriptors are fixed when the changefeed is created or resumed,
+// so schema changes to the watched tables are not supported.
+type changefeed struct {
+	distSender *kv.DistSender
+	jobLogger  *sql.JobLogger
+	sink       changefeedSink
+	encoders   map[sqlbase.ID]*changefeedRowEncoder
+	spans      []roachpb.Span
+	details    sql.ChangefeedJobDetails
+}
+
+func makeChangefeed(
+	distSender *kv.DistSender,
+	jobLogger *sql.JobLogger,
+	sink changefeedSink,
+	tables []*sqlbase.TableDescriptor,
+	details sql.ChangefeedJobDetails,
+) (*changefeed, error) {
+	cf := &changefeed{
+		distSender: distSender,
+		jobLogger:  jobLogger,
+		sink:       sink,
+		encoders:   make(map[sqlbase.ID]*changefeedRowEncoder, len(tables)),
+		details:    details,
+	}
+	for _, desc := range tables {
+		e, err := makeChangefeedRowEncoder(desc)
+		if err != nil {
+			return nil, err
+		}
+		cf.encoders[desc.ID] = e
+		cf.spans = append(cf.spans, desc.PrimaryIndexSpan())
+	}
+	return cf, nil
+}
+
+// run runs the changefeed until the context is canceled or it encounters an
+// error it could not recover from by restarting from its highwater.
+func (cf *changefeed) run(ctx context.Context) error {
+	var err error
+	for r := retry.StartWithCtx(ctx, changefeedRetryOptions); r.Next(); {
+		highwater := cf.details.Highwater
+		err = cf.runOnce(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if sql.IsJobCanceledError(err) {
+			return err
+		}
+		if highwater.Less(cf.details.Highwater) {
+			r.Reset()
+		}
+		log.Warningf(ctx, "changefeed restarting from %s after error: %+v", cf.details.Highwater, err)
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// runOnce starts a RangeFeed for each table from the highwater and emits
+// changes as they are resolved. It only returns on error.
+func (cf *changefeed) runOnce(ctx context.Context) error {
+	eventCh := make(chan *roachpb.RangeFeedEvent, 512)
+	g, gCtx := errgroup.WithContext(ctx)
+	for _, span := range cf.spans {
+		span := span
+		g.Go(func() error {
+			return cf.distSender.RangeFeed(gCtx, span, cf.details.Highwater, eventCh)
+		})
+	}
+	g.Go(func() error {
+		return cf.consume(gCtx, eventCh)
+	})
+	return g.Wait()
+}
+
+func (cf *changefeed) consume(ctx context.Context, eventCh <-chan *roachpb.RangeFeedEvent) error {
+	frontier := makeSpanFrontier(cf.spans...)
+	var buffered []roachpb.KeyValue
+	for {
+		var event *roachpb.RangeFeedEvent
+		select {
+		case event = <-eventCh:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		switch t := event.GetValue().(type) {
+		case *roachpb.RangeFeedValue:
+			if !cf.details.Highwater.Less(t.Value.Timestamp) {
+				// Already emitted before the highwater was persisted.
+				continue
+			}
+			buffered = append(buffered, roachpb.KeyValue{Key: t.Key, Value: t.Value})
+		case *roachpb.RangeFeedCheckpoint:
+			if !frontier.Forward(t.Span, t.ResolvedTS) {
+				continue
+			}
+			resolved := frontier.Frontier()
+			if !cf.details.Highwater.Less(resolved) {
+				continue
+			}
+			var err error
+			if buffered, err = cf.emitResolved(ctx, buffered, resolved); err != nil {
+				return err
+			}
+		default:
+			return errors.Errorf("unexpected RangeFeedEvent: %v", event)
+		}
+	}
+}
+
+// emitResolved emits the buffered changes at or below resolved, in timestamp
+// order, followed by a resolved message, and then persists resolved as the
+// changefeed's highwater. The changes that are still unresolved are returned.
+func (cf *changefeed) emitResolved(
+	ctx context.Context, buffered []roachpb.KeyValue, resolved hlc.Timestamp,
+) ([]roachpb.KeyValue, error) {
+	sort.Slice(buffered, func(i, j int) bool {
+		if ts1, ts2 := buffered[i].Value.Timestamp, buffered[j].Value.Timestamp; ts1 != ts2 {
+			return ts1.Less(ts2)
+		}
+		return bytes.Compare(buffered[i].Key, buffered[j].Key) < 0
+	})
+	n := sort.Search(len(buffered), func(i int) bool {
+		return resolved.Less(buffered[i].Value.Timestamp)
+	})
+	for _, kv := range buffered[:n] {
+		_, tableID, err := keys.DecodeTablePrefix(kv.Key)
+		if err != nil {
+			return nil, err
+		}
+		e, ok := cf.encoders[sqlbase.ID(tableID)]
+		if !ok {
+			return nil, errors.Errorf("changefeed received key for unknown table %d", tableID)
+		}
+		msg, err := e.encode(ctx, kv)
+		if err != nil {
+			return nil, err
+		}
+		if msg == nil {
+			continue
+		}
+		if err := cf.sink.EmitMessage(ctx, msg); err != nil {
+			return nil, err
+		}
+	}
+	msg, err := json.Marshal(changefeedResolvedMessage{Resolved: changefeedTimestamp(resolved)})
+	if err != nil {
+		return nil, err
+	}
+	if err := cf.sink.EmitMessage(ctx, msg); err != nil {
+		return nil, err
+	}
+	if err := cf.sink.Flush(ctx); err != nil {
+		return nil, err
+	}
+
+	cf.details.Highwater = resolved
+	if err := cf.jobLogger.SetDetails(ctx, cf.details); err != nil {
+		return nil, err
+	}
+	// The highwater is persisted, so this is a good time to honor PAUSE JOB
+	// and CANCEL JOB.
+	if err := cf.jobLogger.CheckStatus(ctx); err != nil {
+		return nil, err
+	}
+	return append(buffered[:0], buffered[n:]...), nil
+}
+
+// changefeedRowEncoder encodes the primary index key/values of a table as
+// changefeedRowMessages. The table must have a single column family, so that
+// every row is stored as a single key/value.
+type changefeedRowEncoder struct {
+	desc    *sqlbase.TableDescriptor
+	rf      sqlbase.RowFetcher
+	keyVals []sqlbase.EncDatum
+	keyDirs []encoding.Direction
+	alloc   sqlbase.DatumAlloc
+}
+
+func makeChangefeedRowEncoder(desc *sqlbase.TableDescriptor) (*changefeedRowEncoder, error) {
+	if len(desc.Families) != 1 {
+		return nil, errors.Errorf(
+			"CHANGEFEED does not support tables with multiple column families: %s", desc.Name)
+	}
+	e := &changefeedRowEncoder{desc: desc}
+
+	colIdxMap := make(map[sqlbase.ColumnID]int, len(desc.Columns))
+	valNeededForCol := make([]bool, len(desc.Columns))
+	for i, col := range desc.Columns {
+		colIdxMap[col.ID] = i
+		valNeededForCol[i] = true
+	}
+	if err := e.rf.Init(
+		desc, colIdxMap, &desc.PrimaryIndex, false /* reverse */, false, /* isSecondaryIndex */
+		desc.Columns, valNeededForCol, false, /* returnRangeInfo */
+	); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if e.keyVals, err = sqlbase.MakeEncodedKeyVals(desc, desc.PrimaryIndex.ColumnIDs); err != nil {
+		return nil, err
+	}
+	e.keyDirs = make([]encoding.Direction, len(desc.PrimaryIndex.ColumnDirections))
+	for i, dir := range desc.PrimaryIndex.ColumnDirections {
+		if e.keyDirs[i], err = dir.ToEncodingDirection(); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// encode returns the JSON message for a changed primary index key/value. An
+// empty value is a deletion. Returns nil if the key does not belong to the
+// table's primary index (for example because it is a row of an interleaved
+// child table).
+func (e *changefeedRowEncoder) encode(ctx context.Context, kv roachpb.KeyValue) ([]byte, error) {
+	_, ok, err := sqlbase.DecodeIndexKey(
+		&e.alloc, e.desc, e.desc.PrimaryIndex.ID, e.keyVals, e.keyDirs, kv.Key)
+	if err != nil || !ok {
+		return nil, err
+	}
+	msg := changefeedRowMessage{
+		Table:   e.desc.Name,
+		Key:     make([]interface{}, len(e.keyVals)),
+		Updated: changefeedTimestamp(kv.Value.Timestamp),
+	}
+	for i := range e.keyVals {
+		if err := e.keyVals[i].EnsureDecoded(&e.alloc); err != nil {
+			return nil, err
+		}
+		msg.Key[i] = datumToJSON(e.keyVals[i].Datum)
+	}
+
+	if len(kv.Value.RawBytes) > 0 {
+		if err := e.rf.StartScanFromKVs(ctx, []roachpb.KeyValue{kv}); err != nil {
+			return nil, err
+		}
+		row, err := e.rf.NextRowDecoded(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if row == nil {
+			return nil, errors.Errorf("could not decode row from %s", kv.Key)
+		}
+		msg.Value = make(map[string]interface{}, len(row))
+		for i, col := range e.desc.Columns {
+			msg.Value[col.Name] = datumToJSON(row[i])
+		}
+	}
+	return json.Marshal(msg)
+}
+
+// datumToJSON returns a value that encodes d as JSON: the natural JSON type for
+// booleans, numbers and strings, and the datum's string representation for
+// every other type.
+func datumToJSON(d parser.Datum) interface{} {
+	if d == parser.DNull {
+		return nil
+	}
+	switch t := d.(type) {
+	case *parser.DBool:
+		return bool(*t)
+	case *parser.DInt:
+		return int64(*t)
+	case *parser.DFloat:
+		if f := float64(*t); !math.IsInf(f, 0) && !math.IsNaN(f) {
+			return f
+		}
+	case *parser.DString:
+		return string(*t)
+	}
+	return parser.AsStringWithFlags(d, parser.FmtBareStrings)
+}
+
+// changefeedTables resolves the targets of a changefeed to table descriptors,
+// as of the given timestamp, and checks that the user may read them.
+func changefeedTables(
+	ctx context.Context, p sql.PlanHookState, targets parser.TargetList, ts hlc.Timestamp,
+) ([]*sqlbase.TableDescriptor, error) {
+	if len(targets.Databases) > 0 {
+		return nil, errors.Errorf("CHANGEFEED cannot target databases, only tables")
+	}
+
+	var sqlDescs []sqlbase.Descriptor
+	txn := client.NewTxn(p.ExecCfg().DB)
+	opt := client.TxnExecOptions{AutoRetry: true, AutoCommit: true}
+	if err := txn.Exec(ctx, opt, func(ctx context.Context, txn *client.Txn, opt *client.TxnExecOptions) error {
+		var err error
+		sql.SetTxnTimestamps(txn, ts)
+		sqlDescs, err = allSQLDescriptors(ctx, txn)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	// TODO(dan): Plumb the session database down.
+	sessionDatabase := ""
+	sqlDescs, err := descriptorsMatchingTargets(sessionDatabase, sqlDescs, targets)
+	if err != nil {
+		return nil, err
+	}
+
+	var tables []*sqlbase.TableDescriptor
+	for _, desc := range sqlDescs {
+		if tableDesc := desc.GetTable(); tableDesc != nil {
+			if tableDesc.IsView() {
+				return nil, errors.Errorf("CHANGEFEED cannot target views: %s", tableDesc.Name)
+			}
+			if err := p.CheckPrivilege(tableDesc, privilege.SELECT); err != nil {
+				return nil, err
+			}
+			tables = append(tables, tableDesc)
+		}
+	}
+	if len(tables) == 0 {
+		return nil, errors.Errorf("no tables matched %s", parser.AsString(targets))
+	}
+	return tables, nil
+}
+
+func changefeedJobDescription(changefeed *parser.CreateChangefeed, sinkURI string) string {
+	c := parser.CreateChangefeed{
+		Targets: changefeed.Targets,
+		SinkURI: parser.NewDString(sinkURI),
+		Options: changefeed.Options,
+	}
+	return parser.AsString(&c)
+}
+
+func createChangefeedPlanHook(
+	baseCtx context.Context, stmt parser.Statement, p sql.PlanHookState,
+) (func() ([]parser.Datums, error), sql.ResultColumns, error) {
+	changefeedStmt, ok := stmt.(*parser.CreateChangefeed)
+	if !ok {
+		return nil, nil, nil
+	}
+	if err := utilccl.CheckEnterpriseEnabled("CHANGEFEED"); err != nil {
+		return nil, nil, err
+	}
+	if err := p.RequireSuperUser("CHANGEFEED"); err != nil {
+		return nil, nil, err
+	}
+
+	sinkFn, err := p.TypeAsString(&changefeedStmt.SinkURI)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header := sql.ResultColumns{
+		{Name: "job_id", Typ: parser.TypeInt},
+	}
+	fn := func() ([]parser.Datums, error) {
+		ctx := baseCtx
+		sinkURI := sinkFn()
+		execCfg := p.ExecCfg()
+
+		now := execCfg.Clock.Now()
+		highwater := now
+		if cursor, ok := changefeedStmt.Options.Get(changefeedOptCursor); ok {
+			asOf := parser.AsOfClause{Expr: parser.NewDString(cursor)}
+			var err error
+			if highwater, err = sql.EvalAsOfTimestamp(nil, asOf, now); err != nil {
+				return nil, err
+			}
+		}
+
+		tables, err := changefeedTables(ctx, p, changefeedStmt.Targets, now)
+		if err != nil {
+			return nil, err
+		}
+		sink, err := changefeedSinkFromURI(sinkURI)
+		if err != nil {
+			return nil, err
+		}
+
+		details := sql.ChangefeedJobDetails{SinkURI: sinkURI, Highwater: highwater}
+		var descriptorIDs sqlbase.IDs
+		for _, desc := range tables {
+			descriptorIDs = append(descriptorIDs, desc.ID)
+		}
+		jobLogger := execCfg.JobRegistry.NewJobLogger(sql.JobRecord{
+			Description:   changefeedJobDescription(changefeedStmt, sinkURI),
+			Username:      p.User(),
+			DescriptorIDs: descriptorIDs,
+			Details:       details,
+		})
+		cf, err := makeChangefeed(execCfg.DistSender, &jobLogger, sink, tables, details)
+		if err != nil {
+			_ = sink.Close()
+			return nil, err
+		}
+		if err := jobLogger.Created(ctx); err != nil {
+			_ = sink.Close()
+			return nil, err
+		}
+		if err := jobLogger.Started(ctx); err != nil {
+			_ = sink.Close()
+			jobLogger.Failed(ctx, err)
+			return nil, err
+		}
+
+		// The changefeed outlives the statement that created it, so it runs
+		// under the server's context until the server shuts down.
+		feedCtx := execCfg.Stopper.WithCancel(execCfg.AmbientCtx.AnnotateCtx(context.Background()))
+		jobID := *jobLogger.JobID()
+		if err := execCfg.Stopper.RunAsyncTask(feedCtx, func(ctx context.Context) {
+			defer func() {
+				if err := sink.Close(); err != nil {
+					log.Warningf(ctx, "closing changefeed %d sink: %s", jobID, err)
+				}
+			}()
+			if err := cf.run(ctx); err != nil && ctx.Err() == nil {
+				if sql.IsJobCanceledError(err) {
+					log.Infof(ctx, "changefeed %d canceled", jobID)
+				} else {
+					log.Errorf(ctx, "changefeed %d failed: %+v", jobID, err)
+				}
+				jobLogger.Failed(ctx, err)
+			}
+		}); err != nil {
+			_ = sink.Close()
+			jobLogger.Failed(ctx, err)
+			return nil, err
+		}
+
+		return []parser.Datums{{parser.NewDInt(parser.DInt(jobID))}}, nil
+	}
+	return fn, header, nil
+}
+
+// resumeChangefeed restarts a changefeed whose node died from its last
+// persisted highwater.
+func resumeChangefeed(
+	ctx context.Context, execCfg *sql.ExecutorConfig, jobLogger *sql.JobLogger,
+) error {
+	details := jobLogger.Job.Details.(sql.ChangefeedJobDetails)
+	var tables []*sqlbase.TableDescriptor
+	if err := execCfg.DB.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
+		tables = tables[:0]
+		for _, id := range jobLogger.Job.DescriptorIDs {
+			desc, err := sqlbase.GetTableDescFromID(ctx, txn, id)
+			if err != nil {
+				return err
+			}
+			tables = append(tables, desc)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	sink, err := changefeedSinkFromURI(details.SinkURI)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := sink.Close(); err != nil {
+			log.Warningf(ctx, "closing changefeed %d sink: %s", *jobLogger.JobID(), err)
+		}
+	}()
+	cf, err := makeChangefeed(execCfg.DistSender, jobLogger, sink, tables, details)
+	if err != nil {
+		return err
+	}
+	return cf.run(ctx)
+}
+
+func init() {
+	sql.AddPlanHook(createChangefeedPlanHook)
+	sql.RegisterJobResumer(sql.JobTypeChangefeed, resumeChangefeed)
+}
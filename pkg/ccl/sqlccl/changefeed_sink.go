@@ -0,0 +1,164 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/pkg/ccl/LICENSE
+
+package sqlccl
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/retry"
+)
+
+// changefeedSink is the destination of a changefeed's messages. Each message
+// is a single JSON object. Messages are only durable once Flush has returned
+// without error; a changefeed that fails before then re-emits them when it is
+// resumed, which makes delivery at-least-once.
+type changefeedSink interface {
+	// EmitMessage buffers a message for delivery to the sink.
+	EmitMessage(ctx context.Context, msg []byte) error
+	// Flush blocks until every message emitted so far has been durably
+	// delivered.
+	Flush(ctx context.Context) error
+	// Close releases the sink's resources. Messages that were not flushed may
+	// be lost.
+	Close() error
+}
+
+// changefeedSinkFromURI returns a changefeedSink for the given URI. Supported
+// schemes are "file" (messages are appended as newline-delimited JSON to the
+// file at the URI's path) and "http"/"https" (each flush POSTs the buffered
+// messages as newline-delimited JSON to the URI).
+func changefeedSinkFromURI(uri string) (changefeedSink, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "file":
+		if u.Path == "" {
+			return nil, errors.Errorf("%s sink requires a path: %s", u.Scheme, uri)
+		}
+		return makeFileSink(filepath.Join(u.Host, u.Path))
+	case "http", "https":
+		return &httpSink{uri: uri, client: http.DefaultClient}, nil
+	default:
+		return nil, errors.Errorf("unsupported sink: %s", u.Scheme)
+	}
+}
+
+// fileSink appends messages to a local file.
+type fileSink struct {
+	f   *os.File
+	buf bytes.Buffer
+}
+
+func makeFileSink(path string) (*fileSink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &fileSink{f: f}, nil
+}
+
+// EmitMessage implements the changefeedSink interface.
+func (s *fileSink) EmitMessage(_ context.Context, msg []byte) error {
+	s.buf.Write(msg)
+	s.buf.WriteByte('\n')
+	return nil
+}
+
+// Flush implements the changefeedSink interface.
+func (s *fileSink) Flush(_ context.Context) error {
+	if _, err := s.buf.WriteTo(s.f); err != nil {
+		return err
+	}
+	return s.f.Sync()
+}
+
+// Close implements the changefeedSink interface.
+func (s *fileSink) Close() error {
+	return s.f.Close()
+}
+
+// httpSink delivers messages to an HTTP webhook. Each flush POSTs the messages
+// buffered since the previous flush, retrying until the endpoint acknowledges
+// them with a 2xx status (or the context is canceled).
+type httpSink struct {
+	uri    string
+	client *http.Client
+	buf    bytes.Buffer
+}
+
+var httpSinkRetryOptions = retry.Options{
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     10 * time.Second,
+	Multiplier:     2,
+}
+
+// EmitMessage implements the changefeedSink interface.
+func (s *httpSink) EmitMessage(_ context.Context, msg []byte) error {
+	s.buf.Write(msg)
+	s.buf.WriteByte('\n')
+	return nil
+}
+
+// Flush implements the changefeedSink interface.
+func (s *httpSink) Flush(ctx context.Context) error {
+	if s.buf.Len() == 0 {
+		return nil
+	}
+	var err error
+	for r := retry.StartWithCtx(ctx, httpSinkRetryOptions); r.Next(); {
+		if err = s.post(ctx, s.buf.Bytes()); err == nil {
+			s.buf.Reset()
+			return nil
+		}
+		log.Warningf(ctx, "changefeed sink %s: %s", s.uri, err)
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
+func (s *httpSink) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequest("POST", s.uri, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	resp, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("POST returned %s: %s", resp.Status, msg)
+	}
+	return nil
+}
+
+// Close implements the changefeedSink interface.
+func (s *httpSink) Close() error {
+	return nil
+}
@@ -0,0 +1,268 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/pkg/ccl/LICENSE
+
+package sqlccl
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/testcluster"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+)
+
+func TestSpanFrontier(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	span := func(start, end string) roachpb.Span {
+		return roachpb.Span{Key: roachpb.Key(start), EndKey: roachpb.Key(end)}
+	}
+	ts := func(wallTime int64) hlc.Timestamp {
+		return hlc.Timestamp{WallTime: wallTime}
+	}
+
+	f := makeSpanFrontier(span("c", "e"), span("a", "b"))
+	steps := []struct {
+		span     roachpb.Span
+		ts       hlc.Timestamp
+		advanced bool
+		frontier hlc.Timestamp
+		expected string
+	}{
+		{span("a", "b"), ts(2), false, ts(0), `["a" "b")@0.000000002,0 ["c" "e")@0.000000000,0`},
+		{span("c", "d"), ts(3), false, ts(0), `["a" "b")@0.000000002,0 ["c" "d")@0.000000003,0 ["d" "e")@0.000000000,0`},
+		{span("d", "z"), ts(1), true, ts(1), `["a" "b")@0.000000002,0 ["c" "d")@0.000000003,0 ["d" "e")@0.000000001,0`},
+		// Moving a span backwards is a no-op.
+		{span("c", "e"), ts(0), false, ts(1), `["a" "b")@0.000000002,0 ["c" "d")@0.000000003,0 ["d" "e")@0.000000001,0`},
+		// Untracked spans are ignored.
+		{span("b", "c"), ts(5), false, ts(1), `["a" "b")@0.000000002,0 ["c" "d")@0.000000003,0 ["d" "e")@0.000000001,0`},
+		{span("a", "e"), ts(3), true, ts(3), `["a" "b")@0.000000003,0 ["c" "e")@0.000000003,0`},
+	}
+	for i, s := range steps {
+		if advanced := f.Forward(s.span, s.ts); advanced != s.advanced {
+			t.Errorf("%d: expected advanced=%t, got %t", i, s.advanced, advanced)
+		}
+		if frontier := f.Frontier(); frontier != s.frontier {
+			t.Errorf("%d: expected frontier %s, got %s", i, s.frontier, frontier)
+		}
+		if str := f.String(); str != s.expected {
+			t.Errorf("%d: expected %s, got %s", i, s.expected, str)
+		}
+	}
+}
+
+func TestChangefeedSinks(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	msgs := [][]byte{[]byte(`{"a":1}`), []byte(`{"b":2}`)}
+	const expected = "{\"a\":1}\n{\"b\":2}\n"
+
+	dir, cleanup := testutils.TempDir(t)
+	defer cleanup()
+
+	t.Run("file", func(t *testing.T) {
+		path := filepath.Join(dir, "foo", "changes.ndjson")
+		sink, err := changefeedSinkFromURI("file://" + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sink.Close()
+		for _, msg := range msgs {
+			if err := sink.EmitMessage(ctx, msg); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := os.Stat(path); err != nil {
+			t.Fatal(err)
+		}
+		if err := sink.Flush(ctx); err != nil {
+			t.Fatal(err)
+		}
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(contents) != expected {
+			t.Errorf("expected %q got %q", expected, contents)
+		}
+	})
+
+	t.Run("http", func(t *testing.T) {
+		var mu syncutil.Mutex
+		var attempts int
+		var received string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			attempts++
+			// Fail the first attempt to exercise the retry loop.
+			if attempts == 1 {
+				http.Error(w, "not yet", http.StatusServiceUnavailable)
+				return
+			}
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Error(err)
+			}
+			received += string(body)
+		}))
+		defer srv.Close()
+
+		sink, err := changefeedSinkFromURI(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sink.Close()
+		for _, msg := range msgs {
+			if err := sink.EmitMessage(ctx, msg); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := sink.Flush(ctx); err != nil {
+			t.Fatal(err)
+		}
+		// A flush with nothing buffered doesn't POST.
+		if err := sink.Flush(ctx); err != nil {
+			t.Fatal(err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if attempts != 2 {
+			t.Errorf("expected 2 attempts, got %d", attempts)
+		}
+		if received != expected {
+			t.Errorf("expected %q got %q", expected, received)
+		}
+	})
+
+	if _, err := changefeedSinkFromURI("kafka://foo"); !testutils.IsError(err, "unsupported sink") {
+		t.Fatalf("expected unsupported sink error, got %v", err)
+	}
+}
+
+func TestChangefeed(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	dir, cleanup := testutils.TempDir(t)
+	defer cleanup()
+
+	tc := testcluster.StartTestCluster(t, 1, base.TestClusterArgs{})
+	defer tc.Stopper().Stop()
+	sqlDB := backupSQLRunner(t, tc)
+
+	sqlDB.Exec(`CREATE DATABASE d`)
+	sqlDB.Exec(`CREATE TABLE d.foo (a INT PRIMARY KEY, b STRING)`)
+	sqlDB.Exec(`INSERT INTO d.foo VALUES (0, 'initial')`)
+
+	path := filepath.Join(dir, "changes.ndjson")
+	var jobID int64
+	sqlDB.QueryRow(`CREATE CHANGEFEED FOR d.foo INTO $1`, "file://"+path).Scan(&jobID)
+
+	sqlDB.Exec(`INSERT INTO d.foo VALUES (1, 'a'), (2, 'b')`)
+	sqlDB.Exec(`UPDATE d.foo SET b = 'c' WHERE a = 1`)
+	sqlDB.Exec(`DELETE FROM d.foo WHERE a = 2`)
+
+	expected := []string{
+		`{"key":[1],"table":"foo","value":{"a":1,"b":"a"}}`,
+		`{"key":[2],"table":"foo","value":{"a":2,"b":"b"}}`,
+		`{"key":[1],"table":"foo","value":{"a":1,"b":"c"}}`,
+		`{"key":[2],"table":"foo","value":null}`,
+	}
+
+	// readChanges returns the row messages (without their timestamps and with
+	// their fields sorted) in the sink up to its last resolved message, along
+	// with that resolved timestamp.
+	readChanges := func() ([]string, string, error) {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, "", err
+		}
+		defer f.Close()
+		var rows, pending []string
+		var resolved string
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var msg map[string]interface{}
+			if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+				return nil, "", err
+			}
+			if r, ok := msg["resolved"]; ok {
+				rows, pending, resolved = append(rows, pending...), nil, r.(string)
+				continue
+			}
+			delete(msg, "updated")
+			row, err := json.Marshal(msg)
+			if err != nil {
+				return nil, "", err
+			}
+			pending = append(pending, string(row))
+		}
+		return rows, resolved, scanner.Err()
+	}
+
+	var resolved string
+	testutils.SucceedsSoon(t, func() error {
+		rows, r, err := readChanges()
+		if err != nil {
+			return err
+		}
+		if len(rows) < len(expected) {
+			return errors.Errorf("expected %d rows, got %d: %s",
+				len(expected), len(rows), strings.Join(rows, "\n"))
+		}
+		resolved = r
+		if !reflect.DeepEqual(rows, expected) {
+			t.Fatalf("expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(rows, "\n"))
+		}
+		return nil
+	})
+
+	// The resolved timestamp is persisted in the job and can be used as the
+	// cursor of a new changefeed that picks up where this one left off.
+	var status string
+	sqlDB.QueryRow(`SELECT status FROM system.jobs WHERE id = $1`, jobID).Scan(&status)
+	if status != "running" {
+		t.Fatalf("expected job %d to be running, got %s", jobID, status)
+	}
+	sqlDB.Exec(`INSERT INTO d.foo VALUES (3, 'd')`)
+	resumedPath := filepath.Join(dir, "resumed.ndjson")
+	sqlDB.Exec(fmt.Sprintf(`CREATE CHANGEFEED FOR d.foo INTO $1 WITH OPTIONS ('cursor'='%s')`,
+		resolved), "file://"+resumedPath)
+	path = resumedPath
+	testutils.SucceedsSoon(t, func() error {
+		rows, _, err := readChanges()
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return errors.New("no rows yet")
+		}
+		if expected := `{"key":[3],"table":"foo","value":{"a":3,"b":"d"}}`; rows[0] != expected {
+			t.Fatalf("expected %s, got %s", expected, rows[0])
+		}
+		return nil
+	})
+}
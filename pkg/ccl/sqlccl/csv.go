@@ -0,0 +1,1130 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/pkg/ccl/LICENSE
+
+package sqlccl
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/storageccl"
+	"github.com/cockroachdb/cockroach/pkg/ccl/utilccl"
+	"github.com/cockroachdb/cockroach/pkg/config"
+	"github.com/cockroachdb/cockroach/pkg/gossip"
+	"github.com/cockroachdb/cockroach/pkg/internal/client"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/distsqlrun"
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/privilege"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/cockroach/pkg/util/tracing"
+)
+
+const (
+	importOptionDelimiter = "delimiter"
+	importOptionNullIf    = "nullif"
+	importOptionSkip      = "skip"
+	importOptionTemp      = "temp"
+)
+
+// readCSVSpecFromOptions returns the ReadCSVSpec described by the options of
+// an IMPORT, along with the URI of the temp directory the converted data is
+// written to.
+func readCSVSpecFromOptions(opts parser.KVOptions) (distsqlrun.ReadCSVSpec, string, error) {
+	var spec distsqlrun.ReadCSVSpec
+	if delimiter, ok := opts.Get(importOptionDelimiter); ok {
+		if utf8.RuneCountInString(delimiter) != 1 {
+			return spec, "", errors.Errorf("%s must be exactly one character: %q",
+				importOptionDelimiter, delimiter)
+		}
+		r, _ := utf8.DecodeRuneInString(delimiter)
+		spec.Comma = int32(r)
+	}
+	if nullif, ok := opts.Get(importOptionNullIf); ok {
+		spec.Nullif = &nullif
+	}
+	if skip, ok := opts.Get(importOptionSkip); ok {
+		n, err := strconv.ParseUint(skip, 10, 32)
+		if err != nil {
+			return spec, "", errors.Wrapf(err, "invalid %s value", importOptionSkip)
+		}
+		spec.Skip = uint32(n)
+	}
+	temp, ok := opts.Get(importOptionTemp)
+	if !ok || temp == "" {
+		return spec, "", errors.Errorf("must provide a %s directory for the converted data",
+			importOptionTemp)
+	}
+	return spec, temp, nil
+}
+
+func importJobDescription(
+	importStmt *parser.Import, files []string, temp string,
+) (string, error) {
+	stmt := parser.Import{
+		Table:      importStmt.Table,
+		CreateDefs: importStmt.CreateDefs,
+		FileFormat: importStmt.FileFormat,
+		Files:      make(parser.Exprs, len(files)),
+	}
+	for i, f := range files {
+		sf, err := storageccl.SanitizeExportStorageURI(f)
+		if err != nil {
+			return "", err
+		}
+		stmt.Files[i] = parser.NewDString(sf)
+	}
+	sanitizedTemp, err := storageccl.SanitizeExportStorageURI(temp)
+	if err != nil {
+		return "", err
+	}
+	for _, opt := range importStmt.Options {
+		if opt.Key == importOptionTemp {
+			opt.Value = sanitizedTemp
+		}
+		stmt.Options = append(stmt.Options, opt)
+	}
+	return stmt.String(), nil
+}
+
+// getImportDatabase looks up the database the tables of an import are created
+// in and checks that the user can create tables in it.
+func getImportDatabase(
+	ctx context.Context, txn *client.Txn, p sql.PlanHookState, name string,
+) (*sqlbase.DatabaseDescriptor, error) {
+	dbID, err := txn.Get(ctx, sqlbase.MakeNameMetadataKey(keys.RootNamespaceID, name))
+	if err != nil {
+		return nil, err
+	}
+	if dbID.Value == nil {
+		return nil, sqlbase.NewUndefinedDatabaseError(name)
+	}
+	parentID, err := dbID.Value.GetInt()
+	if err != nil {
+		return nil, err
+	}
+	dbDesc, err := sqlbase.GetDatabaseDescFromID(ctx, txn, sqlbase.ID(parentID))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to lookup parent DB %d", parentID)
+	}
+	if err := p.CheckPrivilege(dbDesc, privilege.CREATE); err != nil {
+		return nil, err
+	}
+	return dbDesc, nil
+}
+
+// writeImportTableDescs writes the descriptors and namespace entries of the
+// offline tables of an import, none of which may already exist.
+func writeImportTableDescs(
+	ctx context.Context, txn *client.Txn, tables []*sqlbase.TableDescriptor,
+) error {
+	b := txn.NewBatch()
+	for _, tableDesc := range tables {
+		b.Get(tableDesc.GetNameMetadataKey())
+	}
+	if err := txn.Run(ctx, b); err != nil {
+		return err
+	}
+	for i, res := range b.Results {
+		if res.Rows[0].Value != nil {
+			return sqlbase.NewRelationAlreadyExistsError(tables[i].Name)
+		}
+	}
+
+	b = txn.NewBatch()
+	for _, tableDesc := range tables {
+		b.CPut(tableDesc.GetDescMetadataKey(), sqlbase.WrapDescriptor(tableDesc), nil)
+		b.CPut(tableDesc.GetNameMetadataKey(), tableDesc.ID, nil)
+	}
+	if err := txn.Run(ctx, b); err != nil {
+		return err
+	}
+	for _, tableDesc := range tables {
+		if err := tableDesc.Validate(ctx, txn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// makeImportTableDesc creates, in the given database, the descriptor and
+// namespace entry of the table being imported. The table is offline until the
+// import finishes.
+func makeImportTableDesc(
+	ctx context.Context, p sql.PlanHookState, importStmt *parser.Import,
+) (*sqlbase.TableDescriptor, error) {
+	// TODO(dan): Support unqualified table names by resolving them against the
+	// session database, as CREATE TABLE does.
+	sessionDatabase := ""
+	tn, err := importStmt.Table.NormalizeWithDatabaseName(sessionDatabase)
+	if err != nil {
+		return nil, err
+	}
+	create := &parser.CreateTable{
+		Table: importStmt.Table,
+		Defs:  importStmt.CreateDefs,
+	}
+
+	var tableDesc sqlbase.TableDescriptor
+	err = p.ExecCfg().DB.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
+		dbDesc, err := getImportDatabase(ctx, txn, p, tn.Database())
+		if err != nil {
+			return err
+		}
+		id, err := sql.GenerateUniqueDescID(ctx, txn)
+		if err != nil {
+			return err
+		}
+		affected := make(map[sqlbase.ID]*sqlbase.TableDescriptor)
+		tableDesc, err = sql.MakeTableDesc(
+			ctx, txn, sql.NilVirtualTabler, nil, create, dbDesc.ID, id, dbDesc.GetPrivileges(),
+			affected, dbDesc.Name,
+		)
+		if err != nil {
+			return err
+		}
+		if len(affected) > 0 || tableDesc.IsInterleaved() {
+			return errors.New("IMPORT does not support foreign keys or interleaved tables")
+		}
+		tableDesc.State = sqlbase.TableDescriptor_OFFLINE
+		return writeImportTableDescs(ctx, txn, []*sqlbase.TableDescriptor{&tableDesc})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &tableDesc, nil
+}
+
+// clusterNodes returns the descriptors of the nodes in the cluster, as known
+// through gossip.
+func clusterNodes(g *gossip.Gossip) ([]roachpb.NodeDescriptor, error) {
+	var nodes []roachpb.NodeDescriptor
+	for k := range g.GetInfoStatus().Infos {
+		nodeID, err := gossip.NodeIDFromKey(k)
+		if err != nil {
+			// Not a node descriptor.
+			continue
+		}
+		desc, err := g.GetNodeDescriptor(nodeID)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, *desc)
+	}
+	return nodes, nil
+}
+
+// convertImportData runs the DistSQL flow converting the files of an import
+// into SSTs in its temp directory and writes a BackupDescriptor describing them
+// next to them.
+func convertImportData(
+	ctx context.Context, execCfg *sql.ExecutorConfig, details sql.ImportJobDetails,
+) (BackupDescriptor, error) {
+	nodes, err := clusterNodes(execCfg.Gossip)
+	if err != nil {
+		return BackupDescriptor{}, err
+	}
+	splitSize := config.DefaultZoneConfig().RangeMaxBytes / 2
+	results, err := execCfg.DistLoader.LoadCSV(
+		ctx, execCfg.DB, nodes, details.ReadCSV, details.Temp, details.Walltime, splitSize,
+	)
+	if err != nil {
+		return BackupDescriptor{}, err
+	}
+
+	var dbDesc *sqlbase.DatabaseDescriptor
+	if err := execCfg.DB.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
+		var err error
+		dbDesc, err = sqlbase.GetDatabaseDescFromID(ctx, txn, details.Tables[0].ParentID)
+		return err
+	}); err != nil {
+		return BackupDescriptor{}, err
+	}
+	backupDesc := BackupDescriptor{
+		EndTime:     hlc.Timestamp{WallTime: details.Walltime},
+		Descriptors: []sqlbase.Descriptor{*sqlbase.WrapDescriptor(dbDesc)},
+	}
+	for _, tableDesc := range details.Tables {
+		// The descriptors are written as the tables will be once published, so
+		// that the converted data can also be restored like a backup.
+		tableDesc.State = sqlbase.TableDescriptor_PUBLIC
+		backupDesc.Spans = append(backupDesc.Spans, tableDesc.TableSpan())
+		backupDesc.Descriptors = append(backupDesc.Descriptors, *sqlbase.WrapDescriptor(&tableDesc))
+	}
+	for _, row := range results {
+		file := BackupDescriptor_File{
+			Path: string(*row[0].(*parser.DString)),
+			EntryCounts: roachpb.BulkOpSummary{
+				Rows:         int64(*row[1].(*parser.DInt)),
+				IndexEntries: int64(*row[2].(*parser.DInt)),
+				DataSize:     int64(*row[3].(*parser.DInt)),
+			},
+			Sha512: []byte(*row[4].(*parser.DBytes)),
+			Span: roachpb.Span{
+				Key:    roachpb.Key(*row[5].(*parser.DBytes)),
+				EndKey: roachpb.Key(*row[6].(*parser.DBytes)),
+			},
+		}
+		backupDesc.Files = append(backupDesc.Files, file)
+		backupDesc.EntryCounts.Add(file.EntryCounts)
+		backupDesc.DataSize += file.EntryCounts.DataSize
+	}
+
+	descBuf, err := backupDesc.Marshal()
+	if err != nil {
+		return BackupDescriptor{}, err
+	}
+	dir, err := exportStorageFromURI(ctx, details.Temp)
+	if err != nil {
+		return BackupDescriptor{}, err
+	}
+	defer dir.Close()
+	if err := dir.WriteFile(ctx, BackupDescriptorName, bytes.NewReader(descBuf)); err != nil {
+		return BackupDescriptor{}, err
+	}
+	backupDesc.Dir = dir.Conf()
+	return backupDesc, nil
+}
+
+// importData runs, or resumes, the import tracked by jobLogger: it converts
+// the files into SSTs, unless a previous run of the job already did, ingests
+// the SSTs and then publishes the tables. Conversion counts as the first half
+// of the progress of the job.
+func importData(
+	ctx context.Context, execCfg *sql.ExecutorConfig, jobLogger *sql.JobLogger,
+) (BackupDescriptor, error) {
+	details := jobLogger.Job.Details.(sql.ImportJobDetails)
+	db := *execCfg.DB
+
+	var backupDesc BackupDescriptor
+	if details.Converted {
+		var err error
+		if backupDesc, err = readBackupDescriptor(ctx, details.Temp, nil /* encryption */); err != nil {
+			return BackupDescriptor{}, err
+		}
+	} else {
+		var err error
+		if backupDesc, err = convertImportData(ctx, execCfg, details); err != nil {
+			return BackupDescriptor{}, errors.Wrap(err, "converting data")
+		}
+		details.Converted = true
+		if err := jobLogger.SetDetails(ctx, details); err != nil {
+			return BackupDescriptor{}, err
+		}
+		if err := jobLogger.Progressed(ctx, 0.5); err != nil {
+			log.Errorf(ctx, "IMPORT ignoring error while updating progress on job %d (%s): %+v",
+				jobLogger.JobID(), jobLogger.Job.Description, err)
+		}
+	}
+
+	// A resumed import whose tables were already published only has to finish
+	// building their indexes and adding their foreign keys.
+	published, err := importedTablesPublished(ctx, db, details.Tables)
+	if err != nil {
+		return BackupDescriptor{}, err
+	}
+	pgDump := details.ReadCSV.Format == distsqlrun.ReadCSVSpec_PGDUMP
+	if !published {
+		if err := ingestImportFiles(ctx, execCfg, jobLogger, backupDesc, details.Tables); err != nil {
+			return BackupDescriptor{}, err
+		}
+		if err := publishImportedTables(ctx, db, details.Tables, pgDump); err != nil {
+			return BackupDescriptor{}, err
+		}
+	}
+	if pgDump {
+		if err := buildPgDumpIndexes(ctx, execCfg, details.Tables); err != nil {
+			return BackupDescriptor{}, err
+		}
+		if err := addPgDumpForeignKeys(ctx, execCfg, details.Tables); err != nil {
+			return BackupDescriptor{}, err
+		}
+	}
+	return backupDesc, nil
+}
+
+// importedTablesPublished returns whether the tables of an import were
+// already brought online, which publishImportedTables does atomically.
+func importedTablesPublished(
+	ctx context.Context, db client.DB, tables []sqlbase.TableDescriptor,
+) (bool, error) {
+	var published bool
+	err := db.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
+		existing, err := sqlbase.GetTableDescFromID(ctx, txn, tables[0].ID)
+		if err != nil {
+			return err
+		}
+		published = !existing.Offline()
+		return nil
+	})
+	return published, err
+}
+
+// ingestImportFiles ingests the SSTs of the converted data of an import into
+// the ranges of its tables.
+func ingestImportFiles(
+	ctx context.Context,
+	execCfg *sql.ExecutorConfig,
+	jobLogger *sql.JobLogger,
+	backupDesc BackupDescriptor,
+	tables []sqlbase.TableDescriptor,
+) error {
+	db := *execCfg.DB
+
+	// The SSTs don't overlap and cover the table spans in order, so each one is
+	// ingested into its own, presplit, range. A resumed import ingests them all
+	// again, which is idempotent as the KVs have a fixed timestamp.
+	files := backupDesc.Files
+	splitKeys := make([]roachpb.Key, 0, len(files))
+	for _, f := range files {
+		splitKey, err := keys.EnsureSafeSplitKey(f.Span.Key)
+		if err != nil {
+			return err
+		}
+		splitKeys = append(splitKeys, splitKey)
+	}
+	if err := presplitRanges(ctx, db, splitKeys); err != nil {
+		return errors.Wrapf(err, "presplitting %d ranges", len(splitKeys))
+	}
+
+	progressLogger := jobProgressLogger{
+		jobLogger:   jobLogger,
+		totalChunks: 2 * len(files),
+		priorChunks: len(files),
+	}
+	var kr storageccl.KeyRewriter
+	for i := range tables {
+		tableDesc := &tables[i]
+		kr = append(kr, MakeKeyRewriterForNewTableID(tableDesc, tableDesc.ID)...)
+	}
+	importsSem := make(chan struct{}, clusterNodeCount(execCfg.Gossip))
+	g, gCtx := errgroup.WithContext(ctx)
+	var statusErr error
+	for i := range files {
+		// Honor PAUSE JOB and CANCEL JOB between requests. In-flight requests
+		// are allowed to finish.
+		if statusErr = jobLogger.CheckStatus(ctx); statusErr != nil {
+			break
+		}
+		select {
+		case importsSem <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		f := files[i]
+		g.Go(func() error {
+			defer func() { <-importsSem }()
+
+			importFiles := []roachpb.ImportRequest_File{{
+				Dir:    backupDesc.Dir,
+				Path:   f.Path,
+				Sha512: f.Sha512,
+			}}
+			if err := Import(
+				gCtx, db, f.Span.Key, f.Span.EndKey, importFiles, kr, hlc.Timestamp{}, nil, /* encryption */
+			); err != nil {
+				return err
+			}
+			if err := progressLogger.chunkFinished(gCtx); err != nil {
+				// Errors while updating progress are not important enough to merit
+				// failing the entire import.
+				log.Errorf(ctx, "IMPORT ignoring error while updating progress on job %d (%s): %+v",
+					jobLogger.JobID(), jobLogger.Job.Description, err)
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return errors.Wrapf(err, "ingesting %d files", len(files))
+	}
+	return statusErr
+}
+
+// publishImportedTables brings the offline tables of an import online, with
+// the given descriptors. If backfillIndexes is set, the tables were loaded
+// with only their primary indexes and are published by withIndexMutations.
+func publishImportedTables(
+	ctx context.Context, db client.DB, tables []sqlbase.TableDescriptor, backfillIndexes bool,
+) error {
+	return db.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
+		b := txn.NewBatch()
+		for i := range tables {
+			existing, err := sqlbase.GetTableDescFromID(ctx, txn, tables[i].ID)
+			if err != nil {
+				return err
+			}
+			if !existing.Offline() {
+				return errors.Errorf("table %q is not offline", existing.Name)
+			}
+			tableDesc := tables[i]
+			if backfillIndexes {
+				if tableDesc, err = withIndexMutations(&tables[i]); err != nil {
+					return err
+				}
+			}
+			tableDesc.State = sqlbase.TableDescriptor_PUBLIC
+			tableDesc.Version = existing.Version + 1
+			b.Put(tableDesc.GetDescMetadataKey(), sqlbase.WrapDescriptor(&tableDesc))
+		}
+		if err := txn.SetSystemConfigTrigger(); err != nil {
+			return err
+		}
+		return txn.Run(ctx, b)
+	})
+}
+
+// dropImportedTables marks the tables of a failed import as dropped. The
+// schema changer then removes them along with any data that was ingested into
+// them, as it does for DROP TABLE. The names of offline tables, which no node
+// can have leased, are freed right away; those of tables that were already
+// published are freed by the schema changer once they're no longer in use.
+func dropImportedTables(ctx context.Context, db client.DB, tables []sqlbase.TableDescriptor) error {
+	return db.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
+		b := txn.NewBatch()
+		for i := range tables {
+			existing, err := sqlbase.GetTableDescFromID(ctx, txn, tables[i].ID)
+			if err != nil {
+				return err
+			}
+			if existing.Offline() {
+				existing.Version++
+				b.Del(existing.GetNameMetadataKey())
+			} else if err := existing.SetUpVersion(); err != nil {
+				return err
+			}
+			existing.State = sqlbase.TableDescriptor_DROP
+			existing.DropTime = timeutil.Now().UnixNano()
+			b.Put(existing.GetDescMetadataKey(), sqlbase.WrapDescriptor(existing))
+		}
+		if err := txn.SetSystemConfigTrigger(); err != nil {
+			return err
+		}
+		return txn.Run(ctx, b)
+	})
+}
+
+// runImportJob runs importData and, if the import fails for any other reason
+// than this node losing the job or shutting down, drops the tables being
+// imported. After a shutdown, the job is resumed by another node.
+func runImportJob(
+	ctx context.Context, execCfg *sql.ExecutorConfig, jobLogger *sql.JobLogger,
+) (BackupDescriptor, error) {
+	backupDesc, err := importData(ctx, execCfg, jobLogger)
+	if err != nil && !sql.IsJobLeaseLostError(err) && ctx.Err() == nil {
+		details := jobLogger.Job.Details.(sql.ImportJobDetails)
+		if dropErr := dropImportedTables(ctx, *execCfg.DB, details.Tables); dropErr != nil {
+			log.Warningf(ctx, "unable to drop tables of failed import: %+v", dropErr)
+		}
+	}
+	return backupDesc, err
+}
+
+func importPlanHook(
+	baseCtx context.Context, stmt parser.Statement, p sql.PlanHookState,
+) (func() ([]parser.Datums, error), sql.ResultColumns, error) {
+	importStmt, ok := stmt.(*parser.Import)
+	if !ok {
+		return nil, nil, nil
+	}
+	if err := utilccl.CheckEnterpriseEnabled("IMPORT"); err != nil {
+		return nil, nil, err
+	}
+	if err := p.RequireSuperUser("IMPORT"); err != nil {
+		return nil, nil, err
+	}
+	if importStmt.FileFormat != "CSV" && importStmt.FileFormat != "PGDUMP" {
+		return nil, nil, errors.Errorf("unsupported import format: %q", importStmt.FileFormat)
+	}
+
+	filesFn, err := p.TypeAsStringArray(&importStmt.Files)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header := sql.ResultColumns{
+		{Name: "job_id", Typ: parser.TypeInt},
+		{Name: "status", Typ: parser.TypeString},
+		{Name: "fraction_completed", Typ: parser.TypeFloat},
+		{Name: "rows", Typ: parser.TypeInt},
+		{Name: "index_entries", Typ: parser.TypeInt},
+		{Name: "bytes", Typ: parser.TypeInt},
+	}
+	if importStmt.FileFormat == "PGDUMP" {
+		header = append(header, sql.ResultColumn{Name: "skipped", Typ: parser.TypeString})
+	}
+	fn := func() ([]parser.Datums, error) {
+		// TODO(dan): Move this span into sql.
+		ctx, span := tracing.ChildSpan(baseCtx, stmt.StatementTag())
+		defer tracing.FinishSpan(span)
+
+		files := filesFn()
+		spec, temp, err := readCSVSpecFromOptions(importStmt.Options)
+		if err != nil {
+			return nil, err
+		}
+		spec.URIs = files
+		description, err := importJobDescription(importStmt, files, temp)
+		if err != nil {
+			return nil, err
+		}
+
+		var tables []sqlbase.TableDescriptor
+		var schema *pgDumpSchema
+		if importStmt.FileFormat == "PGDUMP" {
+			if schema, err = readPgDumpSchema(ctx, files[0]); err != nil {
+				return nil, err
+			}
+			if spec.Tables, tables, err = makePgDumpTableDescs(
+				ctx, p, schema, importStmt.Options,
+			); err != nil {
+				return nil, err
+			}
+			spec.Format = distsqlrun.ReadCSVSpec_PGDUMP
+		} else {
+			tableDesc, err := makeImportTableDesc(ctx, p, importStmt)
+			if err != nil {
+				return nil, err
+			}
+			spec.TableDesc = *tableDesc
+			tables = []sqlbase.TableDescriptor{*tableDesc}
+		}
+		descriptorIDs := make(sqlbase.IDs, len(tables))
+		for i := range tables {
+			descriptorIDs[i] = tables[i].ID
+		}
+
+		jobLogger := p.ExecCfg().JobRegistry.NewJobLogger(sql.JobRecord{
+			Description:   description,
+			Username:      p.User(),
+			DescriptorIDs: descriptorIDs,
+			Details: sql.ImportJobDetails{
+				ReadCSV:  spec,
+				Temp:     temp,
+				Walltime: p.ExecCfg().Clock.Now().WallTime,
+				Tables:   tables,
+			},
+		})
+		if err := jobLogger.Created(ctx); err != nil {
+			if dropErr := dropImportedTables(ctx, *p.ExecCfg().DB, tables); dropErr != nil {
+				log.Warningf(ctx, "unable to drop tables of failed import: %+v", dropErr)
+			}
+			return nil, err
+		}
+		if err := jobLogger.Started(ctx); err != nil {
+			// Unless another node took over the job or will once this one has
+			// shut down, nothing is going to import into the tables, so drop them
+			// and make sure the job isn't resumed.
+			if !sql.IsJobLeaseLostError(err) && ctx.Err() == nil {
+				if dropErr := dropImportedTables(ctx, *p.ExecCfg().DB, tables); dropErr != nil {
+					log.Warningf(ctx, "unable to drop tables of failed import: %+v", dropErr)
+				}
+				jobLogger.Failed(ctx, err)
+			}
+			return nil, err
+		}
+		backupDesc, err := runImportJob(ctx, p.ExecCfg(), &jobLogger)
+		if err != nil {
+			jobLogger.Failed(ctx, err)
+			return nil, err
+		}
+		if err := jobLogger.Succeeded(ctx); err != nil {
+			// An error while marking the job as successful is not important enough to
+			// merit failing the entire import.
+			log.Errorf(ctx, "IMPORT ignoring error while marking job %d (%s) as successful: %+v",
+				jobLogger.JobID(), description, err)
+		}
+		ret := []parser.Datums{{
+			parser.NewDInt(parser.DInt(*jobLogger.JobID())),
+			parser.NewDString(string(sql.JobStatusSucceeded)),
+			parser.NewDFloat(parser.DFloat(1.0)),
+			parser.NewDInt(parser.DInt(backupDesc.EntryCounts.Rows)),
+			parser.NewDInt(parser.DInt(backupDesc.EntryCounts.IndexEntries)),
+			parser.NewDInt(parser.DInt(backupDesc.EntryCounts.DataSize)),
+		}}
+		if schema != nil {
+			ret[0] = append(ret[0], parser.NewDString(schema.skippedReport()))
+		}
+		return ret, nil
+	}
+	return fn, header, nil
+}
+
+// resumeImport continues an import whose node died. The conversion is started
+// over unless it had finished.
+func resumeImport(ctx context.Context, execCfg *sql.ExecutorConfig, jobLogger *sql.JobLogger) error {
+	_, err := runImportJob(ctx, execCfg, jobLogger)
+	return err
+}
+
+// readCSV is a processor that reads CSV, or pg_dump, files and converts their
+// records into the KVs of their tables. See distsqlrun.ReadCSVSpec.
+type readCSV struct {
+	flowCtx *distsqlrun.FlowCtx
+	spec    distsqlrun.ReadCSVSpec
+	output  distsqlrun.RowReceiver
+
+	// sampled is the size of the KVs converted since the last sampled key.
+	sampled int64
+	// pushErr is set once a KV can't be pushed to the output.
+	pushErr error
+}
+
+var _ distsqlrun.Processor = &readCSV{}
+
+func newReadCSVProcessor(
+	flowCtx *distsqlrun.FlowCtx, spec distsqlrun.ReadCSVSpec, output distsqlrun.RowReceiver,
+) (distsqlrun.Processor, error) {
+	return &readCSV{flowCtx: flowCtx, spec: spec, output: output}, nil
+}
+
+// Run is part of the Processor interface.
+func (cp *readCSV) Run(ctx context.Context, wg *sync.WaitGroup) {
+	if wg != nil {
+		defer wg.Done()
+	}
+
+	ctx, span := tracing.ChildSpan(ctx, "readCSV")
+	defer tracing.FinishSpan(span)
+
+	if err := cp.run(ctx); err != nil {
+		cp.output.Push(nil /* row */, distsqlrun.ProducerMetadata{Err: err})
+	}
+	cp.output.ProducerDone()
+}
+
+// errConsumerDone is returned by the readCSV pushes once its consumer doesn't
+// need any more rows.
+var errConsumerDone = errors.New("consumer done")
+
+func (cp *readCSV) run(ctx context.Context) error {
+	evalCtx := parser.EvalContext{NodeID: cp.flowCtx.NodeID()}
+	b := inserter(cp.push)
+	for _, uri := range cp.spec.URIs {
+		var err error
+		switch cp.spec.Format {
+		case distsqlrun.ReadCSVSpec_CSV:
+			err = cp.readCSVFile(ctx, uri, evalCtx, b)
+		case distsqlrun.ReadCSVSpec_PGDUMP:
+			err = cp.readPgDumpFile(ctx, uri, evalCtx, b)
+		default:
+			err = errors.Errorf("unsupported format %s", cp.spec.Format)
+		}
+		if err == errConsumerDone {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// push outputs a converted KV or, when sampling, only some of their keys.
+func (cp *readCSV) push(kv roachpb.KeyValue) {
+	if cp.pushErr != nil {
+		return
+	}
+	key, value := []byte(kv.Key), kv.Value.RawBytes
+	if cp.spec.SampleSize > 0 {
+		cp.sampled += int64(len(key) + len(value))
+		if cp.sampled < int64(cp.spec.SampleSize) {
+			return
+		}
+		cp.sampled = 0
+		// Sampled keys are used as split points, so they must not fall in the
+		// middle of a row.
+		if key, cp.pushErr = keys.EnsureSafeSplitKey(kv.Key); cp.pushErr != nil {
+			return
+		}
+		value = nil
+	}
+	bytesType := sqlbase.ColumnType{Kind: sqlbase.ColumnType_BYTES}
+	row := sqlbase.EncDatumRow{
+		sqlbase.DatumToEncDatum(bytesType, parser.NewDBytes(parser.DBytes(key))),
+		sqlbase.DatumToEncDatum(bytesType, parser.NewDBytes(parser.DBytes(value))),
+	}
+	if cp.output.Push(row, distsqlrun.ProducerMetadata{}) != distsqlrun.NeedMoreRows {
+		cp.pushErr = errConsumerDone
+	}
+}
+
+// readCSVFile converts the records of the CSV file at uri into the KVs of the
+// table of the spec.
+func (cp *readCSV) readCSVFile(
+	ctx context.Context, uri string, evalCtx parser.EvalContext, b inserter,
+) error {
+	// The fields of the records are the visible columns, in order. The other
+	// columns, such as the implicit primary key, get their default values.
+	tableDesc := &cp.spec.TableDesc
+	visibleCols := tableDesc.VisibleColumns()
+	conv, err := newRowConverter(tableDesc, visibleCols, evalCtx)
+	if err != nil {
+		return err
+	}
+
+	return readImportFile(ctx, uri, func(f io.Reader) error {
+		r := csv.NewReader(f)
+		if cp.spec.Comma != 0 {
+			r.Comma = rune(cp.spec.Comma)
+		}
+		r.FieldsPerRecord = len(visibleCols)
+		for i := 0; ; i++ {
+			record, err := r.Read()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return errors.Wrapf(err, "%s: reading record %d", uri, i+1)
+			}
+			if uint32(i) < cp.spec.Skip {
+				continue
+			}
+			if err := cp.convertRecord(ctx, conv, record, b); err != nil {
+				if err == errConsumerDone {
+					return err
+				}
+				return errors.Wrapf(err, "%s: record %d", uri, i+1)
+			}
+		}
+	})
+}
+
+func (cp *readCSV) convertRecord(
+	ctx context.Context, conv *rowConverter, record []string, b inserter,
+) error {
+	row := make(parser.Datums, len(record))
+	for i, field := range record {
+		if cp.spec.Nullif != nil && field == *cp.spec.Nullif {
+			row[i] = parser.DNull
+			continue
+		}
+		var err error
+		if row[i], err = parseStringAs(conv.cols[i].Type.ToDatumType(), field); err != nil {
+			return errors.Wrapf(err, "column %q", conv.cols[i].Name)
+		}
+	}
+	if err := conv.convert(ctx, row, b); err != nil {
+		return err
+	}
+	return cp.pushErr
+}
+
+// readImportFile calls fn with the contents of the file at uri.
+func readImportFile(ctx context.Context, uri string, fn func(io.Reader) error) error {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return err
+	}
+	name := path.Base(parsed.Path)
+	parsed.Path = path.Dir(parsed.Path)
+	dir, err := exportStorageFromURI(ctx, parsed.String())
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	f, err := dir.ReadFile(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return fn(f)
+}
+
+// rowConverter converts rows of values for some of the columns of a table into
+// the KVs of the table, giving the other columns their default values.
+type rowConverter struct {
+	tableDesc *sqlbase.TableDescriptor
+	// cols are the columns given values by the converted rows, in order.
+	cols         []sqlbase.ColumnDescriptor
+	insertCols   []sqlbase.ColumnDescriptor
+	defaultExprs []parser.TypedExpr
+	ri           sqlbase.RowInserter
+	evalCtx      parser.EvalContext
+}
+
+func newRowConverter(
+	tableDesc *sqlbase.TableDescriptor, cols []sqlbase.ColumnDescriptor, evalCtx parser.EvalContext,
+) (*rowConverter, error) {
+	parse := parser.Parser{}
+	insertCols, defaultExprs, err := sql.ProcessDefaultColumns(
+		append([]sqlbase.ColumnDescriptor(nil), cols...), tableDesc, &parse, &evalCtx,
+	)
+	if err != nil {
+		return nil, err
+	}
+	ri, err := sqlbase.MakeRowInserter(nil /* txn */, tableDesc, nil /* fkTables */, insertCols, false /* checkFKs */)
+	if err != nil {
+		return nil, err
+	}
+	return &rowConverter{
+		tableDesc:    tableDesc,
+		cols:         cols,
+		insertCols:   insertCols,
+		defaultExprs: defaultExprs,
+		ri:           ri,
+		evalCtx:      evalCtx,
+	}, nil
+}
+
+// convert passes the KVs of row, which has a value for each of the columns of
+// the converter, to b.
+func (c *rowConverter) convert(ctx context.Context, row parser.Datums, b inserter) error {
+	row, err := sql.GenerateInsertRow(
+		c.defaultExprs, c.ri.InsertColIDtoRowIndex, c.insertCols, c.evalCtx, c.tableDesc, row,
+	)
+	if err != nil {
+		return err
+	}
+	return c.ri.InsertRow(ctx, b, row, true /* ignoreConflicts */)
+}
+
+// parseStringAs parses a CSV field as a datum of the given type.
+func parseStringAs(t parser.Type, s string) (parser.Datum, error) {
+	switch t {
+	case parser.TypeBool:
+		return parser.ParseDBool(s)
+	case parser.TypeBytes:
+		return parser.NewDBytes(parser.DBytes(s)), nil
+	case parser.TypeDate:
+		return parser.ParseDDate(s, time.UTC)
+	case parser.TypeDecimal:
+		return parser.ParseDDecimal(s)
+	case parser.TypeFloat:
+		return parser.ParseDFloat(s)
+	case parser.TypeInt:
+		return parser.ParseDInt(s)
+	case parser.TypeInterval:
+		return parser.ParseDInterval(s)
+	case parser.TypeString:
+		return parser.NewDString(s), nil
+	case parser.TypeTimestamp:
+		return parser.ParseDTimestamp(s, time.Microsecond)
+	case parser.TypeTimestampTZ:
+		return parser.ParseDTimestampTZ(s, time.UTC, time.Microsecond)
+	default:
+		return nil, errors.Errorf("unsupported type %s", t)
+	}
+}
+
+// sstWriter is a processor that sorts the KVs output by readCSV processors
+// and writes them as SSTs. See distsqlrun.SSTWriterSpec.
+type sstWriter struct {
+	flowCtx *distsqlrun.FlowCtx
+	spec    distsqlrun.SSTWriterSpec
+	input   distsqlrun.RowSource
+	output  distsqlrun.RowReceiver
+}
+
+var _ distsqlrun.Processor = &sstWriter{}
+
+func newSSTWriterProcessor(
+	flowCtx *distsqlrun.FlowCtx,
+	spec distsqlrun.SSTWriterSpec,
+	input distsqlrun.RowSource,
+	output distsqlrun.RowReceiver,
+) (distsqlrun.Processor, error) {
+	return &sstWriter{flowCtx: flowCtx, spec: spec, input: input, output: output}, nil
+}
+
+// Run is part of the Processor interface.
+func (sp *sstWriter) Run(ctx context.Context, wg *sync.WaitGroup) {
+	if wg != nil {
+		defer wg.Done()
+	}
+
+	ctx, span := tracing.ChildSpan(ctx, "sstWriter")
+	defer tracing.FinishSpan(span)
+
+	if err := sp.run(ctx); err != nil {
+		distsqlrun.DrainAndClose(ctx, sp.output, err, sp.input)
+		return
+	}
+	sp.output.ProducerDone()
+}
+
+func (sp *sstWriter) run(ctx context.Context) error {
+	// Buffer and sort the KVs in a temporary RocksDB, so that imports larger
+	// than memory spill to disk.
+	tempDir, err := ioutil.TempDir(sp.flowCtx.TempPrefix, "import-sst-writer")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := os.RemoveAll(tempDir); err != nil {
+			log.Warningf(ctx, "could not remove temp directory %s: %s", tempDir, err)
+		}
+	}()
+	cache := engine.NewRocksDBCache(1 << 20 /* 1 MB */)
+	eng, err := engine.NewRocksDB(roachpb.Attributes{}, tempDir, cache, 0, engine.DefaultMaxOpenFiles)
+	cache.Release()
+	if err != nil {
+		return err
+	}
+	defer eng.Close()
+
+	if len(sp.spec.Spans) == 0 {
+		return errors.New("no spans to write")
+	}
+	ts := hlc.Timestamp{WallTime: sp.spec.WalltimeNanos}
+	input := distsqlrun.MakeNoMetadataRowSource(sp.input, sp.output)
+	var alloc sqlbase.DatumAlloc
+	for {
+		row, err := input.NextRow()
+		if err != nil {
+			return err
+		}
+		if row == nil {
+			break
+		}
+		for i := range row {
+			if err := row[i].EnsureDecoded(&alloc); err != nil {
+				return err
+			}
+		}
+		key := engine.MVCCKey{
+			Key:       roachpb.Key(*row[0].Datum.(*parser.DBytes)),
+			Timestamp: ts,
+		}
+		if existing, err := eng.Get(key); err != nil {
+			return err
+		} else if existing != nil {
+			return errors.Errorf("duplicate key: %s", key.Key)
+		}
+		if err := eng.Put(key, []byte(*row[1].Datum.(*parser.DBytes))); err != nil {
+			return err
+		}
+	}
+
+	store, err := exportStorageFromURI(ctx, sp.spec.Destination)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	// Write one SST per span. The spans are ordered, the last one is
+	// unbounded, and empty spans produce no SST.
+	spans := sp.spec.Spans
+	var kvs []engine.MVCCKeyValue
+	flush := func() error {
+		if len(kvs) == 0 {
+			return nil
+		}
+		row, err := writeCSVSST(ctx, sp.flowCtx.TempPrefix, store, spans[0].Name, kvs)
+		kvs = kvs[:0]
+		if err != nil {
+			return err
+		}
+		if sp.output.Push(row, distsqlrun.ProducerMetadata{}) != distsqlrun.NeedMoreRows {
+			return errConsumerDone
+		}
+		return nil
+	}
+	err = eng.Iterate(engine.MVCCKey{Key: keys.MinKey}, engine.MVCCKeyMax,
+		func(kv engine.MVCCKeyValue) (bool, error) {
+			for len(spans[0].End) > 0 && bytes.Compare(kv.Key.Key, spans[0].End) >= 0 {
+				if err := flush(); err != nil {
+					return true, err
+				}
+				spans = spans[1:]
+			}
+			kvs = append(kvs, engine.MVCCKeyValue{
+				Key:   engine.MVCCKey{Key: append(roachpb.Key(nil), kv.Key.Key...), Timestamp: kv.Key.Timestamp},
+				Value: append([]byte(nil), kv.Value...),
+			})
+			return false, nil
+		})
+	if err == nil {
+		err = flush()
+	}
+	if err == errConsumerDone {
+		return nil
+	}
+	return err
+}
+
+// writeCSVSST writes the given sorted KVs to the SST file name in store and
+// returns the row describing it that is output by the sstWriter processor.
+func writeCSVSST(
+	ctx context.Context,
+	tempPrefix string,
+	store storageccl.ExportStorage,
+	name string,
+	kvs []engine.MVCCKeyValue,
+) (sqlbase.EncDatumRow, error) {
+	sstFile, err := storageccl.MakeExportFileTmpWriter(ctx, tempPrefix, store, name)
+	if err != nil {
+		return nil, err
+	}
+	defer sstFile.Close(ctx)
+
+	var counts storageccl.RowCounter
+	sst := engine.MakeRocksDBSstFileWriter()
+	if err := sst.Open(sstFile.LocalFile()); err != nil {
+		return nil, err
+	}
+	for _, kv := range kvs {
+		if err := sst.Add(kv); err != nil {
+			sst.Close()
+			return nil, err
+		}
+		if err := counts.Count(kv.Key.Key); err != nil {
+			sst.Close()
+			return nil, err
+		}
+	}
+	if err := sst.Close(); err != nil {
+		return nil, err
+	}
+	checksum, err := storageccl.SHA512ChecksumFile(sstFile.LocalFile())
+	if err != nil {
+		return nil, err
+	}
+	if err := sstFile.Finish(ctx); err != nil {
+		return nil, err
+	}
+
+	// The end key is exclusive, so use PrefixEnd to get the first key greater
+	// than the last key in the SST.
+	start, end := kvs[0].Key.Key, kvs[len(kvs)-1].Key.Key.PrefixEnd()
+	return sqlbase.EncDatumRow{
+		sqlbase.DatumToEncDatum(sqlbase.ColumnType{Kind: sqlbase.ColumnType_STRING}, parser.NewDString(name)),
+		sqlbase.DatumToEncDatum(sqlbase.ColumnType{Kind: sqlbase.ColumnType_INT}, parser.NewDInt(parser.DInt(counts.Rows))),
+		sqlbase.DatumToEncDatum(sqlbase.ColumnType{Kind: sqlbase.ColumnType_INT}, parser.NewDInt(parser.DInt(counts.IndexEntries))),
+		sqlbase.DatumToEncDatum(sqlbase.ColumnType{Kind: sqlbase.ColumnType_INT}, parser.NewDInt(parser.DInt(sst.DataSize))),
+		sqlbase.DatumToEncDatum(sqlbase.ColumnType{Kind: sqlbase.ColumnType_BYTES}, parser.NewDBytes(parser.DBytes(checksum))),
+		sqlbase.DatumToEncDatum(sqlbase.ColumnType{Kind: sqlbase.ColumnType_BYTES}, parser.NewDBytes(parser.DBytes(start))),
+		sqlbase.DatumToEncDatum(sqlbase.ColumnType{Kind: sqlbase.ColumnType_BYTES}, parser.NewDBytes(parser.DBytes(end))),
+	}, nil
+}
+
+func init() {
+	sql.AddPlanHook(importPlanHook)
+	sql.RegisterJobResumer(sql.JobTypeImport, resumeImport)
+	distsqlrun.NewReadCSVProcessor = newReadCSVProcessor
+	distsqlrun.NewSSTWriterProcessor = newSSTWriterProcessor
+}
@@ -0,0 +1,144 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/pkg/ccl/LICENSE
+
+package sqlccl
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+// writeCSVFiles writes the given CSV files in dir, which is a nodelocal URI,
+// and returns their URIs.
+func writeCSVFiles(t *testing.T, dir string, files map[string]string) []string {
+	var uris []string
+	for name, content := range files {
+		path := filepath.Join(strings.TrimPrefix(dir, "nodelocal://"), name)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		uris = append(uris, dir+"/"+name)
+	}
+	return uris
+}
+
+func quotedList(uris []string) string {
+	quoted := make([]string, len(uris))
+	for i, uri := range uris {
+		quoted[i] = "'" + uri + "'"
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func TestImportCSV(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	const numFiles, rowsPerFile = 3, 1000
+
+	_, dir, _, sqlDB, cleanupFn := backupRestoreTestSetup(t, multiNode, 0)
+	defer cleanupFn()
+
+	// Every file has a header, and every other row a NULL c.
+	files := make(map[string]string, numFiles)
+	for f := 0; f < numFiles; f++ {
+		var buf bytes.Buffer
+		buf.WriteString("a|b|c\n")
+		for i := 0; i < rowsPerFile; i++ {
+			x := f*rowsPerFile + i
+			var c string
+			if x%2 == 0 {
+				c = fmt.Sprint(x)
+			}
+			fmt.Fprintf(&buf, "%d|s%d|%s\n", x, x%10, c)
+		}
+		files[fmt.Sprintf("data-%d.csv", f)] = buf.String()
+	}
+	uris := writeCSVFiles(t, dir, files)
+
+	const schema = `bench.t (a INT PRIMARY KEY, b STRING, c INT, INDEX (b))`
+	var jobID, rows, indexEntries, dataSize int64
+	var status string
+	var fractionCompleted float32
+	sqlDB.QueryRow(fmt.Sprintf(
+		`IMPORT TABLE %s CSV DATA (%s) WITH delimiter = '|', nullif = '', skip = '1', temp = '%s'`,
+		schema, quotedList(uris), dir+"/temp",
+	)).Scan(&jobID, &status, &fractionCompleted, &rows, &indexEntries, &dataSize)
+
+	const expectedRows = numFiles * rowsPerFile
+	if status != string(sql.JobStatusSucceeded) || fractionCompleted != 1 {
+		t.Fatalf("expected a succeeded job, got %s (%f)", status, fractionCompleted)
+	}
+	if rows != expectedRows || indexEntries != expectedRows {
+		t.Fatalf("expected %d rows and index entries, got %d and %d", expectedRows, rows, indexEntries)
+	}
+	if dataSize <= 0 {
+		t.Fatalf("expected a positive data size, got %d", dataSize)
+	}
+
+	var count, nonNull int
+	sqlDB.QueryRow(`SELECT COUNT(*), COUNT(c) FROM bench.t`).Scan(&count, &nonNull)
+	if count != expectedRows || nonNull != expectedRows/2 {
+		t.Fatalf("expected %d rows and %d non-NULL values, got %d and %d",
+			expectedRows, expectedRows/2, count, nonNull)
+	}
+	sqlDB.QueryRow(`SELECT COUNT(*) FROM bench.t@t_b_idx WHERE b = 's5'`).Scan(&count)
+	if count != expectedRows/10 {
+		t.Fatalf("expected %d rows in index, got %d", expectedRows/10, count)
+	}
+
+	var jobType string
+	sqlDB.QueryRow(
+		`SELECT type, status FROM crdb_internal.jobs WHERE id = $1`, jobID,
+	).Scan(&jobType, &status)
+	if jobType != sql.JobTypeImport || status != string(sql.JobStatusSucceeded) {
+		t.Fatalf("unexpected job %d: %s %s", jobID, jobType, status)
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		for i, tc := range []struct {
+			content string
+			err     string
+		}{
+			{"1,a\n2,b,c\n", "wrong number of fields"},
+			{"1,a\nb,c\n", `column "a"`},
+			{"1,a\n1,b\n", "duplicate key"},
+		} {
+			uris := writeCSVFiles(t, dir, map[string]string{fmt.Sprintf("bad-%d.csv", i): tc.content})
+			_, err := sqlDB.DB.Exec(fmt.Sprintf(
+				`IMPORT TABLE bench.bad (a INT PRIMARY KEY, b STRING) CSV DATA (%s) WITH temp = '%s'`,
+				quotedList(uris), fmt.Sprintf("%s/temp-bad-%d", dir, i),
+			))
+			if !testutils.IsError(err, tc.err) {
+				t.Fatalf("%d: expected error %q, got %v", i, tc.err, err)
+			}
+			// The table of a failed import is dropped.
+			if _, err := sqlDB.DB.Exec(`SELECT * FROM bench.bad`); !testutils.IsError(
+				err, "does not exist",
+			) {
+				t.Fatalf("%d: expected table to not exist, got %v", i, err)
+			}
+		}
+	})
+
+	t.Run("no temp", func(t *testing.T) {
+		_, err := sqlDB.DB.Exec(fmt.Sprintf(
+			`IMPORT TABLE bench.notemp (a INT PRIMARY KEY) CSV DATA (%s)`, quotedList(uris),
+		))
+		if !testutils.IsError(err, "must provide a temp directory") {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
@@ -0,0 +1,254 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/pkg/ccl/LICENSE
+
+package sqlccl
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/utilccl"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/distsqlrun"
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/util/tracing"
+)
+
+const (
+	exportOptionDelimiter = "delimiter"
+	exportOptionNullAs    = "nullas"
+	exportOptionChunkRows = "chunk_rows"
+)
+
+// exportChunkRowsDefault is the maximum number of rows in an exported file
+// when chunk_rows isn't specified. Each file is buffered in memory before it
+// is written.
+const exportChunkRowsDefault = 100000
+
+// csvWriterSpecFromOptions returns the CSVWriterSpec described by the options
+// of an EXPORT.
+func csvWriterSpecFromOptions(opts parser.KVOptions) (distsqlrun.CSVWriterSpec, error) {
+	spec := distsqlrun.CSVWriterSpec{ChunkRows: exportChunkRowsDefault}
+	if delimiter, ok := opts.Get(exportOptionDelimiter); ok {
+		if utf8.RuneCountInString(delimiter) != 1 {
+			return spec, errors.Errorf("%s must be exactly one character: %q",
+				exportOptionDelimiter, delimiter)
+		}
+		r, _ := utf8.DecodeRuneInString(delimiter)
+		spec.Comma = int32(r)
+	}
+	if nullAs, ok := opts.Get(exportOptionNullAs); ok {
+		spec.NullEncoding = nullAs
+	}
+	if chunkRows, ok := opts.Get(exportOptionChunkRows); ok {
+		n, err := strconv.ParseInt(chunkRows, 10, 64)
+		if err != nil || n <= 0 {
+			return spec, errors.Errorf("invalid %s value: %q", exportOptionChunkRows, chunkRows)
+		}
+		spec.ChunkRows = n
+	}
+	return spec, nil
+}
+
+func exportPlanHook(
+	baseCtx context.Context, stmt parser.Statement, p sql.PlanHookState,
+) (func() ([]parser.Datums, error), sql.ResultColumns, error) {
+	exportStmt, ok := stmt.(*parser.Export)
+	if !ok {
+		return nil, nil, nil
+	}
+	if err := utilccl.CheckEnterpriseEnabled("EXPORT"); err != nil {
+		return nil, nil, err
+	}
+	if err := p.RequireSuperUser("EXPORT"); err != nil {
+		return nil, nil, err
+	}
+	if exportStmt.FileFormat != "CSV" {
+		return nil, nil, errors.Errorf("unsupported export format: %q", exportStmt.FileFormat)
+	}
+
+	fileFn, err := p.TypeAsString(&exportStmt.File)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fn := func() ([]parser.Datums, error) {
+		// TODO(dan): Move this span into sql.
+		ctx, span := tracing.ChildSpan(baseCtx, stmt.StatementTag())
+		defer tracing.FinishSpan(span)
+
+		spec, err := csvWriterSpecFromOptions(exportStmt.Options)
+		if err != nil {
+			return nil, err
+		}
+		spec.Destination = fileFn()
+
+		// Check the destination before running the query.
+		store, err := exportStorageFromURI(ctx, spec.Destination)
+		if err != nil {
+			return nil, err
+		}
+		if err := store.Close(); err != nil {
+			return nil, err
+		}
+
+		return p.ExportQuery(ctx, exportStmt.Query, spec)
+	}
+	return fn, sql.ExportResultColumns, nil
+}
+
+// csvWriter is a processor that writes the rows it consumes as CSV files. See
+// distsqlrun.CSVWriterSpec.
+type csvWriter struct {
+	flowCtx *distsqlrun.FlowCtx
+	spec    distsqlrun.CSVWriterSpec
+	input   distsqlrun.RowSource
+	output  distsqlrun.RowReceiver
+}
+
+var _ distsqlrun.Processor = &csvWriter{}
+
+func newCSVWriterProcessor(
+	flowCtx *distsqlrun.FlowCtx,
+	spec distsqlrun.CSVWriterSpec,
+	input distsqlrun.RowSource,
+	output distsqlrun.RowReceiver,
+) (distsqlrun.Processor, error) {
+	return &csvWriter{flowCtx: flowCtx, spec: spec, input: input, output: output}, nil
+}
+
+// Run is part of the Processor interface.
+func (sp *csvWriter) Run(ctx context.Context, wg *sync.WaitGroup) {
+	if wg != nil {
+		defer wg.Done()
+	}
+
+	ctx, span := tracing.ChildSpan(ctx, "csvWriter")
+	defer tracing.FinishSpan(span)
+
+	if err := sp.run(ctx); err != nil {
+		distsqlrun.DrainAndClose(ctx, sp.output, err, sp.input)
+		return
+	}
+	sp.output.ProducerDone()
+}
+
+func (sp *csvWriter) run(ctx context.Context) error {
+	store, err := exportStorageFromURI(ctx, sp.spec.Destination)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if sp.spec.Comma != 0 {
+		writer.Comma = rune(sp.spec.Comma)
+	}
+
+	// Write a file every ChunkRows rows, and one for the remaining rows, if
+	// any.
+	var chunk, rows int
+	flush := func() error {
+		if rows == 0 {
+			return nil
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return err
+		}
+		name := fmt.Sprintf("export-%s-%d.csv", sp.spec.Name, chunk)
+		size := buf.Len()
+		if err := store.WriteFile(ctx, name, bytes.NewReader(buf.Bytes())); err != nil {
+			return err
+		}
+		row := sqlbase.EncDatumRow{
+			sqlbase.DatumToEncDatum(
+				sqlbase.ColumnType{Kind: sqlbase.ColumnType_STRING}, parser.NewDString(name),
+			),
+			sqlbase.DatumToEncDatum(
+				sqlbase.ColumnType{Kind: sqlbase.ColumnType_INT}, parser.NewDInt(parser.DInt(rows)),
+			),
+			sqlbase.DatumToEncDatum(
+				sqlbase.ColumnType{Kind: sqlbase.ColumnType_INT}, parser.NewDInt(parser.DInt(size)),
+			),
+		}
+		buf.Reset()
+		chunk++
+		rows = 0
+		if sp.output.Push(row, distsqlrun.ProducerMetadata{}) != distsqlrun.NeedMoreRows {
+			return errConsumerDone
+		}
+		return nil
+	}
+
+	input := distsqlrun.MakeNoMetadataRowSource(sp.input, sp.output)
+	var alloc sqlbase.DatumAlloc
+	var record []string
+	for {
+		row, err := input.NextRow()
+		if err != nil {
+			return err
+		}
+		if row == nil {
+			break
+		}
+		record = record[:0]
+		for i := range row {
+			if err := row[i].EnsureDecoded(&alloc); err != nil {
+				return err
+			}
+			record = append(record, formatCSVField(row[i].Datum, sp.spec.NullEncoding))
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+		rows++
+		if sp.spec.ChunkRows > 0 && int64(rows) >= sp.spec.ChunkRows {
+			if err := flush(); err != nil {
+				if err == errConsumerDone {
+					return nil
+				}
+				return err
+			}
+		}
+	}
+	if err := flush(); err != nil && err != errConsumerDone {
+		return err
+	}
+	return nil
+}
+
+// formatCSVField formats a datum as a CSV field that IMPORT parses back into
+// the same datum. Strings and bytes are written as-is, leaving their quoting
+// to the CSV writer.
+func formatCSVField(d parser.Datum, nullEncoding string) string {
+	switch t := d.(type) {
+	case *parser.DString:
+		return string(*t)
+	case *parser.DBytes:
+		return string(*t)
+	}
+	if d == parser.DNull {
+		return nullEncoding
+	}
+	return parser.AsStringWithFlags(d, parser.FmtBareStrings)
+}
+
+func init() {
+	sql.AddPlanHook(exportPlanHook)
+	distsqlrun.NewCSVWriterProcessor = newCSVWriterProcessor
+}
@@ -0,0 +1,120 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/pkg/ccl/LICENSE
+
+package sqlccl
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func TestExportCSV(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	const numAccounts, chunkRows = 1000, 100
+
+	_, dir, _, sqlDB, cleanupFn := backupRestoreTestSetup(t, multiNode, numAccounts)
+	defer cleanupFn()
+
+	// Some payloads need quoting, and one is NULL.
+	sqlDB.Exec(`UPDATE bench.bank SET payload = 'a,"b"' || e'\n' || 'c' WHERE id = 1`)
+	sqlDB.Exec(`UPDATE bench.bank SET payload = '' WHERE id = 2`)
+	sqlDB.Exec(`UPDATE bench.bank SET payload = NULL WHERE id = 3`)
+
+	rows := sqlDB.Query(fmt.Sprintf(
+		`EXPORT INTO CSV '%s' WITH delimiter = '|', nullas = '\N', chunk_rows = '%d' `+
+			`FROM SELECT * FROM bench.bank WHERE id >= 0`,
+		dir+"/export", chunkRows,
+	))
+	var files []string
+	var totalRows int
+	for rows.Next() {
+		var name string
+		var n, size int
+		if err := rows.Scan(&name, &n, &size); err != nil {
+			t.Fatal(err)
+		}
+		if n <= 0 || n > chunkRows || size <= 0 {
+			t.Fatalf("unexpected file %s: %d rows, %d bytes", name, n, size)
+		}
+		files = append(files, dir+"/export/"+name)
+		totalRows += n
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if totalRows != numAccounts {
+		t.Fatalf("expected %d rows in %d files, got %d", numAccounts, len(files), totalRows)
+	}
+
+	// Importing the files gives back the same table.
+	var jobID, importedRows, indexEntries, dataSize int64
+	var status string
+	var fractionCompleted float32
+	sqlDB.QueryRow(fmt.Sprintf(
+		`IMPORT TABLE bench.bank2 (id INT PRIMARY KEY, balance INT, payload STRING) `+
+			`CSV DATA (%s) WITH delimiter = '|', nullif = '\N', temp = '%s'`,
+		quotedList(files), dir+"/temp",
+	)).Scan(&jobID, &status, &fractionCompleted, &importedRows, &indexEntries, &dataSize)
+	if status != string(sql.JobStatusSucceeded) || importedRows != numAccounts {
+		t.Fatalf("expected a succeeded job with %d rows, got %s with %d", numAccounts, status, importedRows)
+	}
+	var count int
+	sqlDB.QueryRow(`SELECT COUNT(*) FROM bench.bank AS a JOIN bench.bank2 AS b ON a.id = b.id ` +
+		`WHERE a.balance = b.balance AND a.payload = b.payload`).Scan(&count)
+	if count != numAccounts-1 {
+		t.Fatalf("expected %d identical rows, got %d", numAccounts-1, count)
+	}
+	sqlDB.QueryRow(`SELECT COUNT(*) FROM bench.bank2 WHERE id = 3 AND payload IS NULL`).Scan(&count)
+	if count != 1 {
+		t.Fatalf("expected a NULL payload, got %d", count)
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		for _, tc := range []struct {
+			stmt string
+			err  string
+		}{
+			{`EXPORT INTO CSV 'nodelocal:///x' WITH delimiter = '||' FROM SELECT 1`,
+				"delimiter must be exactly one character"},
+			{`EXPORT INTO CSV 'nodelocal:///x' WITH chunk_rows = '0' FROM SELECT 1`,
+				"invalid chunk_rows value"},
+			{`EXPORT INTO CSV 'unknown:///x' FROM SELECT 1`, "unsupported storage scheme"},
+			{`EXPORT INTO CSV 'nodelocal:///x' FROM SELECT * FROM bench.missing`, "does not exist"},
+		} {
+			if _, err := sqlDB.DB.Exec(tc.stmt); !testutils.IsError(err, tc.err) {
+				t.Fatalf("%s: expected error %q, got %v", tc.stmt, tc.err, err)
+			}
+		}
+	})
+}
+
+func TestCSVWriterSpecFromOptionsChunkRows(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	for _, tc := range []struct {
+		opts     parser.KVOptions
+		expected int64
+	}{
+		{nil, exportChunkRowsDefault},
+		{parser.KVOptions{{Key: exportOptionChunkRows, Value: "10"}}, 10},
+	} {
+		spec, err := csvWriterSpecFromOptions(tc.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if spec.ChunkRows != tc.expected {
+			t.Errorf("%v: expected chunk_rows %d, got %d", tc.opts, tc.expected, spec.ChunkRows)
+		}
+	}
+}
@@ -0,0 +1,692 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/pkg/ccl/LICENSE
+
+package sqlccl
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/internal/client"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+)
+
+// importOptionIntoDB is the database the tables of a PGDUMP import are
+// created in. The schemas of the dumped tables are ignored.
+const importOptionIntoDB = "into_db"
+
+// pgCopyNull is the representation of NULL in the data of a COPY.
+const pgCopyNull = `\N`
+
+// pgIndexMethodRE matches the index method of the CREATE INDEX statements of
+// pg_dump, which always names it, even for the default one.
+var pgIndexMethodRE = regexp.MustCompile(`(?i)\s+USING\s+btree\s*\(`)
+
+// readPgDump reads the statements of the pg_dump plaintext dump r. It calls
+// stmtFn with each of them, along with their text, and copyFn with the fields
+// of each row of the data following a COPY ... FROM stdin statement. stmt is
+// nil for statements that can't be parsed and for psql meta-commands, such as
+// \connect.
+func readPgDump(
+	r io.Reader,
+	stmtFn func(stmt parser.Statement, sql string) error,
+	copyFn func(fields []string) error,
+) error {
+	br := bufio.NewReader(r)
+	var buf bytes.Buffer
+	copying := false
+	for lineNum := 1; ; lineNum++ {
+		line, err := br.ReadString('\n')
+		if err == io.EOF && line == "" {
+			break
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		var fnErr error
+		if copying {
+			line = strings.TrimSuffix(line, "\n")
+			if line == `\.` {
+				copying = false
+				continue
+			}
+			fnErr = copyFn(strings.Split(line, "\t"))
+		} else if strings.HasPrefix(line, `\`) && pgDumpStatementKind(buf.String()) == "" {
+			// Meta-commands end at the end of their line.
+			buf.Reset()
+			fnErr = stmtFn(nil, strings.TrimSpace(line))
+		} else {
+			buf.WriteString(line)
+			if err != io.EOF && !isEndOfStatement(parser.Traditional, buf.String()) {
+				continue
+			}
+			sql := buf.String()
+			buf.Reset()
+			stmts, err := parsePgDumpStatement(sql)
+			if err != nil {
+				if rewritten := pgIndexMethodRE.ReplaceAllString(sql, " ("); rewritten != sql {
+					stmts, err = parsePgDumpStatement(rewritten)
+				}
+			}
+			switch {
+			case err != nil:
+				fnErr = stmtFn(nil, sql)
+			case len(stmts) == 1:
+				fnErr = stmtFn(stmts[0], sql)
+				if c, ok := stmts[0].(*parser.CopyFrom); ok && c.Stdin {
+					copying = true
+				}
+			case len(stmts) > 1:
+				fnErr = errors.Errorf("expected 1 statement, found %d", len(stmts))
+			}
+		}
+		if fnErr == errConsumerDone {
+			return fnErr
+		}
+		if fnErr != nil {
+			return errors.Wrapf(fnErr, "line %d", lineNum)
+		}
+	}
+	if copying {
+		return errors.New("unexpected end of COPY data")
+	}
+	return nil
+}
+
+// parsePgDumpStatement parses a statement of a dump, which may also be empty
+// (e.g. only comments).
+func parsePgDumpStatement(sql string) (parser.StatementList, error) {
+	var p parser.Parser
+	return p.Parse(sql, parser.Traditional)
+}
+
+// pgDumpStatementKind describes the kind of the unparsed statement sql, for
+// the report of skipped statements, with its first keywords.
+func pgDumpStatementKind(sql string) string {
+	for _, line := range strings.Split(sql, "\n") {
+		words := strings.Fields(line)
+		if len(words) == 0 || strings.HasPrefix(words[0], "--") {
+			continue
+		}
+		if strings.HasPrefix(words[0], `\`) {
+			return words[0]
+		}
+		kind := strings.ToUpper(words[0])
+		switch kind {
+		case "ALTER", "COMMENT", "CREATE", "DROP":
+			if len(words) > 1 {
+				kind += " " + strings.ToUpper(words[1])
+			}
+		}
+		return kind
+	}
+	return ""
+}
+
+// pgDumpTableName returns the name of a dumped table, without its schema.
+func pgDumpTableName(table *parser.NormalizableTableName) (string, error) {
+	tn, err := table.Normalize()
+	if err != nil {
+		return "", err
+	}
+	return tn.Table(), nil
+}
+
+// pgDumpFK is a foreign key of a dump.
+type pgDumpFK struct {
+	table string
+	def   *parser.ForeignKeyConstraintTableDef
+}
+
+// pgDumpSchema is the schema of the tables of a dump.
+type pgDumpSchema struct {
+	// creates are the CREATE TABLE statements of the tables, in the order of
+	// the dump, with the constraints and indexes added by later statements.
+	creates []*parser.CreateTable
+	names   []string
+	byName  map[string]*parser.CreateTable
+	// fks are the foreign keys of the tables, which are only added once their
+	// data is loaded.
+	fks []pgDumpFK
+	// skipped counts the statements that were ignored, by kind.
+	skipped map[string]int
+}
+
+// readPgDumpSchema reads the schema of the tables of the dump at uri.
+//
+// pg_dump only creates the indexes and constraints of a table after its data,
+// and constraints such as the primary key determine how the data is encoded,
+// so the whole dump is read before any of it is converted. The tables are
+// loaded with only their primary indexes; their secondary indexes are
+// backfilled and their foreign keys validated once the data is ingested.
+func readPgDumpSchema(ctx context.Context, uri string) (*pgDumpSchema, error) {
+	schema := &pgDumpSchema{
+		byName:  make(map[string]*parser.CreateTable),
+		skipped: make(map[string]int),
+	}
+	err := readImportFile(ctx, uri, func(r io.Reader) error {
+		return readPgDump(r, schema.addStatement, func([]string) error { return nil })
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s", uri)
+	}
+	if len(schema.creates) == 0 {
+		return nil, errors.New("no tables to import")
+	}
+	return schema, nil
+}
+
+func (s *pgDumpSchema) lookup(table *parser.NormalizableTableName) (*parser.CreateTable, error) {
+	name, err := pgDumpTableName(table)
+	if err != nil {
+		return nil, err
+	}
+	create, ok := s.byName[name]
+	if !ok {
+		return nil, errors.Errorf("unknown table %q", name)
+	}
+	return create, nil
+}
+
+func (s *pgDumpSchema) addStatement(stmt parser.Statement, sql string) error {
+	switch stmt := stmt.(type) {
+	case nil:
+		kind := pgDumpStatementKind(sql)
+		if kind == "CREATE TABLE" {
+			// The data of the table couldn't be imported.
+			_, err := parser.ParseOne(sql, parser.Traditional)
+			return errors.Wrap(err, "unsupported CREATE TABLE")
+		}
+		s.skipped[kind]++
+
+	case *parser.CreateTable:
+		if stmt.As() {
+			s.skipped["CREATE TABLE AS"]++
+			return nil
+		}
+		name, err := pgDumpTableName(&stmt.Table)
+		if err != nil {
+			return err
+		}
+		if _, ok := s.byName[name]; ok {
+			return errors.Errorf("duplicate CREATE TABLE for %q", name)
+		}
+		var defs parser.TableDefs
+		for _, def := range stmt.Defs {
+			switch def := def.(type) {
+			case *parser.ForeignKeyConstraintTableDef:
+				s.fks = append(s.fks, pgDumpFK{table: name, def: def})
+				continue
+			case *parser.ColumnTableDef:
+				if def.References.Table.TableNameReference != nil {
+					fk := &parser.ForeignKeyConstraintTableDef{
+						Name:     def.References.ConstraintName,
+						Table:    def.References.Table,
+						FromCols: parser.NameList{def.Name},
+					}
+					if def.References.Col != "" {
+						fk.ToCols = parser.NameList{def.References.Col}
+					}
+					s.fks = append(s.fks, pgDumpFK{table: name, def: fk})
+					def.References.Table = parser.NormalizableTableName{}
+					def.References.Col = ""
+					def.References.ConstraintName = ""
+				}
+			}
+			defs = append(defs, def)
+		}
+		stmt.Defs = defs
+		s.creates = append(s.creates, stmt)
+		s.names = append(s.names, name)
+		s.byName[name] = stmt
+
+	case *parser.AlterTable:
+		create, err := s.lookup(&stmt.Table)
+		if err != nil {
+			return err
+		}
+		for _, cmd := range stmt.Cmds {
+			c, ok := cmd.(*parser.AlterTableAddConstraint)
+			if !ok {
+				s.skipped[stmt.StatementTag()]++
+				continue
+			}
+			if fk, ok := c.ConstraintDef.(*parser.ForeignKeyConstraintTableDef); ok {
+				name, _ := pgDumpTableName(&stmt.Table)
+				s.fks = append(s.fks, pgDumpFK{table: name, def: fk})
+			} else {
+				create.Defs = append(create.Defs, c.ConstraintDef)
+			}
+		}
+
+	case *parser.CreateIndex:
+		create, err := s.lookup(&stmt.Table)
+		if err != nil {
+			return err
+		}
+		idx := parser.IndexTableDef{
+			Name:       stmt.Name,
+			Columns:    stmt.Columns,
+			Storing:    stmt.Storing,
+			Interleave: stmt.Interleave,
+		}
+		if stmt.Unique {
+			create.Defs = append(create.Defs, &parser.UniqueConstraintTableDef{IndexTableDef: idx})
+		} else {
+			create.Defs = append(create.Defs, &idx)
+		}
+
+	case *parser.CopyFrom:
+		if _, err := s.lookup(&stmt.Table); err != nil {
+			return errors.Wrap(err, "COPY")
+		}
+
+	case *parser.Insert:
+		table, ok := stmt.Table.(*parser.NormalizableTableName)
+		if !ok {
+			return errors.Errorf("unsupported INSERT target %s", stmt.Table)
+		}
+		if _, err := s.lookup(table); err != nil {
+			return errors.Wrap(err, "INSERT")
+		}
+
+	default:
+		s.skipped[stmt.StatementTag()]++
+	}
+	return nil
+}
+
+// skippedReport summarizes the statements of the dump that were skipped.
+func (s *pgDumpSchema) skippedReport() string {
+	kinds := make([]string, 0, len(s.skipped))
+	for kind := range s.skipped {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for i, kind := range kinds {
+		kinds[i] = fmt.Sprintf("%s: %d", kind, s.skipped[kind])
+	}
+	return strings.Join(kinds, ", ")
+}
+
+// makePgDumpTableDescs creates, in the database given by the options of the
+// import, the offline descriptors and the namespace entries of the tables of
+// the dump. It returns the descriptors the tables are loaded with, which only
+// have their primary indexes, and their complete descriptors, which also have
+// their secondary indexes and foreign keys.
+func makePgDumpTableDescs(
+	ctx context.Context, p sql.PlanHookState, schema *pgDumpSchema, opts parser.KVOptions,
+) ([]sqlbase.TableDescriptor, []sqlbase.TableDescriptor, error) {
+	for _, opt := range []string{importOptionDelimiter, importOptionNullIf, importOptionSkip} {
+		if _, ok := opts.Get(opt); ok {
+			return nil, nil, errors.Errorf("%s is not supported by PGDUMP", opt)
+		}
+	}
+	dbName, ok := opts.Get(importOptionIntoDB)
+	if !ok || dbName == "" {
+		return nil, nil, errors.Errorf("must provide the database to import into with %s",
+			importOptionIntoDB)
+	}
+	qualify := func(name string) parser.NormalizableTableName {
+		return parser.NormalizableTableName{TableNameReference: &parser.TableName{
+			DatabaseName: parser.Name(dbName),
+			TableName:    parser.Name(name),
+		}}
+	}
+
+	tables := make([]sqlbase.TableDescriptor, len(schema.creates))
+	load := make([]sqlbase.TableDescriptor, len(schema.creates))
+	err := p.ExecCfg().DB.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
+		dbDesc, err := getImportDatabase(ctx, txn, p, dbName)
+		if err != nil {
+			return err
+		}
+		byName := make(map[string]*sqlbase.TableDescriptor, len(tables))
+		for i, create := range schema.creates {
+			create.Table = qualify(schema.names[i])
+			id, err := sql.GenerateUniqueDescID(ctx, txn)
+			if err != nil {
+				return err
+			}
+			affected := make(map[sqlbase.ID]*sqlbase.TableDescriptor)
+			tables[i], err = sql.MakeTableDesc(
+				ctx, txn, sql.NilVirtualTabler, nil, create, dbDesc.ID, id, dbDesc.GetPrivileges(),
+				affected, dbDesc.Name,
+			)
+			if err != nil {
+				return errors.Wrapf(err, "table %q", schema.names[i])
+			}
+			if tables[i].IsInterleaved() {
+				return errors.New("IMPORT does not support interleaved tables")
+			}
+			byName[schema.names[i]] = &tables[i]
+		}
+
+		// The foreign keys are validated once the data is loaded. Adding them
+		// may add indexes to the tables, which are built along with the other
+		// secondary indexes.
+		lookup := func(tn *parser.TableName) (*sqlbase.TableDescriptor, error) {
+			return byName[tn.Table()], nil
+		}
+		for _, fk := range schema.fks {
+			target, err := pgDumpTableName(&fk.def.Table)
+			if err != nil {
+				return err
+			}
+			fk.def.Table = qualify(target)
+			backrefs := make(map[sqlbase.ID]*sqlbase.TableDescriptor)
+			if err := sql.ResolveFK(
+				byName[fk.table], fk.def, lookup, backrefs, sqlbase.ConstraintValidity_Unvalidated,
+			); err != nil {
+				return errors.Wrapf(err, "table %q", fk.table)
+			}
+		}
+
+		loadDescs := make([]*sqlbase.TableDescriptor, len(tables))
+		for i := range tables {
+			if err := tables[i].ValidateTable(); err != nil {
+				return err
+			}
+			load[i] = *protoutil.Clone(&tables[i]).(*sqlbase.TableDescriptor)
+			clearForeignKeys(&load[i])
+			load[i].Indexes = nil
+			load[i].State = sqlbase.TableDescriptor_OFFLINE
+			loadDescs[i] = &load[i]
+		}
+		return writeImportTableDescs(ctx, txn, loadDescs)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	log.Infof(ctx, "importing %d tables, skipped statements: %s", len(tables), schema.skippedReport())
+	return load, tables, nil
+}
+
+// clearForeignKeys removes the foreign keys of the indexes of tableDesc, and
+// the references to them.
+func clearForeignKeys(tableDesc *sqlbase.TableDescriptor) {
+	tableDesc.PrimaryIndex.ForeignKey = sqlbase.ForeignKeyReference{}
+	tableDesc.PrimaryIndex.ReferencedBy = nil
+	for i := range tableDesc.Indexes {
+		tableDesc.Indexes[i].ForeignKey = sqlbase.ForeignKeyReference{}
+		tableDesc.Indexes[i].ReferencedBy = nil
+	}
+}
+
+// withIndexMutations returns a copy of the complete descriptor of a table of a
+// dump, without its foreign keys and with its secondary indexes turned into
+// mutations, for the schema changer to backfill. The table is published with
+// it, as it was loaded with only its primary index.
+func withIndexMutations(tableDesc *sqlbase.TableDescriptor) (sqlbase.TableDescriptor, error) {
+	desc := *protoutil.Clone(tableDesc).(*sqlbase.TableDescriptor)
+	clearForeignKeys(&desc)
+	indexes := desc.Indexes
+	desc.Indexes = nil
+	if len(indexes) == 0 {
+		return desc, nil
+	}
+	for _, idx := range indexes {
+		desc.AddIndexMutation(idx, sqlbase.DescriptorMutation_ADD)
+	}
+	_, err := desc.FinalizeMutation()
+	return desc, err
+}
+
+// buildPgDumpIndexes runs the schema changes which backfill the secondary
+// indexes of the published tables of a dump. A unique index for which the
+// data has duplicates fails the import.
+func buildPgDumpIndexes(
+	ctx context.Context, execCfg *sql.ExecutorConfig, tables []sqlbase.TableDescriptor,
+) error {
+	for i := range tables {
+		var mutationID sqlbase.MutationID
+		if err := execCfg.DB.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
+			existing, err := sqlbase.GetTableDescFromID(ctx, txn, tables[i].ID)
+			if err != nil {
+				return err
+			}
+			mutationID = sqlbase.InvalidMutationID
+			if len(existing.Mutations) > 0 {
+				mutationID = existing.Mutations[0].MutationID
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		if mutationID == sqlbase.InvalidMutationID {
+			continue
+		}
+		if err := sql.RunSchemaChange(ctx, execCfg, tables[i].ID, mutationID); err != nil {
+			return errors.Wrapf(err, "building the indexes of table %q", tables[i].Name)
+		}
+	}
+	return nil
+}
+
+// addPgDumpForeignKeys adds the foreign keys of the tables of a dump, once
+// their secondary indexes are built. The foreign keys are validated against
+// the imported data and marked as such; a row without a match fails the
+// import.
+func addPgDumpForeignKeys(
+	ctx context.Context, execCfg *sql.ExecutorConfig, tables []sqlbase.TableDescriptor,
+) error {
+	ie := sql.InternalExecutor{LeaseManager: execCfg.LeaseManager}
+	return execCfg.DB.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
+		existing := make([]*sqlbase.TableDescriptor, len(tables))
+		for i := range tables {
+			var err error
+			if existing[i], err = sqlbase.GetTableDescFromID(ctx, txn, tables[i].ID); err != nil {
+				return err
+			}
+			if len(existing[i].Mutations) > 0 {
+				return errors.Errorf("the indexes of table %q were not built", existing[i].Name)
+			}
+			for _, idx := range tables[i].AllNonDropIndexes() {
+				target, err := existing[i].FindIndexByID(idx.ID)
+				if err != nil {
+					return errors.Wrapf(err, "index %q of table %q was not built", idx.Name, existing[i].Name)
+				}
+				target.ForeignKey = idx.ForeignKey
+				target.ReferencedBy = idx.ReferencedBy
+			}
+		}
+
+		b := txn.NewBatch()
+		for _, tableDesc := range existing {
+			for _, idx := range tableDesc.AllNonDropIndexes() {
+				if !idx.ForeignKey.IsSet() {
+					continue
+				}
+				target, err := tableDesc.FindIndexByID(idx.ID)
+				if err != nil {
+					return err
+				}
+				if err := ie.ValidateForeignKeyInTransaction(ctx, txn, tableDesc, target); err != nil {
+					return err
+				}
+				target.ForeignKey.Validity = sqlbase.ConstraintValidity_Validated
+			}
+			tableDesc.Version++
+			b.Put(tableDesc.GetDescMetadataKey(), sqlbase.WrapDescriptor(tableDesc))
+		}
+		if err := txn.SetSystemConfigTrigger(); err != nil {
+			return err
+		}
+		return txn.Run(ctx, b)
+	})
+}
+
+// readPgDumpFile converts the data of the COPY and INSERT statements of the
+// pg_dump file at uri into the KVs of the tables of the spec.
+func (cp *readCSV) readPgDumpFile(
+	ctx context.Context, uri string, evalCtx parser.EvalContext, b inserter,
+) error {
+	tables := make(map[string]*sqlbase.TableDescriptor, len(cp.spec.Tables))
+	for i := range cp.spec.Tables {
+		tables[cp.spec.Tables[i].Name] = &cp.spec.Tables[i]
+	}
+	// The converters are cached by table and columns, as dumps made with
+	// --inserts have an INSERT statement per row.
+	converters := make(map[string]*rowConverter)
+	converter := func(
+		table *parser.NormalizableTableName, names parser.UnresolvedNames,
+	) (*rowConverter, error) {
+		name, err := pgDumpTableName(table)
+		if err != nil {
+			return nil, err
+		}
+		key := name + parser.AsString(names)
+		if conv, ok := converters[key]; ok {
+			return conv, nil
+		}
+		tableDesc, ok := tables[name]
+		if !ok {
+			return nil, errors.Errorf("unknown table %q", name)
+		}
+		cols := tableDesc.VisibleColumns()
+		if names != nil {
+			cols = make([]sqlbase.ColumnDescriptor, len(names))
+			for i, n := range names {
+				c, err := n.NormalizeUnqualifiedColumnItem()
+				if err != nil {
+					return nil, err
+				}
+				if cols[i], err = tableDesc.FindActiveColumnByName(c.ColumnName); err != nil {
+					return nil, err
+				}
+			}
+		}
+		conv, err := newRowConverter(tableDesc, cols, evalCtx)
+		if err != nil {
+			return nil, err
+		}
+		converters[key] = conv
+		return conv, nil
+	}
+
+	var copyConv *rowConverter
+	stmtFn := func(stmt parser.Statement, _ string) error {
+		var err error
+		switch stmt := stmt.(type) {
+		case *parser.CopyFrom:
+			copyConv, err = converter(&stmt.Table, stmt.Columns)
+		case *parser.Insert:
+			err = cp.convertPgInsert(ctx, stmt, converter, b)
+		}
+		return err
+	}
+	copyFn := func(fields []string) error {
+		if len(fields) != len(copyConv.cols) {
+			return errors.Errorf("expected %d fields, got %d", len(copyConv.cols), len(fields))
+		}
+		row := make(parser.Datums, len(fields))
+		for i, field := range fields {
+			if field == pgCopyNull {
+				row[i] = parser.DNull
+				continue
+			}
+			var err error
+			if row[i], err = parsePgCopyField(copyConv.cols[i].Type.ToDatumType(), field); err != nil {
+				return errors.Wrapf(err, "column %q", copyConv.cols[i].Name)
+			}
+		}
+		if err := copyConv.convert(ctx, row, b); err != nil {
+			return err
+		}
+		return cp.pushErr
+	}
+	return readImportFile(ctx, uri, func(r io.Reader) error {
+		if err := readPgDump(r, stmtFn, copyFn); err != nil {
+			if err == errConsumerDone {
+				return err
+			}
+			return errors.Wrapf(err, "%s", uri)
+		}
+		return nil
+	})
+}
+
+// convertPgInsert converts the rows of an INSERT statement of a dump.
+func (cp *readCSV) convertPgInsert(
+	ctx context.Context,
+	stmt *parser.Insert,
+	converter func(*parser.NormalizableTableName, parser.UnresolvedNames) (*rowConverter, error),
+	b inserter,
+) error {
+	table, ok := stmt.Table.(*parser.NormalizableTableName)
+	if !ok {
+		return errors.Errorf("unsupported INSERT target %s", stmt.Table)
+	}
+	if stmt.OnConflict != nil || parser.HasReturningClause(stmt.Returning) {
+		return errors.Errorf("unsupported INSERT: %s", stmt)
+	}
+	values, ok := stmt.Rows.Select.(*parser.ValuesClause)
+	if !ok || stmt.Rows.Limit != nil || stmt.Rows.OrderBy != nil {
+		return errors.Errorf("unsupported INSERT: %s", stmt)
+	}
+	conv, err := converter(table, stmt.Columns)
+	if err != nil {
+		return err
+	}
+	for _, tuple := range values.Tuples {
+		if len(tuple.Exprs) != len(conv.cols) {
+			return errors.Errorf("expected %d values, got %d", len(conv.cols), len(tuple.Exprs))
+		}
+		row := make(parser.Datums, len(tuple.Exprs))
+		for i, expr := range tuple.Exprs {
+			typed, err := parser.TypeCheckAndRequire(
+				expr, nil, conv.cols[i].Type.ToDatumType(), "INSERT",
+			)
+			if err != nil {
+				return errors.Wrapf(err, "column %q", conv.cols[i].Name)
+			}
+			if row[i], err = typed.Eval(&conv.evalCtx); err != nil {
+				return errors.Wrapf(err, "column %q", conv.cols[i].Name)
+			}
+		}
+		if err := conv.convert(ctx, row, b); err != nil {
+			return err
+		}
+		if cp.pushErr != nil {
+			return cp.pushErr
+		}
+	}
+	return nil
+}
+
+// parsePgCopyField parses a field of the data of a COPY as a datum of the
+// given type.
+func parsePgCopyField(t parser.Type, field string) (parser.Datum, error) {
+	s, err := sql.DecodeCopy(field)
+	if err != nil {
+		return nil, err
+	}
+	// Postgres outputs bytea in its hex format by default.
+	if t == parser.TypeBytes && strings.HasPrefix(s, `\x`) {
+		b, err := hex.DecodeString(s[2:])
+		if err != nil {
+			return nil, err
+		}
+		return parser.NewDBytes(parser.DBytes(b)), nil
+	}
+	return parseStringAs(t, s)
+}
@@ -0,0 +1,234 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/pkg/ccl/LICENSE
+
+package sqlccl
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/internal/client"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+// testPgDump mimics the output of pg_dump, with the data of its tables in
+// COPY and INSERT statements.
+const testPgDump = `--
+-- PostgreSQL database dump
+--
+
+SET statement_timeout = 0;
+SET client_encoding = 'UTF8';
+SET standard_conforming_strings = on;
+SELECT pg_catalog.set_config('search_path', '', false);
+
+\connect shop
+
+CREATE EXTENSION IF NOT EXISTS plpgsql WITH SCHEMA pg_catalog;
+COMMENT ON EXTENSION plpgsql IS 'PL/pgSQL procedural language';
+
+--
+-- Name: customers; Type: TABLE; Schema: public; Owner: admin
+--
+
+CREATE TABLE public.customers (
+    id integer NOT NULL,
+    name character varying(100) NOT NULL,
+    email text
+);
+
+ALTER TABLE public.customers OWNER TO admin;
+
+CREATE SEQUENCE public.customers_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    CACHE 1;
+
+CREATE TABLE public.orders (
+    id integer NOT NULL,
+    customer_id integer,
+    total numeric(10,2),
+    paid boolean,
+    note bytea
+);
+
+ALTER TABLE ONLY public.customers ALTER COLUMN id SET DEFAULT nextval('public.customers_id_seq'::regclass);
+
+--
+-- Data for Name: customers; Type: TABLE DATA; Schema: public; Owner: admin
+--
+
+COPY public.customers (id, name, email) FROM stdin;
+1	Ann	ann@example.com
+2	Bob	\N
+3	Tab\tby	x;y
+\.
+
+COPY public.orders (id, customer_id, total, paid, note) FROM stdin;
+10	1	12.50	t	\\x0102
+11	2	3.00	f	\N
+\.
+
+INSERT INTO public.orders (id, customer_id, total, paid, note) VALUES (12, 3, -1.25, NULL, NULL);
+
+SELECT pg_catalog.setval('public.customers_id_seq', 3, true);
+
+ALTER TABLE ONLY public.customers
+    ADD CONSTRAINT customers_pkey PRIMARY KEY (id);
+
+ALTER TABLE ONLY public.orders
+    ADD CONSTRAINT orders_pkey PRIMARY KEY (id);
+
+CREATE UNIQUE INDEX customers_email_idx ON public.customers USING btree (email);
+
+CREATE INDEX orders_total_idx ON public.orders USING btree (total);
+
+ALTER TABLE ONLY public.orders
+    ADD CONSTRAINT orders_customer_id_fkey FOREIGN KEY (customer_id) REFERENCES public.customers(id);
+
+GRANT ALL ON SCHEMA public TO PUBLIC;
+`
+
+func TestImportPgDump(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	_, dir, tc, sqlDB, cleanupFn := backupRestoreTestSetup(t, multiNode, 0)
+	defer cleanupFn()
+	kvDB := tc.Server(0).KVClient().(*client.DB)
+
+	uri := writeCSVFiles(t, dir, map[string]string{"dump.sql": testPgDump})[0]
+	sqlDB.Exec(`CREATE DATABASE shop`)
+
+	var jobID, rows, indexEntries, dataSize int64
+	var status, skipped string
+	var fractionCompleted float32
+	sqlDB.QueryRow(fmt.Sprintf(
+		`IMPORT PGDUMP '%s' WITH into_db = 'shop', temp = '%s'`, uri, dir+"/temp-pgdump",
+	)).Scan(&jobID, &status, &fractionCompleted, &rows, &indexEntries, &dataSize, &skipped)
+
+	if status != string(sql.JobStatusSucceeded) || fractionCompleted != 1 {
+		t.Fatalf("expected a succeeded job, got %s (%f)", status, fractionCompleted)
+	}
+	// The secondary indexes, including the one added for the foreign key of
+	// the orders, are backfilled once the data is loaded rather than converted
+	// along with it.
+	if rows != 6 || indexEntries != 0 {
+		t.Fatalf("expected 6 rows and 0 index entries, got %d and %d", rows, indexEntries)
+	}
+	const expectedSkipped = `ALTER TABLE: 2, COMMENT ON: 1, CREATE EXTENSION: 1, ` +
+		`CREATE SEQUENCE: 1, GRANT: 1, SELECT: 2, SET: 3, \connect: 1`
+	if skipped != expectedSkipped {
+		t.Fatalf("expected skipped statements %q, got %q", expectedSkipped, skipped)
+	}
+
+	var name, email string
+	sqlDB.QueryRow(`SELECT name, email FROM shop.customers WHERE id = 3`).Scan(&name, &email)
+	if name != "Tab\tby" || email != "x;y" {
+		t.Fatalf("unexpected customer: %q %q", name, email)
+	}
+	var count int
+	sqlDB.QueryRow(`SELECT COUNT(*) FROM shop.customers WHERE email IS NULL`).Scan(&count)
+	if count != 1 {
+		t.Fatalf("expected 1 customer without email, got %d", count)
+	}
+	var note []byte
+	sqlDB.QueryRow(`SELECT note FROM shop.orders WHERE id = 10`).Scan(&note)
+	if !bytes.Equal(note, []byte{1, 2}) {
+		t.Fatalf("unexpected note: %x", note)
+	}
+	sqlDB.QueryRow(`SELECT COUNT(*) FROM shop.orders@orders_total_idx WHERE total < 0`).Scan(&count)
+	if count != 1 {
+		t.Fatalf("expected 1 order with a negative total, got %d", count)
+	}
+	sqlDB.QueryRow(
+		`SELECT COUNT(*) FROM shop.customers@customers_email_idx WHERE email = 'ann@example.com'`,
+	).Scan(&count)
+	if count != 1 {
+		t.Fatalf("expected 1 customer in index, got %d", count)
+	}
+
+	// The foreign key is validated and added once the data is loaded.
+	ordersDesc := sqlbase.GetTableDescriptor(kvDB, "shop", "orders")
+	for _, idx := range ordersDesc.AllNonDropIndexes() {
+		if idx.ForeignKey.IsSet() && idx.ForeignKey.Validity != sqlbase.ConstraintValidity_Validated {
+			t.Fatalf("expected foreign key %q to be validated", idx.ForeignKey.Name)
+		}
+	}
+	if _, err := sqlDB.DB.Exec(
+		`INSERT INTO shop.orders (id, customer_id) VALUES (13, 99)`,
+	); !testutils.IsError(err, "foreign key violation") {
+		t.Fatalf("expected foreign key violation, got %v", err)
+	}
+	if _, err := sqlDB.DB.Exec(`DELETE FROM shop.customers WHERE id = 1`); !testutils.IsError(
+		err, "foreign key violation",
+	) {
+		t.Fatalf("expected foreign key violation, got %v", err)
+	}
+
+	var jobType string
+	sqlDB.QueryRow(
+		`SELECT type, status FROM crdb_internal.jobs WHERE id = $1`, jobID,
+	).Scan(&jobType, &status)
+	if jobType != sql.JobTypeImport || status != string(sql.JobStatusSucceeded) {
+		t.Fatalf("unexpected job %d: %s %s", jobID, jobType, status)
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		for i, tc := range []struct {
+			dump string
+			opts string
+			err  string
+		}{
+			{testPgDump, ``, "must provide the database"},
+			{testPgDump, `, into_db = 'shop'`, `relation "customers" already exists`},
+			{testPgDump, `, into_db = 'shop', delimiter = '|'`, "not supported by PGDUMP"},
+			{`SET search_path = public;`, `, into_db = 'shop'`, "no tables to import"},
+			{`CREATE TABLE t (a INT PRIMARY KEY);
+COPY t (a) FROM stdin;
+1
+1
+\.
+`, `, into_db = 'shop'`, "duplicate key"},
+			{`CREATE TABLE p (a INT PRIMARY KEY);
+CREATE TABLE c (a INT PRIMARY KEY, p INT);
+COPY c (a, p) FROM stdin;
+1	2
+\.
+ALTER TABLE ONLY c ADD CONSTRAINT c_p_fkey FOREIGN KEY (p) REFERENCES p(a);
+`, `, into_db = 'shop'`, "foreign key violation"},
+			{`CREATE TABLE v (a INT PRIMARY KEY, b INT);
+COPY v (a, b) FROM stdin;
+1	1
+2	1
+\.
+CREATE UNIQUE INDEX v_b_key ON v USING btree (b);
+`, `, into_db = 'shop'`, "duplicate key value"},
+			{`CREATE TABLE t (a INT PRIMARY KEY);
+COPY u (a) FROM stdin;
+\.
+`, `, into_db = 'shop'`, `unknown table "u"`},
+		} {
+			uri := writeCSVFiles(t, dir, map[string]string{fmt.Sprintf("bad-%d.sql", i): tc.dump})[0]
+			_, err := sqlDB.DB.Exec(fmt.Sprintf(
+				`IMPORT PGDUMP '%s' WITH temp = '%s'%s`,
+				uri, fmt.Sprintf("%s/temp-pgdump-bad-%d", dir, i), tc.opts,
+			))
+			if !testutils.IsError(err, tc.err) {
+				t.Fatalf("%d: expected error %q, got %v", i, tc.err, err)
+			}
+		}
+		// The tables of a failed import are dropped.
+		if _, err := sqlDB.DB.Exec(`SELECT * FROM shop.t`); !testutils.IsError(err, "does not exist") {
+			t.Fatalf("expected table to not exist, got %v", err)
+		}
+	})
+}
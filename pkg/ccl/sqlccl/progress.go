@@ -35,6 +35,13 @@ type jobProgressLogger struct {
 	jobLogger   *sql.JobLogger
 	totalChunks int
 
+	// These fields may optionally be initialized. priorChunks is the number of
+	// chunks completed before the job was resumed. checkpoint, if set, is
+	// called whenever progress is logged and returns details from which the
+	// job can be resumed, which are then saved.
+	priorChunks int
+	checkpoint  func() (interface{}, error)
+
 	// The remaining fields are for internal use only.
 	mu struct {
 		syncutil.Mutex
@@ -47,7 +54,7 @@ type jobProgressLogger struct {
 func (jpl *jobProgressLogger) chunkFinished(ctx context.Context) error {
 	jpl.mu.Lock()
 	jpl.mu.completedChunks++
-	fraction := float32(jpl.mu.completedChunks) / float32(jpl.totalChunks)
+	fraction := float32(jpl.priorChunks+jpl.mu.completedChunks) / float32(jpl.totalChunks)
 	shouldLogProgress := fraction-jpl.mu.lastReportedFraction > progressFractionThreshold ||
 		jpl.mu.lastReportedAt.Add(progressTimeThreshold).Before(timeutil.Now())
 	if shouldLogProgress {
@@ -56,8 +63,18 @@ func (jpl *jobProgressLogger) chunkFinished(ctx context.Context) error {
 	}
 	jpl.mu.Unlock()
 
-	if shouldLogProgress {
-		return jpl.jobLogger.Progressed(ctx, fraction)
+	if !shouldLogProgress {
+		return nil
+	}
+	if err := jpl.jobLogger.Progressed(ctx, fraction); err != nil {
+		return err
+	}
+	if jpl.checkpoint == nil {
+		return nil
+	}
+	details, err := jpl.checkpoint()
+	if err != nil {
+		return err
 	}
-	return nil
+	return jpl.jobLogger.SetDetails(ctx, details)
 }
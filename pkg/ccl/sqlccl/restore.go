@@ -9,6 +9,12 @@
 package sqlccl
 
 import (
+	"bytes"
+	"crypto/sha512"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
 	"github.com/pkg/errors"
 	"golang.org/x/net/context"
 	"golang.org/x/sync/errgroup"
@@ -27,23 +33,34 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/interval"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
 	"github.com/cockroachdb/cockroach/pkg/util/tracing"
 )
 
 const (
 	restoreOptIntoDB         = "into_db"
 	restoreOptSkipMissingFKs = "skip_missing_foreign_keys"
+	restoreOptVerifyOnly     = "verify_only"
 )
 
+// restoreTempSystemDB is the name of the database into which a full cluster
+// restore restores the backed-up system tables before copying their contents
+// into the real ones.
+const restoreTempSystemDB = "crdb_temp_system"
+
 // Import loads some data in sstables into an empty range. Only the keys between
 // startKey and endKey are loaded. Every row's key is rewritten to be for
-// newTableID.
+// newTableID. If endTime is set, the data is loaded as of that time. If
+// encryption is set, it is used to decrypt the files.
 func Import(
 	ctx context.Context,
 	db client.DB,
 	startKey, endKey roachpb.Key,
 	files []roachpb.ImportRequest_File,
 	kr storageccl.KeyRewriter,
+	endTime hlc.Timestamp,
+	encryption *roachpb.FileEncryptionOptions,
 ) error {
 	var newStartKey, newEndKey roachpb.Key
 	{
@@ -76,17 +93,21 @@ func Import(
 		},
 		Files:       files,
 		KeyRewrites: kr,
+		EndTime:     endTime,
+		Encryption:  encryption,
 	}
 	b := &client.Batch{}
 	b.AddRawRequest(req)
 	return db.Run(ctx, b)
 }
 
-func loadBackupDescs(ctx context.Context, uris []string) ([]BackupDescriptor, error) {
+func loadBackupDescs(
+	ctx context.Context, uris []string, encryption *roachpb.FileEncryptionOptions,
+) ([]BackupDescriptor, error) {
 	backupDescs := make([]BackupDescriptor, len(uris))
 
 	for i, uri := range uris {
-		desc, err := readBackupDescriptor(ctx, uri)
+		desc, err := readBackupDescriptor(ctx, uri, encryption)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to read backup descriptor")
 		}
@@ -98,6 +119,69 @@ func loadBackupDescs(ctx context.Context, uris []string) ([]BackupDescriptor, er
 	return backupDescs, nil
 }
 
+// backupsCoveringTime returns the backups of the given chain that are needed to
+// restore as of endTime, which are the ones that start before it. It returns an
+// error if the chain doesn't cover endTime, or if endTime falls inside the time
+// range of a backup created without revision history.
+func backupsCoveringTime(
+	backupDescs []BackupDescriptor, endTime hlc.Timestamp,
+) ([]BackupDescriptor, error) {
+	if endTime == (hlc.Timestamp{}) {
+		return backupDescs, nil
+	}
+	var n int
+	for n < len(backupDescs) && backupDescs[n].StartTime.Less(endTime) {
+		n++
+	}
+	if n == 0 {
+		return nil, errors.Errorf("invalid RESTORE timestamp: backups start at %s",
+			backupDescs[0].StartTime)
+	}
+	last := backupDescs[n-1]
+	if last.EndTime.Less(endTime) {
+		return nil, errors.Errorf("invalid RESTORE timestamp: backups only cover up to %s",
+			last.EndTime)
+	}
+	if endTime != last.EndTime && !last.RevisionHistory {
+		return nil, errors.Errorf(
+			"invalid RESTORE timestamp: restoring to a time other than the end time of a backup "+
+				"requires the backup covering it to be created with the %q option",
+			backupOptRevisionHistory)
+	}
+	return backupDescs[:n], nil
+}
+
+// loadSQLDescsFromBackupsAtTime returns the SQL descriptors as of endTime,
+// which must be covered by the last of the given backups. If endTime is empty,
+// the descriptors as of the end time of the last backup are returned.
+func loadSQLDescsFromBackupsAtTime(
+	backupDescs []BackupDescriptor, endTime hlc.Timestamp,
+) []sqlbase.Descriptor {
+	lastBackupDesc := backupDescs[len(backupDescs)-1]
+	if endTime == (hlc.Timestamp{}) || endTime == lastBackupDesc.EndTime ||
+		len(lastBackupDesc.DescriptorChanges) == 0 {
+		return lastBackupDesc.Descriptors
+	}
+
+	byID := make(map[sqlbase.ID]*sqlbase.Descriptor)
+	for _, rev := range lastBackupDesc.DescriptorChanges {
+		if endTime.Less(rev.Time) {
+			break
+		}
+		if rev.Desc == nil {
+			delete(byID, rev.ID)
+		} else {
+			byID[rev.ID] = rev.Desc
+		}
+	}
+	sqlDescs := make([]sqlbase.Descriptor, 0, len(byID))
+	for _, desc := range byID {
+		sqlDescs = append(sqlDescs, *desc)
+	}
+	sort.Slice(sqlDescs, func(i, j int) bool { return sqlDescs[i].GetID() < sqlDescs[j].GetID() })
+	return sqlDescs
+}
+
 func reassignParentIDs(
 	ctx context.Context,
 	txn *client.Txn,
@@ -169,15 +253,97 @@ func reassignParentIDs(
 	return nil
 }
 
+// reassignDatabaseIDs is the full cluster restore counterpart of
+// reassignParentIDs: instead of restoring into existing databases, it assigns
+// new IDs to the backed-up databases, which are created along with the tables,
+// and points the tables at them. The backed-up system tables are restored into
+// a temporary database standing in for the system database. The restoring
+// cluster must not contain any databases or tables other than the system ones,
+// except for the temporary database of an earlier restore, which is removed.
+func reassignDatabaseIDs(
+	ctx context.Context,
+	db client.DB,
+	databasesByID map[sqlbase.ID]*sqlbase.DatabaseDescriptor,
+	tables []*sqlbase.TableDescriptor,
+	opt parser.KVOptions,
+) ([]sql.RestoreJobDetails_DatabaseRekey, error) {
+	if _, ok := opt.Get(restoreOptIntoDB); ok {
+		return nil, errors.Errorf("option %q is not supported by a full cluster restore", restoreOptIntoDB)
+	}
+	// The temporary system database of an earlier attempt that failed would
+	// make the cluster look non-empty.
+	if err := removeLeftoverTempSystemDB(ctx, db); err != nil {
+		return nil, err
+	}
+
+	var rekeys []sql.RestoreJobDetails_DatabaseRekey
+	if err := db.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
+		existing, err := allSQLDescriptors(ctx, txn)
+		if err != nil {
+			return err
+		}
+		for _, desc := range existing {
+			if desc.GetID() > keys.MaxReservedDescID {
+				return errors.Errorf("full cluster restore requires an empty cluster, found %q",
+					desc.GetName())
+			}
+		}
+
+		rekeys = rekeys[:0]
+		newDatabaseIDs := make(map[sqlbase.ID]sqlbase.ID, len(databasesByID)+1)
+		addRekey := func(oldID sqlbase.ID, newDesc sqlbase.DatabaseDescriptor) error {
+			newID, err := sql.GenerateUniqueDescID(ctx, txn)
+			if err != nil {
+				return err
+			}
+			newDesc.ID = newID
+			newDatabaseIDs[oldID] = newID
+			rekeys = append(rekeys, sql.RestoreJobDetails_DatabaseRekey{OldID: oldID, NewDesc: newDesc})
+			return nil
+		}
+		if err := addRekey(keys.SystemDatabaseID, sqlbase.DatabaseDescriptor{
+			Name:       restoreTempSystemDB,
+			Privileges: sqlbase.NewDefaultPrivilegeDescriptor(),
+		}); err != nil {
+			return err
+		}
+		ids := make([]sqlbase.ID, 0, len(databasesByID))
+		for id := range databasesByID {
+			if id != keys.SystemDatabaseID {
+				ids = append(ids, id)
+			}
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		for _, id := range ids {
+			if err := addRekey(id, *databasesByID[id]); err != nil {
+				return err
+			}
+		}
+
+		for _, table := range tables {
+			newParentID, ok := newDatabaseIDs[table.ParentID]
+			if !ok {
+				return errors.Errorf("no database with ID %d in backup for table %q", table.ParentID, table.Name)
+			}
+			if table.ParentID == keys.SystemDatabaseID {
+				table.Privileges = sqlbase.NewDefaultPrivilegeDescriptor()
+			}
+			table.ParentID = newParentID
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return rekeys, nil
+}
+
 // reassignTableIDs updates the tables being restored with new TableIDs reserved
 // in the restoring cluster, as well as fixing cross-table references to use the
-// new IDs. It returns a KeyRewriter that can be used to transform KV data to
-// reflect the ID remapping it has done in the descriptors.
+// new IDs. It returns a map from the old IDs of the tables to the new ones.
 func reassignTableIDs(
 	ctx context.Context, db client.DB, tables []*sqlbase.TableDescriptor, opt parser.KVOptions,
-) (storageccl.KeyRewriter, map[sqlbase.ID]sqlbase.ID, error) {
+) (map[sqlbase.ID]sqlbase.ID, error) {
 	var newTableIDs map[sqlbase.ID]sqlbase.ID
-	var kr storageccl.KeyRewriter
 
 	if err := db.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
 		newTableIDs = make(map[sqlbase.ID]sqlbase.ID, len(tables))
@@ -186,20 +352,19 @@ func reassignTableIDs(
 			if err != nil {
 				return err
 			}
-			kr = append(kr, MakeKeyRewriterForNewTableID(table, newTableID)...)
 			newTableIDs[table.ID] = newTableID
 			table.ID = newTableID
 		}
 		return nil
 	}); err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
 	if err := reassignReferencedTables(tables, newTableIDs, opt); err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	return kr, newTableIDs, nil
+	return newTableIDs, nil
 }
 
 func reassignReferencedTables(
@@ -536,11 +701,23 @@ func presplitRanges(baseCtx context.Context, db client.DB, input []roachpb.Key)
 // Write the new descriptors. First the ID -> TableDescriptor for the new table,
 // then flip (or initialize) the name -> ID entry so any new queries will use
 // the new one.
-func restoreTableDescs(ctx context.Context, db client.DB, tables []*sqlbase.TableDescriptor) error {
+func restoreTableDescs(
+	ctx context.Context,
+	db client.DB,
+	databases []*sqlbase.DatabaseDescriptor,
+	tables []*sqlbase.TableDescriptor,
+) error {
 	ctx, span := tracing.ChildSpan(ctx, "restoreTableDescs")
 	defer tracing.FinishSpan(span)
 	err := db.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
 		b := txn.NewBatch()
+		for _, database := range databases {
+			if err := database.Validate(); err != nil {
+				return err
+			}
+			b.CPut(sqlbase.MakeDescMetadataKey(database.ID), sqlbase.WrapDescriptor(database), nil)
+			b.CPut(sqlbase.MakeNameMetadataKey(keys.RootNamespaceID, database.Name), database.ID, nil)
+		}
 		for _, table := range tables {
 			b.CPut(table.GetDescMetadataKey(), sqlbase.WrapDescriptor(table), nil)
 			b.CPut(table.GetNameMetadataKey(), table.ID, nil)
@@ -559,10 +736,139 @@ func restoreTableDescs(ctx context.Context, db client.DB, tables []*sqlbase.Tabl
 	return errors.Wrap(err, "restoring table desc and namespace entries")
 }
 
+// restoreSystemTables copies the contents of the system tables restored into
+// the temporary system database by a full cluster restore into the real system
+// tables, in the order of fullClusterSystemTables. Zone configurations are
+// rewritten to the new IDs of the databases and tables they apply to. Only the
+// jobs that had finished are restored, as the others would be adopted by this
+// cluster and resumed against descriptors it doesn't have.
+func restoreSystemTables(
+	ctx context.Context,
+	execCfg *sql.ExecutorConfig,
+	tempTables []*sqlbase.TableDescriptor,
+	newIDs map[sqlbase.ID]sqlbase.ID,
+) error {
+	ctx, span := tracing.ChildSpan(ctx, "restoreSystemTables")
+	defer tracing.FinishSpan(span)
+
+	restored := make(map[string]struct{}, len(tempTables))
+	for _, table := range tempTables {
+		restored[table.Name] = struct{}{}
+	}
+
+	ie := sql.InternalExecutor{LeaseManager: execCfg.LeaseManager}
+	for _, name := range fullClusterSystemTables {
+		if _, ok := restored[name]; !ok {
+			continue
+		}
+		tempName := parser.TableName{
+			DatabaseName: parser.Name(restoreTempSystemDB), TableName: parser.Name(name),
+		}
+		err := execCfg.DB.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
+			switch name {
+			case sqlbase.ZonesTable.Name:
+				rows, err := ie.QueryRowsInTransaction(ctx, "restore-zones", txn,
+					fmt.Sprintf(`SELECT id, config FROM %s`, tempName.String()))
+				if err != nil {
+					return err
+				}
+				for _, row := range rows {
+					id := sqlbase.ID(parser.MustBeDInt(row[0]))
+					if id > keys.MaxReservedDescID {
+						newID, ok := newIDs[id]
+						if !ok {
+							// The zone config of a database or table that wasn't backed up.
+							continue
+						}
+						id = newID
+					}
+					if _, err := ie.ExecuteStatementInTransaction(ctx, "restore-zones", txn,
+						`UPSERT INTO system.zones (id, config) VALUES ($1, $2)`, int64(id), row[1],
+					); err != nil {
+						return err
+					}
+				}
+				return nil
+			case sqlbase.JobsTable.Name:
+				_, err := ie.ExecuteStatementInTransaction(ctx, "restore-jobs", txn,
+					fmt.Sprintf(`UPSERT INTO system.jobs SELECT * FROM %s WHERE status IN ($1, $2, $3)`,
+						tempName.String()),
+					string(sql.JobStatusSucceeded), string(sql.JobStatusFailed), string(sql.JobStatusCanceled))
+				return err
+			default:
+				_, err := ie.ExecuteStatementInTransaction(ctx, "restore-"+name, txn,
+					fmt.Sprintf(`UPSERT INTO system.%s SELECT * FROM %s`, name, tempName.String()))
+				return err
+			}
+		})
+		if err != nil {
+			return errors.Wrapf(err, "restoring system table %s", name)
+		}
+	}
+	return nil
+}
+
+// removeTempSystemDB removes the temporary system database of a full cluster
+// restore, along with its tables and their data.
+func removeTempSystemDB(
+	ctx context.Context,
+	db client.DB,
+	tempDB *sqlbase.DatabaseDescriptor,
+	tempTables []*sqlbase.TableDescriptor,
+) error {
+	if err := db.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
+		b := txn.NewBatch()
+		for _, table := range tempTables {
+			b.Del(table.GetDescMetadataKey(), table.GetNameMetadataKey())
+		}
+		b.Del(sqlbase.MakeDescMetadataKey(tempDB.ID),
+			sqlbase.MakeNameMetadataKey(keys.RootNamespaceID, tempDB.Name))
+		return txn.Run(ctx, b)
+	}); err != nil {
+		return errors.Wrapf(err, "removing database %s", tempDB.Name)
+	}
+	return clearTableData(ctx, db, tempTables)
+}
+
+// removeLeftoverTempSystemDB removes the temporary system database left behind
+// by an earlier full cluster restore that failed, if there is one.
+func removeLeftoverTempSystemDB(ctx context.Context, db client.DB) error {
+	var tempDB *sqlbase.DatabaseDescriptor
+	var tempTables []*sqlbase.TableDescriptor
+	if err := db.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
+		tempDB, tempTables = nil, nil
+		descs, err := allSQLDescriptors(ctx, txn)
+		if err != nil {
+			return err
+		}
+		for i := range descs {
+			if dbDesc := descs[i].GetDatabase(); dbDesc != nil && dbDesc.Name == restoreTempSystemDB {
+				tempDB = dbDesc
+			}
+		}
+		if tempDB == nil {
+			return nil
+		}
+		for i := range descs {
+			if table := descs[i].GetTable(); table != nil && table.ParentID == tempDB.ID {
+				tempTables = append(tempTables, table)
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	if tempDB == nil {
+		return nil
+	}
+	log.Infof(ctx, "removing database %s left behind by an earlier restore", tempDB.Name)
+	return removeTempSystemDB(ctx, db, tempDB, tempTables)
+}
+
 func restoreJobDescription(restore *parser.Restore, from []string) (string, error) {
 	r := parser.Restore{
 		AsOf:    restore.AsOf,
-		Options: restore.Options,
+		Options: redactOptions(restore.Options),
 		Targets: restore.Targets,
 		From:    make(parser.Exprs, len(restore.From)),
 	}
@@ -579,44 +885,67 @@ func restoreJobDescription(restore *parser.Restore, from []string) (string, erro
 }
 
 // Restore imports a SQL table (or tables) from sets of non-overlapping sstable
-// files.
+// files. If endTime is set, the tables are restored as they were at that time,
+// which requires the backups covering it to have revision history unless it is
+// the end time of one of them. If encryption is set, it is used to decrypt the
+// backups.
 func Restore(
 	ctx context.Context,
 	p sql.PlanHookState,
 	uris []string,
 	targets parser.TargetList,
 	opt parser.KVOptions,
+	endTime hlc.Timestamp,
+	encryption *roachpb.FileEncryptionOptions,
 	jobLogger *sql.JobLogger,
 ) error {
 
 	db := *p.ExecCfg().DB
 
+	// A RESTORE without targets restores an entire cluster.
+	fullCluster := targets.IsEmpty()
+
 	if len(targets.Databases) > 0 {
 		return errors.Errorf("RESTORE DATABASE is not yet supported " +
 			"(but you can use 'RESTORE somedb.*' to restore all backed up tables for a given DB).")
 	}
 
-	backupDescs, err := loadBackupDescs(ctx, uris)
+	backupDescs, err := loadBackupDescs(ctx, uris, encryption)
 	if err != nil {
 		return err
 	}
-	lastBackupDesc := backupDescs[len(backupDescs)-1]
+	if backupDescs, err = backupsCoveringTime(backupDescs, endTime); err != nil {
+		return err
+	}
+	uris = uris[:len(backupDescs)]
+	if fullCluster {
+		for i, desc := range backupDescs {
+			if desc.DescriptorCoverage != BackupDescriptor_AllDescriptors {
+				return errors.Errorf("backup %s is not a full cluster backup: "+
+					"a RESTORE without targets requires full cluster backups", uris[i])
+			}
+		}
+	}
 
 	databasesByID := make(map[sqlbase.ID]*sqlbase.DatabaseDescriptor)
 	var tables []*sqlbase.TableDescriptor
 	{
-		// TODO(dan): Plumb the session database down.
-		sessionDatabase := ""
-		sqlDescs := lastBackupDesc.Descriptors
-		var err error
-		if sqlDescs, err = descriptorsMatchingTargets(sessionDatabase, sqlDescs, targets); err != nil {
-			return err
+		sqlDescs := loadSQLDescsFromBackupsAtTime(backupDescs, endTime)
+		if !fullCluster {
+			// TODO(dan): Plumb the session database down.
+			sessionDatabase := ""
+			var err error
+			if sqlDescs, err = descriptorsMatchingTargets(sessionDatabase, sqlDescs, targets); err != nil {
+				return err
+			}
 		}
 		for _, desc := range sqlDescs {
 			if dbDesc := desc.GetDatabase(); dbDesc != nil {
 				databasesByID[dbDesc.ID] = dbDesc
 			} else if tableDesc := desc.GetTable(); tableDesc != nil {
-				tables = append(tables, tableDesc)
+				// The tables are modified below, but the backup descriptors must
+				// keep describing the tables as they were backed up.
+				tables = append(tables, protoutil.Clone(tableDesc).(*sqlbase.TableDescriptor))
 			}
 		}
 		if len(tables) == 0 {
@@ -624,51 +953,161 @@ func Restore(
 		}
 	}
 
-	// Fail fast if the necessary databases don't exist since the below logic
-	// leaks table IDs when Restore fails.
-	if err := db.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
-		return reassignParentIDs(ctx, txn, p, databasesByID, tables, opt)
-	}); err != nil {
-		return err
+	var databaseRekeys []sql.RestoreJobDetails_DatabaseRekey
+	if fullCluster {
+		var err error
+		if databaseRekeys, err = reassignDatabaseIDs(ctx, db, databasesByID, tables, opt); err != nil {
+			return err
+		}
+	} else {
+		// Fail fast if the necessary databases don't exist since the below logic
+		// leaks table IDs when Restore fails.
+		if err := db.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
+			return reassignParentIDs(ctx, txn, p, databasesByID, tables, opt)
+		}); err != nil {
+			return err
+		}
 	}
 
-	// We get the spans of the restoring tables _as they appear in the backup_,
-	// that is, in the 'old' keyspace, before we reassign the table IDs.
-	spans := spansForAllTableIndexes(tables)
-
-	// Assign new IDs to the tables and update all references to use the new IDs,
-	// and get a KeyRewriter to use when importing their raw data.
+	// Assign new IDs to the tables and update all references to use the new IDs.
 	//
 	// NB: we do this in a standalone transaction, not one that covers the entire
 	// restore since restarts would be terrible (and our bulk import primitive
 	// are non-transactional), but this does mean if something fails during Import,
 	// we've "leaked" the IDs, in that the generator will have been incremented.
-	kr, newTableIDs, err := reassignTableIDs(ctx, db, tables, opt)
+	newTableIDs, err := reassignTableIDs(ctx, db, tables, opt)
 	if err != nil {
 		// We expect user-facing usage errors here, so don't wrapf.
 		return err
 	}
-
-	// Pivot the backups, which are grouped by time, into requests for import,
-	// which are grouped by keyrange.
-	importRequests, _, err := makeImportRequests(spans, backupDescs)
-	if err != nil {
-		return errors.Wrapf(err, "making import requests for %d backups", len(backupDescs))
+	oldTableIDs := make(map[sqlbase.ID]sqlbase.ID, len(newTableIDs))
+	for oldID, newID := range newTableIDs {
+		oldTableIDs[newID] = oldID
 	}
 
-	for _, desc := range newTableIDs {
-		jobLogger.Job.DescriptorIDs = append(jobLogger.Job.DescriptorIDs, desc)
+	details := sql.RestoreJobDetails{
+		URIs:           uris,
+		EndTime:        endTime,
+		DatabaseRekeys: databaseRekeys,
+		Encryption:     encryption,
 	}
+	for _, table := range tables {
+		details.TableRekeys = append(details.TableRekeys, sql.RestoreJobDetails_TableRekey{
+			OldID:   oldTableIDs[table.ID],
+			NewDesc: *table,
+		})
+		jobLogger.Job.DescriptorIDs = append(jobLogger.Job.DescriptorIDs, table.ID)
+	}
+	jobLogger.Job.Details = details
 	if err := jobLogger.Created(ctx); err != nil {
 		return err
 	}
 	if err := jobLogger.Started(ctx); err != nil {
 		return err
 	}
+	return restore(ctx, p.ExecCfg(), backupDescs, jobLogger)
+}
+
+// restore imports the data of the tables described by the details of the given
+// job out of the given backups, skipping the data that was already imported as
+// of its last checkpoint, then publishes the tables.
+func restore(
+	ctx context.Context,
+	execCfg *sql.ExecutorConfig,
+	backupDescs []BackupDescriptor,
+	jobLogger *sql.JobLogger,
+) error {
+	details := jobLogger.Job.Details.(sql.RestoreJobDetails)
+	db := *execCfg.DB
+
+	backupTables := make(map[sqlbase.ID]*sqlbase.TableDescriptor)
+	for _, desc := range loadSQLDescsFromBackupsAtTime(backupDescs, details.EndTime) {
+		if tableDesc := desc.GetTable(); tableDesc != nil {
+			backupTables[tableDesc.ID] = tableDesc
+		}
+	}
+	var oldTables, tables []*sqlbase.TableDescriptor
+	var kr storageccl.KeyRewriter
+	for i := range details.TableRekeys {
+		rekey := &details.TableRekeys[i]
+		oldTable, ok := backupTables[rekey.OldID]
+		if !ok {
+			return errors.Errorf("no table with ID %d in backup for table %q",
+				rekey.OldID, rekey.NewDesc.Name)
+		}
+		oldTables = append(oldTables, oldTable)
+		tables = append(tables, &rekey.NewDesc)
+		// The KeyRewriter transforms the KV data to reflect the new IDs.
+		kr = append(kr, MakeKeyRewriterForNewTableID(oldTable, rekey.NewDesc.ID)...)
+	}
+
+	var databases []*sqlbase.DatabaseDescriptor
+	var tempSystemDB *sqlbase.DatabaseDescriptor
+	newIDs := make(map[sqlbase.ID]sqlbase.ID)
+	for i := range details.DatabaseRekeys {
+		rekey := &details.DatabaseRekeys[i]
+		databases = append(databases, &rekey.NewDesc)
+		newIDs[rekey.OldID] = rekey.NewDesc.ID
+		if rekey.OldID == keys.SystemDatabaseID {
+			tempSystemDB = &rekey.NewDesc
+		}
+	}
+	var tempSystemTables []*sqlbase.TableDescriptor
+	for i := range details.TableRekeys {
+		rekey := &details.TableRekeys[i]
+		newIDs[rekey.OldID] = rekey.NewDesc.ID
+		if tempSystemDB != nil && rekey.NewDesc.ParentID == tempSystemDB.ID {
+			tempSystemTables = append(tempSystemTables, &rekey.NewDesc)
+		}
+	}
+
+	// We get the spans of the restoring tables _as they appear in the backup_,
+	// that is, in the 'old' keyspace.
+	spans := spansForAllTableIndexes(oldTables, nil)
+
+	// Pivot the backups, which are grouped by time, into requests for import,
+	// which are grouped by keyrange.
+	importRequests, _, err := makeImportRequests(spans, backupDescs)
+	if err != nil {
+		return errors.Wrapf(err, "making import requests for %d backups", len(backupDescs))
+	}
+	totalRequests := len(importRequests)
 
+	// A resumed restore skips the requests below the checkpointed low-water
+	// mark, which were already imported.
+	if details.LowWaterMark != nil {
+		i := sort.Search(len(importRequests), func(i int) bool {
+			return importRequests[i].Key.Compare(details.LowWaterMark) >= 0
+		})
+		importRequests = importRequests[i:]
+	}
+
+	mu := struct {
+		syncutil.Mutex
+		done []bool
+		// lowWater is the index of the first request that is not done.
+		lowWater int
+	}{
+		done: make([]bool, len(importRequests)),
+	}
 	progressLogger := jobProgressLogger{
 		jobLogger:   jobLogger,
-		totalChunks: len(importRequests),
+		totalChunks: totalRequests,
+		priorChunks: totalRequests - len(importRequests),
+		checkpoint: func() (interface{}, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			for mu.lowWater < len(mu.done) && mu.done[mu.lowWater] {
+				mu.lowWater++
+			}
+			checkpoint := details
+			if mu.lowWater < len(importRequests) {
+				checkpoint.LowWaterMark = importRequests[mu.lowWater].Key
+			} else if len(importRequests) > 0 {
+				checkpoint.LowWaterMark = importRequests[len(importRequests)-1].EndKey
+			}
+			return checkpoint, nil
+		},
 	}
 
 	// The Import (and resulting WriteBatch) requests made below run on
@@ -686,7 +1125,7 @@ func Restore(
 		return errors.Wrapf(err, "presplitting %d ranges", len(importRequests))
 	}
 	{
-		newSpans := spansForAllTableIndexes(tables)
+		newSpans := spansForAllTableIndexes(tables, nil)
 		g, gCtx := errgroup.WithContext(ctx)
 		for i := range newSpans {
 			span := newSpans[i]
@@ -727,24 +1166,35 @@ func Restore(
 	// TODO(dan): Make this limiting per node.
 	//
 	// TODO(dan): See if there's some better solution than rate-limiting #14798.
-	maxConcurrentImports := clusterNodeCount(p.ExecCfg().Gossip)
+	maxConcurrentImports := clusterNodeCount(execCfg.Gossip)
 	importsSem := make(chan struct{}, maxConcurrentImports)
 
 	g, gCtx := errgroup.WithContext(ctx)
+	var statusErr error
 	for i := range importRequests {
+		// Honor PAUSE JOB and CANCEL JOB between requests. In-flight requests
+		// are allowed to finish.
+		if statusErr = jobLogger.CheckStatus(ctx); statusErr != nil {
+			break
+		}
 		select {
 		case importsSem <- struct{}{}:
 		case <-ctx.Done():
 			return ctx.Err()
 		}
 
-		ir := importRequests[i]
+		i, ir := i, importRequests[i]
 		g.Go(func() error {
 			defer func() { <-importsSem }()
 
-			if err := Import(gCtx, db, ir.Key, ir.EndKey, ir.files, kr); err != nil {
+			if err := Import(
+				gCtx, db, ir.Key, ir.EndKey, ir.files, kr, details.EndTime, details.Encryption,
+			); err != nil {
 				return err
 			}
+			mu.Lock()
+			mu.done[i] = true
+			mu.Unlock()
 			if err := progressLogger.chunkFinished(gCtx); err != nil {
 				// Errors while updating progress are not important enough to merit
 				// failing the entire restore.
@@ -761,14 +1211,40 @@ func Restore(
 		// TODO(dan): Build tooling to allow a user to restart a failed restore.
 		return errors.Wrapf(err, "importing %d ranges", len(importRequests))
 	}
+	if statusErr != nil {
+		if sql.IsJobCanceledError(statusErr) {
+			// The restored tables were never made public, so drop their
+			// partially imported data.
+			if err := clearTableData(ctx, db, tables); err != nil {
+				log.Warningf(ctx, "unable to clear data imported by canceled restore: %+v", err)
+			}
+		}
+		return statusErr
+	}
 
 	// Write the new TableDescriptors and flip the namespace entries over to
 	// them. After this call, any queries on a table will be served by the newly
 	// restored data.
-	if err := restoreTableDescs(ctx, db, tables); err != nil {
+	if err := restoreTableDescs(ctx, db, databases, tables); err != nil {
 		return errors.Wrapf(err, "restoring %d TableDescriptors", len(tables))
 	}
 
+	// A full cluster restore finishes by copying the restored system tables into
+	// the real ones. The temporary system database is removed even if that
+	// fails, as it can't be used to finish the restore.
+	if tempSystemDB != nil {
+		err := restoreSystemTables(ctx, execCfg, tempSystemTables, newIDs)
+		if removeErr := removeTempSystemDB(ctx, db, tempSystemDB, tempSystemTables); removeErr != nil {
+			if err == nil {
+				return removeErr
+			}
+			log.Warningf(ctx, "unable to remove database %s: %+v", tempSystemDB.Name, removeErr)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
 	// TODO(dan): Delete any old table data here. The first version of restore
 	// assumes that it's operating on a new cluster. If it's not empty,
 	// everything works but the table data is left abandoned.
@@ -776,6 +1252,118 @@ func Restore(
 	return nil
 }
 
+// clearTableData removes all the data in the spans of the given tables.
+func clearTableData(ctx context.Context, db client.DB, tables []*sqlbase.TableDescriptor) error {
+	for _, table := range tables {
+		// ClearRange must be alone in its batch.
+		span := table.TableSpan()
+		b := &client.Batch{}
+		b.AddRawRequest(&roachpb.ClearRangeRequest{
+			Span: roachpb.Span{Key: span.Key, EndKey: span.EndKey},
+		})
+		if err := db.Run(ctx, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyBackups checks, without restoring anything, that the given chain of
+// backups can be restored: that their descriptors can be read, that they cover
+// the targets and that every one of their files is present and matches its
+// checksum and, if the backups are encrypted, can be decrypted.
+func verifyBackups(
+	ctx context.Context,
+	uris []string,
+	targets parser.TargetList,
+	endTime hlc.Timestamp,
+	encryption *roachpb.FileEncryptionOptions,
+) error {
+	backupDescs, err := loadBackupDescs(ctx, uris, encryption)
+	if err != nil {
+		return err
+	}
+	if backupDescs, err = backupsCoveringTime(backupDescs, endTime); err != nil {
+		return err
+	}
+
+	var spans []roachpb.Span
+	if !targets.IsEmpty() {
+		// TODO(dan): Plumb the session database down.
+		sessionDatabase := ""
+		sqlDescs, err := descriptorsMatchingTargets(
+			sessionDatabase, loadSQLDescsFromBackupsAtTime(backupDescs, endTime), targets,
+		)
+		if err != nil {
+			return err
+		}
+		var tables []*sqlbase.TableDescriptor
+		for _, desc := range sqlDescs {
+			if tableDesc := desc.GetTable(); tableDesc != nil {
+				tables = append(tables, tableDesc)
+			}
+		}
+		if len(tables) == 0 {
+			return errors.Errorf("no tables found: %s", parser.AsString(targets))
+		}
+		spans = spansForAllTableIndexes(tables, nil)
+	}
+	if _, _, err := makeImportRequests(spans, backupDescs); err != nil {
+		return errors.Wrapf(err, "verifying %d backups", len(backupDescs))
+	}
+
+	for i, backupDesc := range backupDescs {
+		if err := verifyBackupFiles(ctx, backupDesc, encryption); err != nil {
+			return errors.Wrapf(err, "verifying backup %s", uris[i])
+		}
+	}
+	return nil
+}
+
+// verifyBackupFiles checks that every file of the given backup is present and
+// matches its checksum and, if encryption is set, can be decrypted with it.
+func verifyBackupFiles(
+	ctx context.Context, backupDesc BackupDescriptor, encryption *roachpb.FileEncryptionOptions,
+) error {
+	dir, err := storageccl.MakeExportStorage(ctx, backupDesc.Dir)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+
+	for _, file := range backupDesc.Files {
+		if len(file.Path) == 0 {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		data, err := func() ([]byte, error) {
+			r, err := dir.ReadFile(ctx, file.Path)
+			if err != nil {
+				return nil, err
+			}
+			defer r.Close()
+			return ioutil.ReadAll(r)
+		}()
+		if err != nil {
+			return errors.Wrapf(err, "reading %s", file.Path)
+		}
+		if len(file.Sha512) > 0 {
+			checksum := sha512.Sum512(data)
+			if !bytes.Equal(checksum[:], file.Sha512) {
+				return errors.Errorf("checksum mismatch for %s", file.Path)
+			}
+		}
+		if encryption != nil {
+			if _, err := storageccl.DecryptFile(data, encryption.Key); err != nil {
+				return errors.Wrapf(err, "decrypting %s", file.Path)
+			}
+		}
+	}
+	return nil
+}
+
 func restorePlanHook(
 	baseCtx context.Context, stmt parser.Statement, p sql.PlanHookState,
 ) (func() ([]parser.Datums, error), sql.ResultColumns, error) {
@@ -802,14 +1390,34 @@ func restorePlanHook(
 		defer tracing.FinishSpan(span)
 
 		from := fromFn()
+		var endTime hlc.Timestamp
+		if restore.AsOf.Expr != nil {
+			var err error
+			endTime, err = sql.EvalAsOfTimestamp(nil, restore.AsOf, p.ExecCfg().Clock.Now())
+			if err != nil {
+				return nil, err
+			}
+		}
+		if len(from) == 0 {
+			return nil, errors.New("no backups found")
+		}
+		encryption, err := encryptionFromOptions(ctx, restore.Options, from[0])
+		if err != nil {
+			return nil, err
+		}
+		if verifyOnly, ok := restore.Options.Get(restoreOptVerifyOnly); ok {
+			if verifyOnly != "" {
+				return nil, errors.Errorf("option %q does not take a value", restoreOptVerifyOnly)
+			}
+			return nil, verifyBackups(ctx, from, restore.Targets, endTime, encryption)
+		}
 		description, err := restoreJobDescription(restore, from)
 		if err != nil {
 			return nil, err
 		}
-		jobLogger := sql.NewJobLogger(p.ExecCfg().DB, p.LeaseMgr(), sql.JobRecord{
+		jobLogger := p.ExecCfg().JobRegistry.NewJobLogger(sql.JobRecord{
 			Description: description,
 			Username:    p.User(),
-			Details:     sql.RestoreJobDetails{},
 		})
 		err = Restore(
 			ctx,
@@ -817,6 +1425,8 @@ func restorePlanHook(
 			from,
 			restore.Targets,
 			restore.Options,
+			endTime,
+			encryption,
 			&jobLogger,
 		)
 		if err != nil {
@@ -834,6 +1444,32 @@ func restorePlanHook(
 	return fn, nil, nil
 }
 
+// resumeRestore continues a restore whose node died from its last checkpoint.
+func resumeRestore(ctx context.Context, execCfg *sql.ExecutorConfig, jobLogger *sql.JobLogger) error {
+	details := jobLogger.Job.Details.(sql.RestoreJobDetails)
+	if details.Encryption != nil {
+		// The encryption key is not persisted with the job, so the restore can't
+		// go on. The restored tables were never made public, so drop their
+		// partially imported data.
+		tables := make([]*sqlbase.TableDescriptor, len(details.TableRekeys))
+		for i := range details.TableRekeys {
+			tables[i] = &details.TableRekeys[i].NewDesc
+		}
+		if err := clearTableData(ctx, *execCfg.DB, tables); err != nil {
+			log.Warningf(ctx, "unable to clear data imported by restore job %d: %+v",
+				*jobLogger.JobID(), err)
+		}
+		return errors.Errorf("cannot resume restore job %d of encrypted backups: run the RESTORE again",
+			*jobLogger.JobID())
+	}
+	backupDescs, err := loadBackupDescs(ctx, details.URIs, details.Encryption)
+	if err != nil {
+		return err
+	}
+	return restore(ctx, execCfg, backupDescs, jobLogger)
+}
+
 func init() {
 	sql.AddPlanHook(restorePlanHook)
+	sql.RegisterJobResumer(sql.JobTypeRestore, resumeRestore)
 }
@@ -0,0 +1,170 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/LICENSE
+
+package sqlccl
+
+import (
+	"sort"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/tracing"
+)
+
+// BackupTableSummary describes the contents of a backup for one table.
+type BackupTableSummary struct {
+	Database  string
+	Table     string
+	StartTime hlc.Timestamp
+	EndTime   hlc.Timestamp
+	DataSize  int64
+	Rows      int64
+	Files     int64
+}
+
+// ReadBackupDescriptorFromURI reads the BackupDescriptor stored at the given
+// URI. It does not need a running server for local (nodelocal) URIs.
+func ReadBackupDescriptorFromURI(ctx context.Context, uri string) (BackupDescriptor, error) {
+	return readBackupDescriptor(ctx, uri, nil /* encryption */)
+}
+
+// SummarizeBackup returns one BackupTableSummary per table in the given
+// backup, sorted by database and table name.
+func SummarizeBackup(desc BackupDescriptor) []BackupTableSummary {
+	dbNames := make(map[sqlbase.ID]string)
+	for _, d := range desc.Descriptors {
+		if db := d.GetDatabase(); db != nil {
+			dbNames[db.ID] = db.Name
+		}
+	}
+
+	byID := make(map[sqlbase.ID]*BackupTableSummary)
+	var summaries []*BackupTableSummary
+	for _, d := range desc.Descriptors {
+		if table := d.GetTable(); table != nil {
+			s := &BackupTableSummary{
+				Database:  dbNames[table.ParentID],
+				Table:     table.Name,
+				StartTime: desc.StartTime,
+				EndTime:   desc.EndTime,
+			}
+			byID[table.ID] = s
+			summaries = append(summaries, s)
+		}
+	}
+
+	for _, file := range desc.Files {
+		// Files are produced by exporting the spans of individual indexes, so
+		// each one is entirely contained in a single table.
+		_, tableID, err := keys.DecodeTablePrefix(file.Span.Key)
+		if err != nil {
+			continue
+		}
+		s, ok := byID[sqlbase.ID(tableID)]
+		if !ok {
+			continue
+		}
+		s.DataSize += file.EntryCounts.DataSize
+		s.Rows += file.EntryCounts.Rows
+		s.Files++
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Database != summaries[j].Database {
+			return summaries[i].Database < summaries[j].Database
+		}
+		return summaries[i].Table < summaries[j].Table
+	})
+	ret := make([]BackupTableSummary, len(summaries))
+	for i := range summaries {
+		ret[i] = *summaries[i]
+	}
+	return ret
+}
+
+func backupTimeDatum(ts hlc.Timestamp) parser.Datum {
+	if ts == (hlc.Timestamp{}) {
+		return parser.DNull
+	}
+	return parser.MakeDTimestamp(ts.GoTime(), time.Nanosecond)
+}
+
+func showBackupPlanHook(
+	baseCtx context.Context, stmt parser.Statement, p sql.PlanHookState,
+) (func() ([]parser.Datums, error), sql.ResultColumns, error) {
+	backup, ok := stmt.(*parser.ShowBackup)
+	if !ok {
+		return nil, nil, nil
+	}
+	if err := p.RequireSuperUser("SHOW BACKUP"); err != nil {
+		return nil, nil, err
+	}
+
+	pathFn, err := p.TypeAsString(&backup.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	incrementalFromFn, err := p.TypeAsStringArray(&backup.IncrementalFrom)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header := sql.ResultColumns{
+		{Name: "database", Typ: parser.TypeString},
+		{Name: "table", Typ: parser.TypeString},
+		{Name: "start_time", Typ: parser.TypeTimestamp},
+		{Name: "end_time", Typ: parser.TypeTimestamp},
+		{Name: "size_bytes", Typ: parser.TypeInt},
+		{Name: "rows", Typ: parser.TypeInt},
+		{Name: "files", Typ: parser.TypeInt},
+	}
+	fn := func() ([]parser.Datums, error) {
+		// TODO(dan): Move this span into sql.
+		ctx, span := tracing.ChildSpan(baseCtx, stmt.StatementTag())
+		defer tracing.FinishSpan(span)
+
+		path := pathFn()
+		if backup.IncrementalFrom != nil {
+			// Check that the given chain of backups, ending with this one, is
+			// complete.
+			uris := append(incrementalFromFn(), path)
+			if _, err := ValidatePreviousBackups(ctx, uris); err != nil {
+				return nil, err
+			}
+		}
+		desc, err := readBackupDescriptor(ctx, path, nil /* encryption */)
+		if err != nil {
+			return nil, err
+		}
+
+		var rows []parser.Datums
+		for _, s := range SummarizeBackup(desc) {
+			rows = append(rows, parser.Datums{
+				parser.NewDString(s.Database),
+				parser.NewDString(s.Table),
+				backupTimeDatum(s.StartTime),
+				backupTimeDatum(s.EndTime),
+				parser.NewDInt(parser.DInt(s.DataSize)),
+				parser.NewDInt(parser.DInt(s.Rows)),
+				parser.NewDInt(parser.DInt(s.Files)),
+			})
+		}
+		return rows, nil
+	}
+	return fn, header, nil
+}
+
+func init() {
+	sql.AddPlanHook(showBackupPlanHook)
+}
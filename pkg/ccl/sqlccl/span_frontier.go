@@ -0,0 +1,123 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/pkg/ccl/LICENSE
+
+package sqlccl
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+)
+
+// spanFrontierEntry is a piece of a spanFrontier's tracked spans, along with
+// the timestamp to which it has been resolved.
+type spanFrontierEntry struct {
+	span roachpb.Span
+	ts   hlc.Timestamp
+}
+
+// spanFrontier tracks the timestamp to which each part of a set of spans has
+// been resolved and computes the frontier: the minimum of those timestamps,
+// below which every tracked key is known to be resolved.
+//
+// The entries are kept sorted, non-overlapping and covering exactly the
+// tracked spans, so every operation is linear in the number of entries. The
+// number of entries is bounded by the number of ranges in the tracked spans,
+// which is small enough for changefeeds over a handful of tables.
+type spanFrontier struct {
+	entries []spanFrontierEntry
+}
+
+// makeSpanFrontier returns a spanFrontier that tracks the given spans, all of
+// which start out resolved at the zero timestamp. The spans must not overlap.
+func makeSpanFrontier(spans ...roachpb.Span) spanFrontier {
+	var f spanFrontier
+	for _, s := range spans {
+		f.entries = append(f.entries, spanFrontierEntry{span: s})
+	}
+	sort.Slice(f.entries, func(i, j int) bool {
+		return bytes.Compare(f.entries[i].span.Key, f.entries[j].span.Key) < 0
+	})
+	return f
+}
+
+// Frontier returns the minimum timestamp to which any tracked span has been
+// resolved.
+func (f *spanFrontier) Frontier() hlc.Timestamp {
+	if len(f.entries) == 0 {
+		return hlc.Timestamp{}
+	}
+	ts := f.entries[0].ts
+	for _, e := range f.entries[1:] {
+		if e.ts.Less(ts) {
+			ts = e.ts
+		}
+	}
+	return ts
+}
+
+// Forward advances the timestamp of the tracked parts of span to ts, if it is
+// greater than the timestamp they were previously resolved to. Parts of span
+// that are not tracked are ignored. Returns true if the frontier advanced.
+func (f *spanFrontier) Forward(span roachpb.Span, ts hlc.Timestamp) bool {
+	prev := f.Frontier()
+	entries := make([]spanFrontierEntry, 0, len(f.entries)+2)
+	for _, e := range f.entries {
+		start, end := e.span.Key, e.span.EndKey
+		if bytes.Compare(span.EndKey, start) <= 0 || bytes.Compare(end, span.Key) <= 0 ||
+			!e.ts.Less(ts) {
+			// No overlap or nothing to forward; keep the entry unchanged.
+			entries = append(entries, e)
+			continue
+		}
+		// Split the entry into the pieces before, within and after span.
+		if bytes.Compare(start, span.Key) < 0 {
+			entries = append(entries, spanFrontierEntry{
+				span: roachpb.Span{Key: start, EndKey: span.Key}, ts: e.ts,
+			})
+			start = span.Key
+		}
+		mid := spanFrontierEntry{span: roachpb.Span{Key: start, EndKey: end}, ts: ts}
+		if bytes.Compare(span.EndKey, end) < 0 {
+			mid.span.EndKey = span.EndKey
+			entries = append(entries, mid, spanFrontierEntry{
+				span: roachpb.Span{Key: span.EndKey, EndKey: end}, ts: e.ts,
+			})
+		} else {
+			entries = append(entries, mid)
+		}
+	}
+	// Merge adjacent entries that were resolved to the same timestamp to keep
+	// the number of entries from growing without bound.
+	f.entries = entries[:0]
+	for _, e := range entries {
+		if n := len(f.entries); n > 0 {
+			last := &f.entries[n-1]
+			if last.ts == e.ts && last.span.EndKey.Equal(e.span.Key) {
+				last.span.EndKey = e.span.EndKey
+				continue
+			}
+		}
+		f.entries = append(f.entries, e)
+	}
+	return prev.Less(f.Frontier())
+}
+
+func (f *spanFrontier) String() string {
+	var buf bytes.Buffer
+	for i, e := range f.entries {
+		if i > 0 {
+			buf.WriteString(" ")
+		}
+		fmt.Fprintf(&buf, "[%s %s)@%s", e.span.Key, e.span.EndKey, e.ts)
+	}
+	return buf.String()
+}
@@ -9,6 +9,7 @@
 package sqlccl
 
 import (
+	"github.com/cockroachdb/cockroach/pkg/keys"
 	"github.com/cockroachdb/cockroach/pkg/sql/parser"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
 	"github.com/pkg/errors"
@@ -94,3 +95,43 @@ func descriptorsMatchingTargets(
 	}
 	return ret, nil
 }
+
+// fullClusterSystemTables are the system tables whose contents are included in
+// a full cluster backup, in the order in which a full cluster restore copies
+// them into the restoring cluster: users before anything that may refer to
+// them, and jobs last. The namespace, descriptor, lease and log tables are
+// left out, since descriptors are restored with new IDs and the rest is
+// specific to the nodes of the backed-up cluster.
+var fullClusterSystemTables = []string{
+	sqlbase.UsersTable.Name,
+	sqlbase.SettingsTable.Name,
+	sqlbase.ZonesTable.Name,
+	sqlbase.UITable.Name,
+	sqlbase.JobsTable.Name,
+}
+
+// fullClusterDescriptors returns the descriptors backed up by a full cluster
+// backup: every user database and non-dropped table along with the system
+// database and the descriptors of fullClusterSystemTables.
+func fullClusterDescriptors(descriptors []sqlbase.Descriptor) []sqlbase.Descriptor {
+	systemTables := make(map[string]struct{}, len(fullClusterSystemTables))
+	for _, name := range fullClusterSystemTables {
+		systemTables[name] = struct{}{}
+	}
+
+	var ret []sqlbase.Descriptor
+	for _, desc := range descriptors {
+		if dbDesc := desc.GetDatabase(); dbDesc != nil {
+			ret = append(ret, desc)
+		} else if tableDesc := desc.GetTable(); tableDesc != nil {
+			if tableDesc.ParentID == keys.SystemDatabaseID {
+				if _, ok := systemTables[tableDesc.Name]; ok {
+					ret = append(ret, desc)
+				}
+			} else if !tableDesc.Dropped() {
+				ret = append(ret, desc)
+			}
+		}
+	}
+	return ret
+}
@@ -0,0 +1,111 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/pkg/ccl/LICENSE
+
+package storageccl
+
+import (
+	"fmt"
+	"hash/crc32"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/storageccl/engineccl"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/storage"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine"
+	"github.com/cockroachdb/cockroach/pkg/storage/storagebase"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/tracing"
+)
+
+func init() {
+	storage.SetAddSSTableCmd(storage.Command{
+		DeclareKeys: storage.DefaultDeclareKeys,
+		Eval:        evalAddSSTable,
+	})
+}
+
+// evalAddSSTable links the SSTable in the request into the engines of all
+// replicas. The SSTable is verified and its MVCCStats computed here; the
+// payload itself is sideloaded by raft and ingested when the command applies.
+//
+// Ingested keys shadow existing ones, which wouldn't be reflected in the
+// MVCCStats. So if the affected keyrange already contains data (for example
+// because the command is being retried), the existing data is cleared and the
+// SSTable's contents are written through the WriteBatch instead, which makes
+// this command idempotent just like WriteBatch.
+func evalAddSSTable(
+	ctx context.Context, batch engine.ReadWriter, cArgs storage.CommandArgs, _ roachpb.Response,
+) (storage.EvalResult, error) {
+	args := cArgs.Args.(*roachpb.AddSSTableRequest)
+	h := cArgs.Header
+	ms := cArgs.Stats
+
+	_, span := tracing.ChildSpan(ctx, fmt.Sprintf("AddSSTable [%s,%s)", args.Key, args.EndKey))
+	defer tracing.FinishSpan(span)
+	if log.V(1) {
+		log.Infof(ctx, "addsstable [%s,%s)", args.Key, args.EndKey)
+	}
+
+	mvccStartKey := engine.MVCCKey{Key: args.Key}
+	mvccEndKey := engine.MVCCKey{Key: args.EndKey}
+
+	// Verify that the keys in the SSTable are within the range specified by
+	// the request header. Since the SSTable is an opaque blob to DistSender,
+	// this is also what catches a request which was split across ranges.
+	stats, err := engineccl.VerifySSTable(args.Data, mvccStartKey, mvccEndKey, h.Timestamp.WallTime)
+	if err != nil {
+		return storage.EvalResult{}, errors.Wrap(err, "verifying SSTable")
+	}
+
+	iter := batch.NewIterator(false)
+	defer iter.Close()
+	iter.Seek(mvccStartKey)
+	if ok, err := iter.Valid(); err != nil {
+		return storage.EvalResult{}, err
+	} else if !ok || !iter.Key().Less(mvccEndKey) {
+		// The keyrange is empty, so the SSTable can be ingested as is.
+		ms.Add(stats)
+		var pd storage.EvalResult
+		pd.Replicated.AddSSTable = &storagebase.AddSSTable{
+			Data:  args.Data,
+			CRC32: crc32.ChecksumIEEE(args.Data),
+		}
+		return pd, nil
+	}
+
+	log.VEventf(ctx, 2, "keyrange [%s,%s) is not empty, not sideloading", args.Key, args.EndKey)
+	existingStats, err := iter.ComputeStats(mvccStartKey, mvccEndKey, h.Timestamp.WallTime)
+	if err != nil {
+		return storage.EvalResult{}, err
+	}
+	// If this is a SpanSetIterator, we have to unwrap it because
+	// ClearIterRange needs a plain rocksdb iterator (and can't unwrap it
+	// itself because of import cycles).
+	if ssi, ok := iter.(*storage.SpanSetIterator); ok {
+		iter = ssi.Iterator()
+	}
+	if err := batch.ClearIterRange(iter, mvccStartKey, mvccEndKey); err != nil {
+		return storage.EvalResult{}, err
+	}
+	ms.Subtract(existingStats)
+
+	sst := engine.NewInMem(roachpb.Attributes{}, 1<<20)
+	defer sst.Close()
+	if err := sst.IngestExternalData(args.Data); err != nil {
+		return storage.EvalResult{}, err
+	}
+	if err := sst.Iterate(mvccStartKey, mvccEndKey, func(kv engine.MVCCKeyValue) (bool, error) {
+		return false, batch.Put(kv.Key, kv.Value)
+	}); err != nil {
+		return storage.EvalResult{}, err
+	}
+	ms.Add(stats)
+	return storage.EvalResult{}, nil
+}
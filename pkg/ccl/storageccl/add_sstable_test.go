@@ -0,0 +1,201 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/pkg/ccl/LICENSE
+
+package storageccl
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/storage"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func singleKVSSTable(t *testing.T, key engine.MVCCKey, value []byte) []byte {
+	dir, cleanup := testutils.TempDir(t)
+	defer cleanup()
+	path := filepath.Join(dir, "data.sst")
+
+	sst := engine.MakeRocksDBSstFileWriter()
+	if err := sst.Open(path); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err := sst.Add(engine.MVCCKeyValue{Key: key, Value: value}); err != nil {
+		_ = sst.Close()
+		t.Fatalf("%+v", err)
+	}
+	if err := sst.Close(); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	return data
+}
+
+func TestDBAddSSTable(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	s, _, db := serverutils.StartServer(t, base.TestServerArgs{Insecure: true})
+	defer s.Stopper().Stop()
+	ctx := context.Background()
+
+	{
+		key := engine.MVCCKey{Key: []byte("bb"), Timestamp: hlc.Timestamp{WallTime: 1}}
+		data := singleKVSSTable(t, key, roachpb.MakeValueFromString("1").RawBytes)
+
+		// Key is before the range in the request span.
+		if err := db.AddSSTable(
+			ctx, "d", "e", data,
+		); !testutils.IsError(err, "key not in request range") {
+			t.Fatalf("expected request range error got: %+v", err)
+		}
+		// Key is after the range in the request span.
+		if err := db.AddSSTable(
+			ctx, "a", "b", data,
+		); !testutils.IsError(err, "key not in request range") {
+			t.Fatalf("expected request range error got: %+v", err)
+		}
+
+		if err := db.AddSSTable(ctx, "b", "c", data); err != nil {
+			t.Fatalf("%+v", err)
+		}
+		if result, err := db.Get(ctx, "bb"); err != nil {
+			t.Fatalf("%+v", err)
+		} else if result := result.ValueBytes(); !bytes.Equal([]byte("1"), result) {
+			t.Errorf("expected \"%s\", got \"%s\"", []byte("1"), result)
+		}
+	}
+
+	// Key range in request span is not empty.
+	{
+		key := engine.MVCCKey{Key: []byte("bb2"), Timestamp: hlc.Timestamp{WallTime: 1}}
+		data := singleKVSSTable(t, key, roachpb.MakeValueFromString("2").RawBytes)
+		if err := db.AddSSTable(ctx, "b", "c", data); err != nil {
+			t.Fatalf("%+v", err)
+		}
+
+		if result, err := db.Get(ctx, "bb2"); err != nil {
+			t.Fatalf("%+v", err)
+		} else if result := result.ValueBytes(); !bytes.Equal([]byte("2"), result) {
+			t.Errorf("expected \"%s\", got \"%s\"", []byte("2"), result)
+		}
+
+		if result, err := db.Get(ctx, "bb"); err != nil {
+			t.Fatalf("%+v", err)
+		} else if result := result.ValueBytes(); result != nil {
+			t.Errorf("expected nil, got \"%s\"", result)
+		}
+	}
+
+	// Invalid key/value entry checksum.
+	{
+		key := engine.MVCCKey{Key: []byte("bb"), Timestamp: hlc.Timestamp{WallTime: 1}}
+		value := roachpb.MakeValueFromString("1")
+		value.InitChecksum([]byte("foo"))
+		data := singleKVSSTable(t, key, value.RawBytes)
+
+		if err := db.AddSSTable(ctx, "b", "c", data); !testutils.IsError(err, "invalid checksum") {
+			t.Fatalf("expected 'invalid checksum' error got: %+v", err)
+		}
+	}
+}
+
+func TestAddSSTableMVCCStats(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	e := engine.NewInMem(roachpb.Attributes{}, 1<<20)
+	defer e.Close()
+
+	key := engine.MVCCKey{Key: []byte("bb"), Timestamp: hlc.Timestamp{WallTime: 1}}
+	data := singleKVSSTable(t, key, roachpb.MakeValueFromString("1").RawBytes)
+	span := roachpb.Span{Key: []byte("b"), EndKey: []byte("c")}
+
+	cArgs := storage.CommandArgs{
+		Args: &roachpb.AddSSTableRequest{
+			Span: span,
+			Data: data,
+		},
+		Stats: &enginepb.MVCCStats{},
+	}
+
+	// The keyrange is empty, so the SSTable is sideloaded.
+	pd, err := evalAddSSTable(ctx, e, cArgs, nil)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if pd.Replicated.AddSSTable == nil || !bytes.Equal(pd.Replicated.AddSSTable.Data, data) {
+		t.Fatalf("expected SSTable to be sideloaded, got %+v", pd.Replicated.AddSSTable)
+	}
+	if kvs, err := engine.Scan(e, engine.MVCCKey{Key: keys.MinKey}, engine.MVCCKey{Key: keys.MaxKey}, 0); err != nil {
+		t.Fatal(err)
+	} else if len(kvs) != 0 {
+		t.Fatalf("evaluation should not have written to the engine: %+v", kvs)
+	}
+	expectedStats := &enginepb.MVCCStats{
+		LiveBytes: 21,
+		LiveCount: 1,
+		KeyBytes:  15,
+		KeyCount:  1,
+		ValBytes:  6,
+		ValCount:  1,
+	}
+	if !reflect.DeepEqual(expectedStats, cArgs.Stats) {
+		t.Errorf("mvcc stats mismatch %+v != %+v", expectedStats, cArgs.Stats)
+	}
+
+	// Put something in the keyrange. AddSSTable now clears it and writes the
+	// SSTable's contents through the batch, adjusting the stats accordingly.
+	const numInitialEntries = 100
+	for i := 0; i < numInitialEntries; i++ {
+		if err := e.Put(engine.MVCCKey{Key: append([]byte("b"), byte(i))}, nil); err != nil {
+			t.Fatalf("%+v", err)
+		}
+	}
+	cArgs.Stats = &enginepb.MVCCStats{
+		LiveBytes: 10000,
+		LiveCount: 10000,
+		KeyBytes:  10000,
+		KeyCount:  10000,
+		ValBytes:  10000,
+		ValCount:  10000,
+	}
+	pd, err = evalAddSSTable(ctx, e, cArgs, nil)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if pd.Replicated.AddSSTable != nil {
+		t.Fatal("expected SSTable not to be sideloaded into a non-empty keyrange")
+	}
+	expectedStats = &enginepb.MVCCStats{
+		LiveBytes: 9721,
+		LiveCount: 9901,
+		KeyBytes:  9715,
+		KeyCount:  9901,
+		ValBytes:  10006,
+		ValCount:  10001,
+	}
+	if !reflect.DeepEqual(expectedStats, cArgs.Stats) {
+		t.Errorf("mvcc stats mismatch %+v != %+v", expectedStats, cArgs.Stats)
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/LICENSE
+
+package storageccl
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// encryptionPreamble is the prefix of every encrypted file. It is followed by
+// the version of the encryption scheme, the nonce and the ciphertext, which is
+// sealed with AES-256 in GCM mode and so is authenticated as well.
+var encryptionPreamble = []byte("encrypt")
+
+const (
+	encryptionVersion = 1
+
+	// encryptionSaltSize is the size of the salt generated by GenerateSalt.
+	encryptionSaltSize = 16
+	// encryptionKeySize selects AES-256.
+	encryptionKeySize = 32
+	// encryptionKeyIterations is the number of PBKDF2 iterations used to derive
+	// a key from a passphrase.
+	encryptionKeyIterations = 64000
+)
+
+// GenerateSalt returns a random salt with which to derive keys.
+func GenerateSalt() ([]byte, error) {
+	salt := make([]byte, encryptionSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
+
+// GenerateKey derives a key with which to encrypt files from a passphrase and
+// a salt.
+func GenerateKey(passphrase, salt []byte) []byte {
+	return pbkdf2.Key(passphrase, salt, encryptionKeyIterations, encryptionKeySize, sha256.New)
+}
+
+// AppearsEncrypted returns true if the given file content looks like it was
+// produced by EncryptFile.
+func AppearsEncrypted(text []byte) bool {
+	return bytes.HasPrefix(text, encryptionPreamble)
+}
+
+func aesgcm(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptFile encrypts the given file content with the given key.
+func EncryptFile(plaintext, key []byte) ([]byte, error) {
+	gcm, err := aesgcm(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, len(encryptionPreamble)+1+len(nonce))
+	header = append(header, encryptionPreamble...)
+	header = append(header, encryptionVersion)
+	header = append(header, nonce...)
+	return gcm.Seal(header, nonce, plaintext, nil), nil
+}
+
+// DecryptFile decrypts file content produced by EncryptFile with the given
+// key. It returns an error if the content was not encrypted with that key or
+// has been modified since.
+func DecryptFile(ciphertext, key []byte) ([]byte, error) {
+	if !AppearsEncrypted(ciphertext) {
+		return nil, errors.New("file does not appear to be encrypted")
+	}
+	buf := ciphertext[len(encryptionPreamble):]
+	if len(buf) < 1 || buf[0] != encryptionVersion {
+		return nil, errors.New("unexpected encryption version")
+	}
+	buf = buf[1:]
+
+	gcm, err := aesgcm(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(buf) < gcm.NonceSize() {
+		return nil, errors.New("file is too short to be encrypted")
+	}
+	nonce, buf := buf[:gcm.NonceSize()], buf[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, buf, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "decrypting file (incorrect passphrase or corrupted file?)")
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,173 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/LICENSE
+
+#include <limits.h>
+#include <openssl/evp.h>
+#include <openssl/rand.h>
+#include "crypto.h"
+
+namespace {
+
+// cipherContext is an OpenSSL cipher context which is freed when it goes out
+// of scope.
+class cipherContext {
+ public:
+  cipherContext()
+      : ctx_(EVP_CIPHER_CTX_new()) {
+  }
+  ~cipherContext() {
+    EVP_CIPHER_CTX_free(ctx_);
+  }
+
+  // Init prepares the context to encrypt with the given cipher, key and IV.
+  // It returns false on failure.
+  bool Init(const EVP_CIPHER* cipher, const std::string& key, const uint8_t* iv) {
+    return ctx_ != NULL && cipher != NULL &&
+        EVP_EncryptInit_ex(ctx_, cipher, NULL,
+                           reinterpret_cast<const uint8_t*>(key.data()), iv) == 1 &&
+        EVP_CIPHER_CTX_set_padding(ctx_, 0) == 1;
+  }
+
+  // Update encrypts the n bytes at in into out, which may be the same
+  // buffer. It returns false on failure.
+  bool Update(const uint8_t* in, uint8_t* out, size_t n) {
+    while (n > 0) {
+      // EVP_EncryptUpdate takes the length as an int.
+      const int chunk = n > INT_MAX / 2 ? INT_MAX / 2 : int(n);
+      int out_len = 0;
+      if (EVP_EncryptUpdate(ctx_, out, &out_len, in, chunk) != 1 || out_len != chunk) {
+        return false;
+      }
+      in += chunk;
+      out += chunk;
+      n -= chunk;
+    }
+    return true;
+  }
+
+ private:
+  EVP_CIPHER_CTX* ctx_;
+};
+
+const EVP_CIPHER* aesECB(size_t key_size) {
+  switch (key_size) {
+    case 16:
+      return EVP_aes_128_ecb();
+    case 24:
+      return EVP_aes_192_ecb();
+    case 32:
+      return EVP_aes_256_ecb();
+  }
+  return NULL;
+}
+
+const EVP_CIPHER* aesCTR(size_t key_size) {
+  switch (key_size) {
+    case 16:
+      return EVP_aes_128_ctr();
+    case 24:
+      return EVP_aes_192_ctr();
+    case 32:
+      return EVP_aes_256_ctr();
+  }
+  return NULL;
+}
+
+uint64_t loadBigEndian64(const uint8_t* b) {
+  uint64_t w = 0;
+  for (int i = 0; i < 8; i++) {
+    w = (w << 8) | b[i];
+  }
+  return w;
+}
+
+void storeBigEndian64(uint64_t w, uint8_t* b) {
+  for (int i = 7; i >= 0; i--) {
+    b[i] = uint8_t(w);
+    w >>= 8;
+  }
+}
+
+}  // namespace
+
+bool AESCipher::Init(const std::string& key) {
+  if (key.size() != 16 && key.size() != 24 && key.size() != 32) {
+    return false;
+  }
+  key_ = key;
+  return true;
+}
+
+bool AESCipher::EncryptBlock(const uint8_t* in, uint8_t* out) const {
+  cipherContext ctx;
+  return ctx.Init(aesECB(key_.size()), key_, NULL) && ctx.Update(in, out, kAESBlockSize);
+}
+
+CTRCipherStream::CTRCipherStream(const AESCipher* cipher, const std::string& iv)
+    : cipher_(cipher),
+      iv_hi_(0),
+      iv_lo_(0) {
+  if (iv.size() == kAESBlockSize) {
+    const uint8_t* b = reinterpret_cast<const uint8_t*>(iv.data());
+    iv_hi_ = loadBigEndian64(b);
+    iv_lo_ = loadBigEndian64(b + 8);
+  }
+}
+
+bool CTRCipherStream::XORKeyStream(uint64_t offset, char* data, size_t n) const {
+  // The counter of the block containing offset is the IV plus the index of
+  // the block, as a 128-bit big-endian integer. OpenSSL increments it the
+  // same way for the following blocks.
+  const uint64_t block = offset / kAESBlockSize;
+  const uint64_t lo = iv_lo_ + block;
+  const uint64_t hi = iv_hi_ + (lo < iv_lo_ ? 1 : 0);
+  uint8_t counter[kAESBlockSize];
+  storeBigEndian64(hi, counter);
+  storeBigEndian64(lo, counter + 8);
+
+  cipherContext ctx;
+  if (!ctx.Init(aesCTR(cipher_->key_.size()), cipher_->key_, counter)) {
+    return false;
+  }
+  // Discard the part of the keystream of the first block that precedes the
+  // offset.
+  uint8_t skip[kAESBlockSize] = {0};
+  if (!ctx.Update(skip, skip, offset % kAESBlockSize)) {
+    return false;
+  }
+  uint8_t* b = reinterpret_cast<uint8_t*>(data);
+  return ctx.Update(b, b, n);
+}
+
+bool AESKeyID(const AESCipher& cipher, std::string* id) {
+  static const char kHex[] = "0123456789abcdef";
+  uint8_t block[kAESBlockSize] = {0};
+  if (!cipher.EncryptBlock(block, block)) {
+    return false;
+  }
+  id->clear();
+  for (int i = 0; i < 8; i++) {
+    id->push_back(kHex[block[i] >> 4]);
+    id->push_back(kHex[block[i] & 0xf]);
+  }
+  return true;
+}
+
+bool RandomBytes(char* buf, size_t n) {
+  uint8_t* b = reinterpret_cast<uint8_t*>(buf);
+  while (n > 0) {
+    // RAND_bytes takes the length as an int.
+    const int chunk = n > INT_MAX / 2 ? INT_MAX / 2 : int(n);
+    if (RAND_bytes(b, chunk) != 1) {
+      return false;
+    }
+    b += chunk;
+    n -= chunk;
+  }
+  return true;
+}
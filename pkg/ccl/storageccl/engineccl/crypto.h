@@ -0,0 +1,72 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/LICENSE
+
+#ifndef ROACHLIBCCL_CRYPTO_H
+#define ROACHLIBCCL_CRYPTO_H
+
+#include <stddef.h>
+#include <stdint.h>
+#include <string>
+
+const int kAESBlockSize = 16;
+
+// AESCipher holds an AES key. The cipher itself is OpenSSL's, which uses the
+// AES-NI instructions when the processor has them and otherwise falls back to
+// implementations that avoid secret-dependent table lookups where possible.
+class AESCipher {
+ public:
+  // Init sets the 16, 24 or 32 byte key (for AES-128, AES-192 or AES-256
+  // respectively). It returns false if the key has any other size.
+  bool Init(const std::string& key);
+
+  // EncryptBlock encrypts the 16 byte block at in into out, which may be
+  // the same buffer. It returns false on failure.
+  bool EncryptBlock(const uint8_t* in, uint8_t* out) const;
+
+ private:
+  friend class CTRCipherStream;
+
+  std::string key_;
+};
+
+// CTRCipherStream encrypts and decrypts data with a block cipher in counter
+// mode: the keystream is made of the encryptions of successive increments of
+// an initialization vector. As the keystream doesn't depend on the data, any
+// part of the stream can be processed independently of the rest, which is
+// what allows random access to encrypted files.
+class CTRCipherStream {
+ public:
+  // The cipher must outlive the stream.
+  CTRCipherStream(const AESCipher* cipher, const std::string& iv);
+
+  // XORKeyStream encrypts or decrypts (the operation is its own inverse) the n
+  // bytes at data in place, given that they are located at the specified
+  // offset in the stream. It is safe to call concurrently. It returns false
+  // on failure, in which case the contents of data are unspecified.
+  bool XORKeyStream(uint64_t offset, char* data, size_t n) const;
+
+ private:
+  const AESCipher* cipher_;
+  uint64_t iv_hi_;
+  uint64_t iv_lo_;
+};
+
+// AESKeyID sets id to a non-secret identifier for an AES key: the hex encoding
+// of the first 8 bytes of the encryption of the zero block, also known as its
+// key check value. It returns false on failure.
+bool AESKeyID(const AESCipher& cipher, std::string* id);
+
+// RandomBytes fills buf with n cryptographically secure random bytes. It
+// returns false on failure.
+bool RandomBytes(char* buf, size_t n);
+
+#endif // ROACHLIBCCL_CRYPTO_H
+
+// local variables:
+// mode: c++
+// end:
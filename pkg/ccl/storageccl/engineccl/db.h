@@ -20,6 +20,10 @@ extern "C" {
 DBStatus DBBatchReprVerify(
   DBSlice repr, DBKey start, DBKey end, int64_t now_nanos, MVCCStatsResult* stats);
 
+// DBInitEncryption registers the open hook which implements encryption at
+// rest, configured through DBOptions.extra_options.
+void DBInitEncryption();
+
 #ifdef __cplusplus
 }  // extern "C"
 #endif
@@ -0,0 +1,573 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/LICENSE
+
+#include <string.h>
+#include "rocksdb/env.h"
+#include "rocksdb/options.h"
+#include "../../../storage/engine/db_internal.h"
+#include "db.h"
+#include "encrypted_env.h"
+
+const char kPlainKeyID[] = "plain";
+
+// The header of an encrypted file is laid out as follows (all other bytes are
+// zero):
+//
+//   [0, 8)     magic ("CRDB-ENC")
+//   [8]        format version (1)
+//   [9]        length of the store key ID
+//   [10, 74)   store key ID
+//   [80, 96)   IV
+//   [96, 128)  data key, encrypted with the store key in counter mode using
+//              the same IV
+//
+// The header is followed by the file contents, encrypted with the data key in
+// counter mode. Offsets in the encrypted contents are relative to the end of
+// the header, which is a multiple of the block size of the filesystem so that
+// reads and writes of the contents remain aligned.
+//
+// NB: This layout must be kept in sync with encryption.go.
+const char kMagic[] = "CRDB-ENC";
+const size_t kMagicLen = 8;
+const uint8_t kVersion = 1;
+const size_t kMaxKeyIDLen = 64;
+const size_t kKeyIDOffset = 10;
+const size_t kIVOffset = 80;
+const size_t kDataKeyOffset = 96;
+const size_t kDataKeyLen = 32;
+const size_t kHeaderSize = 4096;
+
+const DBStatus kSuccess = { NULL, 0 };
+
+// FileCipher is the cipher used for the contents of an encrypted file.
+struct FileCipher {
+  AESCipher data_key;
+  CTRCipherStream stream;
+
+  FileCipher(const std::string& key, const std::string& iv)
+      : stream(&data_key, iv) {
+    data_key.Init(key);
+  }
+};
+
+namespace {
+
+// EncryptedSequentialFile decrypts the contents of a file read sequentially.
+class EncryptedSequentialFile : public rocksdb::SequentialFile {
+ public:
+  EncryptedSequentialFile(std::unique_ptr<rocksdb::SequentialFile> file,
+                          std::unique_ptr<FileCipher> cipher)
+      : file_(std::move(file)),
+        cipher_(std::move(cipher)),
+        offset_(0) {
+  }
+
+  rocksdb::Status Read(size_t n, rocksdb::Slice* result, char* scratch) override {
+    rocksdb::Status s = file_->Read(n, result, scratch);
+    if (!s.ok()) {
+      return s;
+    }
+    const size_t size = result->size();
+    if (result->data() != scratch) {
+      memmove(scratch, result->data(), size);
+    }
+    if (!cipher_->stream.XORKeyStream(offset_, scratch, size)) {
+      return rocksdb::Status::IOError("unable to decrypt file contents");
+    }
+    offset_ += size;
+    *result = rocksdb::Slice(scratch, size);
+    return s;
+  }
+
+  rocksdb::Status Skip(uint64_t n) override {
+    rocksdb::Status s = file_->Skip(n);
+    if (s.ok()) {
+      offset_ += n;
+    }
+    return s;
+  }
+
+  rocksdb::Status InvalidateCache(size_t offset, size_t length) override {
+    return file_->InvalidateCache(offset + kHeaderSize, length);
+  }
+
+ private:
+  std::unique_ptr<rocksdb::SequentialFile> file_;
+  std::unique_ptr<FileCipher> cipher_;
+  uint64_t offset_;
+};
+
+// EncryptedRandomAccessFile decrypts the contents of a file read at arbitrary
+// offsets.
+class EncryptedRandomAccessFile : public rocksdb::RandomAccessFile {
+ public:
+  EncryptedRandomAccessFile(std::unique_ptr<rocksdb::RandomAccessFile> file,
+                            std::unique_ptr<FileCipher> cipher)
+      : file_(std::move(file)),
+        cipher_(std::move(cipher)) {
+  }
+
+  rocksdb::Status Read(uint64_t offset, size_t n, rocksdb::Slice* result,
+                       char* scratch) const override {
+    rocksdb::Status s = file_->Read(offset + kHeaderSize, n, result, scratch);
+    if (!s.ok()) {
+      return s;
+    }
+    const size_t size = result->size();
+    if (result->data() != scratch) {
+      memmove(scratch, result->data(), size);
+    }
+    if (!cipher_->stream.XORKeyStream(offset, scratch, size)) {
+      return rocksdb::Status::IOError("unable to decrypt file contents");
+    }
+    *result = rocksdb::Slice(scratch, size);
+    return s;
+  }
+
+  size_t GetUniqueId(char* id, size_t max_size) const override {
+    return file_->GetUniqueId(id, max_size);
+  }
+
+  void Hint(AccessPattern pattern) override {
+    file_->Hint(pattern);
+  }
+
+  rocksdb::Status InvalidateCache(size_t offset, size_t length) override {
+    return file_->InvalidateCache(offset + kHeaderSize, length);
+  }
+
+ private:
+  std::unique_ptr<rocksdb::RandomAccessFile> file_;
+  std::unique_ptr<FileCipher> cipher_;
+};
+
+// EncryptedWritableFile encrypts the contents written to a file. The header
+// must already have been written to the underlying file.
+class EncryptedWritableFile : public rocksdb::WritableFile {
+ public:
+  EncryptedWritableFile(std::unique_ptr<rocksdb::WritableFile> file,
+                        std::unique_ptr<FileCipher> cipher, uint64_t size)
+      : file_(std::move(file)),
+        cipher_(std::move(cipher)),
+        size_(size) {
+  }
+
+  rocksdb::Status Append(const rocksdb::Slice& data) override {
+    buf_.assign(data.data(), data.size());
+    if (!cipher_->stream.XORKeyStream(size_, &buf_[0], buf_.size())) {
+      return rocksdb::Status::IOError("unable to encrypt file contents");
+    }
+    rocksdb::Status s = file_->Append(buf_);
+    if (s.ok()) {
+      size_ += data.size();
+    }
+    return s;
+  }
+
+  rocksdb::Status PositionedAppend(const rocksdb::Slice& data, uint64_t offset) override {
+    buf_.assign(data.data(), data.size());
+    if (!cipher_->stream.XORKeyStream(offset, &buf_[0], buf_.size())) {
+      return rocksdb::Status::IOError("unable to encrypt file contents");
+    }
+    rocksdb::Status s = file_->PositionedAppend(buf_, offset + kHeaderSize);
+    if (s.ok()) {
+      size_ = offset + data.size();
+    }
+    return s;
+  }
+
+  rocksdb::Status Truncate(uint64_t size) override {
+    rocksdb::Status s = file_->Truncate(size + kHeaderSize);
+    if (s.ok()) {
+      size_ = size;
+    }
+    return s;
+  }
+
+  rocksdb::Status Close() override { return file_->Close(); }
+  rocksdb::Status Flush() override { return file_->Flush(); }
+  rocksdb::Status Sync() override { return file_->Sync(); }
+  rocksdb::Status Fsync() override { return file_->Fsync(); }
+  bool IsSyncThreadSafe() const override { return file_->IsSyncThreadSafe(); }
+  uint64_t GetFileSize() override { return size_; }
+
+  size_t GetUniqueId(char* id, size_t max_size) const override {
+    return file_->GetUniqueId(id, max_size);
+  }
+
+  rocksdb::Status InvalidateCache(size_t offset, size_t length) override {
+    return file_->InvalidateCache(offset + kHeaderSize, length);
+  }
+
+  rocksdb::Status RangeSync(uint64_t offset, uint64_t nbytes) override {
+    return file_->RangeSync(offset + kHeaderSize, nbytes);
+  }
+
+  rocksdb::Status Allocate(uint64_t offset, uint64_t len) override {
+    return file_->Allocate(offset + kHeaderSize, len);
+  }
+
+ private:
+  std::unique_ptr<rocksdb::WritableFile> file_;
+  std::unique_ptr<FileCipher> cipher_;
+  uint64_t size_;
+  // buf_ holds the encrypted copy of appended data. RocksDB doesn't append to
+  // a file concurrently, so it is reused across calls.
+  std::string buf_;
+};
+
+}  // namespace
+
+EncryptedEnv::EncryptedEnv(rocksdb::Env* base_env, std::vector<std::unique_ptr<StoreKey>> keys)
+    : rocksdb::EnvWrapper(base_env),
+      keys_(std::move(keys)),
+      active_(keys_.front().get()) {
+  for (const auto& key : keys_) {
+    keys_by_id_[key->id] = key.get();
+  }
+}
+
+EncryptedEnv::~EncryptedEnv() {
+}
+
+rocksdb::Status EncryptedEnv::newHeader(std::string* header, std::unique_ptr<FileCipher>* cipher) {
+  std::string data_key(kDataKeyLen, '\0');
+  std::string iv(kAESBlockSize, '\0');
+  if (!RandomBytes(&data_key[0], data_key.size()) || !RandomBytes(&iv[0], iv.size())) {
+    return rocksdb::Status::IOError("unable to generate data key");
+  }
+
+  header->assign(kHeaderSize, '\0');
+  char* h = &(*header)[0];
+  memcpy(h, kMagic, kMagicLen);
+  h[kMagicLen] = kVersion;
+  h[kMagicLen + 1] = active_->id.size();
+  memcpy(h + kKeyIDOffset, active_->id.data(), active_->id.size());
+  memcpy(h + kIVOffset, iv.data(), iv.size());
+  memcpy(h + kDataKeyOffset, data_key.data(), data_key.size());
+  if (!CTRCipherStream(&active_->cipher, iv).XORKeyStream(0, h + kDataKeyOffset, kDataKeyLen)) {
+    return rocksdb::Status::IOError("unable to encrypt data key");
+  }
+
+  cipher->reset(new FileCipher(data_key, iv));
+  return rocksdb::Status::OK();
+}
+
+rocksdb::Status EncryptedEnv::parseHeader(const std::string& fname, const rocksdb::Slice& header,
+                                          std::unique_ptr<FileCipher>* cipher,
+                                          std::string* key_id) {
+  cipher->reset();
+  if (header.size() < kHeaderSize || memcmp(header.data(), kMagic, kMagicLen) != 0) {
+    if (key_id != nullptr) {
+      *key_id = kPlainKeyID;
+    }
+    return rocksdb::Status::OK();
+  }
+  const char* h = header.data();
+  if (uint8_t(h[kMagicLen]) != kVersion) {
+    return rocksdb::Status::Corruption(fname, "unknown encryption header version");
+  }
+  const size_t id_len = uint8_t(h[kMagicLen + 1]);
+  if (id_len > kMaxKeyIDLen) {
+    return rocksdb::Status::Corruption(fname, "invalid encryption header");
+  }
+  const std::string id(h + kKeyIDOffset, id_len);
+  if (key_id != nullptr) {
+    *key_id = id;
+  }
+  auto it = keys_by_id_.find(id);
+  if (it == keys_by_id_.end() || it->second->plain()) {
+    return rocksdb::Status::InvalidArgument(
+        fname, "encrypted with store key " + id + ", which was not provided");
+  }
+
+  const std::string iv(h + kIVOffset, kAESBlockSize);
+  std::string data_key(h + kDataKeyOffset, kDataKeyLen);
+  if (!CTRCipherStream(&it->second->cipher, iv).XORKeyStream(0, &data_key[0], data_key.size())) {
+    return rocksdb::Status::IOError(fname, "unable to decrypt data key");
+  }
+  cipher->reset(new FileCipher(data_key, iv));
+  return rocksdb::Status::OK();
+}
+
+rocksdb::Status EncryptedEnv::readHeader(const std::string& fname,
+                                         std::unique_ptr<FileCipher>* cipher,
+                                         std::string* key_id) {
+  std::unique_ptr<rocksdb::SequentialFile> file;
+  rocksdb::Status s = target()->NewSequentialFile(fname, &file, rocksdb::EnvOptions());
+  if (!s.ok()) {
+    return s;
+  }
+  std::string scratch(kHeaderSize, '\0');
+  rocksdb::Slice header;
+  s = file->Read(kHeaderSize, &header, &scratch[0]);
+  if (!s.ok()) {
+    return s;
+  }
+  return parseHeader(fname, header, cipher, key_id);
+}
+
+rocksdb::Status EncryptedEnv::NewSequentialFile(const std::string& fname,
+                                                std::unique_ptr<rocksdb::SequentialFile>* result,
+                                                const rocksdb::EnvOptions& options) {
+  std::unique_ptr<rocksdb::SequentialFile> file;
+  rocksdb::Status s = target()->NewSequentialFile(fname, &file, options);
+  if (!s.ok()) {
+    return s;
+  }
+  std::string scratch(kHeaderSize, '\0');
+  rocksdb::Slice header;
+  s = file->Read(kHeaderSize, &header, &scratch[0]);
+  if (!s.ok()) {
+    return s;
+  }
+  std::unique_ptr<FileCipher> cipher;
+  s = parseHeader(fname, header, &cipher, nullptr);
+  if (!s.ok()) {
+    return s;
+  }
+  if (cipher == nullptr) {
+    // The file isn't encrypted: reopen it to read it from the start.
+    return target()->NewSequentialFile(fname, result, options);
+  }
+  result->reset(new EncryptedSequentialFile(std::move(file), std::move(cipher)));
+  return rocksdb::Status::OK();
+}
+
+rocksdb::Status EncryptedEnv::NewRandomAccessFile(const std::string& fname,
+                                                  std::unique_ptr<rocksdb::RandomAccessFile>* result,
+                                                  const rocksdb::EnvOptions& options) {
+  std::unique_ptr<rocksdb::RandomAccessFile> file;
+  rocksdb::Status s = target()->NewRandomAccessFile(fname, &file, options);
+  if (!s.ok()) {
+    return s;
+  }
+  std::string scratch(kHeaderSize, '\0');
+  rocksdb::Slice header;
+  s = file->Read(0, kHeaderSize, &header, &scratch[0]);
+  if (!s.ok()) {
+    return s;
+  }
+  std::unique_ptr<FileCipher> cipher;
+  s = parseHeader(fname, header, &cipher, nullptr);
+  if (!s.ok()) {
+    return s;
+  }
+  if (cipher == nullptr) {
+    *result = std::move(file);
+  } else {
+    result->reset(new EncryptedRandomAccessFile(std::move(file), std::move(cipher)));
+  }
+  return rocksdb::Status::OK();
+}
+
+rocksdb::Status EncryptedEnv::NewWritableFile(const std::string& fname,
+                                              std::unique_ptr<rocksdb::WritableFile>* result,
+                                              const rocksdb::EnvOptions& options) {
+  std::unique_ptr<rocksdb::WritableFile> file;
+  rocksdb::Status s = target()->NewWritableFile(fname, &file, options);
+  if (!s.ok() || active_->plain()) {
+    *result = std::move(file);
+    return s;
+  }
+  std::string header;
+  std::unique_ptr<FileCipher> cipher;
+  s = newHeader(&header, &cipher);
+  if (!s.ok()) {
+    return s;
+  }
+  s = file->Append(header);
+  if (!s.ok()) {
+    return s;
+  }
+  result->reset(new EncryptedWritableFile(std::move(file), std::move(cipher), 0));
+  return rocksdb::Status::OK();
+}
+
+rocksdb::Status EncryptedEnv::ReopenWritableFile(const std::string& fname,
+                                                 std::unique_ptr<rocksdb::WritableFile>* result,
+                                                 const rocksdb::EnvOptions& options) {
+  uint64_t size = 0;
+  if (target()->FileExists(fname).ok()) {
+    rocksdb::Status s = target()->GetFileSize(fname, &size);
+    if (!s.ok()) {
+      return s;
+    }
+  }
+  if (size == 0) {
+    // Appending to an empty file is the same as writing a new one, except
+    // that the file must not be truncated.
+    std::unique_ptr<rocksdb::WritableFile> file;
+    rocksdb::Status s = target()->ReopenWritableFile(fname, &file, options);
+    if (!s.ok() || active_->plain()) {
+      *result = std::move(file);
+      return s;
+    }
+    std::string header;
+    std::unique_ptr<FileCipher> cipher;
+    s = newHeader(&header, &cipher);
+    if (!s.ok()) {
+      return s;
+    }
+    s = file->Append(header);
+    if (!s.ok()) {
+      return s;
+    }
+    result->reset(new EncryptedWritableFile(std::move(file), std::move(cipher), 0));
+    return rocksdb::Status::OK();
+  }
+
+  // Existing files are appended to with whatever key they were written with
+  // (or unencrypted), as a file can't mix the two.
+  std::unique_ptr<FileCipher> cipher;
+  rocksdb::Status s = readHeader(fname, &cipher, nullptr);
+  if (!s.ok()) {
+    return s;
+  }
+  std::unique_ptr<rocksdb::WritableFile> file;
+  s = target()->ReopenWritableFile(fname, &file, options);
+  if (!s.ok() || cipher == nullptr) {
+    *result = std::move(file);
+    return s;
+  }
+  result->reset(new EncryptedWritableFile(std::move(file), std::move(cipher), size - kHeaderSize));
+  return rocksdb::Status::OK();
+}
+
+rocksdb::Status EncryptedEnv::ReuseWritableFile(const std::string& fname,
+                                                const std::string& old_fname,
+                                                std::unique_ptr<rocksdb::WritableFile>* result,
+                                                const rocksdb::EnvOptions& options) {
+  // Recycled files can't keep their old contents as they need a new header
+  // (and data key), so reusing a file is just renaming and truncating it.
+  rocksdb::Status s = target()->RenameFile(old_fname, fname);
+  if (!s.ok()) {
+    return s;
+  }
+  return NewWritableFile(fname, result, options);
+}
+
+rocksdb::Status EncryptedEnv::GetFileSize(const std::string& fname, uint64_t* file_size) {
+  rocksdb::Status s = target()->GetFileSize(fname, file_size);
+  if (!s.ok() || *file_size < kHeaderSize) {
+    return s;
+  }
+  std::unique_ptr<FileCipher> cipher;
+  std::string key_id;
+  s = readHeader(fname, &cipher, &key_id);
+  if (!s.ok()) {
+    return s;
+  }
+  if (key_id != kPlainKeyID) {
+    *file_size -= kHeaderSize;
+  }
+  return rocksdb::Status::OK();
+}
+
+rocksdb::Status EncryptedEnv::GetChildrenFileAttributes(
+    const std::string& dir, std::vector<rocksdb::Env::FileAttributes>* result) {
+  // The default implementation uses GetFileSize, which accounts for the
+  // headers of encrypted files, while the wrapped env's might not.
+  return rocksdb::Env::GetChildrenFileAttributes(dir, result);
+}
+
+rocksdb::Status EncryptedEnv::ReencryptFile(const std::string& fname) {
+  std::unique_ptr<FileCipher> cipher;
+  std::string key_id;
+  rocksdb::Status s = readHeader(fname, &cipher, &key_id);
+  if (!s.ok() || key_id == active_->id) {
+    return s;
+  }
+  std::string data;
+  s = rocksdb::ReadFileToString(this, fname, &data);
+  if (!s.ok()) {
+    return s;
+  }
+  const std::string tmp = fname + ".reencrypt";
+  s = rocksdb::WriteStringToFile(this, data, tmp, true /* should_sync */);
+  if (!s.ok()) {
+    return s;
+  }
+  return RenameFile(tmp, fname);
+}
+
+namespace {
+
+// parseStoreKeys parses the extra options passed by encryption.go, which
+// are a sequence of store keys, each a one byte length followed by the key
+// itself (an empty key denotes plaintext). The first key is the active one.
+DBStatus parseStoreKeys(const std::string& opts, std::vector<std::unique_ptr<StoreKey>>* keys) {
+  size_t pos = 0;
+  while (pos < opts.size()) {
+    const size_t len = uint8_t(opts[pos++]);
+    if (pos + len > opts.size()) {
+      return FmtStatus("invalid encryption options");
+    }
+    std::unique_ptr<StoreKey> key(new StoreKey);
+    if (len == 0) {
+      key->id = kPlainKeyID;
+    } else {
+      if (!key->cipher.Init(opts.substr(pos, len))) {
+        return FmtStatus("invalid store key length %d", int(len));
+      }
+      if (!AESKeyID(key->cipher, &key->id)) {
+        return FmtStatus("unable to compute store key ID");
+      }
+    }
+    pos += len;
+    keys->push_back(std::move(key));
+  }
+  if (keys->empty()) {
+    return FmtStatus("no store key specified");
+  }
+  return kSuccess;
+}
+
+// DBOpenHookCCL is the DBOpenHook which sets up encryption at rest.
+DBStatus DBOpenHookCCL(const std::string& db_dir, const DBOptions db_opts,
+                       rocksdb::Options* options, rocksdb::Env** env) {
+  std::vector<std::unique_ptr<StoreKey>> keys;
+  DBStatus status = parseStoreKeys(ToString(db_opts.extra_options), &keys);
+  if (status.data != NULL) {
+    return status;
+  }
+
+  EncryptedEnv* encrypted_env = new EncryptedEnv(options->env, std::move(keys));
+  *env = encrypted_env;
+  options->env = encrypted_env;
+  // Direct writes require aligned buffers, which the encryption of appended
+  // data doesn't preserve.
+  options->use_direct_writes = false;
+
+  // Files which are never rewritten by RocksDB in the normal course of
+  // operation are re-encrypted when the database is opened so that key
+  // rotation can complete. The other files are either recreated on every
+  // open (MANIFEST, CURRENT, WAL) or rewritten by compactions (sstables).
+  if (!db_dir.empty() && encrypted_env->FileExists(db_dir).ok()) {
+    std::vector<std::string> children;
+    rocksdb::Status s = encrypted_env->GetChildren(db_dir, &children);
+    if (!s.ok()) {
+      return ToDBStatus(s);
+    }
+    for (const auto& child : children) {
+      if (child == "IDENTITY" || child.compare(0, 8, "OPTIONS-") == 0) {
+        s = encrypted_env->ReencryptFile(db_dir + "/" + child);
+        if (!s.ok()) {
+          return ToDBStatus(s);
+        }
+      }
+    }
+  }
+  return kSuccess;
+}
+
+}  // namespace
+
+void DBInitEncryption() {
+  DBSetOpenHook(DBOpenHookCCL);
+}
@@ -0,0 +1,93 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/LICENSE
+
+#ifndef ROACHLIBCCL_ENCRYPTED_ENV_H
+#define ROACHLIBCCL_ENCRYPTED_ENV_H
+
+#include <map>
+#include <memory>
+#include <string>
+#include <vector>
+#include "rocksdb/env.h"
+#include "crypto.h"
+
+// kPlainKeyID is the key ID of unencrypted files. It is also used as the ID
+// of the active store key when new files are to be written unencrypted.
+extern const char kPlainKeyID[];
+
+// StoreKey is a key used to encrypt the data keys of a store's files.
+struct StoreKey {
+  std::string id;
+  AESCipher cipher;
+
+  bool plain() const { return id == kPlainKeyID; }
+};
+
+struct FileCipher;
+
+// EncryptedEnv is an Env that encrypts the contents of the files it writes
+// with AES in counter mode. Each file has its own randomly generated data
+// key, which is itself encrypted with the active store key and stored, along
+// with the ID of that store key, in a fixed size header at the start of the
+// file. Files encrypted with any of the known store keys, as well as
+// unencrypted files, can be read, which allows rotating the store key without
+// rewriting all the data at once.
+class EncryptedEnv : public rocksdb::EnvWrapper {
+ public:
+  // The first of keys is the active store key. The base env must outlive the
+  // EncryptedEnv.
+  EncryptedEnv(rocksdb::Env* base_env, std::vector<std::unique_ptr<StoreKey>> keys);
+  virtual ~EncryptedEnv();
+
+  rocksdb::Status NewSequentialFile(const std::string& fname,
+                                    std::unique_ptr<rocksdb::SequentialFile>* result,
+                                    const rocksdb::EnvOptions& options) override;
+  rocksdb::Status NewRandomAccessFile(const std::string& fname,
+                                      std::unique_ptr<rocksdb::RandomAccessFile>* result,
+                                      const rocksdb::EnvOptions& options) override;
+  rocksdb::Status NewWritableFile(const std::string& fname,
+                                  std::unique_ptr<rocksdb::WritableFile>* result,
+                                  const rocksdb::EnvOptions& options) override;
+  rocksdb::Status ReopenWritableFile(const std::string& fname,
+                                     std::unique_ptr<rocksdb::WritableFile>* result,
+                                     const rocksdb::EnvOptions& options) override;
+  rocksdb::Status ReuseWritableFile(const std::string& fname,
+                                    const std::string& old_fname,
+                                    std::unique_ptr<rocksdb::WritableFile>* result,
+                                    const rocksdb::EnvOptions& options) override;
+  rocksdb::Status GetFileSize(const std::string& fname, uint64_t* file_size) override;
+  rocksdb::Status GetChildrenFileAttributes(
+      const std::string& dir, std::vector<rocksdb::Env::FileAttributes>* result) override;
+
+  // ReencryptFile rewrites the file so that it is encrypted with the active
+  // store key, if it isn't already. It must not be used on files that may be
+  // concurrently written.
+  rocksdb::Status ReencryptFile(const std::string& fname);
+
+ private:
+  // newHeader generates a data key for a new file and returns the file's
+  // header along with the cipher for its contents.
+  rocksdb::Status newHeader(std::string* header, std::unique_ptr<FileCipher>* cipher);
+  // parseHeader returns the cipher for the contents of the file with the
+  // given header, or a null cipher if the file is not encrypted.
+  rocksdb::Status parseHeader(const std::string& fname, const rocksdb::Slice& header,
+                              std::unique_ptr<FileCipher>* cipher, std::string* key_id);
+  // readHeader reads and parses the header of the named file.
+  rocksdb::Status readHeader(const std::string& fname, std::unique_ptr<FileCipher>* cipher,
+                             std::string* key_id);
+
+  std::vector<std::unique_ptr<StoreKey>> keys_;
+  std::map<std::string, const StoreKey*> keys_by_id_;
+  const StoreKey* active_;
+};
+
+#endif // ROACHLIBCCL_ENCRYPTED_ENV_H
+
+// local variables:
+// mode: c++
+// end:
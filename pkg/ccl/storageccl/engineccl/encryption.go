@@ -0,0 +1,308 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/pkg/ccl/LICENSE
+
+package engineccl
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine"
+	"github.com/cockroachdb/cockroach/pkg/util/envutil"
+	"github.com/cockroachdb/cockroach/pkg/util/humanizeutil"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+)
+
+// #include "db.h"
+import "C"
+
+func init() {
+	C.DBInitEncryption()
+	engine.SetEncryptionHook(encryptionHook)
+}
+
+// The layout of the header of encrypted files. See encrypted_env.cc.
+const (
+	encryptionMagic       = "CRDB-ENC"
+	encryptionKeyIDOffset = 10
+	encryptionMaxKeyIDLen = 64
+	encryptionHeaderSize  = 4096
+)
+
+// encryptionRotationInterval is the interval at which the files of a store
+// are checked for ones that are still encrypted with an old store key.
+var encryptionRotationInterval = envutil.EnvOrDefaultDuration(
+	"COCKROACH_ENCRYPTION_ROTATION_INTERVAL", time.Minute)
+
+// encryptionRotationBytes is the maximum total size of the sstables that are
+// rewritten with the active store key per rotation interval, which limits the
+// impact of key rotation on foreground traffic.
+var encryptionRotationBytes = envutil.EnvOrDefaultBytes(
+	"COCKROACH_ENCRYPTION_ROTATION_BYTES", 256<<20)
+
+// storeKey is an AES key used to encrypt the data keys of a store's files.
+type storeKey struct {
+	// id identifies the key in file headers. It is derived from the key (see
+	// storeKeyID) or, for plaintext, is base.PlainStoreKey.
+	id string
+	// key is nil for plaintext.
+	key []byte
+}
+
+// storeKeyID returns the identifier of an AES key: the hex encoding of the
+// first 8 bytes of the encryption of the zero block with the key (its key
+// check value). This must match AESKeyID in crypto.cc.
+func storeKeyID(key []byte) (string, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	var check [aes.BlockSize]byte
+	block.Encrypt(check[:], check[:])
+	return hex.EncodeToString(check[:8]), nil
+}
+
+// loadStoreKey reads the store key in the file at the given path, which must
+// contain exactly 16, 24 or 32 bytes (for AES-128, AES-192 or AES-256
+// respectively). A path of base.PlainStoreKey denotes plaintext.
+func loadStoreKey(path string) (storeKey, error) {
+	if path == base.PlainStoreKey {
+		return storeKey{id: base.PlainStoreKey}, nil
+	}
+	key, err := ioutil.ReadFile(path)
+	if err != nil {
+		return storeKey{}, errors.Wrap(err, "reading store key")
+	}
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		return storeKey{}, errors.Errorf(
+			"store key file %s must contain a 16, 24 or 32 byte AES key, found %d bytes", path, len(key))
+	}
+	id, err := storeKeyID(key)
+	if err != nil {
+		return storeKey{}, err
+	}
+	return storeKey{id: id, key: key}, nil
+}
+
+// StoreKeyID returns the ID under which the files encrypted with the store key
+// in the file at the given path are recorded, or base.PlainStoreKey if the
+// path is base.PlainStoreKey.
+func StoreKeyID(path string) (string, error) {
+	k, err := loadStoreKey(path)
+	return k.id, err
+}
+
+// encodeEncryptionOptions encodes the store keys in the format expected by
+// parseStoreKeys in encrypted_env.cc: each key is prefixed by its length, and
+// an empty key denotes plaintext. The active key comes first.
+func encodeEncryptionOptions(keys ...storeKey) []byte {
+	var buf bytes.Buffer
+	for _, k := range keys {
+		buf.WriteByte(byte(len(k.key)))
+		buf.Write(k.key)
+	}
+	return buf.Bytes()
+}
+
+// encryptionHook implements engine.EncryptionHook.
+func encryptionHook(
+	dir, keyPath, oldKeyPath string,
+) ([]byte, func(*engine.RocksDB, <-chan struct{}), error) {
+	active, err := loadStoreKey(keyPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	keys := []storeKey{active}
+	if oldKeyPath != "" {
+		old, err := loadStoreKey(oldKeyPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		keys = append(keys, old)
+	}
+	return encodeEncryptionOptions(keys...), func(r *engine.RocksDB, stop <-chan struct{}) {
+		rotateStoreKey(r, dir, active.id, stop)
+	}, nil
+}
+
+// fileKeyID returns the ID of the store key with which the file at the given
+// path is encrypted, or base.PlainStoreKey if it isn't.
+func fileKeyID(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	header := make([]byte, encryptionHeaderSize)
+	if _, err := io.ReadFull(f, header); err == io.EOF || err == io.ErrUnexpectedEOF {
+		return base.PlainStoreKey, nil
+	} else if err != nil {
+		return "", err
+	}
+	if !bytes.HasPrefix(header, []byte(encryptionMagic)) {
+		return base.PlainStoreKey, nil
+	}
+	idLen := int(header[encryptionKeyIDOffset-1])
+	if idLen > encryptionMaxKeyIDLen {
+		return "", errors.Errorf("%s: invalid encryption header", path)
+	}
+	return string(header[encryptionKeyIDOffset : encryptionKeyIDOffset+idLen]), nil
+}
+
+// EncryptionStatus describes how far along a store is in encrypting its files
+// with its active store key.
+type EncryptionStatus struct {
+	ActiveKeyID string
+	// Files and Bytes are the number and total size of the store's files.
+	Files int
+	Bytes int64
+	// ActiveFiles and ActiveBytes are the number and total size of the files
+	// encrypted with the active store key.
+	ActiveFiles int
+	ActiveBytes int64
+	// KeyFiles is the number of files encrypted with each store key, by key
+	// ID; unencrypted files are counted under base.PlainStoreKey.
+	KeyFiles map[string]int
+}
+
+// Percent returns the percentage of the store's files that are encrypted with
+// the active store key.
+func (s EncryptionStatus) Percent() float64 {
+	if s.Files == 0 {
+		return 100
+	}
+	return 100 * float64(s.ActiveFiles) / float64(s.Files)
+}
+
+func (s EncryptionStatus) String() string {
+	return fmt.Sprintf("%.1f%% of files (%d/%d, %s/%s) encrypted with active store key %s",
+		s.Percent(), s.ActiveFiles, s.Files,
+		humanizeutil.IBytes(s.ActiveBytes), humanizeutil.IBytes(s.Bytes), s.ActiveKeyID)
+}
+
+// encryptionSkippedFiles are the files in a store directory that are not
+// written by RocksDB and so are never encrypted.
+var encryptionSkippedFiles = map[string]struct{}{
+	"LOCK":                {},
+	"COCKROACHDB_VERSION": {},
+}
+
+// GetEncryptionStatus returns the encryption status of the store in the
+// given directory, whose active store key has the given ID.
+func GetEncryptionStatus(dir, activeKeyID string) (EncryptionStatus, error) {
+	status := EncryptionStatus{ActiveKeyID: activeKeyID, KeyFiles: make(map[string]int)}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return status, err
+	}
+	for _, info := range infos {
+		if !info.Mode().IsRegular() {
+			continue
+		}
+		if _, ok := encryptionSkippedFiles[info.Name()]; ok {
+			continue
+		}
+		id, err := fileKeyID(filepath.Join(dir, info.Name()))
+		if os.IsNotExist(err) {
+			// The file was deleted concurrently (e.g. by a compaction).
+			continue
+		} else if err != nil {
+			return status, err
+		}
+		status.Files++
+		status.Bytes += info.Size()
+		status.KeyFiles[id]++
+		if id == activeKeyID {
+			status.ActiveFiles++
+			status.ActiveBytes += info.Size()
+		}
+	}
+	return status, nil
+}
+
+// rotateStoreKey gradually re-encrypts the files of a store with the active
+// store key after a key rotation, until stop is closed. Most files are
+// rewritten by RocksDB in the normal course of operation, or when it is
+// opened; the remaining sstables are rewritten by forcing compactions of their
+// key ranges, a bounded amount at a time.
+func rotateStoreKey(r *engine.RocksDB, dir, activeKeyID string, stop <-chan struct{}) {
+	ctx := context.TODO()
+	status, err := GetEncryptionStatus(dir, activeKeyID)
+	if err != nil {
+		log.Warningf(ctx, "unable to get encryption status of %s: %s", dir, err)
+	} else {
+		log.Infof(ctx, "encryption status of %s: %s", dir, status)
+	}
+	rotating := err != nil || status.ActiveFiles < status.Files
+
+	ticker := time.NewTicker(encryptionRotationInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+		if !rotating {
+			continue
+		}
+		if err := reencryptSSTables(r, dir, activeKeyID); err != nil {
+			log.Warningf(ctx, "unable to re-encrypt sstables of %s: %s", dir, err)
+			continue
+		}
+		status, err := GetEncryptionStatus(dir, activeKeyID)
+		if err != nil {
+			log.Warningf(ctx, "unable to get encryption status of %s: %s", dir, err)
+			continue
+		}
+		log.Infof(ctx, "encryption status of %s: %s", dir, status)
+		if status.ActiveFiles == status.Files {
+			log.Infof(ctx, "store key rotation of %s complete; the old key is no longer needed", dir)
+			rotating = false
+		}
+	}
+}
+
+// reencryptSSTables compacts the key ranges of sstables that are not
+// encrypted with the active store key, up to encryptionRotationBytes worth of
+// sstables.
+func reencryptSSTables(r *engine.RocksDB, dir, activeKeyID string) error {
+	var rewritten int64
+	for _, sst := range r.GetSSTables() {
+		if rewritten >= encryptionRotationBytes {
+			break
+		}
+		id, err := fileKeyID(filepath.Join(dir, sst.Name))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if id == activeKeyID {
+			continue
+		}
+		if err := r.CompactRange(sst.Start, sst.End); err != nil {
+			return err
+		}
+		rewritten += sst.Size
+	}
+	return nil
+}
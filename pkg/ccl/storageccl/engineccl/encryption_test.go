@@ -0,0 +1,232 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/pkg/ccl/LICENSE
+
+package engineccl
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine"
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func writeStoreKey(t *testing.T, dir, name string, size int) string {
+	key := make([]byte, size)
+	for i := range key {
+		key[i] = byte(len(name) + i)
+	}
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, key, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadStoreKey(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	dir, cleanup := testutils.TempDir(t)
+	defer cleanup()
+
+	for _, size := range []int{16, 24, 32} {
+		key, err := loadStoreKey(writeStoreKey(t, dir, "valid.key", size))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(key.key) != size || len(key.id) != 16 {
+			t.Errorf("unexpected key for size %d: %+v", size, key)
+		}
+	}
+	if _, err := loadStoreKey(writeStoreKey(t, dir, "invalid.key", 7)); !testutils.IsError(
+		err, "must contain a 16, 24 or 32 byte AES key, found 7 bytes",
+	) {
+		t.Errorf("expected invalid key error, got %v", err)
+	}
+	if _, err := loadStoreKey(filepath.Join(dir, "missing.key")); !testutils.IsError(
+		err, "reading store key",
+	) {
+		t.Errorf("expected missing key error, got %v", err)
+	}
+	if key, err := loadStoreKey(base.PlainStoreKey); err != nil || key.key != nil ||
+		key.id != base.PlainStoreKey {
+		t.Errorf("unexpected plain key %+v: %v", key, err)
+	}
+}
+
+// decryptFile decrypts the contents of a file written by the encrypted env,
+// independently of the C++ implementation.
+func decryptFile(path string, key []byte) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < encryptionHeaderSize || !bytes.HasPrefix(data, []byte(encryptionMagic)) {
+		return nil, errors.Errorf("%s is not encrypted", path)
+	}
+	iv := data[80:96]
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	dataKey := make([]byte, 32)
+	cipher.NewCTR(block, iv).XORKeyStream(dataKey, data[96:128])
+	if block, err = aes.NewCipher(dataKey); err != nil {
+		return nil, err
+	}
+	contents := data[encryptionHeaderSize:]
+	cipher.NewCTR(block, iv).XORKeyStream(contents, contents)
+	return contents, nil
+}
+
+func TestEncryptedRocksDB(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	dir, cleanup := testutils.TempDir(t)
+	defer cleanup()
+	keysDir, keysCleanup := testutils.TempDir(t)
+	defer keysCleanup()
+
+	cache := engine.NewRocksDBCache(1 << 20)
+	defer cache.Release()
+
+	key := engine.MakeMVCCMetadataKey(roachpb.Key("a"))
+	value := []byte(strings.Repeat("secret", 100))
+
+	open := func(keyPath, oldKeyPath string) (*engine.RocksDB, error) {
+		return engine.NewEncryptedRocksDB(roachpb.Attributes{}, dir, cache, 0,
+			engine.DefaultMaxOpenFiles, keyPath, oldKeyPath)
+	}
+	checkValue := func(db *engine.RocksDB) {
+		if v, err := db.Get(key); err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(v, value) {
+			t.Fatalf("expected %q, got %q", value, v)
+		}
+	}
+	checkStatus := func(keyPath string, expected float64) {
+		k, err := loadStoreKey(keyPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testutils.SucceedsSoon(t, func() error {
+			status, err := GetEncryptionStatus(dir, k.id)
+			if err != nil {
+				return err
+			}
+			if status.Percent() != expected {
+				return errors.Errorf("expected %.1f%% encrypted, got %s", expected, status)
+			}
+			if expected == 100 && status.KeyFiles[k.id] != status.Files {
+				return errors.Errorf("expected all files under key %s, got %v", k.id, status.KeyFiles)
+			}
+			return nil
+		})
+	}
+
+	// Write some data with encryption enabled.
+	keyPath := writeStoreKey(t, keysDir, "store.key", 32)
+	db, err := open(keyPath, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Put(key, value); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	checkValue(db)
+	db.Close()
+	checkStatus(keyPath, 100)
+
+	// The data isn't readable on disk, but can be decrypted with the key.
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, info := range infos {
+		if !info.Mode().IsRegular() {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, info.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(data, []byte("secret")) {
+			t.Errorf("%s contains plaintext data", info.Name())
+		}
+	}
+	storeKey, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current, err := decryptFile(filepath.Join(dir, "CURRENT"), storeKey); err != nil {
+		t.Fatal(err)
+	} else if !bytes.HasPrefix(current, []byte("MANIFEST-")) {
+		t.Fatalf("unexpected decrypted CURRENT file: %q", current)
+	}
+
+	// The store can't be opened without its key.
+	if _, err := engine.NewRocksDB(roachpb.Attributes{}, dir, cache, 0,
+		engine.DefaultMaxOpenFiles); err == nil {
+		t.Fatal("expected error opening encrypted store without its key")
+	}
+	otherKeyPath := writeStoreKey(t, keysDir, "other.key", 16)
+	if _, err := open(otherKeyPath, ""); !testutils.IsError(err, "which was not provided") {
+		t.Fatalf("expected unknown key error, got %v", err)
+	}
+
+	// Rotate the key. The data is re-encrypted with the new key once it's
+	// compacted.
+	newKeyPath := writeStoreKey(t, keysDir, "new.key", 24)
+	db, err = open(newKeyPath, keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkValue(db)
+	if err := db.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	checkStatus(newKeyPath, 100)
+	db.Close()
+
+	db, err = open(newKeyPath, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkValue(db)
+	db.Close()
+
+	// Decrypt the store.
+	db, err = open(base.PlainStoreKey, newKeyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+	checkStatus(base.PlainStoreKey, 100)
+	db, err = engine.NewRocksDB(roachpb.Attributes{}, dir, cache, 0, engine.DefaultMaxOpenFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkValue(db)
+	db.Close()
+}
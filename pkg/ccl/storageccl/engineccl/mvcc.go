@@ -20,7 +20,9 @@ import (
 // [startKey,endKey) and time range [startTime,endTime). If a key was added or
 // modified between startTime and endTime, the iterator will position at the
 // most recent version (before endTime) of that key. If the key was most
-// recently deleted, this is signalled with an empty value.
+// recently deleted, this is signalled with an empty value. NextRevision can be
+// used instead of Next to visit every version of the key in the time range,
+// including deletions, from newest to oldest.
 //
 // Expected usage:
 //    iter := NewMVCCIncrementalIterator(e)
@@ -147,6 +149,17 @@ func (i *MVCCIncrementalIterator) Next() {
 	}
 }
 
+// NextRevision advances the iterator to the next version of the current key
+// in the time range or, if there is none, to the most recent version of the
+// next key in the iteration.
+func (i *MVCCIncrementalIterator) NextRevision() {
+	if i.valid && i.nextkey {
+		i.nextkey = false
+		i.iter.Next()
+	}
+	i.Next()
+}
+
 // Valid returns true if the iterator is currently valid. An iterator that
 // hasn't had Reset called on it or has gone past the end of the key range is
 // invalid.
@@ -169,3 +169,78 @@ func TestMVCCIterateIncremental(t *testing.T) {
 	}
 	t.Run("intents4", assertEqualKVs(e, keyMin, keyMax, ts0, tsMax, kvs(kv1_4_4, kv2_2_2)))
 }
+
+func TestMVCCIterateAllRevisions(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	ctx := context.Background()
+	e := engine.NewInMem(roachpb.Attributes{}, 1<<20)
+	defer e.Close()
+
+	var (
+		testKey1 = roachpb.Key("/db1")
+		testKey2 = roachpb.Key("/db2")
+
+		ts1 = hlc.Timestamp{WallTime: 1}
+		ts2 = hlc.Timestamp{WallTime: 2}
+		ts3 = hlc.Timestamp{WallTime: 3}
+		ts4 = hlc.Timestamp{WallTime: 4}
+		ts5 = hlc.Timestamp{WallTime: 5}
+	)
+
+	for _, kv := range []engine.MVCCKeyValue{
+		{Key: engine.MVCCKey{Key: testKey1, Timestamp: ts1}, Value: []byte("val1")},
+		{Key: engine.MVCCKey{Key: testKey1, Timestamp: ts2}, Value: []byte("val2")},
+		{Key: engine.MVCCKey{Key: testKey2, Timestamp: ts2}, Value: []byte("val3")},
+	} {
+		v := roachpb.Value{RawBytes: kv.Value}
+		if err := engine.MVCCPut(ctx, e, nil, kv.Key.Key, kv.Key.Timestamp, v, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := engine.MVCCDelete(ctx, e, nil, testKey1, ts3, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	revisions := func(startTime, endTime hlc.Timestamp) []engine.MVCCKey {
+		iter := NewMVCCIncrementalIterator(e)
+		defer iter.Close()
+		var keys []engine.MVCCKey
+		for iter.Reset(roachpb.KeyMin, roachpb.KeyMax, startTime, endTime); iter.Valid(); iter.NextRevision() {
+			keys = append(keys, iter.Key())
+		}
+		if err := iter.Error(); err != nil {
+			t.Fatal(err)
+		}
+		return keys
+	}
+
+	for _, tc := range []struct {
+		startTime, endTime hlc.Timestamp
+		expected           []engine.MVCCKey
+	}{
+		{hlc.Timestamp{}, ts5, []engine.MVCCKey{
+			{Key: testKey1, Timestamp: ts3},
+			{Key: testKey1, Timestamp: ts2},
+			{Key: testKey1, Timestamp: ts1},
+			{Key: testKey2, Timestamp: ts2},
+		}},
+		{ts2, ts3, []engine.MVCCKey{
+			{Key: testKey1, Timestamp: ts2},
+			{Key: testKey2, Timestamp: ts2},
+		}},
+		{ts3, ts4, []engine.MVCCKey{
+			{Key: testKey1, Timestamp: ts3},
+		}},
+		{ts4, ts5, nil},
+	} {
+		keys := revisions(tc.startTime, tc.endTime)
+		if len(keys) != len(tc.expected) {
+			t.Fatalf("%s-%s: got %v but expected %v", tc.startTime, tc.endTime, keys, tc.expected)
+		}
+		for i := range keys {
+			if !keys[i].Equal(tc.expected[i]) {
+				t.Fatalf("%s-%s: got %v but expected %v", tc.startTime, tc.endTime, keys, tc.expected)
+			}
+		}
+	}
+}
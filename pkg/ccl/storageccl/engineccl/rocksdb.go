@@ -20,6 +20,7 @@ import (
 // TODO(tamird): why does rocksdb not link jemalloc,snappy statically?
 
 // #cgo CXXFLAGS: -std=c++11 -Werror -Wall -Wno-sign-compare
+// #cgo LDFLAGS: -lcrypto
 // #cgo linux LDFLAGS: -lrt
 // #cgo windows CPPFLAGS: -I../../../../obj/rocksdbsrc/include
 // #cgo windows LDFLAGS: -L${SRCDIR}/../../../../obj/rocksdb-build -lrocksdblib
@@ -78,6 +79,42 @@ func VerifyBatchRepr(
 	return cStatsToGoStats(stats, nowNanos)
 }
 
+// VerifySSTable asserts that all keys in the SSTable encoded in data are
+// between the specified start and end keys and that their values carry valid
+// checksums, and computes the enginepb.MVCCStats for it.
+func VerifySSTable(
+	data []byte, start, end engine.MVCCKey, nowNanos int64,
+) (enginepb.MVCCStats, error) {
+	// The SSTable is ingested into a throwaway in-memory engine, which gives
+	// us an iterator over it.
+	e := engine.NewInMem(roachpb.Attributes{}, 1<<20)
+	defer e.Close()
+	if err := e.IngestExternalData(data); err != nil {
+		return enginepb.MVCCStats{}, errors.Wrap(err, "reading SSTable")
+	}
+
+	iter := e.NewIterator(false)
+	defer iter.Close()
+	for iter.Seek(engine.MVCCKey{Key: roachpb.KeyMin}); ; iter.Next() {
+		if ok, err := iter.Valid(); err != nil {
+			return enginepb.MVCCStats{}, err
+		} else if !ok {
+			break
+		}
+		unsafeKey := iter.UnsafeKey()
+		if unsafeKey.Less(start) || !unsafeKey.Less(end) {
+			return enginepb.MVCCStats{}, errors.Errorf("key not in request range: %s", unsafeKey)
+		}
+		if unsafeKey.IsValue() {
+			v := roachpb.Value{RawBytes: iter.UnsafeValue()}
+			if err := v.Verify(unsafeKey.Key); err != nil {
+				return enginepb.MVCCStats{}, err
+			}
+		}
+	}
+	return iter.ComputeStats(start, end, nowNanos)
+}
+
 // TODO(dan): The following are all duplicated from storage/engine/rocksdb.go,
 // but if you export the ones there and reuse them here, it doesn't work.
 //
@@ -9,9 +9,11 @@
 package storageccl
 
 import (
+	"bytes"
 	"crypto/sha512"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 
 	"golang.org/x/net/context"
@@ -22,6 +24,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/parser"
 	"github.com/cockroachdb/cockroach/pkg/storage"
 	"github.com/cockroachdb/cockroach/pkg/storage/engine"
+	"github.com/cockroachdb/cockroach/pkg/util/encoding"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/cockroach/pkg/util/tracing"
@@ -75,11 +78,16 @@ func evalExport(
 	defer endLimitedRequest()
 	log.Infof(ctx, "export [%s,%s)", args.Key, args.EndKey)
 
-	exportStore, err := MakeExportStorage(ctx, args.Storage)
-	if err != nil {
-		return storage.EvalResult{}, err
+	// With ReturnSST, the data is returned in the response and never written to
+	// export storage.
+	var exportStore ExportStorage
+	if !args.ReturnSST {
+		exportStore, err = MakeExportStorage(ctx, args.Storage)
+		if err != nil {
+			return storage.EvalResult{}, err
+		}
+		defer exportStore.Close()
 	}
-	defer exportStore.Close()
 
 	filename := fmt.Sprintf("%d.sst", parser.GenerateUniqueInt(cArgs.EvalCtx.NodeID()))
 	temp, err := MakeExportFileTmpWriter(ctx, cArgs.EvalCtx.GetTempPrefix(), exportStore, filename)
@@ -105,15 +113,25 @@ func evalExport(
 	// TODO(dan): Move all this iteration into cpp to avoid the cgo calls.
 	// TODO(dan): Consider checking ctx periodically during the MVCCIterate call.
 	var entries int64
+	var rows RowCounter
 	iter := engineccl.NewMVCCIncrementalIterator(batch)
 	defer iter.Close()
+	advance := iter.Next
+	if args.MVCCFilter == roachpb.MVCCFilter_All {
+		advance = iter.NextRevision
+	}
 	iter.Reset(args.Key, args.EndKey, args.StartTime, h.Timestamp)
-	for ; iter.Valid(); iter.Next() {
+	for ; iter.Valid(); advance() {
 		if log.V(3) {
 			v := roachpb.Value{RawBytes: iter.UnsafeValue()}
 			log.Infof(ctx, "Export %s %s", iter.UnsafeKey(), v.PrettyPrint())
 		}
 		entries++
+		if len(iter.UnsafeValue()) > 0 {
+			if err := rows.Count(iter.UnsafeKey().Key); err != nil {
+				return storage.EvalResult{}, errors.Wrapf(err, "decoding %s", iter.UnsafeKey())
+			}
+		}
 		if err := sst.Add(engine.MVCCKeyValue{Key: iter.UnsafeKey(), Value: iter.UnsafeValue()}); err != nil {
 			return storage.EvalResult{}, errors.Wrapf(err, "adding key %s", iter.UnsafeKey())
 		}
@@ -139,13 +157,44 @@ func evalExport(
 	}
 	size := sst.DataSize
 	sst = nil
+	rows.DataSize = size
 
-	// Compute the checksum before we upload and remove the local file.
-	checksum, err := sha512ChecksumFile(localPath)
+	if args.Encryption != nil && !args.ReturnSST {
+		data, err := ioutil.ReadFile(localPath)
+		if err != nil {
+			return storage.EvalResult{}, err
+		}
+		if data, err = EncryptFile(data, args.Encryption.Key); err != nil {
+			return storage.EvalResult{}, err
+		}
+		if err := ioutil.WriteFile(localPath, data, 0600); err != nil {
+			return storage.EvalResult{}, err
+		}
+	}
+
+	// Compute the checksum before we upload and remove the local file. It is
+	// computed over the file as stored, so it can be verified before any
+	// decryption.
+	checksum, err := SHA512ChecksumFile(localPath)
 	if err != nil {
 		return storage.EvalResult{}, err
 	}
 
+	if args.ReturnSST {
+		data, err := ioutil.ReadFile(localPath)
+		if err != nil {
+			return storage.EvalResult{}, err
+		}
+		reply.Files = []roachpb.ExportResponse_File{{
+			Span:     args.Span,
+			DataSize: size,
+			Sha512:   checksum,
+			SST:      data,
+			Exported: rows.BulkOpSummary,
+		}}
+		return storage.EvalResult{}, nil
+	}
+
 	if err := temp.Finish(ctx); err != nil {
 		return storage.EvalResult{}, err
 	}
@@ -155,12 +204,14 @@ func evalExport(
 		Path:     filename,
 		DataSize: size,
 		Sha512:   checksum,
+		Exported: rows.BulkOpSummary,
 	}}
 
 	return storage.EvalResult{}, nil
 }
 
-func sha512ChecksumFile(path string) ([]byte, error) {
+// SHA512ChecksumFile returns the SHA512 checksum of the file at path.
+func SHA512ChecksumFile(path string) ([]byte, error) {
 	h := sha512.New()
 	f, err := os.Open(path)
 	if err != nil {
@@ -172,3 +223,51 @@ func sha512ChecksumFile(path string) ([]byte, error) {
 	}
 	return h.Sum(nil), nil
 }
+
+// RowCounter counts the rows, secondary index entries and system table entries
+// of a sequence of keys.
+type RowCounter struct {
+	roachpb.BulkOpSummary
+	prev roachpb.Key
+}
+
+// Count counts the given key. The versions and
+// column families of a row are counted once, as long as they are counted
+// consecutively.
+func (r *RowCounter) Count(key roachpb.Key) error {
+	// EnsureSafeSplitKey strips the column family from the key, which leaves
+	// the prefix shared by all the keys of the row.
+	row, err := keys.EnsureSafeSplitKey(key)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(row, r.prev) {
+		return nil
+	}
+	r.prev = append(r.prev[:0], row...)
+
+	if encoding.PeekType(row) != encoding.Int {
+		// Not a table key.
+		r.SystemRecords++
+		return nil
+	}
+	rest, tableID, err := keys.DecodeTablePrefix(row)
+	if err != nil {
+		return err
+	}
+	if tableID <= keys.MaxReservedDescID {
+		r.SystemRecords++
+		return nil
+	}
+	_, indexID, err := encoding.DecodeUvarintAscending(rest)
+	if err != nil {
+		return err
+	}
+	// The primary index of a table always has ID 1.
+	if indexID == 1 {
+		r.Rows++
+	} else {
+		r.IndexEntries++
+	}
+	return nil
+}
@@ -151,3 +151,70 @@ func TestExportGCThreshold(t *testing.T) {
 		t.Fatalf(`expected "must be after replica GC threshold" error got: %+v`, pErr)
 	}
 }
+
+func TestExportAllRevisions(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	tc := testcluster.StartTestCluster(t, 1, base.TestClusterArgs{})
+	defer tc.Stopper().Stop()
+	sqlDB := sqlutils.MakeSQLRunner(t, tc.Conns[0])
+	kvDB := tc.Server(0).KVClient().(*client.DB)
+
+	sqlDB.Exec(`CREATE DATABASE export`)
+	sqlDB.Exec(`CREATE TABLE export.export (id INT PRIMARY KEY, v INT)`)
+	sqlDB.Exec(`INSERT INTO export.export VALUES (1, 1)`)
+	sqlDB.Exec(`UPDATE export.export SET v = 2 WHERE id = 1`)
+	sqlDB.Exec(`DELETE FROM export.export WHERE id = 1`)
+
+	exportAndSlurp := func(filter roachpb.MVCCFilter) []engine.MVCCKeyValue {
+		req := &roachpb.ExportRequest{
+			Span:       roachpb.Span{Key: keys.UserTableDataMin, EndKey: keys.MaxKey},
+			MVCCFilter: filter,
+			ReturnSST:  true,
+		}
+		res, pErr := client.SendWrapped(ctx, kvDB.GetSender(), req)
+		if pErr != nil {
+			t.Fatalf("%+v", pErr)
+		}
+		var kvs []engine.MVCCKeyValue
+		for _, file := range res.(*roachpb.ExportResponse).Files {
+			if file.Path != "" {
+				t.Fatalf("expected the export to not be written to storage, got %s", file.Path)
+			}
+			sst := engine.NewInMem(roachpb.Attributes{}, 1<<20)
+			defer sst.Close()
+			if err := sst.IngestExternalData(file.SST); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			start, end := engine.MVCCKey{Key: keys.MinKey}, engine.MVCCKey{Key: keys.MaxKey}
+			if err := sst.Iterate(start, end, func(kv engine.MVCCKeyValue) (bool, error) {
+				kvs = append(kvs, kv)
+				return false, nil
+			}); err != nil {
+				t.Fatalf("%+v", err)
+			}
+		}
+		return kvs
+	}
+
+	// Only the deletion is the latest version of the row.
+	if kvs := exportAndSlurp(roachpb.MVCCFilter_Latest); len(kvs) != 1 || len(kvs[0].Value) != 0 {
+		t.Fatalf("expected a single deletion tombstone got %v", kvs)
+	}
+
+	// All revisions include the deletion followed by both values.
+	kvs := exportAndSlurp(roachpb.MVCCFilter_All)
+	if expected := 3; len(kvs) != expected {
+		t.Fatalf("expected %d kvs in export got %d", expected, len(kvs))
+	}
+	if len(kvs[0].Value) != 0 {
+		v := roachpb.Value{RawBytes: kvs[0].Value}
+		t.Fatalf("expected a deletion tombstone got %s", v.PrettyPrint())
+	}
+	for i := 1; i < len(kvs); i++ {
+		if !kvs[i].Key.Key.Equal(kvs[0].Key.Key) || !kvs[i].Key.Timestamp.Less(kvs[i-1].Key.Timestamp) {
+			t.Fatalf("expected revisions of %s from newest to oldest, got %v", kvs[0].Key.Key, kvs)
+		}
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 
 	"github.com/pkg/errors"
 	"golang.org/x/net/context"
@@ -22,6 +23,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/storage"
 	"github.com/cockroachdb/cockroach/pkg/storage/engine"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/cockroach/pkg/util/tracing"
 )
@@ -61,28 +63,61 @@ func evalImport(ctx context.Context, cArgs storage.CommandArgs) error {
 	// Arrived at by tuning and watching the effect on BenchmarkRestore.
 	const batchSizeBytes = 1000000
 
-	type batchBuilder struct {
-		batch         engine.RocksDBBatchBuilder
+	tempPrefix := cArgs.EvalCtx.GetTempPrefix()
+	writerTempDir, err := ioutil.TempDir(tempPrefix, "import-sstwriter")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := os.RemoveAll(writerTempDir); err != nil {
+			log.Warning(ctx, err)
+		}
+	}()
+
+	// The imported data is sent as SSTables, which are linked directly into
+	// RocksDB on every replica instead of going through the raft log and the
+	// memtable. An SSTable must be built from keys in increasing order.
+	type sstBuilder struct {
+		sst           engine.RocksDBSstFileWriter
+		path          string
 		batchStartKey []byte
 		batchEndKey   []byte
 	}
-	b := batchBuilder{}
+	var b *sstBuilder
+	var sstCount int
+	defer func() {
+		if b != nil {
+			_ = b.sst.Close()
+		}
+	}()
 	g, gCtx := errgroup.WithContext(ctx)
-	sendWriteBatch := func() {
+	sendAddSSTable := func() error {
 		start := roachpb.Key(b.batchStartKey)
-		// The end key of the WriteBatch request is exclusive, but batchEndKey is
-		// currently the largest key in the batch. Increment it.
+		// The end key of the AddSSTable request is exclusive, but batchEndKey
+		// is currently the largest key in the SSTable. Increment it.
 		end := roachpb.Key(b.batchEndKey).Next()
 
-		repr := b.batch.Finish()
+		err := b.sst.Close()
+		path := b.path
+		b = nil
+		if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
 		g.Go(func() error {
 			if log.V(1) {
-				log.Infof(gCtx, "writebatch [%s,%s)", start, end)
+				log.Infof(gCtx, "addsstable [%s,%s)", start, end)
 			}
 
-			return errors.Wrapf(db.WriteBatch(gCtx, start, end, repr), "writebatch [%s,%s)", start, end)
+			return errors.Wrapf(db.AddSSTable(gCtx, start, end, data), "addsstable [%s,%s)", start, end)
 		})
-		b = batchBuilder{}
+		return nil
 	}
 
 	var iters []engine.Iterator
@@ -100,7 +135,6 @@ func evalImport(ctx context.Context, cArgs storage.CommandArgs) error {
 				log.Warningf(ctx, "close export storage failed %v", err)
 			}
 		}()
-		tempPrefix := cArgs.EvalCtx.GetTempPrefix()
 		localPath, cleanup, err := FetchFile(ctx, tempPrefix, dir, file.Path)
 		if err != nil {
 			return err
@@ -108,7 +142,7 @@ func evalImport(ctx context.Context, cArgs storage.CommandArgs) error {
 		defer cleanup()
 
 		if len(file.Sha512) > 0 {
-			checksum, err := sha512ChecksumFile(localPath)
+			checksum, err := SHA512ChecksumFile(localPath)
 			if err != nil {
 				return err
 			}
@@ -127,6 +161,22 @@ func evalImport(ctx context.Context, cArgs storage.CommandArgs) error {
 			}
 		}()
 
+		// Local files are read in place, so decrypt into the reader's temp dir
+		// rather than over the original.
+		if args.Encryption != nil {
+			data, err := ioutil.ReadFile(localPath)
+			if err != nil {
+				return err
+			}
+			if data, err = DecryptFile(data, args.Encryption.Key); err != nil {
+				return errors.Wrapf(err, "decrypting %s", file.Path)
+			}
+			localPath = filepath.Join(readerTempDir, "decrypted.sst")
+			if err := ioutil.WriteFile(localPath, data, 0600); err != nil {
+				return err
+			}
+		}
+
 		sst, err := engine.MakeRocksDBSstFileReader(readerTempDir)
 		if err != nil {
 			return err
@@ -149,7 +199,18 @@ func evalImport(ctx context.Context, cArgs storage.CommandArgs) error {
 	startKeyMVCC, endKeyMVCC := engine.MVCCKey{Key: args.DataSpan.Key}, engine.MVCCKey{Key: args.DataSpan.EndKey}
 	iter := engineccl.MakeMultiIterator(iters)
 	var keyScratch, valueScratch []byte
-	for iter.Seek(startKeyMVCC); iter.Valid() && iter.UnsafeKey().Less(endKeyMVCC); iter.NextKey() {
+	for iter.Seek(startKeyMVCC); ; iter.NextKey() {
+		if args.EndTime != (hlc.Timestamp{}) {
+			// The files may contain every revision of each key. Skip the ones
+			// after EndTime, leaving the iterator at the revision that was
+			// current as of EndTime.
+			for iter.Valid() && args.EndTime.Less(iter.UnsafeKey().Timestamp) {
+				iter.Next()
+			}
+		}
+		if !iter.Valid() || !iter.UnsafeKey().Less(endKeyMVCC) {
+			break
+		}
 		if len(iter.UnsafeValue()) == 0 {
 			// Value is deleted.
 			continue
@@ -178,27 +239,44 @@ func evalImport(ctx context.Context, cArgs storage.CommandArgs) error {
 		if log.V(3) {
 			log.Infof(ctx, "Put %s -> %s", key.Key, value.PrettyPrint())
 		}
-		b.batch.Put(key, value.RawBytes)
 
-		// Update the range currently represented in this batch, as
-		// necessary.
-		if len(b.batchStartKey) == 0 || bytes.Compare(key.Key, b.batchStartKey) < 0 {
+		// Key rewriting doesn't necessarily preserve the order of the keys, in
+		// which case the current SSTable is sent and a new one started.
+		if b != nil && bytes.Compare(key.Key, b.batchEndKey) <= 0 {
+			if err := sendAddSSTable(); err != nil {
+				return err
+			}
+		}
+		if b == nil {
+			sstCount++
+			b = &sstBuilder{
+				sst:  engine.MakeRocksDBSstFileWriter(),
+				path: filepath.Join(writerTempDir, fmt.Sprintf("%d.sst", sstCount)),
+			}
+			if err := b.sst.Open(b.path); err != nil {
+				return err
+			}
 			b.batchStartKey = append(b.batchStartKey[:0], key.Key...)
 		}
-		if len(b.batchEndKey) == 0 || bytes.Compare(key.Key, b.batchEndKey) > 0 {
-			b.batchEndKey = append(b.batchEndKey[:0], key.Key...)
+		if err := b.sst.Add(engine.MVCCKeyValue{Key: key, Value: value.RawBytes}); err != nil {
+			return err
 		}
+		b.batchEndKey = append(b.batchEndKey[:0], key.Key...)
 
-		if b.batch.Len() > batchSizeBytes {
-			sendWriteBatch()
+		if b.sst.DataSize > batchSizeBytes {
+			if err := sendAddSSTable(); err != nil {
+				return err
+			}
 		}
 	}
 	if err := iter.Error(); err != nil {
 		return err
 	}
-	// Flush out the last batch.
-	if b.batch.Len() > 0 {
-		sendWriteBatch()
+	// Flush out the last SSTable.
+	if b != nil {
+		if err := sendAddSSTable(); err != nil {
+			return err
+		}
 	}
 	return g.Wait()
 }
@@ -120,7 +120,7 @@ func init() {
 		// TODO(pmattis): stats
 		genCmd,
 		versionCmd,
-		debugCmd,
+		DebugCmd,
 	)
 }
 
@@ -1527,6 +1527,9 @@ writing ` + os.DevNull + `
   debug/nodes/1/ranges/8
   debug/nodes/1/ranges/9
   debug/nodes/1/ranges/10
+  debug/nodes/1/ranges/11
+  debug/nodes/1/ranges/12
+  debug/nodes/1/ranges/13
   debug/schema/system@details
   debug/schema/system/descriptor
   debug/schema/system/eventlog
@@ -1534,7 +1537,10 @@ writing ` + os.DevNull + `
   debug/schema/system/lease
   debug/schema/system/namespace
   debug/schema/system/rangelog
+  debug/schema/system/replication_reports
   debug/schema/system/settings
+  debug/schema/system/statement_statistics
+  debug/schema/system/table_statistics
   debug/schema/system/ui
   debug/schema/system/users
   debug/schema/system/zones
@@ -373,6 +373,21 @@ memory that the store may consume, for example:
   --store=type=mem,size=20GiB
   --store=type=mem,size=90%
 
+</PRE>
+(Enterprise feature) The "key" field enables encryption at rest of the store's
+data. It is the path to a file containing a 16, 24 or 32 byte AES key (for
+AES-128, AES-192 or AES-256 respectively), which can be generated with e.g.
+"openssl rand -out store.key 32". To rotate the key, specify the new key in the
+"key" field and the previous one in the "old-key" field; data encrypted with the
+old key is re-encrypted in the background and the old key can be dropped once
+the node reports that all files are encrypted with the new key. The value
+"plain" denotes unencrypted data, so encryption can be enabled on an existing
+store with "old-key=plain", for example:
+<PRE>
+
+  --store=path=/mnt/ssd01,key=/keys/new.key,old-key=/keys/store.key
+  --store=path=/mnt/ssd01,key=/keys/store.key,old-key=plain
+
 </PRE>
 Commas are forbidden in all values, since they are used to separate fields.
 Also, if you use equal signs in the file path to a store, you must use the
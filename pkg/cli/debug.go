@@ -773,7 +773,7 @@ func runDebugSSTables(cmd *cobra.Command, args []string) error {
 }
 
 func init() {
-	debugCmd.AddCommand(debugCmds...)
+	DebugCmd.AddCommand(debugCmds...)
 }
 
 var debugCmds = []*cobra.Command{
@@ -791,7 +791,9 @@ var debugCmds = []*cobra.Command{
 	debugZipCmd,
 }
 
-var debugCmd = &cobra.Command{
+// DebugCmd is the root of all debug commands. Exported to allow modification
+// by CCL code.
+var DebugCmd = &cobra.Command{
 	Use:   "debug [command]",
 	Short: "debugging commands",
 	Long: `Various commands for debugging.
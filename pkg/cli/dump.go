@@ -25,9 +25,11 @@ import (
 	"strings"
 	"time"
 
+	"github.com/cockroachdb/cockroach/pkg/config"
 	"github.com/cockroachdb/cockroach/pkg/sql/parser"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
 )
 
 // dumpCmd dumps SQL tables.
@@ -98,6 +100,9 @@ type tableMetadata struct {
 	columnNames  string
 	columnTypes  map[string]string
 	createStmt   string
+	// zoneStmts holds the statements that recreate the zone configs set on
+	// the table and its indexes.
+	zoneStmts []string
 }
 
 // getDumpMetadata retrieves the table information for the specified table(s).
@@ -288,6 +293,11 @@ func getMetadataForTable(
 	}
 	create := vals[0].(string)
 
+	zoneStmts, err := getZoneConfigStatements(conn, name, ts)
+	if err != nil {
+		return tableMetadata{}, err
+	}
+
 	return tableMetadata{
 		name:         name,
 		primaryIndex: primaryIndex,
@@ -296,9 +306,105 @@ func getMetadataForTable(
 		columnNames:  colnames.String(),
 		columnTypes:  coltypes,
 		createStmt:   create,
+		zoneStmts:    zoneStmts,
 	}, nil
 }
 
+// getZoneConfigStatements returns the CONFIGURE ZONE statements for the zone
+// configs set on the given table and its indexes and partitions as of the
+// given timestamp. Zone configs inherited from the database are not included.
+func getZoneConfigStatements(conn *sqlConn, name *parser.TableName, ts string) ([]string, error) {
+	rows, err := conn.Query(fmt.Sprintf(`
+		SELECT z.index_id, z.index_name, z.partition_name, z.config_yaml
+		FROM crdb_internal.zones AS z
+		JOIN crdb_internal.tables AS t ON z.zone_id = t.table_id
+		AS OF SYSTEM TIME '%s'
+		WHERE t.database_name = $1
+			AND t.name = $2
+			AND t.state = 'PUBLIC'
+		ORDER BY z.index_id, z.partition_name
+		`, ts), []driver.Value{string(name.DatabaseName), string(name.TableName)})
+	if err != nil {
+		return nil, err
+	}
+
+	// The dumped CREATE TABLE statement does not qualify the table name, so
+	// neither do the zone config statements.
+	tableName := parser.Name(name.TableName).String()
+	var stmts []string
+	vals := make([]driver.Value, 4)
+	for {
+		if err := rows.Next(vals); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		specifier := "TABLE " + tableName
+		if vals[0].(int64) != 0 {
+			if vals[1] == nil {
+				// The subzone belongs to an index that no longer exists.
+				continue
+			}
+			specifier = "INDEX " + tableName + "@" + parser.Name(vals[1].(string)).String()
+			if partition := vals[2].(string); partition != "" {
+				specifier = "PARTITION " + parser.Name(partition).String() + " OF " + specifier
+			}
+		}
+		var zone config.ZoneConfig
+		if err := yaml.Unmarshal([]byte(vals[3].(string)), &zone); err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, zoneConfigStatement(specifier, zone))
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	return stmts, nil
+}
+
+// zoneConfigStatement returns a statement that sets the zone config of the
+// given zone specifier.
+func zoneConfigStatement(specifier string, zone config.ZoneConfig) string {
+	constraints := make([]string, len(zone.Constraints.Constraints))
+	for i, c := range zone.Constraints.Constraints {
+		constraints[i] = c.String()
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "ALTER %s CONFIGURE ZONE USING num_replicas = %d, constraints = ",
+		specifier, zone.NumReplicas)
+	parser.FormatNode(&buf, parser.FmtSimple,
+		parser.NewDString("["+strings.Join(constraints, ", ")+"]"))
+	if len(zone.ReplicaConstraints.Constraints) > 0 {
+		replicaConstraints := make([]string, len(zone.ReplicaConstraints.Constraints))
+		for i, rc := range zone.ReplicaConstraints.Constraints {
+			constraints := make([]string, len(rc.Constraints))
+			for j, c := range rc.Constraints {
+				constraints[j] = c.String()
+			}
+			replicaConstraints[i] = fmt.Sprintf("%q: %d", strings.Join(constraints, ","), rc.NumReplicas)
+		}
+		buf.WriteString(", replica_constraints = ")
+		parser.FormatNode(&buf, parser.FmtSimple,
+			parser.NewDString("{"+strings.Join(replicaConstraints, ", ")+"}"))
+	}
+	if len(zone.LeasePreferences) > 0 {
+		preferences := make([]string, len(zone.LeasePreferences))
+		for i, p := range zone.LeasePreferences {
+			constraints := make([]string, len(p.Constraints))
+			for j, c := range p.Constraints {
+				constraints[j] = c.String()
+			}
+			preferences[i] = "[" + strings.Join(constraints, ", ") + "]"
+		}
+		buf.WriteString(", lease_preferences = ")
+		parser.FormatNode(&buf, parser.FmtSimple,
+			parser.NewDString("["+strings.Join(preferences, ", ")+"]"))
+	}
+	fmt.Fprintf(&buf, ", range_min_bytes = %d, range_max_bytes = %d, gc.ttlseconds = %d",
+		zone.RangeMinBytes, zone.RangeMaxBytes, zone.GC.TTLSeconds)
+	return buf.String()
+}
+
 // dumpCreateTable dumps the CREATE statement of the specified table to w.
 func dumpCreateTable(w io.Writer, md tableMetadata) error {
 	if _, err := w.Write([]byte(md.createStmt)); err != nil {
@@ -307,6 +413,11 @@ func dumpCreateTable(w io.Writer, md tableMetadata) error {
 	if _, err := w.Write([]byte(";\n")); err != nil {
 		return err
 	}
+	for _, stmt := range md.zoneStmts {
+		if _, err := fmt.Fprintf(w, "%s;\n", stmt); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -314,6 +314,86 @@ func TestDumpBytes(t *testing.T) {
 	}
 }
 
+func TestDumpZoneConfigs(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	c := newCLITest(cliTestParams{t: t})
+	defer c.cleanup()
+
+	url, cleanup := sqlutils.PGUrl(t, c.ServingAddr(), "TestDumpZoneConfigs", url.User(security.RootUser))
+	defer cleanup()
+
+	conn := makeSQLConn(url.String())
+	defer conn.Close()
+
+	if err := conn.Exec(`
+		CREATE DATABASE d;
+		SET DATABASE = d;
+		CREATE TABLE t (
+			a INT PRIMARY KEY,
+			b INT,
+			INDEX idx (b) PARTITION BY LIST (b) (PARTITION q VALUES IN (1, 2))
+		) PARTITION BY RANGE (a) (PARTITION p VALUES < 10, PARTITION r VALUES < MAXVALUE);
+		ALTER DATABASE d CONFIGURE ZONE USING num_replicas = 5;
+		ALTER TABLE t CONFIGURE ZONE USING num_replicas = 1, constraints = '[+region=us]';
+		ALTER INDEX t@idx CONFIGURE ZONE USING gc.ttlseconds = 3600;
+		ALTER PARTITION p OF TABLE t CONFIGURE ZONE USING num_replicas = 3,
+			constraints = '[+region=eu]', replica_constraints = '{"+dc=1,+ssd": 1, "+dc=2": 2}',
+			lease_preferences = '[[+region=eu, +dc=1], [+region=eu]]';
+		ALTER PARTITION q OF INDEX t@idx CONFIGURE ZONE USING constraints = '[+region=ca]';
+	`, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := dumpSingleTable(&b, conn, "d", "t"); err != nil {
+		t.Fatal(err)
+	}
+	dump := b.String()
+	b.Reset()
+
+	// The zone config of the database is not part of the dump of a table.
+	for _, expected := range []string{
+		"ALTER TABLE t CONFIGURE ZONE USING num_replicas = 1, constraints = '[+region=us]'",
+		"ALTER INDEX t@idx CONFIGURE ZONE USING num_replicas = 1, constraints = '[+region=us]'",
+		"gc.ttlseconds = 3600;",
+		"PARTITION BY RANGE (a) (PARTITION p VALUES < 10, PARTITION r VALUES < MAXVALUE)",
+		"INDEX idx (b ASC) PARTITION BY LIST (b) (PARTITION q VALUES IN (1, 2))",
+		`ALTER PARTITION p OF INDEX t@"primary" CONFIGURE ZONE USING num_replicas = 3, ` +
+			`constraints = '[+region=eu]', replica_constraints = '{"+dc=1,+ssd": 1, "+dc=2": 2}', ` +
+			"lease_preferences = '[[+region=eu, +dc=1], [+region=eu]]'",
+		"ALTER PARTITION q OF INDEX t@idx CONFIGURE ZONE USING num_replicas = 1, " +
+			"constraints = '[+region=ca]'",
+	} {
+		if !strings.Contains(dump, expected) {
+			t.Fatalf("expected dump to contain %q:\n%s", expected, dump)
+		}
+	}
+	if strings.Contains(dump, "num_replicas = 5") {
+		t.Fatalf("expected dump not to contain the zone config of the database:\n%s", dump)
+	}
+	if strings.Contains(dump, "PARTITION r OF") {
+		t.Fatalf("expected dump not to contain the inherited zone config of partition r:\n%s", dump)
+	}
+
+	if err := conn.Exec(`
+		CREATE DATABASE o;
+		SET DATABASE = o;
+	`, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Exec(dump, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := dumpSingleTable(&b, conn, "o", "t"); err != nil {
+		t.Fatal(err)
+	}
+	dump2 := b.String()
+	if dump != dump2 {
+		t.Fatalf("unmatching dumps:\n%s\n%s", dump, dump2)
+	}
+}
+
 const durationRandom = "duration-random"
 
 var randomTestTime = pflag.Duration(durationRandom, time.Second, "duration for randomized dump test to run")
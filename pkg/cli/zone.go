@@ -116,8 +116,13 @@ func queryZone(conn *sqlConn, id sqlbase.ID) (config.ZoneConfig, bool, error) {
 func queryZonePath(conn *sqlConn, path []sqlbase.ID) (sqlbase.ID, config.ZoneConfig, error) {
 	for i := len(path) - 1; i >= 0; i-- {
 		zone, found, err := queryZone(conn, path[i])
-		if err != nil || found {
-			return path[i], zone, err
+		if err != nil {
+			return 0, config.ZoneConfig{}, err
+		}
+		// Placeholders only hold the zone configs of a table's indexes, so the
+		// table itself inherits its config.
+		if found && !zone.IsSubzonePlaceholder() {
+			return path[i], zone, nil
 		}
 	}
 	return 0, config.ZoneConfig{}, nil
@@ -329,11 +334,15 @@ func runLsZones(cmd *cobra.Command, args []string) error {
 	// Loop over the zones and determine the name for each based on the name of
 	// the corresponding descriptor.
 	var output []string
-	for id := range zones {
+	for id, zone := range zones {
 		if id == 0 {
 			// We handle the default zone below.
 			continue
 		}
+		if zone.IsSubzonePlaceholder() {
+			// The table only has zone configs for some of its indexes.
+			continue
+		}
 		desc, ok := descs[id]
 		if !ok {
 			continue
@@ -404,6 +413,22 @@ func runRmZone(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("unable to remove special zone %s", args[0])
 		}
 
+		zone, found, err := queryZone(conn, id)
+		if err != nil {
+			return err
+		}
+		if found && len(zone.Subzones) > 0 {
+			// Keep the zone configs of the table's indexes around.
+			placeholder := config.ZoneConfig{Subzones: zone.Subzones, SubzoneSpans: zone.SubzoneSpans}
+			buf, err := protoutil.Marshal(&placeholder)
+			if err != nil {
+				return err
+			}
+			return runQueryAndFormatResults(conn, os.Stdout,
+				makeQuery(`UPSERT INTO system.zones (id, config) VALUES ($1, $2)`, id, buf),
+				cliCtx.tableDisplayFormat)
+		}
+
 		if err := runQueryAndFormatResults(conn, os.Stdout,
 			makeQuery(`DELETE FROM system.zones WHERE id=$1`, id), cliCtx.tableDisplayFormat); err != nil {
 			return err
@@ -425,6 +450,8 @@ The zone config format has the following YAML schema:
 
   num_replicas: <num>
   constraints: [comma-separated attribute list]
+  replica_constraints: {comma-separated attribute list: <num>, ...}
+  lease_preferences: [[comma-separated attribute list], ...]
   range_min_bytes: <size-in-bytes>
   range_max_bytes: <size-in-bytes>
   gc:
@@ -498,6 +525,15 @@ func runSetZone(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			return err
 		}
+		// Preserve the zone configs of the indexes of the table, which are
+		// stored alongside its own config.
+		id := path[len(path)-1]
+		existing, _, err := queryZone(conn, id)
+		if err != nil {
+			return err
+		}
+		zone.Subzones = existing.Subzones
+		zone.SubzoneSpans = existing.SubzoneSpans
 		// Convert it to proto and marshal it again to put into the table. This is a
 		// bit more tedious than taking protos directly, but yaml is a more widely
 		// understood format.
@@ -520,7 +556,6 @@ func runSetZone(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("unable to parse zone config file %q: %s", args[1], err)
 		}
 
-		id := path[len(path)-1]
 		_, _, _, err = runQuery(conn, makeQuery(
 			`UPSERT INTO system.zones (id, config) VALUES ($1, $2)`,
 			id, buf), false)
@@ -130,6 +130,77 @@ func (c *Constraints) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	return nil
 }
 
+var _ yaml.Marshaler = ReplicaConstraints{}
+var _ yaml.Unmarshaler = &ReplicaConstraints{}
+
+// MarshalYAML implements yaml.Marshaler. Replica constraints are represented
+// by a map from the comma-separated short form of each set of constraints to
+// the number of replicas it applies to, such as {+region=us: 2, +region=eu: 1}.
+func (rc ReplicaConstraints) MarshalYAML() (interface{}, error) {
+	m := make(yaml.MapSlice, len(rc.Constraints))
+	for i, c := range rc.Constraints {
+		short := make([]string, len(c.Constraints))
+		for j, c := range c.Constraints {
+			short[j] = c.String()
+		}
+		m[i] = yaml.MapItem{Key: strings.Join(short, ","), Value: c.NumReplicas}
+	}
+	return m, nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (rc *ReplicaConstraints) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var m yaml.MapSlice
+	if err := unmarshal(&m); err != nil {
+		return err
+	}
+	constraints := make([]Constraints, len(m))
+	for i, item := range m {
+		key, ok := item.Key.(string)
+		if !ok {
+			return errors.Errorf("replica constraints must be keyed by constraints, not %v", item.Key)
+		}
+		numReplicas, ok := item.Value.(int)
+		if !ok {
+			return errors.Errorf("the number of replicas for %q must be an integer, not %v",
+				key, item.Value)
+		}
+		for _, short := range strings.Split(key, ",") {
+			short = strings.TrimSpace(short)
+			if short == "" {
+				return errors.Errorf("empty constraint in %q", key)
+			}
+			var c Constraint
+			if err := c.FromString(short); err != nil {
+				return err
+			}
+			constraints[i].Constraints = append(constraints[i].Constraints, c)
+		}
+		constraints[i].NumReplicas = int32(numReplicas)
+	}
+	rc.Constraints = constraints
+	return nil
+}
+
+var _ yaml.Marshaler = LeasePreference{}
+var _ yaml.Unmarshaler = &LeasePreference{}
+
+// MarshalYAML implements yaml.Marshaler. A lease preference is represented by
+// the short form of its constraints, like Constraints.
+func (p LeasePreference) MarshalYAML() (interface{}, error) {
+	return Constraints{Constraints: p.Constraints}.MarshalYAML()
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (p *LeasePreference) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var c Constraints
+	if err := c.UnmarshalYAML(unmarshal); err != nil {
+		return err
+	}
+	p.Constraints = c.Constraints
+	return nil
+}
+
 // DefaultZoneConfig is the default zone configuration used when no custom
 // config has been specified.
 func DefaultZoneConfig() ZoneConfig {
@@ -170,9 +241,137 @@ func (z ZoneConfig) Validate() error {
 		return fmt.Errorf("RangeMinBytes %d is greater than or equal to RangeMaxBytes %d",
 			z.RangeMinBytes, z.RangeMaxBytes)
 	}
+	var replicaConstraintsTotal int32
+	for _, c := range z.ReplicaConstraints.Constraints {
+		if len(c.Constraints) == 0 {
+			return fmt.Errorf("every replica constraint must include at least one constraint")
+		}
+		if c.NumReplicas <= 0 {
+			return fmt.Errorf("replica constraint %v must apply to at least one replica", c.Constraints)
+		}
+		for _, constraint := range c.Constraints {
+			if constraint.Type == Constraint_POSITIVE {
+				return fmt.Errorf("replica constraint %q must be required (+) or prohibited (-)",
+					constraint)
+			}
+		}
+		replicaConstraintsTotal += c.NumReplicas
+	}
+	if replicaConstraintsTotal > z.NumReplicas {
+		return fmt.Errorf("replica constraints apply to %d replicas, but num_replicas is %d",
+			replicaConstraintsTotal, z.NumReplicas)
+	}
+	for _, p := range z.LeasePreferences {
+		if len(p.Constraints) == 0 {
+			return fmt.Errorf("every lease preference must include at least one constraint")
+		}
+		for _, c := range p.Constraints {
+			if c.Type == Constraint_POSITIVE {
+				return fmt.Errorf("lease preference constraint %q must be required (+) or prohibited (-)", c)
+			}
+		}
+	}
+	for _, s := range z.Subzones {
+		if len(s.Config.Subzones) > 0 || len(s.Config.SubzoneSpans) > 0 {
+			return fmt.Errorf("subzone for index %d must not have subzones", s.IndexID)
+		}
+		if err := s.Config.Validate(); err != nil {
+			if s.PartitionName != "" {
+				return errors.Wrapf(err, "invalid subzone for partition %q of index %d",
+					s.PartitionName, s.IndexID)
+			}
+			return errors.Wrapf(err, "invalid subzone for index %d", s.IndexID)
+		}
+	}
+	for _, span := range z.SubzoneSpans {
+		if span.SubzoneIndex < 0 || int(span.SubzoneIndex) >= len(z.Subzones) {
+			return fmt.Errorf("subzone span %s refers to missing subzone %d",
+				span.Key, span.SubzoneIndex)
+		}
+	}
+	return nil
+}
+
+// IsSubzonePlaceholder returns whether the zone config exists only to store
+// the subzones of a table that does not have a zone config of its own.
+func (z ZoneConfig) IsSubzonePlaceholder() bool {
+	return z.NumReplicas == 0 && len(z.Subzones) > 0
+}
+
+// GetSubzone returns the subzone for the given index and partition, or nil if
+// there is none. An empty partition name refers to the whole index.
+func (z *ZoneConfig) GetSubzone(indexID uint32, partition string) *Subzone {
+	for i := range z.Subzones {
+		if z.Subzones[i].IndexID == indexID && z.Subzones[i].PartitionName == partition {
+			return &z.Subzones[i]
+		}
+	}
 	return nil
 }
 
+// SetSubzone installs the given subzone, replacing any existing subzone for the
+// same index and partition. The caller is responsible for updating
+// SubzoneSpans, as the indexes of the subzones may change.
+func (z *ZoneConfig) SetSubzone(subzone Subzone) {
+	if existing := z.GetSubzone(subzone.IndexID, subzone.PartitionName); existing != nil {
+		*existing = subzone
+		return
+	}
+	z.Subzones = append(z.Subzones, subzone)
+	sort.Slice(z.Subzones, func(i, j int) bool {
+		if z.Subzones[i].IndexID != z.Subzones[j].IndexID {
+			return z.Subzones[i].IndexID < z.Subzones[j].IndexID
+		}
+		return z.Subzones[i].PartitionName < z.Subzones[j].PartitionName
+	})
+}
+
+// DeleteSubzone removes the subzone for the given index and partition,
+// returning whether it existed. The caller is responsible for updating
+// SubzoneSpans.
+func (z *ZoneConfig) DeleteSubzone(indexID uint32, partition string) bool {
+	for i := range z.Subzones {
+		if z.Subzones[i].IndexID == indexID && z.Subzones[i].PartitionName == partition {
+			z.Subzones = append(z.Subzones[:i], z.Subzones[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// getSubzoneForKey returns the subzone that applies to the given key of the
+// table this zone config belongs to, or nil if there is none. Subzones of
+// index partitions take precedence over subzones of whole indexes.
+func (z *ZoneConfig) getSubzoneForKey(key roachpb.RKey) *Subzone {
+	rest, _, err := keys.DecodeTablePrefix(roachpb.Key(key))
+	if err != nil {
+		return nil
+	}
+	// The spans are sorted and do not overlap, so the only span that can
+	// contain the key is the last one that starts at or before it.
+	i := sort.Search(len(z.SubzoneSpans), func(i int) bool {
+		return bytes.Compare(rest, z.SubzoneSpans[i].Key) < 0
+	}) - 1
+	if i >= 0 {
+		span := z.SubzoneSpans[i]
+		if bytes.Compare(rest, span.end()) < 0 && int(span.SubzoneIndex) < len(z.Subzones) {
+			return &z.Subzones[span.SubzoneIndex]
+		}
+	}
+	if indexID, ok := indexIDForKey(rest); ok {
+		return z.GetSubzone(indexID, "")
+	}
+	return nil
+}
+
+// end returns the exclusive end of the span, without the table prefix.
+func (s SubzoneSpan) end() roachpb.Key {
+	if len(s.EndKey) == 0 {
+		return s.Key.PrefixEnd()
+	}
+	return s.EndKey
+}
+
 // ObjectIDForKey returns the object ID (table or database) for 'key',
 // or (_, false) if not within the structured key space.
 func ObjectIDForKey(key roachpb.RKey) (uint32, bool) {
@@ -346,6 +545,18 @@ func (s SystemConfig) GetLargestObjectID(maxID uint32) (uint32, error) {
 // GetZoneConfigForKey looks up the zone config for the range containing 'key'.
 // It is the caller's responsibility to ensure that the range does not need to be split.
 func (s SystemConfig) GetZoneConfigForKey(key roachpb.RKey) (ZoneConfig, error) {
+	_, _, zone, err := s.GetZoneForKey(key)
+	return zone, err
+}
+
+// GetZoneForKey is like GetZoneConfigForKey, but also returns the ID of the
+// object that the key belongs to as far as zone configs are concerned and the
+// subzone of that object that applies to the key, or nil if none applies.
+// Subzones are identified by their index ID and partition name; their
+// positions in the zone config change as subzones are added.
+func (s SystemConfig) GetZoneForKey(
+	key roachpb.RKey,
+) (objectID uint32, subzone *Subzone, zone ZoneConfig, err error) {
 	objectID, ok := ObjectIDForKey(key)
 	if !ok {
 		// Not in the structured data namespace.
@@ -367,7 +578,28 @@ func (s SystemConfig) GetZoneConfigForKey(key roachpb.RKey) (ZoneConfig, error)
 		objectID = keys.SystemRangesID
 	}
 
-	return s.getZoneConfigForID(objectID)
+	zone, err = s.getZoneConfigForID(objectID)
+	if err != nil || len(zone.Subzones) == 0 {
+		return objectID, nil, zone, err
+	}
+	// Keys in indexes and index partitions with their own zone configs use
+	// those instead of the config of their table.
+	if subzone := zone.getSubzoneForKey(key); subzone != nil {
+		return objectID, subzone, subzone.Config, nil
+	}
+	zone.Subzones = nil
+	zone.SubzoneSpans = nil
+	return objectID, nil, zone, nil
+}
+
+// indexIDForKey returns the index ID for a key within a table, given without
+// the table prefix, or (_, false) if the key does not contain one.
+func indexIDForKey(rest []byte) (uint32, bool) {
+	if len(rest) == 0 || encoding.PeekType(rest) != encoding.Int {
+		return 0, false
+	}
+	_, indexID, err := encoding.DecodeUvarintAscending(rest)
+	return uint32(indexID), err == nil
 }
 
 // getZoneConfigForID looks up the zone config for the object (table or database)
@@ -376,6 +608,10 @@ func (s SystemConfig) getZoneConfigForID(id uint32) (ZoneConfig, error) {
 	testingLock.Lock()
 	hook := ZoneConfigHook
 	testingLock.Unlock()
+	if hook == nil {
+		// No zone configs can be looked up without the SQL layer.
+		return DefaultZoneConfig(), nil
+	}
 	if cfg, found, err := hook(s, id); err != nil || found {
 		return cfg, err
 	}
@@ -441,6 +677,13 @@ func (s SystemConfig) ComputeSplitKey(startKey, endKey roachpb.RKey) roachpb.RKe
 	// If the above iteration over the static split points didn't decide anything,
 	// the key range must be somewhere in the SQL table part of the keyspace.
 	startID, ok := ObjectIDForKey(startKey)
+	if ok && startID > keys.MaxReservedDescID {
+		// Indexes with their own zone configs are split off from the rest of
+		// their table.
+		if splitKey := s.subzoneSplitKey(startID, startKey, endKey); splitKey != nil {
+			return splitKey
+		}
+	}
 	if !ok || startID <= keys.MaxSystemConfigDescID {
 		// The start key is either:
 		// - not part of the structured data span
@@ -464,6 +707,11 @@ func (s SystemConfig) ComputeSplitKey(startKey, endKey roachpb.RKey) roachpb.RKe
 	findSplitKey := func(startID, endID uint32) roachpb.RKey {
 		// endID could be smaller than startID if we don't have user tables.
 		for id := startID; id <= endID; id++ {
+			// The IDs that refer to parts of the system ranges have no table data
+			// to split off.
+			if id == keys.MetaRangesID || id == keys.SystemRangesID || id == keys.TimeseriesRangesID {
+				continue
+			}
 			key := roachpb.RKey(keys.MakeRowSentinelKey(keys.MakeTablePrefix(id)))
 			// Skip if this ID matches the provided startKey.
 			if !startKey.Less(key) {
@@ -501,6 +749,38 @@ func (s SystemConfig) ComputeSplitKey(startKey, endKey roachpb.RKey) roachpb.RKe
 	return findSplitKey(startID, endID)
 }
 
+// subzoneSplitKey returns the first boundary of an index or index partition
+// with a subzone of the table with the given ID that lies strictly within
+// (startKey, endKey), or nil if there is none.
+func (s SystemConfig) subzoneSplitKey(id uint32, startKey, endKey roachpb.RKey) roachpb.RKey {
+	zone, err := s.getZoneConfigForID(id)
+	if err != nil {
+		log.Errorf(context.TODO(), "unable to look up zone config for table %d: %s", id, err)
+		return nil
+	}
+	tablePrefix := keys.MakeTablePrefix(id)
+	var splitKey roachpb.RKey
+	maybeSplitAt := func(key roachpb.RKey) {
+		if startKey.Less(key) && key.Less(endKey) && (splitKey == nil || key.Less(splitKey)) {
+			splitKey = key
+		}
+	}
+	for _, subzone := range zone.Subzones {
+		if subzone.PartitionName != "" {
+			continue
+		}
+		prefix := roachpb.RKey(encoding.EncodeUvarintAscending(
+			append([]byte(nil), tablePrefix...), uint64(subzone.IndexID)))
+		maybeSplitAt(prefix)
+		maybeSplitAt(prefix.PrefixEnd())
+	}
+	for _, span := range zone.SubzoneSpans {
+		maybeSplitAt(roachpb.RKey(append(append([]byte(nil), tablePrefix...), span.Key...)))
+		maybeSplitAt(roachpb.RKey(append(append([]byte(nil), tablePrefix...), span.end()...)))
+	}
+	return splitKey
+}
+
 // NeedsSplit returns whether the range [startKey, endKey) needs a split due
 // to zone configs.
 func (s SystemConfig) NeedsSplit(startKey, endKey roachpb.RKey) bool {
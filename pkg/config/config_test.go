@@ -31,6 +31,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/testutils"
 	"github.com/cockroachdb/cockroach/pkg/util/encoding"
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/stop"
 )
 
 func plainKV(k, v string) roachpb.KeyValue {
@@ -301,6 +302,12 @@ func TestComputeSplitKeyTableIDs(t *testing.T) {
 	allSql := append(schema.GetInitialValues(),
 		descriptor(start), descriptor(start+1), descriptor(start+5))
 	sort.Sort(roachpb.KeyValueByKey(allSql))
+	// Real system tables plus the ones added by migrations, whose IDs skip the
+	// IDs that refer to parts of the system ranges.
+	migratedSql := append(schema.GetInitialValues(),
+		descriptor(keys.JobsTableID), descriptor(keys.ReplicationReportsTableID),
+		descriptor(keys.StatementStatsTableID), descriptor(keys.TableStatisticsTableID))
+	sort.Sort(roachpb.KeyValueByKey(migratedSql))
 
 	testCases := []struct {
 		values     []roachpb.KeyValue
@@ -326,6 +333,12 @@ func TestComputeSplitKeyTableIDs(t *testing.T) {
 		{baseSql, testutils.MakeKey(keys.MakeTablePrefix(reservedStart), roachpb.RKey("foo")),
 			testutils.MakeKey(keys.MakeTablePrefix(start+10), roachpb.RKey("foo")), reservedStart + 1},
 
+		// Reserved descriptors added by migrations.
+		{migratedSql, keys.MakeTablePrefix(keys.JobsTableID), roachpb.RKeyMax, keys.ReplicationReportsTableID},
+		{migratedSql, keys.MakeTablePrefix(keys.ReplicationReportsTableID), roachpb.RKeyMax, keys.StatementStatsTableID},
+		{migratedSql, keys.MakeTablePrefix(keys.StatementStatsTableID), roachpb.RKeyMax, keys.TableStatisticsTableID},
+		{migratedSql, keys.MakeTablePrefix(keys.TableStatisticsTableID), roachpb.RKeyMax, -1},
+
 		// Reserved + User descriptors.
 		{allSql, keys.MakeTablePrefix(start - 1), roachpb.RKeyMax, start},
 		{allSql, keys.MakeTablePrefix(start), roachpb.RKeyMax, start + 1},
@@ -367,6 +380,184 @@ func TestComputeSplitKeyTableIDs(t *testing.T) {
 	}
 }
 
+func TestSubzones(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	stopper := stop.NewStopper()
+	defer stopper.Stop()
+	config.TestingSetupZoneConfigHook(stopper)
+
+	const id = keys.MaxReservedDescID + 1
+	tableZone := config.DefaultZoneConfig()
+	indexZone := config.DefaultZoneConfig()
+	indexZone.NumReplicas = 5
+	tableZone.Subzones = []config.Subzone{
+		{IndexID: 2, Config: indexZone},
+		{IndexID: 4, Config: indexZone},
+	}
+	config.TestingSetZoneConfig(id, tableZone)
+	config.TestingSetZoneConfig(id+1, config.DefaultZoneConfig())
+
+	cfg := config.SystemConfig{}
+	cfg.Values = append(sqlbase.MakeMetadataSchema().GetInitialValues(),
+		descriptor(id), descriptor(id+1))
+	sort.Sort(roachpb.KeyValueByKey(cfg.Values))
+
+	indexPrefix := func(indexID uint64) roachpb.RKey {
+		return encoding.EncodeUvarintAscending(keys.MakeTablePrefix(id), indexID)
+	}
+	tableEnd := roachpb.RKey(keys.MakeTablePrefix(id + 1))
+	splitTestCases := []struct {
+		start, end roachpb.RKey
+		split      roachpb.RKey
+	}{
+		{keys.MakeTablePrefix(id), tableEnd, indexPrefix(2)},
+		{indexPrefix(1), tableEnd, indexPrefix(2)},
+		{indexPrefix(2), tableEnd, indexPrefix(3)},
+		{testutils.MakeKey(indexPrefix(2), roachpb.RKey("foo")), tableEnd, indexPrefix(3)},
+		{indexPrefix(3), tableEnd, indexPrefix(4)},
+		{indexPrefix(4), tableEnd, indexPrefix(5)},
+		{indexPrefix(5), tableEnd, nil},
+		{indexPrefix(5), roachpb.RKeyMax, keys.MakeRowSentinelKey(keys.MakeTablePrefix(id + 1))},
+		{keys.MakeTablePrefix(id), indexPrefix(2), nil},
+	}
+	for i, tc := range splitTestCases {
+		if splitKey := cfg.ComputeSplitKey(tc.start, tc.end); !splitKey.Equal(tc.split) {
+			t.Errorf("%d: bad split:\ngot: %v\nexpected: %v", i, splitKey, tc.split)
+		}
+	}
+
+	zoneTestCases := []struct {
+		key         roachpb.RKey
+		numReplicas int32
+	}{
+		{keys.MakeTablePrefix(id), 3},
+		{indexPrefix(1), 3},
+		{testutils.MakeKey(indexPrefix(2), roachpb.RKey("foo")), 5},
+		{indexPrefix(3), 3},
+		{indexPrefix(4), 5},
+	}
+	for i, tc := range zoneTestCases {
+		zone, err := cfg.GetZoneConfigForKey(tc.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if zone.NumReplicas != tc.numReplicas {
+			t.Errorf("%d: expected %d replicas, got %d", i, tc.numReplicas, zone.NumReplicas)
+		}
+		if len(zone.Subzones) > 0 {
+			t.Errorf("%d: expected no subzones, got %+v", i, zone.Subzones)
+		}
+	}
+}
+
+func TestSubzoneSpans(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	stopper := stop.NewStopper()
+	defer stopper.Stop()
+	config.TestingSetupZoneConfigHook(stopper)
+
+	const id = keys.MaxReservedDescID + 1
+	indexPrefix := func(indexID uint64) roachpb.RKey {
+		return encoding.EncodeUvarintAscending(keys.MakeTablePrefix(id), indexID)
+	}
+	// partitionKey returns the key of the row of index 1 whose first column is
+	// v, without the table prefix.
+	partitionKey := func(v int64) roachpb.Key {
+		return encoding.EncodeVarintAscending(encoding.EncodeUvarintAscending(nil, 1), v)
+	}
+	rowKey := func(v int64) roachpb.RKey {
+		return testutils.MakeKey(keys.MakeTablePrefix(id), partitionKey(v))
+	}
+
+	tableZone := config.DefaultZoneConfig()
+	zone := func(numReplicas int32) config.ZoneConfig {
+		z := config.DefaultZoneConfig()
+		z.NumReplicas = numReplicas
+		return z
+	}
+	tableZone.SetSubzone(config.Subzone{IndexID: 1, PartitionName: "p2", Config: zone(1)})
+	tableZone.SetSubzone(config.Subzone{IndexID: 1, PartitionName: "p1", Config: zone(5)})
+	tableZone.SetSubzone(config.Subzone{IndexID: 1, Config: zone(7)})
+	tableZone.SubzoneSpans = []config.SubzoneSpan{
+		// p1 is a LIST partition holding the value 5.
+		{Key: partitionKey(5), SubzoneIndex: 1},
+		// p2 is a RANGE partition holding the values in [10, 20).
+		{Key: partitionKey(10), EndKey: partitionKey(20), SubzoneIndex: 2},
+	}
+	if err := tableZone.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	config.TestingSetZoneConfig(id, tableZone)
+	config.TestingSetZoneConfig(id+1, config.DefaultZoneConfig())
+
+	cfg := config.SystemConfig{}
+	cfg.Values = append(sqlbase.MakeMetadataSchema().GetInitialValues(),
+		descriptor(id), descriptor(id+1))
+	sort.Sort(roachpb.KeyValueByKey(cfg.Values))
+
+	tableEnd := roachpb.RKey(keys.MakeTablePrefix(id + 1))
+	splitTestCases := []struct {
+		start, end roachpb.RKey
+		split      roachpb.RKey
+	}{
+		{keys.MakeTablePrefix(id), tableEnd, indexPrefix(1)},
+		{indexPrefix(1), tableEnd, rowKey(5)},
+		{rowKey(5), tableEnd, rowKey(5).PrefixEnd()},
+		{rowKey(5).PrefixEnd(), tableEnd, rowKey(10)},
+		{rowKey(10), tableEnd, rowKey(20)},
+		{rowKey(20), tableEnd, indexPrefix(2)},
+		{indexPrefix(2), tableEnd, nil},
+	}
+	for i, tc := range splitTestCases {
+		if splitKey := cfg.ComputeSplitKey(tc.start, tc.end); !splitKey.Equal(tc.split) {
+			t.Errorf("%d: bad split:\ngot: %v\nexpected: %v", i, splitKey, tc.split)
+		}
+	}
+
+	// An index ID of 0 means that no subzone applies.
+	zoneTestCases := []struct {
+		key         roachpb.RKey
+		numReplicas int32
+		indexID     uint32
+		partition   string
+	}{
+		{keys.MakeTablePrefix(id), 3, 0, ""},
+		{rowKey(3), 7, 1, ""},
+		{rowKey(5), 5, 1, "p1"},
+		{testutils.MakeKey(rowKey(5), roachpb.RKey("foo")), 5, 1, "p1"},
+		{rowKey(6), 7, 1, ""},
+		{rowKey(10), 1, 1, "p2"},
+		{rowKey(19), 1, 1, "p2"},
+		{rowKey(20), 7, 1, ""},
+		{indexPrefix(2), 3, 0, ""},
+	}
+	for i, tc := range zoneTestCases {
+		objectID, subzone, zone, err := cfg.GetZoneForKey(tc.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if objectID != id {
+			t.Errorf("%d: expected zone %d, got %d", i, id, objectID)
+		}
+		var indexID uint32
+		var partition string
+		if subzone != nil {
+			indexID, partition = subzone.IndexID, subzone.PartitionName
+		}
+		if indexID != tc.indexID || partition != tc.partition {
+			t.Errorf("%d: expected subzone %d.%q, got %d.%q", i, tc.indexID, tc.partition, indexID, partition)
+		}
+		if zone.NumReplicas != tc.numReplicas {
+			t.Errorf("%d: expected %d replicas, got %d", i, tc.numReplicas, zone.NumReplicas)
+		}
+		if len(zone.Subzones) > 0 || len(zone.SubzoneSpans) > 0 {
+			t.Errorf("%d: expected no subzones, got %+v", i, zone)
+		}
+	}
+}
+
 func TestGetZoneConfigForKey(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 
@@ -399,6 +590,9 @@ func TestGetZoneConfigForKey(t *testing.T) {
 		{keys.MakeTablePrefix(keys.ZonesTableID), keys.SystemDatabaseID},
 		{keys.MakeTablePrefix(keys.LeaseTableID), keys.SystemDatabaseID},
 		{keys.MakeTablePrefix(keys.JobsTableID), keys.SystemDatabaseID},
+		{keys.MakeTablePrefix(keys.ReplicationReportsTableID), keys.SystemDatabaseID},
+		{keys.MakeTablePrefix(keys.StatementStatsTableID), keys.SystemDatabaseID},
+		{keys.MakeTablePrefix(keys.TableStatisticsTableID), keys.SystemDatabaseID},
 		{keys.MakeTablePrefix(keys.MaxReservedDescID + 1), keys.MaxReservedDescID + 1},
 		{keys.MakeTablePrefix(keys.MaxReservedDescID + 23), keys.MaxReservedDescID + 23},
 		{roachpb.RKeyMax, keys.RootNamespaceID},
@@ -465,6 +659,133 @@ func TestZoneConfigValidate(t *testing.T) {
 			},
 			"is greater than or equal to RangeMaxBytes",
 		},
+		{
+			config.ZoneConfig{
+				NumReplicas:   1,
+				RangeMaxBytes: config.DefaultZoneConfig().RangeMaxBytes,
+				Subzones:      []config.Subzone{{IndexID: 1, Config: config.ZoneConfig{NumReplicas: 2}}},
+			},
+			"invalid subzone for index 1: at least 3 replicas are required",
+		},
+		{
+			config.ZoneConfig{
+				NumReplicas:   1,
+				RangeMaxBytes: config.DefaultZoneConfig().RangeMaxBytes,
+				Subzones: []config.Subzone{
+					{IndexID: 1, PartitionName: "p1", Config: config.ZoneConfig{NumReplicas: 2}},
+				},
+			},
+			`invalid subzone for partition "p1" of index 1: at least 3 replicas are required`,
+		},
+		{
+			config.ZoneConfig{
+				NumReplicas:   1,
+				RangeMaxBytes: config.DefaultZoneConfig().RangeMaxBytes,
+				SubzoneSpans:  []config.SubzoneSpan{{Key: roachpb.Key("a"), SubzoneIndex: 0}},
+			},
+			"refers to missing subzone 0",
+		},
+		{
+			config.ZoneConfig{
+				NumReplicas:   3,
+				RangeMaxBytes: config.DefaultZoneConfig().RangeMaxBytes,
+				ReplicaConstraints: config.ReplicaConstraints{
+					Constraints: []config.Constraints{{NumReplicas: 1}},
+				},
+			},
+			"every replica constraint must include at least one constraint",
+		},
+		{
+			config.ZoneConfig{
+				NumReplicas:   3,
+				RangeMaxBytes: config.DefaultZoneConfig().RangeMaxBytes,
+				ReplicaConstraints: config.ReplicaConstraints{
+					Constraints: []config.Constraints{{Constraints: []config.Constraint{
+						{Type: config.Constraint_REQUIRED, Key: "region", Value: "us"},
+					}}},
+				},
+			},
+			`replica constraint \[\+region=us\] must apply to at least one replica`,
+		},
+		{
+			config.ZoneConfig{
+				NumReplicas:   3,
+				RangeMaxBytes: config.DefaultZoneConfig().RangeMaxBytes,
+				ReplicaConstraints: config.ReplicaConstraints{
+					Constraints: []config.Constraints{{
+						Constraints: []config.Constraint{{Type: config.Constraint_POSITIVE, Value: "ssd"}},
+						NumReplicas: 1,
+					}},
+				},
+			},
+			`replica constraint "ssd" must be required \(\+\) or prohibited \(-\)`,
+		},
+		{
+			config.ZoneConfig{
+				NumReplicas:   3,
+				RangeMaxBytes: config.DefaultZoneConfig().RangeMaxBytes,
+				ReplicaConstraints: config.ReplicaConstraints{
+					Constraints: []config.Constraints{
+						{
+							Constraints: []config.Constraint{
+								{Type: config.Constraint_REQUIRED, Key: "region", Value: "us"},
+							},
+							NumReplicas: 2,
+						},
+						{
+							Constraints: []config.Constraint{
+								{Type: config.Constraint_REQUIRED, Key: "region", Value: "eu"},
+							},
+							NumReplicas: 2,
+						},
+					},
+				},
+			},
+			"replica constraints apply to 4 replicas, but num_replicas is 3",
+		},
+		{
+			config.ZoneConfig{
+				NumReplicas:   3,
+				RangeMaxBytes: config.DefaultZoneConfig().RangeMaxBytes,
+				ReplicaConstraints: config.ReplicaConstraints{
+					Constraints: []config.Constraints{{
+						Constraints: []config.Constraint{
+							{Type: config.Constraint_REQUIRED, Key: "region", Value: "us"},
+						},
+						NumReplicas: 2,
+					}},
+				},
+			},
+			"",
+		},
+		{
+			config.ZoneConfig{
+				NumReplicas:      1,
+				RangeMaxBytes:    config.DefaultZoneConfig().RangeMaxBytes,
+				LeasePreferences: []config.LeasePreference{{}},
+			},
+			"every lease preference must include at least one constraint",
+		},
+		{
+			config.ZoneConfig{
+				NumReplicas:   1,
+				RangeMaxBytes: config.DefaultZoneConfig().RangeMaxBytes,
+				LeasePreferences: []config.LeasePreference{
+					{Constraints: []config.Constraint{{Type: config.Constraint_POSITIVE, Value: "ssd"}}},
+				},
+			},
+			`lease preference constraint "ssd" must be required \(\+\) or prohibited \(-\)`,
+		},
+		{
+			config.ZoneConfig{
+				NumReplicas:   1,
+				RangeMaxBytes: config.DefaultZoneConfig().RangeMaxBytes,
+				LeasePreferences: []config.LeasePreference{
+					{Constraints: []config.Constraint{{Type: config.Constraint_REQUIRED, Value: "ssd"}}},
+				},
+			},
+			"",
+		},
 	}
 	for i, c := range testCases {
 		err := c.cfg.Validate()
@@ -504,6 +825,58 @@ func TestZoneConfigMarshalYAML(t *testing.T) {
 				},
 			},
 		},
+		ReplicaConstraints: config.ReplicaConstraints{
+			Constraints: []config.Constraints{
+				{
+					Constraints: []config.Constraint{
+						{
+							Type:  config.Constraint_REQUIRED,
+							Key:   "duck",
+							Value: "foo",
+						},
+					},
+					NumReplicas: 2,
+				},
+				{
+					Constraints: []config.Constraint{
+						{
+							Type:  config.Constraint_REQUIRED,
+							Key:   "duck",
+							Value: "bar",
+						},
+						{
+							Type:  config.Constraint_PROHIBITED,
+							Value: "ssd",
+						},
+					},
+					NumReplicas: 1,
+				},
+			},
+		},
+		LeasePreferences: []config.LeasePreference{
+			{
+				Constraints: []config.Constraint{
+					{
+						Type:  config.Constraint_REQUIRED,
+						Key:   "duck",
+						Value: "foo",
+					},
+				},
+			},
+			{
+				Constraints: []config.Constraint{
+					{
+						Type:  config.Constraint_PROHIBITED,
+						Key:   "duck",
+						Value: "foo",
+					},
+					{
+						Type:  config.Constraint_REQUIRED,
+						Value: "ssd",
+					},
+				},
+			},
+		},
 	}
 
 	expected := `range_min_bytes: 1
@@ -512,6 +885,8 @@ gc:
   ttlseconds: 1
 num_replicas: 1
 constraints: [foo, +duck=foo, -duck=foo]
+replica_constraints: {+duck=foo: 2, '+duck=bar,-ssd': 1}
+lease_preferences: [[+duck=foo], [-duck=foo, +ssd]]
 `
 
 	body, err := yaml.Marshal(original)
@@ -74,6 +74,11 @@ const (
 	// The value if a config.SystemConfig which holds all key/value
 	// pairs in the system DB span.
 	KeySystemConfig = "system-db"
+
+	// KeyTableStatAddedPrefix is the key prefix for gossiping that new
+	// statistics were collected on a table, so that the nodes refresh their
+	// cached statistics. The suffix is the table ID and the value is empty.
+	KeyTableStatAddedPrefix = "table-stat-added"
 )
 
 // MakeKey creates a canonical key under which to gossip a piece of
@@ -121,6 +126,27 @@ func MakeStoreKey(storeID roachpb.StoreID) string {
 	return MakeKey(KeyStorePrefix, storeID.String())
 }
 
+// MakeTableStatAddedKey returns the gossip key used to notify that new
+// statistics were collected on the given table.
+func MakeTableStatAddedKey(tableID uint32) string {
+	return MakeKey(KeyTableStatAddedPrefix, strconv.FormatUint(uint64(tableID), 10))
+}
+
+// TableIDFromTableStatAddedKey attempts to extract the table ID from the
+// provided key. The key should have been constructed by MakeTableStatAddedKey.
+// Returns an error if the key is not of the correct type or is not parsable.
+func TableIDFromTableStatAddedKey(key string) (uint32, error) {
+	trimmedKey := strings.TrimPrefix(key, KeyTableStatAddedPrefix+separator)
+	if trimmedKey == key {
+		return 0, errors.Errorf("%q is not a table statistics key", key)
+	}
+	tableID, err := strconv.ParseUint(trimmedKey, 10, 32)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed parsing table ID from key %q", key)
+	}
+	return uint32(tableID), nil
+}
+
 // MakeDeadReplicasKey returns the dead replicas gossip key for the given store.
 func MakeDeadReplicasKey(storeID roachpb.StoreID) string {
 	return MakeKey(KeyDeadReplicasPrefix, storeID.String())
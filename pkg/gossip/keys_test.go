@@ -59,3 +59,36 @@ func TestNodeIDFromKey(t *testing.T) {
 		})
 	}
 }
+
+func TestTableIDFromTableStatAddedKey(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	testCases := []struct {
+		key     string
+		tableID uint32
+		success bool
+	}{
+		{MakeTableStatAddedKey(0), 0, true},
+		{MakeTableStatAddedKey(51), 51, true},
+		{MakeTableStatAddedKey(51) + "foo", 0, false},
+		{"foo" + MakeTableStatAddedKey(51), 0, false},
+		{KeyTableStatAddedPrefix + ":", 0, false},
+		{KeyTableStatAddedPrefix + ":-1", 0, false},
+		{MakeNodeIDKey(1), 0, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.key, func(t *testing.T) {
+			tableID, err := TableIDFromTableStatAddedKey(tc.key)
+			if err != nil {
+				if tc.success {
+					t.Errorf("expected success, got error: %s", err)
+				}
+			} else if !tc.success {
+				t.Errorf("expected failure, got table ID %d", tableID)
+			} else if tableID != tc.tableID {
+				t.Errorf("expected table ID %d, got %d", tc.tableID, tableID)
+			}
+		})
+	}
+}
@@ -260,6 +260,7 @@ func (b *Batch) fillResults() error {
 			case *roachpb.CheckConsistencyRequest:
 			case *roachpb.WriteBatchRequest:
 			case *roachpb.ImportRequest:
+			case *roachpb.AddSSTableRequest:
 			case *roachpb.AdminScatterRequest:
 			}
 			// Fill up the resume span.
@@ -652,3 +653,23 @@ func (b *Batch) writeBatch(s, e interface{}, data []byte) {
 	b.appendReqs(req)
 	b.initResult(1, 0, notRaw, nil)
 }
+
+// addSSTable is only exported on DB.
+func (b *Batch) addSSTable(s, e interface{}, data []byte) {
+	begin, err := marshalKey(s)
+	if err != nil {
+		b.initResult(0, 0, notRaw, err)
+		return
+	}
+	end, err := marshalKey(e)
+	if err != nil {
+		b.initResult(0, 0, notRaw, err)
+		return
+	}
+	req := &roachpb.AddSSTableRequest{
+		Span: roachpb.Span{Key: begin, EndKey: end},
+		Data: data,
+	}
+	b.appendReqs(req)
+	b.initResult(1, 0, notRaw, nil)
+}
@@ -304,4 +304,10 @@ const (
 	MetaRangesID       = 16
 	SystemRangesID     = 17
 	TimeseriesRangesID = 18
+
+	// The IDs of the system tables added after the IDs above were reserved.
+	// NOTE: IDs must be <= MaxReservedDescID.
+	ReplicationReportsTableID = 19
+	StatementStatsTableID     = 20
+	TableStatisticsTableID    = 21
 )
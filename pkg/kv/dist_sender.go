@@ -763,10 +763,14 @@ func (ds *DistSender) divideAndSendBatchToRanges(
 			}
 			// If the request is more than but ends with EndTransaction, we
 			// want the caller to come again with the EndTransaction in an
-			// extra call.
+			// extra call. This doesn't apply to parallel commits, where the
+			// EndTransaction only stages the transaction and is meant to be
+			// sent in parallel with the transaction's final writes.
 			if l := len(ba.Requests) - 1; l > 0 && ba.Requests[l].GetInner().Method() == roachpb.EndTransaction {
-				responseCh <- response{pErr: errNo1PCTxn}
-				return
+				if et := ba.Requests[l].GetInner().(*roachpb.EndTransactionRequest); len(et.InFlightWrites) == 0 {
+					responseCh <- response{pErr: errNo1PCTxn}
+					return
+				}
 			}
 		}
 
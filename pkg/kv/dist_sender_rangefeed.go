@@ -0,0 +1,265 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package kv
+
+import (
+	"fmt"
+	"io"
+
+	"golang.org/x/net/context"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/retry"
+)
+
+// RangeFeed divides a RangeFeed request on range boundaries and establishes a
+// RangeFeed to each of the individual ranges. It streams back results on the
+// provided channel and only returns when the context is canceled or an
+// unrecoverable error is encountered.
+//
+// Each range feed is retried transparently (from its last checkpoint) when its
+// range splits, its lease moves or its connection fails. As a consequence the
+// same RangeFeedValue may be delivered more than once, and the timestamps of
+// RangeFeedCheckpoint events for the different parts of the span advance
+// independently; callers that need a resolved timestamp for the whole span
+// must track the minimum over all of it.
+func (ds *DistSender) RangeFeed(
+	ctx context.Context,
+	span roachpb.Span,
+	startFrom hlc.Timestamp,
+	eventCh chan<- *roachpb.RangeFeedEvent,
+) error {
+	ctx = ds.AnnotateCtx(ctx)
+	startRKey, err := keys.Addr(span.Key)
+	if err != nil {
+		return err
+	}
+	endRKey, err := keys.Addr(span.EndKey)
+	if err != nil {
+		return err
+	}
+	rs := roachpb.RSpan{Key: startRKey, EndKey: endRKey}
+
+	g, gCtx := errgroup.WithContext(ctx)
+	rangeCh := make(chan singleRangeInfo, 16)
+	g.Go(func() error {
+		for {
+			select {
+			case sri := <-rangeCh:
+				// Spawn a child goroutine to process this feed.
+				g.Go(func() error {
+					return ds.partialRangeFeed(gCtx, sri.rs, sri.ts, sri.desc, sri.token, rangeCh, eventCh)
+				})
+			case <-gCtx.Done():
+				return gCtx.Err()
+			}
+		}
+	})
+
+	// Kick off the initial set of ranges.
+	g.Go(func() error {
+		return ds.divideAndSendRangeFeedToRanges(gCtx, rs, startFrom, rangeCh)
+	})
+
+	return g.Wait()
+}
+
+// singleRangeInfo describes the part of a range feed's span that falls within
+// a single range, along with the timestamp to start that part of the feed from.
+type singleRangeInfo struct {
+	desc  *roachpb.RangeDescriptor
+	rs    roachpb.RSpan
+	ts    hlc.Timestamp
+	token *EvictionToken
+}
+
+// divideAndSendRangeFeedToRanges splits the provided span on range boundaries
+// and sends a singleRangeInfo for each piece on rangeCh.
+func (ds *DistSender) divideAndSendRangeFeedToRanges(
+	ctx context.Context, rs roachpb.RSpan, ts hlc.Timestamp, rangeCh chan<- singleRangeInfo,
+) error {
+	nextRS := rs
+	ri := NewRangeIterator(ds)
+	for ri.Seek(ctx, nextRS.Key, Ascending); ri.Valid(); ri.Next(ctx) {
+		desc := ri.Desc()
+		partialRS, err := nextRS.Intersect(desc)
+		if err != nil {
+			return err
+		}
+		nextRS.Key = partialRS.EndKey
+		select {
+		case rangeCh <- singleRangeInfo{
+			desc:  desc,
+			rs:    partialRS,
+			ts:    ts,
+			token: ri.Token(),
+		}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if !ri.NeedAnother(nextRS) {
+			break
+		}
+	}
+	return ri.Error().GoError()
+}
+
+// partialRangeFeed establishes a RangeFeed to the range specified by desc. It
+// manages lifecycle events of the range in order to maintain the RangeFeed
+// connection; this may involve instructing higher-level functions to retry
+// this rangefeed, or subdividing the range further in the event of a split.
+func (ds *DistSender) partialRangeFeed(
+	ctx context.Context,
+	rs roachpb.RSpan,
+	ts hlc.Timestamp,
+	desc *roachpb.RangeDescriptor,
+	token *EvictionToken,
+	rangeCh chan<- singleRangeInfo,
+	eventCh chan<- *roachpb.RangeFeedEvent,
+) error {
+	span := roachpb.Span{Key: rs.Key.AsRawKey(), EndKey: rs.EndKey.AsRawKey()}
+
+	for r := retry.StartWithCtx(ctx, ds.rpcRetryOptions); r.Next(); {
+		// If we've cleared the descriptor on a send failure, re-lookup.
+		if desc == nil {
+			var err error
+			desc, token, err = ds.getDescriptor(ctx, rs.Key, token, false)
+			if err != nil {
+				log.VEventf(ctx, 1, "range descriptor re-lookup failed: %s", err)
+				continue
+			}
+		}
+		if !desc.ContainsKeyRange(rs.Key, rs.EndKey) {
+			// The range was split; divide the span up again from where we got
+			// to and let the new pieces be picked up by other goroutines.
+			return ds.divideAndSendRangeFeedToRanges(ctx, rs, ts, rangeCh)
+		}
+
+		// Establish a RangeFeed for a single Range.
+		maxTS, pErr := ds.singleRangeFeed(ctx, span, ts, desc, eventCh)
+
+		// Forward the timestamp in case we end up sending it again.
+		ts.Forward(maxTS)
+
+		if pErr == nil {
+			continue
+		}
+		if log.V(1) {
+			log.Infof(ctx, "RangeFeed %s disconnected with last checkpoint %s: %s", span, ts, pErr)
+		}
+		switch tErr := pErr.GetDetail().(type) {
+		case *roachpb.SendError, *roachpb.RangeNotFoundError, *roachpb.RangeKeyMismatchError,
+			*roachpb.StoreNotFoundError, *roachpb.NodeUnavailableError:
+			// Evict the lease holder and descriptor from the caches and reload
+			// on the next attempt.
+			ds.updateLeaseHolderCache(ctx, desc.RangeID, roachpb.ReplicaDescriptor{})
+			if err := token.Evict(ctx); err != nil {
+				return err
+			}
+			desc = nil
+		case *roachpb.NotLeaseHolderError:
+			var lh roachpb.ReplicaDescriptor
+			if tErr.LeaseHolder != nil {
+				lh = *tErr.LeaseHolder
+			}
+			ds.updateLeaseHolderCache(ctx, desc.RangeID, lh)
+		default:
+			return pErr.GoError()
+		}
+	}
+	return ctx.Err()
+}
+
+// singleRangeFeed gathers and rearranges the replicas, and makes a RangeFeed
+// RPC call. Results will be sent on the provided channel. Returns the
+// timestamp of the maximum rangefeed checkpoint seen, which can be used to
+// re-establish the rangefeed with a larger starting timestamp, reflecting the
+// fact that all values up to the last checkpoint have definitely been seen.
+func (ds *DistSender) singleRangeFeed(
+	ctx context.Context,
+	span roachpb.Span,
+	ts hlc.Timestamp,
+	desc *roachpb.RangeDescriptor,
+	eventCh chan<- *roachpb.RangeFeedEvent,
+) (hlc.Timestamp, *roachpb.Error) {
+	replicas := NewReplicaSlice(ds.gossip, desc)
+	if len(replicas) == 0 {
+		return ts, roachpb.NewError(roachpb.NewSendError(
+			fmt.Sprintf("no replica node addresses available via gossip for r%d", desc.RangeID)))
+	}
+	replicas.OptimizeReplicaOrder(ds.getNodeDescriptor())
+	// Range feeds are served by the lease holder, so try it first.
+	if leaseHolder, ok := ds.leaseHolderCache.Lookup(ctx, desc.RangeID); ok {
+		if i := replicas.FindReplica(leaseHolder.StoreID); i >= 0 {
+			replicas.MoveToFront(i)
+		}
+	}
+
+	// Cancel the stream when we return, regardless of the reason.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	args := roachpb.RangeFeedRequest{Span: span}
+	args.Timestamp = ts
+	args.RangeID = desc.RangeID
+
+	// Only connection failures cause us to try the next replica. Errors
+	// returned by the replica itself are handled by the caller.
+	var stream roachpb.Internal_RangeFeedClient
+	var lastErr error
+	for _, replica := range replicas {
+		args.Replica = replica.ReplicaDescriptor
+		conn, err := ds.rpcContext.GRPCDial(replica.NodeDesc.Address.String())
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		stream, err = roachpb.NewInternalClient(conn).RangeFeed(ctx, &args)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		break
+	}
+	if stream == nil {
+		return ts, roachpb.NewError(roachpb.NewSendError(fmt.Sprintf(
+			"sending to all %d replicas failed; last error: %v", len(replicas), lastErr)))
+	}
+
+	for {
+		event, err := stream.Recv()
+		if err == io.EOF {
+			return ts, nil
+		}
+		if err != nil {
+			return ts, roachpb.NewError(roachpb.NewSendError(err.Error()))
+		}
+		switch t := event.GetValue().(type) {
+		case *roachpb.RangeFeedCheckpoint:
+			ts.Forward(t.ResolvedTS)
+		case *roachpb.RangeFeedError:
+			return ts, &t.Error
+		}
+		select {
+		case eventCh <- event:
+		case <-ctx.Done():
+			return ts, roachpb.NewError(ctx.Err())
+		}
+	}
+}
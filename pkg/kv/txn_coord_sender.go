@@ -30,7 +30,9 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/base"
 	"github.com/cockroachdb/cockroach/pkg/internal/client"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
 	"github.com/cockroachdb/cockroach/pkg/util"
+	"github.com/cockroachdb/cockroach/pkg/util/envutil"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/cockroach/pkg/util/metric"
@@ -50,6 +52,18 @@ const (
 
 var errNoState = errors.New("writing transaction timed out or ran on multiple coordinators")
 
+// pipelineWrites enables write pipelining: transactional point writes are
+// acknowledged before they have been replicated, and are proven to have
+// succeeded using QueryIntent requests before any overlapping request and
+// before the transaction commits.
+var pipelineWrites = envutil.EnvOrDefaultBool("COCKROACH_TXN_PIPELINE_WRITES", false)
+
+// parallelCommits enables parallel commits: the EndTransaction request of a
+// transaction is evaluated in parallel with its final writes, moving the
+// transaction record to the STAGING status, instead of only being sent once
+// all of the writes have succeeded.
+var parallelCommits = envutil.EnvOrDefaultBool("COCKROACH_TXN_PARALLEL_COMMITS", false)
+
 // txnMetadata holds information about an ongoing transaction, as
 // seen from the perspective of this coordinator. It records all
 // keys (and key ranges) mutated as part of the transaction for
@@ -63,6 +77,10 @@ type txnMetadata struct {
 	// to update the write intent when the transaction is committed.
 	keys []roachpb.Span
 
+	// inFlightWrites are the pipelined writes which haven't been proven to
+	// have succeeded yet, keyed by key.
+	inFlightWrites map[string]roachpb.SequencedWrite
+
 	// lastUpdateNanos is the latest wall time in nanos the client sent
 	// transaction operations to this coordinator. Accessed and updated
 	// atomically.
@@ -171,6 +189,11 @@ type TxnCoordSender struct {
 	linearizable bool // enables linearizable behaviour
 	stopper      *stop.Stopper
 	metrics      TxnMetrics
+
+	// pipelineWrites and parallelCommits default to the values of the
+	// corresponding environment variables.
+	pipelineWrites  bool
+	parallelCommits bool
 }
 
 var _ client.Sender = &TxnCoordSender{}
@@ -196,6 +219,8 @@ func NewTxnCoordSender(
 		linearizable:      linearizable,
 		stopper:           stopper,
 		metrics:           txnMetrics,
+		pipelineWrites:    pipelineWrites,
+		parallelCommits:   parallelCommits,
 	}
 	tc.txnMu.txns = map[uuid.UUID]*txnMetadata{}
 
@@ -304,6 +329,10 @@ func (tc *TxnCoordSender) Send(
 
 	startNS := tc.clock.PhysicalNow()
 
+	var et *roachpb.EndTransactionRequest
+	// numQueries is the number of QueryIntent requests prepended to the
+	// batch to prove in-flight writes.
+	var numQueries int
 	if ba.Txn != nil {
 		// If this request is part of a transaction...
 		if err := tc.validateTxnForBatch(&ba); err != nil {
@@ -319,7 +348,6 @@ func (tc *TxnCoordSender) Send(
 		sp.SetTag("txnID", txnIDStr)
 		sp.SetBaggageItem("txnID", txnIDStr)
 
-		var et *roachpb.EndTransactionRequest
 		var hasET bool
 		{
 			var rArgs roachpb.Request
@@ -347,6 +375,7 @@ func (tc *TxnCoordSender) Send(
 			}
 
 			if !hasET {
+				numQueries = tc.maybePipelineWritesLocked(&ba)
 				return nil
 			}
 			// Everything below is carried out only when trying to commit.
@@ -390,6 +419,7 @@ func (tc *TxnCoordSender) Send(
 			if txnMeta != nil {
 				txnMeta.keys = et.IntentSpans
 			}
+			numQueries = tc.maybeParallelCommitLocked(&ba, et)
 			return nil
 		}(); pErr != nil {
 			return nil, pErr
@@ -441,16 +471,31 @@ func (tc *TxnCoordSender) Send(
 			br, pErr = tc.resendWithTxn(ctx, ba)
 		}
 
-		if pErr = tc.updateState(ctx, startNS, ba, br, pErr); pErr != nil {
+		pErr = tc.updateState(ctx, startNS, ba, br, pErr)
+		if numQueries > 0 || ba.AsyncConsensus {
+			tc.updateInFlightWrites(ba, numQueries, pErr)
+		}
+		if pErr != nil {
+			pErr = stripQueryErrorIndex(pErr, numQueries)
 			log.Eventf(ctx, "error: %s", pErr)
 			return nil, pErr
 		}
+		ba.Requests = ba.Requests[numQueries:]
+		br.Responses = br.Responses[numQueries:]
 	}
 
 	if br.Txn == nil {
 		return br, nil
 	}
 
+	if br.Txn.Status == roachpb.STAGING {
+		var pErr *roachpb.Error
+		if br, pErr = tc.commitStagingTxn(ctx, startNS, ba, br, et); pErr != nil {
+			log.Eventf(ctx, "error: %s", pErr)
+			return nil, pErr
+		}
+	}
+
 	if _, ok := ba.GetArg(roachpb.EndTransaction); !ok {
 		return br, nil
 	}
@@ -589,6 +634,7 @@ func (tc *TxnCoordSender) unregisterTxnLocked(
 	status = txnMeta.txn.Status
 
 	txnMeta.keys = nil
+	txnMeta.inFlightWrites = nil
 
 	delete(tc.txnMu.txns, txnID)
 
@@ -715,7 +761,7 @@ func (tc *TxnCoordSender) heartbeat(ctx context.Context, txnID uuid.UUID) bool {
 	hasAbandoned := txnMeta.hasClientAbandonedCoord(tc.clock.PhysicalNow())
 	tc.txnMu.Unlock()
 
-	if txn.Status != roachpb.PENDING {
+	if txn.Status.IsFinalized() {
 		// A previous iteration has already determined that the transaction is
 		// already finalized, so we wait for the client to realize that and
 		// want to keep our state for the time being (to dish out the right
@@ -934,6 +980,10 @@ func (tc *TxnCoordSender) updateState(
 	if txnMeta != nil {
 		txnMeta.txn.Update(&newTxn)
 		txnMeta.setLastUpdate(tc.clock.PhysicalNow())
+		if newTxn.Epoch > ba.Txn.Epoch {
+			// The writes of the previous epoch don't need to be proven.
+			txnMeta.inFlightWrites = nil
+		}
 	}
 
 	if pErr == nil {
@@ -957,6 +1007,208 @@ func (tc *TxnCoordSender) updateState(
 	return pErr
 }
 
+// maybePipelineWritesLocked prepares a batch which doesn't end the
+// transaction for write pipelining. A QueryIntent request is prepended for
+// each in-flight write the batch overlaps, proving that the write succeeded
+// before the batch observes or overwrites it. Otherwise, if the batch
+// consists only of point writes, it is sent with AsyncConsensus set. Returns
+// the number of prepended requests.
+func (tc *TxnCoordSender) maybePipelineWritesLocked(ba *roachpb.BatchRequest) int {
+	txnMeta := tc.txnMu.txns[*ba.Txn.ID]
+	if txnMeta == nil {
+		return 0
+	}
+	var queries []roachpb.Request
+	for _, w := range txnMeta.inFlightWrites {
+		for _, union := range ba.Requests {
+			if union.GetInner().Header().Overlaps(roachpb.Span{Key: w.Key}) {
+				queries = append(queries, makeQueryIntent(ba.Txn, w))
+				break
+			}
+		}
+	}
+	if len(queries) > 0 {
+		prependRequests(ba, queries)
+		return len(queries)
+	}
+	if tc.pipelineWrites && ba.Txn.Writing && onlyPointWrites(ba.Requests) {
+		ba.AsyncConsensus = true
+	}
+	return 0
+}
+
+// maybeParallelCommitLocked prepares a committing batch. The transaction
+// can only commit once its in-flight writes have been proven, so a
+// QueryIntent request is prepended for each of them. With parallel commits,
+// the EndTransaction request additionally carries these writes along with
+// those of the batch, which lets it be evaluated in parallel with them: the
+// transaction is staged, and is committed once they all succeed. Returns the
+// number of prepended requests.
+func (tc *TxnCoordSender) maybeParallelCommitLocked(
+	ba *roachpb.BatchRequest, et *roachpb.EndTransactionRequest,
+) int {
+	txnMeta := tc.txnMu.txns[*ba.Txn.ID]
+	if txnMeta == nil || !et.Commit {
+		return 0
+	}
+	var queries []roachpb.Request
+	var inFlight []roachpb.SequencedWrite
+	for _, w := range txnMeta.inFlightWrites {
+		queries = append(queries, makeQueryIntent(ba.Txn, w))
+		inFlight = append(inFlight, w)
+	}
+	writes := ba.Requests[:len(ba.Requests)-1]
+	if tc.parallelCommits && et.InternalCommitTrigger == nil &&
+		ba.Txn.Isolation == enginepb.SERIALIZABLE && onlyPointWrites(writes) {
+		for _, union := range writes {
+			inFlight = append(inFlight, roachpb.SequencedWrite{
+				Key:      union.GetInner().Header().Key,
+				Sequence: ba.Txn.Sequence + 1,
+			})
+		}
+		if len(inFlight) > 0 {
+			et.InFlightWrites = inFlight
+		}
+	}
+	prependRequests(ba, queries)
+	return len(queries)
+}
+
+// updateInFlightWrites removes the in-flight writes proven by the first
+// numQueries requests of the batch and records the writes of a pipelined
+// batch as in flight. The latter happens even if the batch failed, since
+// part of it may have been written nonetheless.
+func (tc *TxnCoordSender) updateInFlightWrites(
+	ba roachpb.BatchRequest, numQueries int, pErr *roachpb.Error,
+) {
+	tc.txnMu.Lock()
+	defer tc.txnMu.Unlock()
+	txnMeta := tc.txnMu.txns[*ba.Txn.ID]
+	if txnMeta == nil || txnMeta.txn.Epoch != ba.Txn.Epoch {
+		return
+	}
+	if pErr == nil {
+		for _, union := range ba.Requests[:numQueries] {
+			delete(txnMeta.inFlightWrites, string(union.GetInner().Header().Key))
+		}
+	}
+	if !ba.AsyncConsensus {
+		return
+	}
+	if txnMeta.inFlightWrites == nil {
+		txnMeta.inFlightWrites = map[string]roachpb.SequencedWrite{}
+	}
+	for _, union := range ba.Requests {
+		key := union.GetInner().Header().Key
+		// The writes' sequence numbers are incremented by the DistSender, so
+		// they're at least one above that of the batch's transaction.
+		txnMeta.inFlightWrites[string(key)] = roachpb.SequencedWrite{
+			Key:      key,
+			Sequence: ba.Txn.Sequence + 1,
+		}
+	}
+}
+
+// commitStagingTxn explicitly commits a transaction which was staged by a
+// parallel commit, so that its intents can be resolved. If all of the
+// transaction's writes succeeded at the staging timestamp, the transaction
+// is already implicitly committed and the explicit commit is sent
+// asynchronously. Otherwise, it is sent synchronously; this typically
+// results in a retry error, as the transaction's timestamp was pushed.
+func (tc *TxnCoordSender) commitStagingTxn(
+	ctx context.Context,
+	startNS int64,
+	ba roachpb.BatchRequest,
+	br *roachpb.BatchResponse,
+	et *roachpb.EndTransactionRequest,
+) (*roachpb.BatchResponse, *roachpb.Error) {
+	txn := br.Txn.Clone()
+	txn.Status = roachpb.PENDING
+	txn.InFlightWrites = nil
+	commit := *et
+	commit.InFlightWrites = nil
+	var commitBa roachpb.BatchRequest
+	commitBa.Header = ba.Header
+	commitBa.Txn = &txn
+	commitBa.Add(&commit)
+
+	if txn.Timestamp == ba.Txn.Timestamp {
+		br.Txn.Status = roachpb.COMMITTED
+		br.Txn.InFlightWrites = nil
+		asyncCtx := tc.AnnotateCtx(context.Background())
+		if err := tc.stopper.RunAsyncTask(asyncCtx, func(ctx context.Context) {
+			if _, pErr := tc.wrapped.Send(ctx, commitBa); pErr != nil {
+				// The transaction will be recovered by whoever runs into its
+				// intents.
+				log.Warningf(ctx, "explicit commit of %s failed: %s", txn, pErr)
+			}
+		}); err != nil {
+			log.Warningf(ctx, "unable to commit %s explicitly: %s", txn, err)
+		}
+		return br, nil
+	}
+
+	commitBr, pErr := tc.wrapped.Send(ctx, commitBa)
+	if pErr = tc.updateState(ctx, startNS, commitBa, commitBr, pErr); pErr != nil {
+		return nil, pErr
+	}
+	br.Txn = commitBr.Txn
+	br.Responses[len(br.Responses)-1] = commitBr.Responses[0]
+	return br, nil
+}
+
+// makeQueryIntent returns a request which proves that the given in-flight
+// write of the transaction succeeded.
+func makeQueryIntent(txn *roachpb.Transaction, w roachpb.SequencedWrite) roachpb.Request {
+	meta := txn.TxnMeta
+	meta.Sequence = w.Sequence
+	return &roachpb.QueryIntentRequest{
+		Span:           roachpb.Span{Key: w.Key},
+		Txn:            meta,
+		ErrorIfMissing: true,
+	}
+}
+
+// prependRequests inserts the given requests at the front of the batch,
+// without modifying the batch's original slice of requests.
+func prependRequests(ba *roachpb.BatchRequest, reqs []roachpb.Request) {
+	if len(reqs) == 0 {
+		return
+	}
+	orig := ba.Requests
+	ba.Requests = make([]roachpb.RequestUnion, 0, len(reqs)+len(orig))
+	ba.Add(reqs...)
+	ba.Requests = append(ba.Requests, orig...)
+}
+
+// onlyPointWrites returns whether all of the given requests are
+// transactional point writes.
+func onlyPointWrites(reqs []roachpb.RequestUnion) bool {
+	for _, union := range reqs {
+		args := union.GetInner()
+		if !roachpb.IsTransactionWrite(args) || roachpb.IsRange(args) {
+			return false
+		}
+	}
+	return true
+}
+
+// stripQueryErrorIndex adjusts the index of an error for the removal of the
+// numQueries requests prepended to the batch. Errors caused by one of them
+// no longer refer to a request of the batch.
+func stripQueryErrorIndex(pErr *roachpb.Error, numQueries int) *roachpb.Error {
+	if numQueries == 0 || pErr.Index == nil {
+		return pErr
+	}
+	pErrShallow := *pErr
+	if index := pErr.Index.Index - int32(numQueries); index >= 0 {
+		pErrShallow.SetErrorIndex(index)
+	} else {
+		pErrShallow.Index = nil
+	}
+	return &pErrShallow
+}
+
 // TODO(tschottdorf): this method is somewhat awkward but unless we want to
 // give this error back to the client, our options are limited. We'll have to
 // run the whole thing for them, or any restart will still end up at the client
@@ -40,6 +40,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/cockroach/pkg/util/metric"
 	"github.com/cockroachdb/cockroach/pkg/util/stop"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
 	"github.com/cockroachdb/cockroach/pkg/util/tracing"
 	"github.com/cockroachdb/cockroach/pkg/util/uuid"
 )
@@ -1201,6 +1202,148 @@ func TestTxnCoordSenderNoDuplicateIntents(t *testing.T) {
 	}
 }
 
+// TestTxnCoordSenderPipelinedWrites verifies that point writes are sent with
+// AsyncConsensus once write pipelining is enabled, that they're proven by
+// QueryIntent requests before overlapping requests and before committing,
+// and that with parallel commits the final EndTransaction stages the
+// transaction and is followed by an asynchronous explicit commit.
+func TestTxnCoordSenderPipelinedWrites(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	stopper := stop.NewStopper()
+	manual := hlc.NewManualClock(123)
+	clock := hlc.NewClock(manual.UnixNano, time.Nanosecond)
+
+	var mu struct {
+		syncutil.Mutex
+		batches []roachpb.BatchRequest
+	}
+	var senderFn client.SenderFunc = func(_ context.Context, ba roachpb.BatchRequest) (
+		*roachpb.BatchResponse, *roachpb.Error) {
+		if _, ok := ba.GetArg(roachpb.HeartbeatTxn); !ok {
+			mu.Lock()
+			mu.batches = append(mu.batches, ba)
+			mu.Unlock()
+		}
+		br := ba.CreateReply()
+		txnClone := ba.Txn.Clone()
+		br.Txn = &txnClone
+		br.Txn.Writing = true
+		if rArgs, ok := ba.GetArg(roachpb.EndTransaction); ok {
+			if len(rArgs.(*roachpb.EndTransactionRequest).InFlightWrites) > 0 {
+				br.Txn.Status = roachpb.STAGING
+			} else {
+				br.Txn.Status = roachpb.COMMITTED
+			}
+		}
+		for i, req := range ba.Requests {
+			if _, ok := req.GetInner().(*roachpb.QueryIntentRequest); ok {
+				br.Responses[i].GetInner().(*roachpb.QueryIntentResponse).FoundIntent = true
+			}
+		}
+		return br, nil
+	}
+	ambient := log.AmbientContext{Tracer: tracing.NewTracer()}
+	ts := NewTxnCoordSender(
+		ambient,
+		senderFn,
+		clock,
+		false,
+		stopper,
+		MakeTxnMetrics(metric.TestSampleInterval),
+	)
+	ts.pipelineWrites = true
+	ts.parallelCommits = true
+
+	defer stopper.Stop()
+	defer teardownHeartbeats(ts)
+
+	// describe returns the methods of the batch's requests, prefixed by
+	// "async" if the batch doesn't wait for consensus.
+	describe := func(ba roachpb.BatchRequest) string {
+		var methods []string
+		if ba.AsyncConsensus {
+			methods = append(methods, "async")
+		}
+		for _, req := range ba.Requests {
+			methods = append(methods, req.GetInner().Method().String())
+		}
+		return fmt.Sprint(methods)
+	}
+	checkBatches := func(expected ...string) []roachpb.BatchRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		var actual []string
+		for _, ba := range mu.batches {
+			actual = append(actual, describe(ba))
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("expected batches %v, got %v", expected, actual)
+		}
+		batches := mu.batches
+		mu.batches = nil
+		return batches
+	}
+
+	db := client.NewDB(ts, clock)
+	txn := client.NewTxn(db)
+	ctx := context.TODO()
+
+	// The first write carries a BeginTransaction and can't be pipelined.
+	if err := txn.Put(ctx, roachpb.Key("a"), []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+	checkBatches("[BeginTransaction Put]")
+
+	if err := txn.Put(ctx, roachpb.Key("b"), []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+	checkBatches("[async Put]")
+
+	// Reading the pipelined write first proves it.
+	if _, err := txn.Get(ctx, roachpb.Key("b")); err != nil {
+		t.Fatal(err)
+	}
+	checkBatches("[QueryIntent Get]")
+
+	if err := txn.Put(ctx, roachpb.Key("c"), []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+	checkBatches("[async Put]")
+
+	// The commit proves the write to c, and stages the transaction along with
+	// the writes to c and d.
+	b := txn.NewBatch()
+	b.Put(roachpb.Key("d"), []byte("value"))
+	if err := txn.CommitInBatch(ctx, b); err != nil {
+		t.Fatal(err)
+	}
+	if txn.Proto().Status != roachpb.COMMITTED {
+		t.Fatalf("expected committed txn, got %s", txn.Proto().Status)
+	}
+	testutils.SucceedsSoon(t, func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(mu.batches) != 2 {
+			return errors.Errorf("expected 2 batches, got %d", len(mu.batches))
+		}
+		return nil
+	})
+	batches := checkBatches("[QueryIntent Put EndTransaction]", "[EndTransaction]")
+
+	et := batches[0].Requests[2].GetInner().(*roachpb.EndTransactionRequest)
+	var inFlight []string
+	for _, w := range et.InFlightWrites {
+		inFlight = append(inFlight, string(w.Key))
+	}
+	if expected := []string{"c", "d"}; !reflect.DeepEqual(inFlight, expected) {
+		t.Errorf("expected in-flight writes %v, got %v", expected, inFlight)
+	}
+	if et := batches[1].Requests[0].GetInner().(*roachpb.EndTransactionRequest); !et.Commit ||
+		len(et.InFlightWrites) != 0 || len(et.IntentSpans) != 4 {
+		t.Errorf("unexpected explicit commit %+v", et)
+	}
+}
+
 // checkTxnMetrics verifies that the provided Sender's transaction metrics match the expected
 // values. This is done through a series of retries with increasing backoffs, to work around
 // the TxnCoordSender's asynchronous updating of metrics after a transaction ends.
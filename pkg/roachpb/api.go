@@ -281,6 +281,14 @@ func (er *ExportResponse) combine(c combinable) error {
 
 var _ combinable = &ExportResponse{}
 
+// Add combines the values from other, for use on an accumulator BulkOpSummary.
+func (b *BulkOpSummary) Add(other BulkOpSummary) {
+	b.DataSize += other.DataSize
+	b.Rows += other.Rows
+	b.IndexEntries += other.IndexEntries
+	b.SystemRecords += other.SystemRecords
+}
+
 // Combine implements the combinable interface.
 func (r *AdminScatterResponse) combine(c combinable) error {
 	if r != nil {
@@ -295,6 +303,20 @@ func (r *AdminScatterResponse) combine(c combinable) error {
 
 var _ combinable = &AdminScatterResponse{}
 
+// Combine implements the combinable interface.
+func (r *ScanChangesResponse) combine(c combinable) error {
+	if r != nil {
+		otherR := c.(*ScanChangesResponse)
+		if err := r.ResponseHeader.combine(otherR.Header()); err != nil {
+			return err
+		}
+		r.Rows = append(r.Rows, otherR.Rows...)
+	}
+	return nil
+}
+
+var _ combinable = &ScanChangesResponse{}
+
 // Header implements the Request interface.
 func (rh Span) Header() Span {
 	return rh
@@ -502,6 +524,21 @@ func (*ImportRequest) Method() Method { return Import }
 // Method implements the Request interface.
 func (*AdminScatterRequest) Method() Method { return AdminScatter }
 
+// Method implements the Request interface.
+func (*ScanChangesRequest) Method() Method { return ScanChanges }
+
+// Method implements the Request interface.
+func (*QueryIntentRequest) Method() Method { return QueryIntent }
+
+// Method implements the Request interface.
+func (*RecoverTxnRequest) Method() Method { return RecoverTxn }
+
+// Method implements the Request interface.
+func (*ClearRangeRequest) Method() Method { return ClearRange }
+
+// Method implements the Request interface.
+func (*AddSSTableRequest) Method() Method { return AddSSTable }
+
 // ShallowCopy implements the Request interface.
 func (gr *GetRequest) ShallowCopy() Request {
 	shallowCopy := *gr
@@ -712,6 +749,36 @@ func (r *AdminScatterRequest) ShallowCopy() Request {
 	return &shallowCopy
 }
 
+// ShallowCopy implements the Request interface.
+func (r *ScanChangesRequest) ShallowCopy() Request {
+	shallowCopy := *r
+	return &shallowCopy
+}
+
+// ShallowCopy implements the Request interface.
+func (r *QueryIntentRequest) ShallowCopy() Request {
+	shallowCopy := *r
+	return &shallowCopy
+}
+
+// ShallowCopy implements the Request interface.
+func (r *RecoverTxnRequest) ShallowCopy() Request {
+	shallowCopy := *r
+	return &shallowCopy
+}
+
+// ShallowCopy implements the Request interface.
+func (r *ClearRangeRequest) ShallowCopy() Request {
+	shallowCopy := *r
+	return &shallowCopy
+}
+
+// ShallowCopy implements the Request interface.
+func (r *AddSSTableRequest) ShallowCopy() Request {
+	shallowCopy := *r
+	return &shallowCopy
+}
+
 // NewGet returns a Request initialized to get the value at key.
 func NewGet(key Key) Request {
 	return &GetRequest{
@@ -933,6 +1000,14 @@ func (*WriteBatchRequest) flags() int               { return isWrite | isRange }
 func (*ExportRequest) flags() int                   { return isRead | isRange }
 func (*ImportRequest) flags() int                   { return isAdmin | isAlone }
 func (*AdminScatterRequest) flags() int             { return isAdmin | isAlone | isRange }
+func (*ScanChangesRequest) flags() int              { return isRead | isRange | updatesTSCache }
+
+// QueryIntent updates the read timestamp cache so that an intent found to
+// be missing can't be written at or below the queried timestamp later on.
+func (*QueryIntentRequest) flags() int { return isRead | updatesTSCache }
+func (*RecoverTxnRequest) flags() int  { return isWrite | isAlone }
+func (*ClearRangeRequest) flags() int  { return isWrite | isRange | isAlone }
+func (*AddSSTableRequest) flags() int  { return isWrite | isRange | isAlone }
 
 // Keys returns credentials in an s3gof3r.Keys
 func (b *ExportStorage_S3) Keys() s3gof3r.Keys {
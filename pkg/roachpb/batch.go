@@ -315,8 +315,10 @@ func (ba BatchRequest) Split(canSplitET bool) [][]RequestUnion {
 			args := union.GetInner()
 			flags := args.flags()
 			method := args.Method()
-			// Regardless of flags, a NoopRequest is always compatible.
-			if method == Noop {
+			// Regardless of flags, a NoopRequest is always compatible. So is
+			// a QueryIntentRequest, which allows pipelined writes to be
+			// proven in the same batch as subsequent writes.
+			if method == Noop || method == QueryIntent {
 				continue
 			}
 			if !compatible(method, gFlags, flags) {
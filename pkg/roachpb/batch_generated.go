@@ -8,7 +8,7 @@ import (
 	"strconv"
 )
 
-type reqCounts [35]int32
+type reqCounts [40]int32
 
 // getReqCounts returns the number of times each
 // request type appears in the batch.
@@ -86,6 +86,16 @@ func (ba *BatchRequest) getReqCounts() reqCounts {
 			counts[33]++
 		case r.AdminScatter != nil:
 			counts[34]++
+		case r.ScanChanges != nil:
+			counts[35]++
+		case r.QueryIntent != nil:
+			counts[36]++
+		case r.RecoverTxn != nil:
+			counts[37]++
+		case r.ClearRange != nil:
+			counts[38]++
+		case r.AddSstable != nil:
+			counts[39]++
 		default:
 			panic(fmt.Sprintf("unsupported request: %+v", r))
 		}
@@ -129,6 +139,11 @@ var requestNames = []string{
 	"Import",
 	"QueryTxn",
 	"AdmScatter",
+	"ScanChanges",
+	"QueryIntent",
+	"RecoverTxn",
+	"ClearRange",
+	"AddSstable",
 }
 
 // Summary prints a short summary of the requests in a batch.
@@ -198,6 +213,11 @@ func (ba *BatchRequest) CreateReply() *BatchResponse {
 	var buf32 []ImportResponse
 	var buf33 []QueryTxnResponse
 	var buf34 []AdminScatterResponse
+	var buf35 []ScanChangesResponse
+	var buf36 []QueryIntentResponse
+	var buf37 []RecoverTxnResponse
+	var buf38 []ClearRangeResponse
+	var buf39 []AddSSTableResponse
 
 	for i, r := range ba.Requests {
 		switch {
@@ -411,6 +431,36 @@ func (ba *BatchRequest) CreateReply() *BatchResponse {
 			}
 			br.Responses[i].AdminScatter = &buf34[0]
 			buf34 = buf34[1:]
+		case r.ScanChanges != nil:
+			if buf35 == nil {
+				buf35 = make([]ScanChangesResponse, counts[35])
+			}
+			br.Responses[i].ScanChanges = &buf35[0]
+			buf35 = buf35[1:]
+		case r.QueryIntent != nil:
+			if buf36 == nil {
+				buf36 = make([]QueryIntentResponse, counts[36])
+			}
+			br.Responses[i].QueryIntent = &buf36[0]
+			buf36 = buf36[1:]
+		case r.RecoverTxn != nil:
+			if buf37 == nil {
+				buf37 = make([]RecoverTxnResponse, counts[37])
+			}
+			br.Responses[i].RecoverTxn = &buf37[0]
+			buf37 = buf37[1:]
+		case r.ClearRange != nil:
+			if buf38 == nil {
+				buf38 = make([]ClearRangeResponse, counts[38])
+			}
+			br.Responses[i].ClearRange = &buf38[0]
+			buf38 = buf38[1:]
+		case r.AddSstable != nil:
+			if buf39 == nil {
+				buf39 = make([]AddSSTableResponse, counts[39])
+			}
+			br.Responses[i].AddSstable = &buf39[0]
+			buf39 = buf39[1:]
 		default:
 			panic(fmt.Sprintf("unsupported request: %+v", r))
 		}
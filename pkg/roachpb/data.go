@@ -697,6 +697,12 @@ func NewTransaction(
 	}
 }
 
+// IsFinalized returns true if the status is COMMITTED or ABORTED, that is,
+// if the outcome of the transaction is known and can't change any more.
+func (ts TransactionStatus) IsFinalized() bool {
+	return ts == COMMITTED || ts == ABORTED
+}
+
 // LastActive returns the last timestamp at which client activity definitely
 // occurred, i.e. the maximum of OrigTimestamp and LastHeartbeat.
 func (t Transaction) LastActive() hlc.Timestamp {
@@ -723,6 +729,7 @@ func (t Transaction) Clone() Transaction {
 	// Note that we're not cloning the span keys under the assumption that the
 	// keys themselves are not mutable.
 	t.Intents = append([]Span(nil), t.Intents...)
+	t.InFlightWrites = append([]SequencedWrite(nil), t.InFlightWrites...)
 	return t
 }
 
@@ -872,7 +879,9 @@ func (t *Transaction) Update(o *Transaction) {
 	if len(t.Key) == 0 {
 		t.Key = o.Key
 	}
-	if o.Status != PENDING {
+	// A STAGING transaction may have been finalized in the meantime, in which
+	// case it must not regress.
+	if o.Status != PENDING && !(o.Status == STAGING && t.Status.IsFinalized()) {
 		t.Status = o.Status
 	}
 	if t.Epoch < o.Epoch {
@@ -910,6 +919,9 @@ func (t *Transaction) Update(o *Transaction) {
 	if len(o.Intents) > 0 {
 		t.Intents = o.Intents
 	}
+	if len(o.InFlightWrites) > 0 {
+		t.InFlightWrites = o.InFlightWrites
+	}
 }
 
 // UpgradePriority sets transaction priority to the maximum of current
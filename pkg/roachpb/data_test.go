@@ -418,6 +418,7 @@ var nonZeroTxn = Transaction{
 	WriteTooOld:        true,
 	RetryOnPush:        true,
 	Intents:            []Span{{Key: []byte("a"), EndKey: []byte("b")}},
+	InFlightWrites:     []SequencedWrite{{Key: []byte("c"), Sequence: 122}},
 }
 
 func TestTransactionUpdate(t *testing.T) {
@@ -455,6 +456,7 @@ func TestTransactionClone(t *testing.T) {
 	// listed below. If this test fails, please update the list below and/or
 	// Transaction.Clone().
 	expFields := []string{
+		"InFlightWrites.Key",
 		"Intents.EndKey",
 		"Intents.Key",
 		"TxnMeta.ID",
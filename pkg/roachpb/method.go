@@ -133,4 +133,16 @@ const (
 	// AdminScatter moves replicas and leaseholders for a selection of ranges.
 	// Best-effort.
 	AdminScatter
+	// ScanChanges returns all the MVCC versions written to a keyrange in a
+	// time interval.
+	ScanChanges
+	// QueryIntent checks whether a transaction has written an intent to a
+	// key.
+	QueryIntent
+	// RecoverTxn finalizes a STAGING transaction whose coordinator failed.
+	RecoverTxn
+	// ClearRange removes all data in a key span, including its MVCC history.
+	ClearRange
+	// AddSSTable links a file into the RocksDB log-structured merge-tree.
+	AddSSTable
 )
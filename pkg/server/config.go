@@ -385,13 +385,26 @@ func (cfg *Config) CreateEngines() (Engines, error) {
 					spec.SizePercent, spec.Path, humanizeutil.IBytes(sizeInBytes), humanizeutil.IBytes(base.MinimumStoreSize))
 			}
 
-			eng, err := engine.NewRocksDB(
-				spec.Attributes,
-				spec.Path,
-				cache,
-				sizeInBytes,
-				openFileLimitPerStore,
-			)
+			var eng *engine.RocksDB
+			if spec.EncryptionKeyPath != "" {
+				eng, err = engine.NewEncryptedRocksDB(
+					spec.Attributes,
+					spec.Path,
+					cache,
+					sizeInBytes,
+					openFileLimitPerStore,
+					spec.EncryptionKeyPath,
+					spec.OldEncryptionKeyPath,
+				)
+			} else {
+				eng, err = engine.NewRocksDB(
+					spec.Attributes,
+					spec.Path,
+					cache,
+					sizeInBytes,
+					openFileLimitPerStore,
+				)
+			}
 			if err != nil {
 				return Engines{}, err
 			}
@@ -887,6 +887,25 @@ func (n *Node) Batch(
 	return br, nil
 }
 
+// RangeFeed implements the roachpb.InternalServer interface.
+func (n *Node) RangeFeed(
+	args *roachpb.RangeFeedRequest, stream roachpb.Internal_RangeFeedServer,
+) error {
+	growStack()
+
+	// As with Batch, errors are returned in-band via a RangeFeedError event so
+	// their structure is preserved; plain errors are presumed to be from the
+	// RPC framework.
+	return n.stopper.RunTaskWithErr(func() error {
+		if pErr := n.stores.RangeFeed(args, stream); pErr != nil {
+			var event roachpb.RangeFeedEvent
+			event.SetValue(&roachpb.RangeFeedError{Error: *pErr})
+			return stream.Send(&event)
+		}
+		return nil
+	})
+}
+
 // setupSpanForIncomingRPC takes a context and returns a derived context with a
 // new span in it. Depending on the input context, that span might be a root
 // span or a child span. If it is a child span, it might be a child span of a
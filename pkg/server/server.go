@@ -52,6 +52,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql"
 	"github.com/cockroachdb/cockroach/pkg/sql/distsqlrun"
 	"github.com/cockroachdb/cockroach/pkg/sql/pgwire"
+	"github.com/cockroachdb/cockroach/pkg/sql/stats"
 	"github.com/cockroachdb/cockroach/pkg/storage"
 	"github.com/cockroachdb/cockroach/pkg/ts"
 	"github.com/cockroachdb/cockroach/pkg/ui"
@@ -80,6 +81,10 @@ var (
 	GracefulDrainModes = []serverpb.DrainMode{serverpb.DrainMode_CLIENT, serverpb.DrainMode_LEASES}
 )
 
+// tableStatsCacheSize is the number of tables whose statistics are cached by
+// each node.
+const tableStatsCacheSize = 256
+
 // Server is the cockroach server node.
 type Server struct {
 	nodeIDContainer base.NodeIDContainer
@@ -109,7 +114,9 @@ type Server struct {
 	raftTransport      *storage.RaftTransport
 	stopper            *stop.Stopper
 	sqlExecutor        *sql.Executor
+	execCfg            *sql.ExecutorConfig
 	leaseMgr           *sql.LeaseManager
+	jobRegistry        *sql.JobRegistry
 	engines            Engines
 	internalMemMetrics sql.MemoryMetrics
 	adminMemMetrics    sql.MemoryMetrics
@@ -248,6 +255,9 @@ func NewServer(cfg Config, stopper *stop.Stopper) (*Server, error) {
 		s.stopper, &s.internalMemMetrics)
 	s.leaseMgr.RefreshLeases(s.stopper, s.db, s.gossip)
 
+	s.jobRegistry = sql.NewJobRegistry(
+		s.cfg.AmbientCtx, s.db, s.leaseMgr, &s.nodeIDContainer, s.nodeLiveness)
+
 	s.refreshSettings()
 
 	// Set up the DistSQL server
@@ -268,6 +278,10 @@ func NewServer(cfg Config, stopper *stop.Stopper) (*Server, error) {
 	s.adminMemMetrics = sql.MakeMemMetrics("admin", cfg.HistogramWindowInterval())
 	s.registry.AddMetricStruct(s.adminMemMetrics)
 
+	tableStatsCache := stats.NewTableStatisticsCache(
+		tableStatsCacheSize, s.gossip, s.db, sql.InternalExecutor{LeaseManager: s.leaseMgr},
+	)
+
 	// Set up Executor
 	execCfg := sql.ExecutorConfig{
 		AmbientCtx:              s.cfg.AmbientCtx,
@@ -279,6 +293,9 @@ func NewServer(cfg Config, stopper *stop.Stopper) (*Server, error) {
 		LeaseManager:            s.leaseMgr,
 		Clock:                   s.clock,
 		DistSQLSrv:              s.distSQLServer,
+		Stopper:                 s.stopper,
+		JobRegistry:             s.jobRegistry,
+		TableStatsCache:         tableStatsCache,
 		HistogramWindowInterval: s.cfg.HistogramWindowInterval(),
 		RangeDescriptorCache:    s.distSender.RangeDescriptorCache(),
 		LeaseHolderCache:        s.distSender.LeaseHolderCache(),
@@ -295,6 +312,7 @@ func NewServer(cfg Config, stopper *stop.Stopper) (*Server, error) {
 		execCfg.SchemaChangerTestingKnobs = &sql.SchemaChangerTestingKnobs{}
 	}
 	s.sqlExecutor = sql.NewExecutor(execCfg, s.stopper)
+	s.execCfg = &execCfg
 	s.registry.AddMetricStruct(s.sqlExecutor)
 
 	s.pgServer = pgwire.MakeServer(
@@ -614,6 +632,9 @@ func (s *Server) Start(ctx context.Context) error {
 		return errors.Wrap(err, "failed to create engines")
 	}
 	s.stopper.AddCloser(&s.engines)
+	if len(s.engines) > 0 {
+		s.distSQLServer.TempPrefix = s.engines[0].GetTempDir()
+	}
 
 	// We might have to sleep a bit to protect against this node producing non-
 	// monotonic timestamps. Before restarting, its clock might have been driven
@@ -696,6 +717,7 @@ func (s *Server) Start(ctx context.Context) error {
 	).Start(s.stopper)
 
 	s.sqlExecutor.Start(ctx, &s.adminMemMetrics, s.node.Descriptor)
+	s.execCfg.DistLoader = s.sqlExecutor.DistLoader()
 	s.distSQLServer.Start()
 
 	log.Infof(ctx, "starting %s server at %s", s.cfg.HTTPRequestScheme(), unresolvedHTTPAddr)
@@ -812,6 +834,10 @@ func (s *Server) Start(ctx context.Context) error {
 	close(serveSQL)
 	log.Info(ctx, "serving sql connections")
 
+	// Adopt jobs whose node died. Resuming them runs SQL queries, so this must
+	// also be done after the SQL layer is ready.
+	s.jobRegistry.Start(ctx, s.stopper, s.execCfg)
+
 	// Record that this node joined the cluster in the event log. Since this
 	// executes a SQL query, this must be done after the SQL layer is ready.
 	s.node.recordJoinEvent()
@@ -843,6 +869,11 @@ func (s *Server) doDrain(modes []serverpb.DrainMode, setTo bool) ([]serverpb.Dra
 			}(); err != nil {
 				return nil, err
 			}
+			if setTo {
+				// Persist the statement statistics now that the clients are
+				// gone, while the node is still fully functional.
+				s.sqlExecutor.FlushStmtStats(context.TODO())
+			}
 		case serverpb.DrainMode_LEASES:
 			s.nodeLiveness.SetDraining(context.TODO(), setTo)
 			if err := s.node.SetDraining(setTo); err != nil {
@@ -17,6 +17,7 @@ package server_test
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -147,3 +148,62 @@ func TestSettingsRefresh(t *testing.T) {
 		return nil
 	})
 }
+
+var testingDuration = settings.RegisterDurationSetting(
+	"server.testing.duration", "a duration for testing", time.Second,
+)
+
+func TestSettingsOnChange(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	changes := make(chan time.Duration, 10)
+	testingDuration.OnChange(func() {
+		changes <- testingDuration.Get()
+	})
+
+	s, rawDB, _ := serverutils.StartServer(t, base.TestServerArgs{})
+	defer s.Stopper().Stop()
+
+	db := sqlutils.MakeSQLRunner(t, rawDB)
+
+	waitForChange := func(expected time.Duration) {
+		select {
+		case actual := <-changes:
+			if expected != actual {
+				t.Fatalf("expected %v, got %v", expected, actual)
+			}
+		case <-time.After(testutils.DefaultSucceedsSoonDuration):
+			t.Fatalf("no change to %v observed", expected)
+		}
+	}
+
+	db.Exec(`SET CLUSTER SETTING server.testing.duration = '1h'`)
+	waitForChange(time.Hour)
+	if expected, actual := "1h0m0s", db.QueryStr(
+		"SHOW CLUSTER SETTING server.testing.duration",
+	)[0][0]; expected != actual {
+		t.Fatalf("expected %v, got %v", expected, actual)
+	}
+
+	// Resetting the setting reverts it to its default.
+	db.Exec(`RESET CLUSTER SETTING server.testing.duration`)
+	waitForChange(time.Second)
+
+	// SHOW ALL CLUSTER SETTINGS lists the setting with its type and
+	// description.
+	found := false
+	for _, row := range db.QueryStr(`SHOW ALL CLUSTER SETTINGS`) {
+		if row[0] != testingDuration.Key() {
+			continue
+		}
+		found = true
+		if expected := []string{
+			"server.testing.duration", "1s", "duration", "a duration for testing",
+		}; fmt.Sprint(expected) != fmt.Sprint(row) {
+			t.Fatalf("expected %v, got %v", expected, row)
+		}
+	}
+	if !found {
+		t.Fatal("setting not listed by SHOW ALL CLUSTER SETTINGS")
+	}
+}
@@ -444,6 +444,11 @@ func (ts *TestServer) DistSQLServer() interface{} {
 	return ts.distSQLServer
 }
 
+// JobRegistry is part of TestServerInterface.
+func (ts *TestServer) JobRegistry() interface{} {
+	return ts.jobRegistry
+}
+
 // SetDistSQLSpanResolver is part of TestServerInterface.
 func (ts *Server) SetDistSQLSpanResolver(spanResolver interface{}) {
 	ts.sqlExecutor.SetDistSQLSpanResolver(spanResolver.(distsqlplan.SpanResolver))
@@ -15,8 +15,11 @@
 package settings
 
 import (
+	"strings"
+
 	"github.com/pkg/errors"
 
+	"github.com/cockroachdb/cockroach/pkg/util/humanizeutil"
 	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
 )
 
@@ -25,6 +28,9 @@ import (
 var cache struct {
 	syncutil.RWMutex
 	values map[string]value
+	// overrides are the values set by tests, which take precedence over
+	// `values`.
+	overrides map[string]value
 }
 
 // getVal gets the current value for key if it is set or the default value.
@@ -38,34 +44,42 @@ func getVal(key string, t ValueType) value {
 	if !ok {
 		panic(errors.Errorf("invalid setting '%s'", key))
 	}
-	if d.typ != t {
-		panic(errors.Errorf("setting '%s' is defined as %c, not %c)", key, d.typ, t))
+	if d.defaultValue.typ != t {
+		panic(errors.Errorf("setting '%s' is defined as %c, not %c)", key, d.defaultValue.typ, t))
 	}
 
 	cache.RLock()
-	set, ok := cache.values[key]
+	set, ok := cache.overrides[key]
+	if !ok {
+		set, ok = cache.values[key]
+	}
 	cache.RUnlock()
 
 	if ok {
 		return set
 	}
-	return d
-}
-
-func getString(key string) string {
-	return getVal(key, StringValue).s
-}
-
-func getBool(key string) bool {
-	return getVal(key, BoolValue).b
-}
-
-func getInt(key string) int {
-	return getVal(key, IntValue).i
+	return d.defaultValue
 }
 
-func getFloat(key string) float64 {
-	return getVal(key, FloatValue).f
+// setOverride makes the setting `key` take the value v until the returned
+// function is called, regardless of the values applied by Updaters.
+func setOverride(key string, v value) func() {
+	cache.Lock()
+	defer cache.Unlock()
+	if cache.overrides == nil {
+		cache.overrides = make(map[string]value)
+	}
+	prev, hadPrev := cache.overrides[key]
+	cache.overrides[key] = v
+	return func() {
+		cache.Lock()
+		defer cache.Unlock()
+		if hadPrev {
+			cache.overrides[key] = prev
+		} else {
+			delete(cache.overrides, key)
+		}
+	}
 }
 
 func (v value) String() string {
@@ -74,30 +88,75 @@ func (v value) String() string {
 		return v.s
 	case BoolValue:
 		return EncodeBool(v.b)
-	case IntValue:
+	case IntValue, ByteSizeValue, EnumValue:
 		return EncodeInt(v.i)
 	case FloatValue:
 		return EncodeFloat(v.f)
+	case DurationValue:
+		return EncodeDuration(v.d)
 	default:
 		panic("unknown value type " + string(v.typ)) // something something sealed.
 	}
 }
 
 // Show returns a string representation of the current value for a named setting
-// if it exists.
+// if it exists. Unlike the encoded values stored in system.settings, byte sizes
+// are shown in human-readable form and enums by name.
 func Show(key string) (string, bool) {
-	def, ok := registry[key]
+	d, ok := registry[key]
 	if !ok {
 		return "", false
 	}
-	cache.RLock()
-	set, ok := cache.values[key]
-	cache.RUnlock()
+	v := getVal(key, d.defaultValue.typ)
+	switch v.typ {
+	case ByteSizeValue:
+		return humanizeutil.IBytes(v.i), true
+	case EnumValue:
+		if name, ok := d.enumValues[v.i]; ok {
+			return name, true
+		}
+	}
+	return v.String(), true
+}
 
-	if ok {
-		return set.String(), true
+// Validate checks that a value encoded in the format stored in
+// system.settings is valid for the named setting.
+func Validate(key, rawValue string) error {
+	d, ok := registry[key]
+	if !ok {
+		return errors.Errorf("unknown setting '%s'", key)
+	}
+	_, err := d.parse(key, rawValue)
+	return err
+}
+
+// ParseEnum returns the value of the named enum setting with the given name,
+// which is matched case-insensitively.
+func ParseEnum(key, name string) (int64, bool) {
+	d, ok := registry[key]
+	if !ok {
+		return 0, false
 	}
-	return def.String(), true
+	for i, n := range d.enumValues {
+		if strings.EqualFold(n, name) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// parse parses and validates a value of the setting.
+func (d *entry) parse(key, rawValue string) (value, error) {
+	v, err := parseRaw(rawValue, d.defaultValue.typ)
+	if err != nil {
+		return value{}, err
+	}
+	if d.validate != nil {
+		if err := d.validate(v); err != nil {
+			return value{}, errors.Wrapf(err, "invalid value for setting '%s'", key)
+		}
+	}
+	return v, nil
 }
 
 // Updater is a helper for replacing the global settings map. It is intended to
@@ -153,11 +212,11 @@ func (u Updater) Add(key, rawValue, vt string) error {
 	if err != nil {
 		return err
 	}
-	if typ != d.typ {
-		return errors.Errorf("setting '%s' defined as type %c, not %c", key, d.typ, typ)
+	if typ != d.defaultValue.typ {
+		return errors.Errorf("setting '%s' defined as type %c, not %c", key, d.defaultValue.typ, typ)
 	}
 
-	parsed, err := parseRaw(rawValue, typ)
+	parsed, err := d.parse(key, rawValue)
 	if err != nil {
 		return err
 	}
@@ -165,37 +224,50 @@ func (u Updater) Add(key, rawValue, vt string) error {
 	return nil
 }
 
-// Apply swaps the global cache to our new map, and Closes() the Updater.
+// Apply swaps the global cache to our new map, and Closes() the Updater. The
+// OnChange callbacks of the settings whose values changed are then run.
 func (u Updater) Apply() {
 	cache.Lock()
+	prev := cache.values
 	cache.values = u
 	cache.Unlock()
 
-	afterApply.Lock()
-	for _, f := range afterApply.hooks {
-		f()
+	onChange.Lock()
+	defer onChange.Unlock()
+	for key, fns := range onChange.fns {
+		d, ok := registry[key]
+		if !ok {
+			continue
+		}
+		old, ok := prev[key]
+		if !ok {
+			old = d.defaultValue
+		}
+		cur, ok := u[key]
+		if !ok {
+			cur = d.defaultValue
+		}
+		if old == cur {
+			continue
+		}
+		for _, f := range fns {
+			f()
+		}
 	}
-	afterApply.Unlock()
 }
 
-var afterApply struct {
+var onChange struct {
 	syncutil.Mutex
-	hooks []func()
+	fns map[string][]func()
 }
 
-// RegisterCallback registers `f` to be called immediately and then after new
-// settings are read (even if there are no changes).
-//
-// `f` would likely read a setting to update an atomic int or channel, or
-// otherwise trigger additional work. `f` should not block, or otherwise do any
-// lengthy work itself, as it blocks the settings updater.
-//
-// RegisterCallback can (and likely should) be called at `init` -- indeed, the
-// eager call during registration is intended to ensure it initializes defaults
-// even before the first gossip update is received.
-func RegisterCallback(f func()) {
-	f()
-	afterApply.Lock()
-	afterApply.hooks = append(afterApply.hooks, f)
-	afterApply.Unlock()
+// registerOnChange registers `f` to be called after new settings are applied
+// in which the value of the setting `key` changed.
+func registerOnChange(key string, f func()) {
+	onChange.Lock()
+	defer onChange.Unlock()
+	if onChange.fns == nil {
+		onChange.fns = make(map[string][]func())
+	}
+	onChange.fns[key] = append(onChange.fns[key], f)
 }
@@ -16,152 +16,239 @@ package settings
 
 import (
 	"testing"
+	"time"
+
+	"github.com/pkg/errors"
 
 	"github.com/cockroachdb/cockroach/pkg/testutils"
 )
 
-var i1, i2 int
+const mb = int64(1024 * 1024)
 
-func init() {
-	registry = map[string]value{
-		"bool.t":  {typ: BoolValue, b: true},
-		"bool.f":  {typ: BoolValue},
-		"str.foo": {typ: StringValue},
-		"str.bar": {typ: StringValue, s: "bar"},
-		"i.1":     {typ: IntValue},
-		"i.2":     {typ: IntValue, i: 5},
-		"f":       {typ: FloatValue, f: 5.4},
+var boolTA = RegisterBoolSetting("bool.t", "desc", true)
+var boolFA = RegisterBoolSetting("bool.f", "desc", false)
+var strFooA = RegisterStringSetting("str.foo", "desc", "")
+var strBarA = RegisterStringSetting("str.bar", "desc", "bar")
+var i1A = RegisterIntSetting("i.1", "desc", 0)
+var i2A = RegisterIntSetting("i.2", "desc", 5)
+var fA = RegisterFloatSetting("f", "desc", 5.4)
+var dA = RegisterDurationSetting("d", "desc", time.Second)
+var byteSizeA = RegisterByteSizeSetting("zzz", "desc", mb)
+var eA = RegisterEnumSetting("e", "desc", "foo", map[int64]string{1: "foo", 2: "bar", 3: "baz"})
+
+var strVal = RegisterValidatedStringSetting("str.val", "desc", "", func(v string) error {
+	for _, c := range v {
+		if c < 'a' || c > 'z' {
+			return errors.Errorf("not all lowercase: %s", v)
+		}
 	}
+	return nil
+})
+var iVal = RegisterBoundedIntSetting("i.Val", "desc", 0, -5, 5)
+var fVal = RegisterBoundedFloatSetting("f.Val", "desc", 0, -5, 5)
+var dVal = RegisterNonNegativeDurationSetting("d.Val", "desc", time.Second)
+var byteSizeVal = RegisterValidatedByteSizeSetting("byteSize.Val", "desc", mb, func(v int64) error {
+	if v < mb {
+		return errors.Errorf("%d is less than %d", v, mb)
+	}
+	return nil
+})
 
-	RegisterCallback(func() {
-		i1 = getInt("i.1")
-		i2 = getInt("i.2")
-	})
+// i1Changes and i2Changes count the calls of the OnChange callbacks of i.1 and
+// i.2.
+var i1Changes, i2Changes int
+
+func init() {
+	i1A.OnChange(func() { i1Changes++ })
+	i2A.OnChange(func() { i2Changes++ })
+}
+
+// apply applies the given settings, as triples of key, encoded value and
+// type, reverting all the others to their defaults.
+func apply(t *testing.T, kvs ...string) {
+	u := MakeUpdater()
+	for i := 0; i < len(kvs); i += 3 {
+		if err := u.Add(kvs[i], kvs[i+1], kvs[i+2]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	u.Apply()
 }
 
 func TestCache(t *testing.T) {
 	t.Run("defaults", func(t *testing.T) {
-		if expected, actual := false, getBool("bool.f"); expected != actual {
+		if expected, actual := false, boolFA.Get(); expected != actual {
 			t.Fatalf("expected %v, got %v", expected, actual)
 		}
-		if expected, actual := true, getBool("bool.t"); expected != actual {
+		if expected, actual := true, boolTA.Get(); expected != actual {
 			t.Fatalf("expected %v, got %v", expected, actual)
 		}
-		if expected, actual := "", getString("str.foo"); expected != actual {
+		if expected, actual := "", strFooA.Get(); expected != actual {
 			t.Fatalf("expected %v, got %v", expected, actual)
 		}
-		if expected, actual := "bar", getString("str.bar"); expected != actual {
+		if expected, actual := "bar", strBarA.Get(); expected != actual {
 			t.Fatalf("expected %v, got %v", expected, actual)
 		}
-		if expected, actual := 0, getInt("i.1"); expected != actual {
+		if expected, actual := int64(0), i1A.Get(); expected != actual {
 			t.Fatalf("expected %v, got %v", expected, actual)
 		}
-		if expected, actual := 5, getInt("i.2"); expected != actual {
+		if expected, actual := int64(5), i2A.Get(); expected != actual {
 			t.Fatalf("expected %v, got %v", expected, actual)
 		}
-		// registering callback should have also run it initially and set default.
-		if expected, actual := 0, i1; expected != actual {
+		if expected, actual := 5.4, fA.Get(); expected != actual {
 			t.Fatalf("expected %v, got %v", expected, actual)
 		}
-		if expected, actual := 5, i2; expected != actual {
+		if expected, actual := time.Second, dA.Get(); expected != actual {
 			t.Fatalf("expected %v, got %v", expected, actual)
 		}
-		if expected, actual := 5.4, getFloat("f"); expected != actual {
+		if expected, actual := mb, byteSizeA.Get(); expected != actual {
+			t.Fatalf("expected %v, got %v", expected, actual)
+		}
+		if expected, actual := int64(1), eA.Get(); expected != actual {
 			t.Fatalf("expected %v, got %v", expected, actual)
 		}
 		if actual, ok := TypeOf("i.1"); !ok || IntValue != actual {
 			t.Fatalf("expected %v, got %v (exists: %v)", IntValue, actual, ok)
 		}
-		if actual, ok := TypeOf("f"); !ok || FloatValue != actual {
-			t.Fatalf("expected %v, got %v (exists: %v)", FloatValue, actual, ok)
+		if actual, ok := TypeOf("d"); !ok || DurationValue != actual {
+			t.Fatalf("expected %v, got %v (exists: %v)", DurationValue, actual, ok)
 		}
 		if actual, ok := TypeOf("dne"); ok {
 			t.Fatalf("expected nothing, got %v", actual)
 		}
+		if actual, ok := Description("f"); !ok || actual != "desc" {
+			t.Fatalf("expected desc, got %v (exists: %v)", actual, ok)
+		}
+		if expected, actual := "i.1", i1A.Key(); expected != actual {
+			t.Fatalf("expected %v, got %v", expected, actual)
+		}
+	})
+
+	t.Run("show", func(t *testing.T) {
+		for key, expected := range map[string]string{
+			"bool.t":  "true",
+			"str.bar": "bar",
+			"i.2":     "5",
+			"f":       "5.4E+00",
+			"d":       "1s",
+			"zzz":     "1.0 MiB",
+			"e":       "foo",
+		} {
+			if actual, ok := Show(key); !ok || expected != actual {
+				t.Errorf("%s: expected %v, got %v (exists: %v)", key, expected, actual, ok)
+			}
+		}
+	})
+
+	t.Run("keys", func(t *testing.T) {
+		keys := Keys()
+		for i := 1; i < len(keys); i++ {
+			if keys[i-1] >= keys[i] {
+				t.Fatalf("keys not sorted: %v", keys)
+			}
+		}
+		if len(keys) != len(registry) {
+			t.Fatalf("expected %d keys, got %v", len(registry), keys)
+		}
 	})
 
 	t.Run("read and write each type", func(t *testing.T) {
-		u := MakeUpdater()
-		if err := u.Add("bool.t", EncodeBool(false), "b"); err != nil {
-			t.Fatal(err)
+		apply(t,
+			"bool.t", EncodeBool(false), "b",
+			"bool.f", EncodeBool(true), "b",
+			"str.foo", "baz", "s",
+			"i.2", EncodeInt(3), "i",
+			"f", EncodeFloat(3.1), "f",
+			"d", EncodeDuration(2*time.Hour), "d",
+			"zzz", EncodeInt(10*mb), "z",
+			"e", EncodeInt(3), "e",
+		)
+
+		if expected, actual := false, boolTA.Get(); expected != actual {
+			t.Fatalf("expected %v, got %v", expected, actual)
 		}
-		if err := u.Add("bool.f", EncodeBool(true), "b"); err != nil {
-			t.Fatal(err)
+		if expected, actual := true, boolFA.Get(); expected != actual {
+			t.Fatalf("expected %v, got %v", expected, actual)
 		}
-		if err := u.Add("str.foo", "baz", "s"); err != nil {
-			t.Fatal(err)
+		if expected, actual := "baz", strFooA.Get(); expected != actual {
+			t.Fatalf("expected %v, got %v", expected, actual)
 		}
-		if err := u.Add("i.2", EncodeInt(3), "i"); err != nil {
-			t.Fatal(err)
+		if expected, actual := int64(3), i2A.Get(); expected != actual {
+			t.Fatalf("expected %v, got %v", expected, actual)
 		}
-		if err := u.Add("f", EncodeFloat(3.1), "f"); err != nil {
-			t.Fatal(err)
+		if expected, actual := 3.1, fA.Get(); expected != actual {
+			t.Fatalf("expected %v, got %v", expected, actual)
 		}
-		u.Apply()
-
-		if expected, actual := false, getBool("bool.t"); expected != actual {
+		if expected, actual := 2*time.Hour, dA.Get(); expected != actual {
 			t.Fatalf("expected %v, got %v", expected, actual)
 		}
-		if expected, actual := true, getBool("bool.f"); expected != actual {
+		if expected, actual := 10*mb, byteSizeA.Get(); expected != actual {
 			t.Fatalf("expected %v, got %v", expected, actual)
 		}
-		if expected, actual := "baz", getString("str.foo"); expected != actual {
+		if expected, actual := int64(3), eA.Get(); expected != actual {
 			t.Fatalf("expected %v, got %v", expected, actual)
 		}
-		if expected, actual := 3, getInt("i.2"); expected != actual {
+		if actual, _ := Show("e"); actual != "baz" {
+			t.Fatalf("expected baz, got %v", actual)
+		}
+
+		// We didn't change this one, so should still see the default.
+		if expected, actual := "bar", strBarA.Get(); expected != actual {
 			t.Fatalf("expected %v, got %v", expected, actual)
 		}
-		if expected, actual := 0, i1; expected != actual {
+	})
+
+	t.Run("OnChange is called when, and only when, the value changes", func(t *testing.T) {
+		apply(t)
+		i1, i2 := i1Changes, i2Changes
+
+		apply(t, "i.1", EncodeInt(1), "i", "i.2", EncodeInt(5), "i")
+		if expected, actual := i1+1, i1Changes; expected != actual {
 			t.Fatalf("expected %v, got %v", expected, actual)
 		}
-		if expected, actual := 3, i2; expected != actual {
+		// i.2 was set to its default value.
+		if expected, actual := i2, i2Changes; expected != actual {
 			t.Fatalf("expected %v, got %v", expected, actual)
 		}
-		if expected, actual := 3.1, getFloat("f"); expected != actual {
+
+		apply(t, "i.1", EncodeInt(1), "i")
+		if expected, actual := i1+1, i1Changes; expected != actual {
 			t.Fatalf("expected %v, got %v", expected, actual)
 		}
 
-		// We didn't change this one, so should still see the default.
-		if expected, actual := "bar", getString("str.bar"); expected != actual {
+		// Reverting to the default is a change.
+		apply(t)
+		if expected, actual := i1+2, i1Changes; expected != actual {
+			t.Fatalf("expected %v, got %v", expected, actual)
+		}
+		if expected, actual := i2, i2Changes; expected != actual {
 			t.Fatalf("expected %v, got %v", expected, actual)
 		}
 	})
 
 	t.Run("any setting not included in an Updater reverts to default", func(t *testing.T) {
-		{
-			u := MakeUpdater()
-			if err := u.Add("bool.f", EncodeBool(true), "b"); err != nil {
-				t.Fatal(err)
-			}
-			if err := u.Add("i.1", EncodeInt(1), "i"); err != nil {
-				t.Fatal(err)
-			}
-			if err := u.Add("i.2", EncodeInt(7), "i"); err != nil {
-				t.Fatal(err)
-			}
-			u.Apply()
-		}
+		apply(t, "bool.f", EncodeBool(true), "b", "i.1", EncodeInt(1), "i", "i.2", EncodeInt(7), "i")
 
-		if expected, actual := true, getBool("bool.f"); expected != actual {
+		if expected, actual := true, boolFA.Get(); expected != actual {
 			t.Fatalf("expected %v, got %v", expected, actual)
 		}
-		if expected, actual := 1, i1; expected != actual {
+		if expected, actual := int64(1), i1A.Get(); expected != actual {
 			t.Fatalf("expected %v, got %v", expected, actual)
 		}
-		if expected, actual := 7, i2; expected != actual {
+		if expected, actual := int64(7), i2A.Get(); expected != actual {
 			t.Fatalf("expected %v, got %v", expected, actual)
 		}
 		// If the updater doesn't have a key, e.g. if the setting has been deleted,
 		// applying it from the cache.
 		MakeUpdater().Apply()
 
-		if expected, actual := false, getBool("bool.f"); expected != actual {
+		if expected, actual := false, boolFA.Get(); expected != actual {
 			t.Fatalf("expected %v, got %v", expected, actual)
 		}
-		if expected, actual := 0, i1; expected != actual {
+		if expected, actual := int64(0), i1A.Get(); expected != actual {
 			t.Fatalf("expected %v, got %v", expected, actual)
 		}
-		if expected, actual := 5, i2; expected != actual {
+		if expected, actual := int64(5), i2A.Get(); expected != actual {
 			t.Fatalf("expected %v, got %v", expected, actual)
 		}
 
@@ -176,25 +263,18 @@ func TestCache(t *testing.T) {
 			u.Apply()
 		}
 
-		if expected, actual := false, getBool("bool.f"); expected != actual {
+		if expected, actual := false, boolFA.Get(); expected != actual {
 			t.Fatalf("expected %v, got %v", expected, actual)
 		}
 	})
 
 	t.Run("an invalid update to a given setting preserves its previously set value", func(t *testing.T) {
-		{
-			u := MakeUpdater()
-			if err := u.Add("i.2", EncodeInt(9), "i"); err != nil {
-				t.Fatal(err)
-			}
-			u.Apply()
-		}
-		before := getInt("i.2")
+		apply(t, "i.2", EncodeInt(9), "i")
+		before := i2A.Get()
 
 		// Applying after attempting to set with wrong type preserves current value.
 		{
 			u := MakeUpdater()
-			// We don't use testutils.IsError, to avoid the import.
 			if err := u.Add("i.2", EncodeBool(false), "b"); !testutils.IsError(err,
 				"setting 'i.2' defined as type i, not b",
 			) {
@@ -203,7 +283,7 @@ func TestCache(t *testing.T) {
 			u.Apply()
 		}
 
-		if expected, actual := before, getInt("i.2"); expected != actual {
+		if expected, actual := before, i2A.Get(); expected != actual {
 			t.Fatalf("expected %v, got %v", expected, actual)
 		}
 
@@ -211,15 +291,99 @@ func TestCache(t *testing.T) {
 		{
 			u := MakeUpdater()
 			if err := u.Add("i.2", EncodeBool(false), "i"); !testutils.IsError(err,
-				"strconv.Atoi: parsing \"false\": invalid syntax",
+				"strconv.ParseInt: parsing \"false\": invalid syntax",
 			) {
 				t.Fatal(err)
 			}
 			u.Apply()
 		}
 
-		if expected, actual := before, getInt("i.2"); expected != actual {
+		if expected, actual := before, i2A.Get(); expected != actual {
+			t.Fatalf("expected %v, got %v", expected, actual)
+		}
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		apply(t,
+			"str.val", "abc", "s",
+			"i.Val", EncodeInt(-3), "i",
+			"f.Val", EncodeFloat(4.5), "f",
+			"d.Val", EncodeDuration(time.Minute), "d",
+			"byteSize.Val", EncodeInt(2*mb), "z",
+		)
+
+		for _, tc := range []struct {
+			key, raw, typ string
+			err           string
+		}{
+			{"str.val", "abc1", "s", "not all lowercase"},
+			{"i.Val", EncodeInt(6), "i", `6 is outside of the range \[-5, 5\]`},
+			{"f.Val", EncodeFloat(-5.5), "f", `-5.5 is outside of the range \[-5, 5\]`},
+			{"d.Val", EncodeDuration(-time.Minute), "d", "cannot set to a negative duration"},
+			{"byteSize.Val", EncodeInt(mb - 1), "z", "is less than"},
+			{"byteSize.Val", EncodeInt(-mb), "z", "cannot set to a negative size"},
+			{"e", EncodeInt(4), "e", "4 is not one of the values of the enum"},
+		} {
+			if err := Validate(tc.key, tc.raw); !testutils.IsError(err, tc.err) {
+				t.Errorf("%s: expected %q, got %v", tc.key, tc.err, err)
+			}
+			u := MakeUpdater()
+			if err := u.Add(tc.key, tc.raw, tc.typ); !testutils.IsError(err, tc.err) {
+				t.Errorf("%s: expected %q, got %v", tc.key, tc.err, err)
+			}
+		}
+
+		// The previously set values are preserved.
+		if expected, actual := "abc", strVal.Get(); expected != actual {
+			t.Fatalf("expected %v, got %v", expected, actual)
+		}
+		if expected, actual := int64(-3), iVal.Get(); expected != actual {
+			t.Fatalf("expected %v, got %v", expected, actual)
+		}
+		if expected, actual := 4.5, fVal.Get(); expected != actual {
+			t.Fatalf("expected %v, got %v", expected, actual)
+		}
+		if expected, actual := time.Minute, dVal.Get(); expected != actual {
+			t.Fatalf("expected %v, got %v", expected, actual)
+		}
+		if expected, actual := 2*mb, byteSizeVal.Get(); expected != actual {
+			t.Fatalf("expected %v, got %v", expected, actual)
+		}
+	})
+
+	t.Run("overrides take precedence over applied values", func(t *testing.T) {
+		apply(t, "d", EncodeDuration(time.Minute), "d")
+		undo := dA.Override(time.Hour)
+		if expected, actual := time.Hour, dA.Get(); expected != actual {
 			t.Fatalf("expected %v, got %v", expected, actual)
 		}
+		apply(t, "d", EncodeDuration(2*time.Minute), "d")
+		if expected, actual := time.Hour, dA.Get(); expected != actual {
+			t.Fatalf("expected %v, got %v", expected, actual)
+		}
+		undo()
+		if expected, actual := 2*time.Minute, dA.Get(); expected != actual {
+			t.Fatalf("expected %v, got %v", expected, actual)
+		}
+	})
+
+	t.Run("type names", func(t *testing.T) {
+		for key, expected := range map[string]string{
+			"bool.t": "bool", "str.foo": "string", "i.1": "int", "f": "float",
+			"d": "duration", "zzz": "bytesize", "e": "enum",
+		} {
+			if typ, ok := TypeOf(key); !ok || typ.String() != expected {
+				t.Errorf("expected %s to be a %s, got %s (found: %v)", key, expected, typ, ok)
+			}
+		}
+	})
+
+	t.Run("enum names", func(t *testing.T) {
+		if v, ok := ParseEnum("e", "BAR"); !ok || v != 2 {
+			t.Fatalf("expected 2, got %d (found: %v)", v, ok)
+		}
+		if v, ok := ParseEnum("e", "qux"); ok {
+			t.Fatalf("expected nothing, got %d", v)
+		}
 	})
 }
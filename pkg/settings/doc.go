@@ -25,20 +25,28 @@ state obviously apply, it is needed to make the package's functionality
 available to a wide variety of callsites, that may or may not have a *Server or
 similar available to plumb though.
 
-To add a new setting, update `registry.go` to define it in the `registry` map,
-then add an exported accessor (wrapping one of the `getBool`, `getString`, etc
-helpers).
+To add a new setting, call one of the `Register` functions (e.g.
+`RegisterBoolSetting`) at init, with the name, a description and the default
+value of the setting, and keep the returned object to read its value. Settings
+with restricted values can be registered with a validation function (e.g.
+`RegisterValidatedIntSetting`), which is then checked both when the setting is
+changed through SQL and when its value is read from the settings table.
 
 For example, to add an "enterprise" flag:
-var registry = map[string]settingValue{
-	....
-+ "enterprise.enabled": {typ: BoolValue},
 
-...
+	// EnterpriseEnabled is the "enterprise.enabled" setting.
+	var EnterpriseEnabled = settings.RegisterBoolSetting(
+		"enterprise.enabled", "set to true to enable enterprise features", false,
+	)
 
-+// GetEnterpriseEnabled returns the enterprise.enabled setting.
-+func GetEnterpriseEnabled() bool {
-+   return getBool("enterprise.enabled")
-+}
+	...
+
+	if EnterpriseEnabled.Get() {
+		...
+	}
+
+Code that needs to react to changes of a setting, rather than read its value
+when needed, can register a callback with `OnChange`, which the settings worker
+calls after applying new values that change the setting.
 */
 package settings
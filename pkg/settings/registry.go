@@ -14,15 +14,29 @@
 
 package settings
 
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
 // registry contains all defined settings, their types and default values.
 //
-// Entries in registry should be accompanied by an exported, typesafe getter
-// that then wraps one of the private `getBool`, `getString`, etc helpers.
+// Entries in registry are added by the Register* functions (see setting.go),
+// which return the typed objects through which the settings are read.
 //
 // Registry should never be mutated after init (except in tests), as it is read
 // concurrently by different callers.
-var registry = map[string]value{
-	"enterprise.enabled": {typ: BoolValue},
+var registry = map[string]*entry{}
+
+// entry holds the definition of a setting.
+type entry struct {
+	description  string
+	defaultValue value
+	// validate, if set, is run on values before they are applied.
+	validate func(value) error
+	// enumValues maps the values of an enum setting to their names.
+	enumValues map[int64]string
 }
 
 // value holds the (parsed, typed) value of a setting.
@@ -31,50 +45,96 @@ var registry = map[string]value{
 // basically a poor-man's union, without boxing).
 type value struct {
 	typ ValueType
-	// Exactly one of these will be set, determined by typ.
+	// Exactly one of these will be set, determined by typ. Byte sizes and enums
+	// use i.
 	s string
 	b bool
-	i int
+	i int64
 	f float64
+	d time.Duration
+}
+
+// register adds a setting to the registry. It panics if the key is already
+// defined or the default value is invalid.
+func register(key, desc string, defaultValue value, validate func(value) error) *entry {
+	if _, ok := registry[key]; ok {
+		panic(fmt.Sprintf("setting already defined: %s", key))
+	}
+	if validate != nil {
+		if err := validate(defaultValue); err != nil {
+			panic(fmt.Sprintf("invalid default value for %s: %v", key, err))
+		}
+	}
+	e := &entry{description: desc, defaultValue: defaultValue, validate: validate}
+	registry[key] = e
+	return e
 }
 
 // TypeOf returns the type of a setting, if it is defined.
 func TypeOf(key string) (ValueType, bool) {
 	d, ok := registry[key]
-	return d.typ, ok
+	if !ok {
+		return 0, false
+	}
+	return d.defaultValue.typ, true
 }
 
-// EnterpriseEnabled returns the "enterprise.enabled" setting.
-// "enterprise.enabled" allows the use of the enterprise functionality (which
-// requires an enterprise license).
-// This is a temporary setting and will be replaced in the future.
-func EnterpriseEnabled() bool {
-	return getBool("enterprise.enabled")
+// Description returns the description of a setting, if it is defined.
+func Description(key string) (string, bool) {
+	d, ok := registry[key]
+	if !ok {
+		return "", false
+	}
+	return d.description, true
+}
+
+// Keys returns the names of all the defined settings, sorted.
+func Keys() []string {
+	keys := make([]string, 0, len(registry))
+	for k := range registry {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
+// EnterpriseEnabled is the "enterprise.enabled" setting, which allows the use
+// of the enterprise functionality (which requires an enterprise license).
+// This is a temporary setting and will be replaced in the future.
+var EnterpriseEnabled = RegisterBoolSetting(
+	"enterprise.enabled", "set to true to enable enterprise features", false,
+)
+
 // We export Testing* helpers for the settings-related tests in the SQL package.
 const (
 	testingStr = "testing.str"
 	testingInt = "testing.int"
 )
 
+var testingStrSetting *StringSetting
+var testingIntSetting *IntSetting
+
 // TestingAddTestVars registers placeholder string and int settings, returning
 // their names. They default to "<default>" and 1.
 func TestingAddTestVars() (string, string, func()) {
-	registry[testingStr] = value{typ: StringValue, s: "<default>"}
-	registry[testingInt] = value{typ: IntValue, i: 1}
+	testingStrSetting = RegisterStringSetting(testingStr, "for testing", "<default>")
+	testingIntSetting = RegisterIntSetting(testingInt, "for testing", 1)
 	return testingStr, testingInt, func() {
 		delete(registry, testingStr)
 		delete(registry, testingInt)
+		onChange.Lock()
+		delete(onChange.fns, testingStr)
+		delete(onChange.fns, testingInt)
+		onChange.Unlock()
 	}
 }
 
 // TestingGetString gets the current value for the testing string placeholder.
 func TestingGetString() string {
-	return getString(testingStr)
+	return testingStrSetting.Get()
 }
 
 // TestingGetInt gets the current value for the testing int placeholder.
 func TestingGetInt() int {
-	return getInt(testingInt)
+	return int(testingIntSetting.Get())
 }
@@ -0,0 +1,336 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package settings
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// common is embedded in the typed settings, which are the handles through
+// which the code using a setting reads it.
+type common struct {
+	key string
+}
+
+// Key returns the name of the setting.
+func (c common) Key() string {
+	return c.key
+}
+
+// OnChange registers `fn` to be called after new settings are applied in
+// which the value of the setting changed (including when it reverts to its
+// default).
+//
+// `fn` would likely read the setting to update an atomic int or channel, or
+// otherwise trigger additional work. It should not block, or otherwise do any
+// lengthy work itself, as it blocks the settings updater.
+func (c common) OnChange(fn func()) {
+	registerOnChange(c.key, fn)
+}
+
+// BoolSetting is the interface of a setting variable that will be updated
+// automatically when the corresponding cluster-wide setting of type "bool" is
+// updated.
+type BoolSetting struct {
+	common
+}
+
+// Get retrieves the bool value in the setting.
+func (b *BoolSetting) Get() bool {
+	return getVal(b.key, BoolValue).b
+}
+
+// Override sets the value of the setting, for use in tests, until the returned
+// function is called. It takes precedence over the value in system.settings.
+func (b *BoolSetting) Override(v bool) func() {
+	return setOverride(b.key, value{typ: BoolValue, b: v})
+}
+
+// StringSetting is the interface of a setting variable that will be updated
+// automatically when the corresponding cluster-wide setting of type "string"
+// is updated.
+type StringSetting struct {
+	common
+}
+
+// Get retrieves the string value in the setting.
+func (s *StringSetting) Get() string {
+	return getVal(s.key, StringValue).s
+}
+
+// Override sets the value of the setting, for use in tests, until the returned
+// function is called. It takes precedence over the value in system.settings.
+func (s *StringSetting) Override(v string) func() {
+	return setOverride(s.key, value{typ: StringValue, s: v})
+}
+
+// IntSetting is the interface of a setting variable that will be updated
+// automatically when the corresponding cluster-wide setting of type "int" is
+// updated.
+type IntSetting struct {
+	common
+}
+
+// Get retrieves the int value in the setting.
+func (i *IntSetting) Get() int64 {
+	return getVal(i.key, IntValue).i
+}
+
+// Override sets the value of the setting, for use in tests, until the returned
+// function is called. It takes precedence over the value in system.settings.
+func (i *IntSetting) Override(v int64) func() {
+	return setOverride(i.key, value{typ: IntValue, i: v})
+}
+
+// FloatSetting is the interface of a setting variable that will be updated
+// automatically when the corresponding cluster-wide setting of type "float" is
+// updated.
+type FloatSetting struct {
+	common
+}
+
+// Get retrieves the float value in the setting.
+func (f *FloatSetting) Get() float64 {
+	return getVal(f.key, FloatValue).f
+}
+
+// Override sets the value of the setting, for use in tests, until the returned
+// function is called. It takes precedence over the value in system.settings.
+func (f *FloatSetting) Override(v float64) func() {
+	return setOverride(f.key, value{typ: FloatValue, f: v})
+}
+
+// DurationSetting is the interface of a setting variable that will be updated
+// automatically when the corresponding cluster-wide setting of type "duration"
+// is updated.
+type DurationSetting struct {
+	common
+}
+
+// Get retrieves the duration value in the setting.
+func (d *DurationSetting) Get() time.Duration {
+	return getVal(d.key, DurationValue).d
+}
+
+// Override sets the value of the setting, for use in tests, until the returned
+// function is called. It takes precedence over the value in system.settings.
+func (d *DurationSetting) Override(v time.Duration) func() {
+	return setOverride(d.key, value{typ: DurationValue, d: v})
+}
+
+// ByteSizeSetting is the interface of a setting variable that will be updated
+// automatically when the corresponding cluster-wide setting of type
+// "bytesize" is updated.
+type ByteSizeSetting struct {
+	common
+}
+
+// Get retrieves the byte size value in the setting.
+func (b *ByteSizeSetting) Get() int64 {
+	return getVal(b.key, ByteSizeValue).i
+}
+
+// Override sets the value of the setting, for use in tests, until the returned
+// function is called. It takes precedence over the value in system.settings.
+func (b *ByteSizeSetting) Override(v int64) func() {
+	return setOverride(b.key, value{typ: ByteSizeValue, i: v})
+}
+
+// EnumSetting is the interface of a setting variable that will be updated
+// automatically when the corresponding cluster-wide setting of type "enum" is
+// updated. Its values are integers, each of which has a name.
+type EnumSetting struct {
+	common
+}
+
+// Get retrieves the enum value in the setting.
+func (e *EnumSetting) Get() int64 {
+	return getVal(e.key, EnumValue).i
+}
+
+// Override sets the value of the setting, for use in tests, until the returned
+// function is called. It takes precedence over the value in system.settings.
+func (e *EnumSetting) Override(v int64) func() {
+	return setOverride(e.key, value{typ: EnumValue, i: v})
+}
+
+// RegisterBoolSetting defines a new setting with type bool.
+func RegisterBoolSetting(key, desc string, defaultValue bool) *BoolSetting {
+	register(key, desc, value{typ: BoolValue, b: defaultValue}, nil)
+	return &BoolSetting{common{key: key}}
+}
+
+// RegisterStringSetting defines a new setting with type string.
+func RegisterStringSetting(key, desc string, defaultValue string) *StringSetting {
+	return RegisterValidatedStringSetting(key, desc, defaultValue, nil)
+}
+
+// RegisterValidatedStringSetting defines a new setting with type string with
+// a validation function.
+func RegisterValidatedStringSetting(
+	key, desc string, defaultValue string, validateFn func(string) error,
+) *StringSetting {
+	var validate func(value) error
+	if validateFn != nil {
+		validate = func(v value) error { return validateFn(v.s) }
+	}
+	register(key, desc, value{typ: StringValue, s: defaultValue}, validate)
+	return &StringSetting{common{key: key}}
+}
+
+// RegisterIntSetting defines a new setting with type int.
+func RegisterIntSetting(key, desc string, defaultValue int64) *IntSetting {
+	return RegisterValidatedIntSetting(key, desc, defaultValue, nil)
+}
+
+// RegisterValidatedIntSetting defines a new setting with type int with a
+// validation function.
+func RegisterValidatedIntSetting(
+	key, desc string, defaultValue int64, validateFn func(int64) error,
+) *IntSetting {
+	var validate func(value) error
+	if validateFn != nil {
+		validate = func(v value) error { return validateFn(v.i) }
+	}
+	register(key, desc, value{typ: IntValue, i: defaultValue}, validate)
+	return &IntSetting{common{key: key}}
+}
+
+// RegisterBoundedIntSetting defines a new setting with type int, the values of
+// which must be within [min, max].
+func RegisterBoundedIntSetting(
+	key, desc string, defaultValue int64, min, max int64,
+) *IntSetting {
+	return RegisterValidatedIntSetting(key, desc, defaultValue, func(v int64) error {
+		if v < min || v > max {
+			return errors.Errorf("%d is outside of the range [%d, %d]", v, min, max)
+		}
+		return nil
+	})
+}
+
+// RegisterFloatSetting defines a new setting with type float.
+func RegisterFloatSetting(key, desc string, defaultValue float64) *FloatSetting {
+	return RegisterValidatedFloatSetting(key, desc, defaultValue, nil)
+}
+
+// RegisterValidatedFloatSetting defines a new setting with type float with a
+// validation function.
+func RegisterValidatedFloatSetting(
+	key, desc string, defaultValue float64, validateFn func(float64) error,
+) *FloatSetting {
+	var validate func(value) error
+	if validateFn != nil {
+		validate = func(v value) error { return validateFn(v.f) }
+	}
+	register(key, desc, value{typ: FloatValue, f: defaultValue}, validate)
+	return &FloatSetting{common{key: key}}
+}
+
+// RegisterBoundedFloatSetting defines a new setting with type float, the
+// values of which must be within [min, max].
+func RegisterBoundedFloatSetting(
+	key, desc string, defaultValue float64, min, max float64,
+) *FloatSetting {
+	return RegisterValidatedFloatSetting(key, desc, defaultValue, func(v float64) error {
+		if v < min || v > max {
+			return errors.Errorf("%g is outside of the range [%g, %g]", v, min, max)
+		}
+		return nil
+	})
+}
+
+// RegisterDurationSetting defines a new setting with type duration.
+func RegisterDurationSetting(key, desc string, defaultValue time.Duration) *DurationSetting {
+	return RegisterValidatedDurationSetting(key, desc, defaultValue, nil)
+}
+
+// RegisterNonNegativeDurationSetting defines a new setting with type
+// duration, which cannot be negative.
+func RegisterNonNegativeDurationSetting(
+	key, desc string, defaultValue time.Duration,
+) *DurationSetting {
+	return RegisterValidatedDurationSetting(key, desc, defaultValue, func(v time.Duration) error {
+		if v < 0 {
+			return errors.Errorf("cannot set to a negative duration: %s", v)
+		}
+		return nil
+	})
+}
+
+// RegisterValidatedDurationSetting defines a new setting with type duration
+// with a validation function.
+func RegisterValidatedDurationSetting(
+	key, desc string, defaultValue time.Duration, validateFn func(time.Duration) error,
+) *DurationSetting {
+	var validate func(value) error
+	if validateFn != nil {
+		validate = func(v value) error { return validateFn(v.d) }
+	}
+	register(key, desc, value{typ: DurationValue, d: defaultValue}, validate)
+	return &DurationSetting{common{key: key}}
+}
+
+// RegisterByteSizeSetting defines a new setting with type bytesize.
+func RegisterByteSizeSetting(key, desc string, defaultValue int64) *ByteSizeSetting {
+	return RegisterValidatedByteSizeSetting(key, desc, defaultValue, nil)
+}
+
+// RegisterValidatedByteSizeSetting defines a new setting with type bytesize
+// with a validation function. Negative sizes are always rejected.
+func RegisterValidatedByteSizeSetting(
+	key, desc string, defaultValue int64, validateFn func(int64) error,
+) *ByteSizeSetting {
+	validate := func(v value) error {
+		if v.i < 0 {
+			return errors.Errorf("cannot set to a negative size: %d", v.i)
+		}
+		if validateFn != nil {
+			return validateFn(v.i)
+		}
+		return nil
+	}
+	register(key, desc, value{typ: ByteSizeValue, i: defaultValue}, validate)
+	return &ByteSizeSetting{common{key: key}}
+}
+
+// RegisterEnumSetting defines a new setting with type enum, the values of
+// which are the keys of enumValues. The default value is given by name.
+func RegisterEnumSetting(
+	key, desc string, defaultValue string, enumValues map[int64]string,
+) *EnumSetting {
+	var def int64
+	found := false
+	for i, name := range enumValues {
+		if name == defaultValue {
+			def, found = i, true
+		}
+	}
+	if !found {
+		panic(errors.Errorf("enum setting %s registered with default value %s not in map",
+			key, defaultValue))
+	}
+	validate := func(v value) error {
+		if _, ok := enumValues[v.i]; !ok {
+			return errors.Errorf("%d is not one of the values of the enum", v.i)
+		}
+		return nil
+	}
+	e := register(key, desc, value{typ: EnumValue, i: def}, validate)
+	e.enumValues = enumValues
+	return &EnumSetting{common{key: key}}
+}
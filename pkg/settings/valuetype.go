@@ -15,7 +15,9 @@
 package settings
 
 import (
+	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -27,19 +29,45 @@ type ValueType byte
 // NB: We don't reuse sql/parser.Types here, to avoid deps which would then make
 // `settings` unusable in a whole subtree of packages.
 const (
-	StringValue ValueType = 's'
-	BoolValue             = 'b'
-	IntValue              = 'i'
-	FloatValue            = 'f'
+	StringValue   ValueType = 's'
+	BoolValue               = 'b'
+	IntValue                = 'i'
+	FloatValue              = 'f'
+	DurationValue           = 'd'
+	ByteSizeValue           = 'z'
+	EnumValue               = 'e'
 )
 
+// String returns the name of the type, as shown by SHOW ALL CLUSTER SETTINGS.
+// Settings are stored with their type encoded as a single character instead.
+func (t ValueType) String() string {
+	switch t {
+	case StringValue:
+		return "string"
+	case BoolValue:
+		return "bool"
+	case IntValue:
+		return "int"
+	case FloatValue:
+		return "float"
+	case DurationValue:
+		return "duration"
+	case ByteSizeValue:
+		return "bytesize"
+	case EnumValue:
+		return "enum"
+	default:
+		return fmt.Sprintf("unknown(%c)", byte(t))
+	}
+}
+
 func valueTypeFromStr(s string) (ValueType, error) {
 	if len(s) != 1 {
 		return 0, errors.Errorf("invalid value type str '%s'", s)
 	}
 
 	switch t := ValueType(s[0]); t {
-	case StringValue, BoolValue, IntValue, FloatValue:
+	case StringValue, BoolValue, IntValue, FloatValue, DurationValue, ByteSizeValue, EnumValue:
 		return t, nil
 	default:
 		return 0, errors.Errorf("invalid value type %c", t)
@@ -54,10 +82,12 @@ func parseRaw(raw string, typ ValueType) (value, error) {
 		ret.s = raw
 	case BoolValue:
 		ret.b, err = strconv.ParseBool(raw)
-	case IntValue:
-		ret.i, err = strconv.Atoi(raw)
+	case IntValue, ByteSizeValue, EnumValue:
+		ret.i, err = strconv.ParseInt(raw, 10, 64)
 	case FloatValue:
 		ret.f, err = strconv.ParseFloat(raw, 64)
+	case DurationValue:
+		ret.d, err = time.ParseDuration(raw)
 	default:
 		err = errors.Errorf("invalid value type %c", typ)
 	}
@@ -69,12 +99,18 @@ func EncodeBool(b bool) string {
 	return strconv.FormatBool(b)
 }
 
-// EncodeInt encodes an int in the format parseRaw expects.
-func EncodeInt(i int) string {
-	return strconv.Itoa(i)
+// EncodeInt encodes an int, a byte size or the value of an enum in the format
+// parseRaw expects.
+func EncodeInt(i int64) string {
+	return strconv.FormatInt(i, 10)
 }
 
-// EncodeFloat encodes a bool in the format parseRaw expects.
+// EncodeFloat encodes a float in the format parseRaw expects.
 func EncodeFloat(f float64) string {
 	return strconv.FormatFloat(f, 'E', -1, 64)
 }
+
+// EncodeDuration encodes a duration in the format parseRaw expects.
+func EncodeDuration(d time.Duration) string {
+	return d.String()
+}
@@ -19,15 +19,22 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"math"
 	"time"
 
+	"github.com/gogo/protobuf/proto"
+	"github.com/pkg/errors"
 	"golang.org/x/net/context"
 
+	"github.com/cockroachdb/cockroach/pkg/internal/client"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
 	"github.com/cockroachdb/cockroach/pkg/sql/parser"
-	"github.com/cockroachdb/cockroach/pkg/util/envutil"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
 	"github.com/cockroachdb/cockroach/pkg/util/stop"
 	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
 )
 
 // appStats holds per-application statistics.
@@ -46,14 +53,16 @@ type stmtStats struct {
 
 // StmtStatsEnable determines whether to collect per-statement
 // statistics.
-var StmtStatsEnable = envutil.EnvOrDefaultBool(
-	"COCKROACH_SQL_STMT_STATS_ENABLE", true,
+var StmtStatsEnable = settings.RegisterBoolSetting(
+	"sql.metrics.statement_details.enabled", "collect per-statement query statistics", true,
 )
 
 // SQLStatsCollectionLatencyThreshold specifies the minimum amount of time
 // consumed by a SQL statement before it is collected for statistics reporting.
-var SQLStatsCollectionLatencyThreshold = envutil.EnvOrDefaultFloat(
-	"COCKROACH_SQL_STATS_SVCLAT_THRESHOLD", 0,
+var SQLStatsCollectionLatencyThreshold = settings.RegisterNonNegativeDurationSetting(
+	"sql.metrics.statement_details.threshold",
+	"minimum execution time to cause statistics to be collected",
+	0,
 )
 
 func (a *appStats) recordStatement(
@@ -63,12 +72,13 @@ func (a *appStats) recordStatement(
 	numRows int,
 	err error,
 	parseLat, planLat, runLat, svcLat, ovhLat float64,
+	rowsRead, bytesRead int64,
 ) {
-	if a == nil || !StmtStatsEnable {
+	if a == nil || !StmtStatsEnable.Get() {
 		return
 	}
 
-	if svcLat < SQLStatsCollectionLatencyThreshold {
+	if svcLat < SQLStatsCollectionLatencyThreshold.Get().Seconds() {
 		return
 	}
 
@@ -111,9 +121,34 @@ func (a *appStats) recordStatement(
 	s.data.RunLat.record(s.data.Count, runLat)
 	s.data.ServiceLat.record(s.data.Count, svcLat)
 	s.data.OverheadLat.record(s.data.Count, ovhLat)
+	s.data.RowsRead.record(s.data.Count, float64(rowsRead))
+	s.data.BytesRead.record(s.data.Count, float64(bytesRead))
+	s.data.ServiceLatHistogram.record(svcLat)
 	s.Unlock()
 }
 
+// add merges the statistics of other into s.
+func (s *StatementStatistics) add(other *StatementStatistics) {
+	s.NumRows.add(s.Count, other.NumRows, other.Count)
+	s.ParseLat.add(s.Count, other.ParseLat, other.Count)
+	s.PlanLat.add(s.Count, other.PlanLat, other.Count)
+	s.RunLat.add(s.Count, other.RunLat, other.Count)
+	s.ServiceLat.add(s.Count, other.ServiceLat, other.Count)
+	s.OverheadLat.add(s.Count, other.OverheadLat, other.Count)
+	s.RowsRead.add(s.Count, other.RowsRead, other.Count)
+	s.BytesRead.add(s.Count, other.BytesRead, other.Count)
+	s.ServiceLatHistogram.add(other.ServiceLatHistogram)
+
+	s.Count += other.Count
+	s.FirstAttemptCount += other.FirstAttemptCount
+	if other.MaxRetries > s.MaxRetries {
+		s.MaxRetries = other.MaxRetries
+	}
+	if other.LastErr != "" {
+		s.LastErr = other.LastErr
+	}
+}
+
 // Retrieve the variance of the values.
 func (l *NumericStat) getVariance(count int64) float64 {
 	return l.SquaredDiffs / (float64(count) - 1)
@@ -125,6 +160,75 @@ func (l *NumericStat) record(count int64, val float64) {
 	l.SquaredDiffs += delta * (val - l.Mean)
 }
 
+// add merges other, which summarizes otherCount values, into l, which
+// summarizes count values.
+func (l *NumericStat) add(count int64, other NumericStat, otherCount int64) {
+	total := float64(count + otherCount)
+	if total == 0 {
+		return
+	}
+	delta := other.Mean - l.Mean
+	l.Mean += delta * float64(otherCount) / total
+	l.SquaredDiffs += other.SquaredDiffs + delta*delta*float64(count)*float64(otherCount)/total
+}
+
+// latencyHistogramBuckets is the number of buckets of a LatencyHistogram. The
+// last bucket counts the latencies above 2^(latencyHistogramBuckets-2)
+// microseconds, i.e. about 18 minutes.
+const latencyHistogramBuckets = 32
+
+// record counts a latency, in seconds.
+func (h *LatencyHistogram) record(lat float64) {
+	if h.Counts == nil {
+		h.Counts = make([]int64, latencyHistogramBuckets)
+	}
+	i := 0
+	if micros := lat * 1e6; micros > 1 {
+		i = int(math.Ceil(math.Log2(micros)))
+	}
+	if i >= len(h.Counts) {
+		i = len(h.Counts) - 1
+	}
+	h.Counts[i]++
+}
+
+// add merges the counts of other into h.
+func (h *LatencyHistogram) add(other LatencyHistogram) {
+	if len(other.Counts) > len(h.Counts) {
+		counts := make([]int64, len(other.Counts))
+		copy(counts, h.Counts)
+		h.Counts = counts
+	}
+	for i, c := range other.Counts {
+		h.Counts[i] += c
+	}
+}
+
+// percentile estimates the latency, in seconds, below which the fraction p of
+// the latencies fall. The estimate is the upper bound of the bucket holding
+// that latency, or 0 if no latencies were recorded.
+func (h *LatencyHistogram) percentile(p float64) float64 {
+	var total int64
+	for _, c := range h.Counts {
+		total += c
+	}
+	if total == 0 {
+		return 0
+	}
+	rank := int64(math.Ceil(p * float64(total)))
+	if rank < 1 {
+		rank = 1
+	}
+	var seen int64
+	for i, c := range h.Counts {
+		seen += c
+		if seen >= rank {
+			return math.Exp2(float64(i)) / 1e6
+		}
+	}
+	return math.Exp2(float64(len(h.Counts)-1)) / 1e6
+}
+
 // getStatsForStmt retrieves the per-stmt stat object.
 func (a *appStats) getStatsForStmt(stmtKey string) *stmtStats {
 	a.Lock()
@@ -147,6 +251,10 @@ type sqlStats struct {
 	// apps is the container for all the per-application statistics
 	// objects.
 	apps map[string]*appStats
+
+	// flushC is used to ask the reset worker to persist the statistics
+	// collected so far; the worker closes the channel it receives once done.
+	flushC chan chan struct{}
 }
 
 // resetApplicationName initializes both Session.ApplicationName and
@@ -171,8 +279,9 @@ func (s *sqlStats) getStatsForApplication(appName string) *appStats {
 }
 
 // resetStats clears all the stored per-app and per-statement
-// statistics.
-func (s *sqlStats) resetStats(ctx context.Context) {
+// statistics, and returns a copy of them keyed by application name and
+// statement key.
+func (s *sqlStats) resetStats() map[string]map[string]StatementStatistics {
 	// Note: we do not clear the entire s.apps map here. We would need
 	// to do so to prevent problems with a runaway client running `SET
 	// APPLICATION_NAME=...` with a different name every time.  However,
@@ -184,6 +293,7 @@ func (s *sqlStats) resetStats(ctx context.Context) {
 	// the risk of seeing the map grow unboundedly with the number of
 	// different application_names seen so far.
 
+	stats := make(map[string]map[string]StatementStatistics)
 	s.Lock()
 	// Clear the per-apps maps manually,
 	// because any SQL session currently open has cached the
@@ -193,11 +303,20 @@ func (s *sqlStats) resetStats(ctx context.Context) {
 	for appName, a := range s.apps {
 		a.Lock()
 
-		// Save the existing data to logs.
-		// TODO(knz/dt): instead of dumping the stats to the log, save
-		// them in a SQL table so they can be inspected by the DBA and/or
-		// the UI.
-		dumpStmtStats(ctx, appName, a.stmts)
+		if len(a.stmts) > 0 {
+			appStmts := make(map[string]StatementStatistics, len(a.stmts))
+			for key, stmt := range a.stmts {
+				stmt.Lock()
+				data := stmt.data
+				// A session that looked up the statement before the reset
+				// may still be recording into it.
+				data.ServiceLatHistogram.Counts = append(
+					[]int64(nil), data.ServiceLatHistogram.Counts...)
+				appStmts[key] = data
+				stmt.Unlock()
+			}
+			stats[appName] = appStmts
+		}
 
 		// Clear the map, to release the memory; make the new map somewhat
 		// already large for the likely future workload.
@@ -205,19 +324,18 @@ func (s *sqlStats) resetStats(ctx context.Context) {
 		a.Unlock()
 	}
 	s.Unlock()
+	return stats
 }
 
 // Save the existing data for an application to the info log.
-func dumpStmtStats(ctx context.Context, appName string, stats map[string]*stmtStats) {
+func dumpStmtStats(ctx context.Context, appName string, stats map[string]StatementStatistics) {
 	if len(stats) == 0 {
 		return
 	}
 	var buf bytes.Buffer
 	fmt.Fprintf(&buf, "Statistics for %q:\n", appName)
 	for key, s := range stats {
-		s.Lock()
-		json, err := json.Marshal(s.data)
-		s.Unlock()
+		json, err := json.Marshal(s)
 		if err != nil {
 			log.Errorf(ctx, "error while marshaling stats for %q // %q: %v", appName, key, err)
 			continue
@@ -229,25 +347,229 @@ func dumpStmtStats(ctx context.Context, appName string, stats map[string]*stmtSt
 
 // StmtStatsResetFrequency is the frequency at which per-app and
 // per-statement statistics are cleared from memory, to avoid
-// unlimited memory growth.
-var StmtStatsResetFrequency = envutil.EnvOrDefaultDuration(
-	"COCKROACH_SQL_STMT_STATS_RESET_INTERVAL", 1*time.Hour,
+// unlimited memory growth. Before they are cleared, they are
+// persisted in system.statement_statistics.
+var StmtStatsResetFrequency = settings.RegisterValidatedDurationSetting(
+	"sql.metrics.statement_details.reset_interval",
+	"interval at which the collected statement statistics are persisted and cleared",
+	1*time.Hour,
+	func(v time.Duration) error {
+		if v <= 0 {
+			return errors.Errorf("reset interval must be positive: %s", v)
+		}
+		return nil
+	},
+)
+
+// StmtStatsRetention is how long the persisted statement statistics
+// are kept in system.statement_statistics.
+var StmtStatsRetention = settings.RegisterNonNegativeDurationSetting(
+	"sql.metrics.statement_details.retention",
+	"how long the persisted statement statistics are kept (0 keeps them forever)",
+	7*24*time.Hour,
 )
 
+// changeWatchers is a set of channels that are signaled, without blocking,
+// when a setting changes. Settings callbacks can't be unregistered, so a
+// single callback is registered for the whole set, to which the workers of
+// the servers of the process add their channels for as long as they run.
+type changeWatchers struct {
+	syncutil.Mutex
+	chans map[chan<- struct{}]struct{}
+}
+
+func (w *changeWatchers) add(ch chan<- struct{}) {
+	w.Lock()
+	defer w.Unlock()
+	if w.chans == nil {
+		w.chans = make(map[chan<- struct{}]struct{})
+	}
+	w.chans[ch] = struct{}{}
+}
+
+func (w *changeWatchers) remove(ch chan<- struct{}) {
+	w.Lock()
+	defer w.Unlock()
+	delete(w.chans, ch)
+}
+
+func (w *changeWatchers) signal() {
+	w.Lock()
+	defer w.Unlock()
+	for ch := range w.chans {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// stmtStatsResetFrequencyWatchers are signaled when StmtStatsResetFrequency
+// changes.
+var stmtStatsResetFrequencyWatchers changeWatchers
+
+func init() {
+	StmtStatsResetFrequency.OnChange(stmtStatsResetFrequencyWatchers.signal)
+}
+
+// stmtStatsFlushTimeout bounds the time spent persisting the statement
+// statistics while the node drains.
+const stmtStatsFlushTimeout = 5 * time.Second
+
 // startResetWorker ensures that the data is removed from memory
-// periodically, so as to avoid memory blow-ups.
-func (s *sqlStats) startResetWorker(stopper *stop.Stopper) {
+// periodically, so as to avoid memory blow-ups. The data removed at
+// the end of each period, when the period's interval changes or when
+// flush is called is handed to persist, along with the start of the
+// period.
+func (s *sqlStats) startResetWorker(
+	stopper *stop.Stopper,
+	persist func(context.Context, time.Time, map[string]map[string]StatementStatistics) error,
+) {
 	ctx := log.WithLogTag(context.Background(), "sql-stats", nil)
+	flush := func(ctx context.Context, start time.Time) {
+		stats := s.resetStats()
+		if err := persist(ctx, start, stats); err != nil {
+			// Don't lose the statistics altogether.
+			log.Warningf(ctx, "could not persist statement statistics: %s", err)
+			for appName, appStmts := range stats {
+				dumpStmtStats(ctx, appName, appStmts)
+			}
+		}
+	}
+	// The timer is rearmed as soon as the interval changes, rather than at the
+	// end of the current period.
+	intervalChanged := make(chan struct{}, 1)
+	stmtStatsResetFrequencyWatchers.add(intervalChanged)
 	stopper.RunWorker(func() {
-		ticker := time.NewTicker(StmtStatsResetFrequency)
-		defer ticker.Stop()
+		defer stmtStatsResetFrequencyWatchers.remove(intervalChanged)
+		timer := timeutil.NewTimer()
+		defer timer.Stop()
 		for {
+			// The statistics are reset at multiples of the interval, so that
+			// the periods of all the nodes line up and their persisted
+			// statistics can be aggregated.
+			interval := StmtStatsResetFrequency.Get()
+			now := timeutil.Now()
+			end := now.Truncate(interval).Add(interval)
+			timer.Reset(end.Sub(now))
+			// The statistics of a period are merged with those of the other
+			// nodes, and with any persisted earlier in the period, so they can
+			// be persisted before the period ends.
 			select {
-			case <-ticker.C:
-				s.resetStats(ctx)
-			case <-stopper.ShouldStop():
+			case <-timer.C:
+				timer.Read = true
+				flush(ctx, end.Add(-interval))
+			case <-intervalChanged:
+				// The statistics collected so far belong to the period of the
+				// old interval. The current period then ends at the next
+				// multiple of the new interval.
+				flush(ctx, end.Add(-interval))
+			case done := <-s.flushC:
+				flush(ctx, end.Add(-interval))
+				close(done)
+			case <-stopper.ShouldQuiesce():
+				// The statistics collected since the node drained (see
+				// Executor.FlushStmtStats), or all those of the current period
+				// if it didn't, can't be persisted reliably once the stopper is
+				// quiescing. They are only dumped to the logs.
+				for appName, appStmts := range s.resetStats() {
+					dumpStmtStats(ctx, appName, appStmts)
+				}
 				return
 			}
 		}
 	})
 }
+
+// flush asks the reset worker to persist the statistics collected so
+// far, and waits until it has done so or ctx is done.
+func (s *sqlStats) flush(ctx context.Context, stopper *stop.Stopper) {
+	done := make(chan struct{})
+	select {
+	case s.flushC <- done:
+	case <-ctx.Done():
+		return
+	case <-stopper.ShouldQuiesce():
+		return
+	}
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
+
+// persistStmtStats merges the statement statistics of the given node for the
+// period starting at aggregatedTs into system.statement_statistics, and
+// removes the statistics of the node that are past the retention period.
+func persistStmtStats(
+	ctx context.Context,
+	db *client.DB,
+	ie InternalExecutor,
+	nodeID roachpb.NodeID,
+	aggregatedTs time.Time,
+	stats map[string]map[string]StatementStatistics,
+) error {
+	const selectStmt = `
+SELECT application_name, key, statistics FROM system.statement_statistics
+WHERE aggregated_ts = $1 AND node_id = $2
+`
+	const upsertStmt = `
+UPSERT INTO system.statement_statistics (aggregated_ts, node_id, application_name, key, statistics)
+VALUES ($1, $2, $3, $4, $5)
+`
+	const deleteStmt = `
+DELETE FROM system.statement_statistics WHERE node_id = $1 AND aggregated_ts < $2
+`
+	return db.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
+		// The node may already have persisted statistics for the period, for
+		// example if the reset interval was changed in the middle of it.
+		rows, err := ie.QueryRowsInTransaction(
+			ctx, "select-stmt-stats", txn, selectStmt, aggregatedTs, nodeID,
+		)
+		if err != nil {
+			return err
+		}
+		type stmtKey struct {
+			appName, key string
+		}
+		existing := make(map[stmtKey]StatementStatistics, len(rows))
+		for _, row := range rows {
+			var s StatementStatistics
+			if err := proto.Unmarshal([]byte(*row[2].(*parser.DBytes)), &s); err != nil {
+				return err
+			}
+			existing[stmtKey{
+				appName: string(parser.MustBeDString(row[0])),
+				key:     string(parser.MustBeDString(row[1])),
+			}] = s
+		}
+
+		for appName, appStmts := range stats {
+			for key, s := range appStmts {
+				if e, ok := existing[stmtKey{appName: appName, key: key}]; ok {
+					e.add(&s)
+					s = e
+				}
+				statsBytes, err := protoutil.Marshal(&s)
+				if err != nil {
+					return err
+				}
+				if _, err := ie.ExecuteStatementInTransaction(
+					ctx, "upsert-stmt-stats", txn, upsertStmt,
+					aggregatedTs, nodeID, appName, key, statsBytes,
+				); err != nil {
+					return err
+				}
+			}
+		}
+
+		if retention := StmtStatsRetention.Get(); retention != 0 {
+			if _, err := ie.ExecuteStatementInTransaction(
+				ctx, "delete-stmt-stats", txn, deleteStmt, nodeID, aggregatedTs.Add(-retention),
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,224 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/stop"
+)
+
+func TestNumericStatAdd(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	values := []float64{1, 5, 2, 8, 3, 13, 21, 1}
+	var all, first, second NumericStat
+	for i, v := range values {
+		all.record(int64(i+1), v)
+	}
+	const split = 3
+	for i, v := range values[:split] {
+		first.record(int64(i+1), v)
+	}
+	for i, v := range values[split:] {
+		second.record(int64(i+1), v)
+	}
+	first.add(split, second, int64(len(values)-split))
+
+	const epsilon = 1e-9
+	if math.Abs(first.Mean-all.Mean) > epsilon {
+		t.Errorf("expected mean %f, got %f", all.Mean, first.Mean)
+	}
+	if math.Abs(first.SquaredDiffs-all.SquaredDiffs) > epsilon {
+		t.Errorf("expected squared diffs %f, got %f", all.SquaredDiffs, first.SquaredDiffs)
+	}
+
+	// Adding to an empty stat yields the added stat.
+	var empty NumericStat
+	empty.add(0, all, int64(len(values)))
+	if empty != all {
+		t.Errorf("expected %+v, got %+v", all, empty)
+	}
+}
+
+func TestLatencyHistogram(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	var h LatencyHistogram
+	if p := h.percentile(0.5); p != 0 {
+		t.Errorf("expected 0 for an empty histogram, got %f", p)
+	}
+
+	// 90 latencies of 3µs, 9 of 1ms and 1 of 1h.
+	for i := 0; i < 90; i++ {
+		h.record(3e-6)
+	}
+	for i := 0; i < 9; i++ {
+		h.record(1e-3)
+	}
+	h.record(time.Hour.Seconds())
+
+	testCases := []struct {
+		p        float64
+		expected float64
+	}{
+		{0, 4e-6},
+		{0.5, 4e-6},
+		{0.9, 4e-6},
+		{0.91, 1024e-6},
+		{0.99, 1024e-6},
+		{1, math.Exp2(latencyHistogramBuckets-1) / 1e6},
+	}
+	for _, tc := range testCases {
+		if p := h.percentile(tc.p); p != tc.expected {
+			t.Errorf("%.2f: expected %g, got %g", tc.p, tc.expected, p)
+		}
+	}
+
+	// Merging histograms adds up their counts.
+	var merged LatencyHistogram
+	merged.add(h)
+	merged.add(h)
+	for i, c := range merged.Counts {
+		if c != 2*h.Counts[i] {
+			t.Errorf("bucket %d: expected %d, got %d", i, 2*h.Counts[i], c)
+		}
+	}
+}
+
+func TestPersistStmtStats(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	s, sqlDB, kvDB := serverutils.StartServer(t, base.TestServerArgs{})
+	defer s.Stopper().Stop()
+	ctx := context.TODO()
+
+	ie := InternalExecutor{LeaseManager: s.LeaseManager().(*LeaseManager)}
+	aggregatedTs := time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+	stats := func(count int64, svcLat float64) map[string]map[string]StatementStatistics {
+		var s StatementStatistics
+		for i := int64(0); i < count; i++ {
+			s.Count++
+			s.ServiceLat.record(s.Count, svcLat)
+			s.ServiceLatHistogram.record(svcLat)
+		}
+		return map[string]map[string]StatementStatistics{"app": {"SELECT _": s}}
+	}
+
+	// The statistics persisted by a node for the same period are merged, and
+	// the statistics of all the nodes are aggregated.
+	for _, tc := range []struct {
+		nodeID roachpb.NodeID
+		count  int64
+		svcLat float64
+	}{
+		{1, 2, 1},
+		{1, 1, 1},
+		{2, 1, 5},
+	} {
+		if err := persistStmtStats(
+			ctx, kvDB, ie, tc.nodeID, aggregatedTs, stats(tc.count, tc.svcLat),
+		); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var nodeRows int
+	if err := sqlDB.QueryRow(
+		`SELECT count(*) FROM system.statement_statistics`,
+	).Scan(&nodeRows); err != nil {
+		t.Fatal(err)
+	}
+	if nodeRows != 2 {
+		t.Errorf("expected 2 rows in system.statement_statistics, got %d", nodeRows)
+	}
+
+	var ts time.Time
+	var key string
+	var nodeCount, count int
+	var svcLatAvg float64
+	if err := sqlDB.QueryRow(`
+SELECT aggregated_ts, key, node_count, count, service_lat_avg
+  FROM crdb_internal.cluster_statement_statistics WHERE application_name = 'app'`,
+	).Scan(&ts, &key, &nodeCount, &count, &svcLatAvg); err != nil {
+		t.Fatal(err)
+	}
+	if !ts.Equal(aggregatedTs) || key != "SELECT _" || nodeCount != 2 || count != 4 || svcLatAvg != 2 {
+		t.Errorf("unexpected cluster statistics: %s %q %d %d %f", ts, key, nodeCount, count, svcLatAvg)
+	}
+
+	// The statistics past the retention period are removed when a node
+	// persists its statistics.
+	defer StmtStatsRetention.Override(time.Hour)()
+	if err := persistStmtStats(
+		ctx, kvDB, ie, 1, aggregatedTs.Add(2*time.Hour), stats(1, 1),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if err := sqlDB.QueryRow(
+		`SELECT count(*) FROM system.statement_statistics WHERE node_id = 1`,
+	).Scan(&nodeRows); err != nil {
+		t.Fatal(err)
+	}
+	if nodeRows != 1 {
+		t.Errorf("expected 1 row for node 1 in system.statement_statistics, got %d", nodeRows)
+	}
+}
+
+func TestStmtStatsResetWorkerFlush(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	defer StmtStatsResetFrequency.Override(time.Hour)()
+	s := sqlStats{apps: make(map[string]*appStats), flushC: make(chan chan struct{})}
+	stopper := stop.NewStopper()
+	defer stopper.Stop()
+	persisted := make(chan time.Time, 1)
+	s.startResetWorker(stopper, func(
+		_ context.Context, start time.Time, _ map[string]map[string]StatementStatistics,
+	) error {
+		persisted <- start
+		return nil
+	})
+
+	// flush only returns once the statistics are persisted.
+	s.flush(context.TODO(), stopper)
+	select {
+	case start := <-persisted:
+		if !start.Equal(start.Truncate(time.Hour)) {
+			t.Errorf("expected the start of an hourly period, got %s", start)
+		}
+	default:
+		t.Fatal("expected the statistics to be persisted by flush")
+	}
+
+	// Changing the interval persists the statistics collected so far.
+	// Overrides don't run the OnChange callbacks, so the watchers are
+	// signaled directly.
+	defer StmtStatsResetFrequency.Override(2 * time.Hour)()
+	stmtStatsResetFrequencyWatchers.signal()
+	select {
+	case <-persisted:
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected the statistics to be persisted when the interval changes")
+	}
+}
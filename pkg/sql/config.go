@@ -28,14 +28,32 @@ func init() {
 	config.ZoneConfigHook = GetZoneConfig
 }
 
-// GetZoneConfig returns the zone config for the object with 'id'.
+// GetZoneConfig returns the zone config for the object with 'id'. If the
+// object is a table, the returned config includes the subzones of its indexes.
 func GetZoneConfig(cfg config.SystemConfig, id uint32) (config.ZoneConfig, bool, error) {
 	// Look in the zones table.
 	if zoneVal := cfg.GetValue(sqlbase.MakeZoneKey(sqlbase.ID(id))); zoneVal != nil {
-		// We're done.
 		zone, err := config.MigrateZoneConfig(zoneVal)
-		return zone, true, err
+		if err != nil || !zone.IsSubzonePlaceholder() {
+			// We're done.
+			return zone, true, err
+		}
+		// The table only has zone configs for some of its indexes; the rest of
+		// it inherits the zone config of its database.
+		parent, found, err := getZoneConfigForParent(cfg, id)
+		if err != nil || !found {
+			return config.ZoneConfig{}, found, err
+		}
+		parent.Subzones = zone.Subzones
+		parent.SubzoneSpans = zone.SubzoneSpans
+		return parent, true, nil
 	}
+	return getZoneConfigForParent(cfg, id)
+}
+
+// getZoneConfigForParent returns the zone config that the object with 'id'
+// inherits when it does not have one of its own.
+func getZoneConfigForParent(cfg config.SystemConfig, id uint32) (config.ZoneConfig, bool, error) {
 
 	// No zone config for this ID. We need to figure out if it's a database
 	// or table. Lookup its descriptor.
@@ -19,10 +19,12 @@ package sql
 import (
 	"bytes"
 	"fmt"
-	"io"
+	"strings"
 	"time"
+	"unicode/utf8"
 	"unsafe"
 
+	"github.com/pkg/errors"
 	"golang.org/x/net/context"
 
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
@@ -42,15 +44,21 @@ import (
 // to increase performance by batching inserts), they are inserted with an
 // insertNode. A CopyDone message will flush and insert all remaining data.
 //
+// The data is either in the Postgres text format or in CSV, as described by
+// the options of the COPY (see CopyOptions).
+//
 // See: https://www.postgresql.org/docs/9.5/static/sql-copy.html
 type copyNode struct {
 	p             *planner
 	table         parser.TableExpr
 	columns       parser.UnresolvedNames
+	opts          CopyOptions
 	resultColumns ResultColumns
 	buf           bytes.Buffer
 	rows          []*parser.Tuple
 	rowsMemAcc    WrappableMemoryAccount
+	// skippedHeader is set once the header line, if any, has been skipped.
+	skippedHeader bool
 }
 
 func (n *copyNode) Columns() ResultColumns                            { return n.resultColumns }
@@ -83,6 +91,10 @@ func (p *planner) CopyFrom(
 		columns: n.Columns,
 	}
 
+	var err error
+	if cn.opts, err = MakeCopyOptions(n.Options); err != nil {
+		return nil, err
+	}
 	tn, err := n.Table.NormalizeWithDatabaseName(p.session.Database)
 	if err != nil {
 		return nil, err
@@ -115,6 +127,129 @@ func (n *copyNode) Start(context.Context) error {
 	return nil
 }
 
+// CopyTo begins a COPY TO, which sends the results of a query, or the
+// contents of a table, to the client. The rows are formatted according to the
+// options of the COPY by the pgwire layer (see Result.CopyOptions).
+// Privileges: SELECT on table.
+func (p *planner) CopyTo(ctx context.Context, n *parser.CopyTo) (planNode, error) {
+	if _, err := MakeCopyOptions(n.Options); err != nil {
+		return nil, err
+	}
+	query := n.Query
+	if query == nil {
+		exprs := parser.SelectExprs{{Expr: parser.StarExpr()}}
+		if len(n.Columns) > 0 {
+			exprs = make(parser.SelectExprs, len(n.Columns))
+			for i, c := range n.Columns {
+				exprs[i] = parser.SelectExpr{Expr: c}
+			}
+		}
+		query = &parser.Select{
+			Select: &parser.SelectClause{
+				Exprs: exprs,
+				From:  &parser.From{Tables: parser.TableExprs{&n.Table}},
+			},
+		}
+	}
+	return p.newPlan(ctx, query, nil, false /* autoCommit */)
+}
+
+// CopyFormat is the format of the data of a COPY.
+type CopyFormat int
+
+const (
+	// CopyFormatText is the Postgres text format: fields are separated by the
+	// delimiter and special characters are escaped with backslashes.
+	CopyFormatText CopyFormat = iota
+	// CopyFormatCSV is CSV: fields containing special characters are quoted.
+	CopyFormatCSV
+)
+
+// CopyOptions describe the format of the data of a COPY.
+type CopyOptions struct {
+	Format CopyFormat
+	// Delimiter separates the fields of a row.
+	Delimiter byte
+	// Null is the representation of NULL.
+	Null string
+	// Header is set if the first line of the data holds the names of the
+	// columns. It is only valid in CSV.
+	Header bool
+}
+
+const (
+	copyOptionFormat    = "format"
+	copyOptionDelimiter = "delimiter"
+	copyOptionNull      = "null"
+	copyOptionHeader    = "header"
+)
+
+// MakeCopyOptions returns the CopyOptions described by the options of a COPY
+// statement.
+func MakeCopyOptions(opts parser.KVOptions) (CopyOptions, error) {
+	res := CopyOptions{Format: CopyFormatText}
+	var delimiter, null *string
+	for _, o := range opts {
+		value := o.Value
+		switch o.Key {
+		case copyOptionFormat:
+			switch strings.ToLower(value) {
+			case "text":
+				res.Format = CopyFormatText
+			case "csv":
+				res.Format = CopyFormatCSV
+			default:
+				return res, errors.Errorf("COPY format %q not supported", value)
+			}
+		case copyOptionDelimiter:
+			delimiter = &value
+		case copyOptionNull:
+			null = &value
+		case copyOptionHeader:
+			switch strings.ToLower(value) {
+			case "true", "on", "1":
+				res.Header = true
+			case "false", "off", "0":
+				res.Header = false
+			default:
+				return res, errors.Errorf("%s requires a Boolean value", o.Key)
+			}
+		default:
+			return res, errors.Errorf("COPY option %q not supported", o.Key)
+		}
+	}
+
+	switch res.Format {
+	case CopyFormatText:
+		res.Delimiter, res.Null = '\t', nullString
+		if res.Header {
+			return res, errors.New("COPY HEADER available only in CSV mode")
+		}
+	case CopyFormatCSV:
+		res.Delimiter, res.Null = ',', ""
+	}
+	if delimiter != nil {
+		if len(*delimiter) != 1 || !utf8.ValidString(*delimiter) {
+			return res, errors.New("COPY delimiter must be a single one-byte character")
+		}
+		res.Delimiter = (*delimiter)[0]
+	}
+	if null != nil {
+		res.Null = *null
+	}
+	switch {
+	case res.Delimiter == lineDelim || res.Delimiter == '\r':
+		return res, errors.New("COPY delimiter cannot be newline or carriage return")
+	case res.Delimiter == '\\' && res.Format == CopyFormatText:
+		return res, errors.New("COPY delimiter cannot be backslash")
+	case res.Delimiter == csvQuote && res.Format == CopyFormatCSV:
+		return res, errors.New("COPY delimiter cannot be the quote character")
+	case strings.IndexByte(res.Null, res.Delimiter) >= 0:
+		return res, errors.New("COPY delimiter must not appear in the NULL specification")
+	}
+	return res, nil
+}
+
 // CopyDataBlock represents a data block of a COPY FROM statement.
 type CopyDataBlock struct {
 	Done bool
@@ -129,10 +264,8 @@ const (
 
 	nullString = `\N`
 	lineDelim  = '\n'
-)
-
-var (
-	fieldDelim = []byte{'\t'}
+	csvQuote   = '"'
+	endOfData  = `\.`
 )
 
 // ProcessCopyData appends data to the planner's internal COPY state as
@@ -144,70 +277,160 @@ func (s *Session) ProcessCopyData(
 	ctx context.Context, data string, msg copyMsg,
 ) (parser.StatementList, error) {
 	cf := s.copyFrom
-	buf := cf.buf
+	buf := &cf.buf
 
 	switch msg {
 	case copyMsgData:
-		// ignore
+		buf.WriteString(data)
 	case copyMsgDone:
 		var err error
 		// If there's a line in the buffer without \n at EOL, add it here.
 		if buf.Len() > 0 {
-			err = cf.addRow(ctx, buf.Bytes())
+			line := buf.Bytes()
+			buf.Reset()
+			if !bytes.Equal(line, []byte(endOfData)) {
+				err = cf.addRow(ctx, line)
+			}
 		}
 		return parser.StatementList{CopyDataBlock{Done: true}}, err
 	default:
 		return nil, fmt.Errorf("expected copy command")
 	}
 
-	buf.WriteString(data)
-	for buf.Len() > 0 {
-		line, err := buf.ReadBytes(lineDelim)
-		if err != nil {
-			if err != io.EOF {
-				return nil, err
-			}
-		} else {
+	for {
+		line, ok := cf.nextLine()
+		if !ok {
+			break
+		}
+		if buf.Len() == 0 && bytes.Equal(line, []byte(endOfData)) {
+			break
+		}
+		if err := cf.addRow(ctx, line); err != nil {
+			return nil, err
+		}
+	}
+	return parser.StatementList{CopyDataBlock{}}, nil
+}
+
+// nextLine removes the next complete line from the buffer and returns it,
+// without its line ending. It returns false if the buffer does not hold a
+// complete line. In CSV, line breaks inside of quoted fields do not end the
+// line.
+//
+// The returned line is only valid until the buffer is next written to.
+func (n *copyNode) nextLine() ([]byte, bool) {
+	b := n.buf.Bytes()
+	inQuote := false
+	for i, c := range b {
+		switch {
+		case c == csvQuote && n.opts.Format == CopyFormatCSV:
+			inQuote = !inQuote
+		case c == lineDelim && !inQuote:
+			line := n.buf.Next(i + 1)
 			// Remove lineDelim from end.
 			line = line[:len(line)-1]
 			// Remove a single '\r' at EOL, if present.
 			if len(line) > 0 && line[len(line)-1] == '\r' {
 				line = line[:len(line)-1]
 			}
+			return line, true
 		}
-		if buf.Len() == 0 && bytes.Equal(line, []byte(`\.`)) {
-			break
-		}
-		if err := cf.addRow(ctx, line); err != nil {
-			return nil, err
+	}
+	return nil, false
+}
+
+// copyField is a field of a line of COPY data.
+type copyField struct {
+	val  string
+	null bool
+}
+
+// splitLine splits a line of COPY data into its fields.
+func (n *copyNode) splitLine(line []byte) ([]copyField, error) {
+	if n.opts.Format == CopyFormatCSV {
+		return splitCSVLine(line, n.opts.Delimiter, n.opts.Null)
+	}
+	parts := bytes.Split(line, []byte{n.opts.Delimiter})
+	fields := make([]copyField, len(parts))
+	for i, part := range parts {
+		fields[i].val = string(part)
+		fields[i].null = fields[i].val == n.opts.Null
+	}
+	return fields, nil
+}
+
+// splitCSVLine splits a line of CSV data into its fields. Quoted fields may
+// contain the delimiter, line breaks and doubled quotes, and are never NULL.
+func splitCSVLine(line []byte, delim byte, null string) ([]copyField, error) {
+	var fields []copyField
+	var field bytes.Buffer
+	quoted, inQuote := false, false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case inQuote && c == csvQuote:
+			if i+1 < len(line) && line[i+1] == csvQuote {
+				field.WriteByte(csvQuote)
+				i++
+			} else {
+				inQuote = false
+			}
+		case inQuote:
+			field.WriteByte(c)
+		case c == csvQuote:
+			quoted, inQuote = true, true
+		case c == delim:
+			fields = append(fields, copyField{val: field.String(), null: !quoted && field.String() == null})
+			field.Reset()
+			quoted = false
+		default:
+			field.WriteByte(c)
 		}
 	}
-	return parser.StatementList{CopyDataBlock{}}, nil
+	if inQuote {
+		return nil, errors.New("unterminated CSV quoted field")
+	}
+	fields = append(fields, copyField{val: field.String(), null: !quoted && field.String() == null})
+	return fields, nil
+}
+
+// decode unescapes a field of COPY data. Only the text format uses escapes.
+func (n *copyNode) decode(s string) (string, error) {
+	if n.opts.Format == CopyFormatCSV {
+		return s, nil
+	}
+	return DecodeCopy(s)
 }
 
 func (n *copyNode) addRow(ctx context.Context, line []byte) error {
-	var err error
-	parts := bytes.Split(line, fieldDelim)
+	if n.opts.Header && !n.skippedHeader {
+		n.skippedHeader = true
+		return nil
+	}
+	parts, err := n.splitLine(line)
+	if err != nil {
+		return err
+	}
 	if len(parts) != len(n.resultColumns) {
 		return fmt.Errorf("expected %d values, got %d", len(n.resultColumns), len(parts))
 	}
 	exprs := make(parser.Exprs, len(parts))
 	acc := n.rowsMemAcc.Wsession(n.p.session)
 	for i, part := range parts {
-		s := string(part)
-		if s == nullString {
+		if part.null {
 			exprs[i] = parser.DNull
 			continue
 		}
+		s := part.val
 		var d parser.Datum
 		switch t := n.resultColumns[i].Typ; t {
 		case parser.TypeBool:
 			d, err = parser.ParseDBool(s)
 		case parser.TypeBytes:
-			s, err = decodeCopy(s)
+			s, err = n.decode(s)
 			d = parser.NewDBytes(parser.DBytes(s))
 		case parser.TypeDate:
-			s, err = decodeCopy(s)
+			s, err = n.decode(s)
 			if err != nil {
 				break
 			}
@@ -219,22 +442,22 @@ func (n *copyNode) addRow(ctx context.Context, line []byte) error {
 		case parser.TypeInt:
 			d, err = parser.ParseDInt(s)
 		case parser.TypeInterval:
-			s, err = decodeCopy(s)
+			s, err = n.decode(s)
 			if err != nil {
 				break
 			}
 			d, err = parser.ParseDInterval(s)
 		case parser.TypeString:
-			s, err = decodeCopy(s)
+			s, err = n.decode(s)
 			d = parser.NewDString(s)
 		case parser.TypeTimestamp:
-			s, err = decodeCopy(s)
+			s, err = n.decode(s)
 			if err != nil {
 				break
 			}
 			d, err = parser.ParseDTimestamp(s, time.Microsecond)
 		case parser.TypeTimestampTZ:
-			s, err = decodeCopy(s)
+			s, err = n.decode(s)
 			if err != nil {
 				break
 			}
@@ -262,10 +485,10 @@ func (n *copyNode) addRow(ctx context.Context, line []byte) error {
 	return nil
 }
 
-// decodeCopy unescapes a single COPY field.
+// DecodeCopy unescapes a single COPY field.
 //
 // See: https://www.postgresql.org/docs/9.5/static/sql-copy.html#AEN74432
-func decodeCopy(in string) (string, error) {
+func DecodeCopy(in string) (string, error) {
 	var buf bytes.Buffer
 	start := 0
 	for i, n := 0, len(in); i < n; i++ {
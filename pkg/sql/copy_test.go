@@ -17,6 +17,7 @@
 package sql
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
@@ -76,7 +77,7 @@ func TestDecodeCopy(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		out, err := decodeCopy(test.in)
+		out, err := DecodeCopy(test.in)
 		if gotErr := err != nil; gotErr != test.err {
 			if gotErr {
 				t.Errorf("%q: unexpected error: %v", test.in, err)
@@ -90,3 +91,50 @@ func TestDecodeCopy(t *testing.T) {
 		}
 	}
 }
+
+func TestSplitCSVLine(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	tests := []struct {
+		in     string
+		null   string
+		expect []copyField
+		err    bool
+	}{
+		{
+			in:     `a,,""`,
+			expect: []copyField{{val: "a"}, {null: true}, {}},
+		},
+		{
+			in:     `"a,b","say ""hi""",` + "\"x\ny\"",
+			expect: []copyField{{val: "a,b"}, {val: `say "hi"`}, {val: "x\ny"}},
+		},
+		{
+			in:     `\N,"\N",`,
+			null:   `\N`,
+			expect: []copyField{{val: `\N`, null: true}, {val: `\N`}, {}},
+		},
+
+		// Error cases.
+
+		{
+			in:  `a,"b`,
+			err: true,
+		},
+	}
+
+	for _, test := range tests {
+		out, err := splitCSVLine([]byte(test.in), ',', test.null)
+		if gotErr := err != nil; gotErr != test.err {
+			if gotErr {
+				t.Errorf("%q: unexpected error: %v", test.in, err)
+				continue
+			}
+			t.Errorf("%q: expected error", test.in)
+			continue
+		}
+		if !reflect.DeepEqual(out, test.expect) {
+			t.Errorf("%q: got %+v, expected %+v", test.in, out, test.expect)
+		}
+	}
+}
@@ -21,10 +21,14 @@ import (
 	"sort"
 	"time"
 
+	"github.com/gogo/protobuf/proto"
 	"github.com/pkg/errors"
 	"golang.org/x/net/context"
+	yaml "gopkg.in/yaml.v2"
 
 	"github.com/cockroachdb/cockroach/pkg/build"
+	"github.com/cockroachdb/cockroach/pkg/config"
+	"github.com/cockroachdb/cockroach/pkg/keys"
 	"github.com/cockroachdb/cockroach/pkg/security"
 	"github.com/cockroachdb/cockroach/pkg/sql/parser"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
@@ -38,7 +42,11 @@ var crdbInternal = virtualSchema{
 		crdbInternalLeasesTable,
 		crdbInternalSchemaChangesTable,
 		crdbInternalStmtStatsTable,
+		crdbInternalClusterStmtStatsTable,
 		crdbInternalJobsTable,
+		crdbInternalPartitionsTable,
+		crdbInternalReplicationReportsTable,
+		crdbInternalZonesTable,
 	},
 }
 
@@ -152,6 +160,255 @@ CREATE TABLE crdb_internal.tables (
 	},
 }
 
+var crdbInternalPartitionsTable = virtualSchemaTable{
+	schema: `
+CREATE TABLE crdb_internal.partitions (
+  table_id    INT NOT NULL,
+  index_id    INT NOT NULL,
+  index_name  STRING NOT NULL,
+  name        STRING NOT NULL,
+  columns     INT NOT NULL,
+  type        STRING NOT NULL
+);
+`,
+	populate: func(ctx context.Context, p *planner, addRow func(...parser.Datum) error) error {
+		descs, err := getAllDescriptors(ctx, p.txn)
+		if err != nil {
+			return err
+		}
+		for _, desc := range descs {
+			table, ok := desc.(*sqlbase.TableDescriptor)
+			if !ok || table.Dropped() || !userCanSeeDescriptor(table, p.session.User) {
+				continue
+			}
+			for _, index := range table.AllNonDropIndexes() {
+				addPartition := func(name, typ string) error {
+					return addRow(
+						parser.NewDInt(parser.DInt(int64(table.ID))),
+						parser.NewDInt(parser.DInt(int64(index.ID))),
+						parser.NewDString(index.Name),
+						parser.NewDString(name),
+						parser.NewDInt(parser.DInt(int64(index.Partitioning.NumColumns))),
+						parser.NewDString(typ),
+					)
+				}
+				for _, l := range index.Partitioning.List {
+					if err := addPartition(l.Name, "LIST"); err != nil {
+						return err
+					}
+				}
+				for _, r := range index.Partitioning.Range {
+					if err := addPartition(r.Name, "RANGE"); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		return nil
+	},
+}
+
+// specialZoneNames holds the display names of the zones of the parts of the
+// system ranges that have zone configs of their own.
+var specialZoneNames = map[sqlbase.ID]string{
+	keys.RootNamespaceID:    defaultZoneName,
+	keys.MetaRangesID:       ".meta",
+	keys.SystemRangesID:     ".system",
+	keys.TimeseriesRangesID: ".timeseries",
+}
+
+var crdbInternalReplicationReportsTable = virtualSchemaTable{
+	schema: `
+CREATE TABLE crdb_internal.replication_reports (
+  zone_id                 INT NOT NULL,
+  index_id                INT NOT NULL,
+  partition_name          STRING NOT NULL,
+  zone_name               STRING,
+  locality                STRING NOT NULL,
+  total_ranges            INT NOT NULL,
+  unavailable_ranges      INT NOT NULL,
+  under_replicated_ranges INT NOT NULL,
+  violating_ranges        INT NOT NULL,
+  single_locality_ranges  INT NOT NULL,
+  at_risk_ranges          INT NOT NULL,
+  generated               TIMESTAMP NOT NULL
+);
+`,
+	populate: func(ctx context.Context, p *planner, addRow func(...parser.Datum) error) error {
+		rows, err := p.queryRows(ctx, `
+SELECT zone_id, index_id, partition_name, locality, total_ranges,
+       unavailable_ranges, under_replicated_ranges, violating_ranges,
+       single_locality_ranges, at_risk_ranges, generated
+  FROM system.replication_reports`)
+		if err != nil {
+			return err
+		}
+		descs, err := getAllDescriptors(ctx, p.txn)
+		if err != nil {
+			return err
+		}
+		descsByID := make(map[sqlbase.ID]sqlbase.DescriptorProto, len(descs))
+		for _, desc := range descs {
+			descsByID[desc.GetID()] = desc
+		}
+
+		for _, r := range rows {
+			zoneID := sqlbase.ID(parser.MustBeDInt(r[0]))
+			indexID := uint32(parser.MustBeDInt(r[1]))
+			partitionName := string(parser.MustBeDString(r[2]))
+			zoneName, err := p.replicationReportZoneName(ctx, descsByID, zoneID, indexID, partitionName)
+			if err != nil {
+				return err
+			}
+			if err := addRow(
+				r[0], r[1], r[2], zoneName, r[3], r[4], r[5], r[6], r[7], r[8], r[9], r[10],
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+// replicationReportZoneName returns the display name of the zone, or of the
+// subzone of the given index and partition if indexID is not 0, of a row of
+// system.replication_reports or crdb_internal.zones. The names are the ones
+// used by SHOW ZONE CONFIGURATION. It returns NULL if the zone or subzone no longer exists.
+func (p *planner) replicationReportZoneName(
+	ctx context.Context,
+	descsByID map[sqlbase.ID]sqlbase.DescriptorProto,
+	zoneID sqlbase.ID,
+	indexID uint32,
+	partitionName string,
+) (parser.Datum, error) {
+	if name, ok := specialZoneNames[zoneID]; ok {
+		return parser.NewDString(name), nil
+	}
+	switch desc := descsByID[zoneID].(type) {
+	case *sqlbase.DatabaseDescriptor:
+		return parser.NewDString(parser.Name(desc.Name).String()), nil
+	case *sqlbase.TableDescriptor:
+		db, ok := descsByID[desc.ParentID].(*sqlbase.DatabaseDescriptor)
+		if !ok {
+			return parser.DNull, nil
+		}
+		name := parser.Name(db.Name).String() + "." + parser.Name(desc.Name).String()
+		if indexID == 0 {
+			return parser.NewDString(name), nil
+		}
+		zone, found, err := p.getZoneConfigRaw(ctx, desc.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !found || zone.GetSubzone(indexID, partitionName) == nil {
+			return parser.DNull, nil
+		}
+		index, err := desc.FindIndexByID(sqlbase.IndexID(indexID))
+		if err != nil {
+			return parser.DNull, nil
+		}
+		// The partitions of the primary index are shown as partitions of the
+		// table.
+		if partitionName == "" || index.ID != desc.PrimaryIndex.ID {
+			name += "@" + parser.Name(index.Name).String()
+		}
+		if partitionName != "" {
+			name += "." + parser.Name(partitionName).String()
+		}
+		return parser.NewDString(name), nil
+	}
+	return parser.DNull, nil
+}
+
+var crdbInternalZonesTable = virtualSchemaTable{
+	schema: `
+CREATE TABLE crdb_internal.zones (
+  zone_id        INT NOT NULL,
+  index_id       INT NOT NULL,
+  partition_name STRING NOT NULL,
+  zone_name      STRING,
+  index_name     STRING,
+  config_yaml    STRING NOT NULL
+);
+`,
+	populate: func(ctx context.Context, p *planner, addRow func(...parser.Datum) error) error {
+		descs, err := getAllDescriptors(ctx, p.txn)
+		if err != nil {
+			return err
+		}
+		descsByID := make(map[sqlbase.ID]sqlbase.DescriptorProto, len(descs))
+		for _, desc := range descs {
+			descsByID[desc.GetID()] = desc
+		}
+
+		ids := make([]int, 0, len(specialZoneNames)+len(descs))
+		for id := range specialZoneNames {
+			ids = append(ids, int(id))
+		}
+		for _, desc := range descs {
+			if userCanSeeDescriptor(desc, p.session.User) {
+				ids = append(ids, int(desc.GetID()))
+			}
+		}
+		sort.Ints(ids)
+
+		for _, id := range ids {
+			zoneID := sqlbase.ID(id)
+			zone, found, err := p.getZoneConfigRaw(ctx, zoneID)
+			if err != nil {
+				return err
+			}
+			if !found {
+				continue
+			}
+			addZoneRow := func(
+				indexID uint32, partitionName string, indexName parser.Datum, zone config.ZoneConfig,
+			) error {
+				zoneName, err := p.replicationReportZoneName(ctx, descsByID, zoneID, indexID, partitionName)
+				if err != nil {
+					return err
+				}
+				configYAML, err := yaml.Marshal(zone)
+				if err != nil {
+					return err
+				}
+				return addRow(
+					parser.NewDInt(parser.DInt(zoneID)),
+					parser.NewDInt(parser.DInt(indexID)),
+					parser.NewDString(partitionName),
+					zoneName,
+					indexName,
+					parser.NewDString(string(configYAML)),
+				)
+			}
+			if !zone.IsSubzonePlaceholder() {
+				tableZone := zone
+				tableZone.Subzones = nil
+				tableZone.SubzoneSpans = nil
+				if err := addZoneRow(0, "", parser.DNull, tableZone); err != nil {
+					return err
+				}
+			}
+			table, ok := descsByID[zoneID].(*sqlbase.TableDescriptor)
+			if !ok {
+				continue
+			}
+			for _, subzone := range zone.Subzones {
+				indexName := parser.DNull
+				if index, err := table.FindIndexByID(sqlbase.IndexID(subzone.IndexID)); err == nil {
+					indexName = parser.NewDString(index.Name)
+				}
+				if err := addZoneRow(
+					subzone.IndexID, subzone.PartitionName, indexName, subzone.Config,
+				); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	},
+}
+
 var crdbInternalSchemaChangesTable = virtualSchemaTable{
 	schema: `
 CREATE TABLE crdb_internal.schema_changes (
@@ -332,12 +589,9 @@ CREATE TABLE crdb_internal.jobs (
 	},
 }
 
-var crdbInternalStmtStatsTable = virtualSchemaTable{
-	schema: `
-CREATE TABLE crdb_internal.node_statement_statistics (
-  node_id             INT NOT NULL,
-  application_name    STRING NOT NULL,
-  key                 STRING NOT NULL,
+// stmtStatsColumns are the columns of the statement statistics tables that
+// hold the statistics proper. See stmtStatsDatums.
+const stmtStatsColumns = `
   count               INT NOT NULL,
   first_attempt_count INT NOT NULL,
   max_retries         INT NOT NULL,
@@ -353,7 +607,62 @@ CREATE TABLE crdb_internal.node_statement_statistics (
   service_lat_avg     FLOAT NOT NULL,
   service_lat_var     FLOAT NOT NULL,
   overhead_lat_avg    FLOAT NOT NULL,
-  overhead_lat_var    FLOAT NOT NULL
+  overhead_lat_var    FLOAT NOT NULL,
+  service_lat_p50     FLOAT NOT NULL,
+  service_lat_p90     FLOAT NOT NULL,
+  service_lat_p99     FLOAT NOT NULL,
+  rows_read_avg       FLOAT NOT NULL,
+  rows_read_var       FLOAT NOT NULL,
+  bytes_read_avg      FLOAT NOT NULL,
+  bytes_read_var      FLOAT NOT NULL`
+
+// stmtStatsDatums returns the values of the stmtStatsColumns for the given
+// statistics.
+func stmtStatsDatums(s *StatementStatistics) parser.Datums {
+	errString := parser.DNull
+	if s.LastErr != "" {
+		errString = parser.NewDString(s.LastErr)
+	}
+	numericStat := func(l *NumericStat) (parser.Datum, parser.Datum) {
+		return parser.NewDFloat(parser.DFloat(l.Mean)),
+			parser.NewDFloat(parser.DFloat(l.getVariance(s.Count)))
+	}
+	float := func(f float64) parser.Datum {
+		return parser.NewDFloat(parser.DFloat(f))
+	}
+	rowsAvg, rowsVar := numericStat(&s.NumRows)
+	parseLatAvg, parseLatVar := numericStat(&s.ParseLat)
+	planLatAvg, planLatVar := numericStat(&s.PlanLat)
+	runLatAvg, runLatVar := numericStat(&s.RunLat)
+	serviceLatAvg, serviceLatVar := numericStat(&s.ServiceLat)
+	overheadLatAvg, overheadLatVar := numericStat(&s.OverheadLat)
+	rowsReadAvg, rowsReadVar := numericStat(&s.RowsRead)
+	bytesReadAvg, bytesReadVar := numericStat(&s.BytesRead)
+	return parser.Datums{
+		parser.NewDInt(parser.DInt(s.Count)),
+		parser.NewDInt(parser.DInt(s.FirstAttemptCount)),
+		parser.NewDInt(parser.DInt(s.MaxRetries)),
+		errString,
+		rowsAvg, rowsVar,
+		parseLatAvg, parseLatVar,
+		planLatAvg, planLatVar,
+		runLatAvg, runLatVar,
+		serviceLatAvg, serviceLatVar,
+		overheadLatAvg, overheadLatVar,
+		float(s.ServiceLatHistogram.percentile(0.5)),
+		float(s.ServiceLatHistogram.percentile(0.9)),
+		float(s.ServiceLatHistogram.percentile(0.99)),
+		rowsReadAvg, rowsReadVar,
+		bytesReadAvg, bytesReadVar,
+	}
+}
+
+var crdbInternalStmtStatsTable = virtualSchemaTable{
+	schema: `
+CREATE TABLE crdb_internal.node_statement_statistics (
+  node_id             INT NOT NULL,
+  application_name    STRING NOT NULL,
+  key                 STRING NOT NULL,` + stmtStatsColumns + `
 );
 `,
 	populate: func(_ context.Context, p *planner, addRow func(...parser.Datum) error) error {
@@ -398,36 +707,73 @@ CREATE TABLE crdb_internal.node_statement_statistics (
 				s := appStats.getStatsForStmt(stmtKey)
 
 				s.Lock()
-				errString := parser.DNull
-				if s.data.LastErr != "" {
-					errString = parser.NewDString(s.data.LastErr)
-				}
-				err := addRow(
+				row := append(parser.Datums{
 					nodeID,
 					parser.NewDString(appName),
 					parser.NewDString(stmtKey),
-					parser.NewDInt(parser.DInt(s.data.Count)),
-					parser.NewDInt(parser.DInt(s.data.FirstAttemptCount)),
-					parser.NewDInt(parser.DInt(s.data.MaxRetries)),
-					errString,
-					parser.NewDFloat(parser.DFloat(s.data.NumRows.Mean)),
-					parser.NewDFloat(parser.DFloat(s.data.NumRows.getVariance(s.data.Count))),
-					parser.NewDFloat(parser.DFloat(s.data.ParseLat.Mean)),
-					parser.NewDFloat(parser.DFloat(s.data.ParseLat.getVariance(s.data.Count))),
-					parser.NewDFloat(parser.DFloat(s.data.PlanLat.Mean)),
-					parser.NewDFloat(parser.DFloat(s.data.PlanLat.getVariance(s.data.Count))),
-					parser.NewDFloat(parser.DFloat(s.data.RunLat.Mean)),
-					parser.NewDFloat(parser.DFloat(s.data.RunLat.getVariance(s.data.Count))),
-					parser.NewDFloat(parser.DFloat(s.data.ServiceLat.Mean)),
-					parser.NewDFloat(parser.DFloat(s.data.ServiceLat.getVariance(s.data.Count))),
-					parser.NewDFloat(parser.DFloat(s.data.OverheadLat.Mean)),
-					parser.NewDFloat(parser.DFloat(s.data.OverheadLat.getVariance(s.data.Count))),
-				)
+				}, stmtStatsDatums(&s.data)...)
 				s.Unlock()
-				if err != nil {
+				if err := addRow(row...); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	},
+}
+
+// crdbInternalClusterStmtStatsTable exposes the statement statistics
+// persisted by all the nodes, aggregated over the nodes. The statistics
+// that the nodes have not persisted yet are not included; see
+// crdb_internal.node_statement_statistics for those.
+var crdbInternalClusterStmtStatsTable = virtualSchemaTable{
+	schema: `
+CREATE TABLE crdb_internal.cluster_statement_statistics (
+  aggregated_ts       TIMESTAMP NOT NULL,
+  application_name    STRING NOT NULL,
+  key                 STRING NOT NULL,
+  node_count          INT NOT NULL,` + stmtStatsColumns + `
+);
+`,
+	populate: func(ctx context.Context, p *planner, addRow func(...parser.Datum) error) error {
+		if p.session.User != security.RootUser {
+			return errors.New("only root can access application statistics")
+		}
+
+		// The rows come in the order of the primary key, so the rows of the
+		// different nodes for a statement are adjacent.
+		rows, err := p.queryRows(ctx, `
+SELECT aggregated_ts, application_name, key, statistics
+  FROM system.statement_statistics
+ ORDER BY aggregated_ts, application_name, key`)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < len(rows); {
+			var stats StatementStatistics
+			j := i
+			for ; j < len(rows); j++ {
+				if j > i && (rows[j][0].Compare(&p.evalCtx, rows[i][0]) != 0 ||
+					rows[j][1].Compare(&p.evalCtx, rows[i][1]) != 0 ||
+					rows[j][2].Compare(&p.evalCtx, rows[i][2]) != 0) {
+					break
+				}
+				var nodeStats StatementStatistics
+				if err := proto.Unmarshal([]byte(*rows[j][3].(*parser.DBytes)), &nodeStats); err != nil {
 					return err
 				}
+				stats.add(&nodeStats)
+			}
+			row := append(parser.Datums{
+				rows[i][0],
+				rows[i][1],
+				rows[i][2],
+				parser.NewDInt(parser.DInt(j - i)),
+			}, stmtStatsDatums(&stats)...)
+			if err := addRow(row...); err != nil {
+				return err
 			}
+			i = j
 		}
 		return nil
 	},
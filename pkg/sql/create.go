@@ -202,6 +202,16 @@ func (n *createIndexNode) Start(ctx context.Context) error {
 		}
 	}
 
+	if n.n.PartitionBy != nil {
+		index := n.tableDesc.Mutations[mutationIdx].GetIndex()
+		partitioning, err := createPartitioning(
+			n.tableDesc, index, n.n.PartitionBy, n.p.session.SearchPath)
+		if err != nil {
+			return err
+		}
+		index.Partitioning = partitioning
+	}
+
 	if err := n.p.txn.Put(
 		ctx,
 		sqlbase.MakeDescMetadataKey(n.tableDesc.GetID()),
@@ -819,9 +829,26 @@ func resolveFK(
 	d *parser.ForeignKeyConstraintTableDef,
 	backrefs map[sqlbase.ID]*sqlbase.TableDescriptor,
 	mode sqlbase.ConstraintValidity,
+) error {
+	lookup := func(tn *parser.TableName) (*sqlbase.TableDescriptor, error) {
+		return getTableDesc(ctx, txn, vt, tn)
+	}
+	return ResolveFK(tbl, d, lookup, backrefs, mode)
+}
+
+// ResolveFK is like resolveFK, but looks up the referenced table with lookup,
+// which returns nil if the table doesn't exist. It is used to add foreign keys
+// between tables that are created outside of a planner, such as those of an
+// IMPORT.
+func ResolveFK(
+	tbl *sqlbase.TableDescriptor,
+	d *parser.ForeignKeyConstraintTableDef,
+	lookup func(*parser.TableName) (*sqlbase.TableDescriptor, error),
+	backrefs map[sqlbase.ID]*sqlbase.TableDescriptor,
+	mode sqlbase.ConstraintValidity,
 ) error {
 	targetTable := d.Table.TableName()
-	target, err := getTableDesc(ctx, txn, vt, targetTable)
+	target, err := lookup(targetTable)
 	if err != nil {
 		return err
 	}
@@ -1290,6 +1317,9 @@ func MakeTableDesc(
 	}
 
 	var primaryIndexColumnSet map[string]struct{}
+	// The PARTITION BY clauses of the secondary indexes, by position in
+	// desc.Indexes. They are resolved once the column IDs are allocated.
+	partitionBys := make(map[int]*parser.PartitionBy)
 	for _, def := range n.Defs {
 		switch d := def.(type) {
 		case *parser.ColumnTableDef:
@@ -1306,6 +1336,9 @@ func MakeTableDesc(
 			if err := desc.AddIndex(idx, false); err != nil {
 				return desc, err
 			}
+			if d.PartitionBy != nil {
+				partitionBys[len(desc.Indexes)-1] = d.PartitionBy
+			}
 			if d.Interleave != nil {
 				return desc, util.UnimplementedWithIssueErrorf(9148, "use CREATE INDEX to make interleaved indexes")
 			}
@@ -1326,6 +1359,8 @@ func MakeTableDesc(
 				for _, c := range d.Columns {
 					primaryIndexColumnSet[c.Column.Normalize()] = struct{}{}
 				}
+			} else if d.PartitionBy != nil {
+				partitionBys[len(desc.Indexes)-1] = d.PartitionBy
 			}
 			if d.Interleave != nil {
 				return desc, util.UnimplementedWithIssueErrorf(9148, "use CREATE INDEX to make interleaved indexes")
@@ -1371,6 +1406,23 @@ func MakeTableDesc(
 		}
 	}
 
+	if n.PartitionBy != nil {
+		partitioning, err := createPartitioning(&desc, &desc.PrimaryIndex, n.PartitionBy, searchPath)
+		if err != nil {
+			return desc, err
+		}
+		desc.PrimaryIndex.Partitioning = partitioning
+	}
+	for i := range desc.Indexes {
+		if partitionBy, ok := partitionBys[i]; ok {
+			partitioning, err := createPartitioning(&desc, &desc.Indexes[i], partitionBy, searchPath)
+			if err != nil {
+				return desc, err
+			}
+			desc.Indexes[i].Partitioning = partitioning
+		}
+	}
+
 	// With all structural elements in place and IDs allocated, we can resolve the
 	// constraints and qualifications.
 	// FKs are resolved after the descriptor is otherwise complete and IDs have
@@ -0,0 +1,289 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/gossip"
+	"github.com/cockroachdb/cockroach/pkg/internal/client"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/distsqlplan"
+	"github.com/cockroachdb/cockroach/pkg/sql/distsqlrun"
+	"github.com/cockroachdb/cockroach/pkg/sql/mon"
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/privilege"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+)
+
+const (
+	// statsSampleSize is the number of rows sampled to build a histogram.
+	statsSampleSize = 10000
+	// statsHistogramBuckets is the maximum number of buckets of a histogram.
+	statsHistogramBuckets = 200
+)
+
+// createStatsResultColumns are the columns of the rows output by the sample
+// aggregator, one for each collected statistic; see
+// distsqlrun.SampleAggregatorColTypes.
+var createStatsResultColumns = ResultColumns{
+	{Name: "row_count", Typ: parser.TypeInt},
+	{Name: "distinct_count", Typ: parser.TypeInt},
+	{Name: "null_count", Typ: parser.TypeInt},
+	{Name: "histogram", Typ: parser.TypeBytes},
+}
+
+type createStatsNode struct {
+	p         *planner
+	n         *parser.CreateStats
+	tableDesc *sqlbase.TableDescriptor
+	columnID  sqlbase.ColumnID
+}
+
+// CreateStats creates statistics on a column of a table.
+// Privileges: SELECT on table.
+func (p *planner) CreateStats(ctx context.Context, n *parser.CreateStats) (planNode, error) {
+	tn, err := n.Table.NormalizeWithDatabaseName(p.session.Database)
+	if err != nil {
+		return nil, err
+	}
+
+	tableDesc, err := mustGetTableDesc(ctx, p.txn, p.getVirtualTabler(), tn)
+	if err != nil {
+		return nil, err
+	}
+	if tableDesc.IsView() || tableDesc.IsVirtualTable() {
+		return nil, sqlbase.NewWrongObjectTypeError(tn.String(), "table")
+	}
+
+	if err := p.CheckPrivilege(tableDesc, privilege.SELECT); err != nil {
+		return nil, err
+	}
+
+	if len(n.ColumnNames) != 1 {
+		return nil, errors.Errorf("statistics on multiple columns are not supported yet")
+	}
+	col, err := tableDesc.FindActiveColumnByName(n.ColumnNames[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return &createStatsNode{p: p, n: n, tableDesc: tableDesc, columnID: col.ID}, nil
+}
+
+func (n *createStatsNode) Start(ctx context.Context) error {
+	execCfg := n.p.ExecCfg()
+	job := execCfg.JobRegistry.NewJobLogger(JobRecord{
+		Description:   parser.AsString(n.n),
+		Username:      n.p.User(),
+		DescriptorIDs: sqlbase.IDs{n.tableDesc.ID},
+		Details: CreateStatsJobDetails{
+			Name:      string(n.n.Name),
+			TableID:   n.tableDesc.ID,
+			ColumnIDs: []sqlbase.ColumnID{n.columnID},
+		},
+	})
+	if err := job.Created(ctx); err != nil {
+		return err
+	}
+	if err := job.Started(ctx); err != nil {
+		return err
+	}
+	if err := createStatistics(ctx, execCfg, &job); err != nil {
+		job.Failed(ctx, err)
+		return err
+	}
+	if err := job.Succeeded(ctx); err != nil {
+		// An error while marking the job as successful is not important enough
+		// to merit failing the statement: the statistics are already stored.
+		log.Errorf(ctx, "CREATE STATISTICS ignoring error while marking job %d as successful: %+v",
+			*job.JobID(), err)
+	}
+	return nil
+}
+
+func (*createStatsNode) Next(context.Context) (bool, error) { return false, nil }
+func (*createStatsNode) Close(context.Context)              {}
+func (*createStatsNode) Columns() ResultColumns             { return make(ResultColumns, 0) }
+func (*createStatsNode) Ordering() orderingInfo             { return orderingInfo{} }
+func (*createStatsNode) Values() parser.Datums              { return parser.Datums{} }
+func (*createStatsNode) DebugValues() debugValues           { return debugValues{} }
+func (*createStatsNode) MarkDebug(mode explainMode)         {}
+
+func (*createStatsNode) Spans(context.Context) (_, _ roachpb.Spans, _ error) {
+	panic("unimplemented")
+}
+
+// createStatistics collects the statistics described by the details of a
+// CREATE STATISTICS job, stores them in system.table_statistics and notifies
+// the other nodes through gossip. It is also the JobResumer of the job: the
+// statistics are collected from scratch as they are not checkpointed.
+func createStatistics(ctx context.Context, execCfg *ExecutorConfig, job *JobLogger) error {
+	details, ok := job.Job.Details.(CreateStatsJobDetails)
+	if !ok {
+		return errors.Errorf("unexpected details type %T for a CREATE STATISTICS job", job.Job.Details)
+	}
+	if len(details.ColumnIDs) != 1 {
+		return errors.Errorf("expected statistics on a single column, got %d", len(details.ColumnIDs))
+	}
+	columnID := details.ColumnIDs[0]
+
+	dsp := execCfg.DistLoader.distSQLPlanner
+	var result parser.Datums
+	if err := execCfg.DB.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
+		result = nil
+		tableDesc, err := sqlbase.GetTableDescFromID(ctx, txn, details.TableID)
+		if err != nil {
+			return err
+		}
+		colIdx := -1
+		for i := range tableDesc.Columns {
+			if tableDesc.Columns[i].ID == columnID {
+				colIdx = i
+				break
+			}
+		}
+		if colIdx == -1 {
+			return errors.Errorf("column %d of table %q does not exist", columnID, tableDesc.Name)
+		}
+
+		planCtx := dsp.NewPlanningCtx(ctx, txn)
+		plan, err := dsp.createStatsPlan(&planCtx, tableDesc, colIdx)
+		if err != nil {
+			return err
+		}
+		rows := NewRowContainer(mon.MakeStandaloneBudget(math.MaxInt64), createStatsResultColumns, 0)
+		defer rows.Close(ctx)
+
+		dsp.FinalizePlan(&planCtx, &plan)
+		recv := makeDistSQLReceiver(ctx, rows, nil /* rangeCache */, nil /* leaseCache */)
+		if err := dsp.Run(&planCtx, txn, &plan, &recv); err != nil {
+			return err
+		}
+		if recv.err != nil {
+			return recv.err
+		}
+		if rows.Len() != 1 {
+			return errors.Errorf("expected one row of statistics, got %d", rows.Len())
+		}
+		result = append(parser.Datums(nil), rows.At(0)...)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	ie := InternalExecutor{LeaseManager: execCfg.LeaseManager}
+	if err := execCfg.DB.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
+		_, err := ie.ExecuteStatementInTransaction(
+			ctx,
+			"insert-statistic",
+			txn,
+			`INSERT INTO system.table_statistics (
+					"table_id", "column_id", "name", "row_count", "distinct_count", "null_count",
+					"histogram"
+				) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			details.TableID,
+			columnID,
+			details.Name,
+			result[0],
+			result[1],
+			result[2],
+			result[3],
+		)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	// Let the nodes, including this one, know that their cached statistics of
+	// the table are stale.
+	execCfg.TableStatsCache.InvalidateTableStats(details.TableID)
+	return execCfg.Gossip.AddInfo(
+		gossip.MakeTableStatAddedKey(uint32(details.TableID)), nil /* val */, 0, /* ttl */
+	)
+}
+
+// createStatsPlan generates a plan that collects the statistics of a column
+// of a table. The table is read by table readers on the nodes holding its
+// ranges, each feeding a sampler on the same node; the samples and sketches
+// are merged by a single sample aggregator.
+func (dsp *distSQLPlanner) createStatsPlan(
+	planCtx *planningCtx, desc *sqlbase.TableDescriptor, colIdx int,
+) (physicalPlan, error) {
+	spanPartitions, err := dsp.partitionSpans(planCtx, roachpb.Spans{desc.PrimaryIndexSpan()})
+	if err != nil {
+		return physicalPlan{}, err
+	}
+
+	var p physicalPlan
+	for _, sp := range spanPartitions {
+		tr := &distsqlrun.TableReaderSpec{Table: *desc}
+		tr.Spans = make([]distsqlrun.TableReaderSpan, len(sp.spans))
+		for i := range sp.spans {
+			tr.Spans[i].Span = sp.spans[i]
+		}
+
+		proc := distsqlplan.Processor{
+			Node: sp.node,
+			Spec: distsqlrun.ProcessorSpec{
+				Core: distsqlrun.ProcessorCoreUnion{TableReader: tr},
+				Post: distsqlrun.PostProcessSpec{
+					OutputColumns: []uint32{uint32(colIdx)},
+				},
+				Output: []distsqlrun.OutputRouterSpec{{Type: distsqlrun.OutputRouterSpec_PASS_THROUGH}},
+			},
+		}
+		pIdx := p.AddProcessor(proc)
+		p.ResultRouters = append(p.ResultRouters, pIdx)
+	}
+	p.ResultTypes = []sqlbase.ColumnType{desc.Columns[colIdx].Type}
+
+	sketches := []distsqlrun.SketchSpec{{
+		Columns:             []uint32{0},
+		GenerateHistogram:   true,
+		HistogramMaxBuckets: statsHistogramBuckets,
+	}}
+
+	p.AddNoGroupingStage(
+		distsqlrun.ProcessorCoreUnion{Sampler: &distsqlrun.SamplerSpec{
+			Sketches:   sketches,
+			SampleSize: statsSampleSize,
+		}},
+		distsqlrun.PostProcessSpec{},
+		distsqlrun.SamplerColTypes(p.ResultTypes),
+		distsqlrun.Ordering{},
+	)
+
+	p.AddSingleGroupStage(
+		dsp.nodeDesc.NodeID,
+		distsqlrun.ProcessorCoreUnion{SampleAggregator: &distsqlrun.SampleAggregatorSpec{
+			Sketches:   sketches,
+			SampleSize: statsSampleSize,
+		}},
+		distsqlrun.PostProcessSpec{},
+		distsqlrun.SampleAggregatorColTypes,
+	)
+
+	p.planToStreamColMap = []int{0, 1, 2, 3}
+	return p, nil
+}
+
+func init() {
+	RegisterJobResumer(JobTypeCreateStats, createStatistics)
+}
@@ -95,6 +95,12 @@ func (mt mutationTest) makeMutationsActive() {
 	}
 }
 
+// reloadTableDesc reads the table descriptor again, which is needed after a
+// TRUNCATE replaces the table with an empty copy under a new ID.
+func (mt mutationTest) reloadTableDesc() {
+	*mt.tableDesc = *sqlbase.GetTableDescriptor(mt.kvDB, "t", "test")
+}
+
 // writeColumnMutation adds column as a mutation and writes the
 // descriptor to the DB.
 func (mt mutationTest) writeColumnMutation(column string, m sqlbase.DescriptorMutation) {
@@ -181,6 +187,7 @@ CREATE TABLE t.test (k CHAR PRIMARY KEY, v CHAR, i CHAR DEFAULT 'i', FAMILY (k),
 		for _, state := range []sqlbase.DescriptorMutation_State{sqlbase.DescriptorMutation_DELETE_ONLY, sqlbase.DescriptorMutation_WRITE_ONLY} {
 			// Init table to start state.
 			mTest.Exec(`TRUNCATE TABLE t.test`)
+			mTest.reloadTableDesc()
 			initRows := [][]string{{"a", "z", "q"}}
 			for _, row := range initRows {
 				if useUpsert {
@@ -391,6 +398,7 @@ CREATE TABLE t.test (k CHAR PRIMARY KEY, v CHAR, INDEX foo (v));
 			if _, err := sqlDB.Exec(`TRUNCATE TABLE t.test`); err != nil {
 				t.Fatal(err)
 			}
+			mTest.reloadTableDesc()
 			initRows := [][]string{{"a", "z"}, {"b", "y"}}
 			for _, row := range initRows {
 				if useUpsert {
@@ -547,6 +555,7 @@ CREATE TABLE t.test (k CHAR PRIMARY KEY, v CHAR, i CHAR, INDEX foo (i, v), FAMIL
 				if _, err := sqlDB.Exec(`TRUNCATE TABLE t.test`); err != nil {
 					t.Fatal(err)
 				}
+				mTest.reloadTableDesc()
 				initRows := [][]string{{"a", "z", "q"}, {"b", "y", "r"}}
 				for _, row := range initRows {
 					if useUpsert {
@@ -1023,3 +1032,44 @@ func TestAddingFKs(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+// TestTruncateWithMutation tests that TRUNCATE of a table with a pending
+// mutation deletes the table's rows in place instead of replacing the table,
+// so that the mutation isn't lost.
+func TestTruncateWithMutation(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	// The descriptor changes made must have an immediate effect
+	// so disable leases on tables.
+	defer sql.TestDisableTableLeases()()
+	// Disable external processing of mutations.
+	params, _ := createTestServerParams()
+	params.Knobs.SQLSchemaChanger = &sql.SchemaChangerTestingKnobs{
+		AsyncExecNotification: asyncSchemaChangerDisabled,
+	}
+	server, sqlDB, kvDB := serverutils.StartServer(t, params)
+	defer server.Stopper().Stop()
+
+	if _, err := sqlDB.Exec(`
+CREATE DATABASE t;
+CREATE TABLE t.test (k CHAR PRIMARY KEY, v CHAR, i CHAR DEFAULT 'i', FAMILY (k), FAMILY (v), FAMILY (i));
+INSERT INTO t.test VALUES ('a', 'z', 'q'), ('b', 'y', 'r');
+`); err != nil {
+		t.Fatal(err)
+	}
+
+	tableDesc := sqlbase.GetTableDescriptor(kvDB, "t", "test")
+	mTest := makeMutationTest(t, kvDB, sqlDB, tableDesc)
+	mTest.writeColumnMutation("i", sqlbase.DescriptorMutation{})
+
+	mTest.Exec(`TRUNCATE TABLE t.test`)
+
+	desc := sqlbase.GetTableDescriptor(kvDB, "t", "test")
+	if desc.ID != tableDesc.ID {
+		t.Fatalf("expected TRUNCATE to keep table %d, found table %d", tableDesc.ID, desc.ID)
+	}
+	if len(desc.Mutations) != 1 {
+		t.Fatalf("expected the mutation to be kept, found %d mutations", len(desc.Mutations))
+	}
+	mTest.CheckQueryResults(`SELECT * FROM t.test`, [][]string{})
+	mTest.checkTableSize(0)
+}
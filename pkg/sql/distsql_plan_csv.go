@@ -0,0 +1,282 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/internal/client"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/distsqlplan"
+	"github.com/cockroachdb/cockroach/pkg/sql/distsqlrun"
+	"github.com/cockroachdb/cockroach/pkg/sql/mon"
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+)
+
+// DistLoader uses DistSQL to convert external data formats (csv, etc) into
+// SSTs of our MVCC-format key values.
+type DistLoader struct {
+	distSQLPlanner *distSQLPlanner
+}
+
+// csvSamplesPerSST is the number of keys sampled, during the first pass of
+// LoadCSV, for each SST written during the second.
+const csvSamplesPerSST = 100
+
+var (
+	csvKVColumnTypes = []sqlbase.ColumnType{
+		{Kind: sqlbase.ColumnType_BYTES},
+		{Kind: sqlbase.ColumnType_BYTES},
+	}
+	// LoadCSVResultColumns are the columns of the rows returned by LoadCSV,
+	// each describing one written SST.
+	LoadCSVResultColumns = ResultColumns{
+		{Name: "name", Typ: parser.TypeString},
+		{Name: "rows", Typ: parser.TypeInt},
+		{Name: "index_entries", Typ: parser.TypeInt},
+		{Name: "data_size", Typ: parser.TypeInt},
+		{Name: "checksum", Typ: parser.TypeBytes},
+		{Name: "start", Typ: parser.TypeBytes},
+		{Name: "end", Typ: parser.TypeBytes},
+	}
+	sstWriterColumnTypes = []sqlbase.ColumnType{
+		{Kind: sqlbase.ColumnType_STRING},
+		{Kind: sqlbase.ColumnType_INT},
+		{Kind: sqlbase.ColumnType_INT},
+		{Kind: sqlbase.ColumnType_INT},
+		{Kind: sqlbase.ColumnType_BYTES},
+		{Kind: sqlbase.ColumnType_BYTES},
+		{Kind: sqlbase.ColumnType_BYTES},
+	}
+)
+
+// LoadCSV converts the CSV, or pg_dump, files described by spec into the KVs
+// of its tables and writes them, sorted and divided into SSTs of about
+// splitSize bytes, to the ExportStorage at the temp URI. The files are spread
+// among the given nodes, which read and convert them in parallel.
+//
+// The conversion runs in two passes over the files. The first one samples
+// the keys in order to pick the split points of the SSTs; the second one
+// routes each KV to the node responsible for writing the SST covering it.
+//
+// It returns one row per written SST (see LoadCSVResultColumns), ordered by
+// key.
+func (l *DistLoader) LoadCSV(
+	ctx context.Context,
+	db *client.DB,
+	nodes []roachpb.NodeDescriptor,
+	spec distsqlrun.ReadCSVSpec,
+	temp string,
+	walltime int64,
+	splitSize int64,
+) ([]parser.Datums, error) {
+	if len(nodes) == 0 {
+		return nil, errors.New("no nodes to run the import on")
+	}
+	if splitSize <= 0 {
+		return nil, errors.Errorf("invalid split size %d", splitSize)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].NodeID < nodes[j].NodeID })
+
+	// Spread the files among the nodes.
+	readers := make([]distsqlrun.ReadCSVSpec, len(nodes))
+	for i, uri := range spec.URIs {
+		r := &readers[i%len(nodes)]
+		r.URIs = append(r.URIs, uri)
+	}
+
+	// First pass: sample the keys.
+	sampleSize := splitSize / csvSamplesPerSST
+	if sampleSize < 1 {
+		sampleSize = 1
+	} else if sampleSize > math.MaxInt32 {
+		sampleSize = math.MaxInt32
+	}
+	sampleColumns := ResultColumns{{Name: "key", Typ: parser.TypeBytes}}
+	samples, err := l.runCSVPlan(ctx, db, sampleColumns, func(planCtx *planningCtx) physicalPlan {
+		var p physicalPlan
+		for i := range readers {
+			if len(readers[i].URIs) == 0 {
+				continue
+			}
+			planCtx.nodeAddresses[nodes[i].NodeID] = nodes[i].Address.String()
+			rcs := spec
+			rcs.URIs = readers[i].URIs
+			rcs.SampleSize = int32(sampleSize)
+			pIdx := p.AddProcessor(distsqlplan.Processor{
+				Node: nodes[i].NodeID,
+				Spec: distsqlrun.ProcessorSpec{
+					Core:   distsqlrun.ProcessorCoreUnion{ReadCSV: &rcs},
+					Output: []distsqlrun.OutputRouterSpec{{Type: distsqlrun.OutputRouterSpec_PASS_THROUGH}},
+				},
+			})
+			p.ResultRouters = append(p.ResultRouters, pIdx)
+		}
+		p.ResultTypes = csvKVColumnTypes
+		p.planToStreamColMap = []int{0}
+		return p
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "sampling")
+	}
+	if len(samples) == 0 {
+		return nil, nil
+	}
+
+	// Pick every csvSamplesPerSST-th sampled key as a split point.
+	sampleKeys := make([][]byte, len(samples))
+	for i, row := range samples {
+		sampleKeys[i] = []byte(*row[0].(*parser.DBytes))
+	}
+	sort.Slice(sampleKeys, func(i, j int) bool { return bytes.Compare(sampleKeys[i], sampleKeys[j]) < 0 })
+	var splits [][]byte
+	for i := csvSamplesPerSST; i < len(sampleKeys); i += csvSamplesPerSST {
+		if len(splits) > 0 && bytes.Equal(splits[len(splits)-1], sampleKeys[i]) {
+			continue
+		}
+		splits = append(splits, sampleKeys[i])
+	}
+	log.VEventf(ctx, 1, "%d samples, %d splits", len(sampleKeys), len(splits))
+
+	// Second pass: convert the files and write the SSTs. The spans between the
+	// split points are assigned round-robin to SST writers, one per node.
+	numWriters := len(nodes)
+	if numWriters > len(splits)+1 {
+		numWriters = len(splits) + 1
+	}
+	writers := make([]distsqlrun.SSTWriterSpec, numWriters)
+	rangeSpans := make([]distsqlrun.OutputRouterSpec_RangeSpan, len(splits)+1)
+	for i := range rangeSpans {
+		var end []byte
+		if i < len(splits) {
+			end = splits[i]
+		}
+		w := i % numWriters
+		rangeSpans[i] = distsqlrun.OutputRouterSpec_RangeSpan{End: end, Stream: int32(w)}
+		writers[w].Spans = append(writers[w].Spans, distsqlrun.SSTWriterSpec_SpanName{
+			End:  end,
+			Name: fmt.Sprintf("%d.sst", i),
+		})
+	}
+
+	results, err := l.runCSVPlan(ctx, db, LoadCSVResultColumns, func(planCtx *planningCtx) physicalPlan {
+		var p physicalPlan
+		var readerIdxs []distsqlplan.ProcessorIdx
+		for i := range readers {
+			if len(readers[i].URIs) == 0 {
+				continue
+			}
+			planCtx.nodeAddresses[nodes[i].NodeID] = nodes[i].Address.String()
+			rcs := spec
+			rcs.URIs = readers[i].URIs
+			rcs.SampleSize = 0
+			readerIdxs = append(readerIdxs, p.AddProcessor(distsqlplan.Processor{
+				Node: nodes[i].NodeID,
+				Spec: distsqlrun.ProcessorSpec{
+					Core: distsqlrun.ProcessorCoreUnion{ReadCSV: &rcs},
+					Output: []distsqlrun.OutputRouterSpec{{
+						Type:       distsqlrun.OutputRouterSpec_BY_RANGE,
+						RangeSpans: rangeSpans,
+					}},
+				},
+			}))
+		}
+		writerIdxs := make([]distsqlplan.ProcessorIdx, numWriters)
+		for i := range writers {
+			planCtx.nodeAddresses[nodes[i].NodeID] = nodes[i].Address.String()
+			sw := writers[i]
+			sw.Destination = temp
+			sw.WalltimeNanos = walltime
+			writerIdxs[i] = p.AddProcessor(distsqlplan.Processor{
+				Node: nodes[i].NodeID,
+				Spec: distsqlrun.ProcessorSpec{
+					Input: []distsqlrun.InputSyncSpec{{
+						Type:        distsqlrun.InputSyncSpec_UNORDERED,
+						ColumnTypes: csvKVColumnTypes,
+					}},
+					Core:   distsqlrun.ProcessorCoreUnion{SSTWriter: &sw},
+					Output: []distsqlrun.OutputRouterSpec{{Type: distsqlrun.OutputRouterSpec_PASS_THROUGH}},
+				},
+			})
+		}
+		// Every reader has one stream to every writer, in the order of the
+		// writers, which is the order of the streams of the range router.
+		for _, r := range readerIdxs {
+			for slot, w := range writerIdxs {
+				p.Streams = append(p.Streams, distsqlplan.Stream{
+					SourceProcessor:  r,
+					SourceRouterSlot: slot,
+					DestProcessor:    w,
+					DestInput:        0,
+				})
+			}
+		}
+		p.ResultRouters = writerIdxs
+		p.ResultTypes = sstWriterColumnTypes
+		p.planToStreamColMap = []int{0, 1, 2, 3, 4, 5, 6}
+		return p
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return *results[i][5].(*parser.DBytes) < *results[j][5].(*parser.DBytes)
+	})
+	return results, nil
+}
+
+// runCSVPlan runs the plan built by makePlan, in a transaction that is only
+// used to set up the flows, and returns its results, which have the given
+// columns.
+func (l *DistLoader) runCSVPlan(
+	ctx context.Context,
+	db *client.DB,
+	columns ResultColumns,
+	makePlan func(*planningCtx) physicalPlan,
+) ([]parser.Datums, error) {
+	var results []parser.Datums
+	err := db.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
+		results = nil
+		planCtx := l.distSQLPlanner.NewPlanningCtx(ctx, txn)
+		plan := makePlan(&planCtx)
+		if len(plan.ResultRouters) == 0 {
+			return nil
+		}
+		rows := NewRowContainer(mon.MakeStandaloneBudget(math.MaxInt64), columns, 0)
+		defer rows.Close(ctx)
+
+		l.distSQLPlanner.FinalizePlan(&planCtx, &plan)
+		recv := makeDistSQLReceiver(ctx, rows, nil /* rangeCache */, nil /* leaseCache */)
+		if err := l.distSQLPlanner.Run(&planCtx, txn, &plan, &recv); err != nil {
+			return err
+		}
+		if recv.err != nil {
+			return recv.err
+		}
+		for i := 0; i < rows.Len(); i++ {
+			results = append(results, append(parser.Datums(nil), rows.At(i)...))
+		}
+		return nil
+	})
+	return results, err
+}
@@ -0,0 +1,117 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/distsqlrun"
+	"github.com/cockroachdb/cockroach/pkg/sql/mon"
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+)
+
+var (
+	// ExportResultColumns are the columns of the rows returned by ExportQuery,
+	// each describing one written file.
+	ExportResultColumns = ResultColumns{
+		{Name: "filename", Typ: parser.TypeString},
+		{Name: "rows", Typ: parser.TypeInt},
+		{Name: "bytes", Typ: parser.TypeInt},
+	}
+	csvWriterColumnTypes = []sqlbase.ColumnType{
+		{Kind: sqlbase.ColumnType_STRING},
+		{Kind: sqlbase.ColumnType_INT},
+		{Kind: sqlbase.ColumnType_INT},
+	}
+)
+
+// ExportQuery plans query with DistSQL and runs it, in the planner's
+// transaction, with its results written as CSV files by a CSVWriter on each
+// of the nodes producing them. spec is used for every writer, except for its
+// Name, which is made unique per writer.
+//
+// It returns one row per written file (see ExportResultColumns).
+func (p *planner) ExportQuery(
+	ctx context.Context, query *parser.Select, spec distsqlrun.CSVWriterSpec,
+) ([]parser.Datums, error) {
+	plan, err := p.newPlan(ctx, query, nil, false /* autoCommit */)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { plan.Close(ctx) }()
+	if err := p.semaCtx.Placeholders.AssertAllAssigned(); err != nil {
+		return nil, err
+	}
+	plan, err = p.optimizePlan(ctx, plan, allColumns(plan))
+	if err != nil {
+		return nil, err
+	}
+
+	dsp := p.session.distSQLPlanner
+	if _, err := dsp.CheckSupport(plan); err != nil {
+		return nil, errors.Wrap(err, "query cannot be exported")
+	}
+	planCtx := dsp.NewPlanningCtx(ctx, p.txn)
+	physPlan, err := dsp.createPlanForNode(&planCtx, plan)
+	if err != nil {
+		return nil, err
+	}
+
+	// Drop the columns not in the results of the query, and put the others in
+	// its order.
+	cols := make([]uint32, len(physPlan.planToStreamColMap))
+	for i, c := range physPlan.planToStreamColMap {
+		cols[i] = uint32(c)
+	}
+	physPlan.AddProjection(cols)
+
+	first := len(physPlan.Processors)
+	physPlan.AddNoGroupingStage(
+		distsqlrun.ProcessorCoreUnion{CSVWriter: &spec},
+		distsqlrun.PostProcessSpec{},
+		csvWriterColumnTypes,
+		distsqlrun.Ordering{},
+	)
+	// Every writer needs its own spec, to get its own file names.
+	for i := first; i < len(physPlan.Processors); i++ {
+		proc := &physPlan.Processors[i]
+		w := spec
+		w.Name = fmt.Sprintf("n%d.%d", proc.Node, i-first)
+		proc.Spec.Core.CSVWriter = &w
+	}
+	physPlan.planToStreamColMap = []int{0, 1, 2}
+
+	rows := NewRowContainer(mon.MakeStandaloneBudget(math.MaxInt64), ExportResultColumns, 0)
+	defer rows.Close(ctx)
+
+	dsp.FinalizePlan(&planCtx, &physPlan)
+	recv := makeDistSQLReceiver(ctx, rows, nil /* rangeCache */, nil /* leaseCache */)
+	if err := dsp.Run(&planCtx, p.txn, &physPlan, &recv); err != nil {
+		return nil, err
+	}
+	if recv.err != nil {
+		return nil, recv.err
+	}
+	results := make([]parser.Datums, rows.Len())
+	for i := range results {
+		results[i] = append(parser.Datums(nil), rows.At(i)...)
+	}
+	return results, nil
+}
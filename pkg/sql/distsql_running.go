@@ -105,7 +105,7 @@ func (dsp *distSQLPlanner) Run(
 
 	if logPlanDiagram {
 		log.VEvent(ctx, 1, "creating plan diagram")
-		json, url, err := distsqlrun.GeneratePlanDiagramWithURL(flows)
+		json, url, err := distsqlrun.GeneratePlanDiagramWithURL(flows, nil /* stats */)
 		if err != nil {
 			log.Infof(ctx, "Error generating diagram: %s", err)
 		} else {
@@ -131,9 +131,10 @@ func (dsp *distSQLPlanner) Run(
 			continue
 		}
 		req := &distsqlrun.SetupFlowRequest{
-			Version: distsqlrun.Version,
-			Txn:     *txn.Proto(),
-			Flow:    flowSpec,
+			Version:      distsqlrun.Version,
+			Txn:          *txn.Proto(),
+			Flow:         flowSpec,
+			CollectStats: recv.processorStats != nil,
 		}
 		if err := distsqlrun.SetFlowRequestTrace(ctx, req); err != nil {
 			return err
@@ -172,9 +173,10 @@ func (dsp *distSQLPlanner) Run(
 
 	// Set up the flow on this node.
 	localReq := distsqlrun.SetupFlowRequest{
-		Version: distsqlrun.Version,
-		Txn:     *txn.Proto(),
-		Flow:    flows[thisNodeID],
+		Version:      distsqlrun.Version,
+		Txn:          *txn.Proto(),
+		Flow:         flows[thisNodeID],
+		CollectStats: recv.processorStats != nil,
 	}
 	if err := distsqlrun.SetFlowRequestTrace(ctx, &localReq); err != nil {
 		return err
@@ -208,6 +210,12 @@ type distSQLReceiver struct {
 	resultToStreamColMap []int
 	// numRows counts the number of rows we received when rows is nil.
 	numRows int64
+	// rowsRead and bytesRead accumulate the read stats sent by the processors
+	// that read from the KV layer.
+	rowsRead, bytesRead int64
+	// processorStats, if set, asks the flows to collect runtime statistics,
+	// and accumulates the statistics sent by the processors by processor ID.
+	processorStats map[int32]distsqlrun.ProcessorStats
 
 	// err represents the error that we received either from a producer or
 	// internally in the operation of the distSQLReceiver. If set, this will
@@ -252,6 +260,13 @@ func (r *distSQLReceiver) Push(
 		if len(meta.Ranges) > 0 {
 			r.err = r.updateCaches(r.ctx, meta.Ranges)
 		}
+		if meta.ReadStats != nil {
+			r.rowsRead += meta.ReadStats.RowsRead
+			r.bytesRead += meta.ReadStats.BytesRead
+		}
+		if meta.ProcessorStats != nil && r.processorStats != nil {
+			r.processorStats[meta.ProcessorStats.ProcessorID] = *meta.ProcessorStats
+		}
 		return r.status
 	}
 	if r.err != nil {
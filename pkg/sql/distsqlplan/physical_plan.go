@@ -671,17 +671,20 @@ func (p *PhysicalPlan) PopulateEndpoints(nodeAddresses map[roachpb.NodeID]string
 }
 
 // GenerateFlowSpecs takes a plan (with populated endpoints) and generates the
-// set of FlowSpecs (one per node involved in the plan).
+// set of FlowSpecs (one per node involved in the plan). The ID of each
+// processor is its index in the plan.
 func (p *PhysicalPlan) GenerateFlowSpecs() map[roachpb.NodeID]distsqlrun.FlowSpec {
 	flowID := distsqlrun.FlowID{UUID: uuid.MakeV4()}
 	flows := make(map[roachpb.NodeID]distsqlrun.FlowSpec)
 
-	for _, proc := range p.Processors {
+	for i, proc := range p.Processors {
 		flowSpec, ok := flows[proc.Node]
 		if !ok {
 			flowSpec = distsqlrun.FlowSpec{FlowID: flowID}
 		}
-		flowSpec.Processors = append(flowSpec.Processors, proc.Spec)
+		spec := proc.Spec
+		spec.ProcessorID = int32(i)
+		flowSpec.Processors = append(flowSpec.Processors, spec)
 		flows[proc.Node] = flowSpec
 	}
 	return flows
@@ -72,11 +72,14 @@ type aggregator struct {
 	groupCols columns
 	inputCols columns
 	buckets   map[string]struct{} // The set of bucket keys.
+	// mem tracks the size of the bucket keys held by the aggregator and its
+	// func holders. The state of the aggregate functions is not included.
+	mem memUsage
 
 	out procOutputHelper
 }
 
-var _ processor = &aggregator{}
+var _ Processor = &aggregator{}
 
 func newAggregator(
 	flowCtx *FlowCtx,
@@ -126,7 +129,7 @@ func newAggregator(
 	return ag, nil
 }
 
-// Run is part of the processor interface.
+// Run is part of the Processor interface.
 func (ag *aggregator) Run(ctx context.Context, wg *sync.WaitGroup) {
 	if wg != nil {
 		defer wg.Done()
@@ -180,6 +183,11 @@ func (ag *aggregator) Run(ctx context.Context, wg *sync.WaitGroup) {
 	}
 }
 
+// fillStats is part of the statsReporter interface.
+func (ag *aggregator) fillStats(stats *ProcessorStats) {
+	stats.MaxMemoryBytes = ag.mem.max
+}
+
 // accumulateRows reads and accumulates all input rows.
 // If no error is return, it means that all the rows from the input have been
 // consumed.
@@ -223,7 +231,10 @@ func (ag *aggregator) accumulateRows(ctx context.Context) (err error) {
 			return err
 		}
 
-		ag.buckets[string(encoded)] = struct{}{}
+		if _, ok := ag.buckets[string(encoded)]; !ok {
+			ag.buckets[string(encoded)] = struct{}{}
+			ag.mem.grow(int64(len(encoded)))
+		}
 		// Feed the func holders for this bucket the non-grouping datums.
 		for i, colIdx := range ag.inputCols {
 			if err := row[colIdx].EnsureDecoded(&ag.datumAlloc); err != nil {
@@ -265,12 +276,14 @@ func (a *aggregateFuncHolder) add(bucket []byte, d parser.Datum) error {
 			return nil
 		}
 		a.seen[string(encoded)] = struct{}{}
+		a.group.mem.grow(int64(len(encoded)))
 	}
 
 	impl, ok := a.buckets[string(bucket)]
 	if !ok {
 		impl = a.create()
 		a.buckets[string(bucket)] = impl
+		a.group.mem.grow(int64(len(bucket)))
 	}
 
 	impl.Add(&a.group.flowCtx.evalCtx, d)
@@ -262,11 +262,18 @@ type ProducerMetadata struct {
 	Ranges []roachpb.RangeInfo
 	// TODO(vivek): change to type Error
 	Err error
+	// ReadStats is sent by the processors that read from the KV layer once
+	// they are done reading.
+	ReadStats *ReadStats
+	// ProcessorStats is sent by every processor once it is done, if the flow
+	// collects runtime statistics.
+	ProcessorStats *ProcessorStats
 }
 
 // Empty returns true if none of the fields in metadata are populated.
 func (meta ProducerMetadata) Empty() bool {
-	return meta.Ranges == nil && meta.Err == nil
+	return meta.Ranges == nil && meta.Err == nil && meta.ReadStats == nil &&
+		meta.ProcessorStats == nil
 }
 
 // RowChannel is a thin layer over a RowChannelMsg channel, which can be used to
@@ -35,7 +35,7 @@ type distinct struct {
 	out          procOutputHelper
 }
 
-var _ processor = &distinct{}
+var _ Processor = &distinct{}
 
 func newDistinct(
 	flowCtx *FlowCtx, spec *DistinctSpec, input RowSource, post *PostProcessSpec, output RowReceiver,
@@ -59,7 +59,7 @@ func newDistinct(
 	return d, nil
 }
 
-// Run is part of the processor interface.
+// Run is part of the Processor interface.
 func (d *distinct) Run(ctx context.Context, wg *sync.WaitGroup) {
 	if wg != nil {
 		defer wg.Done()
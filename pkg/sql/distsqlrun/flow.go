@@ -63,6 +63,17 @@ type FlowCtx struct {
 	// run.
 	nodeID       roachpb.NodeID
 	testingKnobs TestingKnobs
+	// collectStats is set if the processors collect runtime statistics and
+	// send them as trailing metadata (see ProcessorStats).
+	collectStats bool
+	// TempPrefix is a path under which temp files can be created.
+	TempPrefix string
+}
+
+// NodeID returns the ID of the node on which the processors using this FlowCtx
+// run.
+func (flowCtx *FlowCtx) NodeID() roachpb.NodeID {
+	return flowCtx.nodeID
 }
 
 func (flowCtx *FlowCtx) setupTxn() *client.Txn {
@@ -83,7 +94,7 @@ type Flow struct {
 	FlowCtx
 
 	flowRegistry *flowRegistry
-	processors   []processor
+	processors   []Processor
 	outboxes     []*outbox
 	// syncFlowConsumer is a special outbox which instead of sending rows to
 	// another host, returns them directly (as a result to a SetupSyncFlow RPC,
@@ -188,7 +199,11 @@ func (f *Flow) setupOutboundStream(spec StreamEndpointSpec) (RowReceiver, error)
 	}
 }
 
-func (f *Flow) setupRouter(spec *OutputRouterSpec) (RowReceiver, error) {
+// setupRouter sets up an output router. If stats is set, the statistics of the
+// output streams are collected.
+func (f *Flow) setupRouter(
+	spec *OutputRouterSpec, stats *processorStatsCollector,
+) (RowReceiver, error) {
 	streams := make([]RowReceiver, len(spec.Streams))
 	for i := range spec.Streams {
 		var err error
@@ -196,6 +211,9 @@ func (f *Flow) setupRouter(spec *OutputRouterSpec) (RowReceiver, error) {
 		if err != nil {
 			return nil, err
 		}
+		if stats != nil {
+			streams[i] = stats.wrapStream(spec.Streams[i].StreamID, streams[i])
+		}
 	}
 	return makeRouter(spec, streams)
 }
@@ -210,19 +228,35 @@ func checkNumInOut(inputs []RowSource, outputs []RowReceiver, numIn, numOut int)
 	return nil
 }
 
-func (f *Flow) makeProcessor(ps *ProcessorSpec, inputs []RowSource) (processor, error) {
+func (f *Flow) makeProcessor(ps *ProcessorSpec, inputs []RowSource) (Processor, error) {
 	if len(ps.Output) != 1 {
 		return nil, errors.Errorf("only single-output processors supported")
 	}
+	var stats *processorStatsCollector
+	if f.collectStats {
+		stats = newProcessorStatsCollector(ps.ProcessorID)
+		stats.wrapInputs(inputs)
+	}
 	outputs := make([]RowReceiver, len(ps.Output))
 	for i := range ps.Output {
 		var err error
-		outputs[i], err = f.setupRouter(&ps.Output[i])
+		outputs[i], err = f.setupRouter(&ps.Output[i], stats)
 		if err != nil {
 			return nil, err
 		}
 	}
-	return newProcessor(&f.FlowCtx, &ps.Core, &ps.Post, inputs, outputs)
+	if stats != nil {
+		stats.RowReceiver = outputs[0]
+		outputs[0] = stats
+	}
+	proc, err := newProcessor(&f.FlowCtx, &ps.Core, &ps.Post, inputs, outputs)
+	if err != nil {
+		return nil, err
+	}
+	if stats != nil {
+		stats.proc = proc
+	}
+	return proc, nil
 }
 
 func (f *Flow) setupFlow(ctx context.Context, spec *FlowSpec) error {
@@ -277,7 +311,7 @@ func (f *Flow) setupFlow(ctx context.Context, spec *FlowSpec) error {
 		}
 	}
 
-	f.processors = make([]processor, len(spec.Processors))
+	f.processors = make([]Processor, len(spec.Processors))
 
 	for i := range spec.Processors {
 		var err error
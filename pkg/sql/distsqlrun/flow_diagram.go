@@ -25,6 +25,7 @@ import (
 	"io"
 	"net/url"
 	"sort"
+	"time"
 
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	humanize "github.com/dustin/go-humanize"
@@ -145,6 +146,56 @@ func (bf *BackfillerSpec) summary() (string, []string) {
 	return "Backfiller", details
 }
 
+func (rc *ReadCSVSpec) summary() (string, []string) {
+	name, details := "ReadCSV", []string{rc.TableDesc.Name}
+	if rc.Format == ReadCSVSpec_PGDUMP {
+		name, details = "ReadPGDUMP", []string{fmt.Sprintf("Tables: %d", len(rc.Tables))}
+	}
+	details = append(details, fmt.Sprintf("Files: %d", len(rc.URIs)))
+	if rc.SampleSize != 0 {
+		details = append(details, fmt.Sprintf("Sample size: %d", rc.SampleSize))
+	}
+	return name, details
+}
+
+func (sw *SSTWriterSpec) summary() (string, []string) {
+	details := []string{
+		sw.Destination,
+		fmt.Sprintf("Spans: %d", len(sw.Spans)),
+	}
+	return "SSTWriter", details
+}
+
+func (cw *CSVWriterSpec) summary() (string, []string) {
+	details := []string{
+		cw.Destination,
+		cw.Name,
+	}
+	return "CSVWriter", details
+}
+
+func sketchesSummary(sketches []SketchSpec) []string {
+	details := make([]string, 0, len(sketches))
+	for _, sk := range sketches {
+		str := fmt.Sprintf("sketch %s", colListStr(sk.Columns))
+		if sk.GenerateHistogram {
+			str += fmt.Sprintf(" (histogram: %d buckets)", sk.HistogramMaxBuckets)
+		}
+		details = append(details, str)
+	}
+	return details
+}
+
+func (s *SamplerSpec) summary() (string, []string) {
+	details := append(sketchesSummary(s.Sketches), fmt.Sprintf("Sample size: %d", s.SampleSize))
+	return "Sampler", details
+}
+
+func (s *SampleAggregatorSpec) summary() (string, []string) {
+	details := append(sketchesSummary(s.Sketches), fmt.Sprintf("Sample size: %d", s.SampleSize))
+	return "SampleAggregator", details
+}
+
 func (d *DistinctSpec) summary() (string, []string) {
 	details := []string{
 		colListStr(d.DistinctColumns),
@@ -214,6 +265,34 @@ func (post *PostProcessSpec) summary() []string {
 	return res
 }
 
+func (s *ProcessorStats) summary() []string {
+	var res []string
+	if len(s.InputRows) > 0 {
+		var buf bytes.Buffer
+		buf.WriteString("Rows in:")
+		for _, rows := range s.InputRows {
+			fmt.Fprintf(&buf, " %d", rows)
+		}
+		res = append(res, buf.String())
+		res = append(res, fmt.Sprintf("Stall time: %s", time.Duration(s.StallTimeNanos)))
+	}
+	res = append(res, fmt.Sprintf("Rows out: %d", s.OutputRows))
+	if s.BytesRead > 0 {
+		res = append(res, fmt.Sprintf("KV bytes read: %s", humanize.IBytes(uint64(s.BytesRead))))
+	}
+	if s.MaxMemoryBytes > 0 {
+		res = append(res, fmt.Sprintf("Max memory: %s", humanize.IBytes(uint64(s.MaxMemoryBytes))))
+	}
+	return res
+}
+
+func (s *StreamStats) summary() []string {
+	return []string{
+		fmt.Sprintf("Rows: %d", s.Rows),
+		fmt.Sprintf("Stall time: %s", time.Duration(s.StallTimeNanos)),
+	}
+}
+
 type diagramCell struct {
 	Title   string   `json:"title"`
 	Details []string `json:"details"`
@@ -227,10 +306,11 @@ type diagramProcessor struct {
 }
 
 type diagramEdge struct {
-	SourceProc   int `json:"sourceProc"`
-	SourceOutput int `json:"sourceOutput"`
-	DestProc     int `json:"destProc"`
-	DestInput    int `json:"destInput"`
+	SourceProc   int      `json:"sourceProc"`
+	SourceOutput int      `json:"sourceOutput"`
+	DestProc     int      `json:"destProc"`
+	DestInput    int      `json:"destInput"`
+	Stats        []string `json:"stats,omitempty"`
 }
 
 type diagramData struct {
@@ -239,7 +319,9 @@ type diagramData struct {
 	Edges      []diagramEdge      `json:"edges"`
 }
 
-func generateDiagramData(flows []FlowSpec, nodeNames []string) (diagramData, error) {
+func generateDiagramData(
+	flows []FlowSpec, nodeNames []string, stats map[int32]ProcessorStats,
+) (diagramData, error) {
 	d := diagramData{NodeNames: nodeNames}
 
 	// inPorts maps streams to their "destination" attachment point. Only DestProc
@@ -253,6 +335,9 @@ func generateDiagramData(flows []FlowSpec, nodeNames []string) (diagramData, err
 			proc := diagramProcessor{NodeIdx: n}
 			proc.Core.Title, proc.Core.Details = p.Core.GetValue().(diagramCellType).summary()
 			proc.Core.Details = append(proc.Core.Details, p.Post.summary()...)
+			if s, ok := stats[p.ProcessorID]; ok {
+				proc.Core.Details = append(proc.Core.Details, s.summary()...)
+			}
 
 			// We need explicit synchronizers if we have multiple inputs, or if the
 			// one input has multiple input streams.
@@ -317,6 +402,8 @@ func generateDiagramData(flows []FlowSpec, nodeNames []string) (diagramData, err
 	pIdx = 0
 	for n := range flows {
 		for _, p := range flows[n].Processors {
+			// The stream stats are in the order of the streams of the outputs.
+			streamStats := stats[p.ProcessorID].OutputStreams
 			for i, output := range p.Output {
 				srcOutput := 0
 				if len(d.Processors[pIdx].Outputs) > 0 {
@@ -327,6 +414,10 @@ func generateDiagramData(flows []FlowSpec, nodeNames []string) (diagramData, err
 						SourceProc:   pIdx,
 						SourceOutput: srcOutput,
 					}
+					if len(streamStats) > 0 {
+						edge.Stats = streamStats[0].summary()
+						streamStats = streamStats[1:]
+					}
 					if o.Type == StreamEndpointSpec_SYNC_RESPONSE {
 						edge.DestProc = len(d.Processors) - 1
 					} else {
@@ -348,8 +439,11 @@ func generateDiagramData(flows []FlowSpec, nodeNames []string) (diagramData, err
 
 // GeneratePlanDiagram generates the json data for a flow diagram.  There should
 // be one FlowSpec per node. The function assumes that StreamIDs are unique
-// across all flows.
-func GeneratePlanDiagram(flows map[roachpb.NodeID]FlowSpec, w io.Writer) error {
+// across all flows. If stats is set, the runtime statistics of the processors
+// (keyed by processor ID) are shown on the diagram.
+func GeneratePlanDiagram(
+	flows map[roachpb.NodeID]FlowSpec, stats map[int32]ProcessorStats, w io.Writer,
+) error {
 	// We sort the flows by node because we want the diagram data to be
 	// deterministic.
 	nodeIDs := make([]int, 0, len(flows))
@@ -367,7 +461,7 @@ func GeneratePlanDiagram(flows map[roachpb.NodeID]FlowSpec, w io.Writer) error {
 		nodeNames[i] = n.String()
 	}
 
-	d, err := generateDiagramData(flowSlice, nodeNames)
+	d, err := generateDiagramData(flowSlice, nodeNames, stats)
 	if err != nil {
 		return err
 	}
@@ -376,10 +470,14 @@ func GeneratePlanDiagram(flows map[roachpb.NodeID]FlowSpec, w io.Writer) error {
 
 // GeneratePlanDiagramWithURL generates the json data for a flow diagram and a
 // URL which encodes the diagram. There should be one FlowSpec per node. The
-// function assumes that StreamIDs are unique across all flows.
-func GeneratePlanDiagramWithURL(flows map[roachpb.NodeID]FlowSpec) (string, url.URL, error) {
+// function assumes that StreamIDs are unique across all flows. If stats is set,
+// the runtime statistics of the processors (keyed by processor ID) are shown on
+// the diagram.
+func GeneratePlanDiagramWithURL(
+	flows map[roachpb.NodeID]FlowSpec, stats map[int32]ProcessorStats,
+) (string, url.URL, error) {
 	var json, compressed bytes.Buffer
-	if err := GeneratePlanDiagram(flows, &json); err != nil {
+	if err := GeneratePlanDiagram(flows, stats, &json); err != nil {
 		return "", url.URL{}, err
 	}
 	jsonStr := json.String()
@@ -125,7 +125,7 @@ func TestPlanDiagramIndexJoin(t *testing.T) {
 		},
 	}
 
-	json, url, err := GeneratePlanDiagramWithURL(flows)
+	json, url, err := GeneratePlanDiagramWithURL(flows, nil /* stats */)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -332,7 +332,7 @@ func TestPlanDiagramJoin(t *testing.T) {
 	}
 
 	var buf bytes.Buffer
-	if err := GeneratePlanDiagram(flows, &buf); err != nil {
+	if err := GeneratePlanDiagram(flows, nil /* stats */, &buf); err != nil {
 		t.Fatal(err)
 	}
 
@@ -49,9 +49,11 @@ type hashJoiner struct {
 	rightEqCols columns
 	buckets     map[string]bucket
 	datumAlloc  sqlbase.DatumAlloc
+	// mem tracks the size of the buckets.
+	mem memUsage
 }
 
-var _ processor = &hashJoiner{}
+var _ Processor = &hashJoiner{}
 
 func newHashJoiner(
 	flowCtx *FlowCtx,
@@ -76,7 +78,7 @@ func newHashJoiner(
 	return h, nil
 }
 
-// Run is part of the processor interface.
+// Run is part of the Processor interface.
 func (h *hashJoiner) Run(ctx context.Context, wg *sync.WaitGroup) {
 	if wg != nil {
 		defer wg.Done()
@@ -175,9 +177,13 @@ func (h *hashJoiner) buildPhase(ctx context.Context) (bool, error) {
 			continue
 		}
 
-		b := h.buckets[string(encoded)]
+		b, ok := h.buckets[string(encoded)]
+		if !ok {
+			h.mem.grow(int64(len(encoded)))
+		}
 		b.rows = append(b.rows, rrow)
 		h.buckets[string(encoded)] = b
+		h.mem.grow(int64(rrow.Size()))
 	}
 }
 
@@ -279,6 +285,11 @@ func (h *hashJoiner) probePhase(ctx context.Context) (bool, error) {
 	return false, nil
 }
 
+// fillStats is part of the statsReporter interface.
+func (h *hashJoiner) fillStats(stats *ProcessorStats) {
+	stats.MaxMemoryBytes = h.mem.max
+}
+
 // encodeColumnsOfRow returns the encoding for the grouping columns. This is
 // then used as our group key to determine which bucket to add to.
 // If the row contains any NULLs and encodeNull is false, hasNull is true and
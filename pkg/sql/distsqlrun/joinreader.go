@@ -45,7 +45,7 @@ type joinReader struct {
 	out   procOutputHelper
 }
 
-var _ processor = &joinReader{}
+var _ Processor = &joinReader{}
 
 func newJoinReader(
 	flowCtx *FlowCtx,
@@ -136,6 +136,7 @@ func (jr *joinReader) mainLoop(ctx context.Context) error {
 				if len(spans) == 0 {
 					// No fetching needed since we have collected no spans and
 					// the input has signalled that no more records are coming.
+					jr.sendReadStatsMetadata()
 					jr.out.close()
 					return nil
 				}
@@ -180,13 +181,28 @@ func (jr *joinReader) mainLoop(ctx context.Context) error {
 
 		if len(spans) != joinReaderBatchSize {
 			// This was the last batch.
+			jr.sendReadStatsMetadata()
 			jr.out.close()
 			return nil
 		}
 	}
 }
 
-// Run is part of the processor interface.
+// sendReadStatsMetadata sends the number of rows and bytes read by this
+// joinReader over all its batches.
+func (jr *joinReader) sendReadStatsMetadata() {
+	rowsRead, bytesRead := jr.fetcher.ReadStats()
+	jr.out.output.Push(nil /* row */, ProducerMetadata{
+		ReadStats: &ReadStats{RowsRead: rowsRead, BytesRead: bytesRead},
+	})
+}
+
+// fillStats is part of the statsReporter interface.
+func (jr *joinReader) fillStats(stats *ProcessorStats) {
+	_, stats.BytesRead = jr.fetcher.ReadStats()
+}
+
+// Run is part of the Processor interface.
 func (jr *joinReader) Run(ctx context.Context, wg *sync.WaitGroup) {
 	if wg != nil {
 		defer wg.Done()
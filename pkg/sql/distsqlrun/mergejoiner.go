@@ -36,7 +36,7 @@ type mergeJoiner struct {
 	streamMerger streamMerger
 }
 
-var _ processor = &mergeJoiner{}
+var _ Processor = &mergeJoiner{}
 
 func newMergeJoiner(
 	flowCtx *FlowCtx,
@@ -72,7 +72,7 @@ func newMergeJoiner(
 	return m, nil
 }
 
-// Run is part of the processor interface.
+// Run is part of the Processor interface.
 func (m *mergeJoiner) Run(ctx context.Context, wg *sync.WaitGroup) {
 	if wg != nil {
 		defer wg.Done()
@@ -29,9 +29,9 @@ import (
 	"github.com/pkg/errors"
 )
 
-// processor is a common interface implemented by all processors, used by the
+// Processor is a common interface implemented by all processors, used by the
 // higher-level flow orchestration code.
-type processor interface {
+type Processor interface {
 	// Run is the main loop of the processor.
 	// If wg is non-nil, wg.Done is called before exiting.
 	Run(ctx context.Context, wg *sync.WaitGroup)
@@ -290,7 +290,7 @@ type noopProcessor struct {
 	out     procOutputHelper
 }
 
-var _ processor = &noopProcessor{}
+var _ Processor = &noopProcessor{}
 
 func newNoopProcessor(
 	flowCtx *FlowCtx, input RowSource, post *PostProcessSpec, output RowReceiver,
@@ -302,7 +302,7 @@ func newNoopProcessor(
 	return n, nil
 }
 
-// Run is part of the processor interface.
+// Run is part of the Processor interface.
 func (n *noopProcessor) Run(ctx context.Context, wg *sync.WaitGroup) {
 	if wg != nil {
 		defer wg.Done()
@@ -328,7 +328,7 @@ func newProcessor(
 	post *PostProcessSpec,
 	inputs []RowSource,
 	outputs []RowReceiver,
-) (processor, error) {
+) (Processor, error) {
 	if core.Noop != nil {
 		if err := checkNumInOut(inputs, outputs, 1, 1); err != nil {
 			return nil, err
@@ -402,5 +402,56 @@ func newProcessor(
 		}
 		return newAlgebraicSetOp(flowCtx, core.SetOp, inputs[0], inputs[1], post, outputs[0])
 	}
+	if core.Sampler != nil {
+		if err := checkNumInOut(inputs, outputs, 1, 1); err != nil {
+			return nil, err
+		}
+		return newSamplerProcessor(flowCtx, core.Sampler, inputs[0], post, outputs[0])
+	}
+	if core.SampleAggregator != nil {
+		if err := checkNumInOut(inputs, outputs, 1, 1); err != nil {
+			return nil, err
+		}
+		return newSampleAggregator(flowCtx, core.SampleAggregator, inputs[0], post, outputs[0])
+	}
+	if core.ReadCSV != nil {
+		if err := checkNumInOut(inputs, outputs, 0, 1); err != nil {
+			return nil, err
+		}
+		if NewReadCSVProcessor == nil {
+			return nil, errors.New("ReadCSV processor unimplemented")
+		}
+		return NewReadCSVProcessor(flowCtx, *core.ReadCSV, outputs[0])
+	}
+	if core.SSTWriter != nil {
+		if err := checkNumInOut(inputs, outputs, 1, 1); err != nil {
+			return nil, err
+		}
+		if NewSSTWriterProcessor == nil {
+			return nil, errors.New("SSTWriter processor unimplemented")
+		}
+		return NewSSTWriterProcessor(flowCtx, *core.SSTWriter, inputs[0], outputs[0])
+	}
+	if core.CSVWriter != nil {
+		if err := checkNumInOut(inputs, outputs, 1, 1); err != nil {
+			return nil, err
+		}
+		if NewCSVWriterProcessor == nil {
+			return nil, errors.New("CSVWriter processor unimplemented")
+		}
+		return NewCSVWriterProcessor(flowCtx, *core.CSVWriter, inputs[0], outputs[0])
+	}
 	return nil, errors.Errorf("unsupported processor core %s", core)
 }
+
+// NewReadCSVProcessor is externally implemented and registered by
+// ccl/sqlccl/csv.go.
+var NewReadCSVProcessor func(*FlowCtx, ReadCSVSpec, RowReceiver) (Processor, error)
+
+// NewSSTWriterProcessor is externally implemented and registered by
+// ccl/sqlccl/csv.go.
+var NewSSTWriterProcessor func(*FlowCtx, SSTWriterSpec, RowSource, RowReceiver) (Processor, error)
+
+// NewCSVWriterProcessor is externally implemented and registered by
+// ccl/sqlccl/export.go.
+var NewCSVWriterProcessor func(*FlowCtx, CSVWriterSpec, RowSource, RowReceiver) (Processor, error)
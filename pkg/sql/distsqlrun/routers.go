@@ -22,12 +22,15 @@
 package distsqlrun
 
 import (
+	"bytes"
 	"hash/crc32"
+	"sort"
 
 	"golang.org/x/net/context"
 
 	"github.com/pkg/errors"
 
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 )
@@ -51,6 +54,9 @@ func makeRouter(spec *OutputRouterSpec, streams []RowReceiver) (RowReceiver, err
 	case OutputRouterSpec_MIRROR:
 		return makeMirrorRouter(streams)
 
+	case OutputRouterSpec_BY_RANGE:
+		return makeRangeRouter(spec.RangeSpans, streams)
+
 	default:
 		return nil, errors.Errorf("router type %s not supported", spec.Type)
 	}
@@ -91,8 +97,18 @@ type hashRouter struct {
 	alloc    sqlbase.DatumAlloc
 }
 
+// rangeRouter sends each row to the stream of the span containing the value
+// of its first column.
+type rangeRouter struct {
+	routerBase
+
+	spans []OutputRouterSpec_RangeSpan
+	alloc sqlbase.DatumAlloc
+}
+
 var _ RowReceiver = &hashRouter{}
 var _ RowReceiver = &mirrorRouter{}
+var _ RowReceiver = &rangeRouter{}
 
 var crc32Table = crc32.MakeTable(crc32.Castagnoli)
 
@@ -118,6 +134,29 @@ func makeHashRouter(hashCols []uint32, streams []RowReceiver) (*hashRouter, erro
 	}, nil
 }
 
+func makeRangeRouter(
+	spans []OutputRouterSpec_RangeSpan, streams []RowReceiver,
+) (*rangeRouter, error) {
+	if len(spans) == 0 {
+		return nil, errors.Errorf("no spans for BY_RANGE router")
+	}
+	for i, span := range spans {
+		if span.Stream < 0 || int(span.Stream) >= len(streams) {
+			return nil, errors.Errorf("span %d: invalid stream %d", i, span.Stream)
+		}
+		if i > 0 && bytes.Compare(spans[i-1].End, span.End) >= 0 {
+			return nil, errors.Errorf("span %d: spans out of order", i)
+		}
+	}
+	if spans[len(spans)-1].End != nil {
+		return nil, errors.Errorf("last span of BY_RANGE router must be unbounded")
+	}
+	return &rangeRouter{
+		routerBase: makeRouterBase(streams),
+		spans:      spans,
+	}, nil
+}
+
 // ProducerDone is part of the RowReceiver interface.
 func (rb *routerBase) ProducerDone() {
 	for _, s := range rb.streams {
@@ -232,3 +271,51 @@ func (hr *hashRouter) computeDestination(row sqlbase.EncDatumRow) (int, error) {
 	// accelerated).
 	return int(crc32.Update(0, crc32Table, hr.buffer) % uint32(len(hr.streams))), nil
 }
+
+// Push is part of the RowReceiver interface.
+//
+// If the row needs to go to a consumer that's draining or closed, the row is
+// silently dropped.
+func (rr *rangeRouter) Push(row sqlbase.EncDatumRow, meta ProducerMetadata) ConsumerStatus {
+	if !meta.Empty() {
+		rr.fwdMetadata(meta)
+		return rr.aggregatedStatus
+	}
+	if rr.aggregatedStatus != NeedMoreRows {
+		return rr.aggregatedStatus
+	}
+
+	streamIdx, err := rr.computeDestination(row)
+	if err != nil {
+		rr.fwdMetadata(ProducerMetadata{Err: err})
+		rr.aggregatedStatus = ConsumerClosed
+		return ConsumerClosed
+	}
+
+	if rr.streamStatus[streamIdx] == NeedMoreRows {
+		newStatus := rr.streams[streamIdx].Push(row, ProducerMetadata{})
+		rr.updateStreamState(streamIdx, newStatus)
+	}
+	return rr.aggregatedStatus
+}
+
+// computeDestination returns the index of the output stream on which a row must
+// be sent.
+func (rr *rangeRouter) computeDestination(row sqlbase.EncDatumRow) (int, error) {
+	if len(row) == 0 {
+		return -1, errors.Errorf("range router: row with no columns")
+	}
+	if err := row[0].EnsureDecoded(&rr.alloc); err != nil {
+		return -1, err
+	}
+	b, ok := row[0].Datum.(*parser.DBytes)
+	if !ok {
+		return -1, errors.Errorf("range router: expected BYTES, got %s", row[0].Datum.ResolvedType())
+	}
+	// Find the first span whose end is after the value; the last span is
+	// unbounded.
+	i := sort.Search(len(rr.spans)-1, func(i int) bool {
+		return bytes.Compare([]byte(*b), rr.spans[i].End) < 0
+	})
+	return int(rr.spans[i].Stream), nil
+}
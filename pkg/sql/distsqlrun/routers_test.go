@@ -24,7 +24,9 @@ import (
 
 	"github.com/pkg/errors"
 
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/testutils"
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
 	"github.com/cockroachdb/cockroach/pkg/util/randutil"
 )
@@ -116,6 +118,68 @@ func TestHashRouter(t *testing.T) {
 	}
 }
 
+func TestRangeRouter(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	bytesType := sqlbase.ColumnType{Kind: sqlbase.ColumnType_BYTES}
+	spans := []OutputRouterSpec_RangeSpan{
+		{End: []byte("c"), Stream: 1},
+		{End: []byte("f"), Stream: 0},
+		{End: []byte("k"), Stream: 2},
+		{End: nil, Stream: 1},
+	}
+	const numStreams = 3
+
+	bufs := make([]*RowBuffer, numStreams)
+	recvs := make([]RowReceiver, numStreams)
+	for i := range bufs {
+		bufs[i] = &RowBuffer{}
+		recvs[i] = bufs[i]
+	}
+	rr, err := makeRangeRouter(spans, recvs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]int{
+		"": 1, "a": 1, "bz": 1,
+		"c": 0, "e": 0,
+		"f": 2, "jjj": 2,
+		"k": 1, "zzz": 1,
+	}
+	for key := range expected {
+		row := sqlbase.EncDatumRow{
+			sqlbase.DatumToEncDatum(bytesType, parser.NewDBytes(parser.DBytes(key))),
+		}
+		if status := rr.Push(row, ProducerMetadata{}); status != NeedMoreRows {
+			t.Fatalf("unexpected status: %d", status)
+		}
+	}
+	rr.ProducerDone()
+
+	alloc := &sqlbase.DatumAlloc{}
+	found := 0
+	for i, b := range bufs {
+		for _, row := range getRowsFromBuffer(t, b) {
+			if err := row[0].EnsureDecoded(alloc); err != nil {
+				t.Fatal(err)
+			}
+			key := string(*row[0].Datum.(*parser.DBytes))
+			if expected[key] != i {
+				t.Errorf("key %q: expected stream %d, got %d", key, expected[key], i)
+			}
+			found++
+		}
+	}
+	if found != len(expected) {
+		t.Errorf("expected %d rows, got %d", len(expected), found)
+	}
+
+	if _, err := makeRangeRouter(spans[:3], recvs); !testutils.IsError(err, "must be unbounded") {
+		t.Errorf("expected unbounded error, got %v", err)
+	}
+}
+
 func getRowsFromBuffer(t *testing.T, buf *RowBuffer) sqlbase.EncDatumRows {
 	var res sqlbase.EncDatumRows
 	for {
@@ -0,0 +1,217 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package distsqlrun
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/sql/stats"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/cockroachdb/cockroach/pkg/util/tracing"
+)
+
+// SampleAggregatorColTypes are the types of the output rows of a
+// sampleAggregator; see SampleAggregatorSpec.
+var SampleAggregatorColTypes = []sqlbase.ColumnType{
+	{Kind: sqlbase.ColumnType_INT},   // row_count
+	{Kind: sqlbase.ColumnType_INT},   // distinct_count
+	{Kind: sqlbase.ColumnType_INT},   // null_count
+	{Kind: sqlbase.ColumnType_BYTES}, // histogram
+}
+
+// sampleAggregator merges the samples and sketches output by samplers and
+// computes the resulting statistics; see SampleAggregatorSpec.
+type sampleAggregator struct {
+	flowCtx  *FlowCtx
+	input    RowSource
+	inTypes  []sqlbase.ColumnType
+	sr       sampleReservoir
+	sketches []sketchInfo
+	// numRows is the total number of rows counted by the samplers.
+	numRows int64
+
+	out procOutputHelper
+}
+
+var _ Processor = &sampleAggregator{}
+
+func newSampleAggregator(
+	flowCtx *FlowCtx,
+	spec *SampleAggregatorSpec,
+	input RowSource,
+	post *PostProcessSpec,
+	output RowReceiver,
+) (*sampleAggregator, error) {
+	// The input has the columns sampled by the samplers, followed by the
+	// columns the samplers add.
+	inTypes := input.Types()
+	if len(inTypes) < samplerOutCols {
+		return nil, errors.Errorf("invalid sample aggregator input with %d columns", len(inTypes))
+	}
+	inTypes = inTypes[:len(inTypes)-samplerOutCols]
+	for _, s := range spec.Sketches {
+		if len(s.Columns) == 0 {
+			return nil, errors.New("sketch without columns")
+		}
+		for _, col := range s.Columns {
+			if int(col) >= len(inTypes) {
+				return nil, errors.Errorf("invalid sketch column %d", col)
+			}
+		}
+		if s.GenerateHistogram && s.HistogramMaxBuckets == 0 {
+			return nil, errors.New("histogram requested without a number of buckets")
+		}
+	}
+
+	s := &sampleAggregator{
+		flowCtx: flowCtx,
+		input:   input,
+		inTypes: inTypes,
+		sr:      sampleReservoir{size: int(spec.SampleSize)},
+	}
+	for _, spec := range spec.Sketches {
+		s.sketches = append(s.sketches, sketchInfo{spec: spec, sketch: stats.NewHyperLogLog()})
+	}
+	if err := s.out.init(post, SampleAggregatorColTypes, &flowCtx.evalCtx, output); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Run is part of the Processor interface.
+func (s *sampleAggregator) Run(ctx context.Context, wg *sync.WaitGroup) {
+	if wg != nil {
+		defer wg.Done()
+	}
+
+	ctx = log.WithLogTag(ctx, "SampleAggregator", nil)
+	ctx, span := tracing.ChildSpan(ctx, "sample aggregator")
+	defer tracing.FinishSpan(span)
+
+	earlyExit, err := s.mainLoop(ctx)
+	if err != nil {
+		DrainAndClose(ctx, s.out.output, err, s.input)
+	} else if !earlyExit {
+		s.input.ConsumerClosed()
+		s.out.close()
+	}
+}
+
+// mainLoop consumes the input and emits the results. If earlyExit is true,
+// the output and the input have already been closed.
+func (s *sampleAggregator) mainLoop(ctx context.Context) (earlyExit bool, _ error) {
+	var da sqlbase.DatumAlloc
+	rankCol := len(s.inTypes)
+	for {
+		row, meta := s.input.Next()
+		if !meta.Empty() {
+			if meta.Err != nil {
+				return false, meta.Err
+			}
+			if !emitHelper(ctx, &s.out, nil /* row */, meta, s.input) {
+				return true, nil
+			}
+			continue
+		}
+		if row == nil {
+			break
+		}
+		for i := rankCol; i < len(row); i++ {
+			if err := row[i].EnsureDecoded(&da); err != nil {
+				return false, err
+			}
+		}
+		if row[rankCol].Datum != parser.DNull {
+			// This is a sampled row.
+			rank := int64(*row[rankCol].Datum.(*parser.DInt))
+			s.sr.sampleRow(row[:rankCol], rank)
+			continue
+		}
+		// This is a sketch row.
+		sketchIdx := int(*row[rankCol+1].Datum.(*parser.DInt))
+		if sketchIdx < 0 || sketchIdx >= len(s.sketches) {
+			return false, errors.Errorf("invalid sketch index %d", sketchIdx)
+		}
+		numRows := int64(*row[rankCol+2].Datum.(*parser.DInt))
+		if sketchIdx == 0 {
+			// All the sketches of a sampler count the same rows.
+			s.numRows += numRows
+		}
+		si := &s.sketches[sketchIdx]
+		si.numNulls += int64(*row[rankCol+3].Datum.(*parser.DInt))
+		var sketch stats.HyperLogLog
+		if err := sketch.UnmarshalBinary([]byte(*row[rankCol+4].Datum.(*parser.DBytes))); err != nil {
+			return false, err
+		}
+		si.sketch.Merge(&sketch)
+	}
+
+	outRow := make(sqlbase.EncDatumRow, len(SampleAggregatorColTypes))
+	for _, si := range s.sketches {
+		distinctCount := int64(si.sketch.Estimate())
+		// The estimate can't exceed the number of non-NULL values.
+		if nonNulls := s.numRows - si.numNulls; distinctCount > nonNulls {
+			distinctCount = nonNulls
+		}
+		var histogram parser.Datum = parser.DNull
+		if si.spec.GenerateHistogram {
+			h, err := s.generateHistogram(&da, si)
+			if err != nil {
+				return false, err
+			}
+			data, err := protoutil.Marshal(&h)
+			if err != nil {
+				return false, err
+			}
+			histogram = parser.NewDBytes(parser.DBytes(data))
+		}
+		outRow[0] = sqlbase.DatumToEncDatum(SampleAggregatorColTypes[0], parser.NewDInt(parser.DInt(s.numRows)))
+		outRow[1] = sqlbase.DatumToEncDatum(SampleAggregatorColTypes[1], parser.NewDInt(parser.DInt(distinctCount)))
+		outRow[2] = sqlbase.DatumToEncDatum(SampleAggregatorColTypes[2], parser.NewDInt(parser.DInt(si.numNulls)))
+		outRow[3] = sqlbase.DatumToEncDatum(SampleAggregatorColTypes[3], histogram)
+		if !emitHelper(ctx, &s.out, outRow, ProducerMetadata{}, s.input) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// generateHistogram builds the histogram of the first column of a sketch
+// from the non-NULL values of the merged sample.
+func (s *sampleAggregator) generateHistogram(
+	da *sqlbase.DatumAlloc, si sketchInfo,
+) (stats.HistogramData, error) {
+	col := si.spec.Columns[0]
+	var values parser.Datums
+	for _, sample := range s.sr.samples {
+		ed := &sample.row[col]
+		if err := ed.EnsureDecoded(da); err != nil {
+			return stats.HistogramData{}, err
+		}
+		if ed.Datum != parser.DNull {
+			values = append(values, ed.Datum)
+		}
+	}
+	return stats.EquiDepthHistogram(
+		&s.flowCtx.evalCtx, s.inTypes[col], values, s.numRows-si.numNulls,
+		int(si.spec.HistogramMaxBuckets),
+	)
+}
@@ -0,0 +1,257 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package distsqlrun
+
+import (
+	"container/heap"
+	"math/rand"
+	"sync"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/sql/stats"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/cockroach/pkg/util/tracing"
+)
+
+// samplerOutCols are the columns that the sampler adds after the input
+// columns; see SamplerSpec.
+const samplerOutCols = 5
+
+// SamplerColTypes returns the types of the output rows of a sampler with the
+// given input types.
+func SamplerColTypes(inTypes []sqlbase.ColumnType) []sqlbase.ColumnType {
+	outTypes := append([]sqlbase.ColumnType(nil), inTypes...)
+	return append(outTypes,
+		sqlbase.ColumnType{Kind: sqlbase.ColumnType_INT},   // rank
+		sqlbase.ColumnType{Kind: sqlbase.ColumnType_INT},   // sketch_idx
+		sqlbase.ColumnType{Kind: sqlbase.ColumnType_INT},   // num_rows
+		sqlbase.ColumnType{Kind: sqlbase.ColumnType_INT},   // num_nulls
+		sqlbase.ColumnType{Kind: sqlbase.ColumnType_BYTES}, // sketch
+	)
+}
+
+// sampledRow is a row kept in a sampleReservoir, with its random rank.
+type sampledRow struct {
+	row  sqlbase.EncDatumRow
+	rank int64
+}
+
+// sampleReservoir keeps the rows with the lowest ranks among the rows added
+// to it. When the ranks are random, the rows form a uniform sample of the
+// added rows.
+//
+// It implements heap.Interface as a max-heap by rank, so that the row with
+// the highest rank is replaced first.
+type sampleReservoir struct {
+	size    int
+	samples []sampledRow
+	alloc   sqlbase.EncDatumRowAlloc
+}
+
+var _ heap.Interface = &sampleReservoir{}
+
+func (sr *sampleReservoir) Len() int           { return len(sr.samples) }
+func (sr *sampleReservoir) Less(i, j int) bool { return sr.samples[i].rank > sr.samples[j].rank }
+func (sr *sampleReservoir) Swap(i, j int) {
+	sr.samples[i], sr.samples[j] = sr.samples[j], sr.samples[i]
+}
+
+// Push is part of the heap.Interface.
+func (sr *sampleReservoir) Push(x interface{}) { panic("unimplemented") }
+
+// Pop is part of the heap.Interface.
+func (sr *sampleReservoir) Pop() interface{} { panic("unimplemented") }
+
+// sampleRow adds a row to the reservoir if its rank is among the lowest ones.
+// The row is copied.
+func (sr *sampleReservoir) sampleRow(row sqlbase.EncDatumRow, rank int64) {
+	if len(sr.samples) < sr.size {
+		sr.samples = append(sr.samples, sampledRow{row: sr.alloc.CopyRow(row), rank: rank})
+		if len(sr.samples) == sr.size {
+			heap.Init(sr)
+		}
+		return
+	}
+	if sr.size > 0 && rank < sr.samples[0].rank {
+		sr.samples[0] = sampledRow{row: sr.alloc.CopyRow(row), rank: rank}
+		heap.Fix(sr, 0)
+	}
+}
+
+// sketchInfo holds the state of the sketch of a SketchSpec.
+type sketchInfo struct {
+	spec     SketchSpec
+	sketch   *stats.HyperLogLog
+	numNulls int64
+}
+
+// addRow adds the values of the sketch columns of a row to the sketch, or
+// counts the row as NULL. The buffer is used to encode the values and
+// returned for reuse.
+func (s *sketchInfo) addRow(
+	row sqlbase.EncDatumRow, da *sqlbase.DatumAlloc, buf []byte,
+) ([]byte, error) {
+	buf = buf[:0]
+	for _, col := range s.spec.Columns {
+		if row[col].IsNull() {
+			s.numNulls++
+			return buf, nil
+		}
+		var err error
+		buf, err = row[col].Encode(da, sqlbase.DatumEncoding_ASCENDING_KEY, buf)
+		if err != nil {
+			return buf, err
+		}
+	}
+	s.sketch.InsertBytes(buf)
+	return buf, nil
+}
+
+// samplerProcessor computes a sample of its input rows and the sketches of
+// its SketchSpecs; see SamplerSpec.
+type samplerProcessor struct {
+	flowCtx  *FlowCtx
+	input    RowSource
+	sr       sampleReservoir
+	sketches []sketchInfo
+	rng      *rand.Rand
+	outTypes []sqlbase.ColumnType
+
+	out procOutputHelper
+}
+
+var _ Processor = &samplerProcessor{}
+
+func newSamplerProcessor(
+	flowCtx *FlowCtx, spec *SamplerSpec, input RowSource, post *PostProcessSpec, output RowReceiver,
+) (*samplerProcessor, error) {
+	inTypes := input.Types()
+	for _, s := range spec.Sketches {
+		if len(s.Columns) == 0 {
+			return nil, errors.New("sketch without columns")
+		}
+		for _, col := range s.Columns {
+			if int(col) >= len(inTypes) {
+				return nil, errors.Errorf("invalid sketch column %d", col)
+			}
+		}
+	}
+
+	s := &samplerProcessor{
+		flowCtx:  flowCtx,
+		input:    input,
+		sr:       sampleReservoir{size: int(spec.SampleSize)},
+		rng:      rand.New(rand.NewSource(timeutil.Now().UnixNano())),
+		outTypes: SamplerColTypes(inTypes),
+	}
+	for _, spec := range spec.Sketches {
+		s.sketches = append(s.sketches, sketchInfo{spec: spec, sketch: stats.NewHyperLogLog()})
+	}
+	if err := s.out.init(post, s.outTypes, &flowCtx.evalCtx, output); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Run is part of the Processor interface.
+func (s *samplerProcessor) Run(ctx context.Context, wg *sync.WaitGroup) {
+	if wg != nil {
+		defer wg.Done()
+	}
+
+	ctx = log.WithLogTag(ctx, "Sampler", nil)
+	ctx, span := tracing.ChildSpan(ctx, "sampler")
+	defer tracing.FinishSpan(span)
+
+	earlyExit, err := s.mainLoop(ctx)
+	if err != nil {
+		DrainAndClose(ctx, s.out.output, err, s.input)
+	} else if !earlyExit {
+		s.input.ConsumerClosed()
+		s.out.close()
+	}
+}
+
+// mainLoop consumes the input and emits the results. If earlyExit is true,
+// the output and the input have already been closed.
+func (s *samplerProcessor) mainLoop(ctx context.Context) (earlyExit bool, _ error) {
+	var da sqlbase.DatumAlloc
+	var buf []byte
+	var numRows int64
+	for {
+		row, meta := s.input.Next()
+		if !meta.Empty() {
+			if meta.Err != nil {
+				return false, meta.Err
+			}
+			if !emitHelper(ctx, &s.out, nil /* row */, meta, s.input) {
+				return true, nil
+			}
+			continue
+		}
+		if row == nil {
+			break
+		}
+		numRows++
+		for i := range s.sketches {
+			var err error
+			if buf, err = s.sketches[i].addRow(row, &da, buf); err != nil {
+				return false, err
+			}
+		}
+		s.sr.sampleRow(row, s.rng.Int63())
+	}
+
+	inCols := len(s.input.Types())
+	outRow := make(sqlbase.EncDatumRow, inCols+samplerOutCols)
+	intType := sqlbase.ColumnType{Kind: sqlbase.ColumnType_INT}
+	bytesType := sqlbase.ColumnType{Kind: sqlbase.ColumnType_BYTES}
+
+	// Emit the sampled rows.
+	for i := inCols + 1; i < len(outRow); i++ {
+		outRow[i] = sqlbase.DatumToEncDatum(s.outTypes[i], parser.DNull)
+	}
+	for _, sample := range s.sr.samples {
+		copy(outRow, sample.row)
+		outRow[inCols] = sqlbase.DatumToEncDatum(intType, parser.NewDInt(parser.DInt(sample.rank)))
+		if !emitHelper(ctx, &s.out, outRow, ProducerMetadata{}, s.input) {
+			return true, nil
+		}
+	}
+
+	// Emit the sketches.
+	for i := 0; i <= inCols; i++ {
+		outRow[i] = sqlbase.DatumToEncDatum(s.outTypes[i], parser.DNull)
+	}
+	for i, si := range s.sketches {
+		data, err := si.sketch.MarshalBinary()
+		if err != nil {
+			return false, err
+		}
+		outRow[inCols+1] = sqlbase.DatumToEncDatum(intType, parser.NewDInt(parser.DInt(i)))
+		outRow[inCols+2] = sqlbase.DatumToEncDatum(intType, parser.NewDInt(parser.DInt(numRows)))
+		outRow[inCols+3] = sqlbase.DatumToEncDatum(intType, parser.NewDInt(parser.DInt(si.numNulls)))
+		outRow[inCols+4] = sqlbase.DatumToEncDatum(bytesType, parser.NewDBytes(parser.DBytes(data)))
+		if !emitHelper(ctx, &s.out, outRow, ProducerMetadata{}, s.input) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,184 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package distsqlrun
+
+import (
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/sql/stats"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+// readRows returns the rows pushed to a RowBuffer, and fails the test on any
+// error metadata.
+func readRows(t *testing.T, out *RowBuffer) sqlbase.EncDatumRows {
+	if !out.ProducerClosed {
+		t.Fatalf("output RowReceiver not closed")
+	}
+	var rows sqlbase.EncDatumRows
+	for {
+		row, meta := out.Next()
+		if meta.Err != nil {
+			t.Fatal(meta.Err)
+		}
+		if row == nil && meta.Empty() {
+			return rows
+		}
+		if row != nil {
+			rows = append(rows, row)
+		}
+	}
+}
+
+func TestSamplerAndSampleAggregator(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	const numRows, numSamplers, sampleSize = 1000, 2, 100
+	// The first column has distinct values; the second one has 8 distinct
+	// values, and a NULL in every fifth row.
+	intType := sqlbase.ColumnType{Kind: sqlbase.ColumnType_INT}
+	types := []sqlbase.ColumnType{intType, intType}
+	inputs := make([]sqlbase.EncDatumRows, numSamplers)
+	for i := 0; i < numRows; i++ {
+		var v parser.Datum = parser.DNull
+		if i%5 != 0 {
+			v = parser.NewDInt(parser.DInt(i % 10))
+		}
+		row := sqlbase.EncDatumRow{
+			sqlbase.DatumToEncDatum(intType, parser.NewDInt(parser.DInt(i))),
+			sqlbase.DatumToEncDatum(intType, v),
+		}
+		inputs[i%numSamplers] = append(inputs[i%numSamplers], row)
+	}
+
+	sketches := []SketchSpec{
+		{Columns: []uint32{0}, GenerateHistogram: true, HistogramMaxBuckets: 4},
+		{Columns: []uint32{1}},
+	}
+	flowCtx := &FlowCtx{}
+
+	var samplerRows sqlbase.EncDatumRows
+	for _, input := range inputs {
+		out := &RowBuffer{}
+		spec := &SamplerSpec{Sketches: sketches, SampleSize: sampleSize}
+		p, err := newSamplerProcessor(
+			flowCtx, spec, NewRowBuffer(types, input, RowBufferArgs{}), &PostProcessSpec{}, out,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.Run(context.Background(), nil)
+		rows := readRows(t, out)
+		if len(rows) != sampleSize+len(sketches) {
+			t.Fatalf("expected %d rows from sampler, got %d", sampleSize+len(sketches), len(rows))
+		}
+		samplerRows = append(samplerRows, rows...)
+	}
+
+	out := &RowBuffer{}
+	spec := &SampleAggregatorSpec{Sketches: sketches, SampleSize: sampleSize}
+	agg, err := newSampleAggregator(
+		flowCtx, spec, NewRowBuffer(SamplerColTypes(types), samplerRows, RowBufferArgs{}),
+		&PostProcessSpec{}, out,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	agg.Run(context.Background(), nil)
+	rows := readRows(t, out)
+	if len(rows) != len(sketches) {
+		t.Fatalf("expected %d rows, got %d", len(sketches), len(rows))
+	}
+
+	var da sqlbase.DatumAlloc
+	for i, exp := range []struct {
+		distinct, nulls int64
+	}{
+		{numRows, 0},
+		{8, numRows / 5},
+	} {
+		row := rows[i]
+		for j := range row {
+			if err := row[j].EnsureDecoded(&da); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if rowCount := int64(*row[0].Datum.(*parser.DInt)); rowCount != numRows {
+			t.Errorf("%d: expected row count %d, got %d", i, numRows, rowCount)
+		}
+		distinct := int64(*row[1].Datum.(*parser.DInt))
+		if diff := distinct - exp.distinct; diff < -exp.distinct/20 || diff > exp.distinct/20 {
+			t.Errorf("%d: expected distinct count of about %d, got %d", i, exp.distinct, distinct)
+		}
+		if nulls := int64(*row[2].Datum.(*parser.DInt)); nulls != exp.nulls {
+			t.Errorf("%d: expected null count %d, got %d", i, exp.nulls, nulls)
+		}
+		if !sketches[i].GenerateHistogram {
+			if row[3].Datum != parser.DNull {
+				t.Errorf("%d: unexpected histogram", i)
+			}
+			continue
+		}
+
+		var h stats.HistogramData
+		if err := proto.Unmarshal([]byte(*row[3].Datum.(*parser.DBytes)), &h); err != nil {
+			t.Fatal(err)
+		}
+		if len(h.Buckets) != 4 {
+			t.Fatalf("expected 4 buckets, got %d", len(h.Buckets))
+		}
+		var total int64
+		prev := int64(-1)
+		for j, b := range h.Buckets {
+			total += b.NumEq + b.NumRange
+			upper, err := h.DecodeUpperBound(&da, j)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v := int64(*upper.(*parser.DInt)); v <= prev {
+				t.Errorf("bucket upper bounds not increasing: %d after %d", v, prev)
+			} else {
+				prev = v
+			}
+		}
+		if total != numRows {
+			t.Errorf("expected the buckets to hold %d rows, got %d", numRows, total)
+		}
+	}
+}
+
+func TestSampleReservoir(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	intType := sqlbase.ColumnType{Kind: sqlbase.ColumnType_INT}
+	sr := sampleReservoir{size: 3}
+	for _, rank := range []int64{50, 10, 70, 30, 90, 20, 60} {
+		row := sqlbase.EncDatumRow{sqlbase.DatumToEncDatum(intType, parser.NewDInt(parser.DInt(rank)))}
+		sr.sampleRow(row, rank)
+	}
+	// The reservoir keeps the rows with the lowest ranks.
+	seen := map[int64]bool{}
+	for _, s := range sr.samples {
+		seen[s.rank] = true
+	}
+	if len(seen) != 3 || !seen[10] || !seen[20] || !seen[30] {
+		t.Errorf("expected ranks 10, 20 and 30 to be sampled, got %v", sr.samples)
+	}
+}
@@ -73,6 +73,9 @@ type ServerConfig struct {
 	TestingKnobs TestingKnobs
 	// NodeID is the id of the node on which this Server is running.
 	NodeID *base.NodeIDContainer
+	// TempPrefix is a path under which processors can create temp files. It is
+	// set once the node's stores are created.
+	TempPrefix string
 }
 
 // ServerImpl implements the server for the distributed SQL APIs.
@@ -152,6 +155,8 @@ func (ds *ServerImpl) setupFlow(
 		clientDB:     ds.DB,
 		testingKnobs: ds.TestingKnobs,
 		nodeID:       nodeID,
+		collectStats: req.CollectStats,
+		TempPrefix:   ds.TempPrefix,
 	}
 	ctx = flowCtx.AnnotateCtx(ctx)
 	flowCtx.evalCtx.Ctx = func() context.Context {
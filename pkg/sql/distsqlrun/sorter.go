@@ -39,9 +39,11 @@ type sorter struct {
 	ordering sqlbase.ColumnOrdering
 	matchLen uint32
 	limit    int64
+	// values holds the rows being sorted; it is set by Run.
+	values *sorterValues
 }
 
-var _ processor = &sorter{}
+var _ Processor = &sorter{}
 
 func newSorter(
 	flowCtx *FlowCtx, spec *SorterSpec, input RowSource, post *PostProcessSpec, output RowReceiver,
@@ -59,7 +61,7 @@ func newSorter(
 	return s, nil
 }
 
-// Run is part of the processor interface.
+// Run is part of the Processor interface.
 func (s *sorter) Run(ctx context.Context, wg *sync.WaitGroup) {
 	if wg != nil {
 		defer wg.Done()
@@ -75,33 +77,27 @@ func (s *sorter) Run(ctx context.Context, wg *sync.WaitGroup) {
 	}
 
 	// Construct the optimal sorterStrategy.
+	s.values = &sorterValues{
+		ordering: s.ordering,
+	}
 	var ss sorterStrategy
 	switch {
 	case s.matchLen == 0 && s.limit == 0:
 		// No specified ordering match length and unspecified limit, no optimizations possible so we
 		// simply load all rows into memory and sort all values in-place. It has a worst-case time
 		// complexity of O(n*log(n)) and a worst-case space complexity of O(n).
-		ss = newSortAllStrategy(
-			&sorterValues{
-				ordering: s.ordering,
-			})
+		ss = newSortAllStrategy(s.values)
 	case s.matchLen == 0:
 		// No specified ordering match length but specified limit, we can optimize our sort procedure by
 		// maintaining a max-heap populated with only the smallest k rows seen. It has a worst-case time
 		// complexity of O(n*log(k)) and a worst-case space complexity of O(k).
-		ss = newSortTopKStrategy(
-			&sorterValues{
-				ordering: s.ordering,
-			}, s.limit)
+		ss = newSortTopKStrategy(s.values, s.limit)
 	case s.matchLen != 0:
 		// Ordering match length is specified, but no specified limit. We will be able to use
 		// existing ordering in order to avoid loading all the rows into memory. If we're scanning
 		// an index with a prefix matching an ordering prefix, we can only accumulate values for
 		// equal fields in this prefix, sort the accumulated chunk and then output.
-		ss = newSortChunksStrategy(
-			&sorterValues{
-				ordering: s.ordering,
-			})
+		ss = newSortChunksStrategy(s.values)
 
 	default:
 		// TODO(irfansharif): Add optimization for case where both ordering match length and limit is
@@ -115,3 +111,8 @@ func (s *sorter) Run(ctx context.Context, wg *sync.WaitGroup) {
 	}
 	DrainAndClose(ctx, s.out.output, sortErr, s.rawInput)
 }
+
+// fillStats is part of the statsReporter interface.
+func (s *sorter) fillStats(stats *ProcessorStats) {
+	stats.MaxMemoryBytes = s.values.mem.max
+}
@@ -224,6 +224,7 @@ func (ss *sortTopKStrategy) add(row sqlbase.EncDatumRow) {
 		// Once the heap is full, only replace the top
 		// value if a new value is less than it. If so
 		// replace and fix the heap.
+		ss.sValues.mem.grow(int64(row.Size()) - int64(ss.sValues.rows[0].Size()))
 		ss.sValues.rows[0] = row
 		heap.Fix(ss.sValues, 0)
 	}
@@ -31,6 +31,7 @@ type sorterValues struct {
 	ordering      sqlbase.ColumnOrdering
 	tmpRow        sqlbase.EncDatumRow // Used to store temporary rows.
 	alloc         sqlbase.DatumAlloc
+	mem           memUsage // Tracks the size of the rows.
 }
 
 var _ heap.Interface = &sorterValues{}
@@ -81,7 +82,9 @@ func (sv *sorterValues) NextRow() sqlbase.EncDatumRow {
 	}
 
 	x := heap.Pop(sv)
-	return *x.(*sqlbase.EncDatumRow)
+	row := *x.(*sqlbase.EncDatumRow)
+	sv.mem.grow(-int64(row.Size()))
+	return row
 }
 
 // Push implements the heap.Interface interface.
@@ -95,6 +98,7 @@ func (sv *sorterValues) PushRow(row sqlbase.EncDatumRow) {
 	// Avoid passing slice through interface{} to avoid allocation.
 	sv.tmpRow = row
 	heap.Push(sv, nil)
+	sv.mem.grow(int64(row.Size()))
 }
 
 // Initializes the rows contained within sorterValues as a MaxHeap.
@@ -0,0 +1,170 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package distsqlrun
+
+import (
+	"sync/atomic"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+)
+
+// statsReporter is implemented by the processors that keep runtime statistics
+// which can't be observed from their inputs and outputs.
+type statsReporter interface {
+	// fillStats sets the processor-specific fields of stats. It is called from
+	// the goroutine of the processor, once the processor closes its output.
+	fillStats(stats *ProcessorStats)
+}
+
+// statsRowSource is a RowSource that counts the rows returned by the wrapped
+// RowSource and the time spent waiting for them.
+type statsRowSource struct {
+	RowSource
+
+	rows       int64
+	stallNanos int64
+}
+
+var _ RowSource = &statsRowSource{}
+
+// Next is part of the RowSource interface.
+func (s *statsRowSource) Next() (sqlbase.EncDatumRow, ProducerMetadata) {
+	start := timeutil.Now()
+	row, meta := s.RowSource.Next()
+	atomic.AddInt64(&s.stallNanos, int64(timeutil.Since(start)))
+	if row != nil {
+		atomic.AddInt64(&s.rows, 1)
+	}
+	return row, meta
+}
+
+// statsRowReceiver is a RowReceiver that counts the rows pushed to the wrapped
+// RowReceiver and the time spent waiting for it to accept them.
+type statsRowReceiver struct {
+	RowReceiver
+
+	streamID   StreamID
+	rows       int64
+	stallNanos int64
+}
+
+var _ RowReceiver = &statsRowReceiver{}
+
+// Push is part of the RowReceiver interface.
+func (s *statsRowReceiver) Push(row sqlbase.EncDatumRow, meta ProducerMetadata) ConsumerStatus {
+	if row == nil {
+		return s.RowReceiver.Push(row, meta)
+	}
+	start := timeutil.Now()
+	status := s.RowReceiver.Push(row, meta)
+	atomic.AddInt64(&s.stallNanos, int64(timeutil.Since(start)))
+	atomic.AddInt64(&s.rows, 1)
+	return status
+}
+
+// processorStatsCollector collects the runtime statistics of a processor. It
+// wraps the inputs and the output streams of the processor, and is itself the
+// output of the processor: once the processor is done, the statistics are
+// pushed as trailing metadata before the output is closed.
+//
+// The statistics are only collected on a best-effort basis; they are lost if
+// the consumer of the processor has shut down.
+type processorStatsCollector struct {
+	// RowReceiver is the output router of the processor.
+	RowReceiver
+
+	processorID int32
+	proc        Processor
+	inputs      []*statsRowSource
+	streams     []*statsRowReceiver
+	outputRows  int64
+}
+
+var _ RowReceiver = &processorStatsCollector{}
+
+func newProcessorStatsCollector(processorID int32) *processorStatsCollector {
+	return &processorStatsCollector{processorID: processorID}
+}
+
+// wrapInputs replaces the inputs of the processor with RowSources that collect
+// statistics.
+func (c *processorStatsCollector) wrapInputs(inputs []RowSource) {
+	for i := range inputs {
+		input := &statsRowSource{RowSource: inputs[i]}
+		c.inputs = append(c.inputs, input)
+		inputs[i] = input
+	}
+}
+
+// wrapStream returns a RowReceiver that collects statistics for an output
+// stream of the processor.
+func (c *processorStatsCollector) wrapStream(streamID StreamID, stream RowReceiver) RowReceiver {
+	s := &statsRowReceiver{RowReceiver: stream, streamID: streamID}
+	c.streams = append(c.streams, s)
+	return s
+}
+
+// Push is part of the RowReceiver interface.
+func (c *processorStatsCollector) Push(
+	row sqlbase.EncDatumRow, meta ProducerMetadata,
+) ConsumerStatus {
+	if row != nil {
+		atomic.AddInt64(&c.outputRows, 1)
+	}
+	return c.RowReceiver.Push(row, meta)
+}
+
+// ProducerDone is part of the RowReceiver interface.
+func (c *processorStatsCollector) ProducerDone() {
+	c.RowReceiver.Push(nil /* row */, ProducerMetadata{ProcessorStats: c.stats()})
+	c.RowReceiver.ProducerDone()
+}
+
+// stats returns the statistics collected so far.
+func (c *processorStatsCollector) stats() *ProcessorStats {
+	stats := &ProcessorStats{
+		ProcessorID: c.processorID,
+		OutputRows:  atomic.LoadInt64(&c.outputRows),
+	}
+	for _, input := range c.inputs {
+		stats.InputRows = append(stats.InputRows, atomic.LoadInt64(&input.rows))
+		stats.StallTimeNanos += atomic.LoadInt64(&input.stallNanos)
+	}
+	for _, s := range c.streams {
+		stats.OutputStreams = append(stats.OutputStreams, StreamStats{
+			StreamID:       s.streamID,
+			Rows:           atomic.LoadInt64(&s.rows),
+			StallTimeNanos: atomic.LoadInt64(&s.stallNanos),
+		})
+	}
+	if r, ok := c.proc.(statsReporter); ok {
+		r.fillStats(stats)
+	}
+	return stats
+}
+
+// memUsage tracks the estimated size of the rows and other state that a
+// processor holds in memory, and the peak value it reaches.
+type memUsage struct {
+	cur, max int64
+}
+
+func (m *memUsage) grow(delta int64) {
+	m.cur += delta
+	if m.cur > m.max {
+		m.max = m.cur
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package distsqlrun
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func TestProcessorStatsCollector(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	columnTypeInt := sqlbase.ColumnType{Kind: sqlbase.ColumnType_INT}
+	var input sqlbase.EncDatumRows
+	for _, i := range []int{3, 1, 4, 1, 5} {
+		input = append(input, sqlbase.EncDatumRow{
+			sqlbase.DatumToEncDatum(columnTypeInt, parser.NewDInt(parser.DInt(i))),
+		})
+	}
+
+	// Set up a sorter the way a flow that collects statistics does.
+	const processorID, streamID = 7, 3
+	stats := newProcessorStatsCollector(processorID)
+	inputs := []RowSource{NewRowBuffer(nil /* types */, input, RowBufferArgs{})}
+	stats.wrapInputs(inputs)
+	out := &RowBuffer{}
+	stats.RowReceiver = stats.wrapStream(streamID, out)
+
+	spec := SorterSpec{OutputOrdering: convertToSpecOrdering(sqlbase.ColumnOrdering{{ColIdx: 0}})}
+	post := PostProcessSpec{Limit: 3}
+	s, err := newSorter(&FlowCtx{}, &spec, inputs[0], &post, stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats.proc = s
+	s.Run(context.Background(), nil)
+	if !out.ProducerClosed {
+		t.Fatalf("output RowReceiver not closed")
+	}
+
+	var rows int
+	var procStats *ProcessorStats
+	for {
+		row, meta := out.Next()
+		if row == nil && meta.Empty() {
+			break
+		}
+		if row != nil {
+			rows++
+		} else if meta.ProcessorStats != nil {
+			procStats = meta.ProcessorStats
+		} else {
+			t.Fatalf("unexpected metadata: %v", meta)
+		}
+	}
+	if rows != 3 {
+		t.Errorf("expected 3 rows, got %d", rows)
+	}
+	if procStats == nil {
+		t.Fatal("no processor stats were sent")
+	}
+	if procStats.ProcessorID != processorID {
+		t.Errorf("expected processor ID %d, got %d", processorID, procStats.ProcessorID)
+	}
+	if !reflect.DeepEqual(procStats.InputRows, []int64{5}) {
+		t.Errorf("expected input rows [5], got %v", procStats.InputRows)
+	}
+	if procStats.OutputRows != 3 {
+		t.Errorf("expected 3 output rows, got %d", procStats.OutputRows)
+	}
+	if len(procStats.OutputStreams) != 1 || procStats.OutputStreams[0].StreamID != streamID ||
+		procStats.OutputStreams[0].Rows != 3 {
+		t.Errorf("unexpected output stream stats: %+v", procStats.OutputStreams)
+	}
+	// The sorter holds all the input rows at once.
+	if minMem := int64(len(input)) * int64(input[0].Size()); procStats.MaxMemoryBytes < minMem {
+		t.Errorf("expected max memory of at least %d, got %d", minMem, procStats.MaxMemoryBytes)
+	}
+}
+
+func TestPlanDiagramStats(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	flows := map[roachpb.NodeID]FlowSpec{
+		1: {
+			Processors: []ProcessorSpec{{
+				ProcessorID: 4,
+				Core: ProcessorCoreUnion{TableReader: &TableReaderSpec{
+					Table: sqlbase.TableDescriptor{Name: "Table"},
+				}},
+				Output: []OutputRouterSpec{{
+					Type:    OutputRouterSpec_PASS_THROUGH,
+					Streams: []StreamEndpointSpec{{Type: StreamEndpointSpec_SYNC_RESPONSE}},
+				}},
+			}},
+		},
+	}
+	stats := map[int32]ProcessorStats{
+		4: {
+			ProcessorID:   4,
+			OutputRows:    10,
+			OutputStreams: []StreamStats{{Rows: 10, StallTimeNanos: 2000}},
+			BytesRead:     2048,
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := GeneratePlanDiagram(flows, stats, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `
+    {
+      "nodeNames":["1"],
+      "processors":[
+        {"nodeIdx":0,"inputs":[],"core":{"title":"TableReader","details":["primary@Table","Rows out: 10","KV bytes read: 2.0 KiB"]},"outputs":[]},
+        {"nodeIdx":0,"inputs":[],"core":{"title":"Response","details":[]},"outputs":[]}
+      ],
+      "edges":[
+        {"sourceProc":0,"sourceOutput":0,"destProc":1,"destInput":0,"stats":["Rows: 10","Stall time: 2µs"]}
+      ]
+    }
+  `
+
+	compareDiagrams(t, buf.String(), expected)
+}
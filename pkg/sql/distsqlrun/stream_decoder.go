@@ -100,6 +100,10 @@ func (sd *StreamDecoder) AddMessage(msg *ProducerMessage) error {
 			var meta ProducerMetadata
 			if rangeInfo := md.GetRangeInfo(); rangeInfo != nil {
 				meta.Ranges = rangeInfo.RangeInfo
+			} else if readStats := md.GetReadStats(); readStats != nil {
+				meta.ReadStats = readStats
+			} else if procStats := md.GetProcessorStats(); procStats != nil {
+				meta.ProcessorStats = procStats
 			} else if pErr := md.GetError(); pErr != nil {
 				meta.Err = pErr.ErrorDetail()
 			}
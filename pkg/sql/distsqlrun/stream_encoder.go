@@ -81,6 +81,14 @@ func (se *StreamEncoder) AddMetadata(meta ProducerMetadata) {
 				RangeInfo: meta.Ranges,
 			},
 		}
+	} else if meta.ReadStats != nil {
+		enc.Value = &RemoteProducerMetadata_ReadStats{
+			ReadStats: meta.ReadStats,
+		}
+	} else if meta.ProcessorStats != nil {
+		enc.Value = &RemoteProducerMetadata_ProcessorStats{
+			ProcessorStats: meta.ProcessorStats,
+		}
 	} else {
 		enc.Value = &RemoteProducerMetadata_Error{
 			Error: NewError(meta.Err),
@@ -45,7 +45,7 @@ type tableReader struct {
 	out procOutputHelper
 }
 
-var _ processor = &tableReader{}
+var _ Processor = &tableReader{}
 
 // newTableReader creates a tableReader.
 func newTableReader(
@@ -152,7 +152,11 @@ func initRowFetcher(
 // that were read by this tableReader. This should be called after the fetcher
 // was used to read everything this tableReader was supposed to read.
 func (tr *tableReader) sendMisplannedRangesMetadata(ctx context.Context) {
-	rangeInfos := tr.fetcher.GetRangeInfo()
+	rangeInfos, err := tr.fetcher.GetRangeInfo()
+	if err != nil {
+		tr.out.output.Push(nil /* row */, ProducerMetadata{Err: err})
+		return
+	}
 	var misplannedRanges []roachpb.RangeInfo
 	for _, ri := range rangeInfos {
 		if ri.Lease.Replica.NodeID != tr.flowCtx.nodeID {
@@ -172,7 +176,22 @@ func (tr *tableReader) sendMisplannedRangesMetadata(ctx context.Context) {
 	}
 }
 
-// Run is part of the processor interface.
+// sendReadStatsMetadata sends the number of rows and bytes read by this
+// tableReader. Like sendMisplannedRangesMetadata, this should be called after
+// the fetcher was used to read everything.
+func (tr *tableReader) sendReadStatsMetadata() {
+	rowsRead, bytesRead := tr.fetcher.ReadStats()
+	tr.out.output.Push(nil /* row */, ProducerMetadata{
+		ReadStats: &ReadStats{RowsRead: rowsRead, BytesRead: bytesRead},
+	})
+}
+
+// fillStats is part of the statsReporter interface.
+func (tr *tableReader) fillStats(stats *ProcessorStats) {
+	_, stats.BytesRead = tr.fetcher.ReadStats()
+}
+
+// Run is part of the Processor interface.
 func (tr *tableReader) Run(ctx context.Context, wg *sync.WaitGroup) {
 	if wg != nil {
 		defer wg.Done()
@@ -205,6 +224,7 @@ func (tr *tableReader) Run(ctx context.Context, wg *sync.WaitGroup) {
 				tr.out.output.Push(nil /* row */, ProducerMetadata{Err: err})
 			}
 			tr.sendMisplannedRangesMetadata(ctx)
+			tr.sendReadStatsMetadata()
 			tr.out.close()
 			return
 		}
@@ -215,6 +235,7 @@ func (tr *tableReader) Run(ctx context.Context, wg *sync.WaitGroup) {
 				tr.out.output.Push(nil /* row */, ProducerMetadata{Err: err})
 			}
 			tr.sendMisplannedRangesMetadata(ctx)
+			tr.sendReadStatsMetadata()
 			tr.out.close()
 			return
 		}
@@ -34,7 +34,7 @@ type valuesProcessor struct {
 	out     procOutputHelper
 }
 
-var _ processor = &valuesProcessor{}
+var _ Processor = &valuesProcessor{}
 
 func newValuesProcessor(
 	flowCtx *FlowCtx, spec *ValuesCoreSpec, post *PostProcessSpec, output RowReceiver,
@@ -54,7 +54,7 @@ func newValuesProcessor(
 	return v, nil
 }
 
-// Run is part of the processor interface.
+// Run is part of the Processor interface.
 func (v *valuesProcessor) Run(ctx context.Context, wg *sync.WaitGroup) {
 	if wg != nil {
 		defer wg.Done()
@@ -18,18 +18,21 @@ package sql
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/pkg/errors"
 	"golang.org/x/net/context"
 
 	"github.com/cockroachdb/cockroach/pkg/config"
 	"github.com/cockroachdb/cockroach/pkg/internal/client"
+	"github.com/cockroachdb/cockroach/pkg/keys"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/sql/parser"
 	"github.com/cockroachdb/cockroach/pkg/sql/privilege"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
 	"github.com/cockroachdb/cockroach/pkg/util"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
 )
 
 type dropDatabaseNode struct {
@@ -865,6 +868,7 @@ func (p *planner) initiateDropTable(ctx context.Context, tableDesc *sqlbase.Tabl
 		return err
 	}
 	tableDesc.State = sqlbase.TableDescriptor_DROP
+	tableDesc.DropTime = timeutil.Now().UnixNano()
 	if err := p.writeTableDesc(ctx, tableDesc); err != nil {
 		return err
 	}
@@ -992,14 +996,24 @@ func (p *planner) dropViewImpl(
 	return cascadeDroppedViews, nil
 }
 
+// errNotHitGCTTL is returned by truncateAndDropTable when the GC TTL of a
+// dropped table hasn't elapsed yet, so its data can't be cleared yet. The
+// schema changer retries later.
+var errNotHitGCTTL = errors.New("table GC TTL has not elapsed")
+
 // truncateAndDropTable batches all the commands required for truncating and
 // deleting the table descriptor. It is called from a mutation, async wrt the
 // DROP statement. Before this method is called, the table has already been
 // marked for deletion and has been purged from the descriptor cache on all
 // nodes. No node is reading/writing data on the table at this stage,
-// therefore the entire table can be deleted with no concern for conflicts (we
-// can even eliminate the need to use a transaction for each chunk at a later
-// stage if it proves inefficient).
+// therefore the entire table can be deleted with no concern for conflicts.
+//
+// The table name is released right away, but the data of tables that aren't
+// interleaved is cleared with a ClearRange, which also removes its MVCC
+// history. This is only done once the table's GC TTL has elapsed since it was
+// dropped, so that historical reads within the TTL keep working; until then,
+// errNotHitGCTTL is returned. Interleaved tables share their key span with
+// other tables and are deleted in chunks of regular transactional deletes.
 func truncateAndDropTable(
 	ctx context.Context,
 	tableDesc *sqlbase.TableDescriptor,
@@ -1011,6 +1025,7 @@ func truncateAndDropTable(
 	// Delete the namekey so that it can be used by another table.
 	// We do this before truncating the table because the table truncation
 	// takes too much time.
+	var ttl time.Duration
 	if err := db.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
 		b := &client.Batch{}
 		// Use CPut because we want to remove a specific name -> id map.
@@ -1019,9 +1034,10 @@ func truncateAndDropTable(
 			return err
 		}
 		err := txn.Run(ctx, b)
-		if _, ok := err.(*roachpb.ConditionFailedError); ok {
-			return nil
+		if _, ok := err.(*roachpb.ConditionFailedError); !ok && err != nil {
+			return err
 		}
+		ttl, err = getTableGCTTL(ctx, txn, tableDesc)
 		return err
 	}); err != nil {
 		return err
@@ -1033,8 +1049,25 @@ func truncateAndDropTable(
 		}
 	}
 
-	if err := truncateTableInChunks(ctx, tableDesc, db); err != nil {
-		return err
+	if tableDesc.IsInterleaved() {
+		if err := truncateTableInChunks(ctx, tableDesc, db); err != nil {
+			return err
+		}
+	} else {
+		if timeutil.Since(time.Unix(0, tableDesc.DropTime)) < ttl {
+			return errNotHitGCTTL
+		}
+		if log.V(2) {
+			log.Infof(ctx, "clearing data of table %d", tableDesc.ID)
+		}
+		span := tableDesc.TableSpan()
+		b := &client.Batch{}
+		b.AddRawRequest(&roachpb.ClearRangeRequest{
+			Span: roachpb.Span{Key: span.Key, EndKey: span.EndKey},
+		})
+		if err := db.Run(ctx, b); err != nil {
+			return err
+		}
 	}
 
 	// Finished deleting all the table data, now delete the table meta data.
@@ -1051,6 +1084,29 @@ func truncateAndDropTable(
 	})
 }
 
+// getTableGCTTL returns the GC TTL which applies to the data of the table:
+// that of the table's zone config, else that of its database, else that of
+// the cluster.
+func getTableGCTTL(
+	ctx context.Context, txn *client.Txn, tableDesc *sqlbase.TableDescriptor,
+) (time.Duration, error) {
+	for _, id := range []sqlbase.ID{tableDesc.ID, tableDesc.ParentID, keys.RootNamespaceID} {
+		kv, err := txn.Get(ctx, sqlbase.MakeZoneKey(id))
+		if err != nil {
+			return 0, err
+		}
+		if kv.Value == nil {
+			continue
+		}
+		zone, err := config.MigrateZoneConfig(kv.Value)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(zone.GC.TTLSeconds) * time.Second, nil
+	}
+	return time.Duration(config.DefaultZoneConfig().GC.TTLSeconds) * time.Second, nil
+}
+
 // removeMatchingReferences removes all refs from the provided slice that
 // match the provided ID, returning the modified slice.
 func removeMatchingReferences(
@@ -84,8 +84,10 @@ INSERT INTO t.kv VALUES ('c', 'e'), ('a', 'c'), ('b', 'd');
 	}
 	tbDesc := desc.GetTable()
 
-	// Add a zone config for both the table and database.
+	// Add a zone config for both the table and database. A GC TTL of zero
+	// lets the table's data be cleared as soon as it's dropped.
 	cfg := config.DefaultZoneConfig()
+	cfg.GC.TTLSeconds = 0
 	buf, err := protoutil.Marshal(&cfg)
 	if err != nil {
 		t.Fatal(err)
@@ -218,6 +220,20 @@ func checkKeyCount(t *testing.T, kvDB *client.DB, span roachpb.Span, numKeys int
 	}
 }
 
+// addImmediateGCZoneConfig adds a zone config with a GC TTL of zero for the
+// table with the given ID, so that its data is cleared as soon as it's
+// dropped.
+func addImmediateGCZoneConfig(sqlDB *gosql.DB, id sqlbase.ID) error {
+	cfg := config.DefaultZoneConfig()
+	cfg.GC.TTLSeconds = 0
+	buf, err := protoutil.Marshal(&cfg)
+	if err != nil {
+		return err
+	}
+	_, err = sqlDB.Exec(`UPSERT INTO system.zones VALUES ($1, $2)`, id, buf)
+	return err
+}
+
 func createKVTable(sqlDB *gosql.DB, numRows int) error {
 	// Fix the column families so the key counts don't change if the family
 	// heuristics are updated.
@@ -401,8 +417,10 @@ func TestDropTable(t *testing.T) {
 
 	descKey := sqlbase.MakeDescMetadataKey(sqlbase.ID(gr.ValueInt()))
 
-	// Add a zone config for the table.
+	// Add a zone config for the table. A GC TTL of zero lets the table's
+	// data be cleared as soon as it's dropped.
 	cfg := config.DefaultZoneConfig()
+	cfg.GC.TTLSeconds = 0
 	buf, err := protoutil.Marshal(&cfg)
 	if err != nil {
 		t.Fatal(err)
@@ -571,3 +589,116 @@ CREATE TABLE test.t(a INT PRIMARY KEY);
 		t.Fatal(err)
 	}
 }
+
+// TestDropTableWaitsForGCTTL tests that the data of a dropped table is only
+// cleared once the table's GC TTL has elapsed.
+func TestDropTableWaitsForGCTTL(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	params, _ := createTestServerParams()
+	params.Knobs = base.TestingKnobs{
+		SQLSchemaChanger: &sql.SchemaChangerTestingKnobs{
+			AsyncExecQuickly: true,
+		},
+	}
+	s, sqlDB, kvDB := serverutils.StartServer(t, params)
+	defer s.Stopper().Stop()
+	ctx := context.TODO()
+
+	const numRows = 100
+	if err := createKVTable(sqlDB, numRows); err != nil {
+		t.Fatal(err)
+	}
+	tableDesc := sqlbase.GetTableDescriptor(kvDB, "t", "kv")
+	tableSpan := tableDesc.TableSpan()
+	descKey := sqlbase.MakeDescMetadataKey(tableDesc.ID)
+
+	if _, err := sqlDB.Exec(`DROP TABLE t.kv`); err != nil {
+		t.Fatal(err)
+	}
+
+	// The default GC TTL hasn't elapsed: the name is released right away, but
+	// the data and the descriptor are left in place.
+	if _, err := sqlDB.Exec(`CREATE TABLE t.kv (k INT PRIMARY KEY)`); err != nil {
+		t.Fatal(err)
+	}
+	checkKeyCount(t, kvDB, tableSpan, 3*numRows)
+	desc := &sqlbase.Descriptor{}
+	if err := kvDB.GetProto(ctx, descKey, desc); err != nil {
+		t.Fatal(err)
+	}
+	if droppedDesc := desc.GetTable(); !droppedDesc.Dropped() || droppedDesc.DropTime == 0 {
+		t.Fatalf("expected dropped table with a drop time, got %+v", droppedDesc)
+	}
+
+	// Once the GC TTL is lowered, the data is cleared.
+	if err := addImmediateGCZoneConfig(sqlDB, tableDesc.ID); err != nil {
+		t.Fatal(err)
+	}
+	testutils.SucceedsSoon(t, func() error {
+		if kvs, err := kvDB.Scan(ctx, tableSpan.Key, tableSpan.EndKey, 0); err != nil {
+			return err
+		} else if len(kvs) != 0 {
+			return errors.Errorf("expected table data to be cleared, found %d keys", len(kvs))
+		}
+		if gr, err := kvDB.Get(ctx, descKey); err != nil {
+			return err
+		} else if gr.Exists() {
+			return errors.Errorf("table descriptor still exists")
+		}
+		return nil
+	})
+}
+
+// TestTruncateReplacesTable tests that TRUNCATE replaces a table with an
+// empty copy under a new ID, and that the data of the original table is
+// cleared once its GC TTL has elapsed.
+func TestTruncateReplacesTable(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	params, _ := createTestServerParams()
+	params.Knobs = base.TestingKnobs{
+		SQLSchemaChanger: &sql.SchemaChangerTestingKnobs{
+			AsyncExecQuickly: true,
+		},
+	}
+	s, sqlDB, kvDB := serverutils.StartServer(t, params)
+	defer s.Stopper().Stop()
+
+	const numRows = 100
+	if err := createKVTable(sqlDB, numRows); err != nil {
+		t.Fatal(err)
+	}
+	tableDesc := sqlbase.GetTableDescriptor(kvDB, "t", "kv")
+
+	if _, err := sqlDB.Exec(`TRUNCATE TABLE t.kv`); err != nil {
+		t.Fatal(err)
+	}
+	newTableDesc := sqlbase.GetTableDescriptor(kvDB, "t", "kv")
+	if newTableDesc.ID == tableDesc.ID {
+		t.Fatalf("expected TRUNCATE to replace table %d", tableDesc.ID)
+	}
+	var count int
+	if err := sqlDB.QueryRow(`SELECT COUNT(*) FROM t.kv`).Scan(&count); err != nil {
+		t.Fatal(err)
+	} else if count != 0 {
+		t.Fatalf("expected no rows after TRUNCATE, found %d", count)
+	}
+	if _, err := sqlDB.Exec(`INSERT INTO t.kv VALUES (1, 1)`); err != nil {
+		t.Fatal(err)
+	}
+	checkKeyCount(t, kvDB, tableDesc.TableSpan(), 3*numRows)
+
+	if err := addImmediateGCZoneConfig(sqlDB, tableDesc.ID); err != nil {
+		t.Fatal(err)
+	}
+	tableSpan := tableDesc.TableSpan()
+	testutils.SucceedsSoon(t, func() error {
+		kvs, err := kvDB.Scan(context.TODO(), tableSpan.Key, tableSpan.EndKey, 0)
+		if err != nil {
+			return err
+		} else if len(kvs) != 0 {
+			return errors.Errorf("expected table data to be cleared, found %d keys", len(kvs))
+		}
+		return nil
+	})
+	checkKeyCount(t, kvDB, newTableDesc.TableSpan(), 3)
+}
@@ -40,6 +40,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/distsqlrun"
 	"github.com/cockroachdb/cockroach/pkg/sql/parser"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/sql/stats"
 	"github.com/cockroachdb/cockroach/pkg/util"
 	"github.com/cockroachdb/cockroach/pkg/util/duration"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
@@ -159,6 +160,10 @@ type Result struct {
 	// the result set of the result.
 	// TODO(nvanbenschoten): Can this be streamed from the planNode?
 	Rows *RowContainer
+	// CopyOptions will be populated if the statement type is "CopyOut", whose
+	// result also has Columns and Rows. They describe the format the rows are
+	// copied in.
+	CopyOptions CopyOptions
 }
 
 // Close ensures that the resources claimed by the result are released.
@@ -243,6 +248,10 @@ type ExecutorConfig struct {
 	LeaseManager *LeaseManager
 	Clock        *hlc.Clock
 	DistSQLSrv   *distsqlrun.ServerImpl
+	Stopper      *stop.Stopper
+	JobRegistry  *JobRegistry
+	// TableStatsCache holds the statistics collected by CREATE STATISTICS.
+	TableStatsCache *stats.TableStatisticsCache
 
 	TestingKnobs              *ExecutorTestingKnobs
 	SchemaChangerTestingKnobs *SchemaChangerTestingKnobs
@@ -252,6 +261,10 @@ type ExecutorConfig struct {
 	// Caches updated by DistSQL.
 	RangeDescriptorCache *kv.RangeDescriptorCache
 	LeaseHolderCache     *kv.LeaseHolderCache
+
+	// DistLoader is set once the Executor is started. It is used by jobs,
+	// which run outside of any session, to convert external data.
+	DistLoader *DistLoader
 }
 
 var _ base.ModuleTestingKnobs = &ExecutorTestingKnobs{}
@@ -314,7 +327,10 @@ func NewExecutor(cfg ExecutorConfig, stopper *stop.Stopper) *Executor {
 		DdlCount:    metric.NewCounter(MetaDdl),
 		MiscCount:   metric.NewCounter(MetaMisc),
 		QueryCount:  metric.NewCounter(MetaQuery),
-		sqlStats:    sqlStats{apps: make(map[string]*appStats)},
+		sqlStats: sqlStats{
+			apps:   make(map[string]*appStats),
+			flushC: make(chan chan struct{}),
+		},
 	}
 }
 
@@ -344,10 +360,14 @@ func (e *Executor) Start(
 		}
 	})
 
-	// Until per-statement statistics are properly recorded and
-	// scrubbed, we clear them periodically.
-	// TODO(dt): remove this.
-	e.sqlStats.startResetWorker(e.stopper)
+	// The per-statement statistics are periodically persisted and
+	// cleared from memory.
+	ie := InternalExecutor{LeaseManager: e.cfg.LeaseManager}
+	e.sqlStats.startResetWorker(e.stopper, func(
+		ctx context.Context, aggregatedTs time.Time, stats map[string]map[string]StatementStatistics,
+	) error {
+		return persistStmtStats(ctx, e.cfg.DB, ie, e.cfg.NodeID.Get(), aggregatedTs, stats)
+	})
 
 	ctx = log.WithLogTag(ctx, "startup", nil)
 	startupSession := NewSession(ctx, SessionArgs{}, e, nil, startupMemMetrics)
@@ -358,6 +378,21 @@ func (e *Executor) Start(
 	startupSession.Finish(e)
 }
 
+// FlushStmtStats persists the statement statistics collected so far. It is
+// called while the node drains, once its SQL clients are gone, since the
+// statistics can't be persisted reliably once the stopper is quiescing.
+func (e *Executor) FlushStmtStats(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(e.AnnotateCtx(ctx), stmtStatsFlushTimeout)
+	defer cancel()
+	e.sqlStats.flush(ctx, e.stopper)
+}
+
+// DistLoader returns a DistLoader using the Executor's distSQLPlanner. It must
+// only be called after Start.
+func (e *Executor) DistLoader() *DistLoader {
+	return &DistLoader{distSQLPlanner: e.distSQLPlanner}
+}
+
 // SetDistSQLSpanResolver changes the SpanResolver used for DistSQL. It is the
 // caller's responsibility to make sure no queries are being run with DistSQL at
 // the same time.
@@ -1269,7 +1304,7 @@ func (e *Executor) execStmtInOpenTxn(
 	switch result.Type {
 	case parser.RowsAffected:
 		tResult.count = result.RowsAffected
-	case parser.Rows:
+	case parser.Rows, parser.CopyOut:
 		tResult.count = result.Rows.Len()
 	}
 	sessionEventf(session, "%s done", tResult)
@@ -1349,6 +1384,7 @@ func (e *Executor) execDistSQL(planner *planner, tree planNode, result *Result)
 	ctx := planner.session.Ctx()
 	recv := makeDistSQLReceiver(ctx, result.Rows, e.cfg.RangeDescriptorCache, e.cfg.LeaseHolderCache)
 	err := e.distSQLPlanner.PlanAndRun(ctx, planner.txn, tree, &recv)
+	planner.rowsRead, planner.bytesRead = recv.rowsRead, recv.bytesRead
 	if err != nil {
 		return err
 	}
@@ -1365,6 +1401,7 @@ func (e *Executor) execDistSQL(planner *planner, tree planNode, result *Result)
 // implementation.
 func (e *Executor) execClassic(planner *planner, plan planNode, result *Result) error {
 	ctx := planner.session.Ctx()
+	defer planner.collectReadStats(ctx, plan)
 	if err := planner.startPlan(ctx, plan); err != nil {
 		return err
 	}
@@ -1377,7 +1414,7 @@ func (e *Executor) execClassic(planner *planner, plan planNode, result *Result)
 		}
 		result.RowsAffected += count
 
-	case parser.Rows:
+	case parser.Rows, parser.CopyOut:
 		next, err := plan.Next(ctx)
 		for ; next; next, err = plan.Next(ctx) {
 			// The plan.Values Datums needs to be copied on each iteration.
@@ -1454,7 +1491,13 @@ func makeRes(stmt parser.Statement, planner *planner, plan planNode) (Result, er
 		PGTag: stmt.StatementTag(),
 		Type:  stmt.StatementType(),
 	}
-	if result.Type == parser.Rows {
+	if copyTo, ok := stmt.(*parser.CopyTo); ok {
+		var err error
+		if result.CopyOptions, err = MakeCopyOptions(copyTo.Options); err != nil {
+			return Result{}, err
+		}
+	}
+	if result.Type == parser.Rows || result.Type == parser.CopyOut {
 		result.Columns = plan.Columns()
 		for _, c := range result.Columns {
 			if err := checkResultType(c.Typ); err != nil {
@@ -18,6 +18,8 @@ package sql
 import (
 	"time"
 
+	"golang.org/x/net/context"
+
 	"github.com/cockroachdb/cockroach/pkg/sql/parser"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 )
@@ -96,7 +98,7 @@ func (e *Executor) recordStatementSummary(
 
 	// Collect the statistics.
 	numRows := result.RowsAffected
-	if result.Type == parser.Rows {
+	if result.Type == parser.Rows || result.Type == parser.CopyOut {
 		numRows = result.Rows.Len()
 	}
 
@@ -119,6 +121,7 @@ func (e *Executor) recordStatementSummary(
 	planner.session.appStats.recordStatement(
 		stmt, distSQLUsed, automaticRetryCount, numRows, err,
 		parseLat, planLat, runLat, svcLat, execOverhead,
+		planner.rowsRead, planner.bytesRead,
 	)
 
 	if log.V(2) {
@@ -144,3 +147,20 @@ func (e *Executor) recordStatementSummary(
 		)
 	}
 }
+
+// collectReadStats sets the rows and bytes read by the planner to the totals
+// read by the scans of the given plan, which was run with the classic
+// (non-distributed) SQL implementation.
+func (p *planner) collectReadStats(ctx context.Context, plan planNode) {
+	p.rowsRead, p.bytesRead = 0, 0
+	_ = walkPlan(ctx, plan, planObserver{
+		enterNode: func(_ context.Context, _ string, plan planNode) bool {
+			if n, ok := plan.(*scanNode); ok {
+				rowsRead, bytesRead := n.fetcher.ReadStats()
+				p.rowsRead += rowsRead
+				p.bytesRead += bytesRead
+			}
+			return true
+		},
+	})
+}
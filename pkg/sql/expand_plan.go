@@ -126,6 +126,16 @@ func doExpandPlan(
 		n.source.plan, err = doExpandPlan(ctx, p, params, n.source.plan)
 
 	case *joinNode:
+		if !n.ordered {
+			var reordered planNode
+			reordered, err = p.orderJoins(ctx, n)
+			if err != nil {
+				return plan, err
+			}
+			if reordered != nil {
+				return doExpandPlan(ctx, p, params, reordered)
+			}
+		}
 		n.left.plan, err = doExpandPlan(ctx, p, noParams, n.left.plan)
 		if err != nil {
 			return plan, err
@@ -256,9 +266,11 @@ func doExpandPlan(
 
 	case *valuesNode:
 	case *alterTableNode:
+	case *controlJobNode:
 	case *copyNode:
 	case *createDatabaseNode:
 	case *createIndexNode:
+	case *createStatsNode:
 	case *createUserNode:
 	case *dropDatabaseNode:
 	case *dropIndexNode:
@@ -540,9 +552,11 @@ func simplifyOrderings(plan planNode, usefulOrdering sqlbase.ColumnOrdering) pla
 
 	case *valuesNode:
 	case *alterTableNode:
+	case *controlJobNode:
 	case *copyNode:
 	case *createDatabaseNode:
 	case *createIndexNode:
+	case *createStatsNode:
 	case *createUserNode:
 	case *dropDatabaseNode:
 	case *dropIndexNode:
@@ -39,6 +39,9 @@ const (
 	// query would be run in "auto" DISTSQL mode. See explainDistSQLNode for
 	// details.
 	explainDistSQL
+	// explainAnalyze runs a query under DistSQL and shows its physical plan
+	// annotated with the runtime statistics of the processors.
+	explainAnalyze
 )
 
 var explainStrings = map[explainMode]string{
@@ -46,11 +49,24 @@ var explainStrings = map[explainMode]string{
 	explainPlan:    "plan",
 	explainTrace:   "trace",
 	explainDistSQL: "distsql",
+	explainAnalyze: "analyze",
 }
 
 // Explain executes the explain statement, providing debugging and analysis
 // info about the wrapped statement.
 //
+// The options are either a mode (DEBUG, PLAN, TRACE, DISTSQL or ANALYZE;
+// PLAN is the default) or one of the following, which apply to PLAN:
+//   - EXPRS shows the expressions embedded in the nodes, and TYPES also shows
+//     their types. QUALIFY fully qualifies column names, and SYMVARS shows
+//     column references as ordinals.
+//   - METADATA shows the columns and ordering produced by each node.
+//   - ESTIMATES shows the number of rows each node is estimated to produce,
+//     which is based on the statistics collected by CREATE STATISTICS.
+//   - VERBOSE implies EXPRS, QUALIFY and METADATA.
+//   - INDENT indents the node names by their level in the plan.
+//   - NOEXPAND, NONORMALIZE and NOOPTIMIZE skip steps of the planning.
+//
 // Privileges: the same privileges as the statement being explained.
 func (p *planner) Explain(
 	ctx context.Context, n *parser.Explain, autoCommit bool,
@@ -95,6 +111,9 @@ func (p *planner) Explain(
 			case "metadata":
 				explainer.showMetadata = true
 
+			case "estimates":
+				explainer.showEstimates = true
+
 			case "qualify":
 				explainer.qualifyNames = true
 
@@ -143,11 +162,13 @@ func (p *planner) Explain(
 	case explainDebug:
 		return &explainDebugNode{plan}, nil
 
-	case explainDistSQL:
+	case explainDistSQL, explainAnalyze:
 		return &explainDistSQLNode{
 			plan:           plan,
 			distSQLPlanner: p.session.distSQLPlanner,
 			txn:            p.txn,
+			analyze:        mode == explainAnalyze,
+			execCfg:        p.ExecCfg(),
 		}, nil
 
 	case explainPlan:
@@ -286,6 +307,11 @@ type explainDistSQLNode struct {
 	// txn is the current transaction (used for the fake span resolver).
 	txn *client.Txn
 
+	// analyze is set for EXPLAIN ANALYZE: the plan is run, and the runtime
+	// statistics of the processors are shown on the diagram.
+	analyze bool
+	execCfg *ExecutorConfig
+
 	// The single row returned by the node.
 	values parser.Datums
 
@@ -325,8 +351,25 @@ func (n *explainDistSQLNode) Start(ctx context.Context) error {
 		return err
 	}
 	n.distSQLPlanner.FinalizePlan(&planCtx, &plan)
+
+	var stats map[int32]distsqlrun.ProcessorStats
+	if n.analyze {
+		// The results of the query are discarded; only their count is kept.
+		recv := makeDistSQLReceiver(
+			ctx, nil /* sink */, n.execCfg.RangeDescriptorCache, n.execCfg.LeaseHolderCache,
+		)
+		recv.processorStats = make(map[int32]distsqlrun.ProcessorStats)
+		if err := n.distSQLPlanner.Run(&planCtx, n.txn, &plan, &recv); err != nil {
+			return err
+		}
+		if recv.err != nil {
+			return recv.err
+		}
+		stats = recv.processorStats
+	}
+
 	flows := plan.GenerateFlowSpecs()
-	planJSON, planURL, err := distsqlrun.GeneratePlanDiagramWithURL(flows)
+	planJSON, planURL, err := distsqlrun.GeneratePlanDiagramWithURL(flows, stats)
 	if err != nil {
 		return err
 	}
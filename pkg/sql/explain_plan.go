@@ -18,6 +18,7 @@ package sql
 import (
 	"bytes"
 	"fmt"
+	"math"
 
 	"golang.org/x/net/context"
 
@@ -32,6 +33,10 @@ type explainer struct {
 	// nodes.
 	showMetadata bool
 
+	// showEstimates indicates whether the output has a column for the
+	// estimated number of rows produced by the intermediate nodes.
+	showEstimates bool
+
 	// showExprs indicates whether the plan prints expressions
 	// embedded inside the node.
 	showExprs bool
@@ -85,6 +90,10 @@ func (p *planner) makeExplainPlanNode(
 		// Ordering indicates the known ordering of the data from this source.
 		columns = append(columns, ResultColumn{Name: "Ordering", Typ: parser.TypeString})
 	}
+	if explainer.showEstimates {
+		// Estimated Rows is the estimated number of rows produced by the node.
+		columns = append(columns, ResultColumn{Name: "Estimated Rows", Typ: parser.TypeInt})
+	}
 
 	explainer.fmtFlags = parser.FmtExpr(
 		parser.FmtSimple, explainer.showTypes, explainer.symbolicVars, explainer.qualifyNames,
@@ -127,6 +136,15 @@ func (p *planner) populateExplain(
 				row = append(row, emptyString, emptyString)
 			}
 		}
+		if e.showEstimates {
+			var rows parser.Datum = parser.DNull
+			if plan != nil {
+				if estimate := p.estimateRowCount(ctx, plan); estimate >= 0 {
+					rows = parser.NewDInt(parser.DInt(math.Floor(estimate + 0.5)))
+				}
+			}
+			row = append(row, rows)
+		}
 		if _, err := v.rows.AddRow(ctx, row); err != nil {
 			e.err = err
 		}
@@ -332,9 +332,11 @@ func (p *planner) propagateFilters(
 		}
 
 	case *alterTableNode:
+	case *controlJobNode:
 	case *copyNode:
 	case *createDatabaseNode:
 	case *createIndexNode:
+	case *createStatsNode:
 	case *createUserNode:
 	case *delayedNode:
 	case *dropDatabaseNode:
@@ -102,9 +102,5 @@ func (m *LeaseManager) ExpireLeases(clock *hlc.Clock) {
 // SetDefaultDistSQLMode changes the default DistSQL mode; returns a function
 // that can be used to restore the previous mode.
 func SetDefaultDistSQLMode(mode string) func() {
-	prevMode := defaultDistSQLMode
-	defaultDistSQLMode = distSQLExecModeFromString(mode)
-	return func() {
-		defaultDistSQLMode = prevMode
-	}
+	return DistSQLClusterExecMode.Override(int64(distSQLExecModeFromString(mode)))
 }
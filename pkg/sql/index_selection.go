@@ -121,6 +121,8 @@ func (p *planner) selectIndex(
 		c.init(s)
 	}
 
+	t := p.makeTableEstimate(ctx, &s.desc)
+
 	if s.filter != nil {
 		// Analyze the filter expression, simplifying it and splitting it up into
 		// possibly overlapping ranges.
@@ -164,6 +166,14 @@ func (p *planner) selectIndex(
 		}
 	}
 
+	if t.hasStats() {
+		// Replace the heuristic costs by costs derived from the statistics of
+		// the table.
+		for _, c := range candidates {
+			c.estimateCost(&t, s)
+		}
+	}
+
 	if s.noIndexJoin {
 		// Eliminate non-covering indexes. We do this after the check above for
 		// constant false filter.
@@ -204,6 +214,7 @@ func (p *planner) selectIndex(
 	// After sorting, candidates[0] contains the best index. Copy its info into
 	// the scanNode.
 	c := candidates[0]
+	rows := clampRows(t.rowCount * t.selectivity(s.filter, s.colID))
 	s.index = c.index
 	s.specifiedIndex = nil
 	s.isSecondaryIndex = (c.index != &s.desc.PrimaryIndex)
@@ -235,12 +246,23 @@ func (p *planner) selectIndex(
 	var plan planNode
 	if c.covering {
 		s.initOrdering(c.exactPrefix)
+		s.rowCountEstimate = rows
 		plan = s
 	} else {
 		// Note: makeIndexJoin destroys s and returns a new index scan
 		// node. The filter in that node may be different from the
 		// original table filter.
-		plan, s = s.p.makeIndexJoin(s, c.exactPrefix)
+		var ij *indexJoinNode
+		ij, s = s.p.makeIndexJoin(s, c.exactPrefix)
+		plan = ij
+
+		// The index scan produces the rows that are then filtered by the
+		// remaining filter on the table side.
+		indexRows := t.rowCount
+		if sel := t.selectivity(ij.table.filter, ij.table.colID); rows/sel < indexRows {
+			indexRows = rows / sel
+		}
+		s.rowCountEstimate = clampRows(indexRows)
 	}
 
 	if log.V(3) {
@@ -324,6 +346,31 @@ func (oic orIndexConstraints) String() string {
 	return buf.String()
 }
 
+// selectivity returns the estimated fraction of the rows of the table that
+// are within the spans generated by the constraints.
+func (oic orIndexConstraints) selectivity(t *tableEstimate, colID colIDFn) float64 {
+	if len(oic) == 0 {
+		return 1
+	}
+	sel := 0.0
+	for _, ic := range oic {
+		icSel := 1.0
+		for _, c := range ic {
+			if c.start != nil {
+				icSel *= t.selectivity(c.start, colID)
+			}
+			if c.end != nil && c.end != c.start {
+				icSel *= t.selectivity(c.end, colID)
+			}
+		}
+		sel += icSel
+	}
+	if sel > 1 {
+		return 1
+	}
+	return sel
+}
+
 type indexInfo struct {
 	desc        *sqlbase.TableDescriptor
 	index       *sqlbase.IndexDescriptor
@@ -353,6 +400,24 @@ func (v *indexInfo) init(s *scanNode) {
 	}
 }
 
+// estimateCost sets the cost of using the index from the statistics of the
+// table: the number of keys read from the index, plus the cost of looking up
+// each row in the primary index if the index is not covering.
+func (v *indexInfo) estimateCost(t *tableEstimate, s *scanNode) {
+	rows := clampRows(t.rowCount * v.constraints.selectivity(t, s.colID))
+	primaryKeysPerRow := float64(1 + len(v.desc.Columns) - len(v.desc.PrimaryIndex.ColumnIDs))
+	if v.index == &v.desc.PrimaryIndex {
+		v.cost = rows * primaryKeysPerRow
+		return
+	}
+	v.cost = rows
+	if !v.covering {
+		// The lookups are random reads, which are significantly more expensive
+		// than reading the index.
+		v.cost += rows * primaryKeysPerRow * nonCoveringIndexPenalty
+	}
+}
+
 // analyzeExprs examines the range map to determine the cost of using the
 // index.
 func (v *indexInfo) analyzeExprs(exprs []parser.TypedExprs) {
@@ -61,6 +61,33 @@ func (ie InternalExecutor) QueryRowInTransaction(
 	return p.QueryRow(ctx, statement, qargs...)
 }
 
+// QueryRowsInTransaction executes the supplied SQL statement as part of the
+// supplied transaction and returns the resulting rows. Statements are
+// currently executed as the root user.
+func (ie InternalExecutor) QueryRowsInTransaction(
+	ctx context.Context, opName string, txn *client.Txn, statement string, qargs ...interface{},
+) ([]parser.Datums, error) {
+	p := makeInternalPlanner(opName, txn, security.RootUser, ie.LeaseManager.memMetrics)
+	defer finishInternalPlanner(p)
+	p.session.leases.leaseMgr = ie.LeaseManager
+	return p.queryRows(ctx, statement, qargs...)
+}
+
+// ValidateForeignKeyInTransaction checks, as part of the supplied
+// transaction, that every row of srcTable has a match in the table referenced
+// by the foreign key of its index srcIdx.
+func (ie InternalExecutor) ValidateForeignKeyInTransaction(
+	ctx context.Context,
+	txn *client.Txn,
+	srcTable *sqlbase.TableDescriptor,
+	srcIdx *sqlbase.IndexDescriptor,
+) error {
+	p := makeInternalPlanner("validate-fk", txn, security.RootUser, ie.LeaseManager.memMetrics)
+	defer finishInternalPlanner(p)
+	p.session.leases.leaseMgr = ie.LeaseManager
+	return p.validateForeignKey(ctx, srcTable, srcIdx)
+}
+
 // GetTableSpan gets the key span for a SQL table, including any indices.
 func (ie InternalExecutor) GetTableSpan(
 	ctx context.Context, user string, txn *client.Txn, dbName, tableName string,
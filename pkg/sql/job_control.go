@@ -0,0 +1,142 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/security"
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+)
+
+// jobStatusTransitions lists, for each status that can be requested by PAUSE
+// JOB, RESUME JOB and CANCEL JOB, the statuses a job may be in when the
+// request is made.
+var jobStatusTransitions = map[JobStatus][]JobStatus{
+	JobStatusPaused:   {JobStatusPending, JobStatusRunning},
+	JobStatusRunning:  {JobStatusPaused},
+	JobStatusCanceled: {JobStatusPending, JobStatusRunning, JobStatusPaused},
+}
+
+type controlJobNode struct {
+	p         *planner
+	jobID     parser.TypedExpr
+	newStatus JobStatus
+	action    string
+}
+
+// PauseJob pauses a running job. The job stops at its next checkpoint.
+// Privileges: root or the user that created the job.
+func (p *planner) PauseJob(ctx context.Context, n *parser.PauseJob) (planNode, error) {
+	return p.controlJob(ctx, n.ID, JobStatusPaused, "pause")
+}
+
+// ResumeJob resumes a paused job.
+// Privileges: root or the user that created the job.
+func (p *planner) ResumeJob(ctx context.Context, n *parser.ResumeJob) (planNode, error) {
+	return p.controlJob(ctx, n.ID, JobStatusRunning, "resume")
+}
+
+// CancelJob cancels a job. The job stops at its next checkpoint and cleans up
+// after itself.
+// Privileges: root or the user that created the job.
+func (p *planner) CancelJob(ctx context.Context, n *parser.CancelJob) (planNode, error) {
+	return p.controlJob(ctx, n.ID, JobStatusCanceled, "cancel")
+}
+
+func (p *planner) controlJob(
+	ctx context.Context, jobID parser.Expr, newStatus JobStatus, action string,
+) (planNode, error) {
+	typedJobID, err := p.analyzeExpr(
+		ctx, jobID, nil, parser.IndexedVarHelper{}, parser.TypeInt, true, "JOB",
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &controlJobNode{
+		p:         p,
+		jobID:     typedJobID,
+		newStatus: newStatus,
+		action:    action,
+	}, nil
+}
+
+func (n *controlJobNode) Start(ctx context.Context) error {
+	d, err := n.jobID.Eval(&n.p.evalCtx)
+	if err != nil {
+		return err
+	}
+	if d == parser.DNull {
+		return errors.Errorf("cannot %s job: job ID is NULL", n.action)
+	}
+	jobID := int64(parser.MustBeDInt(d))
+
+	ex := InternalExecutor{LeaseManager: n.p.LeaseMgr()}
+	const selectStmt = "SELECT status, payload FROM system.jobs WHERE id = $1"
+	row, err := ex.QueryRowInTransaction(ctx, n.action+"-job", n.p.txn, selectStmt, jobID)
+	if err != nil {
+		return err
+	}
+	if row == nil {
+		return errors.Errorf("job %d does not exist", jobID)
+	}
+	status := JobStatus(parser.MustBeDString(row[0]))
+	payload, err := unmarshalJobPayload(row[1])
+	if err != nil {
+		return err
+	}
+	if user := n.p.User(); user != security.RootUser && user != payload.Username {
+		return errors.Errorf("only %s or the user that created job %d is allowed to %s it",
+			security.RootUser, jobID, n.action)
+	}
+	if !jobStatusAllowsTransition(status, n.newStatus) {
+		return errors.Errorf("cannot %s job %d with status %s", n.action, jobID, status)
+	}
+
+	payload.ModifiedMicros = jobTimestamp(timeutil.Now())
+	payloadBytes, err := protoutil.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	const updateStmt = "UPDATE system.jobs SET status = $1, payload = $2 WHERE id = $3"
+	_, err = ex.ExecuteStatementInTransaction(
+		ctx, n.action+"-job", n.p.txn, updateStmt, n.newStatus, payloadBytes, jobID)
+	return err
+}
+
+func jobStatusAllowsTransition(from, to JobStatus) bool {
+	for _, s := range jobStatusTransitions[to] {
+		if s == from {
+			return true
+		}
+	}
+	return false
+}
+
+func (*controlJobNode) Next(context.Context) (bool, error) { return false, nil }
+func (*controlJobNode) Close(context.Context)              {}
+func (*controlJobNode) Columns() ResultColumns             { return make(ResultColumns, 0) }
+func (*controlJobNode) Ordering() orderingInfo             { return orderingInfo{} }
+func (*controlJobNode) Values() parser.Datums              { return parser.Datums{} }
+func (*controlJobNode) DebugValues() debugValues           { return debugValues{} }
+func (*controlJobNode) MarkDebug(mode explainMode)         {}
+
+func (*controlJobNode) Spans(context.Context) (_, _ roachpb.Spans, _ error) {
+	panic("unimplemented")
+}
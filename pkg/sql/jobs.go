@@ -17,11 +17,13 @@
 package sql
 
 import (
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/net/context"
 
 	"github.com/cockroachdb/cockroach/pkg/internal/client"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/sql/parser"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
@@ -42,6 +44,18 @@ type JobLogger struct {
 	ex    InternalExecutor
 	jobID *int64
 	Job   JobRecord
+
+	// registry, if set, is used to lease the job to this node when it is
+	// created. See JobRegistry.
+	registry *JobRegistry
+	// lease is the lease under which this JobLogger runs the job. When set,
+	// updates to the job record fail with an error for which
+	// IsJobLeaseLostError returns true if the lease has been taken over by
+	// another node.
+	lease *JobLease
+
+	// statusCheckedAt is the last time CheckStatus read the job's status.
+	statusCheckedAt time.Time
 }
 
 // JobRecord stores the job fields that are not automatically managed by
@@ -66,9 +80,53 @@ const (
 	JobStatusFailed JobStatus = "failed"
 	// JobStatusSucceeded is for jobs that have successfully completed.
 	JobStatusSucceeded JobStatus = "succeeded"
+	// JobStatusPaused is for jobs that were paused with PAUSE JOB. A paused job
+	// stops at its next checkpoint until it is resumed or canceled.
+	JobStatusPaused JobStatus = "paused"
+	// JobStatusCanceled is for jobs that were canceled with CANCEL JOB.
+	JobStatusCanceled JobStatus = "canceled"
 )
 
-// NewJobLogger creates a new JobLogger.
+// errJobCanceled is returned to a job that has been canceled.
+var errJobCanceled = errors.New("job canceled")
+
+// IsJobCanceledError returns true if err indicates that the job which
+// returned it was canceled by the user.
+func IsJobCanceledError(err error) bool {
+	return errors.Cause(err) == errJobCanceled
+}
+
+// errJobLeaseLost is returned to a job whose lease has been taken over by
+// another node, which is now responsible for running it.
+var errJobLeaseLost = errors.New("job lease lost: the job was adopted by another node")
+
+// IsJobLeaseLostError returns true if err indicates that the job which
+// returned it is no longer leased to this node. The caller must stop working
+// on the job without updating its record.
+func IsJobLeaseLostError(err error) bool {
+	return errors.Cause(err) == errJobLeaseLost
+}
+
+// jobStatusPollInterval is the minimum interval between reads of the job
+// record by CheckStatus, and how often a paused job checks whether it has been
+// resumed. It is accessed atomically, since tests override it while jobs
+// are running.
+var jobStatusPollInterval = int64(5 * time.Second)
+
+func getJobStatusPollInterval() time.Duration {
+	return time.Duration(atomic.LoadInt64(&jobStatusPollInterval))
+}
+
+// TestingSetJobStatusPollInterval overrides how often running jobs poll their
+// record for state changes. It returns a function that restores the original
+// interval.
+func TestingSetJobStatusPollInterval(d time.Duration) func() {
+	orig := atomic.SwapInt64(&jobStatusPollInterval, int64(d))
+	return func() { atomic.StoreInt64(&jobStatusPollInterval, orig) }
+}
+
+// NewJobLogger creates a new JobLogger. The job is not leased to any node, so
+// it is never adopted by a JobRegistry; see JobRegistry.NewJobLogger.
 func NewJobLogger(db *client.DB, leaseMgr *LeaseManager, job JobRecord) JobLogger {
 	return JobLogger{
 		db:  db,
@@ -92,23 +150,40 @@ func (jl *JobLogger) Created(ctx context.Context) error {
 		Username:      jl.Job.Username,
 		DescriptorIDs: jl.Job.DescriptorIDs,
 	}
-	switch d := jl.Job.Details.(type) {
-	case BackupJobDetails:
-		payload.Details = &JobPayload_Backup{Backup: &d}
-	case RestoreJobDetails:
-		payload.Details = &JobPayload_Restore{Restore: &d}
-	default:
-		return errors.Errorf("JobLogger: unsupported job details type %T", d)
+	if err := payload.setDetails(jl.Job.Details); err != nil {
+		return err
+	}
+	if jl.registry != nil {
+		lease, err := jl.registry.newLease()
+		if err != nil {
+			return err
+		}
+		payload.Lease = lease
+	}
+	if err := jl.insertJobRecord(ctx, payload); err != nil {
+		return err
+	}
+	jl.lease = payload.Lease
+	if jl.registry != nil {
+		jl.registry.register(*jl.jobID)
 	}
-	return jl.insertJobRecord(ctx, payload)
+	return nil
 }
 
 // Started marks the tracked job as started.
 func (jl *JobLogger) Started(ctx context.Context) error {
-	return jl.updateJobRecord(ctx, JobStatusRunning, func(payload *JobPayload) (bool, error) {
+	return jl.updateJobRecord(ctx, func(status *JobStatus, payload *JobPayload) (bool, error) {
+		if *status == JobStatusCanceled {
+			return false, errJobCanceled
+		}
 		if payload.StartedMicros != 0 {
 			return false, errors.Errorf("JobLogger: job %d already started", jl.jobID)
 		}
+		// A job that was paused before it started stays paused; it blocks in
+		// CheckStatus until it is resumed.
+		if *status == JobStatusPending {
+			*status = JobStatusRunning
+		}
 		payload.StartedMicros = jobTimestamp(timeutil.Now())
 		return true, nil
 	})
@@ -124,7 +199,7 @@ func (jl *JobLogger) Progressed(ctx context.Context, fractionCompleted float32)
 			fractionCompleted, jl.jobID,
 		)
 	}
-	return jl.updateJobRecord(ctx, JobStatusRunning, func(payload *JobPayload) (bool, error) {
+	return jl.updateJobRecord(ctx, func(_ *JobStatus, payload *JobPayload) (bool, error) {
 		if payload.StartedMicros == 0 {
 			return false, errors.Errorf("JobLogger: job %d not started", jl.jobID)
 		}
@@ -139,20 +214,47 @@ func (jl *JobLogger) Progressed(ctx context.Context, fractionCompleted float32)
 	})
 }
 
+// SetDetails replaces the details of the tracked job, for example to record a
+// checkpoint from which a long-running job can later be resumed. The job's
+// details must be of the same type as the ones it was created with.
+func (jl *JobLogger) SetDetails(ctx context.Context, details interface{}) error {
+	return jl.updateJobRecord(ctx, func(_ *JobStatus, payload *JobPayload) (bool, error) {
+		if payload.FinishedMicros != 0 {
+			return false, errors.Errorf("JobLogger: job %d already finished", jl.jobID)
+		}
+		oldTyp := payload.typ()
+		if err := payload.setDetails(details); err != nil {
+			return false, err
+		}
+		if payload.typ() != oldTyp {
+			return false, errors.Errorf("JobLogger: cannot change type of job %d from %s to %s",
+				jl.jobID, oldTyp, payload.typ())
+		}
+		return true, nil
+	})
+}
+
 // Failed marks the tracked job as having failed with the given error. Any
 // errors encountered while updating the jobs table are logged but not returned,
 // under the assumption that the the caller is already handling a more important
-// error and doesn't care about this one.
+// error and doesn't care about this one. A job that was canceled keeps its
+// canceled status.
 func (jl *JobLogger) Failed(ctx context.Context, err error) {
 	// To simplify cleanup routines, it is not an error to call Failed on a job
 	// that was never Created.
 	if jl.jobID == nil {
 		return
 	}
-	internalErr := jl.updateJobRecord(ctx, JobStatusFailed, func(payload *JobPayload) (bool, error) {
+	if jl.registry != nil {
+		defer jl.registry.unregister(*jl.jobID)
+	}
+	internalErr := jl.updateJobRecord(ctx, func(status *JobStatus, payload *JobPayload) (bool, error) {
 		if payload.FinishedMicros != 0 {
 			return false, errors.Errorf("JobLogger: job %d already finished", jl.jobID)
 		}
+		if *status != JobStatusCanceled {
+			*status = JobStatusFailed
+		}
 		payload.Error = err.Error()
 		payload.FinishedMicros = jobTimestamp(timeutil.Now())
 		return true, nil
@@ -164,18 +266,104 @@ func (jl *JobLogger) Failed(ctx context.Context, err error) {
 }
 
 // Succeeded marks the tracked job as having succeeded and sets its fraction
-// completed to 1.0.
+// completed to 1.0. A job that completed its work before noticing that it was
+// paused or canceled is marked as succeeded all the same.
 func (jl *JobLogger) Succeeded(ctx context.Context) error {
-	return jl.updateJobRecord(ctx, JobStatusSucceeded, func(payload *JobPayload) (bool, error) {
+	if jl.registry != nil && jl.jobID != nil {
+		defer jl.registry.unregister(*jl.jobID)
+	}
+	return jl.updateJobRecord(ctx, func(status *JobStatus, payload *JobPayload) (bool, error) {
 		if payload.FinishedMicros != 0 {
 			return false, errors.Errorf("JobLogger: job %d already finished", jl.jobID)
 		}
+		*status = JobStatusSucceeded
 		payload.FinishedMicros = jobTimestamp(timeutil.Now())
 		payload.FractionCompleted = 1.0
 		return true, nil
 	})
 }
 
+// CheckStatus is called by a running job at its checkpoints to honor the
+// state changes requested by PAUSE JOB, RESUME JOB and CANCEL JOB. If the job
+// has been paused, CheckStatus blocks until it is resumed or canceled. If the
+// job has been canceled, an error for which IsJobCanceledError returns true is
+// returned, and the caller is expected to clean up after itself and stop.
+//
+// To avoid hammering the system.jobs table, the job record is read at most
+// once per jobStatusPollInterval; calls in between return nil immediately.
+// CheckStatus must not be called concurrently on the same JobLogger.
+func (jl *JobLogger) CheckStatus(ctx context.Context) error {
+	if jl.jobID == nil {
+		return errors.New("JobLogger cannot check status: job not created")
+	}
+	if timeutil.Since(jl.statusCheckedAt) < getJobStatusPollInterval() {
+		return nil
+	}
+	for loggedPause := false; ; {
+		status, payload, err := jl.load(ctx)
+		if err != nil {
+			return err
+		}
+		if err := jl.checkLease(payload); err != nil {
+			return err
+		}
+		jl.statusCheckedAt = timeutil.Now()
+		switch status {
+		case JobStatusCanceled:
+			return errJobCanceled
+		case JobStatusPaused:
+			if !loggedPause {
+				log.Infof(ctx, "job %d paused", *jl.jobID)
+				loggedPause = true
+			}
+			select {
+			case <-time.After(getJobStatusPollInterval()):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		default:
+			if loggedPause {
+				log.Infof(ctx, "job %d resumed", *jl.jobID)
+			}
+			return nil
+		}
+	}
+}
+
+// load reads the status and payload of the tracked job.
+func (jl *JobLogger) load(ctx context.Context) (JobStatus, *JobPayload, error) {
+	var status JobStatus
+	var payload *JobPayload
+	if err := jl.db.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
+		const selectStmt = "SELECT status, payload FROM system.jobs WHERE id = $1"
+		row, err := jl.ex.QueryRowInTransaction(ctx, "job-status", txn, selectStmt, *jl.jobID)
+		if err != nil {
+			return err
+		}
+		if row == nil {
+			return errors.Errorf("JobLogger: job %d not found", *jl.jobID)
+		}
+		status = JobStatus(parser.MustBeDString(row[0]))
+		payload, err = unmarshalJobPayload(row[1])
+		return err
+	}); err != nil {
+		return "", nil, err
+	}
+	return status, payload, nil
+}
+
+// checkLease returns errJobLeaseLost if the job, as described by payload, is
+// no longer leased to this JobLogger.
+func (jl *JobLogger) checkLease(payload *JobPayload) error {
+	if jl.lease == nil {
+		return nil
+	}
+	if l := payload.Lease; l == nil || l.NodeID != jl.lease.NodeID || l.Epoch != jl.lease.Epoch {
+		return errJobLeaseLost
+	}
+	return nil
+}
+
 func (jl *JobLogger) insertJobRecord(ctx context.Context, payload *JobPayload) error {
 	if jl.jobID != nil {
 		return errors.Errorf("JobLogger cannot create job: job %d already created", jl.jobID)
@@ -200,25 +388,31 @@ func (jl *JobLogger) insertJobRecord(ctx context.Context, payload *JobPayload) e
 	return nil
 }
 
+// updateJobRecord reads the job's status and payload, passes them to updateFn
+// to be modified in place, and writes them back if updateFn returns true.
 func (jl *JobLogger) updateJobRecord(
-	ctx context.Context, newStatus JobStatus, updateFn func(*JobPayload) (doUpdate bool, err error),
+	ctx context.Context, updateFn func(*JobStatus, *JobPayload) (doUpdate bool, err error),
 ) error {
 	if jl.jobID == nil {
 		return errors.New("JobLogger cannot update job: job not created")
 	}
 
 	return jl.db.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
-		const selectStmt = "SELECT payload FROM system.jobs WHERE id = $1"
+		const selectStmt = "SELECT status, payload FROM system.jobs WHERE id = $1"
 		row, err := jl.ex.QueryRowInTransaction(ctx, "log-job", txn, selectStmt, *jl.jobID)
 		if err != nil {
 			return err
 		}
 
-		payload, err := unmarshalJobPayload(row[0])
+		status := JobStatus(parser.MustBeDString(row[0]))
+		payload, err := unmarshalJobPayload(row[1])
 		if err != nil {
 			return err
 		}
-		doUpdate, err := updateFn(payload)
+		if err := jl.checkLease(payload); err != nil {
+			return err
+		}
+		doUpdate, err := updateFn(&status, payload)
 		if err != nil {
 			return err
 		}
@@ -233,7 +427,7 @@ func (jl *JobLogger) updateJobRecord(
 
 		const updateStmt = "UPDATE system.jobs SET status = $1, payload = $2 WHERE id = $3"
 		n, err := jl.ex.ExecuteStatementInTransaction(
-			ctx, "job-update", txn, updateStmt, newStatus, payloadBytes, *jl.jobID)
+			ctx, "job-update", txn, updateStmt, status, payloadBytes, *jl.jobID)
 		if err != nil {
 			return err
 		}
@@ -247,16 +441,79 @@ func (jl *JobLogger) updateJobRecord(
 
 // Job types are named for the SQL query that creates them.
 const (
-	JobTypeBackup  string = "BACKUP"
-	JobTypeRestore string = "RESTORE"
+	JobTypeBackup      string = "BACKUP"
+	JobTypeRestore     string = "RESTORE"
+	JobTypeChangefeed  string = "CHANGEFEED"
+	JobTypeImport      string = "IMPORT"
+	JobTypeCreateStats string = "CREATE STATISTICS"
 )
 
+// setDetails sets the details of the payload. The encryption keys of backup
+// and restore jobs are left out: they are derived from the user's passphrase,
+// and anyone able to read system.jobs or a backup of it could otherwise
+// decrypt the backups.
+func (jp *JobPayload) setDetails(details interface{}) error {
+	switch d := details.(type) {
+	case BackupJobDetails:
+		d.Encryption = withoutEncryptionKey(d.Encryption)
+		jp.Details = &JobPayload_Backup{Backup: &d}
+	case RestoreJobDetails:
+		d.Encryption = withoutEncryptionKey(d.Encryption)
+		jp.Details = &JobPayload_Restore{Restore: &d}
+	case ChangefeedJobDetails:
+		jp.Details = &JobPayload_Changefeed{Changefeed: &d}
+	case ImportJobDetails:
+		jp.Details = &JobPayload_Import{Import: &d}
+	case CreateStatsJobDetails:
+		jp.Details = &JobPayload_CreateStats{CreateStats: &d}
+	default:
+		return errors.Errorf("JobLogger: unsupported job details type %T", d)
+	}
+	return nil
+}
+
+// withoutEncryptionKey returns a copy of the given encryption options without
+// their key, or nil if they are nil.
+func withoutEncryptionKey(
+	encryption *roachpb.FileEncryptionOptions,
+) *roachpb.FileEncryptionOptions {
+	if encryption == nil {
+		return nil
+	}
+	return &roachpb.FileEncryptionOptions{}
+}
+
+// details returns the details of the job, of one of the types accepted by
+// setDetails.
+func (jp *JobPayload) details() interface{} {
+	switch d := jp.Details.(type) {
+	case *JobPayload_Backup:
+		return *d.Backup
+	case *JobPayload_Restore:
+		return *d.Restore
+	case *JobPayload_Changefeed:
+		return *d.Changefeed
+	case *JobPayload_Import:
+		return *d.Import
+	case *JobPayload_CreateStats:
+		return *d.CreateStats
+	default:
+		return nil
+	}
+}
+
 func (jp *JobPayload) typ() string {
 	switch jp.Details.(type) {
 	case *JobPayload_Backup:
 		return JobTypeBackup
 	case *JobPayload_Restore:
 		return JobTypeRestore
+	case *JobPayload_Changefeed:
+		return JobTypeChangefeed
+	case *JobPayload_Import:
+		return JobTypeImport
+	case *JobPayload_CreateStats:
+		return JobTypeCreateStats
 	default:
 		panic("JobPayload.typ called on a payload with an unknown details type")
 	}
@@ -0,0 +1,355 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/internal/client"
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/storage"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/cockroachdb/cockroach/pkg/util/stop"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+)
+
+// JobResumer continues running an adopted job from the progress checkpointed
+// in its details, which are available in job.Job.Details. It returns when
+// the job's work is done; the registry then marks the job as succeeded or
+// failed.
+type JobResumer func(ctx context.Context, execCfg *ExecutorConfig, job *JobLogger) error
+
+var jobResumers = map[string]JobResumer{}
+
+// RegisterJobResumer registers the function used to resume orphaned jobs of
+// the given type. It is meant to be called from init functions.
+func RegisterJobResumer(typ string, fn JobResumer) {
+	jobResumers[typ] = fn
+}
+
+// TestingRegisterJobResumer replaces the function used to resume orphaned
+// jobs of the given type. It returns a function that restores the previous
+// one.
+func TestingRegisterJobResumer(typ string, fn JobResumer) func() {
+	orig, ok := jobResumers[typ]
+	jobResumers[typ] = fn
+	return func() {
+		if ok {
+			jobResumers[typ] = orig
+		} else {
+			delete(jobResumers, typ)
+		}
+	}
+}
+
+// jobAdoptInterval is how often every node scans the jobs table for jobs to
+// adopt.
+var jobAdoptInterval = 30 * time.Second
+
+// TestingSetJobAdoptInterval overrides how often nodes look for orphaned jobs
+// to adopt. It must be called before the registry is started. It returns a
+// function that restores the original interval.
+func TestingSetJobAdoptInterval(d time.Duration) func() {
+	orig := jobAdoptInterval
+	jobAdoptInterval = d
+	return func() { jobAdoptInterval = orig }
+}
+
+// JobRegistry leases the jobs created on this node to it and adopts the jobs
+// whose node has died.
+//
+// A job's lease records the node that runs the job and that node's liveness
+// epoch. A job is orphaned once the liveness epoch of its node has advanced,
+// which the registry of another node brings about if the node is no longer
+// live. The registry of any node may then take over the lease and resume the
+// job with the JobResumer registered for its type. The former node notices
+// that it lost the lease the next time it updates the job record and stops
+// working on the job.
+type JobRegistry struct {
+	ac           log.AmbientContext
+	db           *client.DB
+	ex           InternalExecutor
+	nodeID       *base.NodeIDContainer
+	nodeLiveness *storage.NodeLiveness
+
+	mu struct {
+		syncutil.Mutex
+		// running is the set of IDs of the jobs running on this node, which are
+		// never adopted by it.
+		running map[int64]struct{}
+	}
+}
+
+// NewJobRegistry creates a new JobRegistry.
+func NewJobRegistry(
+	ac log.AmbientContext,
+	db *client.DB,
+	leaseMgr *LeaseManager,
+	nodeID *base.NodeIDContainer,
+	nodeLiveness *storage.NodeLiveness,
+) *JobRegistry {
+	r := &JobRegistry{
+		ac:           ac,
+		db:           db,
+		ex:           InternalExecutor{LeaseManager: leaseMgr},
+		nodeID:       nodeID,
+		nodeLiveness: nodeLiveness,
+	}
+	r.mu.running = make(map[int64]struct{})
+	return r
+}
+
+// NewJobLogger creates a new JobLogger whose job is leased to this node once
+// it is created.
+func (r *JobRegistry) NewJobLogger(job JobRecord) JobLogger {
+	jl := NewJobLogger(r.db, r.ex.LeaseManager, job)
+	jl.registry = r
+	return jl
+}
+
+func (r *JobRegistry) register(jobID int64) {
+	r.mu.Lock()
+	r.mu.running[jobID] = struct{}{}
+	r.mu.Unlock()
+}
+
+func (r *JobRegistry) unregister(jobID int64) {
+	r.mu.Lock()
+	delete(r.mu.running, jobID)
+	r.mu.Unlock()
+}
+
+func (r *JobRegistry) isRunning(jobID int64) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.mu.running[jobID]
+	return ok
+}
+
+func (r *JobRegistry) newLease() (*JobLease, error) {
+	liveness, err := r.nodeLiveness.Self()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to lease job")
+	}
+	return &JobLease{NodeID: r.nodeID.Get(), Epoch: liveness.Epoch}, nil
+}
+
+// isOrphaned returns whether the node holding the given job lease is no
+// longer running the job, which is the case once the node's liveness epoch
+// has advanced past the epoch of the lease. If the node is no longer live,
+// its epoch is incremented first: a node that is merely not live may still be
+// running the job. Jobs without a lease, which were created before leases
+// existed or outside of a registry, are never orphaned.
+func (r *JobRegistry) isOrphaned(ctx context.Context, lease *JobLease) bool {
+	if lease == nil {
+		return false
+	}
+	liveness, err := r.nodeLiveness.GetLiveness(lease.NodeID)
+	if err != nil {
+		// Without the node's liveness record, there's no telling whether it
+		// is still running the job.
+		return false
+	}
+	if liveness.Epoch > lease.Epoch {
+		return true
+	}
+	if live, err := r.nodeLiveness.IsLive(lease.NodeID); err != nil || live {
+		return false
+	}
+	if err := r.nodeLiveness.IncrementEpoch(ctx, liveness); err != nil {
+		log.Infof(ctx, "unable to increment liveness epoch of node %d: %s", lease.NodeID, err)
+		return false
+	}
+	return true
+}
+
+// Start starts the worker that periodically adopts orphaned jobs.
+func (r *JobRegistry) Start(ctx context.Context, stopper *stop.Stopper, execCfg *ExecutorConfig) {
+	ctx = r.ac.AnnotateCtx(ctx)
+	stopper.RunWorker(func() {
+		for {
+			select {
+			case <-time.After(jobAdoptInterval):
+				if err := r.maybeAdoptJobs(ctx, stopper, execCfg); err != nil {
+					log.Errorf(ctx, "error while adopting jobs: %+v", err)
+				}
+			case <-stopper.ShouldStop():
+				return
+			}
+		}
+	})
+}
+
+func (r *JobRegistry) maybeAdoptJobs(
+	ctx context.Context, stopper *stop.Stopper, execCfg *ExecutorConfig,
+) error {
+	var rows []parser.Datums
+	if err := r.db.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
+		const stmt = `SELECT id, payload FROM system.jobs WHERE status IN ($1, $2, $3, $4)`
+		var err error
+		rows, err = r.ex.QueryRowsInTransaction(ctx, "adopt-jobs", txn, stmt,
+			JobStatusPending, JobStatusRunning, JobStatusPaused, JobStatusCanceled)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		id := int64(parser.MustBeDInt(row[0]))
+		payload, err := unmarshalJobPayload(row[1])
+		if err != nil {
+			return err
+		}
+		// Canceled jobs that haven't finished are adopted so that they can clean
+		// up after themselves.
+		if payload.FinishedMicros != 0 || r.isRunning(id) || !r.isOrphaned(ctx, payload.Lease) {
+			continue
+		}
+		resume, ok := jobResumers[payload.typ()]
+		if !ok {
+			continue
+		}
+		jl, err := r.adopt(ctx, id)
+		if err != nil {
+			log.Warningf(ctx, "unable to adopt job %d: %+v", id, err)
+			continue
+		}
+		if jl == nil {
+			// Another node adopted the job first.
+			continue
+		}
+		log.Infof(ctx, "adopted job %d (%s)", id, jl.Job.Description)
+		r.register(id)
+		r.resume(ctx, stopper, execCfg, jl, resume)
+	}
+	return nil
+}
+
+// adopt takes over the lease of the given job if it is still orphaned, and
+// returns a JobLogger for the job. It returns nil if the job was adopted by
+// another node in the meantime.
+func (r *JobRegistry) adopt(ctx context.Context, id int64) (*JobLogger, error) {
+	var jl *JobLogger
+	err := r.db.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
+		jl = nil
+		const selectStmt = "SELECT payload FROM system.jobs WHERE id = $1"
+		row, err := r.ex.QueryRowInTransaction(ctx, "adopt-job", txn, selectStmt, id)
+		if err != nil {
+			return err
+		}
+		if row == nil {
+			return errors.Errorf("job %d not found", id)
+		}
+		payload, err := unmarshalJobPayload(row[0])
+		if err != nil {
+			return err
+		}
+		if payload.FinishedMicros != 0 || !r.isOrphaned(ctx, payload.Lease) {
+			return nil
+		}
+		if payload.Lease, err = r.newLease(); err != nil {
+			return err
+		}
+		payload.ModifiedMicros = jobTimestamp(timeutil.Now())
+		payloadBytes, err := protoutil.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		const updateStmt = "UPDATE system.jobs SET payload = $1 WHERE id = $2"
+		if _, err := r.ex.ExecuteStatementInTransaction(
+			ctx, "adopt-job", txn, updateStmt, payloadBytes, id,
+		); err != nil {
+			return err
+		}
+		jl = &JobLogger{
+			db:    r.db,
+			ex:    r.ex,
+			jobID: &id,
+			Job: JobRecord{
+				Description:   payload.Description,
+				Username:      payload.Username,
+				DescriptorIDs: payload.DescriptorIDs,
+				Details:       payload.details(),
+			},
+			registry: r,
+			lease:    payload.Lease,
+		}
+		return nil
+	})
+	return jl, err
+}
+
+// resume runs an adopted job in an async task.
+func (r *JobRegistry) resume(
+	ctx context.Context,
+	stopper *stop.Stopper,
+	execCfg *ExecutorConfig,
+	jl *JobLogger,
+	resume JobResumer,
+) {
+	jobCtx := stopper.WithCancel(r.ac.AnnotateCtx(context.Background()))
+	if err := stopper.RunAsyncTask(jobCtx, func(ctx context.Context) {
+		defer r.unregister(*jl.jobID)
+		err := jl.startAdopted(ctx)
+		if err == nil {
+			err = resume(ctx, execCfg, jl)
+		}
+		if err != nil {
+			if IsJobLeaseLostError(err) {
+				log.Infof(ctx, "job %d adopted by another node", *jl.jobID)
+				return
+			}
+			if ctx.Err() != nil {
+				// The node is shutting down; the job is adopted again once its
+				// lease has expired.
+				log.Infof(ctx, "resumed job %d interrupted: %s", *jl.jobID, err)
+				return
+			}
+			log.Errorf(ctx, "resumed job %d failed: %+v", *jl.jobID, err)
+			jl.Failed(ctx, err)
+			return
+		}
+		if err := jl.Succeeded(ctx); err != nil {
+			log.Errorf(ctx, "error while marking resumed job %d as successful: %+v", *jl.jobID, err)
+		}
+	}); err != nil {
+		r.unregister(*jl.jobID)
+		log.Warningf(ctx, "unable to resume job %d: %+v", *jl.jobID, err)
+	}
+}
+
+// startAdopted marks an adopted job as started, unless its former node had
+// already done so.
+func (jl *JobLogger) startAdopted(ctx context.Context) error {
+	return jl.updateJobRecord(ctx, func(status *JobStatus, payload *JobPayload) (bool, error) {
+		if payload.StartedMicros != 0 {
+			return false, nil
+		}
+		if *status == JobStatusCanceled {
+			return false, errJobCanceled
+		}
+		if *status == JobStatusPending {
+			*status = JobStatusRunning
+		}
+		payload.StartedMicros = jobTimestamp(timeutil.Now())
+		return true, nil
+	})
+}
@@ -25,13 +25,16 @@ import (
 	"github.com/pkg/errors"
 	"golang.org/x/net/context"
 
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/sql"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
 	"github.com/cockroachdb/cockroach/pkg/testutils"
 	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
 	"github.com/cockroachdb/cockroach/pkg/testutils/sqlutils"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
 	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/gogo/protobuf/proto"
 	"github.com/kr/pretty"
 	"github.com/lib/pq"
 )
@@ -123,7 +126,8 @@ func verifyJobRecord(
 	if started.Valid && created.Time.After(started.Time) {
 		return errors.Errorf("created time %v is after started time %v", created, started)
 	}
-	if status == sql.JobStatusRunning {
+	if status == sql.JobStatusRunning || status == sql.JobStatusPaused ||
+		(status == sql.JobStatusCanceled && !finished.Valid) {
 		return verifyModifiedAgainst("started", started.Time)
 	}
 
@@ -398,4 +402,256 @@ func TestJobLogger(t *testing.T) {
 			t.Fatal(err)
 		}
 	})
+
+	t.Run("pause, resume and cancel", func(t *testing.T) {
+		defer sql.TestingSetJobStatusPollInterval(time.Millisecond)()
+		db := sqlutils.MakeSQLRunner(t, rawSQLDB)
+		job := sql.JobRecord{Details: sql.RestoreJobDetails{}}
+		expectation := jobExpectation{
+			Job:    job,
+			Type:   sql.JobTypeRestore,
+			Before: timeutil.Now(),
+		}
+		logger := sql.NewJobLogger(kvDB, s.LeaseManager().(*sql.LeaseManager), job)
+		if err := logger.Created(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if err := logger.Started(ctx); err != nil {
+			t.Fatal(err)
+		}
+		jobID := *logger.JobID()
+
+		if _, err := rawSQLDB.Exec(`RESUME JOB $1`, jobID); !testutils.IsError(
+			err, `cannot resume job \d+ with status running`,
+		) {
+			t.Fatalf("expected 'cannot resume' error, but got %v", err)
+		}
+
+		db.Exec(`PAUSE JOB $1`, jobID)
+		if err := verifyJobRecord(db, sql.JobStatusPaused, expectation); err != nil {
+			t.Fatal(err)
+		}
+		// Progress updates don't unpause the job.
+		if err := logger.Progressed(ctx, 0.5); err != nil {
+			t.Fatal(err)
+		}
+		expectation.FractionCompleted = 0.5
+		if err := verifyJobRecord(db, sql.JobStatusPaused, expectation); err != nil {
+			t.Fatal(err)
+		}
+
+		// A paused job blocks in CheckStatus until it is resumed.
+		checkDone := make(chan error, 1)
+		go func() { checkDone <- logger.CheckStatus(ctx) }()
+		select {
+		case err := <-checkDone:
+			t.Fatalf("CheckStatus returned %v for a paused job", err)
+		case <-time.After(50 * time.Millisecond):
+		}
+		db.Exec(`RESUME JOB $1`, jobID)
+		if err := <-checkDone; err != nil {
+			t.Fatal(err)
+		}
+		if err := verifyJobRecord(db, sql.JobStatusRunning, expectation); err != nil {
+			t.Fatal(err)
+		}
+
+		db.Exec(`CANCEL JOB $1`, jobID)
+		if err := verifyJobRecord(db, sql.JobStatusCanceled, expectation); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+		if err := logger.CheckStatus(ctx); !sql.IsJobCanceledError(err) {
+			t.Fatalf("expected job canceled error, but got %v", err)
+		}
+		if _, err := rawSQLDB.Exec(`PAUSE JOB $1`, jobID); !testutils.IsError(
+			err, `cannot pause job \d+ with status canceled`,
+		) {
+			t.Fatalf("expected 'cannot pause' error, but got %v", err)
+		}
+
+		// Failing a canceled job records the error but keeps it canceled.
+		logger.Failed(ctx, errors.New("job canceled"))
+		expectation.Error = "job canceled"
+		if err := verifyJobRecord(db, sql.JobStatusCanceled, expectation); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("set details", func(t *testing.T) {
+		db := sqlutils.MakeSQLRunner(t, rawSQLDB)
+		logger := sql.NewJobLogger(kvDB, s.LeaseManager().(*sql.LeaseManager), sql.JobRecord{
+			Details: sql.ChangefeedJobDetails{SinkURI: "file:///foo"},
+		})
+		if err := logger.Created(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if err := logger.Started(ctx); err != nil {
+			t.Fatal(err)
+		}
+		details := sql.ChangefeedJobDetails{
+			SinkURI:   "file:///foo",
+			Highwater: hlc.Timestamp{WallTime: 123, Logical: 4},
+		}
+		if err := logger.SetDetails(ctx, details); err != nil {
+			t.Fatal(err)
+		}
+		var payloadBytes []byte
+		db.QueryRow(`SELECT payload FROM system.jobs WHERE id = $1`, *logger.JobID()).Scan(&payloadBytes)
+		var payload sql.JobPayload
+		if err := proto.Unmarshal(payloadBytes, &payload); err != nil {
+			t.Fatal(err)
+		}
+		if e, a := details, *payload.GetChangefeed(); e != a {
+			t.Fatalf("expected details %+v, got %+v", e, a)
+		}
+		if err := logger.SetDetails(ctx, sql.BackupJobDetails{}); !testutils.IsError(
+			err, `cannot change type of job \d+ from CHANGEFEED to BACKUP`,
+		) {
+			t.Fatalf("expected 'cannot change type' error, but got %v", err)
+		}
+	})
+
+	t.Run("encryption keys are not persisted", func(t *testing.T) {
+		db := sqlutils.MakeSQLRunner(t, rawSQLDB)
+		encryption := &roachpb.FileEncryptionOptions{Key: []byte("0123456789abcdef")}
+		logger := sql.NewJobLogger(kvDB, s.LeaseManager().(*sql.LeaseManager), sql.JobRecord{
+			Details: sql.BackupJobDetails{URI: "nodelocal:///foo", Encryption: encryption},
+		})
+		if err := logger.Created(ctx); err != nil {
+			t.Fatal(err)
+		}
+		var payloadBytes []byte
+		db.QueryRow(`SELECT payload FROM system.jobs WHERE id = $1`, *logger.JobID()).Scan(&payloadBytes)
+		var payload sql.JobPayload
+		if err := proto.Unmarshal(payloadBytes, &payload); err != nil {
+			t.Fatal(err)
+		}
+		if e := payload.GetBackup().Encryption; e == nil || len(e.Key) != 0 {
+			t.Fatalf("expected encryption without a key, got %+v", e)
+		}
+		// The running job keeps its key.
+		if e := logger.Job.Details.(sql.BackupJobDetails).Encryption; e != encryption {
+			t.Fatalf("expected encryption %+v, got %+v", encryption, e)
+		}
+	})
+}
+
+func TestJobRegistryAdoption(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer sql.TestingSetJobAdoptInterval(10 * time.Millisecond)()
+	ctx := context.TODO()
+
+	// Use changefeeds as the type of the job to adopt. Their resumer is
+	// global, and CCL builds register one of their own, so the original one is
+	// restored once the test is done.
+	resumed := make(chan *sql.JobLogger, 1)
+	defer sql.TestingRegisterJobResumer(sql.JobTypeChangefeed, func(
+		_ context.Context, _ *sql.ExecutorConfig, jl *sql.JobLogger,
+	) error {
+		resumed <- jl
+		return nil
+	})()
+
+	params, _ := createTestServerParams()
+	s, rawSQLDB, kvDB := serverutils.StartServer(t, params)
+	defer s.Stopper().Stop()
+	db := sqlutils.MakeSQLRunner(t, rawSQLDB)
+	registry := s.JobRegistry().(*sql.JobRegistry)
+
+	loadPayload := func(jobID int64) *sql.JobPayload {
+		var payloadBytes []byte
+		db.QueryRow(`SELECT payload FROM system.jobs WHERE id = $1`, jobID).Scan(&payloadBytes)
+		var payload sql.JobPayload
+		if err := proto.Unmarshal(payloadBytes, &payload); err != nil {
+			t.Fatal(err)
+		}
+		return &payload
+	}
+	setLease := func(jobID int64, lease *sql.JobLease) {
+		payload := loadPayload(jobID)
+		payload.Lease = lease
+		payloadBytes, err := proto.Marshal(payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		db.Exec(`UPDATE system.jobs SET payload = $1 WHERE id = $2`, payloadBytes, jobID)
+	}
+	expectNotAdopted := func(reason string) {
+		select {
+		case <-resumed:
+			t.Fatalf("job adopted while %s", reason)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	// A job created through the registry is leased to this node. Once the
+	// lease is taken away, the job's former owner can no longer update it.
+	// Backups have no resumer outside of CCL builds, so the job isn't adopted.
+	backupLogger := registry.NewJobLogger(sql.JobRecord{
+		Description: "lost",
+		Username:    "root",
+		Details:     sql.BackupJobDetails{},
+	})
+	if err := backupLogger.Created(ctx); err != nil {
+		t.Fatal(err)
+	}
+	lease := loadPayload(*backupLogger.JobID()).Lease
+	if lease == nil || lease.NodeID != s.NodeID() || lease.Epoch < 1 {
+		t.Fatalf("expected job to be leased to node %d at a positive epoch, got %+v",
+			s.NodeID(), lease)
+	}
+	setLease(*backupLogger.JobID(), &sql.JobLease{NodeID: s.NodeID(), Epoch: lease.Epoch - 1})
+	if err := backupLogger.Started(ctx); !sql.IsJobLeaseLostError(err) {
+		t.Fatalf("expected lease lost error, got %v", err)
+	}
+	if err := backupLogger.CheckStatus(ctx); !sql.IsJobLeaseLostError(err) {
+		t.Fatalf("expected lease lost error, got %v", err)
+	}
+
+	details := sql.ChangefeedJobDetails{
+		SinkURI:   "file:///foo",
+		Highwater: hlc.Timestamp{WallTime: 123},
+	}
+	logger := sql.NewJobLogger(kvDB, s.LeaseManager().(*sql.LeaseManager), sql.JobRecord{
+		Description: "orphan",
+		Username:    "root",
+		Details:     details,
+	})
+	if err := logger.Created(ctx); err != nil {
+		t.Fatal(err)
+	}
+	jobID := *logger.JobID()
+	expectNotAdopted("it has no lease")
+
+	setLease(jobID, lease)
+	expectNotAdopted("its node is live")
+
+	// Pretend that the job's node has restarted since it created the job.
+	setLease(jobID, &sql.JobLease{NodeID: s.NodeID(), Epoch: lease.Epoch - 1})
+	select {
+	case jl := <-resumed:
+		if e, a := jobID, *jl.JobID(); e != a {
+			t.Fatalf("expected job %d to be adopted, got %d", e, a)
+		}
+		if e, a := details, jl.Job.Details; e != a {
+			t.Fatalf("expected details %+v, got %+v", e, a)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the orphaned job to be adopted")
+	}
+	// The job was never started by its former owner, so the registry starts
+	// it before resuming it.
+	testutils.SucceedsSoon(t, func() error {
+		var status string
+		var started bool
+		db.QueryRow(
+			`SELECT status, started IS NOT NULL FROM crdb_internal.jobs WHERE id = $1`, jobID,
+		).Scan(&status, &started)
+		if status != string(sql.JobStatusSucceeded) || !started {
+			return errors.Errorf("expected job to start and succeed, got status %s (started: %t)",
+				status, started)
+		}
+		return nil
+	})
 }
@@ -167,6 +167,10 @@ type joinNode struct {
 	// finishedOutput indicates that we've finished writing all of the rows for
 	// this join and that we can quit as soon as our buffer is empty.
 	finishedOutput bool
+
+	// ordered is set once the order of the inner joins of the tree containing
+	// this join has been chosen (see orderJoins).
+	ordered bool
 }
 
 // commonColumns returns the names of columns common on the
@@ -588,11 +592,15 @@ func (n *joinNode) DebugValues() debugValues {
 
 // Close implements the planNode interface.
 func (n *joinNode) Close(ctx context.Context) {
+	n.closeBuffers(ctx)
+	n.right.plan.Close(ctx)
+	n.left.plan.Close(ctx)
+}
+
+// closeBuffers releases the memory held by the node, but not by its sources.
+func (n *joinNode) closeBuffers(ctx context.Context) {
 	n.buffer.Close(ctx)
 	n.buffer = nil
 	n.buckets.Close(ctx)
 	n.bucketsMemAcc.Wtxn(n.planner.session).Close(ctx)
-
-	n.right.plan.Close(ctx)
-	n.left.plan.Close(ctx)
 }
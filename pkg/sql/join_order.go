@@ -0,0 +1,437 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+)
+
+const (
+	// maxDPJoinRelations is the largest number of relations for which all the
+	// join orders are considered; larger joins are ordered greedily.
+	maxDPJoinRelations = 8
+	// maxJoinOrderRelations is the largest number of relations that can be
+	// reordered, as sets of relations are represented as bitmaps.
+	maxJoinOrderRelations = 64
+	// minJoinReorderGain is the minimal relative decrease of the estimated
+	// cost for which the written order of a join is changed. The estimates
+	// are rough, so the written order is kept when in doubt.
+	minJoinReorderGain = 0.5
+)
+
+// joinOrderPred is a predicate of a tree of inner joins.
+type joinOrderPred struct {
+	// relations is the set of relations referenced by the predicate.
+	relations uint64
+	// selectivity is the estimated fraction of rows satisfying the predicate.
+	selectivity float64
+}
+
+// joinOrderGraph describes a tree of inner joins for the purpose of choosing
+// the order in which its relations are joined. The cost of a join is the
+// number of rows it reads plus the number of rows it produces; the cost of a
+// tree of joins is the sum of the costs of its joins.
+type joinOrderGraph struct {
+	// rows is the estimated number of rows of each relation.
+	rows  []float64
+	preds []joinOrderPred
+}
+
+// rowCount returns the estimated number of rows produced by joining a set of
+// relations with all the predicates that apply to them.
+func (g *joinOrderGraph) rowCount(set uint64) float64 {
+	rows := 1.0
+	for i := range g.rows {
+		if set&(1<<uint(i)) != 0 {
+			rows *= g.rows[i]
+		}
+	}
+	for _, pred := range g.preds {
+		if pred.relations != 0 && pred.relations&^set == 0 {
+			rows *= pred.selectivity
+		}
+	}
+	return clampRows(rows)
+}
+
+// connected returns true if a predicate relates the given relation to the
+// given set of relations, i.e. joining them is not a cross product.
+func (g *joinOrderGraph) connected(set uint64, rel int) bool {
+	bit := uint64(1) << uint(rel)
+	for _, pred := range g.preds {
+		if pred.relations&bit != 0 && pred.relations&set != 0 && pred.relations&^(set|bit) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// joinCost returns the cost of joining two sets of relations.
+func (g *joinOrderGraph) joinCost(left, right uint64) float64 {
+	return g.rowCount(left) + g.rowCount(right) + g.rowCount(left|right)
+}
+
+// orderCost returns the cost of joining the relations from left to right in
+// the given order.
+func (g *joinOrderGraph) orderCost(order []int) float64 {
+	cost := 0.0
+	set := uint64(1) << uint(order[0])
+	for _, rel := range order[1:] {
+		bit := uint64(1) << uint(rel)
+		cost += g.joinCost(set, bit)
+		set |= bit
+	}
+	return cost
+}
+
+// bestOrder returns the order in which to join the relations from left to
+// right and its cost. Cross products are avoided whenever a predicate allows
+// it.
+func (g *joinOrderGraph) bestOrder() ([]int, float64) {
+	var order []int
+	if len(g.rows) <= maxDPJoinRelations {
+		order = g.dpOrder()
+	} else {
+		order = g.greedyOrder()
+	}
+	return order, g.orderCost(order)
+}
+
+// dpOrder finds the cheapest order using dynamic programming over the sets of
+// relations: the best order of a set is the best order of one of its subsets
+// with one less relation, followed by that relation.
+func (g *joinOrderGraph) dpOrder() []int {
+	n := uint(len(g.rows))
+	type setPlan struct {
+		cost float64
+		// last is the relation joined last.
+		last int
+	}
+	best := make([]setPlan, 1<<n)
+	for set := uint64(1); set < 1<<n; set++ {
+		if set&(set-1) == 0 {
+			// A single relation.
+			for i := uint(0); i < n; i++ {
+				if set == 1<<i {
+					best[set] = setPlan{last: int(i)}
+				}
+			}
+			continue
+		}
+		// Subsets are numbered below their supersets, so their best plans are
+		// known at this point. The second pass allows cross products, for
+		// when no relation of the set is connected to the others.
+		found := false
+		for pass := 0; pass < 2 && !found; pass++ {
+			for i := uint(0); i < n; i++ {
+				bit := uint64(1) << i
+				if set&bit == 0 {
+					continue
+				}
+				prev := set &^ bit
+				if pass == 0 && !g.connected(prev, int(i)) {
+					continue
+				}
+				// On ties, the relation written last is joined last, which keeps
+				// the order close to the written one.
+				cost := best[prev].cost + g.joinCost(prev, bit)
+				if !found || cost <= best[set].cost {
+					best[set] = setPlan{cost: cost, last: int(i)}
+					found = true
+				}
+			}
+		}
+	}
+
+	order := make([]int, n)
+	set := uint64(1)<<n - 1
+	for i := int(n) - 1; i >= 0; i-- {
+		order[i] = best[set].last
+		set &^= 1 << uint(order[i])
+	}
+	return order
+}
+
+// greedyOrder builds an order starting with the smallest relation, then
+// repeatedly joining the relation producing the fewest rows, preferring
+// relations connected to those already joined.
+func (g *joinOrderGraph) greedyOrder() []int {
+	n := len(g.rows)
+	order := make([]int, 0, n)
+	first := 0
+	for i := 1; i < n; i++ {
+		if g.rows[i] < g.rows[first] {
+			first = i
+		}
+	}
+	order = append(order, first)
+	set := uint64(1) << uint(first)
+
+	for len(order) < n {
+		next, nextConnected, nextRows := -1, false, 0.0
+		for i := 0; i < n; i++ {
+			bit := uint64(1) << uint(i)
+			if set&bit != 0 {
+				continue
+			}
+			connected := g.connected(set, i)
+			if next != -1 && nextConnected && !connected {
+				continue
+			}
+			rows := g.rowCount(set | bit)
+			if next == -1 || (connected && !nextConnected) || rows < nextRows {
+				next, nextConnected, nextRows = i, connected, rows
+			}
+		}
+		order = append(order, next)
+		set |= 1 << uint(next)
+	}
+	return order
+}
+
+// joinOrderTree is a tree of inner joins flattened into its relations, in
+// the order in which they appear in the tree.
+type joinOrderTree struct {
+	// joins are the joins of the tree.
+	joins []*joinNode
+	// relations are the data sources joined by the tree.
+	relations []planDataSource
+	// firstCol is the position, among the columns produced by the tree, of
+	// the first column of each relation.
+	firstCol []int
+	// colRel is the relation producing each of the columns of the tree.
+	colRel []int
+	// equalities lists the pairs of columns of the tree that are required
+	// to be equal.
+	equalities [][2]int
+	// conds are the other predicates of the joins, using IndexedVars that
+	// refer to the columns of the tree.
+	conds []parser.TypedExpr
+	// origCost is the cost of the tree as written.
+	origCost float64
+}
+
+// orderJoins chooses the order in which the relations of a tree of inner
+// joins are joined, using the estimated number of rows of the relations and
+// the estimated selectivity of the join predicates. It returns nil if the
+// tree is kept as written; otherwise it returns a plan joining the relations
+// in the new order and producing the columns of the tree in their original
+// order.
+func (p *planner) orderJoins(ctx context.Context, n *joinNode) (planNode, error) {
+	var t joinOrderTree
+	t.collect(n)
+	for _, j := range t.joins {
+		j.ordered = true
+	}
+	if len(t.relations) < 3 || len(t.relations) > maxJoinOrderRelations {
+		// Both orders of two relations have the same cost.
+		return nil, nil
+	}
+
+	g := joinOrderGraph{rows: make([]float64, len(t.relations))}
+	for i, rel := range t.relations {
+		g.rows[i] = clampRows(p.sourceRowCount(ctx, rel.plan))
+	}
+	for _, eq := range t.equalities {
+		left, right := t.colRel[eq[0]], t.colRel[eq[1]]
+		leftPlan, rightPlan := t.relations[left].plan, t.relations[right].plan
+		g.preds = append(g.preds, joinOrderPred{
+			relations: 1<<uint(left) | 1<<uint(right),
+			selectivity: p.joinEqualitySelectivity(
+				ctx, leftPlan, eq[0]-t.firstCol[left], g.rows[left],
+				rightPlan, eq[1]-t.firstCol[right], g.rows[right],
+			),
+		})
+	}
+	for _, cond := range t.conds {
+		var rels uint64
+		exprCheckVars(cond, func(expr parser.VariableExpr) (bool, parser.Expr) {
+			if iv, ok := expr.(*parser.IndexedVar); ok {
+				rels |= 1 << uint(t.colRel[iv.Idx])
+			}
+			return true, expr
+		})
+		g.preds = append(g.preds, joinOrderPred{relations: rels, selectivity: defaultFilterSelectivity})
+	}
+	t.origCost, _, _ = t.cost(&g, n, 0)
+
+	order, cost := g.bestOrder()
+	if log.V(2) {
+		log.Infof(ctx, "join order %v: cost=%v, written order cost=%v", order, cost, t.origCost)
+	}
+	if cost >= (1-minJoinReorderGain)*t.origCost {
+		return nil, nil
+	}
+	return p.makeOrderedJoins(ctx, &t, order)
+}
+
+// collect flattens the tree of inner joins rooted at the given source.
+// Joins that merge columns (USING and NATURAL) are left as relations, as
+// their columns cannot be reordered.
+func (t *joinOrderTree) collect(plan planNode) {
+	n, ok := plan.(*joinNode)
+	if !ok || n.ordered || n.joinType != joinTypeInner || n.pred.numMergedEqualityColumns != 0 {
+		return
+	}
+	t.joins = append(t.joins, n)
+	leftBegin := len(t.colRel)
+	t.collectSide(n.left)
+	rightBegin := len(t.colRel)
+	t.collectSide(n.right)
+
+	for i := range n.pred.leftEqualityIndices {
+		t.equalities = append(t.equalities, [2]int{
+			leftBegin + n.pred.leftEqualityIndices[i], rightBegin + n.pred.rightEqualityIndices[i],
+		})
+	}
+	if !isFilterTrue(n.pred.onCond) {
+		// The predicate refers to the columns of the join, which start at
+		// leftBegin in the tree.
+		cond := exprConvertVars(n.pred.onCond, func(expr parser.VariableExpr) (bool, parser.Expr) {
+			if iv, ok := expr.(*parser.IndexedVar); ok {
+				return true, parser.NewOrdinalReference(leftBegin + iv.Idx)
+			}
+			return true, expr
+		})
+		t.conds = append(t.conds, cond)
+	}
+}
+
+// collectSide adds a side of a join of the tree, either a join of the tree
+// or a relation.
+func (t *joinOrderTree) collectSide(src planDataSource) {
+	numJoins := len(t.joins)
+	t.collect(src.plan)
+	if len(t.joins) > numJoins {
+		return
+	}
+	rel := len(t.relations)
+	t.relations = append(t.relations, src)
+	t.firstCol = append(t.firstCol, len(t.colRel))
+	for range src.info.sourceColumns {
+		t.colRel = append(t.colRel, rel)
+	}
+}
+
+// cost returns the cost of the subtree rooted at the given plan, the set of
+// its relations, and the index of the relation following them.
+func (t *joinOrderTree) cost(
+	g *joinOrderGraph, plan planNode, firstRel int,
+) (cost float64, rels uint64, nextRel int) {
+	if n, ok := plan.(*joinNode); ok && t.isJoin(n) {
+		leftCost, left, rightRel := t.cost(g, n.left.plan, firstRel)
+		rightCost, right, nextRel := t.cost(g, n.right.plan, rightRel)
+		return leftCost + rightCost + g.joinCost(left, right), left | right, nextRel
+	}
+	return 0, 1 << uint(firstRel), firstRel + 1
+}
+
+// isJoin returns true if the given node is one of the joins of the tree.
+func (t *joinOrderTree) isJoin(n *joinNode) bool {
+	for _, j := range t.joins {
+		if j == n {
+			return true
+		}
+	}
+	return false
+}
+
+// makeOrderedJoins builds a left-deep tree of joins of the relations of the
+// tree in the given order, and a renderNode producing the columns in their
+// original order. It returns nil if the predicates cannot be rebuilt.
+func (p *planner) makeOrderedJoins(
+	ctx context.Context, t *joinOrderTree, order []int,
+) (planNode, error) {
+	// newCol maps the columns of the tree to the columns of the new joins.
+	newCol := make([]int, len(t.colRel))
+	numCols := 0
+	for _, rel := range order {
+		for i := range t.relations[rel].info.sourceColumns {
+			newCol[t.firstCol[rel]+i] = numCols
+			numCols++
+		}
+	}
+
+	// Check that the equalities can be expressed; this mirrors the check in
+	// joinPredicate.tryAddEqualityFilter.
+	for _, eq := range t.equalities {
+		if _, ok := parser.FindEqualComparisonFunction(t.colType(eq[0]), t.colType(eq[1])); !ok {
+			return nil, nil
+		}
+	}
+
+	src := t.relations[order[0]]
+	for _, rel := range order[1:] {
+		var err error
+		src, err = p.makeJoin(ctx, "CROSS JOIN", src, t.relations[rel], nil)
+		if err != nil {
+			return nil, err
+		}
+		src.plan.(*joinNode).ordered = true
+	}
+	root := src.plan.(*joinNode)
+
+	// Attach the predicates to the new joins by propagating them from the
+	// root, which pushes each one down to the lowest join that has the
+	// columns it refers to.
+	var filter parser.TypedExpr = parser.DBoolTrue
+	for _, eq := range t.equalities {
+		filter = mergeConj(filter, parser.NewTypedComparisonExpr(parser.EQ,
+			root.pred.iVarHelper.IndexedVar(newCol[eq[0]]),
+			root.pred.iVarHelper.IndexedVar(newCol[eq[1]]),
+		))
+	}
+	for _, cond := range t.conds {
+		filter = mergeConj(filter, exprConvertVars(cond,
+			func(expr parser.VariableExpr) (bool, parser.Expr) {
+				if iv, ok := expr.(*parser.IndexedVar); ok {
+					return true, root.pred.iVarHelper.IndexedVar(newCol[iv.Idx])
+				}
+				return true, expr
+			}))
+	}
+	if _, _, err := p.addJoinFilter(ctx, root, filter); err != nil {
+		return nil, err
+	}
+
+	// The joins of the written tree are replaced by the new ones; release
+	// their resources, but not those of the relations.
+	for _, j := range t.joins {
+		j.closeBuffers(ctx)
+	}
+
+	r := &renderNode{
+		planner:    p,
+		source:     src,
+		sourceInfo: multiSourceInfo{src.info},
+		columns:    append(ResultColumns(nil), t.joins[0].columns...),
+	}
+	r.ivarHelper = parser.MakeIndexedVarHelper(r, len(src.info.sourceColumns))
+	r.render = make([]parser.TypedExpr, len(r.columns))
+	for i := range r.render {
+		r.render[i] = r.ivarHelper.IndexedVar(newCol[i])
+	}
+	r.numOriginalCols = len(r.columns)
+	return r, nil
+}
+
+// colType returns the type of a column of the tree.
+func (t *joinOrderTree) colType(col int) parser.Type {
+	rel := t.colRel[col]
+	return t.relations[rel].info.sourceColumns[col-t.firstCol[rel]].Typ
+}
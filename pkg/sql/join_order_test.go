@@ -0,0 +1,203 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/sql/stats"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func TestJoinOrderGraph(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	pred := func(selectivity float64, rels ...int) joinOrderPred {
+		var set uint64
+		for _, rel := range rels {
+			set |= 1 << uint(rel)
+		}
+		return joinOrderPred{relations: set, selectivity: selectivity}
+	}
+
+	testCases := []struct {
+		graph      joinOrderGraph
+		dpCost     float64
+		greedyCost float64
+	}{
+		// A chain 0-3-1-4-2, written with cross products, in which relation 2
+		// is much smaller than the others.
+		{
+			graph: joinOrderGraph{
+				rows: []float64{1000, 1000, 10, 1000, 1000},
+				preds: []joinOrderPred{
+					pred(0.001, 0, 3), pred(0.001, 3, 1), pred(0.001, 1, 4), pred(0.001, 4, 2),
+				},
+			},
+			dpCost:     4080,
+			greedyCost: 4080,
+		},
+		// A star around a large relation.
+		{
+			graph: joinOrderGraph{
+				rows:  []float64{100000, 10, 100, 1000},
+				preds: []joinOrderPred{pred(0.00001, 0, 1), pred(0.00001, 0, 2), pred(0.00001, 0, 3)},
+			},
+			dpCost:     101133,
+			greedyCost: 101133,
+		},
+		// A predicate on three relations.
+		{
+			graph: joinOrderGraph{
+				rows:  []float64{10, 20, 30},
+				preds: []joinOrderPred{pred(0.1, 0, 1, 2)},
+			},
+			dpCost:     1060,
+			greedyCost: 1060,
+		},
+	}
+
+	for i, tc := range testCases {
+		g := &tc.graph
+		order, cost := g.bestOrder()
+		if cost != tc.dpCost {
+			t.Errorf("%d: expected cost %v, got %v for order %v", i, tc.dpCost, cost, order)
+		}
+		if c := g.orderCost(g.dpOrder()); c != cost {
+			t.Errorf("%d: expected dynamic programming cost %v, got %v", i, cost, c)
+		}
+		greedy := g.greedyOrder()
+		if c := g.orderCost(greedy); c != tc.greedyCost {
+			t.Errorf("%d: expected greedy cost %v, got %v for order %v", i, tc.greedyCost, c, greedy)
+		}
+		for _, o := range [][]int{order, greedy} {
+			seen := make(map[int]bool)
+			for _, rel := range o {
+				if seen[rel] {
+					t.Errorf("%d: relation %d appears twice in %v", i, rel, o)
+				}
+				seen[rel] = true
+			}
+			if len(seen) != len(g.rows) {
+				t.Errorf("%d: order %v does not contain all the relations", i, o)
+			}
+		}
+	}
+}
+
+func TestJoinOrderGraphAvoidsCrossProducts(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	// A chain of relations of increasing size, joined on keys of the larger
+	// relation. The chain is long enough for the greedy ordering to be used.
+	var g joinOrderGraph
+	const n = maxDPJoinRelations + 2
+	for i := 0; i < n; i++ {
+		g.rows = append(g.rows, float64(1000*(i+1)))
+		if i > 0 {
+			g.preds = append(g.preds, joinOrderPred{
+				relations:   1<<uint(i-1) | 1<<uint(i),
+				selectivity: 1 / g.rows[i],
+			})
+		}
+	}
+
+	for _, order := range [][]int{g.dpOrder(), g.greedyOrder()} {
+		set := uint64(1) << uint(order[0])
+		for _, rel := range order[1:] {
+			if !g.connected(set, rel) {
+				t.Errorf("order %v joins relation %d with a cross product", order, rel)
+			}
+			set |= 1 << uint(rel)
+		}
+	}
+
+	written := make([]int, n)
+	for i := range written {
+		// Interleave the even and odd relations, so that most joins of the
+		// written order are cross products.
+		written[i] = (i*2)%n + (i*2)/n
+	}
+	if order, cost := g.bestOrder(); cost >= g.orderCost(written) {
+		t.Errorf("expected %v to be cheaper than %v, got costs %v and %v",
+			order, written, cost, g.orderCost(written))
+	}
+}
+
+func TestTableEstimateSelectivity(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	evalCtx := &parser.EvalContext{}
+
+	// A column with the values 1 to 100, and 100 NULLs. The histogram has
+	// the upper bounds 10, 20, ..., 100.
+	colType := sqlbase.ColumnType{Kind: sqlbase.ColumnType_INT}
+	samples := make(parser.Datums, 100)
+	for i := range samples {
+		samples[i] = parser.NewDInt(parser.DInt(i + 1))
+	}
+	h, err := stats.EquiDepthHistogram(evalCtx, colType, samples, 100, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	te := tableEstimate{
+		evalCtx:  evalCtx,
+		desc:     &sqlbase.TableDescriptor{},
+		rowCount: 200,
+		colStats: map[sqlbase.ColumnID]*stats.TableStatistic{
+			1: {ColumnID: 1, RowCount: 200, DistinctCount: 100, NullCount: 100, Histogram: &h},
+		},
+	}
+	colID := func(int) sqlbase.ColumnID { return 1 }
+
+	col := parser.NewOrdinalReference(0)
+	cmp := func(op parser.ComparisonOperator, d parser.Datum) parser.Expr {
+		return &parser.ComparisonExpr{Operator: op, Left: col, Right: d}
+	}
+	testCases := []struct {
+		expr     parser.Expr
+		expected float64
+	}{
+		{cmp(parser.EQ, parser.NewDInt(50)), 0.005},
+		{cmp(parser.EQ, parser.NewDInt(1000)), 0.005},
+		{cmp(parser.Is, parser.DNull), 0.5},
+		{cmp(parser.IsNot, parser.DNull), 0.5},
+		{cmp(parser.LT, parser.NewDInt(10)), 0.045},
+		// Half of the bucket ending at 60 is assumed to be below 51.
+		{cmp(parser.LT, parser.NewDInt(51)), 0.2725},
+		{cmp(parser.GE, parser.NewDInt(51)), 0.2275},
+		{cmp(parser.GT, parser.NewDInt(100)), 0},
+		{&parser.AndExpr{Left: cmp(parser.GE, parser.NewDInt(51)), Right: cmp(parser.IsNot, parser.DNull)}, 0.11375},
+		{&parser.NotExpr{Expr: cmp(parser.Is, parser.DNull)}, 0.5},
+	}
+	for i, tc := range testCases {
+		if sel := te.selectivity(tc.expr, colID); math.Abs(sel-tc.expected) > 1e-9 {
+			t.Errorf("%d: %s: expected selectivity %v, got %v", i, tc.expr, tc.expected, sel)
+		}
+	}
+
+	// Without statistics, the selectivity of an equality on a unique column
+	// is derived from the number of rows.
+	desc := sqlbase.TableDescriptor{
+		PrimaryIndex: sqlbase.IndexDescriptor{ColumnIDs: []sqlbase.ColumnID{1}},
+	}
+	te = tableEstimate{evalCtx: evalCtx, desc: &desc, rowCount: defaultTableRowCount}
+	if sel := te.selectivity(cmp(parser.EQ, parser.NewDInt(1)), colID); sel > 1.0/defaultTableRowCount {
+		t.Errorf("expected at most one row for an equality on the primary key, got selectivity %v", sel)
+	}
+}
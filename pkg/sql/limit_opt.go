@@ -164,9 +164,11 @@ func applyLimit(plan planNode, numRows int64, soft bool) {
 
 	case *valuesNode:
 	case *alterTableNode:
+	case *controlJobNode:
 	case *copyNode:
 	case *createDatabaseNode:
 	case *createIndexNode:
+	case *createStatsNode:
 	case *createUserNode:
 	case *dropDatabaseNode:
 	case *dropIndexNode:
@@ -1409,8 +1409,12 @@ func TestLogic(t *testing.T) {
 	}
 
 	// We want to collect SQL perstatement statistics in tests,
-	// regardless of what the environment / config says.
-	sql.StmtStatsEnable = true
+	// regardless of what the cluster settings say.
+	defer sql.StmtStatsEnable.Override(true)()
+	// Likewise, we don't want them to be cleared from memory in the middle
+	// of a test file. The statistics are cleared at multiples of the reset
+	// interval, so a long interval makes that unlikely.
+	defer sql.StmtStatsResetFrequency.Override(365 * 24 * time.Hour)()
 
 	// mu protects the following vars, which all get updated from within the
 	// possibly parallel subtests.
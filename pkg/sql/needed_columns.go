@@ -190,9 +190,11 @@ func setNeededColumns(plan planNode, needed []bool) {
 		setNeededColumns(n.rows, allColumns(n.rows))
 
 	case *alterTableNode:
+	case *controlJobNode:
 	case *copyNode:
 	case *createDatabaseNode:
 	case *createIndexNode:
+	case *createStatsNode:
 	case *createUserNode:
 	case *delayedNode:
 	case *dropDatabaseNode:
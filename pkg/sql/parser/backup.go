@@ -18,7 +18,8 @@ package parser
 
 import "bytes"
 
-// Backup represents a BACKUP statement.
+// Backup represents a BACKUP statement. A Backup without targets is a backup
+// of the entire cluster.
 type Backup struct {
 	Targets         TargetList
 	To              Expr
@@ -32,8 +33,11 @@ var _ Statement = &Backup{}
 // Format implements the NodeFormatter interface.
 func (node *Backup) Format(buf *bytes.Buffer, f FmtFlags) {
 	buf.WriteString("BACKUP ")
-	FormatNode(buf, f, node.Targets)
-	buf.WriteString(" TO ")
+	if !node.Targets.IsEmpty() {
+		FormatNode(buf, f, node.Targets)
+		buf.WriteString(" ")
+	}
+	buf.WriteString("TO ")
 	FormatNode(buf, f, node.To)
 	if node.AsOf.Expr != nil {
 		buf.WriteString(" ")
@@ -50,7 +54,8 @@ func (node *Backup) Format(buf *bytes.Buffer, f FmtFlags) {
 	}
 }
 
-// Restore represents a RESTORE statement.
+// Restore represents a RESTORE statement. A Restore without targets restores
+// an entire cluster out of a full cluster backup.
 type Restore struct {
 	Targets TargetList
 	From    Exprs
@@ -63,8 +68,11 @@ var _ Statement = &Restore{}
 // Format implements the NodeFormatter interface.
 func (node *Restore) Format(buf *bytes.Buffer, f FmtFlags) {
 	buf.WriteString("RESTORE ")
-	FormatNode(buf, f, node.Targets)
-	buf.WriteString(" FROM ")
+	if !node.Targets.IsEmpty() {
+		FormatNode(buf, f, node.Targets)
+		buf.WriteString(" ")
+	}
+	buf.WriteString("FROM ")
 	FormatNode(buf, f, node.From)
 	if node.AsOf.Expr != nil {
 		buf.WriteString(" ")
@@ -77,6 +85,69 @@ func (node *Restore) Format(buf *bytes.Buffer, f FmtFlags) {
 	}
 }
 
+// Import represents an IMPORT statement, which creates a table out of data
+// in an external format. Table and CreateDefs are unset for formats whose
+// files also contain the schema of their tables, like PGDUMP.
+type Import struct {
+	Table      NormalizableTableName
+	CreateDefs TableDefs
+	FileFormat string
+	Files      Exprs
+	Options    KVOptions
+}
+
+var _ Statement = &Import{}
+
+// Format implements the NodeFormatter interface.
+func (node *Import) Format(buf *bytes.Buffer, f FmtFlags) {
+	if node.FileFormat == "PGDUMP" {
+		// A dump contains both the schema and the data of its tables.
+		buf.WriteString("IMPORT PGDUMP ")
+		FormatNode(buf, f, node.Files)
+	} else {
+		buf.WriteString("IMPORT TABLE ")
+		FormatNode(buf, f, node.Table)
+		buf.WriteString(" (")
+		FormatNode(buf, f, node.CreateDefs)
+		buf.WriteString(") ")
+		buf.WriteString(node.FileFormat)
+		buf.WriteString(" DATA (")
+		FormatNode(buf, f, node.Files)
+		buf.WriteString(")")
+	}
+	if node.Options != nil {
+		buf.WriteString(" WITH OPTIONS (")
+		FormatNode(buf, f, node.Options)
+		buf.WriteString(")")
+	}
+}
+
+// Export represents an EXPORT statement, which writes the results of a query
+// to files in an external format.
+type Export struct {
+	Query      *Select
+	FileFormat string
+	File       Expr
+	Options    KVOptions
+}
+
+var _ Statement = &Export{}
+
+// Format implements the NodeFormatter interface.
+func (node *Export) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("EXPORT INTO ")
+	buf.WriteString(node.FileFormat)
+	buf.WriteString(" ")
+	FormatNode(buf, f, node.File)
+	if node.Options != nil {
+		buf.WriteString(" WITH OPTIONS (")
+		FormatNode(buf, f, node.Options)
+		buf.WriteString(")")
+	}
+	buf.WriteString(" FROM ")
+	FormatNode(buf, f, node.Query)
+}
+
 // KVOption is a key-value option.
 type KVOption struct {
 	Key   string
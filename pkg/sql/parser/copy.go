@@ -23,6 +23,7 @@ type CopyFrom struct {
 	Table   NormalizableTableName
 	Columns UnresolvedNames
 	Stdin   bool
+	Options KVOptions
 }
 
 // Format implements the NodeFormatter interface.
@@ -38,4 +39,55 @@ func (node *CopyFrom) Format(buf *bytes.Buffer, f FmtFlags) {
 	if node.Stdin {
 		buf.WriteString("STDIN")
 	}
+	formatCopyOptions(buf, f, node.Options)
+}
+
+// CopyTo represents a COPY TO statement, which copies either a table or the
+// results of a query.
+type CopyTo struct {
+	Table   NormalizableTableName
+	Columns UnresolvedNames
+	// Query is set instead of Table when the results of a query are copied.
+	Query   *Select
+	Stdout  bool
+	Options KVOptions
+}
+
+// Format implements the NodeFormatter interface.
+func (node *CopyTo) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("COPY ")
+	if node.Query != nil {
+		buf.WriteString("(")
+		FormatNode(buf, f, node.Query)
+		buf.WriteString(")")
+	} else {
+		FormatNode(buf, f, node.Table)
+		if len(node.Columns) > 0 {
+			buf.WriteString(" (")
+			FormatNode(buf, f, node.Columns)
+			buf.WriteString(")")
+		}
+	}
+	buf.WriteString(" TO ")
+	if node.Stdout {
+		buf.WriteString("STDOUT")
+	}
+	formatCopyOptions(buf, f, node.Options)
+}
+
+// formatCopyOptions formats the options of a COPY in their generic form.
+func formatCopyOptions(buf *bytes.Buffer, f FmtFlags, opts KVOptions) {
+	if len(opts) == 0 {
+		return
+	}
+	buf.WriteString(" WITH (")
+	for i, o := range opts {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		FormatNode(buf, f, Name(o.Key))
+		buf.WriteByte(' ')
+		encodeSQLStringWithFlags(buf, o.Value, f)
+	}
+	buf.WriteString(")")
 }
@@ -104,8 +104,9 @@ type CreateIndex struct {
 	Columns     IndexElemList
 	// Extra columns to be stored together with the indexed ones as an optimization
 	// for improved reading performance.
-	Storing    NameList
-	Interleave *InterleaveDef
+	Storing     NameList
+	Interleave  *InterleaveDef
+	PartitionBy *PartitionBy
 }
 
 // Format implements the NodeFormatter interface.
@@ -132,6 +133,9 @@ func (node *CreateIndex) Format(buf *bytes.Buffer, f FmtFlags) {
 	if node.Interleave != nil {
 		FormatNode(buf, f, node.Interleave)
 	}
+	if node.PartitionBy != nil {
+		FormatNode(buf, f, node.PartitionBy)
+	}
 }
 
 // TableDef represents a column, index or constraint definition within a CREATE
@@ -423,10 +427,11 @@ type ColumnFamilyConstraint struct {
 // IndexTableDef represents an index definition within a CREATE TABLE
 // statement.
 type IndexTableDef struct {
-	Name       Name
-	Columns    IndexElemList
-	Storing    NameList
-	Interleave *InterleaveDef
+	Name        Name
+	Columns     IndexElemList
+	Storing     NameList
+	Interleave  *InterleaveDef
+	PartitionBy *PartitionBy
 }
 
 func (node *IndexTableDef) setName(name Name) {
@@ -451,6 +456,9 @@ func (node *IndexTableDef) Format(buf *bytes.Buffer, f FmtFlags) {
 	if node.Interleave != nil {
 		FormatNode(buf, f, node.Interleave)
 	}
+	if node.PartitionBy != nil {
+		FormatNode(buf, f, node.PartitionBy)
+	}
 }
 
 // ConstraintTableDef represents a constraint definition within a CREATE TABLE
@@ -492,6 +500,9 @@ func (node *UniqueConstraintTableDef) Format(buf *bytes.Buffer, f FmtFlags) {
 	if node.Interleave != nil {
 		FormatNode(buf, f, node.Interleave)
 	}
+	if node.PartitionBy != nil {
+		FormatNode(buf, f, node.PartitionBy)
+	}
 }
 
 // ForeignKeyConstraintTableDef represents a FOREIGN KEY constraint in the AST.
@@ -600,11 +611,82 @@ func (node *InterleaveDef) Format(buf *bytes.Buffer, f FmtFlags) {
 	}
 }
 
+// PartitionBy represents a PARTITION BY definition within a CREATE TABLE or
+// CREATE INDEX statement. Exactly one of List and Range is non-empty.
+type PartitionBy struct {
+	Fields NameList
+	List   []ListPartition
+	Range  []RangePartition
+}
+
+// Format implements the NodeFormatter interface.
+func (node *PartitionBy) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString(" PARTITION BY ")
+	if len(node.List) > 0 {
+		buf.WriteString("LIST")
+	} else {
+		buf.WriteString("RANGE")
+	}
+	buf.WriteString(" (")
+	FormatNode(buf, f, node.Fields)
+	buf.WriteString(") (")
+	for i := range node.List {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		FormatNode(buf, f, node.List[i])
+	}
+	for i := range node.Range {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		FormatNode(buf, f, node.Range[i])
+	}
+	buf.WriteByte(')')
+}
+
+// ListPartition represents a PARTITION definition within a PARTITION BY LIST.
+type ListPartition struct {
+	Name Name
+	// Exprs holds the values of the partitioning columns of the rows in the
+	// partition. When partitioning by more than one column, each value is a
+	// tuple.
+	Exprs Exprs
+}
+
+// Format implements the NodeFormatter interface.
+func (node ListPartition) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("PARTITION ")
+	FormatNode(buf, f, node.Name)
+	buf.WriteString(" VALUES IN (")
+	FormatNode(buf, f, node.Exprs)
+	buf.WriteByte(')')
+}
+
+// RangePartition represents a PARTITION definition within a PARTITION BY
+// RANGE.
+type RangePartition struct {
+	Name Name
+	// Expr is the exclusive upper bound of the values of the partitioning
+	// columns of the rows in the partition. When partitioning by more than one
+	// column, it is a tuple. MAXVALUE is represented as an unresolved name.
+	Expr Expr
+}
+
+// Format implements the NodeFormatter interface.
+func (node RangePartition) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("PARTITION ")
+	FormatNode(buf, f, node.Name)
+	buf.WriteString(" VALUES < ")
+	FormatNode(buf, f, node.Expr)
+}
+
 // CreateTable represents a CREATE TABLE statement.
 type CreateTable struct {
 	IfNotExists   bool
 	Table         NormalizableTableName
 	Interleave    *InterleaveDef
+	PartitionBy   *PartitionBy
 	Defs          TableDefs
 	AsSource      *Select
 	AsColumnNames NameList // Only to be used in conjunction with AsSource
@@ -638,9 +720,51 @@ func (node *CreateTable) Format(buf *bytes.Buffer, f FmtFlags) {
 		if node.Interleave != nil {
 			FormatNode(buf, f, node.Interleave)
 		}
+		if node.PartitionBy != nil {
+			FormatNode(buf, f, node.PartitionBy)
+		}
+	}
+}
+
+// CreateChangefeed represents a CREATE CHANGEFEED statement.
+type CreateChangefeed struct {
+	Targets TargetList
+	SinkURI Expr
+	Options KVOptions
+}
+
+var _ Statement = &CreateChangefeed{}
+
+// Format implements the NodeFormatter interface.
+func (node *CreateChangefeed) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("CREATE CHANGEFEED FOR ")
+	FormatNode(buf, f, node.Targets)
+	buf.WriteString(" INTO ")
+	FormatNode(buf, f, node.SinkURI)
+	if node.Options != nil {
+		buf.WriteString(" WITH OPTIONS (")
+		FormatNode(buf, f, node.Options)
+		buf.WriteString(")")
 	}
 }
 
+// CreateStats represents a CREATE STATISTICS statement.
+type CreateStats struct {
+	Name        Name
+	ColumnNames NameList
+	Table       NormalizableTableName
+}
+
+// Format implements the NodeFormatter interface.
+func (node *CreateStats) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("CREATE STATISTICS ")
+	FormatNode(buf, f, node.Name)
+	buf.WriteString(" ON ")
+	FormatNode(buf, f, node.ColumnNames)
+	buf.WriteString(" FROM ")
+	FormatNode(buf, f, node.Table)
+}
+
 // CreateUser represents a CREATE USER statement.
 type CreateUser struct {
 	Name     Name
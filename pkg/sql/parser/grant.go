@@ -42,6 +42,11 @@ type TargetList struct {
 	Tables    TablePatterns
 }
 
+// IsEmpty returns true if the TargetList names no tables or databases.
+func (tl TargetList) IsEmpty() bool {
+	return tl.Databases == nil && tl.Tables == nil
+}
+
 // Format implements the NodeFormatter interface.
 func (tl TargetList) Format(buf *bytes.Buffer, f FmtFlags) {
 	if tl.Databases != nil {
@@ -30,9 +30,11 @@ var keywords = map[string]int{
 	"BY":                BY,
 	"BYTEA":             BYTEA,
 	"BYTES":             BYTES,
+	"CANCEL":            CANCEL,
 	"CASCADE":           CASCADE,
 	"CASE":              CASE,
 	"CAST":              CAST,
+	"CHANGEFEED":        CHANGEFEED,
 	"CHAR":              CHAR,
 	"CHARACTER":         CHARACTER,
 	"CHARACTERISTICS":   CHARACTERISTICS,
@@ -45,6 +47,8 @@ var keywords = map[string]int{
 	"COLUMNS":           COLUMNS,
 	"COMMIT":            COMMIT,
 	"COMMITTED":         COMMITTED,
+	"CONFIGURATION":     CONFIGURATION,
+	"CONFIGURE":         CONFIGURE,
 	"CONFLICT":          CONFLICT,
 	"CONSTRAINT":        CONSTRAINT,
 	"CONSTRAINTS":       CONSTRAINTS,
@@ -52,6 +56,7 @@ var keywords = map[string]int{
 	"COVERING":          COVERING,
 	"CREATE":            CREATE,
 	"CROSS":             CROSS,
+	"CSV":               CSV,
 	"CUBE":              CUBE,
 	"CURRENT":           CURRENT,
 	"CURRENT_CATALOG":   CURRENT_CATALOG,
@@ -72,7 +77,9 @@ var keywords = map[string]int{
 	"DEFAULT":           DEFAULT,
 	"DEFERRABLE":        DEFERRABLE,
 	"DELETE":            DELETE,
+	"DELIMITER":         DELIMITER,
 	"DESC":              DESC,
+	"DISCARD":           DISCARD,
 	"DISTINCT":          DISTINCT,
 	"DO":                DO,
 	"DOUBLE":            DOUBLE,
@@ -84,6 +91,7 @@ var keywords = map[string]int{
 	"EXECUTE":           EXECUTE,
 	"EXISTS":            EXISTS,
 	"EXPLAIN":           EXPLAIN,
+	"EXPORT":            EXPORT,
 	"EXTRACT":           EXTRACT,
 	"EXTRACT_DURATION":  EXTRACT_DURATION,
 	"FALSE":             FALSE,
@@ -104,12 +112,14 @@ var keywords = map[string]int{
 	"GROUP":             GROUP,
 	"GROUPING":          GROUPING,
 	"HAVING":            HAVING,
+	"HEADER":            HEADER,
 	"HELP":              HELP,
 	"HIGH":              HIGH,
 	"HOUR":              HOUR,
 	"IF":                IF,
 	"IFNULL":            IFNULL,
 	"ILIKE":             ILIKE,
+	"IMPORT":            IMPORT,
 	"IN":                IN,
 	"INCREMENTAL":       INCREMENTAL,
 	"INDEX":             INDEX,
@@ -128,6 +138,8 @@ var keywords = map[string]int{
 	"INTO":              INTO,
 	"IS":                IS,
 	"ISOLATION":         ISOLATION,
+	"JOB":               JOB,
+	"JOBS":              JOBS,
 	"JOIN":              JOIN,
 	"KEY":               KEY,
 	"KEYS":              KEYS,
@@ -140,6 +152,7 @@ var keywords = map[string]int{
 	"LEVEL":             LEVEL,
 	"LIKE":              LIKE,
 	"LIMIT":             LIMIT,
+	"LIST":              LIST,
 	"LOCAL":             LOCAL,
 	"LOCALTIME":         LOCALTIME,
 	"LOCALTIMESTAMP":    LOCALTIMESTAMP,
@@ -180,6 +193,8 @@ var keywords = map[string]int{
 	"PARTIAL":           PARTIAL,
 	"PARTITION":         PARTITION,
 	"PASSWORD":          PASSWORD,
+	"PAUSE":             PAUSE,
+	"PGDUMP":            PGDUMP,
 	"PLACING":           PLACING,
 	"POSITION":          POSITION,
 	"PRECEDING":         PRECEDING,
@@ -204,6 +219,7 @@ var keywords = map[string]int{
 	"RESET":             RESET,
 	"RESTORE":           RESTORE,
 	"RESTRICT":          RESTRICT,
+	"RESUME":            RESUME,
 	"RETURNING":         RETURNING,
 	"REVOKE":            REVOKE,
 	"RIGHT":             RIGHT,
@@ -222,6 +238,7 @@ var keywords = map[string]int{
 	"SESSION_USER":      SESSION_USER,
 	"SET":               SET,
 	"SETTING":           SETTING,
+	"SETTINGS":          SETTINGS,
 	"SHOW":              SHOW,
 	"SIMILAR":           SIMILAR,
 	"SIMPLE":            SIMPLE,
@@ -232,8 +249,10 @@ var keywords = map[string]int{
 	"SPLIT":             SPLIT,
 	"SQL":               SQL,
 	"START":             START,
+	"STATISTICS":        STATISTICS,
 	"STATUS":            STATUS,
 	"STDIN":             STDIN,
+	"STDOUT":            STDOUT,
 	"STORING":           STORING,
 	"STRICT":            STRICT,
 	"STRING":            STRING,
@@ -78,6 +78,8 @@ func TestParse(t *testing.T) {
 		{`CREATE UNIQUE INDEX a ON b (c)`},
 		{`CREATE UNIQUE INDEX a ON b (c) STORING (d)`},
 		{`CREATE UNIQUE INDEX a ON b (c) INTERLEAVE IN PARENT d (e, f)`},
+		{`CREATE INDEX a ON b (c) PARTITION BY LIST (c) (PARTITION p1 VALUES IN ('us', 'ca'), PARTITION p2 VALUES IN ('de'))`},
+		{`CREATE INDEX IF NOT EXISTS a ON b (c) STORING (d) PARTITION BY RANGE (c) (PARTITION p1 VALUES < 1)`},
 		{`CREATE UNIQUE INDEX a ON b.c (d)`},
 
 		{`CREATE TABLE a ()`},
@@ -133,6 +135,12 @@ func TestParse(t *testing.T) {
 		{`CREATE TABLE a (b INT, c STRING, FAMILY foo (b), FAMILY (c))`},
 		{`CREATE TABLE a (b INT) INTERLEAVE IN PARENT foo (c, d)`},
 		{`CREATE TABLE a (b INT) INTERLEAVE IN PARENT foo (c) CASCADE`},
+		{`CREATE TABLE a (b INT PRIMARY KEY) PARTITION BY LIST (b) (PARTITION p1 VALUES IN (1, 2), PARTITION p2 VALUES IN (3))`},
+		{`CREATE TABLE a (b STRING, c INT, PRIMARY KEY (b, c)) PARTITION BY LIST (b, c) (PARTITION p1 VALUES IN (('eu', 1), ('eu', 2)))`},
+		{`CREATE TABLE a (b INT PRIMARY KEY) PARTITION BY RANGE (b) (PARTITION p1 VALUES < 10, PARTITION p2 VALUES < MAXVALUE)`},
+		{`CREATE TABLE a (b INT, c INT, PRIMARY KEY (b, c)) PARTITION BY RANGE (b, c) (PARTITION p1 VALUES < (1, maxvalue))`},
+		{`CREATE TABLE a (b INT) INTERLEAVE IN PARENT foo (b) PARTITION BY LIST (b) (PARTITION p1 VALUES IN (1))`},
+		{`CREATE TABLE a (b INT, INDEX (b) PARTITION BY LIST (b) (PARTITION p1 VALUES IN (1)))`},
 		{`CREATE TABLE a.b (b INT)`},
 		{`CREATE TABLE IF NOT EXISTS a (b INT)`},
 
@@ -159,6 +167,10 @@ func TestParse(t *testing.T) {
 		{`CREATE VIEW a (x, y) AS VALUES (1, 'one'), (2, 'two')`},
 		{`CREATE VIEW a AS TABLE b`},
 
+		{`CREATE STATISTICS a ON col1 FROM t`},
+		{`CREATE STATISTICS a ON col1 FROM d.t`},
+		{`CREATE STATISTICS a ON col1, col2 FROM t`},
+
 		{`DELETE FROM a`},
 		{`DELETE FROM a.b`},
 		{`DELETE FROM a WHERE a = b`},
@@ -202,6 +214,7 @@ func TestParse(t *testing.T) {
 		{`EXPLAIN EXPLAIN SELECT 1`},
 		{`EXPLAIN (DEBUG) SELECT 1`},
 		{`EXPLAIN (A, B, C) SELECT 1`},
+		{`EXPLAIN (ANALYZE, INDENT) SELECT 1`},
 		{`SELECT * FROM [EXPLAIN SELECT 1]`},
 
 		{`HELP count`},
@@ -212,6 +225,7 @@ func TestParse(t *testing.T) {
 		{`SHOW SYNTAX`},
 
 		{`SHOW CLUSTER SETTING a`},
+		{`SHOW ALL CLUSTER SETTINGS`},
 
 		{`SHOW DATABASES`},
 		{`SHOW TABLES`},
@@ -224,6 +238,14 @@ func TestParse(t *testing.T) {
 		{`SHOW CONSTRAINTS FROM a.b.c`},
 		{`SHOW TABLES FROM a; SHOW COLUMNS FROM b`},
 		{`SHOW USERS`},
+		{`SHOW JOBS`},
+		{`SHOW ZONE CONFIGURATION FOR DATABASE db`},
+		{`SHOW ZONE CONFIGURATION FOR TABLE d.t`},
+		{`SHOW ZONE CONFIGURATION FOR INDEX t@i`},
+		{`SHOW ZONE CONFIGURATION FOR INDEX d.i`},
+		{`SHOW ZONE CONFIGURATION FOR PARTITION p OF TABLE d.t`},
+		{`SHOW ZONE CONFIGURATION FOR PARTITION p OF INDEX t@i`},
+
 		{`SHOW TESTING_RANGES FROM TABLE d.t`},
 		{`SHOW TESTING_RANGES FROM TABLE t`},
 		{`SHOW TESTING_RANGES FROM INDEX d.t@i`},
@@ -550,6 +572,7 @@ func TestParse(t *testing.T) {
 		{`SET CLUSTER SETTING a = '3'`},
 		{`SET CLUSTER SETTING a = 3.0`},
 		{`SET CLUSTER SETTING a = $1`},
+		{`SET CLUSTER SETTING a = DEFAULT`},
 		{`SET TIME ZONE 'pst8pdt'`},
 		{`SET TIME ZONE 'Europe/Rome'`},
 		{`SET TIME ZONE -7`},
@@ -636,6 +659,10 @@ func TestParse(t *testing.T) {
 
 		{`COPY t FROM STDIN`},
 		{`COPY t (a, b, c) FROM STDIN`},
+		{`COPY t FROM STDIN WITH (format 'csv', header 'true', delimiter '|', "null" '')`},
+		{`COPY t TO STDOUT`},
+		{`COPY t (a, b) TO STDOUT WITH (format 'csv')`},
+		{`COPY (SELECT a FROM t WHERE b > 1) TO STDOUT WITH (format 'text', "null" 'nil')`},
 
 		{`ALTER TABLE a SPLIT AT VALUES (1)`},
 		{`ALTER TABLE a SPLIT AT SELECT * FROM t`},
@@ -650,6 +677,19 @@ func TestParse(t *testing.T) {
 		{`ALTER TABLE d.a TESTING_RELOCATE VALUES (ARRAY[1, 2, 3], 'b', 2)`},
 		{`ALTER INDEX d.i TESTING_RELOCATE VALUES (ARRAY[1], 2)`},
 
+		{`ALTER DATABASE db CONFIGURE ZONE USING num_replicas = 5`},
+		{`ALTER TABLE t CONFIGURE ZONE USING num_replicas = 5, constraints = '[+region=us]'`},
+		{`ALTER TABLE d.t CONFIGURE ZONE USING gc.ttlseconds = $1`},
+		{`ALTER INDEX t@i CONFIGURE ZONE USING range_min_bytes = 1024, range_max_bytes = 65536`},
+		{`ALTER INDEX d.i CONFIGURE ZONE USING num_replicas = 1`},
+		{`ALTER DATABASE db CONFIGURE ZONE DISCARD`},
+		{`ALTER TABLE d.t CONFIGURE ZONE DISCARD`},
+		{`ALTER INDEX t@i CONFIGURE ZONE DISCARD`},
+		{`ALTER PARTITION p OF TABLE t CONFIGURE ZONE USING constraints = '[+region=eu]'`},
+		{`ALTER PARTITION p OF INDEX t@i CONFIGURE ZONE USING num_replicas = 5`},
+		{`ALTER PARTITION p OF TABLE d.t CONFIGURE ZONE DISCARD`},
+		{`ALTER PARTITION p OF INDEX t@i CONFIGURE ZONE DISCARD`},
+
 		{`ALTER TABLE a SCATTER`},
 		{`ALTER TABLE a SCATTER FROM (1, 2, 3) TO (4, 5, 6)`},
 		{`ALTER TABLE d.a SCATTER`},
@@ -670,8 +710,29 @@ func TestParse(t *testing.T) {
 		{`RESTORE DATABASE foo FROM 'bar'`},
 		{`RESTORE DATABASE foo, baz FROM 'bar'`},
 		{`RESTORE DATABASE foo, baz FROM 'bar' AS OF SYSTEM TIME '1'`},
+		{`BACKUP TO 'bar'`},
+		{`BACKUP TO 'bar' AS OF SYSTEM TIME '1' INCREMENTAL FROM 'baz'`},
+		{`RESTORE FROM 'bar'`},
+		{`RESTORE FROM $1, 'bar' AS OF SYSTEM TIME '1'`},
 		{`BACKUP foo TO 'bar' WITH OPTIONS ('key1', 'key2'='value')`},
+		{`SHOW BACKUP 'bar'`},
+		{`SHOW BACKUP $1 INCREMENTAL FROM 'baz', $2`},
 		{`RESTORE foo FROM 'bar' WITH OPTIONS ('key1', 'key2'='value')`},
+		{`IMPORT TABLE foo (id INT PRIMARY KEY, email STRING, age INT) CSV DATA ('path/to/some/file', $1) WITH OPTIONS ('temp'='path/to/temp')`},
+		{`IMPORT TABLE foo (id INT, email STRING, age INT, PRIMARY KEY (id), INDEX idx_email (email)) CSV DATA ('path/to/some/file')`},
+		{`IMPORT PGDUMP 'path/to/dump.sql'`},
+		{`EXPORT INTO CSV 'a' FROM SELECT * FROM foo`},
+		{`EXPORT INTO CSV $1 WITH OPTIONS ('delimiter'='|') FROM SELECT a, sum(b) FROM foo GROUP BY a ORDER BY a LIMIT 10`},
+		{`IMPORT PGDUMP $1 WITH OPTIONS ('into_db'='foo', 'temp'='path/to/temp')`},
+
+		{`CREATE CHANGEFEED FOR foo INTO 'sink'`},
+		{`CREATE CHANGEFEED FOR foo, db.bar INTO $1`},
+		{`CREATE CHANGEFEED FOR foo INTO 'sink' WITH OPTIONS ('cursor'='1')`},
+
+		{`PAUSE JOB 1`},
+		{`RESUME JOB 1`},
+		{`CANCEL JOB 1`},
+		{`CANCEL JOB $1`},
 	}
 	for _, d := range testData {
 		stmts, err := parseTraditional(d.sql)
@@ -703,6 +764,8 @@ func TestParse2(t *testing.T) {
 			`CREATE TABLE a (b INT, CONSTRAINT foo UNIQUE (b))`},
 		{`CREATE TABLE a (b INT, UNIQUE INDEX foo (b) INTERLEAVE IN PARENT c (d))`,
 			`CREATE TABLE a (b INT, CONSTRAINT foo UNIQUE (b) INTERLEAVE IN PARENT c (d))`},
+		{`CREATE TABLE a (b INT, UNIQUE INDEX c (b) PARTITION BY RANGE (b) (PARTITION p1 VALUES < 1))`,
+			`CREATE TABLE a (b INT, CONSTRAINT c UNIQUE (b) PARTITION BY RANGE (b) (PARTITION p1 VALUES < 1))`},
 		{`CREATE INDEX ON a (b) COVERING (c)`, `CREATE INDEX ON a (b) STORING (c)`},
 
 		{`SELECT TIMESTAMP WITHOUT TIME ZONE 'foo'`, `SELECT TIMESTAMP 'foo'`},
@@ -894,6 +957,7 @@ func TestParse2(t *testing.T) {
 		{`SET TRANSACTION PRIORITY NORMAL, ISOLATION LEVEL SERIALIZABLE`,
 			`SET TRANSACTION ISOLATION LEVEL SERIALIZABLE, PRIORITY NORMAL`},
 		{"SET CLUSTER SETTING a TO 1", "SET CLUSTER SETTING a = 1"},
+		{"RESET CLUSTER SETTING a", "SET CLUSTER SETTING a = DEFAULT"},
 		{"RELEASE foo", "RELEASE SAVEPOINT foo"},
 		{"RELEASE SAVEPOINT foo", "RELEASE SAVEPOINT foo"},
 		{"ROLLBACK", "ROLLBACK TRANSACTION"},
@@ -913,6 +977,31 @@ func TestParse2(t *testing.T) {
 			`BACKUP DATABASE foo TO 'bar.12' INCREMENTAL FROM 'baz.34'`},
 		{`RESTORE DATABASE foo FROM bar`,
 			`RESTORE DATABASE foo FROM 'bar'`},
+		{`SHOW BACKUP bar`,
+			`SHOW BACKUP 'bar'`},
+		{`BACKUP foo TO 'bar' WITH revision_history`,
+			`BACKUP foo TO 'bar' WITH OPTIONS ('revision_history')`},
+		{`RESTORE foo FROM 'bar' WITH into_db = 'baz', skip_missing_foreign_keys`,
+			`RESTORE foo FROM 'bar' WITH OPTIONS ('into_db'='baz', 'skip_missing_foreign_keys')`},
+		{`IMPORT TABLE foo (id INT) CSV DATA ('a', 'b') WITH delimiter = '|', nullif = '', skip = '1'`,
+			`IMPORT TABLE foo (id INT) CSV DATA ('a', 'b') WITH OPTIONS ('delimiter'='|', 'nullif', 'skip'='1')`},
+		{`IMPORT PGDUMP 'a' WITH into_db = 'foo'`,
+			`IMPORT PGDUMP 'a' WITH OPTIONS ('into_db'='foo')`},
+		{`EXPORT INTO CSV 'a' WITH delimiter = '|' FROM TABLE foo`,
+			`EXPORT INTO CSV 'a' WITH OPTIONS ('delimiter'='|') FROM TABLE foo`},
+		{`COPY t FROM STDIN CSV HEADER DELIMITER AS '|' NULL AS 'x'`,
+			`COPY t FROM STDIN WITH (format 'csv', header 'true', delimiter '|', "null" 'x')`},
+		{`COPY t TO STDOUT WITH (FORMAT CSV, HEADER, NULL '')`,
+			`COPY t TO STDOUT WITH (format 'CSV', header 'true', "null" '')`},
+		{`COPY (SELECT 1) TO STDOUT (HEADER false, Delimiter E'\t')`,
+			`COPY (SELECT 1) TO STDOUT WITH (header 'false', delimiter e'\t')`},
+
+		{`CREATE CHANGEFEED FOR TABLE foo INTO sink`,
+			`CREATE CHANGEFEED FOR foo INTO 'sink'`},
+
+		{`EXPLAIN ANALYZE SELECT 1`, `EXPLAIN (ANALYZE) SELECT 1`},
+		{`EXPLAIN ANALYSE SELECT 1`, `EXPLAIN (ANALYZE) SELECT 1`},
+		{`EXPLAIN (analyse) SELECT 1`, `EXPLAIN (ANALYZE) SELECT 1`},
 	}
 	for _, d := range testData {
 		stmts, err := parseTraditional(d.sql)
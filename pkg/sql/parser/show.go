@@ -34,6 +34,10 @@ type Show struct {
 func (node *Show) Format(buf *bytes.Buffer, f FmtFlags) {
 	buf.WriteString("SHOW ")
 	if node.ClusterSetting {
+		if node.Name == "all" {
+			buf.WriteString("ALL CLUSTER SETTINGS")
+			return
+		}
 		buf.WriteString("CLUSTER SETTING ")
 	}
 	buf.WriteString(node.Name)
@@ -158,6 +162,31 @@ func (node *ShowUsers) Format(buf *bytes.Buffer, f FmtFlags) {
 	buf.WriteString("SHOW USERS")
 }
 
+// ShowJobs represents a SHOW JOBS statement.
+type ShowJobs struct {
+}
+
+// Format implements the NodeFormatter interface.
+func (node *ShowJobs) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("SHOW JOBS")
+}
+
+// ShowBackup represents a SHOW BACKUP statement.
+type ShowBackup struct {
+	Path            Expr
+	IncrementalFrom Exprs
+}
+
+// Format implements the NodeFormatter interface.
+func (node *ShowBackup) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("SHOW BACKUP ")
+	FormatNode(buf, f, node.Path)
+	if node.IncrementalFrom != nil {
+		buf.WriteString(" INCREMENTAL FROM ")
+		FormatNode(buf, f, node.IncrementalFrom)
+	}
+}
+
 // Help represents a HELP statement.
 type Help struct {
 	Name Name
@@ -320,6 +320,9 @@ func (u *sqlSymUnion) validationBehavior() ValidationBehavior {
 func (u *sqlSymUnion) interleave() *InterleaveDef {
     return u.val.(*InterleaveDef)
 }
+func (u *sqlSymUnion) partitionBy() *PartitionBy {
+    return u.val.(*PartitionBy)
+}
 func (u *sqlSymUnion) windowDef() *WindowDef {
     return u.val.(*WindowDef)
 }
@@ -361,12 +364,18 @@ func (u *sqlSymUnion) kvOptions() []KVOption {
 
 %type <Statement> alter_table_stmt
 %type <Statement> backup_stmt
+%type <Statement> import_stmt
+%type <Statement> export_stmt
+%type <Statement> cancel_job_stmt
 %type <Statement> copy_from_stmt
+%type <Statement> copy_to_stmt
 %type <Statement> create_stmt
 %type <Statement> create_database_stmt
 %type <Statement> create_index_stmt
+%type <Statement> create_stats_stmt
 %type <Statement> create_table_stmt
 %type <Statement> create_table_as_stmt
+%type <Statement> create_changefeed_stmt
 %type <Statement> create_user_stmt
 %type <Statement> create_view_stmt
 %type <Statement> delete_stmt
@@ -380,14 +389,17 @@ func (u *sqlSymUnion) kvOptions() []KVOption {
 %type <Statement> deallocate_stmt
 %type <Statement> grant_stmt
 %type <Statement> insert_stmt
+%type <Statement> pause_job_stmt
 %type <Statement> release_stmt
 %type <Statement> rename_stmt
 %type <Statement> reset_stmt
+%type <Statement> resume_job_stmt
 %type <Statement> revoke_stmt
 %type <*Select> select_stmt
 %type <Statement> savepoint_stmt
 %type <Statement> set_stmt
 %type <Statement> show_stmt
+%type <Statement> set_zone_config_stmt
 %type <Statement> split_stmt
 %type <Statement> testing_relocate_stmt
 %type <Statement> scatter_stmt
@@ -398,6 +410,9 @@ func (u *sqlSymUnion) kvOptions() []KVOption {
 %type <[]string> opt_incremental
 %type <KVOption> kv_option
 %type <[]KVOption> kv_option_list opt_with_options
+%type <KVOption> copy_option copy_legacy_option
+%type <[]KVOption> copy_option_list copy_legacy_option_list opt_copy_options
+%type <str> copy_option_arg
 %type <str> opt_equal_value
 
 %type <*Select> select_no_parens
@@ -462,7 +477,7 @@ func (u *sqlSymUnion) kvOptions() []KVOption {
 %type <Exprs> expr_list
 %type <UnresolvedName> attrs
 %type <SelectExprs> target_list
-%type <UpdateExprs> set_clause_list
+%type <UpdateExprs> set_clause_list zone_option_list
 %type <*UpdateExpr> set_clause multiple_set_clause
 %type <ArraySubscripts> array_subscripts
 %type <UnresolvedName> qname_indirection
@@ -542,6 +557,7 @@ func (u *sqlSymUnion) kvOptions() []KVOption {
 %type <AsOfClause> opt_as_of_clause
 
 %type <str> explain_option_name
+%type <str> analyze_word
 %type <[]string> explain_option_list
 
 %type <ColumnType> typename simple_typename const_typename
@@ -630,40 +646,40 @@ func (u *sqlSymUnion) kvOptions() []KVOption {
 %token <str>   BACKUP BEGIN BETWEEN BIGINT BIGSERIAL BIT
 %token <str>   BLOB BOOL BOOLEAN BOTH BY BYTEA BYTES
 
-%token <str>   CASCADE CASE CAST CHAR
+%token <str>   CANCEL CASCADE CASE CAST CHANGEFEED CHAR
 %token <str>   CHARACTER CHARACTERISTICS CHECK
 %token <str>   CLUSTER COALESCE COLLATE COLLATION COLUMN COLUMNS COMMIT
-%token <str>   COMMITTED CONCAT CONFLICT CONSTRAINT CONSTRAINTS
+%token <str>   COMMITTED CONCAT CONFIGURATION CONFIGURE CONFLICT CONSTRAINT CONSTRAINTS
 %token <str>   COPY COVERING CREATE
-%token <str>   CROSS CUBE CURRENT CURRENT_CATALOG CURRENT_DATE
+%token <str>   CROSS CSV CUBE CURRENT CURRENT_CATALOG CURRENT_DATE
 %token <str>   CURRENT_ROLE CURRENT_TIME CURRENT_TIMESTAMP
 %token <str>   CURRENT_USER CYCLE
 
 %token <str>   DATA DATABASE DATABASES DATE DAY DEC DECIMAL DEFAULT
-%token <str>   DEALLOCATE DEFERRABLE DELETE DESC
-%token <str>   DISTINCT DO DOUBLE DROP
+%token <str>   DEALLOCATE DEFERRABLE DELETE DELIMITER DESC
+%token <str>   DISCARD DISTINCT DO DOUBLE DROP
 
 %token <str>   ELSE ENCODING END ESCAPE EXCEPT
-%token <str>   EXISTS EXECUTE EXPLAIN EXTRACT EXTRACT_DURATION
+%token <str>   EXISTS EXECUTE EXPLAIN EXPORT EXTRACT EXTRACT_DURATION
 
 %token <str>   FALSE FAMILY FETCH FILTER FIRST FLOAT FLOORDIV FOLLOWING FOR
 %token <str>   FORCE_INDEX FOREIGN FROM FULL
 
 %token <str>   GRANT GRANTS GREATEST GROUP GROUPING
 
-%token <str>   HAVING HELP HIGH HOUR
+%token <str>   HAVING HEADER HELP HIGH HOUR
 
-%token <str>   INCREMENTAL IF IFNULL ILIKE IN INTERLEAVE
+%token <str>   IF IFNULL ILIKE IMPORT IN INCREMENTAL INTERLEAVE
 %token <str>   INDEX INDEXES INITIALLY
 %token <str>   INNER INSERT INT INT2VECTOR INT8 INT64 INTEGER
 %token <str>   INTERSECT INTERVAL INTO IS ISOLATION
 
-%token <str>   JOIN
+%token <str>   JOB JOBS JOIN
 
 %token <str>   KEY KEYS
 
 %token <str>   LATERAL LC_CTYPE LC_COLLATE
-%token <str>   LEADING LEAST LEFT LEVEL LIKE LIMIT LOCAL
+%token <str>   LEADING LEAST LEFT LEVEL LIKE LIMIT LIST LOCAL
 %token <str>   LOCALTIME LOCALTIMESTAMP LOW LSHIFT
 
 %token <str>   MATCH MINUTE MONTH
@@ -675,19 +691,19 @@ func (u *sqlSymUnion) kvOptions() []KVOption {
 %token <str>   OF OFF OFFSET OID ON ONLY OPTIONS OR
 %token <str>   ORDER ORDINALITY OUT OUTER OVER OVERLAPS OVERLAY
 
-%token <str>   PARENT PARTIAL PARTITION PASSWORD PLACING POSITION
+%token <str>   PARENT PARTIAL PARTITION PASSWORD PAUSE PGDUMP PLACING POSITION
 %token <str>   PRECEDING PRECISION PREPARE PRIMARY PRIORITY
 
 %token <str>   RANGE READ REAL RECURSIVE REF REFERENCES
 %token <str>   REGCLASS REGPROC REGPROCEDURE REGNAMESPACE REGTYPE
 %token <str>   RENAME REPEATABLE
-%token <str>   RELEASE RESET RESTORE RESTRICT RETURNING REVOKE RIGHT ROLLBACK ROLLUP
+%token <str>   RELEASE RESET RESTORE RESTRICT RESUME RETURNING REVOKE RIGHT ROLLBACK ROLLUP
 %token <str>   ROW ROWS RSHIFT
 
 %token <str>   SAVEPOINT SCATTER SEARCH SECOND SELECT
-%token <str>   SERIAL SERIALIZABLE SESSION SESSION_USER SET SETTING SHOW
+%token <str>   SERIAL SERIALIZABLE SESSION SESSION_USER SET SETTING SETTINGS SHOW
 %token <str>   SIMILAR SIMPLE SMALLINT SMALLSERIAL SNAPSHOT SOME SPLIT SQL
-%token <str>   START STATUS STDIN STRICT STRING STORING SUBSTRING
+%token <str>   START STATISTICS STATUS STDIN STDOUT STRICT STRING STORING SUBSTRING
 %token <str>   SYMMETRIC SYSTEM
 
 %token <str>   TABLE TABLES TEMPLATE TESTING_RANGES TESTING_RELOCATE TEXT THEN
@@ -715,6 +731,8 @@ func (u *sqlSymUnion) kvOptions() []KVOption {
 // needed to make the grammar LALR(1).
 %token     NOT_LA WITH_LA AS_LA
 
+%type <*PartitionBy> opt_partition_by list_partitions range_partitions
+
 // Precedence: lowest to highest
 %nonassoc  VALUES              // see value_clause
 %nonassoc  SET                 // see relation_expr_opt_alias
@@ -806,18 +824,24 @@ stmt_list:
 stmt:
   alter_table_stmt
 | backup_stmt
+| cancel_job_stmt
 | copy_from_stmt
+| copy_to_stmt
 | create_stmt
 | delete_stmt
 | drop_stmt
 | explain_stmt
+| export_stmt
 | help_stmt
+| import_stmt
 | prepare_stmt
 | execute_stmt
 | deallocate_stmt
 | grant_stmt
 | insert_stmt
+| pause_job_stmt
 | rename_stmt
+| resume_job_stmt
 | revoke_stmt
 | savepoint_stmt
 | select_stmt
@@ -825,6 +849,7 @@ stmt:
     $$.val = $1.slct()
   }
 | set_stmt
+| set_zone_config_stmt
 | show_stmt
 | split_stmt
 | testing_relocate_stmt
@@ -1004,6 +1029,38 @@ backup_stmt:
     /* SKIP DOC */
     $$.val = &Restore{Targets: $2.targetList(), From: $4.exprs(), AsOf: $5.asOfClause(), Options: $6.kvOptions()}
   }
+| BACKUP TO string_or_placeholder opt_as_of_clause opt_incremental opt_with_options
+  {
+    /* SKIP DOC */
+    $$.val = &Backup{To: $3.expr(), IncrementalFrom: $5.exprs(), AsOf: $4.asOfClause(), Options: $6.kvOptions()}
+  }
+| RESTORE FROM string_or_placeholder_list opt_as_of_clause opt_with_options
+  {
+    /* SKIP DOC */
+    $$.val = &Restore{From: $3.exprs(), AsOf: $4.asOfClause(), Options: $5.kvOptions()}
+  }
+
+// IMPORT TABLE name (table_elem_list) CSV DATA (files) [WITH options]
+// IMPORT PGDUMP file [WITH options]
+import_stmt:
+  IMPORT TABLE any_name '(' table_elem_list ')' CSV DATA '(' string_or_placeholder_list ')' opt_with_options
+  {
+    /* SKIP DOC */
+    $$.val = &Import{Table: $3.normalizableTableName(), CreateDefs: $5.tblDefs(), FileFormat: "CSV", Files: $10.exprs(), Options: $12.kvOptions()}
+  }
+| IMPORT PGDUMP string_or_placeholder opt_with_options
+  {
+    /* SKIP DOC */
+    $$.val = &Import{FileFormat: "PGDUMP", Files: Exprs{$3.expr()}, Options: $4.kvOptions()}
+  }
+
+// EXPORT INTO CSV file [WITH options] FROM select_stmt
+export_stmt:
+  EXPORT INTO CSV string_or_placeholder opt_with_options FROM select_stmt
+  {
+    /* SKIP DOC */
+    $$.val = &Export{Query: $7.slct(), FileFormat: "CSV", File: $4.expr(), Options: $5.kvOptions()}
+  }
 
 string_or_placeholder:
   non_reserved_word_or_sconst
@@ -1046,7 +1103,11 @@ opt_equal_value:
   }
 
 kv_option:
-  SCONST opt_equal_value
+  name opt_equal_value
+  {
+    $$.val = KVOption{Key: $1, Value: $2}
+  }
+| SCONST opt_equal_value
   {
     $$.val = KVOption{Key: $1, Value: $2}
   }
@@ -1062,30 +1123,129 @@ kv_option_list:
   }
 
 opt_with_options:
-  WITH OPTIONS '(' kv_option_list ')'
+  WITH kv_option_list
+  {
+    $$.val = $2.kvOptions()
+  }
+| WITH OPTIONS '(' kv_option_list ')'
   {
     $$.val = $4.kvOptions()
   }
 | /* EMPTY */ {}
 
 copy_from_stmt:
-  COPY qualified_name FROM STDIN
+  COPY qualified_name FROM STDIN opt_copy_options
+  {
+    $$.val = &CopyFrom{Table: $2.normalizableTableName(), Stdin: true, Options: $5.kvOptions()}
+  }
+| COPY qualified_name '(' ')' FROM STDIN opt_copy_options
+  {
+    $$.val = &CopyFrom{Table: $2.normalizableTableName(), Stdin: true, Options: $7.kvOptions()}
+  }
+| COPY qualified_name '(' qualified_name_list ')' FROM STDIN opt_copy_options
+  {
+    $$.val = &CopyFrom{Table: $2.normalizableTableName(), Columns: $4.unresolvedNames(), Stdin: true, Options: $8.kvOptions()}
+  }
+
+copy_to_stmt:
+  COPY qualified_name TO STDOUT opt_copy_options
   {
-    $$.val = &CopyFrom{Table: $2.normalizableTableName(), Stdin: true}
+    $$.val = &CopyTo{Table: $2.normalizableTableName(), Stdout: true, Options: $5.kvOptions()}
   }
-| COPY qualified_name '(' ')' FROM STDIN
+| COPY qualified_name '(' ')' TO STDOUT opt_copy_options
   {
-    $$.val = &CopyFrom{Table: $2.normalizableTableName(), Stdin: true}
+    $$.val = &CopyTo{Table: $2.normalizableTableName(), Stdout: true, Options: $7.kvOptions()}
   }
-| COPY qualified_name '(' qualified_name_list ')' FROM STDIN
+| COPY qualified_name '(' qualified_name_list ')' TO STDOUT opt_copy_options
   {
-    $$.val = &CopyFrom{Table: $2.normalizableTableName(), Columns: $4.unresolvedNames(), Stdin: true}
+    $$.val = &CopyTo{Table: $2.normalizableTableName(), Columns: $4.unresolvedNames(), Stdout: true, Options: $8.kvOptions()}
   }
+| COPY select_with_parens TO STDOUT opt_copy_options
+  {
+    $$.val = &CopyTo{Query: $2.selectStmt().(*ParenSelect).Select, Stdout: true, Options: $5.kvOptions()}
+  }
+
+// The options of COPY, in either the generic form of Postgres 9.0 and later,
+// [WITH] (name [value], ...), or the older [WITH] name [[AS] value] ... form.
+// Options without a value are booleans set to true.
+opt_copy_options:
+  opt_with '(' copy_option_list ')'
+  {
+    $$.val = $3.kvOptions()
+  }
+| opt_with copy_legacy_option_list
+  {
+    $$.val = $2.kvOptions()
+  }
+| /* EMPTY */ {}
 
-// CREATE [DATABASE|INDEX|TABLE|TABLE AS|VIEW]
+copy_option_list:
+  copy_option
+  {
+    $$.val = []KVOption{$1.kvOption()}
+  }
+| copy_option_list ',' copy_option
+  {
+    $$.val = append($1.kvOptions(), $3.kvOption())
+  }
+
+copy_option:
+  name copy_option_arg
+  {
+    $$.val = KVOption{Key: Name($1).Normalize(), Value: $2}
+  }
+| NULL copy_option_arg
+  {
+    $$.val = KVOption{Key: "null", Value: $2}
+  }
+
+copy_option_arg:
+  non_reserved_word_or_sconst
+| TRUE
+| FALSE
+| /* EMPTY */
+  {
+    $$ = "true"
+  }
+
+copy_legacy_option_list:
+  copy_legacy_option
+  {
+    $$.val = []KVOption{$1.kvOption()}
+  }
+| copy_legacy_option_list copy_legacy_option
+  {
+    $$.val = append($1.kvOptions(), $2.kvOption())
+  }
+
+copy_legacy_option:
+  CSV
+  {
+    $$.val = KVOption{Key: "format", Value: "csv"}
+  }
+| HEADER
+  {
+    $$.val = KVOption{Key: "header", Value: "true"}
+  }
+| DELIMITER opt_as SCONST
+  {
+    $$.val = KVOption{Key: "delimiter", Value: $3}
+  }
+| NULL opt_as SCONST
+  {
+    $$.val = KVOption{Key: "null", Value: $3}
+  }
+
+opt_as:
+  AS {}
+| /* EMPTY */ {}
+
+// CREATE [DATABASE|INDEX|STATISTICS|TABLE|TABLE AS|VIEW]
 create_stmt:
-  create_database_stmt
+  create_changefeed_stmt
+| create_database_stmt
 | create_index_stmt
+| create_stats_stmt
 | create_table_stmt
 | create_table_as_stmt
 | create_user_stmt
@@ -1172,6 +1332,7 @@ attrs:
   }
 
 // EXPLAIN (options) query
+// EXPLAIN ANALYZE query
 explain_stmt:
   EXPLAIN explainable_stmt
   {
@@ -1181,6 +1342,10 @@ explain_stmt:
   {
     $$.val = &Explain{Options: $3.strs(), Statement: $5.stmt()}
   }
+| EXPLAIN analyze_word explainable_stmt
+  {
+    $$.val = &Explain{Options: []string{"ANALYZE"}, Statement: $3.stmt()}
+  }
 
 explainable_stmt:
   select_stmt
@@ -1212,6 +1377,14 @@ explain_option_list:
 
 explain_option_name:
   non_reserved_word
+| analyze_word
+  {
+    $$ = "ANALYZE"
+  }
+
+analyze_word:
+  ANALYZE
+| ANALYSE
 
 // PREPARE <plan_name> [(args, ...)] AS <query>
 prepare_stmt:
@@ -1379,11 +1552,16 @@ grantee_list:
   }
 
 // RESET name
+// RESET CLUSTER SETTING name
 reset_stmt:
   RESET var_name
   {
     $$.val = &Set{Name: $2.unresolvedName(), SetMode: SetModeReset}
   }
+| RESET CLUSTER SETTING var_name
+  {
+    $$.val = &Set{Name: $4.unresolvedName(), SetMode: SetModeClusterSetting}
+  }
 
 // SET name TO 'var_value'
 // SET TIME ZONE 'var_value'
@@ -1605,10 +1783,19 @@ show_stmt:
   {
     $$.val = &Show{Name: $4.unresolvedName().String(), ClusterSetting: true}
   }
+| SHOW ALL CLUSTER SETTINGS
+  {
+    $$.val = &Show{Name: "all", ClusterSetting: true}
+  }
 | SHOW DATABASE
   {
     $$.val = &Show{Name: $2}
   }
+| SHOW BACKUP string_or_placeholder opt_incremental
+  {
+    /* SKIP DOC */
+    $$.val = &ShowBackup{Path: $3.expr(), IncrementalFrom: $4.exprs()}
+  }
 | SHOW COLUMNS FROM var_name
   {
     $$.val = &ShowColumns{Table: $4.normalizableTableName()}
@@ -1621,6 +1808,10 @@ show_stmt:
   {
     $$.val = &ShowGrants{Targets: $3.targetListPtr(), Grantees: $4.nameList()}
   }
+| SHOW JOBS
+  {
+    $$.val = &ShowJobs{}
+  }
 | SHOW INDEX FROM var_name
   {
     $$.val = &ShowIndex{Table: $4.normalizableTableName()}
@@ -1677,6 +1868,26 @@ show_stmt:
   {
     $$.val = &ShowUsers{}
   }
+| SHOW ZONE CONFIGURATION FOR DATABASE name
+  {
+    $$.val = &ShowZoneConfig{ZoneSpecifier{Database: Name($6)}}
+  }
+| SHOW ZONE CONFIGURATION FOR TABLE qualified_name
+  {
+    $$.val = &ShowZoneConfig{ZoneSpecifier{Table: $6.newNormalizableTableName()}}
+  }
+| SHOW ZONE CONFIGURATION FOR INDEX table_name_with_index
+  {
+    $$.val = &ShowZoneConfig{ZoneSpecifier{Index: $6.tableWithIdx()}}
+  }
+| SHOW ZONE CONFIGURATION FOR PARTITION name OF TABLE qualified_name
+  {
+    $$.val = &ShowZoneConfig{ZoneSpecifier{Partition: Name($6), Table: $9.newNormalizableTableName()}}
+  }
+| SHOW ZONE CONFIGURATION FOR PARTITION name OF INDEX table_name_with_index
+  {
+    $$.val = &ShowZoneConfig{ZoneSpecifier{Partition: Name($6), Index: $9.tableWithIdx()}}
+  }
 | SHOW TESTING_RANGES FROM TABLE qualified_name
   {
     /* SKIP DOC */
@@ -1688,6 +1899,24 @@ show_stmt:
     $$.val = &ShowRanges{Index: $5.tableWithIdx()}
   }
 
+pause_job_stmt:
+  PAUSE JOB a_expr
+  {
+    $$.val = &PauseJob{ID: $3.expr()}
+  }
+
+resume_job_stmt:
+  RESUME JOB a_expr
+  {
+    $$.val = &ResumeJob{ID: $3.expr()}
+  }
+
+cancel_job_stmt:
+  CANCEL JOB a_expr
+  {
+    $$.val = &CancelJob{ID: $3.expr()}
+  }
+
 help_stmt:
   HELP unrestricted_name
   {
@@ -1715,6 +1944,58 @@ for_grantee_clause:
     $$.val = NameList(nil)
   }
 
+set_zone_config_stmt:
+  ALTER DATABASE name CONFIGURE ZONE USING zone_option_list
+  {
+    $$.val = &SetZoneConfig{ZoneSpecifier: ZoneSpecifier{Database: Name($3)}, Options: $7.updateExprs()}
+  }
+| ALTER DATABASE name CONFIGURE ZONE DISCARD
+  {
+    $$.val = &SetZoneConfig{ZoneSpecifier: ZoneSpecifier{Database: Name($3)}, Discard: true}
+  }
+| ALTER TABLE qualified_name CONFIGURE ZONE USING zone_option_list
+  {
+    $$.val = &SetZoneConfig{ZoneSpecifier: ZoneSpecifier{Table: $3.newNormalizableTableName()}, Options: $7.updateExprs()}
+  }
+| ALTER TABLE qualified_name CONFIGURE ZONE DISCARD
+  {
+    $$.val = &SetZoneConfig{ZoneSpecifier: ZoneSpecifier{Table: $3.newNormalizableTableName()}, Discard: true}
+  }
+| ALTER INDEX table_name_with_index CONFIGURE ZONE USING zone_option_list
+  {
+    $$.val = &SetZoneConfig{ZoneSpecifier: ZoneSpecifier{Index: $3.tableWithIdx()}, Options: $7.updateExprs()}
+  }
+| ALTER INDEX table_name_with_index CONFIGURE ZONE DISCARD
+  {
+    $$.val = &SetZoneConfig{ZoneSpecifier: ZoneSpecifier{Index: $3.tableWithIdx()}, Discard: true}
+  }
+| ALTER PARTITION name OF TABLE qualified_name CONFIGURE ZONE USING zone_option_list
+  {
+    $$.val = &SetZoneConfig{ZoneSpecifier: ZoneSpecifier{Partition: Name($3), Table: $6.newNormalizableTableName()}, Options: $10.updateExprs()}
+  }
+| ALTER PARTITION name OF TABLE qualified_name CONFIGURE ZONE DISCARD
+  {
+    $$.val = &SetZoneConfig{ZoneSpecifier: ZoneSpecifier{Partition: Name($3), Table: $6.newNormalizableTableName()}, Discard: true}
+  }
+| ALTER PARTITION name OF INDEX table_name_with_index CONFIGURE ZONE USING zone_option_list
+  {
+    $$.val = &SetZoneConfig{ZoneSpecifier: ZoneSpecifier{Partition: Name($3), Index: $6.tableWithIdx()}, Options: $10.updateExprs()}
+  }
+| ALTER PARTITION name OF INDEX table_name_with_index CONFIGURE ZONE DISCARD
+  {
+    $$.val = &SetZoneConfig{ZoneSpecifier: ZoneSpecifier{Partition: Name($3), Index: $6.tableWithIdx()}, Discard: true}
+  }
+
+zone_option_list:
+  var_name '=' a_expr
+  {
+    $$.val = UpdateExprs{&UpdateExpr{Names: UnresolvedNames{$1.unresolvedName()}, Expr: $3.expr()}}
+  }
+| zone_option_list ',' var_name '=' a_expr
+  {
+    $$.val = append($1.updateExprs(), &UpdateExpr{Names: UnresolvedNames{$3.unresolvedName()}, Expr: $5.expr()})
+  }
+
 split_stmt:
   ALTER TABLE qualified_name SPLIT AT select_stmt
   {
@@ -1761,13 +2042,13 @@ scatter_stmt:
 
 // CREATE TABLE relname
 create_table_stmt:
-  CREATE TABLE any_name '(' opt_table_elem_list ')' opt_interleave
+  CREATE TABLE any_name '(' opt_table_elem_list ')' opt_interleave opt_partition_by
   {
-    $$.val = &CreateTable{Table: $3.normalizableTableName(), IfNotExists: false, Interleave: $7.interleave(), Defs: $5.tblDefs(), AsSource: nil, AsColumnNames: nil}
+    $$.val = &CreateTable{Table: $3.normalizableTableName(), IfNotExists: false, Interleave: $7.interleave(), PartitionBy: $8.partitionBy(), Defs: $5.tblDefs(), AsSource: nil, AsColumnNames: nil}
   }
-| CREATE TABLE IF NOT EXISTS any_name '(' opt_table_elem_list ')' opt_interleave
+| CREATE TABLE IF NOT EXISTS any_name '(' opt_table_elem_list ')' opt_interleave opt_partition_by
   {
-    $$.val = &CreateTable{Table: $6.normalizableTableName(), IfNotExists: true, Interleave: $10.interleave(), Defs: $8.tblDefs(), AsSource: nil, AsColumnNames: nil}
+    $$.val = &CreateTable{Table: $6.normalizableTableName(), IfNotExists: true, Interleave: $10.interleave(), PartitionBy: $11.partitionBy(), Defs: $8.tblDefs(), AsSource: nil, AsColumnNames: nil}
   }
 
 create_table_as_stmt:
@@ -1823,6 +2104,46 @@ opt_interleave:
     $$.val = (*InterleaveDef)(nil)
   }
 
+opt_partition_by:
+  PARTITION BY LIST '(' name_list ')' '(' list_partitions ')'
+  {
+    $$.val = &PartitionBy{Fields: $5.nameList(), List: $8.partitionBy().List}
+  }
+| PARTITION BY RANGE '(' name_list ')' '(' range_partitions ')'
+  {
+    $$.val = &PartitionBy{Fields: $5.nameList(), Range: $8.partitionBy().Range}
+  }
+| /* EMPTY */
+  {
+    $$.val = (*PartitionBy)(nil)
+  }
+
+// list_partitions and range_partitions only set the List and Range fields of
+// the PartitionBy they return.
+list_partitions:
+  PARTITION name VALUES IN '(' expr_list ')'
+  {
+    $$.val = &PartitionBy{List: []ListPartition{{Name: Name($2), Exprs: $6.exprs()}}}
+  }
+| list_partitions ',' PARTITION name VALUES IN '(' expr_list ')'
+  {
+    p := $1.partitionBy()
+    p.List = append(p.List, ListPartition{Name: Name($4), Exprs: $8.exprs()})
+    $$.val = p
+  }
+
+range_partitions:
+  PARTITION name VALUES '<' a_expr
+  {
+    $$.val = &PartitionBy{Range: []RangePartition{{Name: Name($2), Expr: $5.expr()}}}
+  }
+| range_partitions ',' PARTITION name VALUES '<' a_expr
+  {
+    p := $1.partitionBy()
+    p.Range = append(p.Range, RangePartition{Name: Name($4), Expr: $7.expr()})
+    $$.val = p
+  }
+
 // TODO(dan): This can be removed in favor of opt_drop_behavior when #7854 is fixed.
 opt_interleave_drop_behavior:
   CASCADE
@@ -1933,16 +2254,17 @@ col_qualification_elem:
  }
 
 index_def:
-  INDEX opt_name '(' index_params ')' opt_storing opt_interleave
+  INDEX opt_name '(' index_params ')' opt_storing opt_interleave opt_partition_by
   {
     $$.val = &IndexTableDef{
       Name:    Name($2),
       Columns: $4.idxElems(),
       Storing: $6.nameList(),
       Interleave: $7.interleave(),
+      PartitionBy: $8.partitionBy(),
     }
   }
-| UNIQUE INDEX opt_name '(' index_params ')' opt_storing opt_interleave
+| UNIQUE INDEX opt_name '(' index_params ')' opt_storing opt_interleave opt_partition_by
   {
     $$.val = &UniqueConstraintTableDef{
       IndexTableDef: IndexTableDef {
@@ -1950,6 +2272,7 @@ index_def:
         Columns: $5.idxElems(),
         Storing: $7.nameList(),
         Interleave: $8.interleave(),
+        PartitionBy: $9.partitionBy(),
       },
     }
   }
@@ -1984,13 +2307,14 @@ constraint_elem:
       Expr: $3.expr(),
     }
   }
-| UNIQUE '(' index_params ')' opt_storing opt_interleave
+| UNIQUE '(' index_params ')' opt_storing opt_interleave opt_partition_by
   {
     $$.val = &UniqueConstraintTableDef{
       IndexTableDef: IndexTableDef{
         Columns: $3.idxElems(),
         Storing: $5.nameList(),
         Interleave: $6.interleave(),
+        PartitionBy: $7.partitionBy(),
       },
     }
   }
@@ -2097,6 +2421,17 @@ truncate_stmt:
     $$.val = &Truncate{Tables: $3.tableNameReferences(), DropBehavior: $4.dropBehavior()}
   }
 
+// CREATE STATISTICS name ON columns FROM table
+create_stats_stmt:
+  CREATE STATISTICS name ON name_list FROM qualified_name
+  {
+    $$.val = &CreateStats{
+      Name:        Name($3),
+      ColumnNames: $5.nameList(),
+      Table:       $7.normalizableTableName(),
+    }
+  }
+
 // CREATE USER
 create_user_stmt:
   CREATE USER name opt_with opt_password
@@ -2114,6 +2449,14 @@ opt_password:
     $$.val = (*string)(nil)
   }
 
+// CREATE CHANGEFEED FOR targets INTO sink
+create_changefeed_stmt:
+  CREATE CHANGEFEED FOR targets INTO string_or_placeholder opt_with_options
+  {
+    /* SKIP DOC */
+    $$.val = &CreateChangefeed{Targets: $4.targetList(), SinkURI: $6.expr(), Options: $7.kvOptions()}
+  }
+
 // CREATE VIEW relname
 create_view_stmt:
   CREATE VIEW any_name opt_column_list AS select_stmt
@@ -2129,7 +2472,7 @@ create_view_stmt:
 
 // CREATE INDEX
 create_index_stmt:
-  CREATE opt_unique INDEX opt_name ON qualified_name '(' index_params ')' opt_storing opt_interleave
+  CREATE opt_unique INDEX opt_name ON qualified_name '(' index_params ')' opt_storing opt_interleave opt_partition_by
   {
     $$.val = &CreateIndex{
       Name:    Name($4),
@@ -2138,9 +2481,10 @@ create_index_stmt:
       Columns: $8.idxElems(),
       Storing: $10.nameList(),
       Interleave: $11.interleave(),
+      PartitionBy: $12.partitionBy(),
     }
   }
-| CREATE opt_unique INDEX IF NOT EXISTS name ON qualified_name '(' index_params ')' opt_storing opt_interleave
+| CREATE opt_unique INDEX IF NOT EXISTS name ON qualified_name '(' index_params ')' opt_storing opt_interleave opt_partition_by
   {
     $$.val = &CreateIndex{
       Name:        Name($7),
@@ -2150,6 +2494,7 @@ create_index_stmt:
       Columns:     $11.idxElems(),
       Storing:     $13.nameList(),
       Interleave: $14.interleave(),
+      PartitionBy: $15.partitionBy(),
     }
   }
 
@@ -5166,15 +5511,20 @@ unreserved_keyword:
 | BEGIN
 | BLOB
 | BY
+| CANCEL
 | CASCADE
+| CHANGEFEED
 | CLUSTER
 | COLUMNS
 | COMMIT
 | COMMITTED
+| CONFIGURATION
+| CONFIGURE
 | CONFLICT
 | CONSTRAINTS
 | COPY
 | COVERING
+| CSV
 | CUBE
 | CURRENT
 | CYCLE
@@ -5184,30 +5534,38 @@ unreserved_keyword:
 | DAY
 | DEALLOCATE
 | DELETE
+| DELIMITER
+| DISCARD
 | DOUBLE
 | DROP
 | ENCODING
 | EXECUTE
 | EXPLAIN
+| EXPORT
 | FILTER
 | FIRST
 | FOLLOWING
 | FORCE_INDEX
 | GRANTS
+| HEADER
 | HELP
 | HIGH
 | HOUR
+| IMPORT
 | INCREMENTAL
 | INDEXES
 | INSERT
 | INT2VECTOR
 | INTERLEAVE
 | ISOLATION
+| JOB
+| JOBS
 | KEY
 | KEYS
 | LC_COLLATE
 | LC_CTYPE
 | LEVEL
+| LIST
 | LOCAL
 | LOW
 | MATCH
@@ -5230,6 +5588,8 @@ unreserved_keyword:
 | PARTIAL
 | PARTITION
 | PASSWORD
+| PAUSE
+| PGDUMP
 | PRECEDING
 | PREPARE
 | PRIORITY
@@ -5248,11 +5608,14 @@ unreserved_keyword:
 | RESET
 | RESTORE
 | RESTRICT
+| RESUME
 | REVOKE
 | ROLLBACK
 | ROLLUP
 | ROWS
 | SETTING
+| SETTINGS
+| STATISTICS
 | STATUS
 | SAVEPOINT
 | SCATTER
@@ -5267,6 +5630,7 @@ unreserved_keyword:
 | SQL
 | START
 | STDIN
+| STDOUT
 | STORING
 | STRICT
 | SPLIT
@@ -51,6 +51,8 @@ const (
 	Rows
 	// CopyIn indicates a COPY FROM statement.
 	CopyIn
+	// CopyOut indicates a COPY TO statement.
+	CopyOut
 	// Unknown indicates that the statement does not have a known
 	// return style at the time of parsing. This is not first in the
 	// enumeration because it is more convenient to have Ack as a zero
@@ -115,12 +117,30 @@ func (*CommitTransaction) StatementTag() string { return "COMMIT" }
 
 func (*CommitTransaction) hiddenFromStats() {}
 
+// StatementType implements the Statement interface.
+func (*CancelJob) StatementType() StatementType { return Ack }
+
+// StatementTag returns a short string identifying the type of statement.
+func (*CancelJob) StatementTag() string { return "CANCEL JOB" }
+
 // StatementType implements the Statement interface.
 func (*CopyFrom) StatementType() StatementType { return CopyIn }
 
 // StatementTag returns a short string identifying the type of statement.
 func (*CopyFrom) StatementTag() string { return "COPY" }
 
+// StatementType implements the Statement interface.
+func (*CopyTo) StatementType() StatementType { return CopyOut }
+
+// StatementTag returns a short string identifying the type of statement.
+func (*CopyTo) StatementTag() string { return "COPY" }
+
+// StatementType implements the Statement interface.
+func (*CreateChangefeed) StatementType() StatementType { return Rows }
+
+// StatementTag returns a short string identifying the type of statement.
+func (*CreateChangefeed) StatementTag() string { return "CREATE CHANGEFEED" }
+
 // StatementType implements the Statement interface.
 func (*CreateDatabase) StatementType() StatementType { return DDL }
 
@@ -133,6 +153,12 @@ func (*CreateIndex) StatementType() StatementType { return DDL }
 // StatementTag returns a short string identifying the type of statement.
 func (*CreateIndex) StatementTag() string { return "CREATE INDEX" }
 
+// StatementType implements the Statement interface.
+func (*CreateStats) StatementType() StatementType { return Ack }
+
+// StatementTag returns a short string identifying the type of statement.
+func (*CreateStats) StatementTag() string { return "CREATE STATISTICS" }
+
 // StatementType implements the Statement interface.
 func (*CreateTable) StatementType() StatementType { return DDL }
 
@@ -214,6 +240,12 @@ func (*Explain) StatementTag() string { return "EXPLAIN" }
 
 func (*Explain) hiddenFromStats() {}
 
+// StatementType implements the Statement interface.
+func (*Export) StatementType() StatementType { return Rows }
+
+// StatementTag returns a short string identifying the type of statement.
+func (*Export) StatementTag() string { return "EXPORT" }
+
 // StatementType implements the Statement interface.
 func (*Grant) StatementType() StatementType { return DDL }
 
@@ -222,6 +254,12 @@ func (*Grant) StatementTag() string { return "GRANT" }
 
 func (*Grant) hiddenFromStats() {}
 
+// StatementType implements the Statement interface.
+func (*Import) StatementType() StatementType { return Rows }
+
+// StatementTag returns a short string identifying the type of statement.
+func (*Import) StatementTag() string { return "IMPORT" }
+
 // StatementType implements the Statement interface.
 func (n *Insert) StatementType() StatementType { return n.Returning.statementType() }
 
@@ -234,6 +272,12 @@ func (*ParenSelect) StatementType() StatementType { return Rows }
 // StatementTag returns a short string identifying the type of statement.
 func (*ParenSelect) StatementTag() string { return "SELECT" }
 
+// StatementType implements the Statement interface.
+func (*PauseJob) StatementType() StatementType { return Ack }
+
+// StatementTag returns a short string identifying the type of statement.
+func (*PauseJob) StatementTag() string { return "PAUSE JOB" }
+
 // StatementType implements the Statement interface.
 func (*Prepare) StatementType() StatementType { return Ack }
 
@@ -291,6 +335,12 @@ func (*Restore) StatementType() StatementType { return Ack }
 // StatementTag returns a short string identifying the type of statement.
 func (*Restore) StatementTag() string { return "RESTORE" }
 
+// StatementType implements the Statement interface.
+func (*ResumeJob) StatementType() StatementType { return Ack }
+
+// StatementTag returns a short string identifying the type of statement.
+func (*ResumeJob) StatementTag() string { return "RESUME JOB" }
+
 // StatementType implements the Statement interface.
 func (*Revoke) StatementType() StatementType { return DDL }
 
@@ -355,6 +405,12 @@ func (*SetTransaction) StatementTag() string { return "SET TRANSACTION" }
 
 func (*SetTransaction) hiddenFromStats() {}
 
+// StatementType implements the Statement interface.
+func (*SetZoneConfig) StatementType() StatementType { return DDL }
+
+// StatementTag returns a short string identifying the type of statement.
+func (*SetZoneConfig) StatementTag() string { return "CONFIGURE ZONE" }
+
 // StatementType implements the Statement interface.
 func (*SetTimeZone) StatementType() StatementType { return Ack }
 
@@ -443,6 +499,24 @@ func (*ShowTransactionStatus) StatementTag() string { return "SHOW TRANSACTION S
 func (*ShowTransactionStatus) hiddenFromStats()                   {}
 func (*ShowTransactionStatus) independentFromParallelizedPriors() {}
 
+// StatementType implements the Statement interface.
+func (*ShowBackup) StatementType() StatementType { return Rows }
+
+// StatementTag returns a short string identifying the type of statement.
+func (*ShowBackup) StatementTag() string { return "SHOW BACKUP" }
+
+func (*ShowBackup) hiddenFromStats()                   {}
+func (*ShowBackup) independentFromParallelizedPriors() {}
+
+// StatementType implements the Statement interface.
+func (*ShowJobs) StatementType() StatementType { return Rows }
+
+// StatementTag returns a short string identifying the type of statement.
+func (*ShowJobs) StatementTag() string { return "SHOW JOBS" }
+
+func (*ShowJobs) hiddenFromStats()                   {}
+func (*ShowJobs) independentFromParallelizedPriors() {}
+
 // StatementType implements the Statement interface.
 func (*ShowUsers) StatementType() StatementType { return Rows }
 
@@ -452,6 +526,15 @@ func (*ShowUsers) StatementTag() string { return "SHOW USERS" }
 func (*ShowUsers) hiddenFromStats()                   {}
 func (*ShowUsers) independentFromParallelizedPriors() {}
 
+// StatementType implements the Statement interface.
+func (*ShowZoneConfig) StatementType() StatementType { return Rows }
+
+// StatementTag returns a short string identifying the type of statement.
+func (*ShowZoneConfig) StatementTag() string { return "SHOW ZONE CONFIGURATION" }
+
+func (*ShowZoneConfig) hiddenFromStats()                   {}
+func (*ShowZoneConfig) independentFromParallelizedPriors() {}
+
 // StatementType implements the Statement interface.
 func (*ShowRanges) StatementType() StatementType { return Rows }
 
@@ -527,10 +610,14 @@ func (n *AlterTableDropNotNull) String() string    { return AsString(n) }
 func (n *AlterTableSetDefault) String() string     { return AsString(n) }
 func (n *Backup) String() string                   { return AsString(n) }
 func (n *BeginTransaction) String() string         { return AsString(n) }
+func (n *CancelJob) String() string                { return AsString(n) }
 func (n *CommitTransaction) String() string        { return AsString(n) }
 func (n *CopyFrom) String() string                 { return AsString(n) }
+func (n *CopyTo) String() string                   { return AsString(n) }
+func (n *CreateChangefeed) String() string         { return AsString(n) }
 func (n *CreateDatabase) String() string           { return AsString(n) }
 func (n *CreateIndex) String() string              { return AsString(n) }
+func (n *CreateStats) String() string              { return AsString(n) }
 func (n *CreateTable) String() string              { return AsString(n) }
 func (n *CreateUser) String() string               { return AsString(n) }
 func (n *CreateView) String() string               { return AsString(n) }
@@ -542,10 +629,13 @@ func (n *DropTable) String() string                { return AsString(n) }
 func (n *DropView) String() string                 { return AsString(n) }
 func (n *Execute) String() string                  { return AsString(n) }
 func (n *Explain) String() string                  { return AsString(n) }
+func (n *Export) String() string                   { return AsString(n) }
 func (n *Grant) String() string                    { return AsString(n) }
 func (n *Help) String() string                     { return AsString(n) }
+func (n *Import) String() string                   { return AsString(n) }
 func (n *Insert) String() string                   { return AsString(n) }
 func (n *ParenSelect) String() string              { return AsString(n) }
+func (n *PauseJob) String() string                 { return AsString(n) }
 func (n *Prepare) String() string                  { return AsString(n) }
 func (n *ReleaseSavepoint) String() string         { return AsString(n) }
 func (n *Relocate) String() string                 { return AsString(n) }
@@ -554,6 +644,7 @@ func (n *RenameDatabase) String() string           { return AsString(n) }
 func (n *RenameIndex) String() string              { return AsString(n) }
 func (n *RenameTable) String() string              { return AsString(n) }
 func (n *Restore) String() string                  { return AsString(n) }
+func (n *ResumeJob) String() string                { return AsString(n) }
 func (n *Revoke) String() string                   { return AsString(n) }
 func (n *RollbackToSavepoint) String() string      { return AsString(n) }
 func (n *RollbackTransaction) String() string      { return AsString(n) }
@@ -565,17 +656,21 @@ func (n *Set) String() string                      { return AsString(n) }
 func (n *SetDefaultIsolation) String() string      { return AsString(n) }
 func (n *SetTimeZone) String() string              { return AsString(n) }
 func (n *SetTransaction) String() string           { return AsString(n) }
+func (n *SetZoneConfig) String() string            { return AsString(n) }
 func (n *Show) String() string                     { return AsString(n) }
+func (n *ShowBackup) String() string               { return AsString(n) }
 func (n *ShowColumns) String() string              { return AsString(n) }
 func (n *ShowCreateTable) String() string          { return AsString(n) }
 func (n *ShowCreateView) String() string           { return AsString(n) }
 func (n *ShowDatabases) String() string            { return AsString(n) }
 func (n *ShowGrants) String() string               { return AsString(n) }
 func (n *ShowIndex) String() string                { return AsString(n) }
+func (n *ShowJobs) String() string                 { return AsString(n) }
 func (n *ShowConstraints) String() string          { return AsString(n) }
 func (n *ShowTables) String() string               { return AsString(n) }
 func (n *ShowTransactionStatus) String() string    { return AsString(n) }
 func (n *ShowUsers) String() string                { return AsString(n) }
+func (n *ShowZoneConfig) String() string           { return AsString(n) }
 func (n *ShowRanges) String() string               { return AsString(n) }
 func (n *Split) String() string                    { return AsString(n) }
 func (l StatementList) String() string             { return AsString(l) }
@@ -0,0 +1,85 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package parser
+
+import "bytes"
+
+// ZoneSpecifier represents a reference to a configurable zone of the keyspace.
+// Only one of Database, Table and Index can be set. Partition can be set along
+// with Table or Index to refer to a partition of the primary index of the table
+// or of the index, respectively.
+type ZoneSpecifier struct {
+	Database  Name
+	Table     *NormalizableTableName
+	Index     *TableNameWithIndex
+	Partition Name
+}
+
+// Format implements the NodeFormatter interface.
+func (node ZoneSpecifier) Format(buf *bytes.Buffer, f FmtFlags) {
+	if node.Partition != "" {
+		buf.WriteString("PARTITION ")
+		FormatNode(buf, f, node.Partition)
+		buf.WriteString(" OF ")
+	}
+	switch {
+	case node.Index != nil:
+		buf.WriteString("INDEX ")
+		FormatNode(buf, f, node.Index)
+	case node.Table != nil:
+		buf.WriteString("TABLE ")
+		FormatNode(buf, f, node.Table)
+	default:
+		buf.WriteString("DATABASE ")
+		FormatNode(buf, f, node.Database)
+	}
+}
+
+// SetZoneConfig represents an `ALTER DATABASE/TABLE/INDEX/PARTITION ..
+// CONFIGURE ZONE` statement.
+type SetZoneConfig struct {
+	ZoneSpecifier
+	// Options holds the `field = value` assignments of the zone config fields
+	// to set. Fields that are not specified are inherited. Options is empty
+	// when Discard is set.
+	Options UpdateExprs
+	// Discard indicates that the zone config should be removed, so that the
+	// zone inherits its config again.
+	Discard bool
+}
+
+// Format implements the NodeFormatter interface.
+func (node *SetZoneConfig) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("ALTER ")
+	FormatNode(buf, f, node.ZoneSpecifier)
+	buf.WriteString(" CONFIGURE ZONE ")
+	if node.Discard {
+		buf.WriteString("DISCARD")
+	} else {
+		buf.WriteString("USING ")
+		FormatNode(buf, f, node.Options)
+	}
+}
+
+// ShowZoneConfig represents a `SHOW ZONE CONFIGURATION FOR ..` statement.
+type ShowZoneConfig struct {
+	ZoneSpecifier
+}
+
+// Format implements the NodeFormatter interface.
+func (node *ShowZoneConfig) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("SHOW ZONE CONFIGURATION FOR ")
+	FormatNode(buf, f, node.ZoneSpecifier)
+}
@@ -0,0 +1,404 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// This file implements the partitioning of indexes with PARTITION BY LIST and
+// PARTITION BY RANGE. A partition is a set of spans of an index that can be
+// given its own zone config, which is stored as a subzone of the zone config
+// of the table.
+
+package sql
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/cockroachdb/cockroach/pkg/config"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/util/encoding"
+)
+
+// maxValueName is the name that stands for the largest value of a column in
+// the upper bound of a RANGE partition.
+const maxValueName = "maxvalue"
+
+// isMaxValue returns whether expr is MAXVALUE.
+func isMaxValue(expr parser.Expr) bool {
+	n, ok := parser.StripParens(expr).(parser.UnresolvedName)
+	if !ok || len(n) != 1 {
+		return false
+	}
+	name, ok := n[0].(parser.Name)
+	return ok && name.Normalize() == maxValueName
+}
+
+// partitionTupleExprs returns the values of the partitioning columns in a
+// value of a partition, which is a tuple when partitioning by more than one
+// column.
+func partitionTupleExprs(partition string, expr parser.Expr, numCols int) (parser.Exprs, error) {
+	expr = parser.StripParens(expr)
+	if numCols == 1 {
+		return parser.Exprs{expr}, nil
+	}
+	tuple, ok := expr.(*parser.Tuple)
+	if !ok || len(tuple.Exprs) != numCols {
+		return nil, errors.Errorf("partition %q: expected a tuple of %d values, got %s",
+			partition, numCols, expr)
+	}
+	return tuple.Exprs, nil
+}
+
+// encodePartitionTuple evaluates the given values of the first columns of an
+// index and encodes them like the index does.
+func encodePartitionTuple(
+	partition string,
+	tableDesc *sqlbase.TableDescriptor,
+	indexDesc *sqlbase.IndexDescriptor,
+	exprs parser.Exprs,
+	searchPath parser.SearchPath,
+) ([]byte, error) {
+	// Partition values are constants, so they do not depend on the session.
+	var evalCtx parser.EvalContext
+	semaCtx := parser.SemaContext{SearchPath: searchPath}
+	var key []byte
+	for i, expr := range exprs {
+		if isMaxValue(expr) {
+			return nil, errors.Errorf("partition %q: MAXVALUE cannot be used here", partition)
+		}
+		if parser.ContainsVars(expr) {
+			return nil, errors.Errorf("partition %q: variable sub-expressions are not allowed in %s",
+				partition, expr)
+		}
+		col, err := tableDesc.FindColumnByID(indexDesc.ColumnIDs[i])
+		if err != nil {
+			return nil, err
+		}
+		typedExpr, err := parser.TypeCheckAndRequire(
+			expr, &semaCtx, col.Type.ToDatumType(), "partition "+parser.Name(partition).String())
+		if err != nil {
+			return nil, err
+		}
+		d, err := typedExpr.Eval(&evalCtx)
+		if err != nil {
+			return nil, err
+		}
+		dir, err := indexDesc.ColumnDirections[i].ToEncodingDirection()
+		if err != nil {
+			return nil, err
+		}
+		if key, err = sqlbase.EncodeTableKey(key, d, dir); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// decodePartitionTuple decodes the values of the first columns of an index
+// that were encoded by encodePartitionTuple. The encoding of the upper bound
+// of a RANGE partition can hold fewer values than there are partitioning
+// columns, the remaining ones being MAXVALUE.
+func decodePartitionTuple(
+	a *sqlbase.DatumAlloc,
+	tableDesc *sqlbase.TableDescriptor,
+	indexDesc *sqlbase.IndexDescriptor,
+	key []byte,
+) (parser.Datums, error) {
+	var datums parser.Datums
+	for i := 0; len(key) > 0; i++ {
+		if i >= int(indexDesc.Partitioning.NumColumns) {
+			return nil, errors.Errorf("partition value has more than %d columns",
+				indexDesc.Partitioning.NumColumns)
+		}
+		col, err := tableDesc.FindColumnByID(indexDesc.ColumnIDs[i])
+		if err != nil {
+			return nil, err
+		}
+		dir, err := indexDesc.ColumnDirections[i].ToEncodingDirection()
+		if err != nil {
+			return nil, err
+		}
+		var d parser.Datum
+		if d, key, err = sqlbase.DecodeTableKey(a, col.Type.ToDatumType(), key, dir); err != nil {
+			return nil, err
+		}
+		datums = append(datums, d)
+	}
+	return datums, nil
+}
+
+// createPartitioning returns the partitioning of an index described by a
+// PARTITION BY clause. The index must have its column IDs allocated.
+func createPartitioning(
+	tableDesc *sqlbase.TableDescriptor,
+	indexDesc *sqlbase.IndexDescriptor,
+	partBy *parser.PartitionBy,
+	searchPath parser.SearchPath,
+) (sqlbase.PartitioningDescriptor, error) {
+	var partitioning sqlbase.PartitioningDescriptor
+	if len(indexDesc.Interleave.Ancestors) > 0 {
+		return partitioning, errors.New("interleaved indexes cannot be partitioned")
+	}
+	numCols := len(partBy.Fields)
+	if numCols > len(indexDesc.ColumnNames) {
+		return partitioning, errors.Errorf(
+			"declared partition columns (%s) exceed the columns of index %q",
+			parser.AsString(partBy.Fields), indexDesc.Name)
+	}
+	for i, field := range partBy.Fields {
+		if field.Normalize() != parser.ReNormalizeName(indexDesc.ColumnNames[i]) {
+			return partitioning, errors.Errorf(
+				"declared partition columns (%s) do not match first %d columns in index %q (%s)",
+				parser.AsString(partBy.Fields), numCols, indexDesc.Name,
+				quoteNames(indexDesc.ColumnNames[:numCols]...))
+		}
+	}
+	partitioning.NumColumns = uint32(numCols)
+
+	names := make(map[string]struct{})
+	checkName := func(name parser.Name) error {
+		if _, ok := names[name.Normalize()]; ok {
+			return errors.Errorf("partition name %q must be unique", name)
+		}
+		names[name.Normalize()] = struct{}{}
+		return nil
+	}
+
+	// The partition of each LIST value, which must be unique.
+	values := make(map[string]string)
+	for _, l := range partBy.List {
+		if err := checkName(l.Name); err != nil {
+			return partitioning, err
+		}
+		p := sqlbase.PartitioningDescriptor_List{Name: string(l.Name)}
+		for _, expr := range l.Exprs {
+			tuple, err := partitionTupleExprs(string(l.Name), expr, numCols)
+			if err != nil {
+				return partitioning, err
+			}
+			value, err := encodePartitionTuple(string(l.Name), tableDesc, indexDesc, tuple, searchPath)
+			if err != nil {
+				return partitioning, err
+			}
+			if other, ok := values[string(value)]; ok {
+				return partitioning, errors.Errorf("%s cannot be present in more than one partition "+
+					"(found in %q and %q)", expr, other, l.Name)
+			}
+			values[string(value)] = string(l.Name)
+			p.Values = append(p.Values, value)
+		}
+		partitioning.List = append(partitioning.List, p)
+	}
+
+	var prevEnd roachpb.Key
+	for i, r := range partBy.Range {
+		if err := checkName(r.Name); err != nil {
+			return partitioning, err
+		}
+		tuple, err := partitionTupleExprs(string(r.Name), r.Expr, numCols)
+		if err != nil {
+			return partitioning, err
+		}
+		// Only a suffix of the values can be MAXVALUE.
+		numValues := len(tuple)
+		for j := range tuple {
+			if isMaxValue(tuple[j]) {
+				numValues = j
+				break
+			}
+		}
+		for _, expr := range tuple[numValues:] {
+			if !isMaxValue(expr) {
+				return partitioning, errors.Errorf(
+					"partition %q: MAXVALUE can only be followed by MAXVALUE", r.Name)
+			}
+		}
+		upperBound, err := encodePartitionTuple(
+			string(r.Name), tableDesc, indexDesc, tuple[:numValues], searchPath)
+		if err != nil {
+			return partitioning, err
+		}
+		p := sqlbase.PartitioningDescriptor_Range{Name: string(r.Name), UpperBound: upperBound}
+		end := rangePartitionEnd(indexDesc, upperBound, numValues == numCols)
+		if i > 0 && bytes.Compare(prevEnd, end) >= 0 {
+			return partitioning, errors.Errorf(
+				"partition %q: upper bound must be greater than the upper bound of partition %q",
+				r.Name, partBy.Range[i-1].Name)
+		}
+		prevEnd = end
+		partitioning.Range = append(partitioning.Range, p)
+	}
+	return partitioning, nil
+}
+
+// indexKeyPrefix returns the prefix of the keys of an index, without the
+// table prefix.
+func indexKeyPrefix(indexDesc *sqlbase.IndexDescriptor) roachpb.Key {
+	return encoding.EncodeUvarintAscending(nil, uint64(indexDesc.ID))
+}
+
+// rangePartitionEnd returns the exclusive end of the keys of a RANGE
+// partition, without the table prefix. complete is whether the upper bound
+// has a value for every partitioning column.
+func rangePartitionEnd(
+	indexDesc *sqlbase.IndexDescriptor, upperBound []byte, complete bool,
+) roachpb.Key {
+	end := append(indexKeyPrefix(indexDesc), upperBound...)
+	if !complete {
+		// The bound is followed by MAXVALUE, which includes every key with the
+		// bound as a prefix.
+		return end.PrefixEnd()
+	}
+	return end
+}
+
+// partitionSpans returns the spans of the keys of a partition of an index,
+// without the table prefix, or (nil, false) if there is no such partition.
+func partitionSpans(
+	a *sqlbase.DatumAlloc,
+	tableDesc *sqlbase.TableDescriptor,
+	indexDesc *sqlbase.IndexDescriptor,
+	name string,
+) ([]config.SubzoneSpan, bool, error) {
+	partitioning := &indexDesc.Partitioning
+	prefix := indexKeyPrefix(indexDesc)
+	for _, p := range partitioning.List {
+		if p.Name != name {
+			continue
+		}
+		spans := make([]config.SubzoneSpan, len(p.Values))
+		for i, value := range p.Values {
+			// The span of a LIST value is every key with the value as a prefix.
+			spans[i].Key = append(append(roachpb.Key(nil), prefix...), value...)
+		}
+		return spans, true, nil
+	}
+	start := prefix
+	for _, p := range partitioning.Range {
+		datums, err := decodePartitionTuple(a, tableDesc, indexDesc, p.UpperBound)
+		if err != nil {
+			return nil, false, err
+		}
+		end := rangePartitionEnd(indexDesc, p.UpperBound, len(datums) == int(partitioning.NumColumns))
+		if p.Name == name {
+			return []config.SubzoneSpan{{Key: start, EndKey: end}}, true, nil
+		}
+		start = end
+	}
+	return nil, false, nil
+}
+
+// findPartition returns whether an index has a partition with the given name.
+func findPartition(indexDesc *sqlbase.IndexDescriptor, name string) bool {
+	for _, p := range indexDesc.Partitioning.List {
+		if p.Name == name {
+			return true
+		}
+	}
+	for _, p := range indexDesc.Partitioning.Range {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// generateSubzoneSpans returns the SubzoneSpans of a zone config of a table
+// with the given subzones. Subzones of partitions that no longer exist are
+// ignored.
+func generateSubzoneSpans(
+	tableDesc *sqlbase.TableDescriptor, subzones []config.Subzone,
+) ([]config.SubzoneSpan, error) {
+	var a sqlbase.DatumAlloc
+	var subzoneSpans []config.SubzoneSpan
+	for i, subzone := range subzones {
+		if subzone.PartitionName == "" {
+			continue
+		}
+		indexDesc, err := tableDesc.FindIndexByID(sqlbase.IndexID(subzone.IndexID))
+		if err != nil {
+			continue
+		}
+		spans, ok, err := partitionSpans(&a, tableDesc, indexDesc, subzone.PartitionName)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		for _, span := range spans {
+			span.SubzoneIndex = int32(i)
+			subzoneSpans = append(subzoneSpans, span)
+		}
+	}
+	sort.Slice(subzoneSpans, func(i, j int) bool {
+		return bytes.Compare(subzoneSpans[i].Key, subzoneSpans[j].Key) < 0
+	})
+	return subzoneSpans, nil
+}
+
+// showCreatePartitioning returns a PARTITION BY clause for the specified
+// index, if applicable.
+func showCreatePartitioning(
+	tableDesc *sqlbase.TableDescriptor, indexDesc *sqlbase.IndexDescriptor,
+) (string, error) {
+	partitioning := &indexDesc.Partitioning
+	if partitioning.NumColumns == 0 {
+		return "", nil
+	}
+	numCols := int(partitioning.NumColumns)
+	partBy := parser.PartitionBy{}
+	for _, name := range indexDesc.ColumnNames[:numCols] {
+		partBy.Fields = append(partBy.Fields, parser.Name(name))
+	}
+	var a sqlbase.DatumAlloc
+	tupleExpr := func(datums parser.Datums) parser.Expr {
+		exprs := make(parser.Exprs, numCols)
+		for i := range exprs {
+			if i < len(datums) {
+				exprs[i] = datums[i]
+			} else {
+				exprs[i] = parser.UnresolvedName{parser.Name("MAXVALUE")}
+			}
+		}
+		if numCols == 1 {
+			return exprs[0]
+		}
+		return &parser.Tuple{Exprs: exprs}
+	}
+	for _, p := range partitioning.List {
+		l := parser.ListPartition{Name: parser.Name(p.Name)}
+		for _, value := range p.Values {
+			datums, err := decodePartitionTuple(&a, tableDesc, indexDesc, value)
+			if err != nil {
+				return "", err
+			}
+			l.Exprs = append(l.Exprs, tupleExpr(datums))
+		}
+		partBy.List = append(partBy.List, l)
+	}
+	for _, p := range partitioning.Range {
+		datums, err := decodePartitionTuple(&a, tableDesc, indexDesc, p.UpperBound)
+		if err != nil {
+			return "", err
+		}
+		partBy.Range = append(partBy.Range, parser.RangePartition{
+			Name: parser.Name(p.Name),
+			Expr: tupleExpr(datums),
+		})
+	}
+	return parser.AsString(&partBy), nil
+}
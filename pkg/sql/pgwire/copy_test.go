@@ -0,0 +1,225 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package pgwire_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+// rawPGConn is a minimal client of the pgwire protocol, used to test the COPY
+// data formats that lib/pq does not support.
+type rawPGConn struct {
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+func dialRawPGConn(addr string, user string) (*rawPGConn, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	c := &rawPGConn{conn: conn, rd: bufio.NewReader(conn)}
+
+	var startup bytes.Buffer
+	_ = binary.Write(&startup, binary.BigEndian, int32(196608) /* version 3.0 */)
+	startup.WriteString("user\x00" + user + "\x00\x00")
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(startup.Len()+4))
+	if _, err := conn.Write(append(length[:], startup.Bytes()...)); err != nil {
+		return nil, err
+	}
+	if _, _, err := c.query("", ""); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *rawPGConn) send(typ byte, body []byte) error {
+	var header [5]byte
+	header[0] = typ
+	binary.BigEndian.PutUint32(header[1:], uint32(len(body)+4))
+	_, err := c.conn.Write(append(header[:], body...))
+	return err
+}
+
+func (c *rawPGConn) recv() (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(c.rd, header[:]); err != nil {
+		return 0, nil, err
+	}
+	body := make([]byte, binary.BigEndian.Uint32(header[1:])-4)
+	if _, err := io.ReadFull(c.rd, body); err != nil {
+		return 0, nil, err
+	}
+	return header[0], body, nil
+}
+
+// query runs a simple query, unless it is empty, and reads the responses of
+// the server up to the next ReadyForQuery. The data of a COPY FROM is sent in
+// small chunks, so that lines span several CopyData messages. It returns the
+// data of a COPY TO and the command tag of the query.
+func (c *rawPGConn) query(sql string, copyIn string) (string, string, error) {
+	if sql != "" {
+		if err := c.send('Q', append([]byte(sql), 0)); err != nil {
+			return "", "", err
+		}
+	}
+	var copyOut bytes.Buffer
+	var tag string
+	var queryErr error
+	for {
+		typ, body, err := c.recv()
+		if err != nil {
+			return "", "", err
+		}
+		switch typ {
+		case 'G': // CopyInResponse
+			const chunkSize = 3
+			for data := copyIn; len(data) > 0; {
+				n := chunkSize
+				if n > len(data) {
+					n = len(data)
+				}
+				if err := c.send('d', []byte(data[:n])); err != nil {
+					return "", "", err
+				}
+				data = data[n:]
+			}
+			if err := c.send('c', nil); err != nil {
+				return "", "", err
+			}
+		case 'd': // CopyData
+			copyOut.Write(body)
+		case 'C': // CommandComplete
+			tag = string(bytes.TrimRight(body, "\x00"))
+		case 'E': // ErrorResponse
+			for _, field := range bytes.Split(body, []byte{0}) {
+				if len(field) > 0 && field[0] == 'M' {
+					queryErr = errors.New(string(field[1:]))
+				}
+			}
+		case 'Z': // ReadyForQuery
+			return copyOut.String(), tag, queryErr
+		}
+	}
+}
+
+func TestPGWireCopy(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	s, db, _ := serverutils.StartServer(t, base.TestServerArgs{Insecure: true})
+	defer s.Stopper().Stop()
+
+	if _, err := db.Exec(`
+		CREATE DATABASE d;
+		CREATE TABLE d.t (a INT PRIMARY KEY, b STRING, c FLOAT);
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := dialRawPGConn(s.ServingAddr(), "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.conn.Close()
+
+	for i, tc := range []struct {
+		sql     string
+		copyIn  string
+		copyOut string
+		tag     string
+	}{
+		{sql: `BEGIN`, tag: "BEGIN"},
+		{
+			sql:    `COPY d.t FROM STDIN WITH (FORMAT csv, HEADER)`,
+			copyIn: "a,b,c\n1,\"x,\"\"y\"\"\nz\",1.5\n2,,\n3,\"\",2\n",
+			tag:    "COPY 3",
+		},
+		{
+			sql:    `COPY d.t FROM STDIN WITH (DELIMITER '|', NULL 'nil')`,
+			copyIn: "4|tab\\there|nil\n",
+			tag:    "COPY 1",
+		},
+		{sql: `COMMIT`, tag: "COMMIT"},
+		{
+			sql:     `COPY d.t TO STDOUT WITH CSV HEADER`,
+			copyOut: "a,b,c\n1,\"x,\"\"y\"\"\nz\",1.5\n2,,\n3,\"\",2\n4,tab\there,\n",
+			tag:     "COPY 4",
+		},
+		{
+			sql:     `COPY (SELECT a, b FROM d.t WHERE a != 3 ORDER BY a) TO STDOUT`,
+			copyOut: "1\tx,\"y\"\\nz\n2\t\\N\n4\ttab\\there\n",
+			tag:     "COPY 3",
+		},
+		{
+			sql:     `COPY d.t (b, a) TO STDOUT WITH DELIMITER ',' NULL 'nil'`,
+			copyOut: "x\\,\"y\"\\nz,1\nnil,2\n,3\ntab\\there,4\n",
+			tag:     "COPY 4",
+		},
+	} {
+		copyOut, tag, err := c.query(tc.sql, tc.copyIn)
+		if err != nil {
+			t.Fatalf("%d: %s: %v", i, tc.sql, err)
+		}
+		if copyOut != tc.copyOut || tag != tc.tag {
+			t.Fatalf("%d: %s: expected %q and %q, got %q and %q",
+				i, tc.sql, tc.copyOut, tc.tag, copyOut, tag)
+		}
+	}
+
+	var b string
+	if err := db.QueryRow(`SELECT b FROM d.t WHERE a = 1`).Scan(&b); err != nil {
+		t.Fatal(err)
+	}
+	if b != "x,\"y\"\nz" {
+		t.Fatalf("unexpected value: %q", b)
+	}
+	var nulls int
+	if err := db.QueryRow(
+		`SELECT COUNT(*) FROM d.t WHERE b IS NULL OR c IS NULL`,
+	).Scan(&nulls); err != nil {
+		t.Fatal(err)
+	}
+	if nulls != 2 {
+		t.Fatalf("expected 2 rows with NULLs, got %d", nulls)
+	}
+
+	for _, tc := range []struct {
+		sql    string
+		copyIn string
+		err    string
+	}{
+		{`COPY d.t TO STDOUT WITH (FORMAT binary)`, "", `COPY format "binary" not supported`},
+		{`COPY d.t TO STDOUT WITH HEADER`, "", "COPY HEADER available only in CSV mode"},
+		{`COPY d.t FROM STDIN WITH (QUOTE '"')`, "", `COPY option "quote" not supported`},
+		{`COPY d.t FROM STDIN CSV`, "5,\"abc\n", "unterminated CSV quoted field"},
+	} {
+		if _, _, err := c.query(tc.sql, tc.copyIn); !testutils.IsError(err, tc.err) {
+			t.Fatalf("%s: expected error %q, got %v", tc.sql, tc.err, err)
+		}
+	}
+}
@@ -7,10 +7,10 @@ import "fmt"
 const (
 	_serverMessageType_name_0 = "serverMsgParseCompleteserverMsgBindCompleteserverMsgCloseComplete"
 	_serverMessageType_name_1 = "serverMsgCommandCompleteserverMsgDataRowserverMsgErrorResponse"
-	_serverMessageType_name_2 = "serverMsgCopyInResponse"
-	_serverMessageType_name_3 = "serverMsgEmptyQuery"
-	_serverMessageType_name_4 = "serverMsgAuthserverMsgParameterStatusserverMsgRowDescription"
-	_serverMessageType_name_5 = "serverMsgReady"
+	_serverMessageType_name_2 = "serverMsgCopyInResponseserverMsgCopyOutResponseserverMsgEmptyQuery"
+	_serverMessageType_name_3 = "serverMsgAuthserverMsgParameterStatusserverMsgRowDescription"
+	_serverMessageType_name_4 = "serverMsgReady"
+	_serverMessageType_name_5 = "serverMsgCopyDoneserverMsgCopyData"
 	_serverMessageType_name_6 = "serverMsgNoData"
 	_serverMessageType_name_7 = "serverMsgParameterDescription"
 )
@@ -18,10 +18,10 @@ const (
 var (
 	_serverMessageType_index_0 = [...]uint8{0, 22, 43, 65}
 	_serverMessageType_index_1 = [...]uint8{0, 24, 40, 62}
-	_serverMessageType_index_2 = [...]uint8{0, 23}
-	_serverMessageType_index_3 = [...]uint8{0, 19}
-	_serverMessageType_index_4 = [...]uint8{0, 13, 37, 60}
-	_serverMessageType_index_5 = [...]uint8{0, 14}
+	_serverMessageType_index_2 = [...]uint8{0, 23, 47, 66}
+	_serverMessageType_index_3 = [...]uint8{0, 13, 37, 60}
+	_serverMessageType_index_4 = [...]uint8{0, 14}
+	_serverMessageType_index_5 = [...]uint8{0, 17, 34}
 	_serverMessageType_index_6 = [...]uint8{0, 15}
 	_serverMessageType_index_7 = [...]uint8{0, 29}
 )
@@ -34,15 +34,17 @@ func (i serverMessageType) String() string {
 	case 67 <= i && i <= 69:
 		i -= 67
 		return _serverMessageType_name_1[_serverMessageType_index_1[i]:_serverMessageType_index_1[i+1]]
-	case i == 71:
-		return _serverMessageType_name_2
-	case i == 73:
-		return _serverMessageType_name_3
+	case 71 <= i && i <= 73:
+		i -= 71
+		return _serverMessageType_name_2[_serverMessageType_index_2[i]:_serverMessageType_index_2[i+1]]
 	case 82 <= i && i <= 84:
 		i -= 82
-		return _serverMessageType_name_4[_serverMessageType_index_4[i]:_serverMessageType_index_4[i+1]]
+		return _serverMessageType_name_3[_serverMessageType_index_3[i]:_serverMessageType_index_3[i+1]]
 	case i == 90:
-		return _serverMessageType_name_5
+		return _serverMessageType_name_4
+	case 99 <= i && i <= 100:
+		i -= 99
+		return _serverMessageType_name_5[_serverMessageType_index_5[i]:_serverMessageType_index_5[i+1]]
 	case i == 110:
 		return _serverMessageType_name_6
 	case i == 116:
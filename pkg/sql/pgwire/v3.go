@@ -19,6 +19,7 @@ package pgwire
 
 import (
 	"bufio"
+	"bytes"
 	"crypto/tls"
 	"fmt"
 	"net"
@@ -66,7 +67,10 @@ const (
 	serverMsgBindComplete         serverMessageType = '2'
 	serverMsgCommandComplete      serverMessageType = 'C'
 	serverMsgCloseComplete        serverMessageType = '3'
+	serverMsgCopyData             serverMessageType = 'd'
+	serverMsgCopyDone             serverMessageType = 'c'
 	serverMsgCopyInResponse       serverMessageType = 'G'
+	serverMsgCopyOutResponse      serverMessageType = 'H'
 	serverMsgDataRow              serverMessageType = 'D'
 	serverMsgEmptyQuery           serverMessageType = 'I'
 	serverMsgErrorResponse        serverMessageType = 'E'
@@ -170,6 +174,7 @@ type v3Conn struct {
 	executor    *sql.Executor
 	readBuf     readBuffer
 	writeBuf    writeBuffer
+	copyBuf     writeBuffer // scratch space to encode COPY TO fields
 	tagBuf      [64]byte
 	sessionArgs sql.SessionArgs
 	session     *sql.Session
@@ -1005,6 +1010,18 @@ func (c *v3Conn) sendResponse(
 				return err
 			}
 
+		case parser.CopyOut:
+			if err := c.copyOut(ctx, &result); err != nil {
+				return err
+			}
+
+			// Send CommandComplete.
+			tag = append(tag, ' ')
+			tag = strconv.AppendUint(tag, uint64(result.Rows.Len()), 10)
+			if err := c.sendCommandComplete(tag); err != nil {
+				return err
+			}
+
 		case parser.CopyIn:
 			rows, err := c.copyIn(ctx, result.Columns)
 			if err != nil {
@@ -1125,6 +1142,126 @@ func (c *v3Conn) copyIn(ctx context.Context, columns []sql.ResultColumn) (int64,
 	}
 }
 
+// copyOut sends the rows of a COPY TO result, formatted according to its
+// CopyOptions, in one CopyData message each.
+// See: https://www.postgresql.org/docs/current/static/protocol-flow.html#PROTOCOL-COPY
+func (c *v3Conn) copyOut(ctx context.Context, result *sql.Result) error {
+	c.writeBuf.initMsg(serverMsgCopyOutResponse)
+	c.writeBuf.writeByte(byte(formatText))
+	c.writeBuf.putInt16(int16(len(result.Columns)))
+	for range result.Columns {
+		c.writeBuf.putInt16(int16(formatText))
+	}
+	if err := c.writeBuf.finishMsg(c.wr); err != nil {
+		return err
+	}
+
+	opts := result.CopyOptions
+	var line bytes.Buffer
+	if opts.Header {
+		for i, col := range result.Columns {
+			if i > 0 {
+				line.WriteByte(opts.Delimiter)
+			}
+			appendCopyField(&line, opts, []byte(col.Name))
+		}
+		line.WriteByte('\n')
+		if err := c.sendCopyData(line.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	nRows := result.Rows.Len()
+	for rowIdx := 0; rowIdx < nRows; rowIdx++ {
+		line.Reset()
+		for i, d := range result.Rows.At(rowIdx) {
+			if i > 0 {
+				line.WriteByte(opts.Delimiter)
+			}
+			if d == parser.DNull {
+				line.WriteString(opts.Null)
+				continue
+			}
+			// Encode the datum as for a DataRow, then strip the length prefix.
+			c.copyBuf.reset()
+			c.copyBuf.writeTextDatum(d, c.session.Location)
+			if c.copyBuf.err != nil {
+				return c.copyBuf.err
+			}
+			appendCopyField(&line, opts, c.copyBuf.wrapped.Bytes()[4:])
+		}
+		line.WriteByte('\n')
+		if err := c.sendCopyData(line.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	c.writeBuf.initMsg(serverMsgCopyDone)
+	return c.writeBuf.finishMsg(c.wr)
+}
+
+func (c *v3Conn) sendCopyData(data []byte) error {
+	c.writeBuf.initMsg(serverMsgCopyData)
+	c.writeBuf.write(data)
+	return c.writeBuf.finishMsg(c.wr)
+}
+
+// appendCopyField appends a field of COPY data to buf. In the text format,
+// special characters are escaped with backslashes; in CSV, fields that
+// contain special characters or that could be mistaken for NULL are quoted.
+func appendCopyField(buf *bytes.Buffer, opts sql.CopyOptions, field []byte) {
+	if opts.Format == sql.CopyFormatCSV {
+		if !csvFieldNeedsQuotes(opts, field) {
+			buf.Write(field)
+			return
+		}
+		buf.WriteByte('"')
+		for _, ch := range field {
+			if ch == '"' {
+				buf.WriteByte('"')
+			}
+			buf.WriteByte(ch)
+		}
+		buf.WriteByte('"')
+		return
+	}
+	for _, ch := range field {
+		switch ch {
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '\v':
+			buf.WriteString(`\v`)
+		default:
+			if ch == opts.Delimiter {
+				buf.WriteByte('\\')
+			}
+			buf.WriteByte(ch)
+		}
+	}
+}
+
+func csvFieldNeedsQuotes(opts sql.CopyOptions, field []byte) bool {
+	if string(field) == opts.Null || string(field) == `\.` {
+		return true
+	}
+	for _, ch := range field {
+		if ch == opts.Delimiter || ch == '"' || ch == '\n' || ch == '\r' {
+			return true
+		}
+	}
+	return false
+}
+
 func newUnrecognizedMsgTypeErr(typ clientMessageType) error {
 	return pgerror.NewErrorf(
 		pgerror.CodeProtocolViolationError, "unrecognized client message type %v", typ)
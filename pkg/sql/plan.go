@@ -171,9 +171,11 @@ type planNodeFastPath interface {
 }
 
 var _ planNode = &alterTableNode{}
+var _ planNode = &controlJobNode{}
 var _ planNode = &copyNode{}
 var _ planNode = &createDatabaseNode{}
 var _ planNode = &createIndexNode{}
+var _ planNode = &createStatsNode{}
 var _ planNode = &createTableNode{}
 var _ planNode = &createViewNode{}
 var _ planNode = &delayedNode{}
@@ -293,14 +295,20 @@ func (p *planner) newPlan(
 		return p.AlterTable(ctx, n)
 	case *parser.BeginTransaction:
 		return p.BeginTransaction(n)
+	case *parser.CancelJob:
+		return p.CancelJob(ctx, n)
 	case CopyDataBlock:
 		return p.CopyData(ctx, n, autoCommit)
 	case *parser.CopyFrom:
 		return p.CopyFrom(ctx, n, autoCommit)
+	case *parser.CopyTo:
+		return p.CopyTo(ctx, n)
 	case *parser.CreateDatabase:
 		return p.CreateDatabase(n)
 	case *parser.CreateIndex:
 		return p.CreateIndex(ctx, n)
+	case *parser.CreateStats:
+		return p.CreateStats(ctx, n)
 	case *parser.CreateTable:
 		return p.CreateTable(ctx, n)
 	case *parser.CreateUser:
@@ -327,6 +335,8 @@ func (p *planner) newPlan(
 		return p.Insert(ctx, n, desiredTypes, autoCommit)
 	case *parser.ParenSelect:
 		return p.newPlan(ctx, n.Select, desiredTypes, autoCommit)
+	case *parser.PauseJob:
+		return p.PauseJob(ctx, n)
 	case *parser.Relocate:
 		return p.Relocate(ctx, n)
 	case *parser.RenameColumn:
@@ -337,6 +347,8 @@ func (p *planner) newPlan(
 		return p.RenameIndex(ctx, n)
 	case *parser.RenameTable:
 		return p.RenameTable(ctx, n)
+	case *parser.ResumeJob:
+		return p.ResumeJob(ctx, n)
 	case *parser.Revoke:
 		return p.Revoke(ctx, n)
 	case *parser.Scatter:
@@ -353,6 +365,8 @@ func (p *planner) newPlan(
 		return p.SetTransaction(n)
 	case *parser.SetDefaultIsolation:
 		return p.SetDefaultIsolation(n)
+	case *parser.SetZoneConfig:
+		return p.SetZoneConfig(ctx, n)
 	case *parser.Show:
 		return p.Show(n)
 	case *parser.ShowColumns:
@@ -369,16 +383,20 @@ func (p *planner) newPlan(
 		return p.ShowGrants(ctx, n)
 	case *parser.ShowIndex:
 		return p.ShowIndex(ctx, n)
+	case *parser.ShowJobs:
+		return p.ShowJobs(ctx, n)
 	case *parser.ShowTables:
 		return p.ShowTables(ctx, n)
 	case *parser.ShowUsers:
 		return p.ShowUsers(ctx, n)
+	case *parser.ShowZoneConfig:
+		return p.ShowZoneConfig(ctx, n)
 	case *parser.ShowRanges:
 		return p.ShowRanges(ctx, n)
 	case *parser.Split:
 		return p.Split(ctx, n)
 	case *parser.Truncate:
-		return p.Truncate(ctx, n)
+		return p.Truncate(ctx, n, autoCommit)
 	case *parser.UnionClause:
 		return p.UnionClause(ctx, n, desiredTypes, autoCommit)
 	case *parser.Update:
@@ -400,6 +418,8 @@ func (p *planner) prepare(ctx context.Context, stmt parser.Statement) (planNode,
 	}
 
 	switch n := stmt.(type) {
+	case *parser.CancelJob:
+		return p.CancelJob(ctx, n)
 	case *parser.Delete:
 		return p.Delete(ctx, n, nil, false)
 	case *parser.Explain:
@@ -408,6 +428,10 @@ func (p *planner) prepare(ctx context.Context, stmt parser.Statement) (planNode,
 		return p.Help(ctx, n)
 	case *parser.Insert:
 		return p.Insert(ctx, n, nil, false)
+	case *parser.PauseJob:
+		return p.PauseJob(ctx, n)
+	case *parser.ResumeJob:
+		return p.ResumeJob(ctx, n)
 	case *parser.Select:
 		return p.Select(ctx, n, nil, false)
 	case *parser.SelectClause:
@@ -426,12 +450,16 @@ func (p *planner) prepare(ctx context.Context, stmt parser.Statement) (planNode,
 		return p.ShowGrants(ctx, n)
 	case *parser.ShowIndex:
 		return p.ShowIndex(ctx, n)
+	case *parser.ShowJobs:
+		return p.ShowJobs(ctx, n)
 	case *parser.ShowConstraints:
 		return p.ShowConstraints(ctx, n)
 	case *parser.ShowTables:
 		return p.ShowTables(ctx, n)
 	case *parser.ShowUsers:
 		return p.ShowUsers(ctx, n)
+	case *parser.ShowZoneConfig:
+		return p.ShowZoneConfig(ctx, n)
 	case *parser.ShowRanges:
 		return p.ShowRanges(ctx, n)
 	case *parser.Split:
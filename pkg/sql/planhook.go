@@ -20,6 +20,7 @@ import (
 	"golang.org/x/net/context"
 
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/distsqlrun"
 	"github.com/cockroachdb/cockroach/pkg/sql/parser"
 )
 
@@ -47,6 +48,9 @@ type PlanHookState interface {
 	TypeAsString(e *parser.Expr) (func() string, error)
 	TypeAsStringArray(e *parser.Exprs) (func() []string, error)
 	User() string
+	ExportQuery(
+		ctx context.Context, query *parser.Select, spec distsqlrun.CSVWriterSpec,
+	) ([]parser.Datums, error)
 	AuthorizationAccessor
 }
 
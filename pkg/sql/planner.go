@@ -65,6 +65,11 @@ type planner struct {
 	// See executor_statement_metrics.go for details.
 	phaseTimes phaseTimes
 
+	// rowsRead and bytesRead count the rows and the bytes of keys and values
+	// read from the KV layer by the execution of the current statement.
+	// See executor_statement_metrics.go for details.
+	rowsRead, bytesRead int64
+
 	// Avoid allocations by embedding commonly used objects and visitors.
 	parser                parser.Parser
 	subqueryVisitor       subqueryVisitor
@@ -0,0 +1,510 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	opentracing "github.com/opentracing/opentracing-go"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/sql/stats"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+)
+
+// The estimates below are used in place of statistics that were not
+// collected (see CREATE STATISTICS).
+const (
+	// defaultTableRowCount is the estimated number of rows of a table.
+	defaultTableRowCount = 1000
+	// defaultDistinctFraction is the estimated ratio of distinct values to
+	// rows in a column that is not known to be unique.
+	defaultDistinctFraction = 0.1
+	// defaultNullSelectivity is the estimated fraction of NULL values in a
+	// nullable column.
+	defaultNullSelectivity = 0.1
+	// defaultRangeSelectivity is the estimated fraction of rows satisfying an
+	// inequality on a column.
+	defaultRangeSelectivity = 1.0 / 3
+	// defaultFilterSelectivity is the estimated fraction of rows satisfying
+	// any other filter.
+	defaultFilterSelectivity = 1.0 / 3
+)
+
+// tableEstimate holds what is known about the rows of a table for the
+// purpose of estimating the number of rows produced by plans.
+type tableEstimate struct {
+	evalCtx *parser.EvalContext
+	// desc is nil when the rows do not come from a table, in which case only
+	// default estimates are used.
+	desc *sqlbase.TableDescriptor
+	// rowCount is the number of rows of the table.
+	rowCount float64
+	// colStats maps the columns with statistics to their latest statistic.
+	colStats map[sqlbase.ColumnID]*stats.TableStatistic
+}
+
+// hasStats returns true if statistics were collected on the table.
+func (t *tableEstimate) hasStats() bool {
+	return len(t.colStats) > 0
+}
+
+// makeTableEstimate looks up the statistics of the given table.
+func (p *planner) makeTableEstimate(
+	ctx context.Context, desc *sqlbase.TableDescriptor,
+) tableEstimate {
+	t := tableEstimate{evalCtx: &p.evalCtx, desc: desc, rowCount: defaultTableRowCount}
+	tableStats := p.getTableStats(ctx, desc)
+	if len(tableStats) == 0 {
+		return t
+	}
+	// The statistics are ordered with the most recent first, which has the
+	// most accurate row count.
+	t.rowCount = float64(tableStats[0].RowCount)
+	t.colStats = make(map[sqlbase.ColumnID]*stats.TableStatistic, len(tableStats))
+	for _, s := range tableStats {
+		t.colStats[s.ColumnID] = s
+	}
+	return t
+}
+
+// getTableStats returns the statistics of the given table, or nil if there
+// are none or they cannot be used.
+func (p *planner) getTableStats(
+	ctx context.Context, desc *sqlbase.TableDescriptor,
+) []*stats.TableStatistic {
+	execCfg := p.ExecCfg()
+	if execCfg == nil || execCfg.TableStatsCache == nil {
+		// Internal planners do not use statistics.
+		return nil
+	}
+	if desc.IsVirtualTable() || desc.ParentID == keys.SystemDatabaseID {
+		// There are no statistics on virtual tables. The system tables are
+		// skipped so that looking up statistics, which queries
+		// system.table_statistics, does not recurse.
+		return nil
+	}
+	// The statistics are read in their own transaction, which is kept out of
+	// the trace of the statement.
+	tableStats, err := execCfg.TableStatsCache.GetTableStats(
+		opentracing.ContextWithSpan(ctx, nil), desc.ID,
+	)
+	if err != nil {
+		log.Warningf(ctx, "unable to get statistics of table %d: %v", desc.ID, err)
+		return nil
+	}
+	return tableStats
+}
+
+// nullFraction returns the estimated fraction of NULL values in a column.
+func (t *tableEstimate) nullFraction(colID sqlbase.ColumnID) float64 {
+	if s, ok := t.colStats[colID]; ok {
+		if s.RowCount == 0 {
+			return 0
+		}
+		return float64(s.NullCount) / float64(s.RowCount)
+	}
+	if t.desc != nil {
+		if col, err := t.desc.FindColumnByID(colID); err == nil && !col.Nullable {
+			return 0
+		}
+	}
+	return defaultNullSelectivity
+}
+
+// distinctCount returns the estimated number of distinct values in a column.
+func (t *tableEstimate) distinctCount(colID sqlbase.ColumnID) float64 {
+	if s, ok := t.colStats[colID]; ok {
+		return clampRows(float64(s.DistinctCount))
+	}
+	if t.desc != nil && t.isUniqueColumn(colID) {
+		return clampRows(t.rowCount)
+	}
+	return clampRows(t.rowCount * defaultDistinctFraction)
+}
+
+// isUniqueColumn returns true if a unique index consists of only the given
+// column.
+func (t *tableEstimate) isUniqueColumn(colID sqlbase.ColumnID) bool {
+	if ids := t.desc.PrimaryIndex.ColumnIDs; len(ids) == 1 && ids[0] == colID {
+		return true
+	}
+	for i := range t.desc.Indexes {
+		idx := &t.desc.Indexes[i]
+		if idx.Unique && len(idx.ColumnIDs) == 1 && idx.ColumnIDs[0] == colID {
+			return true
+		}
+	}
+	return false
+}
+
+// colIDFn maps the index of an IndexedVar to the ID of the column it refers
+// to.
+type colIDFn func(idx int) sqlbase.ColumnID
+
+// selectivity returns the estimated fraction of the rows of the table
+// satisfying the given filter.
+func (t *tableEstimate) selectivity(expr parser.Expr, colID colIDFn) float64 {
+	switch e := expr.(type) {
+	case nil:
+		return 1
+	case *parser.DBool:
+		if *e {
+			return 1
+		}
+		return 0
+	case *parser.ParenExpr:
+		return t.selectivity(e.Expr, colID)
+	case *parser.AndExpr:
+		return t.selectivity(e.Left, colID) * t.selectivity(e.Right, colID)
+	case *parser.OrExpr:
+		l, r := t.selectivity(e.Left, colID), t.selectivity(e.Right, colID)
+		return l + r - l*r
+	case *parser.NotExpr:
+		return 1 - t.selectivity(e.Expr, colID)
+	case *parser.ComparisonExpr:
+		return t.comparisonSelectivity(e, colID)
+	}
+	if expr == parser.DNull {
+		return 0
+	}
+	return defaultFilterSelectivity
+}
+
+// comparisonSelectivity estimates the selectivity of a comparison. Only
+// comparisons between a column and a constant use the statistics of the
+// column.
+func (t *tableEstimate) comparisonSelectivity(
+	e *parser.ComparisonExpr, colID colIDFn,
+) float64 {
+	op := e.Operator
+	v, ok := e.Left.(*parser.IndexedVar)
+	d, dOk := e.Right.(parser.Datum)
+	if !ok || !dOk {
+		// Try the comparison the other way around.
+		v, ok = e.Right.(*parser.IndexedVar)
+		d, dOk = e.Left.(parser.Datum)
+		if !ok || !dOk {
+			return defaultFilterSelectivity
+		}
+		switch op {
+		case parser.LT:
+			op = parser.GT
+		case parser.LE:
+			op = parser.GE
+		case parser.GT:
+			op = parser.LT
+		case parser.GE:
+			op = parser.LE
+		}
+	}
+	id := sqlbase.ColumnID(0)
+	if t.desc != nil {
+		id = colID(v.Idx)
+	}
+
+	switch op {
+	case parser.EQ:
+		return t.eqSelectivity(id, d)
+	case parser.NE:
+		if d == parser.DNull {
+			return 0
+		}
+		return 1 - t.nullFraction(id) - t.eqSelectivity(id, d)
+	case parser.Is, parser.IsNotDistinctFrom:
+		if d == parser.DNull {
+			return t.nullFraction(id)
+		}
+		return t.eqSelectivity(id, d)
+	case parser.IsNot, parser.IsDistinctFrom:
+		if d == parser.DNull {
+			return 1 - t.nullFraction(id)
+		}
+		return 1 - t.eqSelectivity(id, d)
+	case parser.LT, parser.LE, parser.GT, parser.GE:
+		return t.rangeSelectivity(id, op, d)
+	case parser.In, parser.NotIn:
+		tuple, ok := d.(*parser.DTuple)
+		if !ok {
+			return defaultFilterSelectivity
+		}
+		sel := 0.0
+		for _, elem := range tuple.D {
+			sel += t.eqSelectivity(id, elem)
+		}
+		if sel > 1 {
+			sel = 1
+		}
+		if op == parser.NotIn {
+			return 1 - t.nullFraction(id) - sel
+		}
+		return sel
+	}
+	return defaultFilterSelectivity
+}
+
+// eqSelectivity returns the estimated fraction of rows in which the given
+// column is equal to the given value.
+func (t *tableEstimate) eqSelectivity(colID sqlbase.ColumnID, d parser.Datum) float64 {
+	if d == parser.DNull {
+		return 0
+	}
+	if s, ok := t.colStats[colID]; ok && s.Histogram != nil && s.RowCount > 0 {
+		if numEq, _, ok := t.histogramCounts(s.Histogram, d); ok && numEq > 0 {
+			return numEq / float64(s.RowCount)
+		}
+	}
+	return (1 - t.nullFraction(colID)) / t.distinctCount(colID)
+}
+
+// rangeSelectivity returns the estimated fraction of rows in which the given
+// column compares to the given value according to op, one of LT, LE, GT and
+// GE.
+func (t *tableEstimate) rangeSelectivity(
+	colID sqlbase.ColumnID, op parser.ComparisonOperator, d parser.Datum,
+) float64 {
+	if d == parser.DNull {
+		return 0
+	}
+	if s, ok := t.colStats[colID]; ok && s.Histogram != nil && s.RowCount > 0 {
+		if numEq, numLess, ok := t.histogramCounts(s.Histogram, d); ok {
+			numRows := float64(s.RowCount - s.NullCount)
+			var n float64
+			switch op {
+			case parser.LT:
+				n = numLess
+			case parser.LE:
+				n = numLess + numEq
+			case parser.GT:
+				n = numRows - numLess - numEq
+			case parser.GE:
+				n = numRows - numLess
+			}
+			if n < 0 {
+				n = 0
+			}
+			return n / float64(s.RowCount)
+		}
+	}
+	return defaultRangeSelectivity
+}
+
+// histogramCounts returns the estimated numbers of values of a histogram
+// that are equal to and less than the given value. The values are assumed to
+// be uniformly distributed within a bucket. It returns false if the value
+// cannot be compared with the histogram.
+func (t *tableEstimate) histogramCounts(
+	h *stats.HistogramData, d parser.Datum,
+) (numEq, numLess float64, ok bool) {
+	if len(h.Buckets) == 0 {
+		return 0, 0, false
+	}
+	var a sqlbase.DatumAlloc
+	for i := range h.Buckets {
+		b := &h.Buckets[i]
+		upper, err := h.DecodeUpperBound(&a, i)
+		if err != nil || !upper.ResolvedType().Equivalent(d.ResolvedType()) {
+			return 0, 0, false
+		}
+		switch c := d.Compare(t.evalCtx, upper); {
+		case c == 0:
+			return float64(b.NumEq), numLess + float64(b.NumRange), true
+		case c < 0:
+			// The value falls inside this bucket; assume it is in the middle of
+			// the range and as frequent as the average value of the range.
+			numLess += float64(b.NumRange) / 2
+			if b.NumRange > 0 {
+				numEq = float64(b.NumEq)
+			}
+			return numEq, numLess, true
+		}
+		numLess += float64(b.NumEq + b.NumRange)
+	}
+	return 0, numLess, true
+}
+
+// clampRows makes an estimated number of rows at least one, so that estimates
+// derived from it by multiplication remain meaningful.
+func clampRows(rows float64) float64 {
+	if rows < 1 {
+		return 1
+	}
+	return rows
+}
+
+// estimateRowCount returns the estimated number of rows produced by a plan,
+// or -1 if the plan does not produce rows that can be estimated.
+func (p *planner) estimateRowCount(ctx context.Context, plan planNode) float64 {
+	switch n := plan.(type) {
+	case *scanNode:
+		rows := n.rowCountEstimate
+		if rows == 0 {
+			t := p.makeTableEstimate(ctx, &n.desc)
+			rows = clampRows(t.rowCount * t.selectivity(n.filter, n.colID))
+		}
+		if n.hardLimit > 0 && float64(n.hardLimit) < rows {
+			rows = float64(n.hardLimit)
+		}
+		return rows
+
+	case *indexJoinNode:
+		t := p.makeTableEstimate(ctx, &n.table.desc)
+		return clampRows(p.sourceRowCount(ctx, n.index) * t.selectivity(n.table.filter, n.table.colID))
+
+	case *filterNode:
+		return clampRows(p.sourceRowCount(ctx, n.source.plan) * defaultEstimate().selectivity(n.filter, nil))
+
+	case *joinNode:
+		left := p.sourceRowCount(ctx, n.left.plan)
+		right := p.sourceRowCount(ctx, n.right.plan)
+		rows := left * right
+		for i := range n.pred.leftEqualityIndices {
+			rows *= p.joinEqualitySelectivity(
+				ctx, n.left.plan, n.pred.leftEqualityIndices[i], left,
+				n.right.plan, n.pred.rightEqualityIndices[i], right,
+			)
+		}
+		rows = clampRows(rows * defaultEstimate().selectivity(n.pred.onCond, nil))
+		switch n.joinType {
+		case joinTypeLeftOuter:
+			rows = maxRows(rows, left)
+		case joinTypeRightOuter:
+			rows = maxRows(rows, right)
+		case joinTypeFullOuter:
+			rows = maxRows(rows, left+right)
+		}
+		return rows
+
+	case *renderNode:
+		return p.sourceRowCount(ctx, n.source.plan)
+	case *sortNode:
+		return p.sourceRowCount(ctx, n.plan)
+	case *distinctNode:
+		return p.sourceRowCount(ctx, n.plan)
+	case *windowNode:
+		return p.sourceRowCount(ctx, n.plan)
+	case *ordinalityNode:
+		return p.sourceRowCount(ctx, n.source)
+
+	case *groupNode:
+		if n.numGroupBy == 0 {
+			return 1
+		}
+		return clampRows(p.sourceRowCount(ctx, n.plan) * defaultDistinctFraction)
+
+	case *limitNode:
+		rows := p.sourceRowCount(ctx, n.plan)
+		if n.evaluated && float64(n.count) < rows {
+			rows = float64(n.count)
+		}
+		return rows
+
+	case *unionNode:
+		return p.sourceRowCount(ctx, n.left) + p.sourceRowCount(ctx, n.right)
+
+	case *valuesNode:
+		if n.rows != nil && n.rows.Len() > len(n.tuples) {
+			return float64(n.rows.Len())
+		}
+		return float64(len(n.tuples))
+
+	case *emptyNode:
+		if n.results {
+			return 1
+		}
+		return 0
+	}
+	return -1
+}
+
+// sourceRowCount is like estimateRowCount, but uses the default estimate for
+// a table for plans whose number of rows cannot be estimated.
+func (p *planner) sourceRowCount(ctx context.Context, plan planNode) float64 {
+	if rows := p.estimateRowCount(ctx, plan); rows >= 0 {
+		return rows
+	}
+	return defaultTableRowCount
+}
+
+// estimateDistinctCount returns the estimated number of distinct values in a
+// column of the rows produced by a plan, given the estimated number of rows.
+func (p *planner) estimateDistinctCount(
+	ctx context.Context, plan planNode, colIdx int, rows float64,
+) float64 {
+	d := rows
+	switch n := plan.(type) {
+	case *scanNode:
+		t := p.makeTableEstimate(ctx, &n.desc)
+		d = t.distinctCount(n.colID(colIdx))
+	case *indexJoinNode:
+		t := p.makeTableEstimate(ctx, &n.table.desc)
+		d = t.distinctCount(n.table.colID(colIdx))
+	case *filterNode:
+		d = p.estimateDistinctCount(ctx, n.source.plan, colIdx, rows)
+	case *renderNode:
+		if iv, ok := n.render[colIdx].(*parser.IndexedVar); ok {
+			d = p.estimateDistinctCount(ctx, n.source.plan, iv.Idx, rows)
+		}
+	case *joinNode:
+		// The merged columns come first, followed by the left and right
+		// columns.
+		numMerged := n.pred.numMergedEqualityColumns
+		if colIdx < numMerged {
+			colIdx = numMerged + n.pred.leftEqualityIndices[colIdx]
+		}
+		colIdx -= numMerged
+		if numLeft := len(n.left.info.sourceColumns); colIdx < numLeft {
+			d = p.estimateDistinctCount(ctx, n.left.plan, colIdx, rows)
+		} else {
+			d = p.estimateDistinctCount(ctx, n.right.plan, colIdx-numLeft, rows)
+		}
+	}
+	if d > rows {
+		d = rows
+	}
+	return clampRows(d)
+}
+
+// joinEqualitySelectivity returns the estimated fraction of the pairs of
+// rows of two plans for which the given columns are equal. The values of the
+// column with fewer distinct values are assumed to be a subset of the values
+// of the other column.
+func (p *planner) joinEqualitySelectivity(
+	ctx context.Context,
+	left planNode,
+	leftColIdx int,
+	leftRows float64,
+	right planNode,
+	rightColIdx int,
+	rightRows float64,
+) float64 {
+	leftDistinct := p.estimateDistinctCount(ctx, left, leftColIdx, leftRows)
+	rightDistinct := p.estimateDistinctCount(ctx, right, rightColIdx, rightRows)
+	return 1 / maxRows(leftDistinct, rightDistinct)
+}
+
+// defaultEstimate returns a tableEstimate for rows that do not come directly
+// from a table.
+func defaultEstimate() *tableEstimate {
+	return &tableEstimate{rowCount: defaultTableRowCount}
+}
+
+func maxRows(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
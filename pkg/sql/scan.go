@@ -90,6 +90,11 @@ type scanNode struct {
 	// "hint". If hardLimit is set (non-zero), softLimit must be unset (zero).
 	softLimit int64
 
+	// rowCountEstimate, if non-zero, is the estimated number of rows produced
+	// by the scan, computed during index selection from the filter before
+	// the index constraints were removed from it.
+	rowCountEstimate float64
+
 	disableBatchLimits bool
 
 	scanVisibility scanVisibility
@@ -435,6 +440,12 @@ func (n *scanNode) Spans(ctx context.Context) (reads, writes roachpb.Spans, err
 // scanNode implements parser.IndexedVarContainer.
 var _ parser.IndexedVarContainer = &scanNode{}
 
+// colID returns the ID of the column at the given index of the scanned
+// columns.
+func (n *scanNode) colID(idx int) sqlbase.ColumnID {
+	return n.cols[idx].ID
+}
+
 func (n *scanNode) IndexedVarEval(idx int, ctx *parser.EvalContext) (parser.Datum, error) {
 	return n.row[idx].Eval(ctx)
 }
@@ -25,6 +25,7 @@ import (
 	"github.com/pkg/errors"
 	"golang.org/x/net/context"
 
+	"github.com/cockroachdb/cockroach/pkg/base"
 	"github.com/cockroachdb/cockroach/pkg/gossip"
 	"github.com/cockroachdb/cockroach/pkg/internal/client"
 	"github.com/cockroachdb/cockroach/pkg/keys"
@@ -92,6 +93,35 @@ func NewSchemaChangerForTesting(
 	}
 }
 
+// RunSchemaChange runs the schema change of the given mutation of a table to
+// completion, backfilling the indexes it adds. It is used by jobs which add
+// mutations outside of a session, such as IMPORT. Transient errors are
+// retried; a permanent error, such as a unique index violation, is returned
+// once the schema change has been reversed.
+func RunSchemaChange(
+	ctx context.Context, cfg *ExecutorConfig, tableID sqlbase.ID, mutationID sqlbase.MutationID,
+) error {
+	sc := SchemaChanger{
+		tableID:        tableID,
+		mutationID:     mutationID,
+		nodeID:         cfg.NodeID.Get(),
+		db:             *cfg.DB,
+		leaseMgr:       cfg.LeaseManager,
+		testingKnobs:   cfg.SchemaChangerTestingKnobs,
+		distSQLPlanner: cfg.DistLoader.distSQLPlanner,
+	}
+	for r := retry.StartWithCtx(ctx, base.DefaultRetryOptions()); r.Next(); {
+		err := sc.exec(ctx)
+		if err == nil || sqlbase.IsPermanentSchemaChangeError(err) {
+			return err
+		}
+		if err != errExistingSchemaChangeLease {
+			log.Warningf(ctx, "error executing schema change: %s", err)
+		}
+	}
+	return ctx.Err()
+}
+
 func (sc *SchemaChanger) createSchemaChangeLease() sqlbase.TableDescriptor_SchemaChangeLease {
 	return sqlbase.TableDescriptor_SchemaChangeLease{
 		NodeID: sc.nodeID, ExpirationTime: timeutil.Now().Add(SchemaChangeLeaseDuration).UnixNano()}
@@ -853,7 +883,7 @@ func (s *SchemaChangeManager) Start(stopper *stop.Stopper) {
 					if timeutil.Since(sc.execAfter) > 0 {
 						// TODO(andrei): create a proper ctx for executing schema changes.
 						if err := sc.exec(context.TODO()); err != nil {
-							if err != errExistingSchemaChangeLease {
+							if err != errExistingSchemaChangeLease && err != errNotHitGCTTL {
 								log.Warningf(context.TODO(), "Error executing schema change: %s", err)
 							}
 							if err == sqlbase.ErrDescriptorNotFound {
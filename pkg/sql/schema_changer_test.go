@@ -717,6 +717,9 @@ CREATE UNIQUE INDEX vidx ON t.test (v);
 	// Wait until the schema change backfill is partially complete.
 	<-notification
 
+	if err := addImmediateGCZoneConfig(sqlDB, tableDesc.ID); err != nil {
+		t.Fatal(err)
+	}
 	if _, err := sqlDB.Exec("DROP TABLE t.test"); err != nil {
 		t.Fatal(err)
 	}
@@ -32,6 +32,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/config"
 	"github.com/cockroachdb/cockroach/pkg/internal/client"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
 	"github.com/cockroachdb/cockroach/pkg/sql/mon"
 	"github.com/cockroachdb/cockroach/pkg/sql/parser"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
@@ -96,10 +97,20 @@ func distSQLExecModeFromString(val string) distSQLExecMode {
 	}
 }
 
-// defaultDistSQLMode controls the default DistSQL mode (see above). It can
-// still be overridden per-session using `SET DIST_SQL = ...`.
-var defaultDistSQLMode = distSQLExecModeFromString(
-	envutil.EnvOrDefaultString("COCKROACH_DISTSQL_MODE", "OFF"),
+// DistSQLClusterExecMode controls the default DistSQL mode (see above). It can
+// still be overridden per-session using `SET DIST_SQL = ...`. The default
+// value of the setting can be changed with the COCKROACH_DISTSQL_MODE
+// environment variable, for testing.
+var DistSQLClusterExecMode = settings.RegisterEnumSetting(
+	"sql.defaults.distsql",
+	"default distributed SQL execution mode",
+	strings.ToLower(envutil.EnvOrDefaultString("COCKROACH_DISTSQL_MODE", "off")),
+	map[int64]string{
+		int64(distSQLOff):    "off",
+		int64(distSQLAuto):   "auto",
+		int64(distSQLOn):     "on",
+		int64(distSQLAlways): "always",
+	},
 )
 
 // Session contains the state of a SQL client connection.
@@ -254,7 +265,7 @@ func NewSession(
 	ctx = e.AnnotateCtx(ctx)
 	s := &Session{
 		Database:         args.Database,
-		DistSQLMode:      defaultDistSQLMode,
+		DistSQLMode:      distSQLExecMode(DistSQLClusterExecMode.Get()),
 		SearchPath:       parser.SearchPath{"pg_catalog"},
 		Location:         time.UTC,
 		User:             args.User,
@@ -756,10 +767,12 @@ func (scc *schemaChangerCollection) execSchemaChanges(
 		sc.distSQLPlanner = e.distSQLPlanner
 		for r := retry.Start(base.DefaultRetryOptions()); r.Next(); {
 			if err := sc.exec(ctx); err != nil {
-				if err != errExistingSchemaChangeLease {
+				if err != errExistingSchemaChangeLease && err != errNotHitGCTTL {
 					log.Warningf(ctx, "Error executing schema change: %s", err)
 				}
-				if err == sqlbase.ErrDescriptorNotFound {
+				if err == sqlbase.ErrDescriptorNotFound || err == errNotHitGCTTL {
+					// The data of a dropped table is cleared later on by the
+					// SchemaChangeManager, once its GC TTL has elapsed.
 				} else if sqlbase.IsPermanentSchemaChangeError(err) {
 					// All constraint violations can be reported; we report it as the result
 					// corresponding to the statement that enqueued this changer.
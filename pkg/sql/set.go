@@ -28,6 +28,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/settings"
 	"github.com/cockroachdb/cockroach/pkg/sql/parser"
 	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
+	"github.com/cockroachdb/cockroach/pkg/util/humanizeutil"
 	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
 )
 
@@ -110,11 +111,13 @@ func (p *planner) setClusterSetting(
 			return nil, err
 		}
 	case 1:
-		// TODO(dt): validate and properly encode str according to type.
 		encoded, err := p.toSettingString(name, typ, v[0])
 		if err != nil {
 			return nil, err
 		}
+		if err := settings.Validate(name, encoded); err != nil {
+			return nil, err
+		}
 		upsertQ := "UPSERT INTO system.settings (name, value, lastUpdated, valueType) VALUES ($1, $2, NOW(), $3)"
 		if _, err := ie.ExecuteStatementInTransaction(
 			ctx, "update-setting", p.txn, upsertQ, name, encoded, string(typ),
@@ -160,7 +163,7 @@ func (p *planner) toSettingString(
 	case settings.IntValue:
 		return typeCheckAndParse(parser.TypeInt, func(d parser.Datum) (string, error) {
 			if i, ok := d.(*parser.DInt); ok {
-				return settings.EncodeInt(int(*i)), nil
+				return settings.EncodeInt(int64(*i)), nil
 			}
 			return "", errors.Errorf("cannot use %s %T value for int setting", d.ResolvedType(), d)
 		})
@@ -171,11 +174,66 @@ func (p *planner) toSettingString(
 			}
 			return "", errors.Errorf("cannot use %s %T value for float setting", d.ResolvedType(), d)
 		})
+	case settings.DurationValue:
+		return typeCheckAndParse(parser.TypeInterval, func(d parser.Datum) (string, error) {
+			if i, ok := d.(*parser.DInterval); ok {
+				nanos, _, _, err := i.Duration.Encode()
+				if err != nil {
+					return "", err
+				}
+				return settings.EncodeDuration(time.Duration(nanos)), nil
+			}
+			return "", errors.Errorf("cannot use %s %T value for duration setting", d.ResolvedType(), d)
+		})
+	case settings.ByteSizeValue:
+		// Byte sizes can be given as a string with a unit, or as a number of bytes.
+		d, err := p.evalSettingValue(raw)
+		if err != nil {
+			return "", err
+		}
+		switch t := d.(type) {
+		case *parser.DString:
+			size, err := humanizeutil.ParseBytes(string(*t))
+			if err != nil {
+				return "", errors.Wrapf(err, "invalid byte size for setting %s", name)
+			}
+			return settings.EncodeInt(size), nil
+		case *parser.DInt:
+			return settings.EncodeInt(int64(*t)), nil
+		}
+		return "", errors.Errorf("cannot use %s %T value for byte size setting", d.ResolvedType(), d)
+	case settings.EnumValue:
+		// Enums can be given by name, or by value.
+		d, err := p.evalSettingValue(raw)
+		if err != nil {
+			return "", err
+		}
+		switch t := d.(type) {
+		case *parser.DString:
+			i, ok := settings.ParseEnum(name, string(*t))
+			if !ok {
+				return "", errors.Errorf("invalid value %s for enum setting %s", t, name)
+			}
+			return settings.EncodeInt(i), nil
+		case *parser.DInt:
+			return settings.EncodeInt(int64(*t)), nil
+		}
+		return "", errors.Errorf("cannot use %s %T value for enum setting", d.ResolvedType(), d)
 	default:
 		return "", errors.Errorf("unsupported setting type %c", typ)
 	}
 }
 
+// evalSettingValue evaluates the value of a setting which can be given either
+// as a string or as an int.
+func (p *planner) evalSettingValue(raw parser.Expr) (parser.Datum, error) {
+	typed, err := parser.TypeCheck(raw, nil, parser.TypeString)
+	if err != nil {
+		return nil, err
+	}
+	return typed.Eval(&p.evalCtx)
+}
+
 func (p *planner) getStringVal(name string, values []parser.TypedExpr) (string, error) {
 	if len(values) != 1 {
 		return "", fmt.Errorf("set %s: requires a single string value", name)
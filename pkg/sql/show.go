@@ -139,6 +139,9 @@ func queryInfoSchema(
 }
 
 func (p *planner) showClusterSetting(name string) (planNode, error) {
+	if name == "all" {
+		return p.showAllClusterSettings()
+	}
 	_, ok := settings.TypeOf(name)
 	if !ok {
 		return nil, errors.Errorf("unknown setting: %q", name)
@@ -161,6 +164,41 @@ func (p *planner) showClusterSetting(name string) (planNode, error) {
 	}, nil
 }
 
+// showAllClusterSettings lists all the cluster settings, with their current
+// values, types and descriptions.
+func (p *planner) showAllClusterSettings() (planNode, error) {
+	columns := ResultColumns{
+		{Name: "name", Typ: parser.TypeString},
+		{Name: "current_value", Typ: parser.TypeString},
+		{Name: "type", Typ: parser.TypeString},
+		{Name: "description", Typ: parser.TypeString},
+	}
+
+	return &delayedNode{
+		name:    "SHOW ALL CLUSTER SETTINGS",
+		columns: columns,
+		constructor: func(ctx context.Context, p *planner) (planNode, error) {
+			v := p.newContainerValuesNode(columns, 0)
+
+			for _, k := range settings.Keys() {
+				value, _ := settings.Show(k)
+				typ, _ := settings.TypeOf(k)
+				desc, _ := settings.Description(k)
+				if _, err := v.rows.AddRow(ctx, parser.Datums{
+					parser.NewDString(k),
+					parser.NewDString(value),
+					parser.NewDString(typ.String()),
+					parser.NewDString(desc),
+				}); err != nil {
+					v.rows.Close(ctx)
+					return nil, err
+				}
+			}
+			return v, nil
+		},
+	}, nil
+}
+
 // Show a session-local variable name.
 func (p *planner) Show(n *parser.Show) (planNode, error) {
 	origName := n.Name
@@ -389,6 +427,10 @@ func (p *planner) showCreateTable(
 		if err != nil {
 			return "", err
 		}
+		partitioning, err := showCreatePartitioning(desc, &idx)
+		if err != nil {
+			return "", err
+		}
 		if fk := idx.ForeignKey; fk.IsSet() {
 			fkTable, err := p.session.leases.getTableLeaseByID(ctx, p.txn, fk.Table)
 			if err != nil {
@@ -405,12 +447,13 @@ func (p *planner) showCreateTable(
 				quoteNames(fkIdx.ColumnNames...),
 			)
 		} else {
-			fmt.Fprintf(&buf, ",\n\t%sINDEX %s (%s)%s%s",
+			fmt.Fprintf(&buf, ",\n\t%sINDEX %s (%s)%s%s%s",
 				isUnique[idx.Unique],
 				quoteNames(idx.Name),
 				makeIndexColNames(idx),
 				storing,
 				interleave,
+				partitioning,
 			)
 		}
 	}
@@ -443,6 +486,12 @@ func (p *planner) showCreateTable(
 	}
 	buf.WriteString(interleave)
 
+	partitioning, err := showCreatePartitioning(desc, &desc.PrimaryIndex)
+	if err != nil {
+		return "", err
+	}
+	buf.WriteString(partitioning)
+
 	return buf.String(), nil
 }
 
@@ -866,6 +915,20 @@ func (p *planner) ShowTables(ctx context.Context, n *parser.ShowTables) (planNod
 	}, nil
 }
 
+// ShowJobs returns all the jobs in the system.jobs table, most recently
+// created first.
+// Privileges: None.
+func (p *planner) ShowJobs(ctx context.Context, n *parser.ShowJobs) (planNode, error) {
+	stmt, err := parser.ParseOneTraditional(`
+		SELECT id, type, description, username, status, created, started, finished,
+		       modified, fraction_completed, error
+		FROM crdb_internal.jobs ORDER BY created DESC`)
+	if err != nil {
+		return nil, err
+	}
+	return p.newPlan(ctx, stmt, nil, true)
+}
+
 // ShowUsers returns all the users.
 // Privileges: SELECT on system.users.
 func (p *planner) ShowUsers(ctx context.Context, n *parser.ShowUsers) (planNode, error) {
@@ -19,6 +19,7 @@ package sqlbase
 import (
 	"bytes"
 	"fmt"
+	"unsafe"
 
 	"github.com/cockroachdb/cockroach/pkg/sql/parser"
 	"github.com/cockroachdb/cockroach/pkg/util/encoding"
@@ -125,6 +126,16 @@ func (ed *EncDatum) UnsetDatum() {
 	ed.encoding = 0
 }
 
+// Size returns a lower bound on the total size of the receiver in bytes,
+// including the memory referenced by its encoding and its decoded datum.
+func (ed *EncDatum) Size() uintptr {
+	size := unsafe.Sizeof(*ed) + uintptr(len(ed.encoded))
+	if ed.Datum != nil {
+		size += ed.Datum.Size()
+	}
+	return size
+}
+
 // IsUnset returns true if SetEncoded or SetDatum were not called.
 func (ed *EncDatum) IsUnset() bool {
 	return ed.encoded == nil && ed.Datum == nil
@@ -264,6 +275,16 @@ func (r EncDatumRow) String() string {
 	return b.String()
 }
 
+// Size returns a lower bound on the total size of the row in bytes, including
+// the memory referenced by its EncDatums.
+func (r EncDatumRow) Size() uintptr {
+	size := unsafe.Sizeof(r)
+	for i := range r {
+		size += r[i].Size()
+	}
+	return size
+}
+
 // EncDatumRowToDatums converts a given EncDatumRow to a Datums.
 func EncDatumRowToDatums(datums parser.Datums, row EncDatumRow, da *DatumAlloc) error {
 	if len(row) != len(datums) {
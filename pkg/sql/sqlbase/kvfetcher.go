@@ -75,15 +75,47 @@ func PrettySpans(spans []roachpb.Span, skip int) string {
 // TODO(radu): parameters like this should be configurable
 var kvBatchSize int64 = 10000
 
-// SetKVBatchSize changes the kvFetcher batch size, and returns a function that restores it.
+// SetKVBatchSize changes the txnKVFetcher batch size, and returns a function that restores it.
 func SetKVBatchSize(val int64) func() {
 	oldVal := kvBatchSize
 	kvBatchSize = val
 	return func() { kvBatchSize = oldVal }
 }
 
-// kvFetcher handles retrieval of key/values.
-type kvFetcher struct {
+// kvFetcher is an interface used to abstract the retrieval of key/values for a
+// RowFetcher.
+type kvFetcher interface {
+	// nextKV returns the next key/value. When there are no more keys, it
+	// returns false and an empty key/value.
+	nextKV(ctx context.Context) (bool, client.KeyValue, error)
+	// getRangesInfo returns information about the ranges the key/values came
+	// from, or an error if the kvFetcher doesn't keep track of them.
+	getRangesInfo() ([]roachpb.RangeInfo, error)
+}
+
+// spanKVFetcher is a kvFetcher that returns a set slice of key/values, for
+// example ones obtained from a source other than a transaction.
+type spanKVFetcher struct {
+	kvs []roachpb.KeyValue
+}
+
+var _ kvFetcher = &spanKVFetcher{}
+
+func (f *spanKVFetcher) nextKV(ctx context.Context) (bool, client.KeyValue, error) {
+	if len(f.kvs) == 0 {
+		return false, client.KeyValue{}, nil
+	}
+	kv := f.kvs[0]
+	f.kvs = f.kvs[1:]
+	return true, client.KeyValue{Key: kv.Key, Value: &kv.Value}, nil
+}
+
+func (f *spanKVFetcher) getRangesInfo() ([]roachpb.RangeInfo, error) {
+	return nil, errors.New("range info is not available for key/values not read from ranges")
+}
+
+// txnKVFetcher handles retrieval of key/values from a transaction.
+type txnKVFetcher struct {
 	// "Constant" fields, provided by the caller.
 	txn             *client.Txn
 	spans           roachpb.Spans
@@ -100,27 +132,27 @@ type kvFetcher struct {
 	kvIndex      int
 	totalFetched int64
 
-	// returnRangeInfo, is set, causes the kvFetcher to populate rangeInfos.
+	// returnRangeInfo, is set, causes the txnKVFetcher to populate rangeInfos.
 	// See also rowFetcher.returnRangeInfo.
 	returnRangeInfo bool
 
-	// As the kvFetcher fetches batches of kvs, it accumulates information on the
+	// As the txnKVFetcher fetches batches of kvs, it accumulates information on the
 	// replicas where the batches came from. This info can be retrieved through
 	// getRangeInfo(), to be used for updating caches.
 	// rangeInfos are deduped, so they're not ordered in any particular way and
-	// they don't map to kvFetcher.spans in any particular way.
+	// they don't map to txnKVFetcher.spans in any particular way.
 	rangeInfos []roachpb.RangeInfo
 }
 
-func (f *kvFetcher) getRangesInfo() []roachpb.RangeInfo {
+func (f *txnKVFetcher) getRangesInfo() ([]roachpb.RangeInfo, error) {
 	if !f.returnRangeInfo {
-		panic("GetRangeInfo() called on kvFetcher that wasn't configured with returnRangeInfo")
+		return nil, errors.New("range info requested from a txnKVFetcher that wasn't configured with returnRangeInfo")
 	}
-	return f.rangeInfos
+	return f.rangeInfos, nil
 }
 
 // getBatchSize returns the max size of the next batch.
-func (f *kvFetcher) getBatchSize() int64 {
+func (f *txnKVFetcher) getBatchSize() int64 {
 	if !f.useBatchLimit {
 		return 0
 	}
@@ -161,7 +193,7 @@ func (f *kvFetcher) getBatchSize() int64 {
 	}
 }
 
-// makeKVFetcher initializes a kvFetcher for the given spans.
+// makeKVFetcher initializes a txnKVFetcher for the given spans.
 //
 // If useBatchLimit is true, batches are limited to kvBatchSize. If
 // firstBatchLimit is also set, the first batch is limited to that value.
@@ -175,9 +207,9 @@ func makeKVFetcher(
 	useBatchLimit bool,
 	firstBatchLimit int64,
 	returnRangeInfo bool,
-) (kvFetcher, error) {
+) (txnKVFetcher, error) {
 	if firstBatchLimit < 0 || (!useBatchLimit && firstBatchLimit != 0) {
-		return kvFetcher{}, errors.Errorf("invalid batch limit %d (useBatchLimit: %t)",
+		return txnKVFetcher{}, errors.Errorf("invalid batch limit %d (useBatchLimit: %t)",
 			firstBatchLimit, useBatchLimit)
 	}
 
@@ -185,7 +217,7 @@ func makeKVFetcher(
 		// Verify the spans are ordered if a batch limit is used.
 		for i := 1; i < len(spans); i++ {
 			if spans[i].Key.Compare(spans[i-1].EndKey) < 0 {
-				return kvFetcher{}, errors.Errorf("unordered spans (%s %s)", spans[i-1], spans[i])
+				return txnKVFetcher{}, errors.Errorf("unordered spans (%s %s)", spans[i-1], spans[i])
 			}
 		}
 	}
@@ -201,7 +233,7 @@ func makeKVFetcher(
 		}
 	}
 
-	return kvFetcher{
+	return txnKVFetcher{
 		txn:             txn,
 		spans:           copySpans,
 		reverse:         reverse,
@@ -212,7 +244,7 @@ func makeKVFetcher(
 }
 
 // fetch retrieves spans from the kv
-func (f *kvFetcher) fetch(ctx context.Context) error {
+func (f *txnKVFetcher) fetch(ctx context.Context) error {
 	batchSize := f.getBatchSize()
 
 	b := &f.batch
@@ -284,7 +316,7 @@ func (f *kvFetcher) fetch(ctx context.Context) error {
 
 // nextKV returns the next key/value (initiating fetches as necessary). When there are no more keys,
 // returns false and an empty key/value.
-func (f *kvFetcher) nextKV(ctx context.Context) (bool, client.KeyValue, error) {
+func (f *txnKVFetcher) nextKV(ctx context.Context) (bool, client.KeyValue, error) {
 	if f.kvIndex == len(f.kvs) {
 		if f.fetchEnd {
 			return false, client.KeyValue{}, nil
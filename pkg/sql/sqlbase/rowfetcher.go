@@ -97,6 +97,10 @@ type RowFetcher struct {
 
 	// Buffered allocation of decoded datums.
 	alloc DatumAlloc
+
+	// rowsRead and bytesRead count the rows and the bytes of keys and values
+	// read over all the scans.
+	rowsRead, bytesRead int64
 }
 
 // Init sets up a RowFetcher for a given table and index. If we are using a
@@ -190,14 +194,27 @@ func (rf *RowFetcher) StartScan(
 		firstBatchLimit++
 	}
 
-	var err error
-	rf.kvFetcher, err = makeKVFetcher(txn, spans, rf.reverse, limitBatches, firstBatchLimit, rf.returnRangeInfo)
+	f, err := makeKVFetcher(txn, spans, rf.reverse, limitBatches, firstBatchLimit, rf.returnRangeInfo)
 	if err != nil {
 		return err
 	}
+	return rf.startScanFrom(ctx, &f)
+}
+
+// StartScanFromKVs initializes and starts a scan over the given key/values,
+// for example ones obtained from a source other than a transaction. The
+// key/values must be sorted. Can be used multiple times.
+func (rf *RowFetcher) StartScanFromKVs(ctx context.Context, kvs []roachpb.KeyValue) error {
+	return rf.startScanFrom(ctx, &spanKVFetcher{kvs: kvs})
+}
 
+// startScanFrom initializes and starts a scan from the given kvFetcher. Can be
+// used multiple times.
+func (rf *RowFetcher) startScanFrom(ctx context.Context, f kvFetcher) error {
+	rf.indexKey = nil
+	rf.kvFetcher = f
 	// Retrieve the first key.
-	_, err = rf.NextKey(ctx)
+	_, err := rf.NextKey(ctx)
 	return err
 }
 
@@ -216,6 +233,10 @@ func (rf *RowFetcher) NextKey(ctx context.Context) (rowDone bool, err error) {
 		if rf.kvEnd {
 			return true, nil
 		}
+		rf.bytesRead += int64(len(rf.kv.Key))
+		if rf.kv.Value != nil {
+			rf.bytesRead += int64(len(rf.kv.Value.RawBytes))
+		}
 
 		rf.keyRemainingBytes, ok, err = rf.ReadIndexKey(rf.kv.Key)
 		if err != nil {
@@ -540,6 +561,7 @@ func (rf *RowFetcher) NextRow(ctx context.Context) (EncDatumRow, error) {
 		}
 		if rowDone {
 			rf.finalizeRow()
+			rf.rowsRead++
 			return rf.row, nil
 		}
 	}
@@ -582,6 +604,7 @@ func (rf *RowFetcher) NextKeyDebug(
 	}
 	if rowDone {
 		rf.finalizeRow()
+		rf.rowsRead++
 		row = rf.row
 	}
 	return prettyKey, prettyValue, row, nil
@@ -609,7 +632,15 @@ func (rf *RowFetcher) Key() roachpb.Key {
 }
 
 // GetRangeInfo returns information about the ranges where the rows came from.
-// The RangeInfo's are deduped and not ordered.
-func (rf *RowFetcher) GetRangeInfo() []roachpb.RangeInfo {
+// The RangeInfo's are deduped and not ordered. An error is returned if the
+// RowFetcher wasn't initialized with returnRangeInfo or the rows didn't come
+// from a transaction.
+func (rf *RowFetcher) GetRangeInfo() ([]roachpb.RangeInfo, error) {
 	return rf.kvFetcher.getRangesInfo()
 }
+
+// ReadStats returns the number of rows and the number of bytes of keys and
+// values read by the RowFetcher over all its scans.
+func (rf *RowFetcher) ReadStats() (rowsRead, bytesRead int64) {
+	return rf.rowsRead, rf.bytesRead
+}
@@ -123,6 +123,64 @@ CREATE TABLE system.jobs (
 	INDEX (status, created),
 	FAMILY (id, status, created, payload)
 );`
+
+	// replication_reports holds the replication and constraint conformance
+	// reports, which are regenerated periodically by the store holding the
+	// lease on the first range. Each row counts the ranges of a zone that have
+	// a replica in a locality; the empty locality stands for all the ranges of
+	// the zone. Subzones are identified by their index ID and partition name,
+	// which are 0 and empty for the zone of the object itself.
+	ReplicationReportsTableSchema = `
+CREATE TABLE system.replication_reports (
+	zone_id                 INT       NOT NULL,
+	index_id                INT       NOT NULL,
+	partition_name          STRING    NOT NULL,
+	locality                STRING    NOT NULL,
+	total_ranges            INT       NOT NULL,
+	unavailable_ranges      INT       NOT NULL,
+	under_replicated_ranges INT       NOT NULL,
+	violating_ranges        INT       NOT NULL,
+	single_locality_ranges  INT       NOT NULL,
+	at_risk_ranges          INT       NOT NULL,
+	generated               TIMESTAMP NOT NULL,
+	PRIMARY KEY (zone_id, index_id, partition_name, locality),
+	FAMILY "primary" (zone_id, index_id, partition_name, locality, total_ranges,
+		unavailable_ranges, under_replicated_ranges, violating_ranges, single_locality_ranges,
+		at_risk_ranges, generated)
+);`
+
+	// statement_statistics holds the per-statement statistics that each node
+	// persists when it clears them from memory, once per period of
+	// sql.metrics.statement_details.reset_interval. The statistics are
+	// encoded StatementStatistics protos.
+	StatementStatsTableSchema = `
+CREATE TABLE system.statement_statistics (
+	aggregated_ts    TIMESTAMP NOT NULL,
+	application_name STRING    NOT NULL,
+	key              STRING    NOT NULL,
+	node_id          INT       NOT NULL,
+	statistics       BYTES     NOT NULL,
+	PRIMARY KEY (aggregated_ts, application_name, key, node_id),
+	FAMILY "primary" (aggregated_ts, application_name, key, node_id, statistics)
+);`
+
+	// table_statistics holds the column statistics collected by CREATE
+	// STATISTICS. The histogram is an encoded stats.HistogramData proto.
+	TableStatisticsTableSchema = `
+CREATE TABLE system.table_statistics (
+	table_id       INT       NOT NULL,
+	column_id      INT       NOT NULL,
+	statistic_id   INT       NOT NULL DEFAULT unique_rowid(),
+	name           STRING,
+	created        TIMESTAMP NOT NULL DEFAULT now(),
+	row_count      INT       NOT NULL,
+	distinct_count INT       NOT NULL,
+	null_count     INT       NOT NULL,
+	histogram      BYTES,
+	PRIMARY KEY (table_id, column_id, statistic_id),
+	FAMILY "primary" (table_id, column_id, statistic_id, name, created, row_count, distinct_count,
+		null_count, histogram)
+);`
 )
 
 func pk(name string) IndexDescriptor {
@@ -174,7 +232,10 @@ var SystemAllowedPrivileges = map[ID]privilege.Lists{
 	// users will be able to modify system tables' schemas at will. CREATE and
 	// DROP privileges are allowed on the above system tables for backwards
 	// compatibility reasons only!
-	keys.JobsTableID: {privilege.ReadWriteData},
+	keys.JobsTableID:               {privilege.ReadWriteData},
+	keys.ReplicationReportsTableID: {privilege.ReadWriteData},
+	keys.StatementStatsTableID:     {privilege.ReadWriteData},
+	keys.TableStatisticsTableID:    {privilege.ReadWriteData},
 }
 
 // SystemDesiredPrivileges returns the desired privilege list (i.e., the
@@ -522,6 +583,136 @@ var (
 		FormatVersion:  InterleavedFormatVersion,
 		NextMutationID: 1,
 	}
+
+	// ReplicationReportsTable is the descriptor for the replication reports
+	// table.
+	ReplicationReportsTable = TableDescriptor{
+		Name:     "replication_reports",
+		ID:       keys.ReplicationReportsTableID,
+		ParentID: 1,
+		Version:  1,
+		Columns: []ColumnDescriptor{
+			{Name: "zone_id", ID: 1, Type: colTypeInt},
+			{Name: "index_id", ID: 2, Type: colTypeInt},
+			{Name: "partition_name", ID: 3, Type: colTypeString},
+			{Name: "locality", ID: 4, Type: colTypeString},
+			{Name: "total_ranges", ID: 5, Type: colTypeInt},
+			{Name: "unavailable_ranges", ID: 6, Type: colTypeInt},
+			{Name: "under_replicated_ranges", ID: 7, Type: colTypeInt},
+			{Name: "violating_ranges", ID: 8, Type: colTypeInt},
+			{Name: "single_locality_ranges", ID: 9, Type: colTypeInt},
+			{Name: "at_risk_ranges", ID: 10, Type: colTypeInt},
+			{Name: "generated", ID: 11, Type: colTypeTimestamp},
+		},
+		NextColumnID: 12,
+		Families: []ColumnFamilyDescriptor{
+			{
+				Name: "primary",
+				ID:   0,
+				ColumnNames: []string{
+					"zone_id", "index_id", "partition_name", "locality", "total_ranges",
+					"unavailable_ranges", "under_replicated_ranges", "violating_ranges",
+					"single_locality_ranges", "at_risk_ranges", "generated",
+				},
+				ColumnIDs: []ColumnID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11},
+			},
+		},
+		NextFamilyID: 1,
+		PrimaryIndex: IndexDescriptor{
+			Name:             "primary",
+			ID:               1,
+			Unique:           true,
+			ColumnNames:      []string{"zone_id", "index_id", "partition_name", "locality"},
+			ColumnDirections: []IndexDescriptor_Direction{IndexDescriptor_ASC, IndexDescriptor_ASC, IndexDescriptor_ASC, IndexDescriptor_ASC},
+			ColumnIDs:        []ColumnID{1, 2, 3, 4},
+		},
+		NextIndexID:    2,
+		Privileges:     NewPrivilegeDescriptor(security.RootUser, SystemDesiredPrivileges(keys.ReplicationReportsTableID)),
+		FormatVersion:  InterleavedFormatVersion,
+		NextMutationID: 1,
+	}
+
+	// StatementStatsTable is the descriptor for the statement statistics
+	// table.
+	StatementStatsTable = TableDescriptor{
+		Name:     "statement_statistics",
+		ID:       keys.StatementStatsTableID,
+		ParentID: 1,
+		Version:  1,
+		Columns: []ColumnDescriptor{
+			{Name: "aggregated_ts", ID: 1, Type: colTypeTimestamp},
+			{Name: "application_name", ID: 2, Type: colTypeString},
+			{Name: "key", ID: 3, Type: colTypeString},
+			{Name: "node_id", ID: 4, Type: colTypeInt},
+			{Name: "statistics", ID: 5, Type: colTypeBytes},
+		},
+		NextColumnID: 6,
+		Families: []ColumnFamilyDescriptor{
+			{
+				Name:        "primary",
+				ID:          0,
+				ColumnNames: []string{"aggregated_ts", "application_name", "key", "node_id", "statistics"},
+				ColumnIDs:   []ColumnID{1, 2, 3, 4, 5},
+			},
+		},
+		NextFamilyID: 1,
+		PrimaryIndex: IndexDescriptor{
+			Name:             "primary",
+			ID:               1,
+			Unique:           true,
+			ColumnNames:      []string{"aggregated_ts", "application_name", "key", "node_id"},
+			ColumnDirections: []IndexDescriptor_Direction{IndexDescriptor_ASC, IndexDescriptor_ASC, IndexDescriptor_ASC, IndexDescriptor_ASC},
+			ColumnIDs:        []ColumnID{1, 2, 3, 4},
+		},
+		NextIndexID:    2,
+		Privileges:     NewPrivilegeDescriptor(security.RootUser, SystemDesiredPrivileges(keys.StatementStatsTableID)),
+		FormatVersion:  InterleavedFormatVersion,
+		NextMutationID: 1,
+	}
+
+	// TableStatisticsTable is the descriptor for the table statistics table.
+	TableStatisticsTable = TableDescriptor{
+		Name:     "table_statistics",
+		ID:       keys.TableStatisticsTableID,
+		ParentID: 1,
+		Version:  1,
+		Columns: []ColumnDescriptor{
+			{Name: "table_id", ID: 1, Type: colTypeInt},
+			{Name: "column_id", ID: 2, Type: colTypeInt},
+			{Name: "statistic_id", ID: 3, Type: colTypeInt, DefaultExpr: &uniqueRowIDString},
+			{Name: "name", ID: 4, Type: colTypeString, Nullable: true},
+			{Name: "created", ID: 5, Type: colTypeTimestamp, DefaultExpr: &nowString},
+			{Name: "row_count", ID: 6, Type: colTypeInt},
+			{Name: "distinct_count", ID: 7, Type: colTypeInt},
+			{Name: "null_count", ID: 8, Type: colTypeInt},
+			{Name: "histogram", ID: 9, Type: colTypeBytes, Nullable: true},
+		},
+		NextColumnID: 10,
+		Families: []ColumnFamilyDescriptor{
+			{
+				Name: "primary",
+				ID:   0,
+				ColumnNames: []string{
+					"table_id", "column_id", "statistic_id", "name", "created", "row_count",
+					"distinct_count", "null_count", "histogram",
+				},
+				ColumnIDs: []ColumnID{1, 2, 3, 4, 5, 6, 7, 8, 9},
+			},
+		},
+		NextFamilyID: 1,
+		PrimaryIndex: IndexDescriptor{
+			Name:             "primary",
+			ID:               1,
+			Unique:           true,
+			ColumnNames:      []string{"table_id", "column_id", "statistic_id"},
+			ColumnDirections: []IndexDescriptor_Direction{IndexDescriptor_ASC, IndexDescriptor_ASC, IndexDescriptor_ASC},
+			ColumnIDs:        []ColumnID{1, 2, 3},
+		},
+		NextIndexID:    2,
+		Privileges:     NewPrivilegeDescriptor(security.RootUser, SystemDesiredPrivileges(keys.TableStatisticsTableID)),
+		FormatVersion:  InterleavedFormatVersion,
+		NextMutationID: 1,
+	}
 )
 
 // Create the key/value pair for the default zone config entry.
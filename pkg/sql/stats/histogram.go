@@ -0,0 +1,102 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/util/encoding"
+)
+
+// EquiDepthHistogram builds a histogram of a sample of the non-NULL values of
+// a column, with at most maxBuckets buckets that each hold about the same
+// number of sampled values. The counts of the buckets are scaled from the
+// size of the sample to numRows, the number of non-NULL values in the column.
+//
+// The samples are sorted in place.
+func EquiDepthHistogram(
+	evalCtx *parser.EvalContext,
+	colType sqlbase.ColumnType,
+	samples parser.Datums,
+	numRows int64,
+	maxBuckets int,
+) (HistogramData, error) {
+	if maxBuckets < 1 {
+		return HistogramData{}, errors.Errorf("invalid maximum number of buckets %d", maxBuckets)
+	}
+	h := HistogramData{ColumnType: colType}
+	numSamples := len(samples)
+	if numSamples == 0 {
+		return h, nil
+	}
+	for _, d := range samples {
+		if d == parser.DNull {
+			return HistogramData{}, errors.New("NULL values cannot be part of a histogram")
+		}
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i].Compare(evalCtx, samples[j]) < 0 })
+	scale := float64(numRows) / float64(numSamples)
+
+	numBuckets := maxBuckets
+	if numBuckets > numSamples {
+		numBuckets = numSamples
+	}
+	for i, b := 0, 0; i < numSamples; b++ {
+		// Spread the remaining samples evenly among the remaining buckets. The
+		// last bucket takes all the samples that are left, which can happen
+		// when buckets are extended over duplicate values below.
+		num := numSamples - i
+		if b < numBuckets-1 {
+			num /= numBuckets - b
+			if num < 1 {
+				num = 1
+			}
+		}
+		upper := samples[i+num-1]
+		// All the samples equal to the upper bound go in this bucket.
+		end := i + num
+		for end < numSamples && samples[end].Compare(evalCtx, upper) == 0 {
+			end++
+		}
+		numEq := 1
+		for j := end - 2; j >= i && samples[j].Compare(evalCtx, upper) == 0; j-- {
+			numEq++
+		}
+		encoded, err := sqlbase.EncodeTableValue(nil, sqlbase.ColumnID(encoding.NoColumnID), upper)
+		if err != nil {
+			return HistogramData{}, err
+		}
+		h.Buckets = append(h.Buckets, HistogramData_Bucket{
+			NumEq:      int64(float64(numEq)*scale + 0.5),
+			NumRange:   int64(float64(end-i-numEq)*scale + 0.5),
+			UpperBound: encoded,
+		})
+		i = end
+	}
+	return h, nil
+}
+
+// DecodeUpperBound returns the upper bound of the given bucket of the
+// histogram.
+func (h *HistogramData) DecodeUpperBound(
+	a *sqlbase.DatumAlloc, bucket int,
+) (parser.Datum, error) {
+	d, _, err := sqlbase.DecodeTableValue(a, h.ColumnType.ToDatumType(), h.Buckets[bucket].UpperBound)
+	return d, err
+}
@@ -0,0 +1,112 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func TestEquiDepthHistogram(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	type expBucket struct {
+		upper    int
+		numEq    int64
+		numRange int64
+	}
+	testCases := []struct {
+		samples    []int
+		numRows    int64
+		maxBuckets int
+		buckets    []expBucket
+	}{
+		{
+			samples:    []int{},
+			numRows:    0,
+			maxBuckets: 5,
+			buckets:    nil,
+		},
+		{
+			samples:    []int{1, 2, 4, 5, 5, 9},
+			numRows:    6,
+			maxBuckets: 2,
+			buckets:    []expBucket{{4, 1, 2}, {9, 1, 2}},
+		},
+		{
+			// Unsorted samples, and duplicates extending a bucket.
+			samples:    []int{5, 5, 5, 1, 2, 5, 9, 8},
+			numRows:    8,
+			maxBuckets: 4,
+			buckets:    []expBucket{{2, 1, 1}, {5, 4, 0}, {8, 1, 0}, {9, 1, 0}},
+		},
+		{
+			// More buckets than samples; the counts are scaled to numRows.
+			samples:    []int{10, 20, 30},
+			numRows:    300,
+			maxBuckets: 10,
+			buckets:    []expBucket{{10, 100, 0}, {20, 100, 0}, {30, 100, 0}},
+		},
+		{
+			// A single value.
+			samples:    []int{7, 7, 7, 7},
+			numRows:    40,
+			maxBuckets: 3,
+			buckets:    []expBucket{{7, 40, 0}},
+		},
+	}
+
+	evalCtx := &parser.EvalContext{}
+	colType := sqlbase.ColumnType{Kind: sqlbase.ColumnType_INT}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			samples := make(parser.Datums, len(tc.samples))
+			for i := range samples {
+				samples[i] = parser.NewDInt(parser.DInt(tc.samples[i]))
+			}
+			h, err := EquiDepthHistogram(evalCtx, colType, samples, tc.numRows, tc.maxBuckets)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var a sqlbase.DatumAlloc
+			var buckets []expBucket
+			for i, b := range h.Buckets {
+				upper, err := h.DecodeUpperBound(&a, i)
+				if err != nil {
+					t.Fatal(err)
+				}
+				buckets = append(buckets, expBucket{
+					upper:    int(*upper.(*parser.DInt)),
+					numEq:    b.NumEq,
+					numRange: b.NumRange,
+				})
+			}
+			if !reflect.DeepEqual(buckets, tc.buckets) {
+				t.Errorf("expected buckets %v, got %v", tc.buckets, buckets)
+			}
+		})
+	}
+
+	if _, err := EquiDepthHistogram(
+		evalCtx, colType, parser.Datums{parser.DNull}, 1, 1,
+	); err == nil {
+		t.Error("expected error for NULL sample")
+	}
+}
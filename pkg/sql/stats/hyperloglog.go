@@ -0,0 +1,132 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"hash/fnv"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// hllPrecision is the number of bits of the hashes used to pick a register.
+// With 2^14 registers, the standard error of the estimates is about 0.8%.
+const hllPrecision = 14
+
+const hllNumRegisters = 1 << hllPrecision
+
+// HyperLogLog is a sketch that estimates the number of distinct values added
+// to it, in constant space. Sketches built from disjoint parts of the same
+// data can be merged, which is how the distinct counts of a table are
+// computed from the sketches of its ranges.
+//
+// See "HyperLogLog: the analysis of a near-optimal cardinality estimation
+// algorithm" by Flajolet et al.
+type HyperLogLog struct {
+	// registers holds, for every register, the maximum rank (the position of
+	// the leftmost 1 bit after the register bits) of the hashes assigned to
+	// it.
+	registers [hllNumRegisters]uint8
+}
+
+// NewHyperLogLog creates an empty HyperLogLog sketch.
+func NewHyperLogLog() *HyperLogLog {
+	return &HyperLogLog{}
+}
+
+// InsertBytes adds a value, given by its encoding, to the sketch.
+func (h *HyperLogLog) InsertBytes(b []byte) {
+	f := fnv.New64a()
+	_, _ = f.Write(b)
+	h.insertHash(mix64(f.Sum64()))
+}
+
+func (h *HyperLogLog) insertHash(hash uint64) {
+	idx := hash >> (64 - hllPrecision)
+	// The remaining bits, with a sentinel bit in case they are all zero.
+	w := hash<<hllPrecision | 1<<(hllPrecision-1)
+	rank := uint8(1)
+	for w&(1<<63) == 0 {
+		rank++
+		w <<= 1
+	}
+	if rank > h.registers[idx] {
+		h.registers[idx] = rank
+	}
+}
+
+// Merge adds the values of other to the sketch.
+func (h *HyperLogLog) Merge(other *HyperLogLog) {
+	for i, r := range other.registers {
+		if r > h.registers[i] {
+			h.registers[i] = r
+		}
+	}
+}
+
+// Estimate returns the estimated number of distinct values in the sketch.
+func (h *HyperLogLog) Estimate() uint64 {
+	const m = float64(hllNumRegisters)
+	alpha := 0.7213 / (1 + 1.079/m)
+
+	var sum float64
+	var zeros int
+	for _, r := range h.registers {
+		sum += 1 / float64(uint64(1)<<r)
+		if r == 0 {
+			zeros++
+		}
+	}
+	estimate := alpha * m * m / sum
+	// Use linear counting for small cardinalities, for which the raw estimate
+	// is biased.
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return uint64(estimate + 0.5)
+}
+
+// MarshalBinary encodes the sketch. The first byte is the precision, which
+// allows changing it in the future.
+func (h *HyperLogLog) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 1+hllNumRegisters)
+	b[0] = hllPrecision
+	copy(b[1:], h.registers[:])
+	return b, nil
+}
+
+// UnmarshalBinary decodes a sketch encoded by MarshalBinary.
+func (h *HyperLogLog) UnmarshalBinary(b []byte) error {
+	if len(b) == 0 || b[0] != hllPrecision {
+		return errors.New("invalid HyperLogLog sketch encoding: unknown precision")
+	}
+	if len(b) != 1+hllNumRegisters {
+		return errors.Errorf("invalid HyperLogLog sketch encoding: %d bytes", len(b))
+	}
+	copy(h.registers[:], b[1:])
+	return nil
+}
+
+// mix64 is the finalizer of MurmurHash3, which makes all the bits of the
+// hash depend on all the bits of the input. FNV hashes of similar values,
+// such as encoded integers, differ in few bits otherwise.
+func mix64(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
@@ -0,0 +1,93 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func insertInts(h *HyperLogLog, from, to int) {
+	var buf [8]byte
+	for i := from; i < to; i++ {
+		binary.BigEndian.PutUint64(buf[:], uint64(i))
+		h.InsertBytes(buf[:])
+	}
+}
+
+func checkEstimate(t *testing.T, h *HyperLogLog, expected int) {
+	estimate := h.Estimate()
+	if diff := math.Abs(float64(estimate) - float64(expected)); diff > 0.03*float64(expected) {
+		t.Errorf("expected estimate of about %d, got %d", expected, estimate)
+	}
+}
+
+func TestHyperLogLog(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	if e := NewHyperLogLog().Estimate(); e != 0 {
+		t.Errorf("expected empty sketch to estimate 0, got %d", e)
+	}
+
+	for _, n := range []int{1, 10, 100, 1000, 10000, 100000, 1000000} {
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			h := NewHyperLogLog()
+			insertInts(h, 0, n)
+			// Duplicates don't change the estimate.
+			insertInts(h, 0, n)
+			checkEstimate(t, h, n)
+		})
+	}
+}
+
+func TestHyperLogLogMerge(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	a, b := NewHyperLogLog(), NewHyperLogLog()
+	insertInts(a, 0, 60000)
+	insertInts(b, 40000, 100000)
+	a.Merge(b)
+	checkEstimate(t, a, 100000)
+}
+
+func TestHyperLogLogMarshal(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	h := NewHyperLogLog()
+	insertInts(h, 0, 5000)
+	b, err := h.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var h2 HyperLogLog
+	if err := h2.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if h.Estimate() != h2.Estimate() {
+		t.Errorf("expected estimate %d after decoding, got %d", h.Estimate(), h2.Estimate())
+	}
+
+	if err := h2.UnmarshalBinary(b[:10]); err == nil {
+		t.Error("expected error decoding a truncated sketch")
+	}
+	b[0]++
+	if err := h2.UnmarshalBinary(b); err == nil {
+		t.Error("expected error decoding a sketch with a different precision")
+	}
+}
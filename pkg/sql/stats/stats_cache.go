@@ -0,0 +1,228 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/gossip"
+	"github.com/cockroachdb/cockroach/pkg/internal/client"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlutil"
+	"github.com/cockroachdb/cockroach/pkg/util/cache"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+)
+
+// errorRetryInterval is how long an error reading the statistics of a table
+// is cached. Statements are planned with the default estimates until then,
+// instead of each one reading the statistics again.
+const errorRetryInterval = 5 * time.Second
+
+// TableStatistic is a statistic on a column of a table, as stored in
+// system.table_statistics.
+type TableStatistic struct {
+	TableID       sqlbase.ID
+	ColumnID      sqlbase.ColumnID
+	StatisticID   int64
+	Name          string
+	CreatedAt     time.Time
+	RowCount      int64
+	DistinctCount int64
+	NullCount     int64
+	// Histogram is nil if no histogram was collected.
+	Histogram *HistogramData
+}
+
+// TableStatisticsCache is an LRU cache of the latest statistic on each column
+// of the tables it holds, including the tables without statistics. The entry
+// of a table is invalidated when new statistics on it are gossiped; see
+// gossip.MakeTableStatAddedKey. Errors are cached for errorRetryInterval.
+type TableStatisticsCache struct {
+	mu struct {
+		syncutil.Mutex
+		// cache maps a table ID to its *cacheEntry.
+		cache *cache.UnorderedCache
+	}
+	db          *client.DB
+	sqlExecutor sqlutil.InternalExecutor
+}
+
+// cacheEntry holds the statistics of a table. An entry is added to the cache
+// before the statistics are read, so that concurrent lookups of the same table
+// wait for a single read, and an invalidation that arrives during the read
+// removes the entry instead of being overwritten by the result of the read.
+type cacheEntry struct {
+	// mustWait is true while the statistics are being read; waitCond is
+	// signaled once they have been.
+	mustWait bool
+	waitCond sync.Cond
+
+	stats []*TableStatistic
+	err   error
+	// errExpiration is when the statistics are read again if err is set.
+	errExpiration time.Time
+}
+
+// NewTableStatisticsCache creates a TableStatisticsCache holding the
+// statistics of up to cacheSize tables.
+func NewTableStatisticsCache(
+	cacheSize int, g *gossip.Gossip, db *client.DB, sqlExecutor sqlutil.InternalExecutor,
+) *TableStatisticsCache {
+	sc := &TableStatisticsCache{
+		db:          db,
+		sqlExecutor: sqlExecutor,
+	}
+	sc.mu.cache = cache.NewUnorderedCache(cache.Config{
+		Policy: cache.CacheLRU,
+		ShouldEvict: func(s int, key, value interface{}) bool {
+			return s > cacheSize
+		},
+	})
+	g.RegisterCallback(
+		gossip.MakePrefixPattern(gossip.KeyTableStatAddedPrefix), sc.tableStatAddedGossipUpdate,
+	)
+	return sc
+}
+
+// tableStatAddedGossipUpdate is the gossip callback that invalidates the
+// cached statistics of a table when new ones are collected.
+func (sc *TableStatisticsCache) tableStatAddedGossipUpdate(key string, _ roachpb.Value) {
+	tableID, err := gossip.TableIDFromTableStatAddedKey(key)
+	if err != nil {
+		log.Errorf(context.Background(), "tableStatAddedGossipUpdate(%s) error: %v", key, err)
+		return
+	}
+	sc.InvalidateTableStats(sqlbase.ID(tableID))
+}
+
+// InvalidateTableStats removes the statistics of the given table from the
+// cache, so that they are read again on the next lookup.
+func (sc *TableStatisticsCache) InvalidateTableStats(tableID sqlbase.ID) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.mu.cache.Del(tableID)
+}
+
+// GetTableStats returns the latest statistic on each column of the given
+// table that has statistics, most recent first. The result must not be
+// modified.
+func (sc *TableStatisticsCache) GetTableStats(
+	ctx context.Context, tableID sqlbase.ID,
+) ([]*TableStatistic, error) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	for {
+		v, ok := sc.mu.cache.Get(tableID)
+		if !ok {
+			break
+		}
+		e := v.(*cacheEntry)
+		for e.mustWait {
+			e.waitCond.Wait()
+		}
+		if e.err == nil || timeutil.Now().Before(e.errExpiration) {
+			return e.stats, e.err
+		}
+		// The error has expired. Unless another lookup already started to read
+		// the statistics again, do so.
+		if v, ok := sc.mu.cache.Get(tableID); ok && v.(*cacheEntry) == e {
+			sc.mu.cache.Del(tableID)
+		}
+	}
+
+	e := &cacheEntry{mustWait: true}
+	e.waitCond.L = &sc.mu
+	sc.mu.cache.Add(tableID, e)
+
+	sc.mu.Unlock()
+	stats, err := sc.getTableStatsFromDB(ctx, tableID)
+	sc.mu.Lock()
+
+	e.mustWait = false
+	e.stats, e.err = stats, err
+	e.waitCond.Broadcast()
+	if err != nil {
+		if ctx.Err() == nil {
+			e.errExpiration = timeutil.Now().Add(errorRetryInterval)
+		} else if v, ok := sc.mu.cache.Get(tableID); ok && v.(*cacheEntry) == e {
+			// The lookup was canceled, which says nothing about the next one.
+			sc.mu.cache.Del(tableID)
+		}
+	}
+	return stats, err
+}
+
+// getTableStatsFromDB reads the latest statistic on each column of the given
+// table from system.table_statistics.
+func (sc *TableStatisticsCache) getTableStatsFromDB(
+	ctx context.Context, tableID sqlbase.ID,
+) ([]*TableStatistic, error) {
+	const getTableStatisticsStmt = `
+SELECT "column_id", "statistic_id", "name", "created", "row_count", "distinct_count",
+       "null_count", "histogram"
+  FROM system.table_statistics
+ WHERE "table_id" = $1
+ ORDER BY "created" DESC
+`
+	var rows []parser.Datums
+	if err := sc.db.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
+		var err error
+		rows, err = sc.sqlExecutor.QueryRowsInTransaction(
+			ctx, "get-table-statistics", txn, getTableStatisticsStmt, tableID,
+		)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	var stats []*TableStatistic
+	seen := make(map[sqlbase.ColumnID]struct{})
+	for _, row := range rows {
+		columnID := sqlbase.ColumnID(*row[0].(*parser.DInt))
+		if _, ok := seen[columnID]; ok {
+			// An older statistic on a column.
+			continue
+		}
+		seen[columnID] = struct{}{}
+		stat := &TableStatistic{
+			TableID:       tableID,
+			ColumnID:      columnID,
+			StatisticID:   int64(*row[1].(*parser.DInt)),
+			CreatedAt:     row[3].(*parser.DTimestamp).Time,
+			RowCount:      int64(*row[4].(*parser.DInt)),
+			DistinctCount: int64(*row[5].(*parser.DInt)),
+			NullCount:     int64(*row[6].(*parser.DInt)),
+		}
+		if row[2] != parser.DNull {
+			stat.Name = string(*row[2].(*parser.DString))
+		}
+		if row[7] != parser.DNull {
+			stat.Histogram = &HistogramData{}
+			if err := proto.Unmarshal([]byte(*row[7].(*parser.DBytes)), stat.Histogram); err != nil {
+				return nil, err
+			}
+		}
+		stats = append(stats, stat)
+	}
+	return stats, nil
+}
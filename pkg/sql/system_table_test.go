@@ -119,6 +119,9 @@ func TestSystemTableLiterals(t *testing.T) {
 		{keys.UITableID, sqlbase.UITableSchema, sqlbase.UITable},
 		{keys.JobsTableID, sqlbase.JobsTableSchema, sqlbase.JobsTable},
 		{keys.SettingsTableID, sqlbase.SettingsTableSchema, sqlbase.SettingsTable},
+		{keys.ReplicationReportsTableID, sqlbase.ReplicationReportsTableSchema, sqlbase.ReplicationReportsTable},
+		{keys.StatementStatsTableID, sqlbase.StatementStatsTableSchema, sqlbase.StatementStatsTable},
+		{keys.TableStatisticsTableID, sqlbase.TableStatisticsTableSchema, sqlbase.TableStatisticsTable},
 	} {
 		gen, err := sql.CreateTestTableDescriptor(
 			context.TODO(),
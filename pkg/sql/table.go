@@ -213,6 +213,7 @@ func mustGetViewDesc(
 
 var errTableDropped = errors.New("table is being dropped")
 var errTableAdding = errors.New("table is being added")
+var errTableOffline = errors.New("table is offline")
 
 func filterTableState(tableDesc *sqlbase.TableDescriptor) error {
 	switch {
@@ -220,6 +221,8 @@ func filterTableState(tableDesc *sqlbase.TableDescriptor) error {
 		return errTableDropped
 	case tableDesc.Adding():
 		return errTableAdding
+	case tableDesc.Offline():
+		return errTableOffline
 	case tableDesc.State != sqlbase.TableDescriptor_PUBLIC:
 		return errors.Errorf("table in unknown state: %s", tableDesc.State.String())
 	}
@@ -623,6 +626,23 @@ func (p *planner) getTableAndIndex(
 	table *parser.NormalizableTableName,
 	tableWithIndex *parser.TableNameWithIndex,
 	privilege privilege.Kind,
+) (*sqlbase.TableDescriptor, *sqlbase.IndexDescriptor, error) {
+	tableDesc, index, err := p.resolveTableAndIndex(ctx, table, tableWithIndex)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := p.CheckPrivilege(tableDesc, privilege); err != nil {
+		return nil, nil, err
+	}
+	return tableDesc, index, nil
+}
+
+// resolveTableAndIndex is like getTableAndIndex, but does not check any
+// privileges.
+func (p *planner) resolveTableAndIndex(
+	ctx context.Context,
+	table *parser.NormalizableTableName,
+	tableWithIndex *parser.TableNameWithIndex,
 ) (*sqlbase.TableDescriptor, *sqlbase.IndexDescriptor, error) {
 	var tn *parser.TableName
 	var err error
@@ -643,9 +663,6 @@ func (p *planner) getTableAndIndex(
 	if tableDesc == nil {
 		return nil, nil, sqlbase.NewUndefinedTableError(tn.String())
 	}
-	if err := p.CheckPrivilege(tableDesc, privilege); err != nil {
-		return nil, nil, err
-	}
 
 	// Determine which index to use.
 	var index *sqlbase.IndexDescriptor
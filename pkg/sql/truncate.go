@@ -22,11 +22,13 @@ import (
 	"github.com/pkg/errors"
 	"golang.org/x/net/context"
 
+	"github.com/cockroachdb/cockroach/pkg/config"
 	"github.com/cockroachdb/cockroach/pkg/internal/client"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/sql/parser"
 	"github.com/cockroachdb/cockroach/pkg/sql/privilege"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 )
 
@@ -38,7 +40,9 @@ const TableTruncateChunkSize = indexTruncateChunkSize
 // Privileges: DROP on table.
 //   Notes: postgres requires TRUNCATE.
 //          mysql requires DROP (for mysql >= 5.1.16, DELETE before that).
-func (p *planner) Truncate(ctx context.Context, n *parser.Truncate) (planNode, error) {
+func (p *planner) Truncate(
+	ctx context.Context, n *parser.Truncate, autoCommit bool,
+) (planNode, error) {
 	// Since truncation may cascade to a given table any number of times, start by
 	// building the unique set (by ID) of tables to truncate.
 	toTruncate := make(map[sqlbase.ID]*sqlbase.TableDescriptor, len(n.Tables))
@@ -93,7 +97,33 @@ func (p *planner) Truncate(ctx context.Context, n *parser.Truncate) (planNode, e
 		}
 	}
 
-	for _, tableDesc := range toTruncate {
+	// Tables are truncated by replacing them with empty copies where possible.
+	// This is only safe if the transaction commits right away: later
+	// statements in the same transaction would resolve the table's name to
+	// the table being dropped.
+	replace := make(map[sqlbase.ID]bool)
+	if autoCommit {
+		for id, tableDesc := range toTruncate {
+			if canTruncateByReplacing(tableDesc) {
+				replace[id] = true
+			}
+		}
+	}
+	if len(replace) > 0 {
+		// Replacing a table writes descriptors, so the system config needs to
+		// be gossiped on commit.
+		if err := p.txn.SetSystemConfigTrigger(); err != nil {
+			return nil, err
+		}
+	}
+
+	for id, tableDesc := range toTruncate {
+		if replace[id] {
+			if err := p.truncateByReplacing(ctx, id); err != nil {
+				return nil, err
+			}
+			continue
+		}
 		if err := truncateTable(tableDesc, p.txn); err != nil {
 			return nil, err
 		}
@@ -102,6 +132,72 @@ func (p *planner) Truncate(ctx context.Context, n *parser.Truncate) (planNode, e
 	return &emptyNode{}, nil
 }
 
+// canTruncateByReplacing returns whether the table can be truncated by
+// replacing it with an empty copy. This requires that no other descriptor
+// refers to the table by ID, and that the table's data isn't interleaved
+// with that of other tables.
+func canTruncateByReplacing(tableDesc *sqlbase.TableDescriptor) bool {
+	if len(tableDesc.Mutations) > 0 || tableDesc.Renamed() ||
+		len(tableDesc.DependedOnBy) > 0 || tableDesc.IsInterleaved() {
+		return false
+	}
+	for _, idx := range tableDesc.AllNonDropIndexes() {
+		if idx.ForeignKey.IsSet() || len(idx.ReferencedBy) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// truncateByReplacing truncates a table by creating an empty copy of it under
+// a new ID, which takes over the table's name and zone config, and dropping
+// the original table. Unlike deleting all the rows in the transaction, this
+// takes constant time; the data of the original table is cleared by the
+// schema changer once its GC TTL has elapsed.
+func (p *planner) truncateByReplacing(ctx context.Context, tableID sqlbase.ID) error {
+	tableDesc, err := sqlbase.GetTableDescFromID(ctx, p.txn, tableID)
+	if err != nil {
+		return err
+	}
+	newTableDesc, err := sqlbase.GetTableDescFromID(ctx, p.txn, tableID)
+	if err != nil {
+		return err
+	}
+	newID, err := GenerateUniqueDescID(ctx, p.txn)
+	if err != nil {
+		return err
+	}
+	newTableDesc.ID = newID
+	newTableDesc.Version = 1
+	newTableDesc.UpVersion = false
+	newTableDesc.ModificationTime = hlc.Timestamp{}
+	newTableDesc.Lease = nil
+	if err := newTableDesc.ValidateTable(); err != nil {
+		return err
+	}
+
+	zoneKey, nameKey, _ := GetKeysForTableDescriptor(tableDesc)
+	b := &client.Batch{}
+	b.Put(nameKey, newID)
+	b.CPut(sqlbase.MakeDescMetadataKey(newID), sqlbase.WrapDescriptor(newTableDesc), nil)
+	zoneKV, err := p.txn.Get(ctx, zoneKey)
+	if err != nil {
+		return err
+	}
+	if zoneKV.Value != nil {
+		zone, err := config.MigrateZoneConfig(zoneKV.Value)
+		if err != nil {
+			return err
+		}
+		b.Put(sqlbase.MakeZoneKey(newID), &zone)
+	}
+	if err := p.txn.Run(ctx, b); err != nil {
+		return err
+	}
+
+	return p.initiateDropTable(ctx, tableDesc)
+}
+
 // truncateTable truncates the data of a table in a single transaction. It
 // deletes a range of data for the table, which includes the PK and all
 // indexes.
@@ -164,7 +164,7 @@ var varGen = map[string]sessionVar{
 			return "auto"
 		},
 		Reset: func(p *planner) error {
-			p.session.DistSQLMode = defaultDistSQLMode
+			p.session.DistSQLMode = distSQLExecMode(DistSQLClusterExecMode.Get())
 			return nil
 		},
 	},
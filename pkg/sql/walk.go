@@ -497,9 +497,11 @@ func nodeName(plan planNode) string {
 // be changed without changing the output of "EXPLAIN".
 var planNodeNames = map[reflect.Type]string{
 	reflect.TypeOf(&alterTableNode{}):     "alter table",
+	reflect.TypeOf(&controlJobNode{}):     "control job",
 	reflect.TypeOf(&copyNode{}):           "copy",
 	reflect.TypeOf(&createDatabaseNode{}): "create database",
 	reflect.TypeOf(&createIndexNode{}):    "create index",
+	reflect.TypeOf(&createStatsNode{}):    "create statistics",
 	reflect.TypeOf(&createTableNode{}):    "create table",
 	reflect.TypeOf(&createUserNode{}):     "create user",
 	reflect.TypeOf(&createViewNode{}):     "create view",
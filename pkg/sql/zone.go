@@ -0,0 +1,409 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// This file implements the zone config statements:
+//   ALTER DATABASE/TABLE/INDEX/PARTITION ... CONFIGURE ZONE USING field = value, ...
+//   ALTER DATABASE/TABLE/INDEX/PARTITION ... CONFIGURE ZONE DISCARD
+//   SHOW ZONE CONFIGURATION FOR DATABASE/TABLE/INDEX/PARTITION ...
+//
+// Zone configs are stored in system.zones, keyed by the ID of the database or
+// table they apply to. The zone configs of indexes and index partitions are
+// stored as subzones of the zone config of their table.
+
+package sql
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/cockroachdb/cockroach/pkg/config"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+)
+
+// defaultZoneName is the name under which the default zone config is shown.
+const defaultZoneName = ".default"
+
+// zoneTarget is a resolved ZoneSpecifier.
+type zoneTarget struct {
+	// path holds the IDs of the objects whose zone configs apply to the
+	// target, from least to most specific, starting with the default zone.
+	path []sqlbase.ID
+	// names holds the display names of the objects in path.
+	names []string
+	// indexID is the ID of the target index, or 0 if the target is not an
+	// index or a partition. The zone config of an index is stored with the
+	// zone config of its table, which is the last element of path.
+	indexID   sqlbase.IndexID
+	indexName string
+	// partition is the name of the target partition of the index, if any.
+	partition string
+	// tableDesc is the descriptor of the table if the target is a table, an
+	// index or a partition.
+	tableDesc *sqlbase.TableDescriptor
+}
+
+// id returns the ID of the system.zones row that stores the zone config of the
+// target.
+func (t zoneTarget) id() sqlbase.ID {
+	return t.path[len(t.path)-1]
+}
+
+// partitionZoneName returns the display name of the target partition. The
+// partitions of the primary index are shown as partitions of the table.
+func (t zoneTarget) partitionZoneName() string {
+	name := t.names[len(t.names)-1]
+	if t.indexID != t.tableDesc.PrimaryIndex.ID {
+		name += "@" + t.indexName
+	}
+	return name + "." + parser.Name(t.partition).String()
+}
+
+// resolveZoneSpecifier looks up the database, table or index referenced by a
+// ZoneSpecifier.
+func (p *planner) resolveZoneSpecifier(
+	ctx context.Context, zs *parser.ZoneSpecifier,
+) (zoneTarget, error) {
+	target := zoneTarget{
+		path:  []sqlbase.ID{keys.RootNamespaceID},
+		names: []string{defaultZoneName},
+	}
+
+	if zs.Table == nil && zs.Index == nil {
+		dbDesc, err := MustGetDatabaseDesc(ctx, p.txn, p.getVirtualTabler(), string(zs.Database))
+		if err != nil {
+			return zoneTarget{}, err
+		}
+		if err := p.anyPrivilege(dbDesc); err != nil {
+			return zoneTarget{}, err
+		}
+		target.path = append(target.path, dbDesc.ID)
+		target.names = append(target.names, parser.Name(dbDesc.Name).String())
+		return target, nil
+	}
+
+	tableDesc, index, err := p.resolveTableAndIndex(ctx, zs.Table, zs.Index)
+	if err != nil {
+		return zoneTarget{}, err
+	}
+	if err := p.anyPrivilege(tableDesc); err != nil {
+		return zoneTarget{}, err
+	}
+	if tableDesc.IsVirtualTable() {
+		return zoneTarget{}, errors.Errorf("%s is a virtual table", tableDesc.Name)
+	}
+	var tn *parser.TableName
+	if zs.Index != nil {
+		tn, err = zs.Index.Table.NormalizeWithDatabaseName(p.session.Database)
+	} else {
+		tn, err = zs.Table.NormalizeWithDatabaseName(p.session.Database)
+	}
+	if err != nil {
+		return zoneTarget{}, err
+	}
+
+	dbName := tn.Database()
+	target.path = append(target.path, tableDesc.ParentID, tableDesc.ID)
+	target.names = append(target.names,
+		parser.Name(dbName).String(),
+		parser.Name(dbName).String()+"."+parser.Name(tableDesc.Name).String())
+	target.tableDesc = tableDesc
+	if zs.Index != nil || zs.Partition != "" {
+		target.indexID = index.ID
+		target.indexName = parser.Name(index.Name).String()
+	}
+	if zs.Partition != "" {
+		if !findPartition(index, string(zs.Partition)) {
+			return zoneTarget{}, errors.Errorf("partition %s does not exist on index %s",
+				zs.Partition, target.indexName)
+		}
+		target.partition = string(zs.Partition)
+	}
+	return target, nil
+}
+
+// getZoneConfigRaw looks up the zone config stored in system.zones for the
+// given ID. It returns false if there is none.
+func (p *planner) getZoneConfigRaw(
+	ctx context.Context, id sqlbase.ID,
+) (config.ZoneConfig, bool, error) {
+	kv, err := p.txn.Get(ctx, sqlbase.MakeZoneKey(id))
+	if err != nil {
+		return config.ZoneConfig{}, false, err
+	}
+	if kv.Value == nil {
+		return config.ZoneConfig{}, false, nil
+	}
+	zone, err := config.MigrateZoneConfig(kv.Value)
+	return zone, true, err
+}
+
+// getInheritedZoneConfig returns the zone config that applies to the target,
+// along with the display name of the object it is set on. A partition
+// inherits the zone config of its index, which inherits the zone config of its
+// table. The returned config never has subzones.
+func (p *planner) getInheritedZoneConfig(
+	ctx context.Context, target zoneTarget,
+) (string, config.ZoneConfig, error) {
+	for i := len(target.path) - 1; i >= 0; i-- {
+		zone, found, err := p.getZoneConfigRaw(ctx, target.path[i])
+		if err != nil {
+			return "", config.ZoneConfig{}, err
+		}
+		if !found {
+			continue
+		}
+		if i == len(target.path)-1 && target.indexID != 0 {
+			if target.partition != "" {
+				if subzone := zone.GetSubzone(uint32(target.indexID), target.partition); subzone != nil {
+					return target.partitionZoneName(), subzone.Config, nil
+				}
+			}
+			if subzone := zone.GetSubzone(uint32(target.indexID), ""); subzone != nil {
+				return target.names[i] + "@" + target.indexName, subzone.Config, nil
+			}
+		}
+		if zone.IsSubzonePlaceholder() {
+			continue
+		}
+		zone.Subzones = nil
+		zone.SubzoneSpans = nil
+		return target.names[i], zone, nil
+	}
+	return defaultZoneName, config.DefaultZoneConfig(), nil
+}
+
+// writeZoneConfigRaw stores the given zone config in system.zones, or removes
+// the row for the given ID if the config is empty.
+func (p *planner) writeZoneConfigRaw(
+	ctx context.Context, id sqlbase.ID, zone config.ZoneConfig,
+) error {
+	ie := InternalExecutor{LeaseManager: p.LeaseMgr()}
+	if zone.NumReplicas == 0 && len(zone.Subzones) == 0 {
+		_, err := ie.ExecuteStatementInTransaction(
+			ctx, "delete-zone", p.txn, "DELETE FROM system.zones WHERE id = $1", id,
+		)
+		return err
+	}
+	buf, err := protoutil.Marshal(&zone)
+	if err != nil {
+		return err
+	}
+	_, err = ie.ExecuteStatementInTransaction(
+		ctx, "upsert-zone", p.txn, "UPSERT INTO system.zones (id, config) VALUES ($1, $2)", id, buf,
+	)
+	return err
+}
+
+// SetZoneConfig sets or discards the zone config of a database, table, index
+// or index partition.
+// Privileges: superuser.
+func (p *planner) SetZoneConfig(ctx context.Context, n *parser.SetZoneConfig) (planNode, error) {
+	if err := p.RequireSuperUser("CONFIGURE ZONE"); err != nil {
+		return nil, err
+	}
+	target, err := p.resolveZoneSpecifier(ctx, &n.ZoneSpecifier)
+	if err != nil {
+		return nil, err
+	}
+	if len(target.path) > 2 && target.path[1] == keys.SystemDatabaseID {
+		return nil, errors.New("setting zone configs for individual system tables is not supported; " +
+			"try setting your config on the entire \"system\" database instead")
+	}
+
+	// The row that stores the zone config of the target, which holds the zone
+	// configs of its indexes and partitions if the target is a table, an index
+	// or a partition.
+	stored, _, err := p.getZoneConfigRaw(ctx, target.id())
+	if err != nil {
+		return nil, err
+	}
+
+	if n.Discard {
+		if target.indexID != 0 {
+			if !stored.DeleteSubzone(uint32(target.indexID), target.partition) {
+				return &emptyNode{}, nil
+			}
+			if stored.SubzoneSpans, err = generateSubzoneSpans(target.tableDesc, stored.Subzones); err != nil {
+				return nil, err
+			}
+		} else {
+			// Keep the zone configs of the indexes of a table around.
+			stored = config.ZoneConfig{Subzones: stored.Subzones, SubzoneSpans: stored.SubzoneSpans}
+		}
+		if err := p.writeZoneConfigRaw(ctx, target.id(), stored); err != nil {
+			return nil, err
+		}
+		return &emptyNode{}, nil
+	}
+
+	_, zone, err := p.getInheritedZoneConfig(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.applyZoneOptions(&zone, n.Options); err != nil {
+		return nil, err
+	}
+	if err := zone.Validate(); err != nil {
+		return nil, err
+	}
+
+	if target.indexID != 0 {
+		stored.SetSubzone(config.Subzone{
+			IndexID:       uint32(target.indexID),
+			PartitionName: target.partition,
+			Config:        zone,
+		})
+		if stored.SubzoneSpans, err = generateSubzoneSpans(target.tableDesc, stored.Subzones); err != nil {
+			return nil, err
+		}
+	} else {
+		zone.Subzones = stored.Subzones
+		zone.SubzoneSpans = stored.SubzoneSpans
+		stored = zone
+	}
+	if err := p.writeZoneConfigRaw(ctx, target.id(), stored); err != nil {
+		return nil, err
+	}
+	return &emptyNode{}, nil
+}
+
+// applyZoneOptions evaluates the `field = value` assignments of a CONFIGURE
+// ZONE statement and sets the corresponding fields of zone.
+func (p *planner) applyZoneOptions(zone *config.ZoneConfig, options parser.UpdateExprs) error {
+	seen := make(map[string]struct{}, len(options))
+	for _, opt := range options {
+		key := strings.ToLower(opt.Names[0].String())
+		if _, ok := seen[key]; ok {
+			return errors.Errorf("duplicate zone config field %q", key)
+		}
+		seen[key] = struct{}{}
+
+		switch key {
+		case "num_replicas", "range_min_bytes", "range_max_bytes", "gc.ttlseconds":
+			d, err := p.evalZoneOption(key, opt.Expr, parser.TypeInt)
+			if err != nil {
+				return err
+			}
+			v := int64(*d.(*parser.DInt))
+			if v < 0 {
+				return errors.Errorf("%s must not be negative", key)
+			}
+			switch key {
+			case "num_replicas":
+				zone.NumReplicas = int32(v)
+			case "range_min_bytes":
+				zone.RangeMinBytes = v
+			case "range_max_bytes":
+				zone.RangeMaxBytes = v
+			case "gc.ttlseconds":
+				zone.GC.TTLSeconds = int32(v)
+			}
+		case "constraints":
+			d, err := p.evalZoneOption(key, opt.Expr, parser.TypeString)
+			if err != nil {
+				return err
+			}
+			var constraints config.Constraints
+			if err := yaml.Unmarshal([]byte(string(*d.(*parser.DString))), &constraints); err != nil {
+				return errors.Wrapf(err, "invalid constraints")
+			}
+			zone.Constraints = constraints
+		case "replica_constraints":
+			d, err := p.evalZoneOption(key, opt.Expr, parser.TypeString)
+			if err != nil {
+				return err
+			}
+			var replicaConstraints config.ReplicaConstraints
+			if err := yaml.Unmarshal([]byte(string(*d.(*parser.DString))), &replicaConstraints); err != nil {
+				return errors.Wrapf(err, "invalid replica constraints")
+			}
+			zone.ReplicaConstraints = replicaConstraints
+		case "lease_preferences":
+			d, err := p.evalZoneOption(key, opt.Expr, parser.TypeString)
+			if err != nil {
+				return err
+			}
+			var preferences []config.LeasePreference
+			if err := yaml.Unmarshal([]byte(string(*d.(*parser.DString))), &preferences); err != nil {
+				return errors.Wrapf(err, "invalid lease preferences")
+			}
+			zone.LeasePreferences = preferences
+		default:
+			return errors.Errorf("unknown zone config field %q", key)
+		}
+	}
+	return nil
+}
+
+// evalZoneOption evaluates the value of a zone config field, which must be of
+// the given type and not NULL.
+func (p *planner) evalZoneOption(key string, expr parser.Expr, typ parser.Type) (parser.Datum, error) {
+	typedExpr, err := parser.TypeCheckAndRequire(expr, &p.semaCtx, typ, key)
+	if err != nil {
+		return nil, err
+	}
+	d, err := typedExpr.Eval(&p.evalCtx)
+	if err != nil {
+		return nil, err
+	}
+	if d == parser.DNull {
+		return nil, errors.Errorf("%s must not be NULL", key)
+	}
+	return d, nil
+}
+
+// ShowZoneConfig shows the zone config that applies to a database, table,
+// index or index partition, along with the name of the object it is inherited
+// from.
+// Privileges: Any privilege on the database or table.
+func (p *planner) ShowZoneConfig(ctx context.Context, n *parser.ShowZoneConfig) (planNode, error) {
+	target, err := p.resolveZoneSpecifier(ctx, &n.ZoneSpecifier)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := ResultColumns{
+		{Name: "zone_name", Typ: parser.TypeString},
+		{Name: "config", Typ: parser.TypeString},
+	}
+	return &delayedNode{
+		name:    n.String(),
+		columns: columns,
+		constructor: func(ctx context.Context, p *planner) (planNode, error) {
+			name, zone, err := p.getInheritedZoneConfig(ctx, target)
+			if err != nil {
+				return nil, err
+			}
+			res, err := yaml.Marshal(zone)
+			if err != nil {
+				return nil, err
+			}
+			v := p.newContainerValuesNode(columns, 1)
+			if _, err := v.rows.AddRow(ctx, parser.Datums{
+				parser.NewDString(name),
+				parser.NewDString(string(res)),
+			}); err != nil {
+				v.rows.Close(ctx)
+				return nil, err
+			}
+			return v, nil
+		},
+	}, nil
+}
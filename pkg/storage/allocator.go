@@ -30,6 +30,7 @@ import (
 
 	"github.com/cockroachdb/cockroach/pkg/config"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
 	"github.com/cockroachdb/cockroach/pkg/util"
 	"github.com/cockroachdb/cockroach/pkg/util/envutil"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
@@ -59,7 +60,11 @@ var (
 	// EnableLoadBasedLeaseRebalancing controls whether lease rebalancing is done
 	// via the new heuristic based on request load and latency or via the simpler
 	// approach that purely seeks to balance the number of leases per node evenly.
-	EnableLoadBasedLeaseRebalancing = envutil.EnvOrDefaultBool("COCKROACH_ENABLE_LOAD_BASED_LEASE_REBALANCING", true)
+	EnableLoadBasedLeaseRebalancing = settings.RegisterBoolSetting(
+		"kv.allocator.load_based_lease_rebalancing.enabled",
+		"set to enable rebalancing of range leases based on load and latency",
+		true,
+	)
 
 	// LeaseRebalancingAggressiveness enables users to tweak how aggressive their
 	// cluster is at moving leases towards the localities where the most requests
@@ -69,16 +74,20 @@ var (
 	//
 	// Setting this to 0 effectively disables load-based lease rebalancing, and
 	// settings less than 0 are disallowed.
-	LeaseRebalancingAggressiveness = envutil.EnvOrDefaultFloat("COCKROACH_LEASE_REBALANCING_AGGRESSIVENESS", 1.0)
+	LeaseRebalancingAggressiveness = settings.RegisterValidatedFloatSetting(
+		"kv.allocator.lease_rebalancing_aggressiveness",
+		"set greater than 1.0 to rebalance leases toward load more aggressively, "+
+			"or between 0 and 1.0 to be more conservative about rebalancing leases",
+		1.0,
+		func(v float64) error {
+			if v < 0 {
+				return errors.Errorf("cannot set to a negative value: %f", v)
+			}
+			return nil
+		},
+	)
 )
 
-func init() {
-	if LeaseRebalancingAggressiveness < 0 {
-		panic(fmt.Sprintf("COCKROACH_LEASE_REBALANCING_AGGRESSIVENESS must not be negative, got %f",
-			LeaseRebalancingAggressiveness))
-	}
-}
-
 // AllocatorAction enumerates the various replication adjustments that may be
 // recommended by the allocator.
 type AllocatorAction int
@@ -243,15 +252,16 @@ func (a *Allocator) ComputeAction(
 }
 
 // AllocateTarget returns a suitable store for a new allocation with the
-// required attributes. Nodes already accommodating existing replicas are ruled
-// out as targets. The range ID of the replica being allocated for is also
-// passed in to ensure that we don't try to replace an existing dead replica on
-// a store. If relaxConstraints is true, then the required attributes will be
-// relaxed as necessary, from least specific to most specific, in order to
-// allocate a target.
+// attributes required by the zone, preferring stores that satisfy its
+// under-satisfied per-replica constraints. Nodes already accommodating
+// existing replicas are ruled out as targets. The range ID of the replica
+// being allocated for is also passed in to ensure that we don't try to replace
+// an existing dead replica on a store. If relaxConstraints is true, then the
+// required attributes will be relaxed as necessary, from least specific to
+// most specific, in order to allocate a target.
 func (a *Allocator) AllocateTarget(
 	ctx context.Context,
-	constraints config.Constraints,
+	zone config.ZoneConfig,
 	existing []roachpb.ReplicaDescriptor,
 	rangeID roachpb.RangeID,
 	relaxConstraints bool,
@@ -260,7 +270,8 @@ func (a *Allocator) AllocateTarget(
 
 	candidates := allocateCandidates(
 		sl,
-		constraints,
+		zone.Constraints,
+		zone.ReplicaConstraints,
 		existing,
 		a.storePool.getLocalities(existing),
 		a.storePool.deterministic,
@@ -281,17 +292,18 @@ func (a *Allocator) AllocateTarget(
 		return nil, errors.Errorf("%d matching stores are currently throttled", throttledStoreCount)
 	}
 	return nil, &allocatorError{
-		required: constraints.Constraints,
+		required: zone.Constraints.Constraints,
 	}
 }
 
 // RemoveTarget returns a suitable replica to remove from the provided replica
 // set. It first attempts to randomly select a target from the set of stores
-// that have greater than the average number of replicas. Failing that, it
-// falls back to selecting a random target from any of the existing
+// that violate the constraints of the zone, over-satisfy its per-replica
+// constraints or have greater than the average number of replicas. Failing
+// that, it falls back to selecting a random target from any of the existing
 // replicas.
 func (a Allocator) RemoveTarget(
-	ctx context.Context, constraints config.Constraints, existing []roachpb.ReplicaDescriptor,
+	ctx context.Context, zone config.ZoneConfig, existing []roachpb.ReplicaDescriptor,
 ) (roachpb.ReplicaDescriptor, error) {
 	if len(existing) == 0 {
 		return roachpb.ReplicaDescriptor{}, errors.Errorf("must supply at least one replica to allocator.RemoveTarget()")
@@ -306,7 +318,8 @@ func (a Allocator) RemoveTarget(
 
 	candidates := removeCandidates(
 		sl,
-		constraints,
+		zone.Constraints,
+		zone.ReplicaConstraints,
 		a.storePool.getLocalities(existing),
 		a.storePool.deterministic,
 	)
@@ -333,9 +346,11 @@ func (a Allocator) RemoveTarget(
 // criteria. Namely, if chosen, it must further the goal of balancing the
 // cluster.
 //
-// The supplied parameters are the required attributes for the range, a list of
-// the existing replicas of the range, and the range ID of the replica being
-// allocated.
+// The supplied parameters are the zone config of the range, a list of the
+// existing replicas of the range, and the range ID of the replica being
+// allocated. Ranges whose replicas violate the per-replica constraints of the
+// zone are rebalanced even if doing so doesn't further the goal of balancing
+// the cluster.
 //
 // The existing replicas modulo any store with dead replicas are candidates for
 // rebalancing. Note that rebalancing is accomplished by first adding a new
@@ -348,7 +363,7 @@ func (a Allocator) RemoveTarget(
 // under-utilized store.
 func (a Allocator) RebalanceTarget(
 	ctx context.Context,
-	constraints config.Constraints,
+	zone config.ZoneConfig,
 	existing []roachpb.ReplicaDescriptor,
 	rangeID roachpb.RangeID,
 ) (*roachpb.StoreDescriptor, error) {
@@ -357,7 +372,8 @@ func (a Allocator) RebalanceTarget(
 	existingCandidates, candidates := rebalanceCandidates(
 		ctx,
 		sl,
-		constraints,
+		zone.Constraints,
+		zone.ReplicaConstraints,
 		existing,
 		a.storePool.getLocalities(existing),
 		a.storePool.deterministic,
@@ -380,10 +396,13 @@ func (a Allocator) RebalanceTarget(
 
 // TransferLeaseTarget returns a suitable replica to transfer the range lease
 // to from the provided list. It excludes the current lease holder replica
-// unless asked to do otherwise by the checkTransferLeaseSource parameter.
+// unless asked to do otherwise by the checkTransferLeaseSource parameter. If
+// the lease preferences of the zone can be satisfied, only the preferred
+// replicas are considered, and the lease is always transferred if the current
+// lease holder is not one of them.
 func (a *Allocator) TransferLeaseTarget(
 	ctx context.Context,
-	constraints config.Constraints,
+	zone config.ZoneConfig,
 	existing []roachpb.ReplicaDescriptor,
 	leaseStoreID roachpb.StoreID,
 	rangeID roachpb.RangeID,
@@ -392,7 +411,7 @@ func (a *Allocator) TransferLeaseTarget(
 	checkCandidateFullness bool,
 ) roachpb.ReplicaDescriptor {
 	sl, _, _ := a.storePool.getStoreList(rangeID)
-	sl = sl.filter(constraints)
+	sl = sl.filter(zone.Constraints)
 
 	// Filter stores that are on nodes containing existing replicas, but leave
 	// the stores containing the existing replicas in place. This excludes stores
@@ -417,6 +436,21 @@ func (a *Allocator) TransferLeaseTarget(
 	}
 	sl = makeStoreList(filteredDescs)
 
+	if preferred := a.preferredLeaseholders(zone, rangeID, existing); len(preferred) > 0 {
+		switch {
+		case !containsStore(preferred, leaseStoreID):
+			// The lease holder violates the lease preferences, so the lease is
+			// transferred to a preferred replica regardless of lease counts.
+			existing = preferred
+			checkTransferLeaseSource = false
+			checkCandidateFullness = false
+		case len(preferred) > 1 || checkTransferLeaseSource:
+			// Keep the lease among the preferred replicas, unless the lease
+			// holder is the only preferred replica and the transfer is forced.
+			existing = preferred
+		}
+	}
+
 	source, ok := a.storePool.getStoreDescriptor(leaseStoreID)
 	if !ok {
 		return roachpb.ReplicaDescriptor{}
@@ -467,12 +501,12 @@ func (a *Allocator) TransferLeaseTarget(
 	return candidates[a.randGen.Intn(len(candidates))]
 }
 
-// ShouldTransferLease returns true if the specified store is overfull in terms
-// of leases with respect to the other stores matching the specified
-// attributes.
+// ShouldTransferLease returns true if the specified store violates the lease
+// preferences of the zone or is overfull in terms of leases with respect to
+// the other stores matching the zone's constraints.
 func (a *Allocator) ShouldTransferLease(
 	ctx context.Context,
-	constraints config.Constraints,
+	zone config.ZoneConfig,
 	existing []roachpb.ReplicaDescriptor,
 	leaseStoreID roachpb.StoreID,
 	rangeID roachpb.RangeID,
@@ -482,8 +516,19 @@ func (a *Allocator) ShouldTransferLease(
 	if !ok {
 		return false
 	}
+	if preferred := a.preferredLeaseholders(zone, rangeID, existing); len(preferred) > 0 {
+		if !containsStore(preferred, leaseStoreID) {
+			if log.V(3) {
+				log.Infof(ctx, "ShouldTransferLease (lease-holder=%d): violates lease preferences",
+					leaseStoreID)
+			}
+			return true
+		}
+		// Only balance the lease among the preferred replicas.
+		existing = preferred
+	}
 	sl, _, _ := a.storePool.getStoreList(rangeID)
-	sl = sl.filter(constraints)
+	sl = sl.filter(zone.Constraints)
 	if log.V(3) {
 		log.Infof(ctx, "ShouldTransferLease (lease-holder=%d):\n%s", leaseStoreID, sl)
 	}
@@ -507,6 +552,59 @@ func (a *Allocator) ShouldTransferLease(
 	return result
 }
 
+// preferredLeaseholders returns the live replicas in existing that satisfy the
+// first lease preference of the zone that is satisfied by any of them. It
+// returns nil if the zone has no lease preferences or none of them can be
+// satisfied.
+func (a Allocator) preferredLeaseholders(
+	zone config.ZoneConfig, rangeID roachpb.RangeID, existing []roachpb.ReplicaDescriptor,
+) []roachpb.ReplicaDescriptor {
+	if len(zone.LeasePreferences) == 0 {
+		return nil
+	}
+	liveReplicas, _ := a.storePool.liveAndDeadReplicas(rangeID, existing)
+	for _, preference := range zone.LeasePreferences {
+		constraints := config.Constraints{Constraints: preference.Constraints}
+		var preferred []roachpb.ReplicaDescriptor
+		for _, repl := range liveReplicas {
+			storeDesc, ok := a.storePool.getStoreDescriptor(repl.StoreID)
+			if !ok {
+				continue
+			}
+			if ok, _ := constraintCheck(storeDesc, constraints); ok {
+				preferred = append(preferred, repl)
+			}
+		}
+		if len(preferred) > 0 {
+			return preferred
+		}
+	}
+	return nil
+}
+
+// leaseViolatesPreferences returns true if the lease of the range is held by
+// a store that does not satisfy the lease preferences of the zone, even though
+// another live replica of the range does.
+func (a Allocator) leaseViolatesPreferences(
+	zone config.ZoneConfig,
+	existing []roachpb.ReplicaDescriptor,
+	leaseStoreID roachpb.StoreID,
+	rangeID roachpb.RangeID,
+) bool {
+	preferred := a.preferredLeaseholders(zone, rangeID, existing)
+	return len(preferred) > 0 && !containsStore(preferred, leaseStoreID)
+}
+
+// containsStore returns true if any of the replicas is on the given store.
+func containsStore(repls []roachpb.ReplicaDescriptor, storeID roachpb.StoreID) bool {
+	for _, repl := range repls {
+		if repl.StoreID == storeID {
+			return true
+		}
+	}
+	return false
+}
+
 func (a Allocator) shouldTransferLeaseUsingStats(
 	ctx context.Context,
 	sl StoreList,
@@ -514,7 +612,7 @@ func (a Allocator) shouldTransferLeaseUsingStats(
 	existing []roachpb.ReplicaDescriptor,
 	stats *replicaStats,
 ) (transferDecision, roachpb.ReplicaDescriptor) {
-	if stats == nil || !EnableLoadBasedLeaseRebalancing {
+	if stats == nil || !EnableLoadBasedLeaseRebalancing.Get() {
 		return decideWithoutStats, roachpb.ReplicaDescriptor{}
 	}
 	requestCounts, requestCountsDur := stats.getRequestCounts()
@@ -617,7 +715,7 @@ func (a Allocator) shouldTransferLeaseUsingStats(
 // logic behind each part of the formula is as follows:
 //
 // * LeaseRebalancingAggressiveness: Allow the aggressiveness to be tuned via
-//   an environment variable.
+//   a cluster setting.
 // * 0.1: Constant factor to reduce aggressiveness by default
 // * math.Log10(remoteWeight/sourceWeight): Comparison of the remote replica's
 //   weight to the local replica's weight. Taking the log of the ratio instead
@@ -643,7 +741,7 @@ func loadBasedLeaseRebalanceScore(
 ) int32 {
 	remoteLatencyMillis := float64(remoteLatency) / float64(time.Millisecond)
 	rebalanceAdjustment :=
-		LeaseRebalancingAggressiveness * 0.1 * math.Log10(remoteWeight/sourceWeight) * math.Log1p(remoteLatencyMillis)
+		LeaseRebalancingAggressiveness.Get() * 0.1 * math.Log10(remoteWeight/sourceWeight) * math.Log1p(remoteLatencyMillis)
 	rebalanceThreshold := baseRebalanceThreshold - rebalanceAdjustment
 
 	overfullLeaseThreshold := int32(math.Ceil(meanLeases * (1 + rebalanceThreshold)))
@@ -241,7 +241,7 @@ func TestAllocatorSimpleRetrieval(t *testing.T) {
 	gossiputil.NewStoreGossiper(g).GossipStores(singleStore, t)
 	result, err := a.AllocateTarget(
 		context.Background(),
-		simpleZoneConfig.Constraints,
+		simpleZoneConfig,
 		[]roachpb.ReplicaDescriptor{},
 		firstRange,
 		false,
@@ -275,7 +275,7 @@ func TestAllocatorCorruptReplica(t *testing.T) {
 
 	result, err := a.AllocateTarget(
 		context.Background(),
-		simpleZoneConfig.Constraints,
+		simpleZoneConfig,
 		[]roachpb.ReplicaDescriptor{},
 		firstRange,
 		true,
@@ -295,7 +295,7 @@ func TestAllocatorNoAvailableDisks(t *testing.T) {
 	defer stopper.Stop()
 	result, err := a.AllocateTarget(
 		context.Background(),
-		simpleZoneConfig.Constraints,
+		simpleZoneConfig,
 		[]roachpb.ReplicaDescriptor{},
 		firstRange,
 		false,
@@ -317,7 +317,7 @@ func TestAllocatorTwoDatacenters(t *testing.T) {
 	ctx := context.Background()
 	result1, err := a.AllocateTarget(
 		ctx,
-		multiDCConfig.Constraints,
+		multiDCConfig,
 		[]roachpb.ReplicaDescriptor{},
 		firstRange,
 		false,
@@ -327,7 +327,7 @@ func TestAllocatorTwoDatacenters(t *testing.T) {
 	}
 	result2, err := a.AllocateTarget(
 		ctx,
-		multiDCConfig.Constraints,
+		multiDCConfig,
 		[]roachpb.ReplicaDescriptor{{
 			NodeID:  result1.Node.NodeID,
 			StoreID: result1.StoreID,
@@ -346,7 +346,7 @@ func TestAllocatorTwoDatacenters(t *testing.T) {
 	// Verify that no result is forthcoming if we already have a replica.
 	result3, err := a.AllocateTarget(
 		ctx,
-		multiDCConfig.Constraints,
+		multiDCConfig,
 		[]roachpb.ReplicaDescriptor{
 			{
 				NodeID:  result1.Node.NodeID,
@@ -373,10 +373,12 @@ func TestAllocatorExistingReplica(t *testing.T) {
 	gossiputil.NewStoreGossiper(g).GossipStores(sameDCStores, t)
 	result, err := a.AllocateTarget(
 		context.Background(),
-		config.Constraints{
-			Constraints: []config.Constraint{
-				{Value: "a"},
-				{Value: "hdd"},
+		config.ZoneConfig{
+			Constraints: config.Constraints{
+				Constraints: []config.Constraint{
+					{Value: "a"},
+					{Value: "hdd"},
+				},
 			},
 		},
 		[]roachpb.ReplicaDescriptor{
@@ -557,7 +559,7 @@ func TestAllocatorRelaxConstraints(t *testing.T) {
 			}
 			result, err := a.AllocateTarget(
 				context.Background(),
-				config.Constraints{Constraints: test.constraints},
+				config.ZoneConfig{Constraints: config.Constraints{Constraints: test.constraints}},
 				existing,
 				firstRange,
 				false,
@@ -629,7 +631,7 @@ func TestAllocatorRebalance(t *testing.T) {
 	for i := 0; i < 10; i++ {
 		result, err := a.RebalanceTarget(
 			ctx,
-			config.Constraints{},
+			config.ZoneConfig{},
 			[]roachpb.ReplicaDescriptor{{StoreID: 3}},
 			firstRange,
 		)
@@ -823,7 +825,7 @@ func TestAllocatorRebalanceByCount(t *testing.T) {
 	for i := 0; i < 10; i++ {
 		result, err := a.RebalanceTarget(
 			ctx,
-			config.Constraints{},
+			config.ZoneConfig{},
 			[]roachpb.ReplicaDescriptor{{StoreID: stores[0].StoreID}},
 			firstRange,
 		)
@@ -896,7 +898,7 @@ func TestAllocatorTransferLeaseTarget(t *testing.T) {
 		t.Run("", func(t *testing.T) {
 			target := a.TransferLeaseTarget(
 				context.Background(),
-				config.Constraints{},
+				config.ZoneConfig{},
 				c.existing,
 				c.leaseholder,
 				0,
@@ -949,7 +951,7 @@ func TestAllocatorTransferLeaseTargetMultiStore(t *testing.T) {
 		t.Run("", func(t *testing.T) {
 			target := a.TransferLeaseTarget(
 				context.Background(),
-				config.Constraints{},
+				config.ZoneConfig{},
 				existing,
 				c.leaseholder,
 				0,
@@ -1011,7 +1013,7 @@ func TestAllocatorShouldTransferLease(t *testing.T) {
 		t.Run("", func(t *testing.T) {
 			result := a.ShouldTransferLease(
 				context.Background(),
-				config.Constraints{},
+				config.ZoneConfig{},
 				c.existing,
 				c.leaseholder,
 				0,
@@ -1024,16 +1026,126 @@ func TestAllocatorShouldTransferLease(t *testing.T) {
 	}
 }
 
+// makeRegionStores returns the descriptors of stores in the given regions, the
+// i-th of which is store i+1 on node i+1. All of them have the given capacity.
+func makeRegionStores(
+	regions []string, capacity roachpb.StoreCapacity,
+) []*roachpb.StoreDescriptor {
+	var stores []*roachpb.StoreDescriptor
+	for i, region := range regions {
+		stores = append(stores, &roachpb.StoreDescriptor{
+			StoreID: roachpb.StoreID(i + 1),
+			Node: roachpb.NodeDescriptor{
+				NodeID:   roachpb.NodeID(i + 1),
+				Locality: roachpb.Locality{Tiers: []roachpb.Tier{{Key: "region", Value: region}}},
+			},
+			Capacity: capacity,
+		})
+	}
+	return stores
+}
+
+// makeRegionReplicas returns the descriptors of replicas on the given stores
+// of makeRegionStores. The IDs of their nodes and their replica IDs are the
+// store IDs.
+func makeRegionReplicas(storeIDs ...roachpb.StoreID) []roachpb.ReplicaDescriptor {
+	var r []roachpb.ReplicaDescriptor
+	for _, storeID := range storeIDs {
+		r = append(r, roachpb.ReplicaDescriptor{
+			NodeID:    roachpb.NodeID(storeID),
+			StoreID:   storeID,
+			ReplicaID: roachpb.ReplicaID(storeID),
+		})
+	}
+	return r
+}
+
+func TestAllocatorLeasePreferences(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	stopper, g, _, a, _ := createTestAllocator( /* deterministic */ true)
+	defer stopper.Stop()
+
+	// 4 stores with the same lease count, so that only the lease preferences
+	// cause lease transfers.
+	regions := []string{"us", "us", "eu", "ap"}
+	stores := makeRegionStores(regions, roachpb.StoreCapacity{LeaseCount: 10})
+	sg := gossiputil.NewStoreGossiper(g)
+	sg.GossipStores(stores, t)
+	replicas := makeRegionReplicas
+	preferences := func(shorthands ...string) []config.LeasePreference {
+		var prefs []config.LeasePreference
+		for _, short := range shorthands {
+			var c config.Constraint
+			if err := c.FromString(short); err != nil {
+				t.Fatal(err)
+			}
+			prefs = append(prefs, config.LeasePreference{Constraints: []config.Constraint{c}})
+		}
+		return prefs
+	}
+
+	testCases := []struct {
+		preferences    []config.LeasePreference
+		existing       []roachpb.ReplicaDescriptor
+		leaseholder    roachpb.StoreID
+		expectTransfer bool
+		expectTarget   roachpb.StoreID
+	}{
+		// Without preferences, the balanced lease counts don't call for a
+		// transfer.
+		{nil, replicas(1, 2, 3), 1, false, 0},
+		{preferences("+region=eu"), replicas(1, 2, 3), 1, true, 3},
+		{preferences("+region=eu"), replicas(1, 2, 3), 3, false, 0},
+		// The first preference that any replica satisfies is used.
+		{preferences("+region=eu", "+region=ap"), replicas(1, 2, 4), 1, true, 4},
+		{preferences("+region=eu", "+region=ap"), replicas(1, 2, 4), 4, false, 0},
+		{preferences("+region=ap", "+region=eu"), replicas(1, 3, 4), 3, true, 4},
+		// Preferences that no replica satisfies are ignored.
+		{preferences("+region=eu"), replicas(1, 2, 4), 1, false, 0},
+		{preferences("-region=us"), replicas(1, 2, 3), 2, true, 3},
+	}
+	for _, c := range testCases {
+		t.Run("", func(t *testing.T) {
+			zone := config.ZoneConfig{LeasePreferences: c.preferences}
+			result := a.ShouldTransferLease(
+				context.Background(),
+				zone,
+				c.existing,
+				c.leaseholder,
+				0,
+				nil, /* replicaStats */
+			)
+			if c.expectTransfer != result {
+				t.Errorf("expected ShouldTransferLease %v, but found %v", c.expectTransfer, result)
+			}
+			violating := a.leaseViolatesPreferences(zone, c.existing, c.leaseholder, 0)
+			if c.expectTransfer != violating {
+				t.Errorf("expected leaseViolatesPreferences %v, but found %v", c.expectTransfer, violating)
+			}
+			target := a.TransferLeaseTarget(
+				context.Background(),
+				zone,
+				c.existing,
+				c.leaseholder,
+				0,
+				nil,  /* replicaStats */
+				true, /* checkTransferLeaseSource */
+				true, /* checkCandidateFullness */
+			)
+			if c.expectTarget != target.StoreID {
+				t.Errorf("expected target %d, but found %d", c.expectTarget, target.StoreID)
+			}
+		})
+	}
+}
+
 // Test out the load-based lease transfer algorithm against a variety of
 // request distributions and inter-node latencies.
 func TestAllocatorTransferLeaseTargetLoadBased(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 
 	// TODO(a-robinson): Remove when load-based lease rebalancing is the default.
-	defer func(v bool) {
-		EnableLoadBasedLeaseRebalancing = v
-	}(EnableLoadBasedLeaseRebalancing)
-	EnableLoadBasedLeaseRebalancing = true
+	defer EnableLoadBasedLeaseRebalancing.Override(true)()
 
 	stopper, g, _, storePool, _ := createTestStorePool(
 		TestTimeUntilStoreDeadOff, true /* deterministic */, nodeStatusLive)
@@ -1183,7 +1295,7 @@ func TestAllocatorTransferLeaseTargetLoadBased(t *testing.T) {
 			})
 			target := a.TransferLeaseTarget(
 				context.Background(),
-				config.Constraints{},
+				config.ZoneConfig{},
 				existing,
 				c.leaseholder,
 				0,
@@ -1373,7 +1485,7 @@ func TestAllocatorRemoveTarget(t *testing.T) {
 
 	// Repeat this test 10 times, it should always be either store 2 or 3.
 	for i := 0; i < 10; i++ {
-		targetRepl, err := a.RemoveTarget(ctx, config.Constraints{}, replicas)
+		targetRepl, err := a.RemoveTarget(ctx, config.ZoneConfig{}, replicas)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -1384,6 +1496,116 @@ func TestAllocatorRemoveTarget(t *testing.T) {
 	}
 }
 
+func TestAllocatorReplicaConstraints(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	// 6 stores with the same range count in 3 regions.
+	regions := []string{"us", "us", "us", "eu", "eu", "ap"}
+	stores := makeRegionStores(
+		regions, roachpb.StoreCapacity{Capacity: 100, Available: 100, RangeCount: 10},
+	)
+	region := func(storeID roachpb.StoreID) string {
+		return regions[storeID-1]
+	}
+	replicas := makeRegionReplicas
+
+	// One replica in us and two in eu.
+	zone := config.ZoneConfig{
+		NumReplicas: 3,
+		ReplicaConstraints: config.ReplicaConstraints{
+			Constraints: []config.Constraints{
+				{
+					Constraints: []config.Constraint{
+						{Type: config.Constraint_REQUIRED, Key: "region", Value: "us"},
+					},
+					NumReplicas: 1,
+				},
+				{
+					Constraints: []config.Constraint{
+						{Type: config.Constraint_REQUIRED, Key: "region", Value: "eu"},
+					},
+					NumReplicas: 2,
+				},
+			},
+		},
+	}
+
+	stopper, g, _, a, _ := createTestAllocator( /* deterministic */ false)
+	defer stopper.Stop()
+	sg := gossiputil.NewStoreGossiper(g)
+	sg.GossipStores(stores, t)
+	ctx := context.Background()
+
+	allocateTestCases := []struct {
+		existing []roachpb.ReplicaDescriptor
+		expected string
+	}{
+		{replicas(1), "eu"},
+		{replicas(4, 5), "us"},
+		{replicas(1, 4), "eu"},
+		{replicas(1, 6), "eu"},
+	}
+	for _, c := range allocateTestCases {
+		t.Run("allocate", func(t *testing.T) {
+			target, err := a.AllocateTarget(ctx, zone, c.existing, firstRange, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r := region(target.StoreID); r != c.expected {
+				t.Fatalf("expected a target in %s, but got s%d in %s", c.expected, target.StoreID, r)
+			}
+		})
+	}
+
+	removeTestCases := []struct {
+		existing []roachpb.ReplicaDescriptor
+		expected string
+	}{
+		// The us constraint is over-satisfied.
+		{replicas(1, 2, 4, 5), "us"},
+		// Replicas that are required by the constraints are kept.
+		{replicas(1, 4, 5, 6), "ap"},
+	}
+	for _, c := range removeTestCases {
+		t.Run("remove", func(t *testing.T) {
+			target, err := a.RemoveTarget(ctx, zone, c.existing)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r := region(target.StoreID); r != c.expected {
+				t.Fatalf("expected a target in %s, but got s%d in %s", c.expected, target.StoreID, r)
+			}
+		})
+	}
+
+	rebalanceTestCases := []struct {
+		existing []roachpb.ReplicaDescriptor
+		expected string
+	}{
+		{replicas(1, 2, 4), "eu"},
+		{replicas(4, 5, 6), "us"},
+		// The constraints are satisfied and the range counts are balanced.
+		{replicas(1, 4, 5), ""},
+	}
+	for _, c := range rebalanceTestCases {
+		t.Run("rebalance", func(t *testing.T) {
+			target, err := a.RebalanceTarget(ctx, zone, c.existing, firstRange)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if target == nil {
+				if c.expected != "" {
+					t.Fatalf("expected a target in %s, but got none", c.expected)
+				}
+				return
+			}
+			if r := region(target.StoreID); r != c.expected {
+				t.Fatalf("expected a target in %q, but got s%d in %s", c.expected, target.StoreID, r)
+			}
+		})
+	}
+}
+
 func TestAllocatorComputeAction(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 
@@ -1845,7 +2067,7 @@ func TestAllocatorThrottled(t *testing.T) {
 	// First test to make sure we would send the replica to purgatory.
 	_, err := a.AllocateTarget(
 		ctx,
-		simpleZoneConfig.Constraints,
+		simpleZoneConfig,
 		[]roachpb.ReplicaDescriptor{},
 		firstRange,
 		false,
@@ -1858,7 +2080,7 @@ func TestAllocatorThrottled(t *testing.T) {
 	gossiputil.NewStoreGossiper(g).GossipStores(singleStore, t)
 	result, err := a.AllocateTarget(
 		ctx,
-		simpleZoneConfig.Constraints,
+		simpleZoneConfig,
 		[]roachpb.ReplicaDescriptor{},
 		firstRange,
 		false,
@@ -1881,7 +2103,7 @@ func TestAllocatorThrottled(t *testing.T) {
 	a.storePool.detailsMu.Unlock()
 	_, err = a.AllocateTarget(
 		ctx,
-		simpleZoneConfig.Constraints,
+		simpleZoneConfig,
 		[]roachpb.ReplicaDescriptor{},
 		firstRange,
 		false,
@@ -2063,15 +2285,17 @@ func TestAllocatorRebalanceAway(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.constraint.String(), func(t *testing.T) {
-			constraints := config.Constraints{
-				Constraints: []config.Constraint{
-					tc.constraint,
+			zone := config.ZoneConfig{
+				Constraints: config.Constraints{
+					Constraints: []config.Constraint{
+						tc.constraint,
+					},
 				},
 			}
 
 			actual, err := a.RebalanceTarget(
 				ctx,
-				constraints,
+				zone,
 				existingReplicas,
 				firstRange,
 			)
@@ -2186,7 +2410,7 @@ func Example_rebalancing() {
 			ts := &testStores[j]
 			target, err := alloc.RebalanceTarget(
 				context.Background(),
-				config.Constraints{},
+				config.ZoneConfig{},
 				[]roachpb.ReplicaDescriptor{{NodeID: ts.Node.NodeID, StoreID: ts.StoreID}},
 				firstRange,
 			)
@@ -34,6 +34,7 @@
 #include "cockroach/pkg/storage/engine/enginepb/rocksdb.pb.h"
 #include "cockroach/pkg/storage/engine/enginepb/mvcc.pb.h"
 #include "db.h"
+#include "db_internal.h"
 #include "encoding.h"
 #include "eventlistener.h"
 
@@ -70,18 +71,21 @@ struct DBEngine {
 
 struct DBImpl : public DBEngine {
   std::unique_ptr<rocksdb::Env> memenv;
+  std::unique_ptr<rocksdb::Env> hook_env;
   std::unique_ptr<rocksdb::DB> rep_deleter;
   rocksdb::ReadOptions const read_opts;
   std::shared_ptr<rocksdb::Cache> block_cache;
   std::shared_ptr<DBEventListener> event_listener;
 
-  // Construct a new DBImpl from the specified DB and Env. Both the DB
-  // and Env will be deleted when the DBImpl is deleted. It is ok to
-  // pass NULL for the Env.
-  DBImpl(rocksdb::DB* r, rocksdb::Env* m, std::shared_ptr<rocksdb::Cache> bc,
+  // Construct a new DBImpl from the specified DB and Envs. The DB and
+  // Envs will be deleted when the DBImpl is deleted (the DB first, as
+  // it uses the Envs). It is ok to pass NULL for either Env.
+  DBImpl(rocksdb::DB* r, rocksdb::Env* m, rocksdb::Env* h,
+    std::shared_ptr<rocksdb::Cache> bc,
     std::shared_ptr<DBEventListener> event_listener)
       : DBEngine(r),
         memenv(m),
+        hook_env(h),
         rep_deleter(r),
         block_cache(bc),
         event_listener(event_listener) {
@@ -1358,6 +1362,7 @@ DBSSTable* DBEngine::GetSSTables(int* n) {
       DBString str = ToDBString(tmp);
       tables[i].end_key.key = DBSlice{str.data, str.len};
     }
+    tables[i].name = ToDBString(metadata[i].name);
   }
   return tables;
 }
@@ -1614,6 +1619,13 @@ rocksdb::Options DBMakeOptions(DBOptions db_opts) {
   return options;
 }
 
+// open_hook is the hook registered by DBSetOpenHook, if any.
+DBOpenHook open_hook = nullptr;
+
+void DBSetOpenHook(DBOpenHook hook) {
+  open_hook = hook;
+}
+
 DBStatus DBOpen(DBEngine **db, DBSlice dir, DBOptions db_opts) {
   rocksdb::Options options = DBMakeOptions(db_opts);
 
@@ -1627,12 +1639,25 @@ DBStatus DBOpen(DBEngine **db, DBSlice dir, DBOptions db_opts) {
     options.env = memenv.get();
   }
 
+  std::unique_ptr<rocksdb::Env> hook_env;
+  if (db_opts.extra_options.len != 0) {
+    if (open_hook == nullptr) {
+      return FmtStatus("extra options are only supported in CCL builds");
+    }
+    rocksdb::Env* env = nullptr;
+    DBStatus hook_status = open_hook(ToString(dir), db_opts, &options, &env);
+    hook_env.reset(env);
+    if (hook_status.data != NULL) {
+      return hook_status;
+    }
+  }
+
   rocksdb::DB *db_ptr;
   rocksdb::Status status = rocksdb::DB::Open(options, ToString(dir), &db_ptr);
   if (!status.ok()) {
     return ToDBStatus(status);
   }
-  *db = new DBImpl(db_ptr, memenv.release(),
+  *db = new DBImpl(db_ptr, memenv.release(), hook_env.release(),
       db_opts.cache != nullptr ? db_opts.cache->rep : nullptr,
       event_listener);
   return kSuccess;
@@ -1664,6 +1689,18 @@ DBStatus DBCompact(DBEngine* db) {
   return ToDBStatus(db->rep->CompactRange(options, NULL, NULL));
 }
 
+DBStatus DBCompactRange(DBEngine* db, DBKey start, DBKey end) {
+  rocksdb::CompactRangeOptions options;
+  // See DBCompact for why the bottom level is recompacted. Callers of
+  // DBCompactRange typically want the sstables in the range rewritten.
+  options.bottommost_level_compaction = rocksdb::BottommostLevelCompaction::kForce;
+  const std::string start_key = EncodeKey(start);
+  const std::string end_key = EncodeKey(end);
+  const rocksdb::Slice start_slice(start_key);
+  const rocksdb::Slice end_slice(end_key);
+  return ToDBStatus(db->rep->CompactRange(options, &start_slice, &end_slice));
+}
+
 DBStatus DBImpl::Put(DBKey key, DBSlice value) {
   rocksdb::WriteOptions options;
   return ToDBStatus(rep->Put(options, EncodeKey(key), ToSlice(value)));
@@ -2274,6 +2311,48 @@ DBStatus DBEngineAddFile(DBEngine* db, DBSlice path) {
   return kSuccess;
 }
 
+DBStatus DBIngestExternalFile(DBEngine* db, DBSlice path, bool move_file) {
+  const std::vector<std::string> paths = { ToString(path) };
+  rocksdb::IngestExternalFileOptions ifo;
+  ifo.move_files = move_file;
+  ifo.snapshot_consistency = true;
+  // The ingested file may overlap keys written through the memtable (for
+  // instance the range's MVCCStats), so RocksDB must be allowed to assign it
+  // a global sequence number and to flush the memtable first.
+  ifo.allow_global_seqno = true;
+  ifo.allow_blocking_flush = true;
+
+  rocksdb::Status status = db->rep->IngestExternalFile(
+      db->rep->DefaultColumnFamily(), paths, ifo);
+  if (!status.ok()) {
+    return ToDBStatus(status);
+  }
+  return kSuccess;
+}
+
+DBStatus DBEnvWriteFile(DBEngine* db, DBSlice path, DBSlice contents) {
+  rocksdb::Env* env = db->rep->GetEnv();
+  const rocksdb::EnvOptions soptions;
+  std::unique_ptr<rocksdb::WritableFile> file;
+  rocksdb::Status s = env->NewWritableFile(ToString(path), &file, soptions);
+  if (!s.ok()) {
+    return ToDBStatus(s);
+  }
+  s = file->Append(ToSlice(contents));
+  if (!s.ok()) {
+    return ToDBStatus(s);
+  }
+  s = file->Sync();
+  if (!s.ok()) {
+    return ToDBStatus(s);
+  }
+  return ToDBStatus(file->Close());
+}
+
+DBStatus DBEnvDeleteFile(DBEngine* db, DBSlice path) {
+  return ToDBStatus(db->rep->GetEnv()->DeleteFile(ToString(path)));
+}
+
 struct DBSstFileWriter {
   std::unique_ptr<rocksdb::Options> options;
   rocksdb::SstFileWriter rep;
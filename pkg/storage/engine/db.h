@@ -69,6 +69,11 @@ typedef struct {
   bool logging_enabled;
   int num_cpu;
   int max_open_files;
+  // extra_options is an opaque blob passed to the open hook registered by
+  // DBSetOpenHook (see db_internal.h). It is used by CCL code to configure
+  // encryption at rest. Opening fails if it is non-empty and no hook is
+  // registered.
+  DBSlice extra_options;
 } DBOptions;
 
 // Create a new cache with the specified size.
@@ -101,6 +106,11 @@ DBStatus DBFlush(DBEngine* db);
 // Forces an immediate compaction over all keys.
 DBStatus DBCompact(DBEngine* db);
 
+// Forces an immediate compaction over keys in the specified range
+// [start, end] (both inclusive). Note that the compaction may extend beyond
+// the range to cover the full extent of the overlapping sstables.
+DBStatus DBCompactRange(DBEngine* db, DBKey start, DBKey end);
+
 // Sets the database entry for "key" to "value".
 DBStatus DBPut(DBEngine* db, DBKey key, DBSlice value);
 
@@ -236,11 +246,12 @@ typedef struct {
   uint64_t size;
   DBKey start_key;
   DBKey end_key;
+  DBString name;
 } DBSSTable;
 
 // Retrieve stats about all of the live sstables. Note that the tables
-// array must be freed along with the start_key and end_key of each
-// table.
+// array must be freed along with the start_key, end_key and name of
+// each table.
 DBSSTable* DBGetSSTables(DBEngine* db, int* n);
 
 // DBGetUserProperties fetches the user properties stored in each sstable's
@@ -252,6 +263,19 @@ DBString DBGetUserProperties(DBEngine* db);
 // documentation on `AddFile` for the various restrictions on what can be added.
 DBStatus DBEngineAddFile(DBEngine* db, DBSlice path);
 
+// Ingests the sstable at the given path into the database. Unlike
+// DBEngineAddFile, the file's keys may overlap existing data, in which case
+// RocksDB assigns the file a global sequence number. If move_file is true the
+// file is hard linked into the database and the original path removed.
+DBStatus DBIngestExternalFile(DBEngine* db, DBSlice path, bool move_file);
+
+// Writes the given contents to a file at the given path using the database's
+// Env, which may be an in-memory or encrypted Env.
+DBStatus DBEnvWriteFile(DBEngine* db, DBSlice path, DBSlice contents);
+
+// Deletes the file at the given path using the database's Env.
+DBStatus DBEnvDeleteFile(DBEngine* db, DBSlice path);
+
 typedef struct DBSstFileWriter DBSstFileWriter;
 
 // Creates a new SstFileWriter with the default configuration.
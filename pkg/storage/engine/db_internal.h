@@ -17,6 +17,8 @@
 #include "db.h"
 #include "rocksdb/iterator.h"
 #include "rocksdb/comparator.h"
+#include "rocksdb/env.h"
+#include "rocksdb/options.h"
 #include "rocksdb/write_batch.h"
 #include "rocksdb/write_batch_base.h"
 
@@ -46,3 +48,14 @@ const ::rocksdb::Comparator* CockroachComparator();
 // Stats are only computed for keys between the given range.
 MVCCStatsResult MVCCComputeStatsInternal(
     ::rocksdb::Iterator* const iter_rep, DBKey start, DBKey end, int64_t now_nanos);
+
+// DBOpenHook is called by DBOpen when DBOptions.extra_options is non-empty,
+// before the database is opened. It may modify the RocksDB options, in
+// particular to replace options->env. An Env returned in *env is owned by the
+// engine and deleted after the database is closed.
+typedef DBStatus (*DBOpenHook)(const std::string& db_dir, const DBOptions db_opts,
+                               rocksdb::Options* options, rocksdb::Env** env);
+
+// DBSetOpenHook registers the hook called by DBOpen. It is meant to be called
+// once during initialization.
+void DBSetOpenHook(DBOpenHook hook);
@@ -177,6 +177,9 @@ type Engine interface {
 	Attrs() roachpb.Attributes
 	// Capacity returns capacity details for the engine's available storage.
 	Capacity() (roachpb.StoreCapacity, error)
+	// CompactRange forces compaction over the specified range of keys (both
+	// inclusive).
+	CompactRange(start, end MVCCKey) error
 	// Flush causes the engine to write all in-memory data to disk
 	// immediately.
 	Flush() error
@@ -184,6 +187,13 @@ type Engine interface {
 	GetStats() (*Stats, error)
 	// GetTempDir returns a path under which tempdirs or tempfiles can be created.
 	GetTempDir() string
+	// GetAuxiliaryDir returns a path under which files not managed by the
+	// engine but belonging to the store can be kept. It is empty for
+	// in-memory engines.
+	GetAuxiliaryDir() string
+	// IngestExternalData links the SSTable encoded in data into the engine.
+	// Keys in the SSTable shadow existing versions of the same keys.
+	IngestExternalData(data []byte) error
 	// NewBatch returns a new instance of a batched engine which wraps
 	// this engine. Batched engines accumulate all mutations and apply
 	// them atomically on a call to Commit().
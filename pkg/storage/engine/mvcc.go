@@ -1771,6 +1771,107 @@ func MVCCIterate(
 	return intents, wiErr
 }
 
+// MVCCScanChanges returns every MVCC version written to the key range
+// [key,endKey) at a timestamp in the interval (startTime,endTime], including
+// deletion tombstones (which are returned as values without any bytes). The
+// returned key/values are sorted by key and, for each key, by ascending
+// timestamp. Inline (non-MVCC) values are skipped.
+//
+// Since a change can't be reported until it is committed, any intent with a
+// timestamp at or below endTime causes a WriteIntentError containing all such
+// intents in the key range to be returned.
+func MVCCScanChanges(
+	ctx context.Context, engine Reader, key, endKey roachpb.Key, startTime, endTime hlc.Timestamp,
+) ([]roachpb.KeyValue, error) {
+	if len(endKey) == 0 {
+		return nil, emptyKeyError()
+	}
+
+	iter := engine.NewIterator(false)
+	defer iter.Close()
+
+	encEndKey := MakeMVCCMetadataKey(endKey)
+	var meta enginepb.MVCCMetadata
+	var kvs []roachpb.KeyValue
+	var intents []roachpb.Intent
+	// keyStart is the index in kvs of the first version of the current key and
+	// intentTS the timestamp of its provisional value, if any.
+	var curKey roachpb.Key
+	var keyStart int
+	var intentTS hlc.Timestamp
+
+	// reverseVersions puts the versions of the current key, which the iterator
+	// visits newest first, into ascending timestamp order.
+	reverseVersions := func() {
+		for i, j := keyStart, len(kvs)-1; i < j; i, j = i+1, j-1 {
+			kvs[i], kvs[j] = kvs[j], kvs[i]
+		}
+	}
+
+	for iter.Seek(MakeMVCCMetadataKey(key)); ; {
+		if ok, err := iter.Valid(); err != nil {
+			return nil, err
+		} else if !ok {
+			break
+		}
+		unsafeKey := iter.UnsafeKey()
+		if !unsafeKey.Less(encEndKey) {
+			break
+		}
+		if !unsafeKey.Key.Equal(curKey) {
+			reverseVersions()
+			curKey = append(curKey[:0], unsafeKey.Key...)
+			keyStart = len(kvs)
+			intentTS = hlc.Timestamp{}
+		}
+
+		if !unsafeKey.IsValue() {
+			if err := iter.ValueProto(&meta); err != nil {
+				return nil, err
+			}
+			if meta.IsInline() {
+				iter.Next()
+				continue
+			}
+			if meta.Txn != nil {
+				intentTS = meta.Timestamp
+				if !endTime.Less(meta.Timestamp) {
+					intents = append(intents, roachpb.Intent{
+						Span:   roachpb.Span{Key: iter.Key().Key},
+						Status: roachpb.PENDING,
+						Txn:    *meta.Txn,
+					})
+				}
+			}
+			iter.Next()
+			continue
+		}
+
+		ts := unsafeKey.Timestamp
+		if !startTime.Less(ts) {
+			// All remaining versions of this key are at or below startTime.
+			iter.NextKey()
+			continue
+		}
+		if ts != intentTS && !endTime.Less(ts) {
+			kvs = append(kvs, roachpb.KeyValue{
+				Key: iter.Key().Key,
+				Value: roachpb.Value{
+					RawBytes:  iter.Value(),
+					Timestamp: ts,
+				},
+			})
+		}
+		iter.Next()
+	}
+	reverseVersions()
+
+	if len(intents) > 0 {
+		return nil, &roachpb.WriteIntentError{Intents: intents}
+	}
+	return kvs, nil
+}
+
 // MVCCResolveWriteIntent either commits or aborts (rolls back) an
 // extant write intent for a given txn according to commit parameter.
 // ResolveWriteIntent will skip write intents of other txns.
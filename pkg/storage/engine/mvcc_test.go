@@ -1320,6 +1320,94 @@ func TestMVCCScanInTxn(t *testing.T) {
 	}
 }
 
+// TestMVCCScanChanges verifies that MVCCScanChanges returns all the versions
+// (including deletions) in its time interval and refuses to return results
+// while an intent is present in the interval.
+func TestMVCCScanChanges(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	engine := createTestEngine()
+	defer engine.Close()
+	ctx := context.Background()
+
+	ts1, ts2, ts3, ts4 := hlc.Timestamp{WallTime: 1}, hlc.Timestamp{WallTime: 2},
+		hlc.Timestamp{WallTime: 3}, hlc.Timestamp{WallTime: 4}
+	if err := MVCCPut(ctx, engine, nil, testKey1, ts1, value1, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := MVCCPut(ctx, engine, nil, testKey1, ts2, value2, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := MVCCPut(ctx, engine, nil, testKey2, ts2, value3, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := MVCCDelete(ctx, engine, nil, testKey1, ts3, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := MVCCPut(ctx, engine, nil, []byte("inline"), hlc.Timestamp{}, value4, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	type change struct {
+		key   roachpb.Key
+		value []byte
+		ts    hlc.Timestamp
+	}
+	testCases := []struct {
+		start, end hlc.Timestamp
+		expected   []change
+	}{
+		{hlc.Timestamp{}, ts3, []change{
+			{testKey1, value1.RawBytes, ts1},
+			{testKey1, value2.RawBytes, ts2},
+			{testKey1, nil, ts3},
+			{testKey2, value3.RawBytes, ts2},
+		}},
+		{ts1, ts2, []change{
+			{testKey1, value2.RawBytes, ts2},
+			{testKey2, value3.RawBytes, ts2},
+		}},
+		{ts2, ts3, []change{
+			{testKey1, nil, ts3},
+		}},
+		{ts3, ts4, nil},
+	}
+	for i, c := range testCases {
+		kvs, err := MVCCScanChanges(ctx, engine, keyMin, keyMax, c.start, c.end)
+		if err != nil {
+			t.Fatalf("%d: %s", i, err)
+		}
+		if len(kvs) != len(c.expected) {
+			t.Fatalf("%d: expected %d changes, got %d: %v", i, len(c.expected), len(kvs), kvs)
+		}
+		for j, kv := range kvs {
+			exp := c.expected[j]
+			if !kv.Key.Equal(exp.key) || !bytes.Equal(kv.Value.RawBytes, exp.value) ||
+				kv.Value.Timestamp != exp.ts {
+				t.Errorf("%d: expected change %d to be %+v, got %+v", i, j, exp, kv)
+			}
+		}
+	}
+
+	// An intent at or below the end of the interval prevents the scan, while
+	// one above it is ignored along with its provisional value.
+	if err := MVCCPut(ctx, engine, nil, testKey3, ts4, value1, makeTxn(*txn1, ts4)); err != nil {
+		t.Fatal(err)
+	}
+	kvs, err := MVCCScanChanges(ctx, engine, keyMin, keyMax, ts3, ts3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kvs) != 0 {
+		t.Fatalf("expected no changes, got %v", kvs)
+	}
+	_, err = MVCCScanChanges(ctx, engine, keyMin, keyMax, ts3, ts4)
+	if wiErr, ok := err.(*roachpb.WriteIntentError); !ok {
+		t.Fatalf("expected WriteIntentError, got %v", err)
+	} else if len(wiErr.Intents) != 1 || !wiErr.Intents[0].Key.Equal(testKey3) {
+		t.Fatalf("unexpected intents %v", wiErr.Intents)
+	}
+}
+
 // TestMVCCScanInconsistent writes several values, some as intents and
 // verifies that the scan sees only the committed versions.
 func TestMVCCScanInconsistent(t *testing.T) {
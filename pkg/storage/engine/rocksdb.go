@@ -46,6 +46,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
 	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/cockroach/pkg/util/uuid"
 )
 
 // TODO(tamird): why does rocksdb not link jemalloc,snappy statically?
@@ -109,6 +110,7 @@ type SSTableInfo struct {
 	Size  int64
 	Start MVCCKey
 	End   MVCCKey
+	Name  string
 }
 
 // SSTableInfos is a slice of SSTableInfo structures.
@@ -296,8 +298,16 @@ type RocksDB struct {
 	cache        RocksDBCache       // Shared cache.
 	maxSize      int64              // Used for calculating rebalancing and free space.
 	maxOpenFiles int                // The maximum number of open files this instance will use.
+	extraOptions []byte             // Opaque options passed to the C++ open hook.
 	deallocated  chan struct{}      // Closed when the underlying handle is deallocated.
 
+	// background, if set, is the task returned by the encryption hook. It is
+	// run for the lifetime of the engine and stopped by Close.
+	background struct {
+		stop chan struct{}
+		done chan struct{}
+	}
+
 	commit struct {
 		syncutil.Mutex
 		cond        *sync.Cond
@@ -319,6 +329,70 @@ var _ Engine = &RocksDB{}
 // needed.
 func NewRocksDB(
 	attrs roachpb.Attributes, dir string, cache RocksDBCache, maxSize int64, maxOpenFiles int,
+) (*RocksDB, error) {
+	return newRocksDB(attrs, dir, cache, maxSize, maxOpenFiles, nil /* extraOptions */)
+}
+
+// EncryptionHook is called when opening an encrypted RocksDB instance. It is
+// passed the data directory and the paths of the active and old store key
+// files (see base.StoreSpec), and returns the options to pass to the C++ open
+// hook registered with DBSetOpenHook. It may also return a function that is
+// run in its own goroutine for the lifetime of the engine, which must return
+// once stop is closed.
+type EncryptionHook func(dir, keyPath, oldKeyPath string) (
+	extraOptions []byte, background func(r *RocksDB, stop <-chan struct{}), err error,
+)
+
+var encryptionHook EncryptionHook
+
+// SetEncryptionHook allows setting the hook used to open encrypted RocksDB
+// instances. Only allowed to be called by Init.
+func SetEncryptionHook(hook EncryptionHook) {
+	// This is safe if SetEncryptionHook is only called at init time.
+	encryptionHook = hook
+}
+
+// NewEncryptedRocksDB is like NewRocksDB, but the data is encrypted at rest
+// with the store key in the file at keyPath. If non-empty, oldKeyPath is the
+// previous store key, which is needed to read data written before a key
+// rotation. Encryption is only supported in CCL binaries.
+func NewEncryptedRocksDB(
+	attrs roachpb.Attributes,
+	dir string,
+	cache RocksDBCache,
+	maxSize int64,
+	maxOpenFiles int,
+	keyPath, oldKeyPath string,
+) (*RocksDB, error) {
+	if encryptionHook == nil {
+		return nil, errors.New("encryption at rest requires a CCL binary")
+	}
+	extraOptions, background, err := encryptionHook(dir, keyPath, oldKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	r, err := newRocksDB(attrs, dir, cache, maxSize, maxOpenFiles, extraOptions)
+	if err != nil {
+		return nil, err
+	}
+	if background != nil {
+		r.background.stop = make(chan struct{})
+		r.background.done = make(chan struct{})
+		go func() {
+			defer close(r.background.done)
+			background(r, r.background.stop)
+		}()
+	}
+	return r, nil
+}
+
+func newRocksDB(
+	attrs roachpb.Attributes,
+	dir string,
+	cache RocksDBCache,
+	maxSize int64,
+	maxOpenFiles int,
+	extraOptions []byte,
 ) (*RocksDB, error) {
 	if dir == "" {
 		panic("dir must be non-empty")
@@ -330,6 +404,7 @@ func NewRocksDB(
 		cache:        cache.ref(),
 		maxSize:      maxSize,
 		maxOpenFiles: maxOpenFiles,
+		extraOptions: extraOptions,
 		deallocated:  make(chan struct{}),
 	}
 
@@ -410,6 +485,7 @@ func (r *RocksDB) open() error {
 			logging_enabled:   C.bool(log.V(3)),
 			num_cpu:           C.int(runtime.NumCPU()),
 			max_open_files:    C.int(r.maxOpenFiles),
+			extra_options:     goToCSlice(r.extraOptions),
 		})
 	if err := statusToError(status); err != nil {
 		return errors.Errorf("could not open rocksdb instance: %s", err)
@@ -445,6 +521,10 @@ func (r *RocksDB) Close() {
 	} else {
 		log.Infof(context.TODO(), "closing rocksdb instance at %q", r.dir)
 	}
+	if r.background.stop != nil {
+		close(r.background.stop)
+		<-r.background.done
+	}
 	if r.rdb != nil {
 		C.DBClose(r.rdb)
 		r.rdb = nil
@@ -601,6 +681,12 @@ func (r *RocksDB) Compact() error {
 	return statusToError(C.DBCompact(r.rdb))
 }
 
+// CompactRange forces compaction over the specified range of keys (both
+// inclusive) in the database.
+func (r *RocksDB) CompactRange(start, end MVCCKey) error {
+	return statusToError(C.DBCompactRange(r.rdb, goToCKey(start), goToCKey(end)))
+}
+
 // Destroy destroys the underlying filesystem data associated with the database.
 func (r *RocksDB) Destroy() error {
 	return statusToError(C.DBDestroy(goToCSlice([]byte(r.dir))))
@@ -661,6 +747,7 @@ func (r *RocksDB) GetSSTables() SSTableInfos {
 		r.Size = int64(tv.size)
 		r.Start = cToGoKey(tv.start_key)
 		r.End = cToGoKey(tv.end_key)
+		r.Name = string(cStringToGoBytes(tv.name))
 		if ptr := tv.start_key.key.data; ptr != nil {
 			C.free(unsafe.Pointer(ptr))
 		}
@@ -1807,6 +1894,36 @@ func (r *RocksDB) GetTempDir() string {
 	return r.tempDir
 }
 
+// GetAuxiliaryDir returns a path under which files belonging to the store
+// but not managed by RocksDB can be kept. In-memory engines have no such
+// directory and return an empty string.
+func (r *RocksDB) GetAuxiliaryDir() string {
+	if r.dir == "" {
+		return ""
+	}
+	return filepath.Join(r.dir, "auxiliary")
+}
+
+// IngestExternalData links the SSTable encoded in data into the engine. The
+// data is written to a temporary file through the engine's Env (so that it
+// also works for in-memory and encrypted engines) which is then ingested.
+// Keys in the SSTable shadow any existing versions of the same keys.
+func (r *RocksDB) IngestExternalData(data []byte) error {
+	path := filepath.Join(r.tempDir, fmt.Sprintf("ingest-%s.sst", uuid.MakeV4()))
+	if err := statusToError(C.DBEnvWriteFile(r.rdb, goToCSlice([]byte(path)), goToCSlice(data))); err != nil {
+		return err
+	}
+	// On-disk engines hard link the file into the database, which removes the
+	// temporary file once it has been ingested. In-memory Envs don't support
+	// links, so the file is copied and removed afterwards.
+	move := r.dir != ""
+	err := statusToError(C.DBIngestExternalFile(r.rdb, goToCSlice([]byte(path)), C.bool(move)))
+	if !move || err != nil {
+		_ = statusToError(C.DBEnvDeleteFile(r.rdb, goToCSlice([]byte(path))))
+	}
+	return err
+}
+
 // SetTempDir allows overriding the tempdir returned by GetTempDir.
 func (r *RocksDB) SetTempDir(d string) error {
 	if err := os.MkdirAll(d, 0755); err != nil {
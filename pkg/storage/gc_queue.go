@@ -188,7 +188,7 @@ func processLocalKeyRange(
 
 		// The transaction record should be considered for removal.
 		switch txn.Status {
-		case roachpb.PENDING:
+		case roachpb.PENDING, roachpb.STAGING:
 			// Marked as running, so we need to push it to abort it but won't
 			// try to GC it in this cycle (for convenience).
 			// TODO(tschottdorf): refactor so that we can GC PENDING entries
@@ -590,7 +590,7 @@ func RunGC(
 	var wg sync.WaitGroup
 	sem := make(chan struct{}, gcTaskLimit)
 	for _, txn := range txnMap {
-		if txn.Status != roachpb.PENDING {
+		if txn.Status.IsFinalized() {
 			continue
 		}
 		wg.Add(1)
@@ -610,7 +610,7 @@ func RunGC(
 	// Resolve all intents.
 	var intents []roachpb.Intent
 	for txnID, txn := range txnMap {
-		if txn.Status != roachpb.PENDING {
+		if txn.Status.IsFinalized() {
 			for _, intent := range intentSpanMap[txnID] {
 				intents = append(intents, roachpb.Intent{Span: intent, Status: txn.Status, Txn: txn.TxnMeta})
 			}
@@ -667,4 +667,12 @@ func pushTxn(
 	br := b.RawResponse()
 	// Update the supplied txn on successful push.
 	*txn = br.Responses[0].GetInner().(*roachpb.PushTxnResponse).PusheeTxn
+	if txn.Status == roachpb.STAGING {
+		recovered, err := recoverTxn(ctx, db, txn)
+		if err != nil {
+			log.Warningf(ctx, "recovery of txn %s failed: %s", txn, err)
+			return
+		}
+		*txn = *recovered
+	}
 }
@@ -218,6 +218,18 @@ func (ir *intentResolver) maybePushTransactions(
 	pushedTxns := map[uuid.UUID]roachpb.Transaction{}
 	for _, resp := range br.Responses {
 		txn := resp.GetInner().(*roachpb.PushTxnResponse).PusheeTxn
+		if txn.Status == roachpb.STAGING {
+			// The pushee's coordinator may have failed while committing it in
+			// parallel with its final writes; determine its outcome.
+			recovered, err := recoverTxn(ctx, ir.store.db, &txn)
+			if err != nil {
+				return nil, roachpb.NewError(err)
+			}
+			if !recovered.Status.IsFinalized() {
+				return nil, roachpb.NewError(roachpb.NewTransactionPushError(*recovered))
+			}
+			txn = *recovered
+		}
 		if _, ok := pushedTxns[*txn.ID]; ok {
 			panic(fmt.Sprintf("have two PushTxn responses for %s", txn.ID))
 		}
@@ -105,6 +105,9 @@ var (
 	metaLeaseEpochCount = metric.Metadata{
 		Name: "leases.epoch",
 		Help: "Number of replicas using epoch-based leases"}
+	metaLeasePreferencesViolatingCount = metric.Metadata{
+		Name: "leases.preferences.violating",
+		Help: "Number of replicas holding a lease that violates the lease preferences of their zone"}
 
 	// Storage metrics.
 	metaLiveBytes = metric.Metadata{
@@ -485,6 +488,10 @@ type StoreMetrics struct {
 	LeaseTransferErrorCount   *metric.Counter
 	LeaseExpirationCount      *metric.Gauge
 	LeaseEpochCount           *metric.Gauge
+	// LeasePreferencesViolatingCount counts the leases held by this store
+	// although another live replica of the range satisfies the lease
+	// preferences of its zone better.
+	LeasePreferencesViolatingCount *metric.Gauge
 
 	// Storage metrics.
 	LiveBytes       *metric.Gauge
@@ -667,6 +674,8 @@ func newStoreMetrics(histogramWindow time.Duration) *StoreMetrics {
 		LeaseExpirationCount:      metric.NewGauge(metaLeaseExpirationCount),
 		LeaseEpochCount:           metric.NewGauge(metaLeaseEpochCount),
 
+		LeasePreferencesViolatingCount: metric.NewGauge(metaLeasePreferencesViolatingCount),
+
 		// Storage metrics.
 		LiveBytes:       metric.NewGauge(metaLiveBytes),
 		KeyBytes:        metric.NewGauge(metaKeyBytes),
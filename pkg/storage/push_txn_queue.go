@@ -52,8 +52,9 @@ func shouldPushImmediately(req *roachpb.PushTxnRequest) bool {
 // fulfilled by the current transaction state. This may be true
 // for transactions with pushed timestamps.
 func isPushed(req *roachpb.PushTxnRequest, txn *roachpb.Transaction) bool {
-	return (txn.Status != roachpb.PENDING ||
-		(req.PushType == roachpb.PUSH_TIMESTAMP && req.PushTo.Less(txn.Timestamp)))
+	return txn.Status.IsFinalized() ||
+		(txn.Status == roachpb.PENDING &&
+			req.PushType == roachpb.PUSH_TIMESTAMP && req.PushTo.Less(txn.Timestamp))
 }
 
 func txnExpiration(txn *roachpb.Transaction) hlc.Timestamp {
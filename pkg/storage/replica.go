@@ -230,6 +230,15 @@ type Replica struct {
 	// state. Requires Replica.raftMu is held.
 	stateLoader replicaStateLoader
 
+	// sideloaded holds the payloads of sideloaded raft commands, which are
+	// stored outside of the raft log. It is set at creation time and safe for
+	// concurrent use; it is appended to with Replica.raftMu held.
+	sideloaded sideloadStorage
+
+	// rangeFeeds tracks the keys written to the replica on behalf of the
+	// range feeds it is serving. See Replica.RangeFeed.
+	rangeFeeds rangeFeedRegistry
+
 	cmdQMu struct {
 		// Protects all fields in the cmdQMu struct.
 		//
@@ -501,6 +510,7 @@ func newReplica(rangeID roachpb.RangeID, store *Store) *Replica {
 		AmbientContext: store.cfg.AmbientCtx,
 		RangeID:        rangeID,
 		stateLoader:    makeReplicaStateLoader(rangeID),
+		sideloaded:     newSideloadStorage(store.Engine(), rangeID),
 		store:          store,
 		abortCache:     NewAbortCache(rangeID),
 		pushTxnQueue:   newPushTxnQueue(store),
@@ -649,6 +659,12 @@ func (r *Replica) destroyDataRaftMuLocked(
 	}
 	commitTime := timeutil.Now()
 
+	// The raft log is gone, and with it the references to any sideloaded
+	// payloads.
+	if err := r.sideloaded.Clear(ctx); err != nil {
+		return err
+	}
+
 	ms := r.GetMVCCStats()
 	log.Infof(ctx, "removed %d (%d+%d) keys in %0.0fms [clear=%0.0fms commit=%0.0fms]",
 		ms.KeyCount+ms.SysCount, ms.KeyCount, ms.SysCount,
@@ -2144,6 +2160,10 @@ func (r *Replica) tryExecuteWriteBatch(
 ) (br *roachpb.BatchResponse, pErr *roachpb.Error, retry proposalRetryReason) {
 	startTime := timeutil.Now()
 
+	if ba.AsyncConsensus && !canAsyncConsensus(ba) {
+		ba.AsyncConsensus = false
+	}
+
 	spans, err := collectSpans(*r.Desc(), &ba)
 	if err != nil {
 		return nil, roachpb.NewError(err), proposalNoRetry
@@ -2506,6 +2526,17 @@ func (r *Replica) propose(
 		delete(r.mu.proposals, proposal.idKey)
 		return nil, nil, err
 	}
+	if ba.AsyncConsensus && proposal.Local.Err == nil && proposal.Local.Reply != nil {
+		// The client doesn't wait for the command to be replicated; it proves
+		// that its writes succeeded later on using QueryIntent requests. The
+		// proposal continues in the background with a detached context.
+		reply := protoutil.Clone(proposal.Local.Reply).(*roachpb.BatchResponse)
+		proposal.ctx = r.AnnotateCtx(context.TODO())
+		ch := make(chan proposalResult, 1)
+		ch <- proposalResult{Reply: reply}
+		close(ch)
+		return ch, func() bool { return false }, nil
+	}
 	// Must not use `proposal` in the closure below as a proposal which is not
 	// present in r.mu.proposals is no longer protected by the mutex. Abandoning
 	// a command only abandons the associated context. As soon as we propose a
@@ -2528,6 +2559,23 @@ func (r *Replica) propose(
 	return proposal.doneCh, tryAbandon, nil
 }
 
+// canAsyncConsensus returns whether the batch may return to the client
+// before it has been replicated (see Header.AsyncConsensus). This is only
+// the case for transactional batches consisting solely of point writes,
+// whose outcome the client can later verify using QueryIntent requests.
+func canAsyncConsensus(ba roachpb.BatchRequest) bool {
+	if ba.Txn == nil {
+		return false
+	}
+	for _, union := range ba.Requests {
+		args := union.GetInner()
+		if !roachpb.IsTransactionWrite(args) || roachpb.IsRange(args) {
+			return false
+		}
+	}
+	return true
+}
+
 // submitProposalLocked proposes or re-proposes a command in r.mu.proposals.
 // The replica lock must be held.
 func (r *Replica) submitProposalLocked(p *ProposalData) error {
@@ -2611,6 +2659,12 @@ func defaultSubmitProposalLocked(r *Replica, p *ProposalData) error {
 		})
 	}
 
+	// Commands carrying an SSTable are encoded so that their payload is
+	// sideloaded when the entry is appended to the log.
+	version := raftVersionStandard
+	if p.command.ReplicatedEvalResult != nil && p.command.ReplicatedEvalResult.AddSSTable != nil {
+		version = raftVersionSideloaded
+	}
 	return r.withRaftGroupLocked(true, func(raftGroup *raft.RawNode) (bool, error) {
 		if log.V(4) {
 			log.Infof(ctx, "proposing command %x: %s", p.idKey, p.Request.Summary())
@@ -2618,7 +2672,7 @@ func defaultSubmitProposalLocked(r *Replica, p *ProposalData) error {
 		// We're proposing a command so there is no need to wake the leader if we
 		// were quiesced.
 		r.unquiesceLocked()
-		return false /* !unquiesceAndWakeLeader */, raftGroup.Propose(encodeRaftCommand(p.idKey, data))
+		return false /* !unquiesceAndWakeLeader */, raftGroup.Propose(encodeRaftCommand(version, p.idKey, data))
 	})
 }
 
@@ -2665,7 +2719,7 @@ func (r *Replica) unquiesceAndWakeLeaderLocked() {
 		}
 		r.mu.quiescent = false
 		// Propose an empty command which will wake the leader.
-		_ = r.mu.internalRaftGroup.Propose(encodeRaftCommand(makeIDKey(), nil))
+		_ = r.mu.internalRaftGroup.Propose(encodeRaftCommand(raftVersionStandard, makeIDKey(), nil))
 	}
 }
 
@@ -3889,6 +3943,17 @@ func (r *Replica) applyRaftCommand(
 				oldRaftAppliedIndex, rResult.State.RaftAppliedIndex)))
 	}
 
+	// Ingest a sideloaded SSTable before the rest of the command is
+	// committed. Should we crash in between, the command is applied again
+	// on restart and the SSTable is ingested a second time, which is
+	// harmless.
+	if rResult.AddSSTable != nil {
+		if err := r.ingestSSTable(ctx, rResult.AddSSTable); err != nil {
+			return enginepb.MVCCStats{}, roachpb.NewError(NewReplicaCorruptionError(err))
+		}
+		r.rangeFeeds.recordAll()
+	}
+
 	batch := r.store.Engine().NewWriteOnlyBatch()
 	defer batch.Close()
 
@@ -3897,6 +3962,7 @@ func (r *Replica) applyRaftCommand(
 			return enginepb.MVCCStats{}, roachpb.NewError(NewReplicaCorruptionError(
 				errors.Wrap(err, "unable to apply WriteBatch")))
 		}
+		r.rangeFeeds.recordBatch(ctx, writeBatch.Data)
 	}
 
 	// The only remaining use of the batch is for range-local keys which we know
@@ -4125,7 +4191,15 @@ func (r *Replica) evaluateTxnWriteBatch(
 		log.VEventf(ctx, 2, "1PC execution failed, reverting to regular execution for batch")
 	}
 
-	batch := r.store.Engine().NewBatch()
+	var batch engine.Batch
+	if _, ok := ba.GetArg(roachpb.ClearRange); ok {
+		// Range deletion tombstones can only be written to write-only
+		// batches. ClearRange is always alone in its batch and reads the
+		// data it clears from the engine directly.
+		batch = r.store.Engine().NewWriteOnlyBatch()
+	} else {
+		batch = r.store.Engine().NewBatch()
+	}
 	if spans != nil {
 		batch = makeSpanSetBatch(batch, spans)
 	}
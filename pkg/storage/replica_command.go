@@ -44,6 +44,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
 	"github.com/cockroachdb/cockroach/pkg/storage/storagebase"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/humanizeutil"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
 	"github.com/cockroachdb/cockroach/pkg/util/retry"
@@ -121,6 +122,7 @@ var commands = map[roachpb.Method]Command{
 	roachpb.Increment:          {DeclareKeys: DefaultDeclareKeys, Eval: evalIncrement},
 	roachpb.Delete:             {DeclareKeys: DefaultDeclareKeys, Eval: evalDelete},
 	roachpb.DeleteRange:        {DeclareKeys: DefaultDeclareKeys, Eval: evalDeleteRange},
+	roachpb.ClearRange:         {DeclareKeys: declareKeysClearRange, Eval: evalClearRange},
 	roachpb.Scan:               {DeclareKeys: DefaultDeclareKeys, Eval: evalScan},
 	roachpb.ReverseScan:        {DeclareKeys: DefaultDeclareKeys, Eval: evalReverseScan},
 	roachpb.BeginTransaction:   {DeclareKeys: declareKeysBeginTransaction, Eval: evalBeginTransaction},
@@ -130,6 +132,8 @@ var commands = map[roachpb.Method]Command{
 	roachpb.GC:                 {DeclareKeys: declareKeysGC, Eval: evalGC},
 	roachpb.PushTxn:            {DeclareKeys: declareKeysPushTransaction, Eval: evalPushTxn},
 	roachpb.QueryTxn:           {DeclareKeys: DefaultDeclareKeys, Eval: evalQueryTxn},
+	roachpb.QueryIntent:        {DeclareKeys: DefaultDeclareKeys, Eval: evalQueryIntent},
+	roachpb.RecoverTxn:         {DeclareKeys: declareKeysRecoverTxn, Eval: evalRecoverTxn},
 	roachpb.ResolveIntent:      {DeclareKeys: declareKeysResolveIntent, Eval: evalResolveIntent},
 	roachpb.ResolveIntentRange: {DeclareKeys: declareKeysResolveIntentRange, Eval: evalResolveIntentRange},
 	roachpb.Merge:              {DeclareKeys: DefaultDeclareKeys, Eval: evalMerge},
@@ -140,6 +144,8 @@ var commands = map[roachpb.Method]Command{
 	roachpb.ComputeChecksum:    {DeclareKeys: DefaultDeclareKeys, Eval: evalComputeChecksum},
 	roachpb.WriteBatch:         writeBatchCmd,
 	roachpb.Export:             exportCmd,
+	roachpb.AddSSTable:         addSSTableCmd,
+	roachpb.ScanChanges:        {DeclareKeys: DefaultDeclareKeys, Eval: evalScanChanges},
 
 	roachpb.DeprecatedVerifyChecksum: {
 		DeclareKeys: DefaultDeclareKeys,
@@ -395,6 +401,88 @@ func evalDeleteRange(
 	return EvalResult{}, err
 }
 
+// clearRangeBytesThreshold is the amount of data below which ClearRange
+// removes keys individually instead of writing a range deletion tombstone.
+// Range tombstones slow down reads until they are compacted away, which
+// isn't worth it for small amounts of data.
+const clearRangeBytesThreshold = 512 << 10 // 512 KB
+
+func declareKeysClearRange(
+	desc roachpb.RangeDescriptor, header roachpb.Header, req roachpb.Request, spans *SpanSet,
+) {
+	DefaultDeclareKeys(desc, header, req, spans)
+	spans.Add(SpanReadOnly, roachpb.Span{Key: keys.RangeDescriptorKey(desc.StartKey)})
+	spans.Add(SpanReadOnly, roachpb.Span{Key: keys.RangeStatsKey(header.RangeID)})
+}
+
+// evalClearRange removes all of the data in the specified key span,
+// including its MVCC history. If there is enough data to make it
+// worthwhile, a RocksDB range deletion tombstone is used and a compaction
+// of the span is suggested to all replicas. Range deletions are only
+// supported by write-only batches, so the existing data is read directly
+// from the engine; the command is flagged isAlone and the whole span is
+// latched, so nothing else can be writing to it concurrently.
+func evalClearRange(
+	ctx context.Context, batch engine.ReadWriter, cArgs CommandArgs, resp roachpb.Response,
+) (EvalResult, error) {
+	if cArgs.Header.Txn != nil {
+		return EvalResult{}, errTransactionUnsupported
+	}
+	args := cArgs.Args.(*roachpb.ClearRangeRequest)
+	from := engine.MakeMVCCMetadataKey(args.Key)
+	to := engine.MakeMVCCMetadataKey(args.EndKey)
+
+	delta, err := computeClearRangeStatsDelta(cArgs, from, to)
+	if err != nil {
+		return EvalResult{}, err
+	}
+	cArgs.Stats.Subtract(delta)
+
+	if delta.Total() < clearRangeBytesThreshold {
+		iter := cArgs.EvalCtx.Engine().NewIterator(false)
+		defer iter.Close()
+		if err := batch.ClearIterRange(iter, from, to); err != nil {
+			return EvalResult{}, err
+		}
+		return EvalResult{}, nil
+	}
+
+	if log.V(1) {
+		log.Infof(ctx, "clearing %s of data in [%s,%s) with a range tombstone",
+			humanizeutil.IBytes(delta.Total()), args.Key, args.EndKey)
+	}
+	if err := batch.ClearRange(from, to); err != nil {
+		return EvalResult{}, err
+	}
+	var pd EvalResult
+	pd.Replicated.SuggestedCompactions = []roachpb.Span{{Key: args.Key, EndKey: args.EndKey}}
+	return pd, nil
+}
+
+// computeClearRangeStatsDelta returns the MVCCStats of the data in the
+// span [from, to). If the span covers the entire range, the range's stats
+// are used (minus the range-local system data, which isn't cleared) instead
+// of scanning all of its data.
+func computeClearRangeStatsDelta(
+	cArgs CommandArgs, from, to engine.MVCCKey,
+) (enginepb.MVCCStats, error) {
+	desc, err := cArgs.EvalCtx.Desc()
+	if err != nil {
+		return enginepb.MVCCStats{}, err
+	}
+	if desc.StartKey.Equal(from.Key) && desc.EndKey.Equal(to.Key) {
+		delta, err := cArgs.EvalCtx.GetMVCCStats()
+		if err != nil {
+			return enginepb.MVCCStats{}, err
+		}
+		delta.SysBytes, delta.SysCount = 0, 0
+		return delta, nil
+	}
+	iter := cArgs.EvalCtx.Engine().NewIterator(false)
+	defer iter.Close()
+	return iter.ComputeStats(from, to, cArgs.Header.Timestamp.WallTime)
+}
+
 // evalScan scans the key range specified by start key through end key
 // in ascending order up to some maximum number of results. maxKeys
 // stores the number of scan results remaining for this batch
@@ -435,6 +523,65 @@ func evalReverseScan(
 	return intentsToEvalResult(intents, args), err
 }
 
+// evalScanChanges returns all of the MVCC versions written to the key range
+// specified by start key through end key in the interval between the request's
+// start time and the batch timestamp. It is not transactional.
+func evalScanChanges(
+	ctx context.Context, batch engine.ReadWriter, cArgs CommandArgs, resp roachpb.Response,
+) (EvalResult, error) {
+	args := cArgs.Args.(*roachpb.ScanChangesRequest)
+	h := cArgs.Header
+	reply := resp.(*roachpb.ScanChangesResponse)
+
+	if h.Txn != nil {
+		return EvalResult{}, errors.Errorf("cannot scan changes within a transaction")
+	}
+	gcThreshold, err := cArgs.EvalCtx.GCThreshold()
+	if err != nil {
+		return EvalResult{}, err
+	}
+	if args.StartTime != (hlc.Timestamp{}) && !gcThreshold.Less(args.StartTime) {
+		return EvalResult{}, errors.Errorf("start timestamp %v must be after replica GC threshold %v",
+			args.StartTime, gcThreshold)
+	}
+
+	// A range feed's poll only needs to scan the keys written since its
+	// previous poll.
+	if p, ok := rangeFeedPollFromContext(ctx); ok {
+		if all, spans := p.changedSpans(h.Timestamp); !all {
+			// Collect the intents of all spans into a single error, like a
+			// scan of the whole span would.
+			var wiErr *roachpb.WriteIntentError
+			for _, span := range spans {
+				rows, err := engine.MVCCScanChanges(
+					ctx, batch, span.Key, span.EndKey, args.StartTime, h.Timestamp)
+				if err != nil {
+					e, ok := err.(*roachpb.WriteIntentError)
+					if !ok {
+						return EvalResult{}, err
+					}
+					if wiErr == nil {
+						wiErr = e
+					} else {
+						wiErr.Intents = append(wiErr.Intents, e.Intents...)
+					}
+					continue
+				}
+				reply.Rows = append(reply.Rows, rows...)
+			}
+			if wiErr != nil {
+				reply.Rows = nil
+				return EvalResult{}, wiErr
+			}
+			return EvalResult{}, nil
+		}
+	}
+
+	rows, err := engine.MVCCScanChanges(ctx, batch, args.Key, args.EndKey, args.StartTime, h.Timestamp)
+	reply.Rows = rows
+	return EvalResult{}, err
+}
+
 func verifyTransaction(h roachpb.Header, args roachpb.Request) error {
 	if h.Txn == nil {
 		return errors.Errorf("no transaction specified to %s", args.Method())
@@ -636,6 +783,11 @@ func declareKeysEndTransaction(
 // evalEndTransaction either commits or aborts (rolls back) an extant
 // transaction according to the args.Commit parameter. Rolling back
 // an already rolled-back txn is ok.
+//
+// If the transaction is committed with in-flight writes, it is moved to
+// STAGING instead: its outcome depends on the success of those writes, so
+// none of its intents are resolved. The coordinator later commits it
+// explicitly with a second EndTransaction.
 func evalEndTransaction(
 	ctx context.Context, batch engine.ReadWriter, cArgs CommandArgs, resp roachpb.Response,
 ) (EvalResult, error) {
@@ -699,7 +851,7 @@ func evalEndTransaction(
 		return intentsToEvalResult(roachpb.AsIntents(args.IntentSpans, reply.Txn), args),
 			roachpb.NewTransactionAbortedError()
 
-	case roachpb.PENDING:
+	case roachpb.PENDING, roachpb.STAGING:
 		if h.Txn.Epoch < reply.Txn.Epoch {
 			// TODO(tschottdorf): this leaves the Txn record (and more
 			// importantly, intents) dangling; we can't currently write on
@@ -759,10 +911,14 @@ func evalEndTransaction(
 		if isEndTransactionTriggeringRetryError(h.Txn, reply.Txn) {
 			return EvalResult{}, roachpb.NewTransactionRetryError()
 		}
+		if len(args.InFlightWrites) > 0 {
+			return evalStagingEndTransaction(ctx, batch, ms, *args, reply.Txn)
+		}
 		reply.Txn.Status = roachpb.COMMITTED
 	} else {
 		reply.Txn.Status = roachpb.ABORTED
 	}
+	reply.Txn.InFlightWrites = nil
 
 	desc, err := cArgs.EvalCtx.Desc()
 	if err != nil {
@@ -809,6 +965,31 @@ func evalEndTransaction(
 	return pd, nil
 }
 
+// evalStagingEndTransaction moves the transaction record to STAGING,
+// recording the transaction's in-flight writes and intents in it so that
+// the transaction can be recovered should its coordinator fail.
+func evalStagingEndTransaction(
+	ctx context.Context,
+	batch engine.ReadWriter,
+	ms *enginepb.MVCCStats,
+	args roachpb.EndTransactionRequest,
+	txn *roachpb.Transaction,
+) (EvalResult, error) {
+	if args.InternalCommitTrigger != nil {
+		return EvalResult{}, errors.Errorf("cannot stage a transaction with a commit trigger")
+	}
+	txn.Status = roachpb.STAGING
+	txn.InFlightWrites = args.InFlightWrites
+	txn.Intents = args.IntentSpans
+	key := keys.TransactionKey(txn.Key, *txn.ID)
+	if err := engine.MVCCPutProto(ctx, batch, ms, key, hlc.Timestamp{}, nil /* txn */, txn); err != nil {
+		return EvalResult{}, err
+	}
+	var result EvalResult
+	result.Local.updatedTxn = txn
+	return result, nil
+}
+
 // isEndTransactionExceedingDeadline returns true if the transaction
 // exceeded its deadline.
 func isEndTransactionExceedingDeadline(t hlc.Timestamp, args roachpb.EndTransactionRequest) bool {
@@ -1350,7 +1531,7 @@ func evalHeartbeatTxn(
 		return EvalResult{}, errors.Errorf("heartbeat for transaction %s failed; record not present", h.Txn)
 	}
 
-	if txn.Status == roachpb.PENDING {
+	if !txn.Status.IsFinalized() {
 		if txn.LastHeartbeat == nil {
 			txn.LastHeartbeat = &hlc.Timestamp{}
 		}
@@ -1567,14 +1748,16 @@ func evalPushTxn(
 	}
 
 	// If already committed or aborted, return success.
-	if reply.PusheeTxn.Status != roachpb.PENDING {
+	if reply.PusheeTxn.Status.IsFinalized() {
 		// Trivial noop.
 		return EvalResult{}, nil
 	}
 
 	// If we're trying to move the timestamp forward, and it's already
-	// far enough forward, return success.
-	if args.PushType == roachpb.PUSH_TIMESTAMP && args.PushTo.Less(reply.PusheeTxn.Timestamp) {
+	// far enough forward, return success. This doesn't apply to STAGING
+	// transactions, whose intents can't be moved (see below).
+	if reply.PusheeTxn.Status == roachpb.PENDING &&
+		args.PushType == roachpb.PUSH_TIMESTAMP && args.PushTo.Less(reply.PusheeTxn.Timestamp) {
 		// Trivial noop.
 		return EvalResult{}, nil
 	}
@@ -1624,6 +1807,15 @@ func evalPushTxn(
 		return EvalResult{}, err
 	}
 
+	if reply.PusheeTxn.Status == roachpb.STAGING {
+		// A STAGING transaction may already be implicitly committed, in
+		// which case it can neither be aborted nor have its intents moved to
+		// a higher timestamp. Return its record as is; the pusher recovers
+		// the transaction to determine its outcome (see recoverTxn).
+		reply.PusheeTxn = existTxn.Clone()
+		return EvalResult{}, nil
+	}
+
 	// Upgrade priority of pushed transaction to one less than pusher's.
 	reply.PusheeTxn.UpgradePriority(args.PusherTxn.Priority - 1)
 
@@ -1696,6 +1888,90 @@ func evalQueryTxn(
 	return EvalResult{}, nil
 }
 
+// evalQueryIntent checks whether the transaction specified in the request
+// has written an intent to the requested key at or below its timestamp,
+// with a sequence number at least that of the request's transaction. If
+// not, and the request asks for it, a TransactionRetryError is returned.
+func evalQueryIntent(
+	ctx context.Context, batch engine.ReadWriter, cArgs CommandArgs, resp roachpb.Response,
+) (EvalResult, error) {
+	args := cArgs.Args.(*roachpb.QueryIntentRequest)
+	reply := resp.(*roachpb.QueryIntentResponse)
+
+	var meta enginepb.MVCCMetadata
+	ok, _, _, err := batch.GetProto(engine.MakeMVCCMetadataKey(args.Key), &meta)
+	if err != nil {
+		return EvalResult{}, err
+	}
+	if ok && meta.Txn != nil && roachpb.TxnIDEqual(meta.Txn.ID, args.Txn.ID) {
+		reply.FoundIntent = meta.Txn.Epoch == args.Txn.Epoch &&
+			meta.Txn.Sequence >= args.Txn.Sequence &&
+			!args.Txn.Timestamp.Less(meta.Timestamp)
+	}
+	if !reply.FoundIntent && args.ErrorIfMissing {
+		if log.V(1) {
+			log.Infof(ctx, "intent of %s at %s is missing", args.Txn.ID, args.Key)
+		}
+		return EvalResult{}, roachpb.NewTransactionRetryError()
+	}
+	return EvalResult{}, nil
+}
+
+func declareKeysRecoverTxn(
+	_ roachpb.RangeDescriptor, header roachpb.Header, req roachpb.Request, spans *SpanSet,
+) {
+	rr := req.(*roachpb.RecoverTxnRequest)
+	spans.Add(SpanReadWrite, roachpb.Span{Key: keys.TransactionKey(rr.Txn.Key, *rr.Txn.ID)})
+}
+
+// evalRecoverTxn finalizes a STAGING transaction after its in-flight writes
+// have been queried: it is committed if all of them were found and aborted
+// otherwise. Querying the writes prevents any missing ones from succeeding
+// later, so the outcome can't change after the fact. Transactions which are
+// already finalized, or which have been restarted at a higher epoch in the
+// meantime, are returned unchanged.
+func evalRecoverTxn(
+	ctx context.Context, batch engine.ReadWriter, cArgs CommandArgs, resp roachpb.Response,
+) (EvalResult, error) {
+	args := cArgs.Args.(*roachpb.RecoverTxnRequest)
+	reply := resp.(*roachpb.RecoverTxnResponse)
+
+	if cArgs.Header.Txn != nil {
+		return EvalResult{}, errTransactionUnsupported
+	}
+	if !bytes.Equal(args.Key, args.Txn.Key) {
+		return EvalResult{}, errors.Errorf("request key %s does not match txn key %s", args.Key, args.Txn.Key)
+	}
+	key := keys.TransactionKey(args.Txn.Key, *args.Txn.ID)
+
+	txn := &reply.RecoveredTxn
+	if ok, err := engine.MVCCGetProto(ctx, batch, key, hlc.Timestamp{},
+		true /* consistent */, nil /* txn */, txn); err != nil {
+		return EvalResult{}, err
+	} else if !ok {
+		return EvalResult{}, errors.Errorf("transaction record for %s not found", args.Txn.ID)
+	}
+	if txn.Status != roachpb.STAGING || txn.Epoch > args.Txn.Epoch {
+		return EvalResult{}, nil
+	}
+
+	if args.ImplicitlyCommitted {
+		txn.Status = roachpb.COMMITTED
+	} else {
+		txn.Status = roachpb.ABORTED
+	}
+	txn.InFlightWrites = nil
+	if log.V(1) {
+		log.Infof(ctx, "recovered %s", txn)
+	}
+	if err := engine.MVCCPutProto(ctx, batch, cArgs.Stats, key, hlc.Timestamp{}, nil, txn); err != nil {
+		return EvalResult{}, err
+	}
+	var result EvalResult
+	result.Local.updatedTxn = txn
+	return result, nil
+}
+
 // setAbortCache clears any abort cache entry if poison is false.
 // Otherwise, if poison is true, creates an entry for this transaction
 // in the abort cache to prevent future reads or writes from
@@ -2351,6 +2627,7 @@ func makeUnimplementedCommand(method roachpb.Method) Command {
 
 var writeBatchCmd = makeUnimplementedCommand(roachpb.WriteBatch)
 var exportCmd = makeUnimplementedCommand(roachpb.Export)
+var addSSTableCmd = makeUnimplementedCommand(roachpb.AddSSTable)
 var importCmdFn ImportCmdFunc = func(context.Context, CommandArgs) error {
 	return errors.Errorf("unimplemented command: %s", roachpb.Import)
 }
@@ -2369,6 +2646,13 @@ func SetExportCmd(cmd Command) {
 	commands[roachpb.Export] = cmd
 }
 
+// SetAddSSTableCmd allows setting the function that will be called as the
+// implementation of the AddSSTable command. Only allowed to be called by Init.
+func SetAddSSTableCmd(cmd Command) {
+	// This is safe if SetAddSSTableCmd is only called at init time.
+	commands[roachpb.AddSSTable] = cmd
+}
+
 // ImportCmdFunc is the type of the function that will be called as the
 // implementation of the Import command.
 type ImportCmdFunc func(context.Context, CommandArgs) error
@@ -267,6 +267,16 @@ func (p *EvalResult) MergeAndDestroy(q EvalResult) error {
 	}
 	q.Replicated.RaftLogDelta = nil
 
+	if p.Replicated.AddSSTable == nil {
+		p.Replicated.AddSSTable = q.Replicated.AddSSTable
+	} else if q.Replicated.AddSSTable != nil {
+		return errors.New("conflicting AddSSTable")
+	}
+	q.Replicated.AddSSTable = nil
+
+	p.Replicated.SuggestedCompactions = append(p.Replicated.SuggestedCompactions, q.Replicated.SuggestedCompactions...)
+	q.Replicated.SuggestedCompactions = nil
+
 	if q.Local.intents != nil {
 		if p.Local.intents == nil {
 			p.Local.intents = q.Local.intents
@@ -504,6 +514,8 @@ func (r *Replica) handleReplicatedEvalResult(
 		rResult.Timestamp = hlc.Timestamp{}
 		rResult.StartKey = nil
 		rResult.EndKey = nil
+		// The SSTable was ingested when the command was applied.
+		rResult.AddSSTable = nil
 	}
 
 	if rResult.BlockReads {
@@ -630,6 +642,25 @@ func (r *Replica) handleReplicatedEvalResult(
 		r.mu.Lock()
 		r.mu.state.TruncatedState = newTruncState
 		r.mu.Unlock()
+		// Remove the sideloaded payloads of the truncated entries. This
+		// happens after releasing the lock, so that readers of the replica
+		// don't wait on the disk: the log is read under the lock, and readers
+		// that acquire it from now on don't read the truncated entries. The
+		// payloads' size was accounted for in raftLogSize when they were
+		// appended.
+		freed, err := r.sideloaded.TruncateTo(ctx, newTruncState.Index+1)
+		if err != nil {
+			// Leftover files are removed by the next truncation.
+			log.Warningf(ctx, "while removing sideloaded files during log truncation: %s", err)
+		}
+		if freed != 0 {
+			r.mu.Lock()
+			r.mu.raftLogSize -= freed
+			if r.mu.raftLogSize < 0 {
+				r.mu.raftLogSize = 0
+			}
+			r.mu.Unlock()
+		}
 		// Clear any entries in the Raft log entry cache for this range up
 		// to and including the most recently truncated index.
 		r.store.raftEntryCache.clearTo(r.RangeID, newTruncState.Index+1)
@@ -666,6 +697,11 @@ func (r *Replica) handleReplicatedEvalResult(
 		rResult.RaftLogDelta = nil
 	}
 
+	if len(rResult.SuggestedCompactions) > 0 {
+		r.store.compactSpans(ctx, rResult.SuggestedCompactions)
+		rResult.SuggestedCompactions = nil
+	}
+
 	if !reflect.DeepEqual(rResult, storagebase.ReplicatedEvalResult{}) {
 		log.Fatalf(ctx, "unhandled field in ReplicatedEvalResult: %s", pretty.Diff(rResult, storagebase.ReplicatedEvalResult{}))
 	}
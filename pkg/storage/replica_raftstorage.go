@@ -75,14 +75,20 @@ func (r *Replica) Entries(lo, hi, maxBytes uint64) ([]raftpb.Entry, error) {
 	snap := r.store.NewSnapshot()
 	defer snap.Close()
 	ctx := r.AnnotateCtx(context.TODO())
-	return entries(ctx, snap, r.RangeID, r.store.raftEntryCache, lo, hi, maxBytes)
+	return entries(ctx, snap, r.RangeID, r.store.raftEntryCache, r.sideloaded, lo, hi, maxBytes)
 }
 
+// entries retrieves the entries in [lo, hi) from the entry cache and the
+// raft log. Sideloaded commands read from the log are inlined using the
+// given sideloadStorage. If it is nil, the entries are returned as they are
+// stored (which is only useful to callers interested in the entries' terms)
+// and are not added to the cache, which must only contain inlined entries.
 func entries(
 	ctx context.Context,
 	e engine.Reader,
 	rangeID roachpb.RangeID,
 	eCache *raftEntryCache,
+	sideloaded sideloadStorage,
 	lo, hi, maxBytes uint64,
 ) ([]raftpb.Entry, error) {
 	if lo > hi {
@@ -117,6 +123,16 @@ func entries(
 			return true, nil
 		}
 		expectedIndex++
+
+		if sideloaded != nil {
+			newEnt, err := maybeInlineSideloadedRaftCommand(ctx, rangeID, ent, sideloaded)
+			if err != nil {
+				return true, err
+			}
+			if newEnt != nil {
+				ent = *newEnt
+			}
+		}
 		size += uint64(ent.Size())
 		ents = append(ents, ent)
 		exceededMaxBytes = maxBytes > 0 && size > maxBytes
@@ -126,8 +142,10 @@ func entries(
 	if err := iterateEntries(ctx, e, rangeID, expectedIndex, hi, scanFunc); err != nil {
 		return nil, err
 	}
-	// Cache the fetched entries.
-	eCache.addEntries(rangeID, ents)
+	// Cache the fetched entries, unless they may not have been inlined.
+	if sideloaded != nil {
+		eCache.addEntries(rangeID, ents)
+	}
 
 	// Did the correct number of results come back? If so, we're all good.
 	if uint64(len(ents)) == hi-lo {
@@ -205,7 +223,9 @@ func (r *Replica) Term(i uint64) (uint64, error) {
 func term(
 	ctx context.Context, eng engine.Reader, rangeID roachpb.RangeID, eCache *raftEntryCache, i uint64,
 ) (uint64, error) {
-	ents, err := entries(ctx, eng, rangeID, eCache, i, i+1, 0)
+	// The term is all we need, so there's no need to inline sideloaded
+	// commands.
+	ents, err := entries(ctx, eng, rangeID, eCache, nil /* sideloaded */, i, i+1, 0)
 	if err == raft.ErrCompacted {
 		ts, err := loadTruncatedState(ctx, eng, rangeID)
 		if err != nil {
@@ -319,6 +339,7 @@ func (r *Replica) GetSnapshot(ctx context.Context, snapType string) (*OutgoingSn
 		log.Errorf(ctx, "error generating snapshot: %s", err)
 		return nil, err
 	}
+	snapData.sideloaded = r.sideloaded
 	return &snapData, nil
 }
 
@@ -335,6 +356,9 @@ type OutgoingSnapshot struct {
 	Iter *ReplicaDataIterator
 	// The replica state within the snapshot.
 	State storagebase.ReplicaState
+	// Used to inline the sideloaded payloads of the log entries sent along
+	// with the snapshot. May be nil if there are none.
+	sideloaded sideloadStorage
 }
 
 // Close releases the resources associated with the snapshot.
@@ -432,7 +456,9 @@ func snapshot(
 // append the given entries to the raft log. Takes the previous values of
 // r.mu.lastIndex and r.mu.raftLogSize, and returns new values. We do this
 // rather than modifying them directly because these modifications need to be
-// atomic with the commit of the batch.
+// atomic with the commit of the batch. The payloads of sideloaded commands
+// are written to the replica's sideloadStorage and are accounted for in the
+// returned raft log size. Requires that Replica.raftMu is held.
 func (r *Replica) append(
 	ctx context.Context,
 	batch engine.ReadWriter,
@@ -443,6 +469,10 @@ func (r *Replica) append(
 	if len(entries) == 0 {
 		return prevLastIndex, prevRaftLogSize, nil
 	}
+	entries, sideloadedSize, err := maybeSideloadEntries(ctx, entries, r.sideloaded)
+	if err != nil {
+		return 0, 0, err
+	}
 	var diff enginepb.MVCCStats
 	var value roachpb.Value
 	for i := range entries {
@@ -477,7 +507,7 @@ func (r *Replica) append(
 		return 0, 0, err
 	}
 
-	raftLogSize := prevRaftLogSize + diff.SysBytes
+	raftLogSize := prevRaftLogSize + diff.SysBytes + sideloadedSize
 
 	return lastIndex, raftLogSize, nil
 }
@@ -669,6 +699,9 @@ func (r *Replica) applySnapshot(
 		return err
 	}
 	stats.commit = timeutil.Now()
+	// The snapshot replaced the replica's data wholesale, so range feeds
+	// can't tell which keys changed and have to rescan their spans.
+	r.rangeFeeds.recordAll()
 
 	r.mu.Lock()
 	// We set the persisted last index to the last applied index. This is
@@ -687,6 +720,15 @@ func (r *Replica) applySnapshot(
 	r.assertStateRLocked(r.store.Engine())
 	r.mu.Unlock()
 
+	// Sideloaded payloads of entries which precede the snapshot are no longer
+	// referenced by the log. They are removed without holding the lock, so
+	// that readers of the replica don't wait on the disk.
+	if s.TruncatedState != nil {
+		if _, err := r.sideloaded.TruncateTo(ctx, s.TruncatedState.Index+1); err != nil {
+			log.Warningf(ctx, "while removing sideloaded files after applying snapshot: %s", err)
+		}
+	}
+
 	// As the last deferred action after committing the batch, update other
 	// fields which are uninitialized or need updating. This may not happen
 	// if the system config has not yet been loaded. While config update
@@ -701,28 +743,33 @@ func (r *Replica) applySnapshot(
 	return nil
 }
 
-// Raft commands are encoded with a 1-byte version (currently 0), an 8-byte ID,
-// followed by the payload. This inflexible encoding is used so we can efficiently
-// parse the command id while processing the logs.
+// Raft commands are encoded with a 1-byte version, an 8-byte ID, followed by
+// the payload. This inflexible encoding is used so we can efficiently parse
+// the command id while processing the logs.
 // TODO(bdarnell): Is this commandID still appropriate for our needs?
 const (
 	// The prescribed length for each command ID.
-	raftCommandIDLen                = 8
-	raftCommandEncodingVersion byte = 0
+	raftCommandIDLen = 8
+	// raftVersionStandard is the encoding version of most commands.
+	raftVersionStandard byte = 0
+	// raftVersionSideloaded is the encoding version of commands whose
+	// payload (an AddSSTable) is stored outside of the raft log once the
+	// entry has been appended. See maybeSideloadEntries.
+	raftVersionSideloaded byte = 1
 	// The no-split bit is now unused, but we still apply the mask to the first
 	// byte of the command for backward compatibility.
 	raftCommandNoSplitBit  = 1 << 7
 	raftCommandNoSplitMask = raftCommandNoSplitBit - 1
 )
 
-// encode a command ID, an encoded storagebase.RaftCommand, and
-// whether the command contains a split.
-func encodeRaftCommand(commandID storagebase.CmdIDKey, command []byte) []byte {
+// encode a command ID and an encoded storagebase.RaftCommand using the given
+// encoding version.
+func encodeRaftCommand(version byte, commandID storagebase.CmdIDKey, command []byte) []byte {
 	if len(commandID) != raftCommandIDLen {
 		panic(fmt.Sprintf("invalid command ID length; %d != %d", len(commandID), raftCommandIDLen))
 	}
 	x := make([]byte, 1, 1+raftCommandIDLen+len(command))
-	x[0] = raftCommandEncodingVersion
+	x[0] = version
 	x = append(x, []byte(commandID)...)
 	x = append(x, command...)
 	return x
@@ -734,7 +781,9 @@ func encodeRaftCommand(commandID storagebase.CmdIDKey, command []byte) []byte {
 // than a real command). Usage is mostly internal to the storage package
 // but is exported for use by debugging tools.
 func DecodeRaftCommand(data []byte) (storagebase.CmdIDKey, []byte) {
-	if data[0]&raftCommandNoSplitMask != raftCommandEncodingVersion {
+	switch data[0] & raftCommandNoSplitMask {
+	case raftVersionStandard, raftVersionSideloaded:
+	default:
 		panic(fmt.Sprintf("unknown command encoding version %v", data[0]))
 	}
 	return storagebase.CmdIDKey(data[1 : 1+raftCommandIDLen]), data[1+raftCommandIDLen:]
@@ -0,0 +1,270 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine"
+	"github.com/cockroachdb/cockroach/pkg/util/envutil"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+)
+
+// rangeFeedPollInterval is the interval at which a range feed scans its span
+// for new changes and advances its resolved timestamp.
+var rangeFeedPollInterval = envutil.EnvOrDefaultDuration(
+	"COCKROACH_RANGEFEED_POLL_INTERVAL", time.Second)
+
+// rangeFeedRegistration records the keys written to a range feed's span since
+// the feed last scanned it.
+type rangeFeedRegistration struct {
+	span roachpb.Span
+
+	mu struct {
+		syncutil.Mutex
+		// all is set when the whole span has to be scanned, either because the
+		// feed has not scanned it yet or because a change was applied which
+		// can't be attributed to individual keys (an ingested SSTable or a
+		// snapshot).
+		all bool
+		// changed maps the keys written since the last scan to the highest
+		// MVCC timestamp written to them.
+		changed map[string]hlc.Timestamp
+	}
+}
+
+// rangeFeedRegistry holds the registrations of the range feeds served by a
+// replica. Writes applied to the replica are recorded in every registration
+// whose span they touch.
+type rangeFeedRegistry struct {
+	syncutil.Mutex
+	regs map[*rangeFeedRegistration]struct{}
+}
+
+func (f *rangeFeedRegistry) register(span roachpb.Span) *rangeFeedRegistration {
+	reg := &rangeFeedRegistration{span: span}
+	reg.mu.all = true
+	reg.mu.changed = make(map[string]hlc.Timestamp)
+	f.Lock()
+	defer f.Unlock()
+	if f.regs == nil {
+		f.regs = make(map[*rangeFeedRegistration]struct{})
+	}
+	f.regs[reg] = struct{}{}
+	return reg
+}
+
+func (f *rangeFeedRegistry) unregister(reg *rangeFeedRegistration) {
+	f.Lock()
+	defer f.Unlock()
+	delete(f.regs, reg)
+}
+
+// recordAll marks the whole span of every registration as changed.
+func (f *rangeFeedRegistry) recordAll() {
+	f.Lock()
+	defer f.Unlock()
+	for reg := range f.regs {
+		reg.mu.Lock()
+		reg.mu.all = true
+		reg.mu.Unlock()
+	}
+}
+
+// recordBatch records the keys written by the given batch repr in the
+// registrations whose span they fall into. A batch which can't be decoded is
+// treated as having changed everything.
+func (f *rangeFeedRegistry) recordBatch(ctx context.Context, repr []byte) {
+	f.Lock()
+	defer f.Unlock()
+	if len(f.regs) == 0 {
+		return
+	}
+	if err := f.recordBatchLocked(repr); err != nil {
+		log.Warningf(ctx, "range feeds will rescan their spans: unable to decode batch: %s", err)
+		for reg := range f.regs {
+			reg.mu.Lock()
+			reg.mu.all = true
+			reg.mu.Unlock()
+		}
+	}
+}
+
+func (f *rangeFeedRegistry) recordBatchLocked(repr []byte) error {
+	r, err := engine.NewRocksDBBatchReader(repr)
+	if err != nil {
+		return err
+	}
+	for r.Next() {
+		key, err := engine.DecodeKey(r.UnsafeKey())
+		if err != nil {
+			return err
+		}
+		if keys.IsLocal(key.Key) {
+			continue
+		}
+		for reg := range f.regs {
+			if !reg.span.Contains(roachpb.Span{Key: key.Key}) {
+				continue
+			}
+			reg.mu.Lock()
+			if ts, ok := reg.mu.changed[string(key.Key)]; !ok || ts.Less(key.Timestamp) {
+				reg.mu.changed[string(key.Key)] = key.Timestamp
+			}
+			reg.mu.Unlock()
+		}
+	}
+	return r.Error()
+}
+
+// rangeFeedPoll carries a registration's changed keys into the evaluation of
+// one poll's ScanChangesRequest. It is attached to the request's context,
+// which is only possible because the request is sent through the local store.
+type rangeFeedPoll struct {
+	reg *rangeFeedRegistration
+	all bool
+	// spans accumulates the changed keys taken from the registration, so that
+	// a poll whose evaluation is retried (for example after pushing an intent)
+	// still scans the keys taken by earlier attempts.
+	spans []roachpb.Span
+}
+
+type rangeFeedPollKey struct{}
+
+// rangeFeedPollFromContext returns the rangeFeedPoll attached to the context,
+// if any.
+func rangeFeedPollFromContext(ctx context.Context) (*rangeFeedPoll, bool) {
+	p, ok := ctx.Value(rangeFeedPollKey{}).(*rangeFeedPoll)
+	return p, ok
+}
+
+// changedSpans takes the keys changed since the previous poll out of the
+// registration and returns the spans that a scan of changes up to endTime
+// has to cover, or all=true if it has to cover the whole span. It must be
+// called while evaluating the ScanChangesRequest: the request then holds the
+// whole span in the command queue, so every write which could still commit
+// at or below endTime has already been applied and recorded. Keys with
+// versions above endTime stay recorded, so that a later poll picks those
+// versions up once its end time has passed them.
+func (p *rangeFeedPoll) changedSpans(endTime hlc.Timestamp) (all bool, spans []roachpb.Span) {
+	p.reg.mu.Lock()
+	if p.reg.mu.all {
+		p.all = true
+		p.reg.mu.all = false
+	}
+	for k, ts := range p.reg.mu.changed {
+		key := roachpb.Key(k)
+		p.spans = append(p.spans, roachpb.Span{Key: key, EndKey: key.Next()})
+		if !endTime.Less(ts) {
+			delete(p.reg.mu.changed, k)
+		}
+	}
+	p.reg.mu.Unlock()
+
+	if p.all {
+		return true, nil
+	}
+	p.spans, _ = roachpb.MergeSpans(p.spans)
+	return false, p.spans
+}
+
+// RangeFeed streams the changes made to the requested span of this range,
+// starting after the request's timestamp, to the provided stream. Each poll
+// scans for committed changes up to the current time, sends them as
+// RangeFeedValue events and then sends a RangeFeedCheckpoint resolving the
+// span at that time. The changes are read with a ScanChangesRequest sent
+// through the store, so the scan is served by the lease holder, advances the
+// timestamp cache (no write can subsequently commit at or below the
+// checkpoint) and pushes any conflicting intents out of its way.
+//
+// The first poll scans the whole span. The replica records the keys written
+// to the span while the feed is registered, and later polls only scan those
+// keys, so that the cost of a poll is proportional to the write rate rather
+// than to the size of the span.
+//
+// RangeFeed returns when the stream's context is canceled, the server is
+// shutting down or an error (for example a NotLeaseHolderError or a
+// RangeKeyMismatchError after a split) is encountered. In the latter case the
+// client is expected to resume the feed from its last checkpoint.
+func (r *Replica) RangeFeed(
+	args *roachpb.RangeFeedRequest, stream roachpb.Internal_RangeFeedServer,
+) *roachpb.Error {
+	ctx := r.AnnotateCtx(stream.Context())
+
+	if !r.ContainsKeyRange(args.Span.Key, args.Span.EndKey) {
+		return roachpb.NewError(roachpb.NewRangeKeyMismatchError(
+			args.Span.Key, args.Span.EndKey, r.Desc()))
+	}
+	replica, err := r.GetReplicaDescriptor()
+	if err != nil {
+		return roachpb.NewError(err)
+	}
+
+	reg := r.rangeFeeds.register(args.Span)
+	defer r.rangeFeeds.unregister(reg)
+
+	ticker := time.NewTicker(rangeFeedPollInterval)
+	defer ticker.Stop()
+
+	resolved := args.Timestamp
+	var event roachpb.RangeFeedEvent
+	for {
+		var ba roachpb.BatchRequest
+		ba.RangeID = r.RangeID
+		ba.Replica = replica
+		ba.Timestamp = r.store.Clock().Now()
+		ba.Add(&roachpb.ScanChangesRequest{
+			Span:      args.Span,
+			StartTime: resolved,
+		})
+		pollCtx := context.WithValue(ctx, rangeFeedPollKey{}, &rangeFeedPoll{reg: reg})
+		br, pErr := r.store.Send(pollCtx, ba)
+		if pErr != nil {
+			return pErr
+		}
+
+		for _, kv := range br.Responses[0].GetInner().(*roachpb.ScanChangesResponse).Rows {
+			event.Reset()
+			event.SetValue(&roachpb.RangeFeedValue{Key: kv.Key, Value: kv.Value})
+			if err := stream.Send(&event); err != nil {
+				return roachpb.NewError(err)
+			}
+		}
+		resolved = ba.Timestamp
+		event.Reset()
+		event.SetValue(&roachpb.RangeFeedCheckpoint{Span: args.Span, ResolvedTS: resolved})
+		if err := stream.Send(&event); err != nil {
+			return roachpb.NewError(err)
+		}
+		if log.V(2) {
+			log.Infof(ctx, "range feed for %s resolved to %s", args.Span, resolved)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return roachpb.NewError(ctx.Err())
+		case <-r.store.Stopper().ShouldQuiesce():
+			return roachpb.NewError(errors.New("server is shutting down"))
+		}
+	}
+}
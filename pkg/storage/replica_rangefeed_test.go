@@ -0,0 +1,105 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func TestRangeFeedRegistryChangedSpans(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	eng := engine.NewInMem(roachpb.Attributes{}, 1<<20)
+	defer eng.Close()
+
+	var f rangeFeedRegistry
+	reg := f.register(roachpb.Span{Key: roachpb.Key("a"), EndKey: roachpb.Key("m")})
+	defer f.unregister(reg)
+
+	write := func(key string, wallTime int64) {
+		b := eng.NewBatch()
+		defer b.Close()
+		if err := engine.MVCCPut(ctx, b, nil, roachpb.Key(key), hlc.Timestamp{WallTime: wallTime},
+			roachpb.MakeValueFromString("v"), nil); err != nil {
+			t.Fatal(err)
+		}
+		f.recordBatch(ctx, b.Repr())
+	}
+	poll := func(endTime int64) (bool, []roachpb.Span) {
+		p := &rangeFeedPoll{reg: reg}
+		return p.changedSpans(hlc.Timestamp{WallTime: endTime})
+	}
+	point := func(key string) roachpb.Span {
+		return roachpb.Span{Key: roachpb.Key(key), EndKey: roachpb.Key(key).Next()}
+	}
+
+	// The first poll scans the whole span.
+	if all, _ := poll(1); !all {
+		t.Fatal("expected the first poll to scan the whole span")
+	}
+
+	write("b", 2)
+	write("c", 5)
+	write("z", 2) // outside of the span
+
+	testCases := []struct {
+		endTime  int64
+		expected []roachpb.Span
+	}{
+		{3, []roachpb.Span{point("b"), point("c")}},
+		// The version of "c" is above the previous end time, so the key is
+		// scanned until an end time covers it.
+		{4, []roachpb.Span{point("c")}},
+		{6, []roachpb.Span{point("c")}},
+		{7, nil},
+	}
+	for _, tc := range testCases {
+		all, spans := poll(tc.endTime)
+		if all {
+			t.Fatalf("%d: unexpectedly scanning the whole span", tc.endTime)
+		}
+		if len(spans) == 0 {
+			spans = nil
+		}
+		if !reflect.DeepEqual(spans, tc.expected) {
+			t.Errorf("%d: expected spans %v, got %v", tc.endTime, tc.expected, spans)
+		}
+	}
+
+	// A retried evaluation of the same poll still covers the keys taken by
+	// earlier attempts.
+	write("d", 8)
+	p := &rangeFeedPoll{reg: reg}
+	if _, spans := p.changedSpans(hlc.Timestamp{WallTime: 9}); !reflect.DeepEqual(spans, []roachpb.Span{point("d")}) {
+		t.Errorf("expected spans %v, got %v", []roachpb.Span{point("d")}, spans)
+	}
+	if _, spans := p.changedSpans(hlc.Timestamp{WallTime: 9}); !reflect.DeepEqual(spans, []roachpb.Span{point("d")}) {
+		t.Errorf("expected retried poll to keep spans %v, got %v", []roachpb.Span{point("d")}, spans)
+	}
+
+	f.recordAll()
+	if all, _ := poll(10); !all {
+		t.Fatal("expected a poll after recordAll to scan the whole span")
+	}
+}
@@ -0,0 +1,356 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/coreos/etcd/raft/raftpb"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine"
+	"github.com/cockroachdb/cockroach/pkg/storage/storagebase"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+)
+
+// errSideloadedFileNotFound is returned by sideloadStorage when there is no
+// payload for the requested index and term.
+var errSideloadedFileNotFound = errors.New("sideloaded file not found")
+
+// sideloadStorage stores the payloads of sideloaded raft commands (currently
+// only AddSSTable) outside of the raft log. Payloads are keyed by the index
+// and term of the log entry they belong to. Implementations must be safe for
+// concurrent use, since the log is read under Replica.mu while it is
+// appended to under Replica.raftMu.
+type sideloadStorage interface {
+	// PutIfNotExists writes the payload for the given index and term unless
+	// one already exists. The write is durable when the method returns.
+	PutIfNotExists(_ context.Context, index, term uint64, contents []byte) error
+	// Get returns the payload for the given index and term, or
+	// errSideloadedFileNotFound.
+	Get(_ context.Context, index, term uint64) ([]byte, error)
+	// Purge removes the payload for the given index and term, or returns
+	// errSideloadedFileNotFound.
+	Purge(_ context.Context, index, term uint64) error
+	// Clear removes all payloads.
+	Clear(context.Context) error
+	// TruncateTo removes all payloads belonging to indexes strictly smaller
+	// than the given one and returns the number of bytes freed.
+	TruncateTo(_ context.Context, index uint64) (int64, error)
+}
+
+// newSideloadStorage returns the sideloadStorage to be used by the replica of
+// the given range on the given engine. On-disk engines keep the payloads in
+// files under the engine's auxiliary directory, in-memory engines in memory.
+func newSideloadStorage(eng engine.Engine, rangeID roachpb.RangeID) sideloadStorage {
+	if dir := eng.GetAuxiliaryDir(); dir != "" {
+		return newDiskSideloadStorage(
+			filepath.Join(dir, "sideloading", fmt.Sprintf("r%d", rangeID)),
+		)
+	}
+	return newInMemSideloadStorage()
+}
+
+type diskSideloadStorage struct {
+	dir string
+}
+
+var _ sideloadStorage = &diskSideloadStorage{}
+
+func newDiskSideloadStorage(dir string) *diskSideloadStorage {
+	return &diskSideloadStorage{dir: dir}
+}
+
+func (ss *diskSideloadStorage) filename(index, term uint64) string {
+	return filepath.Join(ss.dir, fmt.Sprintf("i%d.t%d", index, term))
+}
+
+// PutIfNotExists implements sideloadStorage.
+func (ss *diskSideloadStorage) PutIfNotExists(
+	_ context.Context, index, term uint64, contents []byte,
+) error {
+	filename := ss.filename(index, term)
+	if _, err := os.Stat(filename); err == nil {
+		return nil
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.MkdirAll(ss.dir, 0755); err != nil {
+		return err
+	}
+	// Write to a temporary file first so that a crash can't leave a partial
+	// payload behind under the final name.
+	tmp := filename + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(contents); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
+
+// Get implements sideloadStorage.
+func (ss *diskSideloadStorage) Get(_ context.Context, index, term uint64) ([]byte, error) {
+	b, err := ioutil.ReadFile(ss.filename(index, term))
+	if os.IsNotExist(err) {
+		return nil, errSideloadedFileNotFound
+	}
+	return b, err
+}
+
+// Purge implements sideloadStorage.
+func (ss *diskSideloadStorage) Purge(_ context.Context, index, term uint64) error {
+	err := os.Remove(ss.filename(index, term))
+	if os.IsNotExist(err) {
+		return errSideloadedFileNotFound
+	}
+	return err
+}
+
+// Clear implements sideloadStorage.
+func (ss *diskSideloadStorage) Clear(_ context.Context) error {
+	return os.RemoveAll(ss.dir)
+}
+
+// TruncateTo implements sideloadStorage.
+func (ss *diskSideloadStorage) TruncateTo(_ context.Context, index uint64) (int64, error) {
+	matches, err := filepath.Glob(filepath.Join(ss.dir, "i*"))
+	if err != nil {
+		return 0, err
+	}
+	var freed int64
+	for _, match := range matches {
+		base := filepath.Base(match)
+		i, err := strconv.ParseUint(strings.SplitN(base[1:], ".", 2)[0], 10, 64)
+		if err != nil {
+			return freed, errors.Wrapf(err, "while parsing %q during TruncateTo", match)
+		}
+		if i >= index {
+			continue
+		}
+		fi, err := os.Stat(match)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return freed, err
+		}
+		if err := os.Remove(match); err != nil && !os.IsNotExist(err) {
+			return freed, err
+		}
+		if !strings.HasSuffix(base, ".tmp") {
+			freed += fi.Size()
+		}
+	}
+	return freed, nil
+}
+
+type slKey struct {
+	index, term uint64
+}
+
+type inMemSideloadStorage struct {
+	mu struct {
+		syncutil.Mutex
+		m map[slKey][]byte
+	}
+}
+
+var _ sideloadStorage = &inMemSideloadStorage{}
+
+func newInMemSideloadStorage() *inMemSideloadStorage {
+	ss := &inMemSideloadStorage{}
+	ss.mu.m = make(map[slKey][]byte)
+	return ss
+}
+
+// PutIfNotExists implements sideloadStorage.
+func (ss *inMemSideloadStorage) PutIfNotExists(
+	_ context.Context, index, term uint64, contents []byte,
+) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	key := slKey{index: index, term: term}
+	if _, ok := ss.mu.m[key]; !ok {
+		ss.mu.m[key] = contents
+	}
+	return nil
+}
+
+// Get implements sideloadStorage.
+func (ss *inMemSideloadStorage) Get(_ context.Context, index, term uint64) ([]byte, error) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	data, ok := ss.mu.m[slKey{index: index, term: term}]
+	if !ok {
+		return nil, errSideloadedFileNotFound
+	}
+	return data, nil
+}
+
+// Purge implements sideloadStorage.
+func (ss *inMemSideloadStorage) Purge(_ context.Context, index, term uint64) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	key := slKey{index: index, term: term}
+	if _, ok := ss.mu.m[key]; !ok {
+		return errSideloadedFileNotFound
+	}
+	delete(ss.mu.m, key)
+	return nil
+}
+
+// Clear implements sideloadStorage.
+func (ss *inMemSideloadStorage) Clear(_ context.Context) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.mu.m = make(map[slKey][]byte)
+	return nil
+}
+
+// TruncateTo implements sideloadStorage.
+func (ss *inMemSideloadStorage) TruncateTo(_ context.Context, index uint64) (int64, error) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	var freed int64
+	for k, v := range ss.mu.m {
+		if k.index < index {
+			freed += int64(len(v))
+			delete(ss.mu.m, k)
+		}
+	}
+	return freed, nil
+}
+
+// sniffSideloadedRaftCommand returns whether the entry data was encoded as a
+// sideloaded raft command.
+func sniffSideloadedRaftCommand(data []byte) bool {
+	return len(data) > 0 && data[0]&raftCommandNoSplitMask == raftVersionSideloaded
+}
+
+// maybeSideloadEntries is called with the "fat" entries which are about to be
+// appended to the raft log. The payloads of sideloaded commands are written
+// to the sideloadStorage and the commands re-encoded without them. The
+// returned slice contains the "thin" entries which are to be written to the
+// log, along with the total size of the sideloaded payloads. The payloads
+// must be durable before the thin entries are, so this must be called before
+// the entries are persisted.
+//
+// The passed-in slice is not mutated: raft and the entry cache hold on to it.
+func maybeSideloadEntries(
+	ctx context.Context, entries []raftpb.Entry, sideloaded sideloadStorage,
+) ([]raftpb.Entry, int64, error) {
+	var sideloadedSize int64
+	copied := false
+	for i := range entries {
+		if entries[i].Type != raftpb.EntryNormal || !sniffSideloadedRaftCommand(entries[i].Data) {
+			continue
+		}
+		if !copied {
+			entries = append([]raftpb.Entry(nil), entries...)
+			copied = true
+		}
+		ent := &entries[i]
+		cmdID, data := DecodeRaftCommand(ent.Data)
+		var cmd storagebase.RaftCommand
+		if err := cmd.Unmarshal(data); err != nil {
+			return nil, 0, err
+		}
+		rResult := cmd.ReplicatedEvalResult
+		if rResult == nil || rResult.AddSSTable == nil || len(rResult.AddSSTable.Data) == 0 {
+			// Nothing to sideload (or the entry is already thin).
+			continue
+		}
+		payload := rResult.AddSSTable.Data
+		rResult.AddSSTable.Data = nil
+		thin, err := protoutil.Marshal(&cmd)
+		if err != nil {
+			return nil, 0, err
+		}
+		if err := sideloaded.PutIfNotExists(ctx, ent.Index, ent.Term, payload); err != nil {
+			return nil, 0, errors.Wrapf(err, "while sideloading entry %d", ent.Index)
+		}
+		ent.Data = encodeRaftCommand(raftVersionSideloaded, cmdID, thin)
+		sideloadedSize += int64(len(payload))
+	}
+	return entries, sideloadedSize, nil
+}
+
+// maybeInlineSideloadedRaftCommand inspects an entry read from the raft log.
+// If it is a thin sideloaded command, the payload is loaded from the
+// sideloadStorage and a new, fat entry returned. Otherwise, nil is returned.
+func maybeInlineSideloadedRaftCommand(
+	ctx context.Context, rangeID roachpb.RangeID, ent raftpb.Entry, sideloaded sideloadStorage,
+) (*raftpb.Entry, error) {
+	if ent.Type != raftpb.EntryNormal || !sniffSideloadedRaftCommand(ent.Data) {
+		return nil, nil
+	}
+	cmdID, data := DecodeRaftCommand(ent.Data)
+	var cmd storagebase.RaftCommand
+	if err := cmd.Unmarshal(data); err != nil {
+		return nil, err
+	}
+	rResult := cmd.ReplicatedEvalResult
+	if rResult == nil || rResult.AddSSTable == nil || len(rResult.AddSSTable.Data) > 0 {
+		return nil, nil
+	}
+	payload, err := sideloaded.Get(ctx, ent.Index, ent.Term)
+	if err != nil {
+		return nil, errors.Wrapf(err, "r%d: loading sideloaded data for entry %d (term %d)",
+			rangeID, ent.Index, ent.Term)
+	}
+	rResult.AddSSTable.Data = payload
+	fat, err := protoutil.Marshal(&cmd)
+	if err != nil {
+		return nil, err
+	}
+	ent.Data = encodeRaftCommand(raftVersionSideloaded, cmdID, fat)
+	return &ent, nil
+}
+
+// ingestSSTable verifies the checksum of a sideloaded SSTable and links it
+// into the store's engine. It is called before the rest of the command is
+// applied; ingesting the same file twice is harmless, so a command which is
+// replayed after a crash can simply ingest it again.
+func (r *Replica) ingestSSTable(ctx context.Context, sst *storagebase.AddSSTable) error {
+	if crc := crc32.ChecksumIEEE(sst.Data); crc != sst.CRC32 {
+		return errors.Errorf("checksum mismatch for sideloaded SSTable: expected %x, got %x",
+			sst.CRC32, crc)
+	}
+	if err := r.store.Engine().IngestExternalData(sst.Data); err != nil {
+		return errors.Wrap(err, "while ingesting sideloaded SSTable")
+	}
+	log.Eventf(ctx, "ingested SSTable of %d bytes", len(sst.Data))
+	return nil
+}
@@ -0,0 +1,199 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"hash/crc32"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/coreos/etcd/raft/raftpb"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/storage/storagebase"
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+)
+
+func TestSideloadStorage(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	dir, cleanup := testutils.TempDir(t)
+	defer cleanup()
+
+	for _, tc := range []struct {
+		name string
+		ss   sideloadStorage
+	}{
+		{"disk", newDiskSideloadStorage(filepath.Join(dir, "r1"))},
+		{"mem", newInMemSideloadStorage()},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			ss := tc.ss
+			payload := func(index, term uint64) []byte {
+				return []byte(fmt.Sprintf("i%dt%d", index, term))
+			}
+
+			if _, err := ss.Get(ctx, 1, 1); err != errSideloadedFileNotFound {
+				t.Fatalf("expected %v, got %v", errSideloadedFileNotFound, err)
+			}
+			if err := ss.Purge(ctx, 1, 1); err != errSideloadedFileNotFound {
+				t.Fatalf("expected %v, got %v", errSideloadedFileNotFound, err)
+			}
+
+			for index := uint64(1); index <= 5; index++ {
+				for term := uint64(1); term <= 2; term++ {
+					if err := ss.PutIfNotExists(ctx, index, term, payload(index, term)); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			// An existing payload is not overwritten.
+			if err := ss.PutIfNotExists(ctx, 1, 1, []byte("foo")); err != nil {
+				t.Fatal(err)
+			}
+			if b, err := ss.Get(ctx, 1, 1); err != nil {
+				t.Fatal(err)
+			} else if !bytes.Equal(b, payload(1, 1)) {
+				t.Fatalf("expected %q, got %q", payload(1, 1), b)
+			}
+
+			if err := ss.Purge(ctx, 2, 1); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := ss.Get(ctx, 2, 1); err != errSideloadedFileNotFound {
+				t.Fatalf("expected %v, got %v", errSideloadedFileNotFound, err)
+			}
+			if _, err := ss.Get(ctx, 2, 2); err != nil {
+				t.Fatal(err)
+			}
+
+			// Indexes 1 and 2 hold three payloads between them at this point.
+			freed, err := ss.TruncateTo(ctx, 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if expected := int64(3 * len(payload(1, 1))); freed != expected {
+				t.Fatalf("expected %d bytes to be freed, got %d", expected, freed)
+			}
+			for index := uint64(1); index <= 5; index++ {
+				_, err := ss.Get(ctx, index, 2)
+				if index < 3 && err != errSideloadedFileNotFound {
+					t.Fatalf("%d: expected %v, got %v", index, errSideloadedFileNotFound, err)
+				} else if index >= 3 && err != nil {
+					t.Fatalf("%d: %v", index, err)
+				}
+			}
+
+			if err := ss.Clear(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := ss.Get(ctx, 5, 2); err != errSideloadedFileNotFound {
+				t.Fatalf("expected %v, got %v", errSideloadedFileNotFound, err)
+			}
+		})
+	}
+}
+
+func TestRaftSideloadingSideloadAndInline(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	mkEnt := func(version byte, index, term uint64, cmd *storagebase.RaftCommand) raftpb.Entry {
+		data, err := protoutil.Marshal(cmd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return raftpb.Entry{
+			Index: index,
+			Term:  term,
+			Data:  encodeRaftCommand(version, makeIDKey(), data),
+		}
+	}
+
+	sst := []byte("not really an sstable")
+	fatCmd := &storagebase.RaftCommand{
+		ReplicatedEvalResult: &storagebase.ReplicatedEvalResult{
+			AddSSTable: &storagebase.AddSSTable{Data: sst, CRC32: crc32.ChecksumIEEE(sst)},
+		},
+	}
+	plainCmd := &storagebase.RaftCommand{
+		ReplicatedEvalResult: &storagebase.ReplicatedEvalResult{IsLeaseRequest: true},
+	}
+
+	fat := []raftpb.Entry{
+		mkEnt(raftVersionStandard, 10, 1, plainCmd),
+		mkEnt(raftVersionSideloaded, 11, 1, fatCmd),
+		mkEnt(raftVersionStandard, 12, 1, plainCmd),
+	}
+	orig := append([]raftpb.Entry(nil), fat...)
+
+	ss := newInMemSideloadStorage()
+	thin, size, err := maybeSideloadEntries(ctx, fat, ss)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fat, orig) {
+		t.Fatal("input entries were mutated")
+	}
+	if size != int64(len(sst)) {
+		t.Fatalf("expected %d sideloaded bytes, got %d", len(sst), size)
+	}
+	if !reflect.DeepEqual(thin[0], fat[0]) || !reflect.DeepEqual(thin[2], fat[2]) {
+		t.Fatal("entries without sideloaded payloads were modified")
+	}
+	if len(thin[1].Data) >= len(fat[1].Data) {
+		t.Fatalf("entry was not thinned: %d >= %d bytes", len(thin[1].Data), len(fat[1].Data))
+	}
+	if b, err := ss.Get(ctx, 11, 1); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(b, sst) {
+		t.Fatalf("expected %q to be sideloaded, got %q", sst, b)
+	}
+
+	for i := range thin {
+		newEnt, err := maybeInlineSideloadedRaftCommand(ctx, 1, thin[i], ss)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i != 1 {
+			if newEnt != nil {
+				t.Fatalf("%d: unexpectedly inlined entry", i)
+			}
+			continue
+		}
+		if newEnt == nil || !reflect.DeepEqual(*newEnt, fat[i]) {
+			t.Fatalf("%d: expected inlined entry to match the original", i)
+		}
+	}
+
+	// Inlining a fat entry is a no-op, and a missing payload is an error.
+	if newEnt, err := maybeInlineSideloadedRaftCommand(ctx, 1, fat[1], ss); err != nil || newEnt != nil {
+		t.Fatalf("expected no-op, got %v, %v", newEnt, err)
+	}
+	if _, err := ss.TruncateTo(ctx, 12); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := maybeInlineSideloadedRaftCommand(ctx, 1, thin[1], ss); !testutils.IsError(
+		err, errSideloadedFileNotFound.Error(),
+	) {
+		t.Fatalf("expected %v, got %v", errSideloadedFileNotFound, err)
+	}
+}
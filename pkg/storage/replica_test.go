@@ -3177,6 +3177,201 @@ func TestEndTransactionBeforeHeartbeat(t *testing.T) {
 	}
 }
 
+// TestQueryIntent verifies that QueryIntent finds the intents of a
+// transaction only at the transaction's epoch and at or above the queried
+// sequence number, and returns a retry error for missing intents if asked to.
+func TestQueryIntent(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	tc := testContext{}
+	stopper := stop.NewStopper()
+	defer stopper.Stop()
+	tc.Start(t, stopper)
+
+	key := roachpb.Key("a")
+	txn := newTransaction("test", key, 1, enginepb.SERIALIZABLE, tc.Clock())
+	_, btH := beginTxnArgs(key, txn)
+	put := putArgs(key, key)
+	if _, pErr := maybeWrapWithBeginTransaction(context.Background(), tc.Sender(), btH, &put); pErr != nil {
+		t.Fatal(pErr)
+	}
+
+	higherSeq := txn.TxnMeta
+	higherSeq.Sequence++
+	higherEpoch := txn.TxnMeta
+	higherEpoch.Epoch++
+
+	testCases := []struct {
+		key   roachpb.Key
+		meta  enginepb.TxnMeta
+		found bool
+	}{
+		{key, txn.TxnMeta, true},
+		{key, higherSeq, false},
+		{key, higherEpoch, false},
+		{roachpb.Key("b"), txn.TxnMeta, false},
+	}
+	for i, c := range testCases {
+		for _, errorIfMissing := range []bool{false, true} {
+			args := roachpb.QueryIntentRequest{
+				Span:           roachpb.Span{Key: c.key},
+				Txn:            c.meta,
+				ErrorIfMissing: errorIfMissing,
+			}
+			resp, pErr := tc.SendWrappedWith(roachpb.Header{Timestamp: txn.Timestamp}, &args)
+			if !c.found && errorIfMissing {
+				if _, ok := pErr.GetDetail().(*roachpb.TransactionRetryError); !ok {
+					t.Errorf("%d: expected retry error, got %v", i, pErr)
+				}
+				continue
+			}
+			if pErr != nil {
+				t.Fatalf("%d: %s", i, pErr)
+			}
+			if found := resp.(*roachpb.QueryIntentResponse).FoundIntent; found != c.found {
+				t.Errorf("%d: expected found=%t, got %t", i, c.found, found)
+			}
+		}
+	}
+}
+
+// TestClearRange verifies that ClearRange removes all of the data in a key
+// span, both when clearing keys individually and when writing a range
+// deletion tombstone, and that the range's stats are kept accurate.
+func TestClearRange(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	tc := testContext{}
+	stopper := stop.NewStopper()
+	defer stopper.Stop()
+	tc.Start(t, stopper)
+
+	testCases := []struct {
+		prefix    string
+		valueSize int
+	}{
+		{"small", 10},
+		{"large", clearRangeBytesThreshold / 8},
+	}
+	for _, c := range testCases {
+		t.Run(c.prefix, func(t *testing.T) {
+			start := roachpb.Key(c.prefix)
+			end := start.PrefixEnd()
+			value := bytes.Repeat([]byte("v"), c.valueSize)
+			for i := 0; i < 10; i++ {
+				put := putArgs(roachpb.Key(fmt.Sprintf("%s-%02d", c.prefix, i)), value)
+				if _, pErr := tc.SendWrapped(&put); pErr != nil {
+					t.Fatal(pErr)
+				}
+			}
+			// Write a key outside of the cleared span.
+			outside := putArgs(end, value)
+			if _, pErr := tc.SendWrapped(&outside); pErr != nil {
+				t.Fatal(pErr)
+			}
+
+			clear := roachpb.ClearRangeRequest{Span: roachpb.Span{Key: start, EndKey: end}}
+			if _, pErr := tc.SendWrapped(&clear); pErr != nil {
+				t.Fatal(pErr)
+			}
+
+			scan := scanArgs(start, end.PrefixEnd())
+			reply, pErr := tc.SendWrapped(&scan)
+			if pErr != nil {
+				t.Fatal(pErr)
+			}
+			if rows := reply.(*roachpb.ScanResponse).Rows; len(rows) != 1 || !rows[0].Key.Equal(end) {
+				t.Fatalf("expected only %s to remain, got %v", end, rows)
+			}
+
+			ms := tc.repl.GetMVCCStats()
+			expMS, err := ComputeStatsForRange(tc.repl.Desc(), tc.engine, ms.LastUpdateNanos)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ms != expMS {
+				t.Errorf("expected stats %+v, got %+v", expMS, ms)
+			}
+		})
+	}
+}
+
+// TestEndTransactionStagingRecovery verifies that committing a transaction
+// with in-flight writes stages it, that pushers get back the staged record
+// unchanged, and that RecoverTxn finalizes it according to whether it was
+// implicitly committed.
+func TestEndTransactionStagingRecovery(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer setTxnAutoGC(false)()
+	tc := testContext{}
+	stopper := stop.NewStopper()
+	defer stopper.Stop()
+	tc.Start(t, stopper)
+
+	key := roachpb.Key("a")
+	for _, implicitlyCommitted := range []bool{true, false} {
+		txn := newTransaction("test", key, 1, enginepb.SERIALIZABLE, tc.Clock())
+		_, btH := beginTxnArgs(key, txn)
+		put := putArgs(key, key)
+		if _, pErr := maybeWrapWithBeginTransaction(context.Background(), tc.Sender(), btH, &put); pErr != nil {
+			t.Fatal(pErr)
+		}
+		inFlight := []roachpb.SequencedWrite{{Key: key, Sequence: txn.Sequence}}
+		txn.Sequence++
+		txn.Writing = true
+		args, h := endTxnArgs(txn, true)
+		args.IntentSpans = []roachpb.Span{{Key: key}}
+		args.InFlightWrites = inFlight
+		resp, pErr := tc.SendWrappedWith(h, &args)
+		if pErr != nil {
+			t.Fatal(pErr)
+		}
+		reply := resp.(*roachpb.EndTransactionResponse)
+		if reply.Txn.Status != roachpb.STAGING || len(reply.Txn.InFlightWrites) != 1 {
+			t.Fatalf("expected staging txn with in-flight writes, got %+v", reply.Txn)
+		}
+
+		// A pusher can't abort the staging transaction.
+		pusher := newTransaction("pusher", key, 1, enginepb.SERIALIZABLE, tc.Clock())
+		pusher.Priority = roachpb.MaxTxnPriority
+		pArgs := pushTxnArgs(pusher, txn, roachpb.PUSH_ABORT)
+		resp, pErr = tc.SendWrapped(&pArgs)
+		if pErr != nil {
+			t.Fatal(pErr)
+		}
+		if status := resp.(*roachpb.PushTxnResponse).PusheeTxn.Status; status != roachpb.STAGING {
+			t.Fatalf("expected pushee to be staging, got %s", status)
+		}
+
+		rArgs := roachpb.RecoverTxnRequest{
+			Span:                roachpb.Span{Key: txn.Key},
+			Txn:                 txn.TxnMeta,
+			ImplicitlyCommitted: implicitlyCommitted,
+		}
+		resp, pErr = tc.SendWrapped(&rArgs)
+		if pErr != nil {
+			t.Fatal(pErr)
+		}
+		recovered := resp.(*roachpb.RecoverTxnResponse).RecoveredTxn
+		expStatus := roachpb.COMMITTED
+		if !implicitlyCommitted {
+			expStatus = roachpb.ABORTED
+		}
+		if recovered.Status != expStatus || len(recovered.InFlightWrites) != 0 {
+			t.Errorf("expected recovered txn to be %s, got %+v", expStatus, recovered)
+		}
+
+		// Recovering a finalized transaction is a noop.
+		rArgs.ImplicitlyCommitted = !implicitlyCommitted
+		resp, pErr = tc.SendWrapped(&rArgs)
+		if pErr != nil {
+			t.Fatal(pErr)
+		}
+		if status := resp.(*roachpb.RecoverTxnResponse).RecoveredTxn.Status; status != expStatus {
+			t.Errorf("expected recovered txn to remain %s, got %s", expStatus, status)
+		}
+		key = key.Next()
+	}
+}
+
 // TestEndTransactionAfterHeartbeat verifies that a transaction
 // can be committed/aborted after being heartbeat.
 func TestEndTransactionAfterHeartbeat(t *testing.T) {
@@ -175,7 +175,7 @@ func (rq *replicateQueue) shouldQueue(
 	if lease, _ := repl.getLease(); lease != nil && repl.IsLeaseValid(lease, now) {
 		if rq.canTransferLease() &&
 			rq.allocator.ShouldTransferLease(
-				ctx, zone.Constraints, desc.Replicas, lease.Replica.StoreID, desc.RangeID, repl.stats) {
+				ctx, zone, desc.Replicas, lease.Replica.StoreID, desc.RangeID, repl.stats) {
 			if log.V(2) {
 				log.Infof(ctx, "lease transfer needed, enqueuing")
 			}
@@ -188,7 +188,7 @@ func (rq *replicateQueue) shouldQueue(
 	// replica to be considered a rebalancing source.
 	target, err := rq.allocator.RebalanceTarget(
 		ctx,
-		zone.Constraints,
+		zone,
 		desc.Replicas,
 		desc.RangeID,
 	)
@@ -269,7 +269,7 @@ func (rq *replicateQueue) processOneChange(
 		}
 		newStore, err := rq.allocator.AllocateTarget(
 			ctx,
-			zone.Constraints,
+			zone,
 			desc.Replicas,
 			desc.RangeID,
 			true, /* relaxConstraints */
@@ -295,7 +295,7 @@ func (rq *replicateQueue) processOneChange(
 		}
 		removeReplica, err := rq.allocator.RemoveTarget(
 			ctx,
-			zone.Constraints,
+			zone,
 			desc.Replicas,
 		)
 		if err != nil {
@@ -391,7 +391,7 @@ func (rq *replicateQueue) processOneChange(
 
 		rebalanceStore, err := rq.allocator.RebalanceTarget(
 			ctx,
-			zone.Constraints,
+			zone,
 			desc.Replicas,
 			desc.RangeID,
 		)
@@ -434,7 +434,7 @@ func (rq *replicateQueue) transferLease(
 	candidates := filterBehindReplicas(repl.RaftStatus(), desc.Replicas)
 	if target := rq.allocator.TransferLeaseTarget(
 		ctx,
-		zone.Constraints,
+		zone,
 		candidates,
 		repl.store.StoreID(),
 		desc.RangeID,
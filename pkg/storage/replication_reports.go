@@ -0,0 +1,318 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/config"
+	"github.com/cockroachdb/cockroach/pkg/internal/client"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+)
+
+// ReplicationReportsInterval is the interval at which the store holding the
+// lease on the first range regenerates system.replication_reports.
+var ReplicationReportsInterval = settings.RegisterNonNegativeDurationSetting(
+	"kv.replication_reports.interval",
+	"the frequency at which the replication reports are regenerated (0 disables)",
+	time.Minute,
+)
+
+// replicationReportsScanBatchSize is the number of range descriptors read from
+// meta2 per request while generating the replication reports.
+const replicationReportsScanBatchSize = 10000
+
+// replicationReportKey identifies a row of system.replication_reports. A
+// subzone is identified by its index ID and partition name; an index ID of 0
+// stands for the zone of the object itself. The empty locality stands for all
+// the ranges of the zone.
+type replicationReportKey struct {
+	zoneID, indexID uint32
+	partitionName   string
+	locality        string
+}
+
+// replicationReport holds the range counts of a row of
+// system.replication_reports. Each count only includes the ranges of the zone
+// that have a replica in the locality of the row.
+type replicationReport struct {
+	// totalRanges is the number of ranges.
+	totalRanges int64
+	// unavailableRanges is the number of ranges whose live replicas do not
+	// form a quorum.
+	unavailableRanges int64
+	// underReplicatedRanges is the number of ranges with fewer live replicas
+	// than the zone config asks for.
+	underReplicatedRanges int64
+	// violatingRanges is the number of ranges with replicas that do not
+	// satisfy the constraints or the replica constraints of the zone config.
+	violatingRanges int64
+	// singleLocalityRanges is the number of ranges whose replicas all sit in
+	// the locality, or in any single locality for the empty locality.
+	singleLocalityRanges int64
+	// atRiskRanges is the number of available ranges that would become
+	// unavailable if the locality failed, or if any single locality failed for
+	// the empty locality.
+	atRiskRanges int64
+}
+
+// replicationReportsBuilder accumulates the replication reports of the ranges
+// added to it.
+type replicationReportsBuilder struct {
+	cfg config.SystemConfig
+	// getStore returns the descriptor of a store, or false if it is unknown.
+	getStore func(roachpb.StoreID) (roachpb.StoreDescriptor, bool)
+	// isLive returns whether a node is live.
+	isLive  func(roachpb.NodeID) bool
+	reports map[replicationReportKey]*replicationReport
+}
+
+func makeReplicationReportsBuilder(
+	cfg config.SystemConfig,
+	getStore func(roachpb.StoreID) (roachpb.StoreDescriptor, bool),
+	isLive func(roachpb.NodeID) bool,
+) replicationReportsBuilder {
+	return replicationReportsBuilder{
+		cfg:      cfg,
+		getStore: getStore,
+		isLive:   isLive,
+		reports:  make(map[replicationReportKey]*replicationReport),
+	}
+}
+
+// addRange adds the range with the given descriptor to the reports of its
+// zone.
+func (b *replicationReportsBuilder) addRange(desc roachpb.RangeDescriptor) error {
+	zoneID, subzone, zone, err := b.cfg.GetZoneForKey(desc.StartKey)
+	if err != nil {
+		return err
+	}
+	var indexID uint32
+	var partitionName string
+	if subzone != nil {
+		indexID, partitionName = subzone.IndexID, subzone.PartitionName
+	}
+
+	numReplicas := len(desc.Replicas)
+	quorum := numReplicas/2 + 1
+	var numLive int
+	var violating bool
+	stores := make([]roachpb.StoreDescriptor, 0, numReplicas)
+	// replicasIn and liveIn count the replicas, and the live replicas, in each
+	// of the localities of the nodes of the replicas, including the localities
+	// that only specify a prefix of their tiers.
+	replicasIn := make(map[string]int)
+	liveIn := make(map[string]int)
+	for _, repl := range desc.Replicas {
+		live := b.isLive(repl.NodeID)
+		if live {
+			numLive++
+		}
+		store, ok := b.getStore(repl.StoreID)
+		if !ok {
+			// Neither the locality nor the attributes of the store are known, so
+			// the replica can't be checked against the zone config.
+			continue
+		}
+		stores = append(stores, store)
+		if ok, _ := constraintCheck(store, zone.Constraints); !ok {
+			violating = true
+		}
+		tiers := store.Node.Locality.Tiers
+		for i := range tiers {
+			locality := roachpb.Locality{Tiers: tiers[:i+1]}.String()
+			replicasIn[locality]++
+			if live {
+				liveIn[locality]++
+			}
+		}
+	}
+	counts := replicaConstraintCounts(zone.ReplicaConstraints, stores, nil)
+	for i, c := range zone.ReplicaConstraints.Constraints {
+		if counts[i] != int(c.NumReplicas) {
+			violating = true
+		}
+	}
+	unavailable := numLive < quorum
+	underReplicated := numLive < int(zone.NumReplicas)
+
+	add := func(locality string, singleLocality, atRisk bool) {
+		key := replicationReportKey{
+			zoneID: zoneID, indexID: indexID, partitionName: partitionName, locality: locality,
+		}
+		report, ok := b.reports[key]
+		if !ok {
+			report = &replicationReport{}
+			b.reports[key] = report
+		}
+		report.totalRanges++
+		if unavailable {
+			report.unavailableRanges++
+		}
+		if underReplicated {
+			report.underReplicatedRanges++
+		}
+		if violating {
+			report.violatingRanges++
+		}
+		if singleLocality {
+			report.singleLocalityRanges++
+		}
+		if atRisk {
+			report.atRiskRanges++
+		}
+	}
+	var anySingleLocality, anyAtRisk bool
+	for locality, n := range replicasIn {
+		singleLocality := n == numReplicas
+		atRisk := !unavailable && numLive-liveIn[locality] < quorum
+		add(locality, singleLocality, atRisk)
+		anySingleLocality = anySingleLocality || singleLocality
+		anyAtRisk = anyAtRisk || atRisk
+	}
+	add("", anySingleLocality, anyAtRisk)
+	return nil
+}
+
+// sortedKeys returns the keys of the reports in the order of the primary key
+// of system.replication_reports.
+func (b *replicationReportsBuilder) sortedKeys() []replicationReportKey {
+	reportKeys := make([]replicationReportKey, 0, len(b.reports))
+	for key := range b.reports {
+		reportKeys = append(reportKeys, key)
+	}
+	sort.Slice(reportKeys, func(i, j int) bool {
+		a, b := reportKeys[i], reportKeys[j]
+		if a.zoneID != b.zoneID {
+			return a.zoneID < b.zoneID
+		}
+		if a.indexID != b.indexID {
+			return a.indexID < b.indexID
+		}
+		if a.partitionName != b.partitionName {
+			return a.partitionName < b.partitionName
+		}
+		return a.locality < b.locality
+	})
+	return reportKeys
+}
+
+// startReplicationReports starts a worker that periodically regenerates the
+// replication reports if the store holds the lease on the first range.
+func (s *Store) startReplicationReports() {
+	s.stopper.RunWorker(func() {
+		ctx := s.AnnotateCtx(context.Background())
+		timer := timeutil.NewTimer()
+		defer timer.Stop()
+		for {
+			// The interval is read again after each run, so that changes to the
+			// setting are picked up. While the reports are disabled, the setting
+			// is checked again every minute.
+			interval := ReplicationReportsInterval.Get()
+			if interval == 0 {
+				interval = time.Minute
+			}
+			timer.Reset(interval)
+			select {
+			case <-timer.C:
+				timer.Read = true
+			case <-s.stopper.ShouldStop():
+				return
+			}
+			if ReplicationReportsInterval.Get() == 0 {
+				continue
+			}
+			if repl := s.LookupReplica(roachpb.RKeyMin, nil); repl == nil ||
+				!repl.ownsValidLease(s.cfg.Clock.Now()) {
+				continue
+			}
+			if err := s.generateReplicationReports(ctx); err != nil {
+				log.Warningf(ctx, "could not generate replication reports: %s", err)
+			}
+		}
+	})
+}
+
+// generateReplicationReports walks the range descriptors in the meta ranges
+// and replaces the contents of system.replication_reports with the reports of
+// their zones.
+func (s *Store) generateReplicationReports(ctx context.Context) error {
+	cfg, ok := s.cfg.Gossip.GetSystemConfig()
+	if !ok {
+		return errors.New("no system config available")
+	}
+	isLive := s.cfg.NodeLiveness.GetIsLiveMap()
+	b := makeReplicationReportsBuilder(cfg, s.cfg.StorePool.getStoreDescriptor,
+		func(nodeID roachpb.NodeID) bool { return isLive[nodeID] })
+
+	// The range descriptors are read in batches, so that a large cluster's
+	// meta2 isn't read in a single request.
+	for start := roachpb.Key(keys.Meta2Prefix); ; {
+		rows, err := s.DB().Scan(ctx, start, keys.MetaMax, replicationReportsScanBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			var desc roachpb.RangeDescriptor
+			if err := row.ValueProto(&desc); err != nil {
+				return err
+			}
+			if err := b.addRange(desc); err != nil {
+				return err
+			}
+		}
+		if len(rows) < replicationReportsScanBatchSize {
+			break
+		}
+		start = rows[len(rows)-1].Key.Next()
+	}
+
+	const insertReportStmt = `
+INSERT INTO system.replication_reports (
+  zone_id, index_id, partition_name, locality, total_ranges,
+  unavailable_ranges, under_replicated_ranges, violating_ranges,
+  single_locality_ranges, at_risk_ranges, generated
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+`
+	generated := s.cfg.Clock.PhysicalTime()
+	return s.DB().Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
+		if _, err := s.cfg.SQLExecutor.ExecuteStatementInTransaction(
+			ctx, "delete-replication-reports", txn, `DELETE FROM system.replication_reports`,
+		); err != nil {
+			return err
+		}
+		for _, key := range b.sortedKeys() {
+			r := b.reports[key]
+			if _, err := s.cfg.SQLExecutor.ExecuteStatementInTransaction(
+				ctx, "insert-replication-report", txn, insertReportStmt,
+				key.zoneID, key.indexID, key.partitionName, key.locality, r.totalRanges,
+				r.unavailableRanges, r.underReplicatedRanges, r.violatingRanges,
+				r.singleLocalityRanges, r.atRiskRanges, generated,
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
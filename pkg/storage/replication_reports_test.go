@@ -0,0 +1,139 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kr/pretty"
+
+	"github.com/cockroachdb/cockroach/pkg/config"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/util/encoding"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/stop"
+)
+
+func TestReplicationReports(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	stopper := stop.NewStopper()
+	defer stopper.Stop()
+	config.TestingSetupZoneConfigHook(stopper)
+
+	const tableID = keys.MaxReservedDescID + 1
+	zone := config.DefaultZoneConfig()
+	zone.Constraints = config.Constraints{Constraints: []config.Constraint{
+		{Type: config.Constraint_PROHIBITED, Key: "region", Value: "ap"},
+	}}
+	config.TestingSetZoneConfig(tableID, zone)
+
+	// Every store is on the node with the same ID. Node 4 is dead.
+	localities := map[roachpb.StoreID][]roachpb.Tier{
+		1: {{Key: "region", Value: "us"}, {Key: "zone", Value: "a"}},
+		2: {{Key: "region", Value: "us"}, {Key: "zone", Value: "b"}},
+		3: {{Key: "region", Value: "eu"}, {Key: "zone", Value: "a"}},
+		4: {{Key: "region", Value: "eu"}, {Key: "zone", Value: "b"}},
+		5: {{Key: "region", Value: "ap"}, {Key: "zone", Value: "a"}},
+	}
+	getStore := func(storeID roachpb.StoreID) (roachpb.StoreDescriptor, bool) {
+		tiers, ok := localities[storeID]
+		return roachpb.StoreDescriptor{
+			StoreID: storeID,
+			Node: roachpb.NodeDescriptor{
+				NodeID:   roachpb.NodeID(storeID),
+				Locality: roachpb.Locality{Tiers: tiers},
+			},
+		}, ok
+	}
+	isLive := func(nodeID roachpb.NodeID) bool {
+		return nodeID != 4
+	}
+	b := makeReplicationReportsBuilder(config.SystemConfig{}, getStore, isLive)
+
+	rangeDesc := func(tableID uint32, indexID uint64, storeIDs ...roachpb.StoreID) roachpb.RangeDescriptor {
+		desc := roachpb.RangeDescriptor{
+			StartKey: encoding.EncodeUvarintAscending(keys.MakeTablePrefix(tableID), indexID),
+		}
+		for _, storeID := range storeIDs {
+			desc.Replicas = append(desc.Replicas, roachpb.ReplicaDescriptor{
+				NodeID:  roachpb.NodeID(storeID),
+				StoreID: storeID,
+			})
+		}
+		return desc
+	}
+	for _, desc := range []roachpb.RangeDescriptor{
+		// Losing region=us loses the quorum of this range.
+		rangeDesc(tableID, 1, 1, 2, 3),
+		// This range has a replica in the prohibited region.
+		rangeDesc(tableID, 2, 1, 3, 5),
+		// This range has lost its quorum, and all its replicas are in region=eu.
+		rangeDesc(tableID, 3, 3, 4),
+		// This range has the default zone config, which asks for 3 replicas.
+		rangeDesc(tableID+1, 1, 1),
+	} {
+		if err := b.addRange(desc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	type report struct {
+		total, unavailable, underReplicated, violating, singleLocality, atRisk int64
+	}
+	key := func(zoneID uint32, locality string) replicationReportKey {
+		return replicationReportKey{zoneID: zoneID, locality: locality}
+	}
+	expected := map[replicationReportKey]report{
+		key(tableID, ""):                 {3, 1, 1, 1, 1, 1},
+		key(tableID, "region=us"):        {2, 0, 0, 1, 0, 1},
+		key(tableID, "region=us,zone=a"): {2, 0, 0, 1, 0, 0},
+		key(tableID, "region=us,zone=b"): {1, 0, 0, 0, 0, 0},
+		key(tableID, "region=eu"):        {3, 1, 1, 1, 1, 0},
+		key(tableID, "region=eu,zone=a"): {3, 1, 1, 1, 0, 0},
+		key(tableID, "region=eu,zone=b"): {1, 1, 1, 0, 0, 0},
+		key(tableID, "region=ap"):        {1, 0, 0, 1, 0, 0},
+		key(tableID, "region=ap,zone=a"): {1, 0, 0, 1, 0, 0},
+
+		key(tableID+1, ""):                 {1, 0, 1, 0, 1, 1},
+		key(tableID+1, "region=us"):        {1, 0, 1, 0, 1, 1},
+		key(tableID+1, "region=us,zone=a"): {1, 0, 1, 0, 1, 1},
+	}
+	actual := make(map[replicationReportKey]report, len(b.reports))
+	for k, r := range b.reports {
+		actual[k] = report{
+			total:           r.totalRanges,
+			unavailable:     r.unavailableRanges,
+			underReplicated: r.underReplicatedRanges,
+			violating:       r.violatingRanges,
+			singleLocality:  r.singleLocalityRanges,
+			atRisk:          r.atRiskRanges,
+		}
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("unexpected reports:\n%s", pretty.Diff(expected, actual))
+	}
+
+	sortedKeys := b.sortedKeys()
+	if len(sortedKeys) != len(expected) {
+		t.Fatalf("expected %d sorted keys, got %d", len(expected), len(sortedKeys))
+	}
+	if first, last := sortedKeys[0], sortedKeys[len(sortedKeys)-1]; first != key(tableID, "") ||
+		last != key(tableID+1, "region=us,zone=a") {
+		t.Errorf("unexpected order of sorted keys: %v", sortedKeys)
+	}
+}
@@ -54,14 +54,20 @@ type candidate struct {
 	constraintScore float64
 	rangeCount      int
 	details         string
+	// replicaConstraintScore measures how much a replica on the store furthers
+	// the per-replica constraints of the zone. It takes precedence over the
+	// constraint score.
+	replicaConstraintScore int
 }
 
 func (c candidate) String() string {
-	return fmt.Sprintf("s%d, valid:%t, con:%.2f, ranges:%d, details:(%s)",
-		c.store.StoreID, c.valid, c.constraintScore, c.rangeCount, c.details)
+	return fmt.Sprintf("s%d, valid:%t, rcon:%d, con:%.2f, ranges:%d, details:(%s)",
+		c.store.StoreID, c.valid, c.replicaConstraintScore, c.constraintScore, c.rangeCount,
+		c.details)
 }
 
-// less first compares valid, then constraint scores, then range counts.
+// less first compares valid, then replica constraint scores, then constraint
+// scores, then range counts.
 func (c candidate) less(o candidate) bool {
 	if !o.valid {
 		return false
@@ -69,6 +75,9 @@ func (c candidate) less(o candidate) bool {
 	if !c.valid {
 		return true
 	}
+	if c.replicaConstraintScore != o.replicaConstraintScore {
+		return c.replicaConstraintScore < o.replicaConstraintScore
+	}
 	if c.constraintScore != o.constraintScore {
 		return c.constraintScore < o.constraintScore
 	}
@@ -107,7 +116,8 @@ var _ sort.Interface = byScoreAndID(nil)
 
 func (c byScoreAndID) Len() int { return len(c) }
 func (c byScoreAndID) Less(i, j int) bool {
-	if c[i].constraintScore == c[j].constraintScore &&
+	if c[i].replicaConstraintScore == c[j].replicaConstraintScore &&
+		c[i].constraintScore == c[j].constraintScore &&
 		c[i].rangeCount == c[j].rangeCount &&
 		c[i].valid == c[j].valid {
 		return c[i].store.StoreID < c[j].store.StoreID
@@ -128,14 +138,16 @@ func (cl candidateList) onlyValid() candidateList {
 }
 
 // best returns all the elements in a sorted (by score reversed) candidate list
-// that share the highest constraint score and are valid.
+// that share the highest replica constraint and constraint scores and are
+// valid.
 func (cl candidateList) best() candidateList {
 	cl = cl.onlyValid()
 	if len(cl) <= 1 {
 		return cl
 	}
 	for i := 1; i < len(cl); i++ {
-		if cl[i].constraintScore < cl[0].constraintScore {
+		if cl[i].replicaConstraintScore < cl[0].replicaConstraintScore ||
+			cl[i].constraintScore < cl[0].constraintScore {
 			return cl[:i]
 		}
 	}
@@ -143,7 +155,7 @@ func (cl candidateList) best() candidateList {
 }
 
 // worst returns all the elements in a sorted (by score reversed) candidate
-// list that share the lowest constraint score.
+// list that share the lowest replica constraint and constraint scores.
 func (cl candidateList) worst() candidateList {
 	if len(cl) <= 1 {
 		return cl
@@ -157,8 +169,10 @@ func (cl candidateList) worst() candidateList {
 		}
 	}
 	// Find the worst constraint values.
+	last := cl[len(cl)-1]
 	for i := len(cl) - 2; i >= 0; i-- {
-		if cl[i].constraintScore > cl[len(cl)-1].constraintScore {
+		if cl[i].replicaConstraintScore > last.replicaConstraintScore ||
+			cl[i].constraintScore > last.constraintScore {
 			return cl[i+1:]
 		}
 	}
@@ -226,10 +240,16 @@ func (cl candidateList) selectBad(randGen allocatorRand) *roachpb.StoreDescripto
 func allocateCandidates(
 	sl StoreList,
 	constraints config.Constraints,
+	replicaConstraints config.ReplicaConstraints,
 	existing []roachpb.ReplicaDescriptor,
 	existingNodeLocalities map[roachpb.NodeID]roachpb.Locality,
 	deterministic bool,
 ) candidateList {
+	existingStoreIDs := make(map[roachpb.StoreID]struct{}, len(existing))
+	for _, repl := range existing {
+		existingStoreIDs[repl.StoreID] = struct{}{}
+	}
+	counts := replicaConstraintCounts(replicaConstraints, sl.stores, existingStoreIDs)
 	var candidates candidateList
 	for _, s := range sl.stores {
 		if !preexistingReplicaCheck(s.Node.NodeID, existing) {
@@ -250,6 +270,7 @@ func allocateCandidates(
 			rangeCount:      int(s.Capacity.RangeCount),
 			details: fmt.Sprintf("diversity=%.2f, preferred=%d",
 				diversityScore, preferredMatched),
+			replicaConstraintScore: replicaConstraintsAddScore(s, replicaConstraints, counts),
 		})
 	}
 	if deterministic {
@@ -266,9 +287,11 @@ func allocateCandidates(
 func removeCandidates(
 	sl StoreList,
 	constraints config.Constraints,
+	replicaConstraints config.ReplicaConstraints,
 	existingNodeLocalities map[roachpb.NodeID]roachpb.Locality,
 	deterministic bool,
 ) candidateList {
+	counts := replicaConstraintCounts(replicaConstraints, sl.stores, nil /* storeIDs */)
 	var candidates candidateList
 	for _, s := range sl.stores {
 		constraintsOk, preferredMatched := constraintCheck(s, constraints)
@@ -305,6 +328,7 @@ func removeCandidates(
 			rangeCount:      int(s.Capacity.RangeCount),
 			details: fmt.Sprintf("diversity=%.2f, preferred=%d, converge=%.2f",
 				diversityScore, preferredMatched, convergesScore),
+			replicaConstraintScore: replicaConstraintsRemoveScore(s, replicaConstraints, counts),
 		})
 	}
 	if deterministic {
@@ -323,6 +347,7 @@ func rebalanceCandidates(
 	ctx context.Context,
 	sl StoreList,
 	constraints config.Constraints,
+	replicaConstraints config.ReplicaConstraints,
 	existing []roachpb.ReplicaDescriptor,
 	existingNodeLocalities map[roachpb.NodeID]roachpb.Locality,
 	deterministic bool,
@@ -333,6 +358,7 @@ func rebalanceCandidates(
 	for _, repl := range existing {
 		existingStoreIDs[repl.StoreID] = struct{}{}
 	}
+	counts := replicaConstraintCounts(replicaConstraints, sl.stores, existingStoreIDs)
 
 	// Go through all the stores and find all that match the constraints so that
 	// we can have accurate stats for rebalance calculations.
@@ -358,6 +384,14 @@ func rebalanceCandidates(
 		}
 	}
 
+	if !rebalanceConstraintsCheck && replicaConstraintsViolated(
+		replicaConstraints, counts, constraintsOkStoreDescriptors, existingStoreIDs) {
+		rebalanceConstraintsCheck = true
+		if log.V(2) {
+			log.Infof(ctx, "must rebalance due to replica constraints %v", counts)
+		}
+	}
+
 	constraintsOkStoreList := makeStoreList(constraintsOkStoreDescriptors)
 	var shouldRebalanceCheck bool
 	if !rebalanceConstraintsCheck {
@@ -415,6 +449,7 @@ func rebalanceCandidates(
 				rangeCount:      int(s.Capacity.RangeCount),
 				details: fmt.Sprintf("diversity=%.2f, preferred=%d, converge=%.2f",
 					diversityScore, storeInfo.matched, convergesScore),
+				replicaConstraintScore: replicaConstraintsRemoveScore(s, replicaConstraints, counts),
 			})
 		} else {
 			if !storeInfo.ok || !maxCapacityOK {
@@ -439,6 +474,7 @@ func rebalanceCandidates(
 				rangeCount:      int(s.Capacity.RangeCount),
 				details: fmt.Sprintf("diversity=%.2f, preferred=%d, converge=%.2f",
 					diversityScore, storeInfo.matched, convergesScore),
+				replicaConstraintScore: replicaConstraintsAddScore(s, replicaConstraints, counts),
 			})
 		}
 	}
@@ -548,6 +584,106 @@ func constraintCheck(store roachpb.StoreDescriptor, constraints config.Constrain
 	return true, positive
 }
 
+// replicaConstraintCounts returns, for each of the per-replica constraints,
+// the number of stores satisfying it. Only the stores in storeIDs are counted,
+// unless storeIDs is nil.
+func replicaConstraintCounts(
+	replicaConstraints config.ReplicaConstraints,
+	stores []roachpb.StoreDescriptor,
+	storeIDs map[roachpb.StoreID]struct{},
+) []int {
+	if len(replicaConstraints.Constraints) == 0 {
+		return nil
+	}
+	counts := make([]int, len(replicaConstraints.Constraints))
+	for _, s := range stores {
+		if storeIDs != nil {
+			if _, ok := storeIDs[s.StoreID]; !ok {
+				continue
+			}
+		}
+		for i, c := range replicaConstraints.Constraints {
+			if ok, _ := constraintCheck(s, c); ok {
+				counts[i]++
+			}
+		}
+	}
+	return counts
+}
+
+// replicaConstraintsAddScore returns how much adding a replica on the store
+// furthers the per-replica constraints, given the number of existing replicas
+// satisfying each of them: a point for each under-satisfied constraint that the
+// store satisfies, minus a point for each constraint that it would
+// over-satisfy.
+func replicaConstraintsAddScore(
+	store roachpb.StoreDescriptor, replicaConstraints config.ReplicaConstraints, counts []int,
+) int {
+	var score int
+	for i, c := range replicaConstraints.Constraints {
+		if ok, _ := constraintCheck(store, c); !ok {
+			continue
+		}
+		if counts[i] < int(c.NumReplicas) {
+			score++
+		} else {
+			score--
+		}
+	}
+	return score
+}
+
+// replicaConstraintsRemoveScore returns how much keeping the replica on the
+// store furthers the per-replica constraints, given the number of replicas,
+// including this one, satisfying each of them: a point for each constraint that
+// the store satisfies and that removing the replica would under-satisfy, minus
+// a point for each constraint that the store satisfies and that is
+// over-satisfied. Replicas with lower scores are better removal targets.
+func replicaConstraintsRemoveScore(
+	store roachpb.StoreDescriptor, replicaConstraints config.ReplicaConstraints, counts []int,
+) int {
+	var score int
+	for i, c := range replicaConstraints.Constraints {
+		if ok, _ := constraintCheck(store, c); !ok {
+			continue
+		}
+		if counts[i] > int(c.NumReplicas) {
+			score--
+		} else {
+			score++
+		}
+	}
+	return score
+}
+
+// replicaConstraintsViolated returns true if the existing replicas
+// over-satisfy any of the per-replica constraints, or under-satisfy one that
+// a store without a replica could satisfy.
+func replicaConstraintsViolated(
+	replicaConstraints config.ReplicaConstraints,
+	counts []int,
+	stores []roachpb.StoreDescriptor,
+	existingStoreIDs map[roachpb.StoreID]struct{},
+) bool {
+	for i, c := range replicaConstraints.Constraints {
+		if counts[i] > int(c.NumReplicas) {
+			return true
+		}
+		if counts[i] == int(c.NumReplicas) {
+			continue
+		}
+		for _, s := range stores {
+			if _, ok := existingStoreIDs[s.StoreID]; ok {
+				continue
+			}
+			if ok, _ := constraintCheck(s, c); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // diversityScore returns a score between 1 and 0 where higher scores are stores
 // with the fewest locality tiers in common with already existing replicas.
 func diversityScore(
@@ -41,6 +41,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/keys"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/rpc"
+	"github.com/cockroachdb/cockroach/pkg/settings"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlutil"
 	"github.com/cockroachdb/cockroach/pkg/storage/engine"
 	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
@@ -48,8 +49,10 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/util/bufalloc"
 	"github.com/cockroachdb/cockroach/pkg/util/envutil"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/humanizeutil"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/cockroach/pkg/util/metric"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
 	"github.com/cockroachdb/cockroach/pkg/util/retry"
 	"github.com/cockroachdb/cockroach/pkg/util/shuffle"
 	"github.com/cockroachdb/cockroach/pkg/util/stop"
@@ -1251,6 +1254,12 @@ func (s *Store) Start(ctx context.Context, stopper *stop.Stopper) error {
 			}
 		})
 
+		// Start the worker that regenerates the replication reports. It needs
+		// the SQL executor to write them, which is missing in some tests.
+		if s.cfg.SQLExecutor != nil && s.cfg.NodeLiveness != nil && s.cfg.StorePool != nil {
+			s.startReplicationReports()
+		}
+
 		// Run metrics computation up front to populate initial statistics.
 		if err = s.ComputeMetrics(ctx, -1); err != nil {
 			log.Infof(ctx, "%s: failed initial metrics computation: %s", s, err)
@@ -1460,6 +1469,24 @@ func (s *Store) maybeGossipOnCapacityChange(ctx context.Context, cce capacityCha
 	}
 }
 
+// compactSpans asynchronously compacts the given spans of the store's
+// engine. It is used after data has been removed with range deletion
+// tombstones, which are only reclaimed from disk once compacted.
+func (s *Store) compactSpans(ctx context.Context, spans []roachpb.Span) {
+	if err := s.stopper.RunAsyncTask(ctx, func(ctx context.Context) {
+		for _, span := range spans {
+			start := engine.MakeMVCCMetadataKey(span.Key)
+			end := engine.MakeMVCCMetadataKey(span.EndKey)
+			if err := s.engine.CompactRange(start, end); err != nil {
+				log.Warningf(ctx, "unable to compact %s: %s", span, err)
+				return
+			}
+		}
+	}); err != nil {
+		log.Warningf(ctx, "unable to compact %d spans: %s", len(spans), err)
+	}
+}
+
 func (s *Store) canCampaignIdleReplica() bool {
 	s.idleReplicaElectionTime.Lock()
 	defer s.idleReplicaElectionTime.Unlock()
@@ -2632,6 +2659,21 @@ func (s *Store) Send(
 	}
 }
 
+// RangeFeed registers a range feed on the replica specified in the request
+// header. See Replica.RangeFeed.
+func (s *Store) RangeFeed(
+	args *roachpb.RangeFeedRequest, stream roachpb.Internal_RangeFeedServer,
+) *roachpb.Error {
+	if err := verifyKeys(args.Span.Key, args.Span.EndKey, true); err != nil {
+		return roachpb.NewError(err)
+	}
+	repl, err := s.GetReplica(args.RangeID)
+	if err != nil {
+		return roachpb.NewError(err)
+	}
+	return repl.RangeFeed(args, stream)
+}
+
 // reserveSnapshot throttles incoming snapshots. The returned closure is used
 // to cleanup the reservation and release its resources. A nil cleanup function
 // and a nil error indicates the reservation was declined.
@@ -3180,10 +3222,27 @@ type SnapshotStorePool interface {
 	throttle(reason throttleReason, toStoreID roachpb.StoreID)
 }
 
-var preemptiveSnapshotRate = envutil.EnvOrDefaultBytes(
-	"COCKROACH_PREEMPTIVE_SNAPSHOT_RATE", 2<<20 /* 2 MB */)
-var raftSnapshotRate = envutil.EnvOrDefaultBytes(
-	"COCKROACH_RAFT_SNAPSHOT_RATE", 8<<20 /* 8 MB */)
+// validateSnapshotRate rejects rates too low for snapshots to make progress.
+func validateSnapshotRate(v int64) error {
+	if v < 1<<20 {
+		return errors.Errorf("snapshot rate cannot be set to a value below 1.0 MiB: %s",
+			humanizeutil.IBytes(v))
+	}
+	return nil
+}
+
+var preemptiveSnapshotRate = settings.RegisterValidatedByteSizeSetting(
+	"kv.snapshot_rebalance.max_rate",
+	"the rate limit (bytes/sec) to use for rebalance snapshots",
+	2<<20, /* 2 MB */
+	validateSnapshotRate,
+)
+var raftSnapshotRate = settings.RegisterValidatedByteSizeSetting(
+	"kv.snapshot_recovery.max_rate",
+	"the rate limit (bytes/sec) to use for recovery snapshots",
+	8<<20, /* 8 MB */
+	validateSnapshotRate,
+)
 
 // sendSnapshot sends an outgoing snapshot via a pre-opened GRPC stream.
 func sendSnapshot(
@@ -3242,9 +3301,9 @@ func sendSnapshot(
 	// which seems to disable the rate limiting, or call WaitN in smaller than
 	// burst size chunks which caused excessive slowness in testing. Would be
 	// nice to figure this out, but the batches/sec rate limit works for now.
-	targetRate := rate.Limit(raftSnapshotRate) / batchSize
+	targetRate := rate.Limit(raftSnapshotRate.Get()) / batchSize
 	if header.CanDecline {
-		targetRate = rate.Limit(preemptiveSnapshotRate) / batchSize
+		targetRate = rate.Limit(preemptiveSnapshotRate.Get()) / batchSize
 	}
 	limiter := rate.NewLimiter(targetRate, 1 /* burst size */)
 
@@ -3305,15 +3364,34 @@ func sendSnapshot(
 	endIndex := snap.RaftSnap.Metadata.Index + 1
 	logEntries := make([][]byte, 0, endIndex-firstIndex)
 
+	rangeID := header.State.Desc.RangeID
+
 	scanFunc := func(kv roachpb.KeyValue) (bool, error) {
 		bytes, err := kv.Value.GetBytes()
-		if err == nil {
-			logEntries = append(logEntries, bytes)
+		if err != nil {
+			return false, err
+		}
+		// Sideloaded payloads are not stored in the log, so they have to be
+		// inlined into the entries we send.
+		if snap.sideloaded != nil {
+			var ent raftpb.Entry
+			if err := ent.Unmarshal(bytes); err != nil {
+				return false, err
+			}
+			newEnt, err := maybeInlineSideloadedRaftCommand(ctx, rangeID, ent, snap.sideloaded)
+			if err != nil {
+				return false, err
+			}
+			if newEnt != nil {
+				if bytes, err = protoutil.Marshal(newEnt); err != nil {
+					return false, err
+				}
+			}
 		}
-		return false, err
+		logEntries = append(logEntries, bytes)
+		return false, nil
 	}
 
-	rangeID := header.State.Desc.RangeID
 	if err := iterateEntries(ctx, snap.EngineSnap, rangeID, firstIndex, endIndex, scanFunc); err != nil {
 		return err
 	}
@@ -3814,6 +3892,7 @@ func (s *Store) updateReplicationGauges(ctx context.Context) error {
 		leaseHolderCount              int64
 		leaseExpirationCount          int64
 		leaseEpochCount               int64
+		leasePreferencesViolating     int64
 		raftLeaderNotLeaseHolderCount int64
 		quiescentCount                int64
 
@@ -3840,6 +3919,13 @@ func (s *Store) updateReplicationGauges(ctx context.Context) error {
 		}
 		if metrics.leaseholder {
 			leaseHolderCount++
+			desc := rep.Desc()
+			if zone, err := cfg.GetZoneConfigForKey(desc.StartKey); err != nil {
+				log.Error(ctx, err)
+			} else if s.allocator.leaseViolatesPreferences(
+				zone, desc.Replicas, s.StoreID(), desc.RangeID) {
+				leasePreferencesViolating++
+			}
 		}
 		switch metrics.leaseType {
 		case roachpb.LeaseNone:
@@ -3870,6 +3956,7 @@ func (s *Store) updateReplicationGauges(ctx context.Context) error {
 	s.metrics.LeaseHolderCount.Update(leaseHolderCount)
 	s.metrics.LeaseExpirationCount.Update(leaseExpirationCount)
 	s.metrics.LeaseEpochCount.Update(leaseEpochCount)
+	s.metrics.LeasePreferencesViolatingCount.Update(leasePreferencesViolating)
 	s.metrics.QuiescentCount.Update(quiescentCount)
 
 	s.metrics.RangeCount.Update(rangeCount)
@@ -191,6 +191,21 @@ func (ls *Stores) Send(
 	return br, pErr
 }
 
+// RangeFeed registers a range feed on the store and replica specified in the
+// request header. See Replica.RangeFeed.
+func (ls *Stores) RangeFeed(
+	args *roachpb.RangeFeedRequest, stream roachpb.Internal_RangeFeedServer,
+) *roachpb.Error {
+	if args.RangeID == 0 || args.Replica.StoreID == 0 {
+		return roachpb.NewErrorf("range feed requires the range and replica to be specified")
+	}
+	store, err := ls.GetStore(args.Replica.StoreID)
+	if err != nil {
+		return roachpb.NewError(err)
+	}
+	return store.RangeFeed(args, stream)
+}
+
 // LookupReplica looks up replica by key [range]. Lookups are done
 // by consulting each store in turn via Store.LookupReplica(key).
 // Returns RangeID and replica on success; RangeKeyMismatch error
@@ -0,0 +1,77 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/internal/client"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+)
+
+// recoverTxn determines the outcome of a STAGING transaction whose
+// coordinator may have failed before committing it explicitly. Each of the
+// transaction's in-flight writes is queried at the transaction's timestamp:
+// if all of them are found, the transaction is implicitly committed;
+// otherwise, querying a missing write prevents it from ever succeeding (it
+// bumps the timestamp cache), so the transaction can be aborted. The
+// finalized transaction is returned. If the transaction was restarted or
+// finalized in the meantime, its current record is returned instead.
+func recoverTxn(
+	ctx context.Context, db *client.DB, txn *roachpb.Transaction,
+) (*roachpb.Transaction, error) {
+	if txn.Status != roachpb.STAGING {
+		return txn, nil
+	}
+
+	implicitlyCommitted := true
+	if len(txn.InFlightWrites) > 0 {
+		b := &client.Batch{}
+		b.Header.Timestamp = txn.Timestamp
+		for _, w := range txn.InFlightWrites {
+			meta := txn.TxnMeta
+			meta.Sequence = w.Sequence
+			b.AddRawRequest(&roachpb.QueryIntentRequest{
+				Span: roachpb.Span{Key: w.Key},
+				Txn:  meta,
+			})
+		}
+		if err := db.Run(ctx, b); err != nil {
+			return nil, err
+		}
+		for _, resp := range b.RawResponse().Responses {
+			if !resp.GetInner().(*roachpb.QueryIntentResponse).FoundIntent {
+				implicitlyCommitted = false
+				break
+			}
+		}
+	}
+
+	b := &client.Batch{}
+	b.AddRawRequest(&roachpb.RecoverTxnRequest{
+		Span:                roachpb.Span{Key: txn.Key},
+		Txn:                 txn.TxnMeta,
+		ImplicitlyCommitted: implicitlyCommitted,
+	})
+	if err := db.Run(ctx, b); err != nil {
+		return nil, err
+	}
+	recovered := b.RawResponse().Responses[0].GetInner().(*roachpb.RecoverTxnResponse).RecoveredTxn
+	if log.V(1) {
+		log.Infof(ctx, "recovered %s as %s", txn.ID, recovered.Status)
+	}
+	return &recovered, nil
+}